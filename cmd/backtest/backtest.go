@@ -0,0 +1,126 @@
+package backtest
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/config"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/backtest"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/venues"
+)
+
+var (
+	configPath string
+	dataFile   string
+	exchanges  []string
+	feeBps     float64
+	verbose    bool
+	fromDate   string
+	toDate     string
+	outputFile string
+)
+
+// BacktestCmd represents the backtest command
+var BacktestCmd = &cobra.Command{
+	Use:   "backtest",
+	Short: "Replays historical funding rates through the arbitrage strategy.",
+	Long: `Replays a CSV of historical funding rates through the funding rate arbitrage strategy,
+using the thresholds and sizing in the .env file, and reports the funding earned, trading fees,
+net APR on deployed capital, maximum drawdown and trade count per market.
+
+The CSV has a header of time,exchange,market,rate[,price]: one row per funding payment, with the
+rate per funding interval of the exchange. Use "backtest download" to fetch it from the venues.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, err := config.LoadConfig(configPath)
+		if err != nil {
+			log.Fatalf("cannot load config: %v", err)
+		}
+		if dataFile == "" {
+			log.Fatalf("no funding history given, pass --data")
+		}
+
+		f, err := os.Open(dataFile)
+		if err != nil {
+			log.Fatalf("cannot open funding history: %v", err)
+		}
+		samples, err := backtest.LoadCSV(f)
+		f.Close()
+		if err != nil {
+			log.Fatalf("cannot read funding history: %v", err)
+		}
+
+		opts := backtest.Options{Exchanges: exchanges, FeeBps: feeBps}
+		if verbose {
+			opts.Logger = log.New(os.Stderr, "[BACKTEST] ", 0)
+		}
+		result, err := backtest.Run(cfg, samples, opts)
+		if err != nil {
+			log.Fatalf("backtest failed: %v", err)
+		}
+		if err := backtest.WriteReport(os.Stdout, result); err != nil {
+			log.Fatalf("cannot write report: %v", err)
+		}
+	},
+}
+
+// downloadCmd represents the backtest download command
+var downloadCmd = &cobra.Command{
+	Use:   "download",
+	Short: "Downloads historical funding rates from the configured exchanges.",
+	Long: `Fetches the funding rates paid on MARKETS between --from and --to from every exchange in
+EXCHANGES and writes them as a CSV for the backtest command. Venues without a funding history
+endpoint (Lighter) are reported as errors.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, err := config.LoadConfig(configPath)
+		if err != nil {
+			log.Fatalf("cannot load config: %v", err)
+		}
+		from, err := time.Parse("2006-01-02", fromDate)
+		if err != nil {
+			log.Fatalf("invalid --from: %v", err)
+		}
+		to := time.Now()
+		if toDate != "" {
+			if to, err = time.Parse("2006-01-02", toDate); err != nil {
+				log.Fatalf("invalid --to: %v", err)
+			}
+		}
+
+		exchanges, err := venues.FromConfig(cfg)
+		if err != nil {
+			log.Fatalf("cannot create exchanges: %v", err)
+		}
+		samples, err := backtest.Download(cmd.Context(), exchanges, cfg.Markets, from, to)
+		if err != nil {
+			log.Fatalf("cannot download funding history: %v", err)
+		}
+
+		f, err := os.Create(outputFile)
+		if err != nil {
+			log.Fatalf("cannot create %s: %v", outputFile, err)
+		}
+		defer f.Close()
+		if err := backtest.WriteCSV(f, samples); err != nil {
+			log.Fatalf("cannot write %s: %v", outputFile, err)
+		}
+		fmt.Printf("Wrote %d funding rates to %s\n", len(samples), outputFile)
+	},
+}
+
+func init() {
+	BacktestCmd.PersistentFlags().StringVar(&configPath, "path", ".", "Path to the directory containing the .env file")
+	BacktestCmd.Flags().StringVar(&dataFile, "data", "", "CSV of historical funding rates")
+	BacktestCmd.Flags().StringSliceVar(&exchanges, "exchanges", nil, "The exchanges to trade between, as named in the CSV (default: every exchange in the data)")
+	BacktestCmd.Flags().Float64Var(&feeBps, "fee-bps", 5, "Trading fee per fill, in basis points of notional")
+	BacktestCmd.Flags().BoolVar(&verbose, "verbose", false, "Print the strategy's decisions")
+
+	downloadCmd.Flags().StringVar(&fromDate, "from", "", "First day to download (YYYY-MM-DD)")
+	downloadCmd.Flags().StringVar(&toDate, "to", "", "Day to stop at, exclusive (YYYY-MM-DD, default now)")
+	downloadCmd.Flags().StringVar(&outputFile, "out", "funding_history.csv", "File to write")
+	downloadCmd.MarkFlagRequired("from")
+	BacktestCmd.AddCommand(downloadCmd)
+}
@@ -0,0 +1,92 @@
+package balance
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/config"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/collateral"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/venues"
+)
+
+var (
+	configPath string
+	asJSON     bool
+)
+
+// BalanceCmd represents the balance command
+var BalanceCmd = &cobra.Command{
+	Use:   "balance",
+	Short: "Prints the collateral on every configured exchange and its USD total.",
+	Long: `Fetches the collateral of every exchange in EXCHANGES and prints its equity, the part
+that is free for new orders and the part used as margin, where the exchange reports them,
+with the equity converted to USD at COLLATERAL_PRICES and a total over all exchanges.
+Use it to check that every venue is funded before starting the bot. Nothing is traded.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, err := config.LoadConfig(configPath)
+		if err != nil {
+			log.Fatalf("cannot load config: %v", err)
+		}
+		prices, err := collateral.ParseStaticPrices(cfg.CollateralPrices)
+		if err != nil {
+			log.Fatalf("cannot load config: %v", err)
+		}
+
+		exchanges, err := venues.FromConfig(cfg)
+		if err != nil {
+			log.Fatalf("cannot create exchanges: %v", err)
+		}
+
+		balances := collateral.NewConverter(prices).Balances(cmd.Context(), exchanges)
+		if asJSON {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			err = encoder.Encode(struct {
+				Exchanges []collateral.VenueBalance `json:"exchanges"`
+				TotalUSD  float64                   `json:"totalUsd"`
+			}{balances, collateral.TotalUSD(balances)})
+		} else {
+			err = write(balances)
+		}
+		if err != nil {
+			log.Fatalf("cannot write balances: %v", err)
+		}
+		for _, b := range balances {
+			if b.Error != "" {
+				log.Fatalf("could not fetch the balance of every exchange, so the total is incomplete")
+			}
+		}
+	},
+}
+
+func write(balances []collateral.VenueBalance) error {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "EXCHANGE\tASSET\tEQUITY\tFREE\tUSED\tEQUITY USD")
+	for _, b := range balances {
+		if b.Error != "" {
+			fmt.Fprintf(tw, "%s\t%s\terror: %s\t\t\t\n", b.Exchange, b.Asset, b.Error)
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%.2f\t%s\t%s\t%.2f\n", b.Exchange, b.Asset, b.Equity, amount(b.Free), amount(b.Used), b.EquityUSD)
+	}
+	fmt.Fprintf(tw, "TOTAL\t\t\t\t\t%.2f\n", collateral.TotalUSD(balances))
+	return tw.Flush()
+}
+
+// amount formats an amount the exchange may not report.
+func amount(v *float64) string {
+	if v == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%.2f", *v)
+}
+
+func init() {
+	BalanceCmd.Flags().StringVar(&configPath, "path", ".", "Path to the directory containing the .env file")
+	BalanceCmd.Flags().BoolVar(&asJSON, "json", false, "Print the balances as JSON")
+}
@@ -0,0 +1,127 @@
+package closecmd
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/config"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/instance"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/state"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/strategy"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/venues"
+)
+
+var (
+	configPath string
+	all        bool
+	market     string
+	force      bool
+)
+
+// CloseCmd represents the close command
+var CloseCmd = &cobra.Command{
+	Use:   "close",
+	Short: "Market-closes both legs of the bot's tracked positions.",
+	Long: `Unwinds the positions saved in STATE_FILE by market-closing both of their legs, on
+every market with --all or on one with --market. The positions are listed and must be
+confirmed unless --force is given. The bot must not be running: stop it first, or use
+the close chat command of the running bot instead.
+
+Positions whose legs both close are removed from STATE_FILE. Those with a failed leg
+stay, and running the command again retries them without closing the legs that are
+already gone.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if all == (market != "") {
+			log.Fatalf("give either --all or --market")
+		}
+		market = strings.ToUpper(market)
+
+		cfg, err := config.LoadConfig(configPath)
+		if err != nil {
+			log.Fatalf("cannot load config: %v", err)
+		}
+		if cfg.StateFile == "" {
+			log.Fatalf("STATE_FILE is not set, so no positions are tracked")
+		}
+
+		// A running bot manages the same positions, so closing them under it would race its loop.
+		accountIDs, err := venues.AccountIDs(cfg)
+		if err != nil {
+			log.Fatalf("cannot load config: %v", err)
+		}
+		lock, err := instance.Acquire(instance.LockPaths(cfg.LockDir, accountIDs...)...)
+		if err != nil {
+			log.Fatalf("cannot close positions: %v", err)
+		}
+		defer lock.Release()
+
+		store := state.Open(cfg.StateFile)
+		positions, err := strategy.SavedPositions(store, market)
+		if err != nil {
+			log.Fatalf("cannot load positions: %v", err)
+		}
+		if len(positions) == 0 {
+			fmt.Println("No tracked positions to close.")
+			return
+		}
+		fmt.Printf("Tracked positions on %s:\n", map[bool]string{true: "testnet", false: "MAINNET"}[cfg.Testnet])
+		for _, p := range positions {
+			fmt.Printf("  %s long %s / short %s, %.2f USD, opened %s\n",
+				p.Market, p.LongExchange, p.ShortExchange, p.SizeUSD, p.OpenedAt.Format("2006-01-02 15:04:05"))
+		}
+		if !force && !confirm(fmt.Sprintf("Market-close both legs of %d position(s)?", len(positions))) {
+			fmt.Println("Aborted, nothing was closed.")
+			return
+		}
+
+		exchanges, err := venues.FromConfig(cfg)
+		if err != nil {
+			log.Fatalf("cannot create exchanges: %v", err)
+		}
+		results, err := strategy.Unwind(cmd.Context(), store, exchanges, market)
+		failed := 0
+		for _, r := range results {
+			p := r.Position
+			if r.Closed() {
+				fmt.Printf("Closed %s: long %s and short %s, %s each.\n", p.Market, p.LongExchange, p.ShortExchange, r.Amount)
+				continue
+			}
+			failed++
+			fmt.Printf("Could not close %s: long %s: %s, short %s: %s\n", p.Market, p.LongExchange, outcome(r.LongErr), p.ShortExchange, outcome(r.ShortErr))
+		}
+		lock.Release()
+		if err != nil {
+			log.Fatalf("cannot update %s: %v", cfg.StateFile, err)
+		}
+		if failed > 0 {
+			log.Fatalf("%d position(s) are not fully closed and stay tracked; run the command again to retry", failed)
+		}
+	},
+}
+
+// confirm asks a yes/no question on the terminal. Anything but y or yes is a no.
+func confirm(question string) bool {
+	fmt.Printf("%s [y/N] ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+func outcome(err error) string {
+	if err == nil {
+		return "closed"
+	}
+	return err.Error()
+}
+
+func init() {
+	CloseCmd.Flags().StringVar(&configPath, "path", ".", "Path to the directory containing the .env file")
+	CloseCmd.Flags().BoolVar(&all, "all", false, "Close every tracked position")
+	CloseCmd.Flags().StringVar(&market, "market", "", "Close the tracked position on this market, e.g. BTC-USD")
+	CloseCmd.Flags().BoolVar(&force, "force", false, "Close without asking for confirmation")
+}
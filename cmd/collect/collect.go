@@ -0,0 +1,71 @@
+package collect
+
+import (
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/config"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/backtest"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/exchange"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/venues"
+)
+
+var (
+	configPath string
+	outputFile string
+	interval   time.Duration
+	lookback   time.Duration
+	once       bool
+)
+
+// CollectCmd represents the collect command
+var CollectCmd = &cobra.Command{
+	Use:   "collect",
+	Short: "Keeps a local history of the funding rates paid on the configured exchanges.",
+	Long: `Polls every exchange in EXCHANGES for the funding rates paid on MARKETS and appends the
+ones not recorded yet to a CSV in the format read by the backtest command, so the history grows
+for as long as the collector runs. Markets missing from the file are fetched from --lookback ago.
+Venues without a funding history endpoint (Lighter) are skipped.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, err := config.LoadConfig(configPath)
+		if err != nil {
+			log.Fatalf("cannot load config: %v", err)
+		}
+		exchanges, err := venues.FromConfig(cfg)
+		if err != nil {
+			log.Fatalf("cannot create exchanges: %v", err)
+		}
+		logger := log.New(os.Stdout, "[COLLECT] ", log.LstdFlags)
+		for _, ex := range exchanges {
+			if _, ok := ex.(exchange.FundingHistorian); !ok {
+				logger.Printf("%s does not provide funding history, skipping it.", ex.Name())
+			}
+		}
+
+		collector := &backtest.Collector{Exchanges: exchanges, Markets: cfg.Markets, Path: outputFile, Lookback: lookback, Logger: logger}
+		if once {
+			written, err := collector.Collect(cmd.Context(), time.Now())
+			if err != nil {
+				log.Fatalf("cannot collect funding history: %v", err)
+			}
+			logger.Printf("Collected %d funding rates into %s.", written, outputFile)
+			return
+		}
+		ctx, stop := signal.NotifyContext(cmd.Context(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+		collector.Run(ctx, interval)
+	},
+}
+
+func init() {
+	CollectCmd.Flags().StringVar(&configPath, "path", ".", "Path to the directory containing the .env file")
+	CollectCmd.Flags().StringVar(&outputFile, "out", "funding_history.csv", "CSV file to append to")
+	CollectCmd.Flags().DurationVar(&interval, "interval", time.Hour, "How often to poll the exchanges")
+	CollectCmd.Flags().DurationVar(&lookback, "lookback", 30*24*time.Hour, "How far back to fetch markets missing from the file")
+	CollectCmd.Flags().BoolVar(&once, "once", false, "Collect once and exit, e.g. from cron")
+}
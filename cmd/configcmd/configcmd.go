@@ -0,0 +1,104 @@
+package configcmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/config"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/calendar"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/capital"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/collateral"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/exchange"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/execution"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/oracle"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/strategy"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/venues"
+)
+
+var configPath string
+
+// ConfigCmd groups commands that work on the configuration.
+var ConfigCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspects the configuration.",
+}
+
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Checks the configuration without trading.",
+	Long: `Loads the .env file, the structured config file (config.yaml or config.json) and the
+environment as the bot would, and reports every unknown key, unparseable list entry and value the
+bot can't trade with. No exchange is contacted. Exits with status 1 if any problem is found.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, err := config.LoadConfig(configPath)
+		if err == nil {
+			err = check(cfg)
+		}
+		if err != nil {
+			fmt.Println("The configuration has problems:")
+			for _, line := range strings.Split(err.Error(), "\n") {
+				fmt.Printf("  - %s\n", line)
+			}
+			os.Exit(1)
+		}
+		fmt.Println("The configuration is valid.")
+	},
+}
+
+// check runs the config's own validation and every parser the bot runs on its list settings.
+func check(cfg config.Config) error {
+	errs := []error{cfg.Validate(), venues.Validate(cfg)}
+	for _, name := range strings.Split(cfg.Strategy, ",") {
+		if !known(strings.TrimSpace(name), strategy.Names()) {
+			errs = append(errs, fmt.Errorf("STRATEGY: unknown strategy %q (available: %s)", strings.TrimSpace(name), strings.Join(strategy.Names(), ", ")))
+		}
+	}
+	if cfg.Oracle != "" && cfg.Oracle != "pyth" {
+		errs = append(errs, fmt.Errorf("ORACLE: unknown oracle %q (available: pyth)", cfg.Oracle))
+	}
+
+	if _, err := capital.ParseBudgets(cfg.StrategyBudgets); err != nil {
+		errs = append(errs, fmt.Errorf("STRATEGY_BUDGETS: %w", err))
+	}
+	if _, err := calendar.ParseSchedules(cfg.FundingSchedules); err != nil {
+		errs = append(errs, fmt.Errorf("FUNDING_SCHEDULES: %w", err))
+	}
+	if _, err := collateral.ParseStaticPrices(cfg.CollateralPrices); err != nil {
+		errs = append(errs, fmt.Errorf("COLLATERAL_PRICES: %w", err))
+	}
+	if _, err := oracle.ParseFeeds(cfg.OracleFeeds); err != nil {
+		errs = append(errs, fmt.Errorf("ORACLE_FEEDS: %w", err))
+	}
+	if _, err := exchange.ParseMarginModes(cfg.MarginModes); err != nil {
+		errs = append(errs, fmt.Errorf("MARGIN_MODES: %w", err))
+	}
+	if _, err := exchange.ParseLeverages(cfg.Leverages); err != nil {
+		errs = append(errs, fmt.Errorf("LEVERAGES: %w", err))
+	}
+	if _, err := execution.ParseConfigs(cfg.MakerEntry); err != nil {
+		errs = append(errs, fmt.Errorf("MAKER_ENTRY: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+// known reports whether name is one of names; an empty name selects the default strategy.
+func known(name string, names []string) bool {
+	if name == "" {
+		return true
+	}
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+func init() {
+	validateCmd.Flags().StringVar(&configPath, "path", ".", "Path to the directory containing the .env and config files")
+	ConfigCmd.AddCommand(validateCmd)
+}
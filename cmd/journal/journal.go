@@ -0,0 +1,88 @@
+package journal
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/config"
+	tradejournal "github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/journal"
+)
+
+var (
+	configPath  string
+	journalFile string
+	outFile     string
+	asCSV       bool
+)
+
+// JournalCmd groups commands that work on the trade journal.
+var JournalCmd = &cobra.Command{
+	Use:   "journal",
+	Short: "Inspects the trade journal.",
+}
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Exports every journal entry.",
+	Long: `Writes every order, fill, close, funding payment and error recorded in the trade journal,
+in the order they happened, as JSON lines or, with --csv, as CSV.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if journalFile == "" {
+			cfg, err := config.LoadConfig(configPath)
+			if err != nil {
+				log.Fatalf("cannot load config: %v", err)
+			}
+			journalFile = cfg.JournalFile
+		}
+		if journalFile == "" {
+			log.Fatalf("no journal configured, set JOURNAL_FILE or pass --journal")
+		}
+
+		entries, err := tradejournal.ReadAll(journalFile)
+		if err != nil {
+			log.Fatalf("cannot read journal: %v", err)
+		}
+
+		var out io.Writer = os.Stdout
+		if outFile != "" {
+			f, err := os.Create(outFile)
+			if err != nil {
+				log.Fatalf("cannot create %s: %v", outFile, err)
+			}
+			defer f.Close()
+			out = f
+		}
+		if asCSV {
+			err = tradejournal.WriteCSV(out, entries)
+		} else {
+			err = writeJSONLines(out, entries)
+		}
+		if err != nil {
+			log.Fatalf("cannot export journal: %v", err)
+		}
+	},
+}
+
+func writeJSONLines(w io.Writer, entries []tradejournal.Entry) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+func init() {
+	exportCmd.Flags().StringVar(&configPath, "path", ".", "Path to the directory containing the .env file")
+	exportCmd.Flags().StringVar(&journalFile, "journal", "", "Journal file to read (defaults to JOURNAL_FILE)")
+	exportCmd.Flags().StringVar(&outFile, "out", "", "File to write to (defaults to standard output)")
+	exportCmd.Flags().BoolVar(&asCSV, "csv", false, "Write CSV instead of JSON lines")
+	JournalCmd.AddCommand(exportCmd)
+}
@@ -0,0 +1,66 @@
+package matrix
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/config"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/marketdata"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/rates"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/venues"
+)
+
+var (
+	configPath string
+	asJSON     bool
+)
+
+// MatrixCmd represents the matrix command
+var MatrixCmd = &cobra.Command{
+	Use:   "matrix",
+	Short: "Prints the cross-venue funding spread matrix for every market.",
+	Long: `Fetches funding rates from every configured exchange and prints, for each market,
+an N×N matrix of annualized spreads (rows are the short venue, columns the long venue).
+The best pair per market is marked with an asterisk.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, err := config.LoadConfig(configPath)
+		if err != nil {
+			log.Fatalf("cannot load config: %v", err)
+		}
+
+		exchanges, err := venues.FromConfig(cfg)
+		if err == nil {
+			exchanges, err = venues.Rename(cfg, exchanges)
+		}
+		if err != nil {
+			log.Fatalf("cannot create exchanges: %v", err)
+		}
+
+		cache := marketdata.NewCache(time.Duration(cfg.MarketDataTTLSeconds) * time.Second)
+		snapshot := rates.NewAggregator(exchanges, cfg.Markets, cache).Collect(cmd.Context())
+		for name, msg := range snapshot.Errors {
+			log.Printf("%s: %s", name, msg)
+		}
+
+		matrices := rates.BuildMatrices(snapshot)
+		if asJSON {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			err = encoder.Encode(matrices)
+		} else {
+			err = rates.WriteMatrices(os.Stdout, matrices)
+		}
+		if err != nil {
+			log.Fatalf("cannot write matrix: %v", err)
+		}
+	},
+}
+
+func init() {
+	MatrixCmd.Flags().StringVar(&configPath, "path", ".", "Path to the directory containing the .env file")
+	MatrixCmd.Flags().BoolVar(&asJSON, "json", false, "Print the matrices as JSON")
+}
@@ -0,0 +1,73 @@
+package ratescmd
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/config"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/marketdata"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/rates"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/venues"
+)
+
+var (
+	configPath string
+	asJSON     bool
+	bestOnly   bool
+	top        int
+)
+
+// RatesCmd represents the rates command
+var RatesCmd = &cobra.Command{
+	Use:   "rates",
+	Short: "Prints the cross-venue funding spreads, widest first.",
+	Long: `Fetches funding rates from every configured exchange and prints the annualized
+funding difference of every venue pair on each market, sorted from the widest, with
+the annualized rates of both legs. Nothing is traded.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, err := config.LoadConfig(configPath)
+		if err != nil {
+			log.Fatalf("cannot load config: %v", err)
+		}
+
+		exchanges, err := venues.FromConfig(cfg)
+		if err == nil {
+			exchanges, err = venues.Rename(cfg, exchanges)
+		}
+		if err != nil {
+			log.Fatalf("cannot create exchanges: %v", err)
+		}
+
+		cache := marketdata.NewCache(time.Duration(cfg.MarketDataTTLSeconds) * time.Second)
+		snapshot := rates.NewAggregator(exchanges, cfg.Markets, cache).Collect(cmd.Context())
+		for name, msg := range snapshot.Errors {
+			log.Printf("%s: %s", name, msg)
+		}
+
+		opportunities := rates.Opportunities(snapshot, bestOnly)
+		if top > 0 && len(opportunities) > top {
+			opportunities = opportunities[:top]
+		}
+		if asJSON {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			err = encoder.Encode(opportunities)
+		} else {
+			err = rates.WriteOpportunities(os.Stdout, opportunities, time.Now())
+		}
+		if err != nil {
+			log.Fatalf("cannot write rates: %v", err)
+		}
+	},
+}
+
+func init() {
+	RatesCmd.Flags().StringVar(&configPath, "path", ".", "Path to the directory containing the .env file")
+	RatesCmd.Flags().BoolVar(&asJSON, "json", false, "Print the spreads as JSON")
+	RatesCmd.Flags().BoolVar(&bestOnly, "best", false, "Only print the widest spread of each market")
+	RatesCmd.Flags().IntVar(&top, "top", 0, "Only print the widest N spreads (0 prints all)")
+}
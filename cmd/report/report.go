@@ -0,0 +1,126 @@
+package report
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/config"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/journal"
+	taxreport "github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/report"
+)
+
+var (
+	configPath  string
+	journalFile string
+	outputDir   string
+	format      string
+	fromDate    string
+	toDate      string
+)
+
+// ReportCmd represents the report command
+var ReportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Generates tax, accounting and execution reports from the trade journal.",
+	Long: `Reads the trade journal and writes realized gains (first-in-first-out cost basis per
+exchange and market, fees included) and funding income aggregated by day as CSV files.
+
+Formats:
+  generic    realized_gains.csv and funding_income.csv
+  koinly     koinly.csv in Koinly's universal import format
+  execution  execution.csv with per-exchange slippage and latency of fills`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if journalFile == "" {
+			cfg, err := config.LoadConfig(configPath)
+			if err != nil {
+				log.Fatalf("cannot load config: %v", err)
+			}
+			journalFile = cfg.JournalFile
+		}
+		if journalFile == "" {
+			log.Fatalf("no journal configured, set JOURNAL_FILE or pass --journal")
+		}
+
+		from, err := parseDate(fromDate)
+		if err != nil {
+			log.Fatalf("invalid --from: %v", err)
+		}
+		to, err := parseDate(toDate)
+		if err != nil {
+			log.Fatalf("invalid --to: %v", err)
+		}
+
+		entries, err := journal.ReadAll(journalFile)
+		if err != nil {
+			log.Fatalf("cannot read journal: %v", err)
+		}
+		report := taxreport.BuildTaxReport(entries, from, to)
+
+		if err := os.MkdirAll(outputDir, 0o755); err != nil {
+			log.Fatalf("cannot create output directory: %v", err)
+		}
+		switch format {
+		case "generic":
+			err = writeFile(filepath.Join(outputDir, "realized_gains.csv"), func(f *os.File) error {
+				return taxreport.WriteGainsCSV(f, report.Gains)
+			})
+			if err == nil {
+				err = writeFile(filepath.Join(outputDir, "funding_income.csv"), func(f *os.File) error {
+					return taxreport.WriteFundingCSV(f, report.Funding)
+				})
+			}
+		case "koinly":
+			err = writeFile(filepath.Join(outputDir, "koinly.csv"), func(f *os.File) error {
+				return taxreport.WriteKoinlyCSV(f, report)
+			})
+		case "execution":
+			execution := taxreport.BuildExecutionReport(entries, from, to)
+			err = writeFile(filepath.Join(outputDir, "execution.csv"), func(f *os.File) error {
+				return taxreport.WriteExecutionCSV(f, execution)
+			})
+			if err == nil {
+				fmt.Println(taxreport.FormatExecution(execution))
+			}
+		default:
+			log.Fatalf("unknown format %q (available: generic, koinly, execution)", format)
+		}
+		if err != nil {
+			log.Fatalf("cannot write report: %v", err)
+		}
+		fmt.Printf("Wrote %d realized gains and %d funding days to %s\n", len(report.Gains), len(report.Funding), outputDir)
+	},
+}
+
+// parseDate parses a YYYY-MM-DD date in UTC. An empty string yields the zero time.
+func parseDate(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse("2006-01-02", s)
+}
+
+func writeFile(path string, write func(f *os.File) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func init() {
+	ReportCmd.Flags().StringVar(&configPath, "path", ".", "Path to the directory containing the .env file")
+	ReportCmd.Flags().StringVar(&journalFile, "journal", "", "Journal file to read (defaults to JOURNAL_FILE)")
+	ReportCmd.Flags().StringVar(&outputDir, "out", ".", "Directory to write the CSV files to")
+	ReportCmd.Flags().StringVar(&format, "format", "generic", "Output format: generic, koinly or execution")
+	ReportCmd.Flags().StringVar(&fromDate, "from", "", "Only include events on or after this date (YYYY-MM-DD)")
+	ReportCmd.Flags().StringVar(&toDate, "to", "", "Only include events before this date (YYYY-MM-DD)")
+}
@@ -4,7 +4,19 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/cmd/backtest"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/cmd/balance"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/cmd/closecmd"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/cmd/collect"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/cmd/configcmd"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/cmd/journal"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/cmd/matrix"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/cmd/ratescmd"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/cmd/report"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/cmd/serve"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/cmd/testnet"
 	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/cmd/trade"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/cmd/watch"
 
 	"github.com/spf13/cobra"
 )
@@ -26,4 +38,16 @@ func Execute() {
 
 func init() {
 	rootCmd.AddCommand(trade.TradeCmd)
+	rootCmd.AddCommand(serve.ServeCmd)
+	rootCmd.AddCommand(report.ReportCmd)
+	rootCmd.AddCommand(matrix.MatrixCmd)
+	rootCmd.AddCommand(ratescmd.RatesCmd)
+	rootCmd.AddCommand(testnet.TestnetCmd)
+	rootCmd.AddCommand(backtest.BacktestCmd)
+	rootCmd.AddCommand(collect.CollectCmd)
+	rootCmd.AddCommand(journal.JournalCmd)
+	rootCmd.AddCommand(configcmd.ConfigCmd)
+	rootCmd.AddCommand(closecmd.CloseCmd)
+	rootCmd.AddCommand(balance.BalanceCmd)
+	rootCmd.AddCommand(watch.WatchCmd)
 }
@@ -0,0 +1,106 @@
+package serve
+
+import (
+	"encoding/json"
+	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/config"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/logging"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/marketdata"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/rates"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/venues"
+)
+
+var (
+	configPath string
+	listenAddr string
+)
+
+// ServeCmd represents the serve command
+var ServeCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serves aggregated cross-venue funding rates over HTTP.",
+	Long: `Starts an HTTP server exposing normalized funding rates and pairwise spreads
+for every configured exchange as JSON, without running the trading strategy.
+
+Endpoints:
+  GET /rates    annualized funding rates per exchange and market
+  GET /spreads  pairwise annualized spreads, best first
+  GET /matrix   N×N spread matrix per market with the best pair
+  GET /healthz  liveness probe`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, err := config.LoadConfig(configPath)
+		if err != nil {
+			log.Fatalf("cannot load config: %v", err)
+		}
+
+		structured, err := logging.New(os.Stdout, logging.FromConfig(cfg))
+		if err != nil {
+			log.Fatalf("cannot load config: %v", err)
+		}
+		slog.SetDefault(structured)
+		logger := logging.Std(structured, "serve")
+
+		exchanges, err := venues.FromConfig(cfg)
+		if err == nil {
+			exchanges, err = venues.Rename(cfg, exchanges)
+		}
+		if err != nil {
+			log.Fatalf("cannot create exchanges: %v", err)
+		}
+
+		cache := marketdata.NewCache(time.Duration(cfg.MarketDataTTLSeconds) * time.Second)
+		aggregator := rates.NewAggregator(exchanges, cfg.Markets, cache)
+
+		mux := http.NewServeMux()
+		mux.HandleFunc("/rates", func(w http.ResponseWriter, r *http.Request) {
+			snapshot := aggregator.Collect(r.Context())
+			writeJSON(w, struct {
+				Rates  []rates.VenueRate `json:"rates"`
+				Errors map[string]string `json:"errors,omitempty"`
+			}{snapshot.Rates, snapshot.Errors})
+		})
+		mux.HandleFunc("/spreads", func(w http.ResponseWriter, r *http.Request) {
+			snapshot := aggregator.Collect(r.Context())
+			writeJSON(w, struct {
+				Spreads []rates.Spread    `json:"spreads"`
+				Errors  map[string]string `json:"errors,omitempty"`
+			}{snapshot.Spreads, snapshot.Errors})
+		})
+		mux.HandleFunc("/matrix", func(w http.ResponseWriter, r *http.Request) {
+			snapshot := aggregator.Collect(r.Context())
+			writeJSON(w, struct {
+				Matrices []rates.Matrix    `json:"matrices"`
+				Errors   map[string]string `json:"errors,omitempty"`
+			}{rates.BuildMatrices(snapshot), snapshot.Errors})
+		})
+		mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, map[string]string{"status": "ok"})
+		})
+
+		logger.Printf("Serving funding rates on %s", listenAddr)
+		server := &http.Server{Addr: listenAddr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+		if err := server.ListenAndServe(); err != nil {
+			logger.Fatalf("HTTP server stopped: %v", err)
+		}
+	},
+}
+
+// writeJSON encodes v as the JSON response body.
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func init() {
+	ServeCmd.Flags().StringVar(&configPath, "path", ".", "Path to the directory containing the .env file")
+	ServeCmd.Flags().StringVar(&listenAddr, "addr", ":8080", "Address for the HTTP server to listen on")
+}
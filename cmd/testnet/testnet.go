@@ -0,0 +1,160 @@
+package testnet
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/config"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/exchange"
+)
+
+var (
+	configPath string
+	skipFaucet bool
+)
+
+// TestnetCmd groups helpers for working against exchange testnets.
+var TestnetCmd = &cobra.Command{
+	Use:   "testnet",
+	Short: "Helpers for running the bot against exchange testnets.",
+}
+
+// setupCmd represents the testnet setup command
+var setupCmd = &cobra.Command{
+	Use:   "setup",
+	Short: "Requests test funds and checks that testnet accounts are ready to trade.",
+	Long: `Checks the testnet credentials in the .env file, requests test funds from every venue
+that exposes a faucet (Extended Sepolia), verifies each account is trade-ready and prints the
+minimal configuration needed to run the bot and the integration test.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, err := config.LoadConfig(configPath)
+		if err != nil {
+			log.Fatalf("cannot load config: %v", err)
+		}
+		if !cfg.Testnet {
+			log.Fatalf("TESTNET is false; refusing to run testnet setup against mainnet accounts")
+		}
+
+		ready := true
+		if missing := missingKeys(map[string]string{
+			"EXTENDED_API_KEY":     cfg.ExtendedAPIKey,
+			"EXTENDED_PRIVATE_KEY": cfg.ExtendedPrivateKey,
+			"EXTENDED_PUBLIC_KEY":  cfg.ExtendedPublicKey,
+		}); len(missing) > 0 || cfg.ExtendedVaultID == 0 {
+			if cfg.ExtendedVaultID == 0 {
+				missing = append(missing, "EXTENDED_VAULT_ID")
+			}
+			fmt.Printf("[Extended] missing %s. Create a testnet account at https://starknet.sepolia.extended.exchange and generate an API key.\n", strings.Join(missing, ", "))
+			ready = false
+		} else {
+			extendedEx, err := exchange.NewExtended(cfg.ExtendedAPIKey, cfg.ExtendedPrivateKey, cfg.ExtendedPublicKey, cfg.ExtendedVaultID, true)
+			if err != nil {
+				fmt.Printf("[Extended] %v\n", err)
+				ready = false
+			} else {
+				ready = checkVenue(cmd.Context(), extendedEx) && ready
+			}
+		}
+
+		if missing := missingKeys(map[string]string{
+			"LIGHTER_API_KEY":     cfg.LighterAPIKey,
+			"LIGHTER_PRIVATE_KEY": cfg.LighterPrivateKey,
+			"LIGHTER_SIGNER_CMD":  cfg.LighterSignerCmd,
+		}); len(missing) > 0 {
+			fmt.Printf("[Lighter] missing %s. Create a testnet API key at https://testnet.app.lighter.xyz.\n", strings.Join(missing, ", "))
+			ready = false
+		} else {
+			lighterEx := exchange.NewLighter(cfg.LighterAPIKey, cfg.LighterPrivateKey, true)
+			signer, err := exchange.NewCommandSigner(cfg.LighterSignerCmd, cfg.LighterPrivateKey, true)
+			if err != nil {
+				fmt.Printf("[Lighter] %v\n", err)
+				ready = false
+			} else {
+				lighterEx.SetSigner(signer, cfg.LighterAccountIndex, cfg.LighterAPIKeyIndex)
+				ready = checkVenue(cmd.Context(), lighterEx) && ready
+			}
+		}
+
+		fmt.Println()
+		fmt.Println("Minimal .env for testnet:")
+		fmt.Println("TESTNET=true")
+		fmt.Println("LIGHTER_API_KEY=" + placeholder(cfg.LighterAPIKey))
+		fmt.Println("LIGHTER_PRIVATE_KEY=" + placeholder(cfg.LighterPrivateKey))
+		fmt.Println("LIGHTER_SIGNER_CMD=" + placeholder(cfg.LighterSignerCmd))
+		fmt.Println("EXTENDED_API_KEY=" + placeholder(cfg.ExtendedAPIKey))
+		fmt.Println("EXTENDED_PRIVATE_KEY=" + placeholder(cfg.ExtendedPrivateKey))
+		fmt.Println("EXTENDED_PUBLIC_KEY=" + placeholder(cfg.ExtendedPublicKey))
+		fmt.Printf("EXTENDED_VAULT_ID=%d\n", cfg.ExtendedVaultID)
+		fmt.Println("MARKETS=BTC-USD")
+		fmt.Println("MIN_FUNDING_RATE_DIFF=0.0001")
+		fmt.Println("POSITION_SIZE_USD=10")
+		fmt.Println("MAX_POSITION_USD=100")
+
+		if !ready {
+			fmt.Println()
+			fmt.Println("Some venues are not trade-ready yet, see the messages above.")
+			os.Exit(1)
+		}
+		fmt.Println()
+		fmt.Println("All venues are trade-ready. Run `go test -v -tags integration ./pkg/strategy/` to execute the integration test.")
+	},
+}
+
+// checkVenue requests test funds where a faucet is available and reports whether the account has
+// a usable balance.
+func checkVenue(ctx context.Context, ex exchange.Exchange) bool {
+	if f, ok := ex.(exchange.Fauceter); ok && !skipFaucet {
+		if err := f.RequestTestFunds(ctx); err != nil {
+			fmt.Printf("[%s] faucet request failed: %v\n", ex.Name(), err)
+		} else {
+			fmt.Printf("[%s] test funds requested\n", ex.Name())
+			// Faucet credits are not always visible immediately.
+			time.Sleep(2 * time.Second)
+		}
+	} else if !ok {
+		fmt.Printf("[%s] no faucet endpoint, fund the account through the venue's testnet UI\n", ex.Name())
+	}
+
+	balance, err := ex.GetBalance(ctx, "USDC")
+	if err != nil {
+		fmt.Printf("[%s] cannot verify balance: %v\n", ex.Name(), err)
+		return false
+	}
+	if balance <= 0 {
+		fmt.Printf("[%s] balance is 0, account is not funded\n", ex.Name())
+		return false
+	}
+	fmt.Printf("[%s] trade-ready, balance %.2f\n", ex.Name(), balance)
+	return true
+}
+
+func missingKeys(values map[string]string) []string {
+	var missing []string
+	for key, value := range values {
+		if value == "" {
+			missing = append(missing, key)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+func placeholder(value string) string {
+	if value == "" {
+		return "<required>"
+	}
+	return "<set>"
+}
+
+func init() {
+	setupCmd.Flags().StringVar(&configPath, "path", ".", "Path to the directory containing the .env file")
+	setupCmd.Flags().BoolVar(&skipFaucet, "skip-faucet", false, "Only verify the accounts, do not request test funds")
+	TestnetCmd.AddCommand(setupCmd)
+}
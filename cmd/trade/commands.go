@@ -0,0 +1,93 @@
+package trade
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/notifications"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/pnl"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/strategy"
+)
+
+// commandTimeout bounds how long a chat command waits for a strategy loop to execute it.
+const commandTimeout = 30 * time.Second
+
+// operator is implemented by strategies that can be inspected and controlled from chat.
+type operator interface {
+	Status() string
+	PnL() pnl.Summary
+	Balances() string
+	Do(name strategy.CommandName, market string, timeout time.Duration) error
+}
+
+// commandHelp lists the chat commands.
+const commandHelp = `/status - open positions and current rate differences
+/pnl - realized and unrealized PnL
+/balance - collateral on each exchange
+/close <market> - close the position on a market
+/flatten - close every position
+/preserve - leave the positions open when asked on shutdown
+/pause - stop opening new positions
+/resume - resume opening new positions`
+
+// registerCommands lets the operator inspect and control the strategies that support it from
+// the configured Telegram chat.
+func registerCommands(notifier *notifications.TelegramNotifier, runners []strategy.Runner) {
+	var operators []operator
+	for _, runner := range runners {
+		if op, ok := runner.(operator); ok {
+			operators = append(operators, op)
+		}
+	}
+	if len(operators) == 0 {
+		return
+	}
+
+	each := func(describe func(op operator) string) notifications.CommandHandler {
+		return func(string) string {
+			parts := make([]string, len(operators))
+			for i, op := range operators {
+				parts[i] = describe(op)
+			}
+			return strings.Join(parts, "\n\n")
+		}
+	}
+	control := func(name strategy.CommandName, done string) notifications.CommandHandler {
+		return func(string) string {
+			for _, op := range operators {
+				if err := op.Do(name, "", commandTimeout); err != nil {
+					return fmt.Sprintf("❌ %s: %v", name, err)
+				}
+			}
+			return done
+		}
+	}
+
+	notifier.HandleCommand("start", func(string) string { return commandHelp })
+	notifier.HandleCommand("help", func(string) string { return commandHelp })
+	notifier.HandleCommand("status", each(operator.Status))
+	notifier.HandleCommand("pnl", each(func(op operator) string { return pnl.Format(op.PnL()) }))
+	notifier.HandleCommand("balance", each(operator.Balances))
+	notifier.HandleCommand("pause", control(strategy.CommandPause, "⏸ Paused, no new positions will be opened."))
+	notifier.HandleCommand("resume", control(strategy.CommandResume, "▶️ Resumed."))
+	notifier.HandleCommand("flatten", control(strategy.CommandFlatten, "✅ Closed every position."))
+	notifier.HandleCommand("preserve", control(strategy.CommandPreserve, "⏹ Positions left open."))
+	notifier.HandleCommand("close", func(args string) string {
+		market := strings.ToUpper(args)
+		if market == "" {
+			return "Usage: /close <market>, e.g. /close BTC-USD"
+		}
+		// Only the strategy holding the market can close it; the others report no position.
+		var errs []error
+		for _, op := range operators {
+			err := op.Do(strategy.CommandClose, market, commandTimeout)
+			if err == nil {
+				return fmt.Sprintf("✅ Closed the %s position.", market)
+			}
+			errs = append(errs, err)
+		}
+		return fmt.Sprintf("❌ close %s: %v", market, errors.Join(errs...))
+	})
+}
@@ -1,20 +1,52 @@
 package trade
 
 import (
+	"context"
+	"fmt"
 	"log"
+	"log/slog"
+	"net/http"
 	"os"
 	"os/signal"
+	"strings"
+	"sync"
 	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/config"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/api"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/capital"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/collateral"
 	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/exchange"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/export"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/instance"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/journal"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/logging"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/marketdata"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/metrics"
 	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/notifications"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/rates"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/state"
 	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/strategy"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/venues"
 )
 
-var configPath string
+const (
+	// defaultShutdownTimeout bounds a graceful shutdown when SHUTDOWN_TIMEOUT_SECONDS is not set.
+	defaultShutdownTimeout = 20 * time.Second
+	// defaultPaperBalance is the virtual balance per exchange when PAPER_BALANCE_USD is not set.
+	defaultPaperBalance = 10000.0
+	// selfTestTimeout bounds the startup self-test of the exchanges.
+	selfTestTimeout = 30 * time.Second
+)
+
+var (
+	configPath string
+	paper      bool
+	dryRun     bool
+)
 
 // TradeCmd represents the trade command
 var TradeCmd = &cobra.Command{
@@ -24,49 +56,358 @@ var TradeCmd = &cobra.Command{
 It connects to the configured exchanges, fetches funding rates,
 and executes trades when an arbitrage opportunity is identified based on the provided configuration.`,
 	Run: func(cmd *cobra.Command, args []string) {
+		if paper && dryRun {
+			log.Fatalf("--paper and --dry-run can't be combined")
+		}
+
 		// Load configuration
 		cfg, err := config.LoadConfig(configPath)
 		if err != nil {
 			log.Fatalf("cannot load config: %v", err)
 		}
 
-		// Setup logger
-		logger := log.New(os.Stdout, "[ARB-BOT] ", log.LstdFlags)
+		// Setup logger. Code that logs without a logger, such as the exchange clients, logs to the
+		// default logger, which writes through it as well.
+		structured, err := logging.New(os.Stdout, logging.FromConfig(cfg))
+		if err != nil {
+			log.Fatalf("cannot load config: %v", err)
+		}
+		slog.SetDefault(structured)
+		logger := logging.Std(structured, "bot")
+
+		// Refuse to start if another instance is already trading these accounts. Paper and dry runs
+		// place no orders, so they can run next to a live instance.
+		var lock *instance.Lock
+		if !paper && !dryRun {
+			accountIDs, err := venues.AccountIDs(cfg)
+			if err != nil {
+				log.Fatalf("cannot load config: %v", err)
+			}
+			lock, err = instance.Acquire(instance.LockPaths(cfg.LockDir, accountIDs...)...)
+			if err != nil {
+				log.Fatalf("cannot start bot: %v", err)
+			}
+			defer lock.Release()
+		}
 
 		// Initialize exchanges
 		logger.Printf("Initializing exchanges in %s mode...", map[bool]string{true: "Testnet", false: "Mainnet"}[cfg.Testnet])
 
-		lighterEx := exchange.NewLighter(cfg.LighterAPIKey, cfg.LighterPrivateKey, cfg.Testnet)
-		extendedEx := exchange.NewExtended(cfg.ExtendedAPIKey, cfg.ExtendedPrivateKey, cfg.ExtendedPublicKey, cfg.ExtendedVaultID, cfg.Testnet)
+		// Optionally serve Prometheus metrics, timing the exchanges' REST requests below their
+		// rate limiters
+		var botMetrics *metrics.Metrics
+		var wrap venues.TransportWrapper
+		if cfg.MetricsAddr != "" {
+			botMetrics = metrics.New()
+			wrap = botMetrics.Transport
+		}
+		exchanges, err := venues.FromConfigWrapped(cfg, wrap)
+		if err != nil {
+			log.Fatalf("cannot create exchanges: %v", err)
+		}
+		// Venues that can't submit orders would only ever fill one leg, so they are left out
+		// unless paper trading simulates their orders.
+		if !paper {
+			var readOnly []exchange.Exchange
+			exchanges, readOnly = venues.Tradable(exchanges)
+			for _, ex := range readOnly {
+				logger.Printf("%s can't submit orders yet and is not traded; use --paper to simulate it.", ex.Name())
+			}
+		}
+
+		if botMetrics != nil {
+			mux := http.NewServeMux()
+			mux.Handle("/metrics", botMetrics.Handler())
+			go func() {
+				logger.Printf("Serving metrics on %s/metrics", cfg.MetricsAddr)
+				if err := http.ListenAndServe(cfg.MetricsAddr, mux); err != nil {
+					logger.Printf("Metrics server stopped: %v", err)
+				}
+			}()
+		}
+
+		// Optionally fill orders virtually against live market data
+		var paperExchanges []*exchange.Paper
+		if paper {
+			paperCfg := exchange.PaperConfig{Balance: cfg.PaperBalanceUSD, SlippageBps: cfg.PaperSlippageBps, FeeBps: cfg.PaperFeeBps}
+			if paperCfg.Balance <= 0 {
+				paperCfg.Balance = defaultPaperBalance
+			}
+			logger.Printf("Paper trading enabled: %+v", paperCfg)
+			for i, ex := range exchanges {
+				paperEx := exchange.NewPaper(ex, paperCfg)
+				paperExchanges = append(paperExchanges, paperEx)
+				exchanges[i] = paperEx
+			}
+			// Virtual positions must not overwrite the state of a live instance.
+			cfg.StateFile = ""
+		}
+
+		// Check every exchange before trading, so bad credentials or a drifting clock stop the bot
+		// here rather than on the first order. Paper accounts are virtual and pass the credential check.
+		if !cfg.SkipSelfTest {
+			testCtx, cancelTest := context.WithTimeout(cmd.Context(), selfTestTimeout)
+			err := venues.SelfTest(testCtx, exchanges, time.Duration(cfg.MaxClockSkewMs)*time.Millisecond)
+			cancelTest()
+			if err != nil {
+				log.Fatalf("exchange self-test failed, fix the venues below or set SKIP_SELF_TEST=true:\n%v", err)
+			}
+			logger.Printf("Self-test passed for %d exchange(s).", len(exchanges))
+		}
+
+		var spotEx exchange.SpotExchange
+		switch cfg.SpotExchange {
+		case "":
+		case "binance":
+			spotEx = exchange.NewBinanceSpot(cfg.BinanceAPIKey, cfg.BinanceSecretKey, cfg.SpotQuoteAsset, cfg.Testnet)
+		default:
+			log.Fatalf("unknown spot exchange %q", cfg.SpotExchange)
+		}
+
+		if cfg.PrebuildOrders {
+			// The Extended clients may be behind paper trading or ACCOUNTS.
+			for _, ex := range exchanges {
+				for _, client := range exchange.Clients(ex) {
+					if extendedEx, ok := client.(*exchange.Extended); ok {
+						logger.Println("Pre-building Extended order templates...")
+						if err := extendedEx.PrepareOrderTemplates(cfg.Markets); err != nil {
+							logger.Printf("Could not pre-build order templates, orders will fetch market details on demand: %v", err)
+						}
+					}
+				}
+			}
+		}
+
+		// Trade assets some venues list under another ticker as one market
+		exchanges, err = venues.Rename(cfg, exchanges)
+		if err != nil {
+			log.Fatalf("cannot create exchanges: %v", err)
+		}
+
+		// Initialize the notifiers. Chat commands are only answered on Telegram.
+		telegram := notifications.NewTelegramNotifier(cfg.TelegramBotToken, cfg.TelegramChatID, logger)
+		slack := notifications.NewSlackNotifier(cfg.SlackBotToken, cfg.SlackChannel, cfg.SlackWebhookURL, logger)
+		webhook, err := notifications.NewWebhookNotifier(cfg.WebhookURL, cfg.WebhookSecret, cfg.WebhookEvents, logger)
+		if err != nil {
+			log.Fatalf("cannot load config: %v", err)
+		}
+		notifier := notifications.Multi{telegram, slack, webhook}
+
+		// Decide what happens to open positions on shutdown
+		shutdownPolicy, err := strategy.ParseShutdownPolicy(cfg.ShutdownPolicy)
+		if err != nil {
+			log.Fatalf("cannot load config: %v", err)
+		}
+		if shutdownPolicy == strategy.ShutdownAskTelegram && telegram == nil {
+			logger.Println("Ignoring SHUTDOWN_POLICY ask-telegram: Telegram is not configured, positions are preserved on shutdown.")
+			shutdownPolicy = strategy.ShutdownPreserve
+		}
+		cfg.ShutdownPolicy = string(shutdownPolicy)
+
+		// Optionally report would-be orders instead of sending them
+		if dryRun {
+			logger.Println("Dry run enabled: orders are logged and notified but never sent.")
+			for i, ex := range exchanges {
+				name := ex.Name()
+				exchanges[i] = exchange.NewDryRun(ex, func(order exchange.Order) {
+					message := fmt.Sprintf("DRY RUN: would %s %s %s on %s with a %s order at %s", order.Side, order.Amount, order.Market, name, order.Type, order.Price)
+					logger.Println(message)
+					notifier.SendMessage("🧪 " + message)
+				})
+			}
+			// Would-be positions must not overwrite the state or the journal of a live instance.
+			cfg.StateFile = ""
+			cfg.JournalFile = ""
+		}
+
+		// Optionally export closed positions to Google Sheets
+		sheets, err := export.NewSheetsExporter(cfg.GoogleSheetsCredentialsFile, cfg.GoogleSheetsSpreadsheetID, logger)
+		if err != nil {
+			logger.Printf("Could not initialize Google Sheets exporter, export disabled: %v", err)
+		}
+		defer sheets.Close()
+
+		// Optionally record fills to the trade journal
+		tradeJournal, err := journal.Open(cfg.JournalFile)
+		if err != nil {
+			logger.Printf("Could not open trade journal, journaling disabled: %v", err)
+		}
+		defer tradeJournal.Close()
+
+		// Optionally inject faults for resilience testing (testnet only)
+		chaosCfg := exchange.ChaosConfig{
+			Latency:         time.Duration(cfg.ChaosLatencyMs) * time.Millisecond,
+			Jitter:          time.Duration(cfg.ChaosJitterMs) * time.Millisecond,
+			ErrorRate:       cfg.ChaosErrorRate,
+			TimeoutRate:     cfg.ChaosTimeoutRate,
+			PartialFillRate: cfg.ChaosPartialFillRate,
+			Seed:            cfg.ChaosSeed,
+		}
+		if chaosCfg.Enabled() {
+			if !cfg.Testnet {
+				log.Fatalf("CHAOS_* settings are only allowed with TESTNET=true")
+			}
+			logger.Printf("Chaos mode enabled: %+v", chaosCfg)
+			for i, ex := range exchanges {
+				exchanges[i] = exchange.NewChaos(ex, chaosCfg)
+			}
+		}
+
+		// Balance requests made outside the strategies are cancelled on shutdown as well
+		ctx, cancel := context.WithCancel(cmd.Context())
+		defer cancel()
+
+		// Share capital between strategies running in this process
+		capitalManager, err := newCapitalManager(ctx, cfg, exchanges)
+		if err != nil {
+			log.Fatalf("cannot create capital manager: %v", err)
+		}
 
-		// Initialize Telegram notifier
-		notifier := notifications.NewTelegramNotifier(cfg.TelegramBotToken, cfg.TelegramChatID, logger)
+		// Create the strategies, reading rates and prices through one cache with the status API
+		marketData := strategy.NewMarketData(cfg)
+		var runners []strategy.Runner
+		for _, name := range strings.Split(cfg.Strategy, ",") {
+			runner, err := strategy.New(strings.TrimSpace(name), strategy.Dependencies{
+				Config:     cfg,
+				Exchanges:  exchanges,
+				Spot:       spotEx,
+				Logger:     logger,
+				Notifier:   notifier,
+				Sheets:     sheets,
+				Journal:    tradeJournal,
+				Capital:    capitalManager,
+				State:      state.Open(cfg.StateFile),
+				Metrics:    botMetrics,
+				MarketData: marketData,
+			})
+			if err != nil {
+				log.Fatalf("cannot create strategy: %v", err)
+			}
+			runners = append(runners, runner)
+		}
 
-		// Create the strategy
-		arbStrategy := strategy.NewFundingRateArb(cfg, lighterEx, extendedEx, logger, notifier)
+		// Optionally serve the bot's state to monitoring
+		if cfg.APIAddr != "" {
+			serveAPI(cfg, exchanges, runners, marketData, logger)
+		}
 
 		// Handle graceful shutdown
 		stop := make(chan struct{})
 		osSignal := make(chan os.Signal, 1)
 		signal.Notify(osSignal, syscall.SIGINT, syscall.SIGTERM)
 
+		shutdownTimeout := time.Duration(cfg.ShutdownTimeoutSeconds) * time.Second
+		if shutdownTimeout <= 0 {
+			shutdownTimeout = defaultShutdownTimeout
+		}
+		// The operator's answer is awaited on top of the time it takes to act on it.
+		askTelegram := shutdownPolicy == strategy.ShutdownAskTelegram
+		if askTelegram {
+			shutdownTimeout += strategy.ShutdownAskTimeout(cfg.ShutdownAskTimeoutSeconds)
+		}
+
 		go func() {
-			<-osSignal
-			logger.Println("Interrupt signal received. Shutting down gracefully...")
-			notifier.Stop()
+			sig := <-osSignal
+			logger.Printf("%s received. Shutting down gracefully (deadline %s)...", sig, shutdownTimeout)
+			// Keep answering chat commands while the operator is asked about the open positions.
+			if !askTelegram {
+				telegram.Stop()
+			}
 			close(stop)
+			cancel()
+
+			// Bound the shutdown so orchestrators don't have to escalate to SIGKILL.
+			select {
+			case <-osSignal:
+				logger.Println("Second signal received, exiting immediately.")
+			case <-time.After(shutdownTimeout):
+				logger.Println("Shutdown deadline exceeded, exiting.")
+			}
+			lock.Release()
+			os.Exit(1)
 		}()
 
-		// Start the notifier's poller
-		notifier.Start()
+		// Answer chat commands, then start the notifier's poller
+		registerCommands(telegram, runners)
+		telegram.Start()
 
-		// Run the strategy
-		arbStrategy.Run(stop)
+		// Run the strategies until they have all stopped
+		var wg sync.WaitGroup
+		for _, runner := range runners {
+			wg.Add(1)
+			go func(runner strategy.Runner) {
+				defer wg.Done()
+				runner.Run(stop)
+			}(runner)
+		}
+		wg.Wait()
+		if askTelegram {
+			telegram.Stop()
+		}
 
+		for _, paperEx := range paperExchanges {
+			summary := paperEx.Summary()
+			logger.Printf("Paper account on %s: equity %.2f USD (started %.2f), realized PnL %.2f, unrealized PnL %.2f, funding %.2f, fees %.2f over %d fills.",
+				summary.Exchange, summary.Equity, summary.StartBalance, summary.RealizedPnL, summary.UnrealizedPnL, summary.Funding, summary.Fees, summary.Fills)
+		}
 		logger.Println("Bot has been shut down.")
 	},
 }
 
+// newCapitalManager returns the capital manager shared by all strategies, or nil when no
+// STRATEGY_BUDGETS are configured. Account margin is the USD value of the collateral on every
+// exchange whose balance can be read.
+func newCapitalManager(ctx context.Context, cfg config.Config, exchanges []exchange.Exchange) (*capital.Manager, error) {
+	budgets, err := capital.ParseBudgets(cfg.StrategyBudgets)
+	if err != nil || len(budgets) == 0 {
+		return nil, err
+	}
+	prices, err := collateral.ParseStaticPrices(cfg.CollateralPrices)
+	if err != nil {
+		return nil, err
+	}
+	converter := collateral.NewConverter(prices)
+	margin := func() (float64, error) {
+		total, known := 0.0, 0
+		var lastErr error
+		for _, ex := range exchanges {
+			balance, err := converter.BalanceUSD(ctx, ex)
+			if err != nil {
+				lastErr = err
+				continue
+			}
+			total += balance
+			known++
+		}
+		if known == 0 {
+			return 0, lastErr
+		}
+		return total, nil
+	}
+	return capital.NewManager(budgets, cfg.Leverage, margin), nil
+}
+
+// serveAPI serves the positions and PnL of the strategies that report them on API_ADDR, with
+// funding rates read through cache, the market data cache the strategies share.
+func serveAPI(cfg config.Config, exchanges []exchange.Exchange, runners []strategy.Runner, cache *marketdata.Cache, logger *log.Logger) {
+	var sources []api.Source
+	for _, runner := range runners {
+		if source, ok := runner.(api.Source); ok {
+			sources = append(sources, source)
+		}
+	}
+	server := api.New(sources, rates.NewAggregator(exchanges, cfg.Markets, cache), cache, cfg.Settings())
+	go func() {
+		logger.Printf("Serving the status API on %s", cfg.APIAddr)
+		httpServer := &http.Server{Addr: cfg.APIAddr, Handler: server.Handler(), ReadHeaderTimeout: 10 * time.Second}
+		if err := httpServer.ListenAndServe(); err != nil {
+			logger.Printf("Status API server stopped: %v", err)
+		}
+	}()
+}
+
 func init() {
 	TradeCmd.Flags().StringVar(&configPath, "path", ".", "Path to the directory containing the .env file")
+	TradeCmd.Flags().BoolVar(&paper, "paper", false, "Fill orders virtually against live market data instead of trading")
+	TradeCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Log and notify every order the strategy decides on, against the real balances, without sending any")
 }
@@ -0,0 +1,87 @@
+package watch
+
+import (
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/config"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/marketdata"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/notifications"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/rates"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/venues"
+)
+
+var (
+	configPath    string
+	minAnnualDiff float64
+	interval      time.Duration
+	repeat        time.Duration
+	once          bool
+)
+
+// WatchCmd represents the watch command
+var WatchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Alerts on wide funding spreads without trading.",
+	Long: `Polls the funding rates of every exchange in EXCHANGES on MARKETS and notifies the
+configured Telegram, Slack and webhook targets when the widest annualized spread of a market
+reaches --min-annual-diff, with the venue to short, the venue to long, the annualized rate of each
+and the funding a POSITION_SIZE_USD position would earn a day. A market is alerted again when its
+best venue pair changes, every --repeat while it stays wide, and when it crosses the threshold
+again. Nothing is traded, for operators who execute by hand.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if minAnnualDiff <= 0 {
+			log.Fatalf("--min-annual-diff must be positive, e.g. 0.2 for 20%% a year")
+		}
+		cfg, err := config.LoadConfig(configPath)
+		if err != nil {
+			log.Fatalf("cannot load config: %v", err)
+		}
+		exchanges, err := venues.FromConfig(cfg)
+		if err == nil {
+			exchanges, err = venues.Rename(cfg, exchanges)
+		}
+		if err != nil {
+			log.Fatalf("cannot create exchanges: %v", err)
+		}
+		logger := log.New(os.Stdout, "[WATCH] ", log.LstdFlags)
+
+		telegram := notifications.NewTelegramNotifier(cfg.TelegramBotToken, cfg.TelegramChatID, logger)
+		slack := notifications.NewSlackNotifier(cfg.SlackBotToken, cfg.SlackChannel, cfg.SlackWebhookURL, logger)
+		webhook, err := notifications.NewWebhookNotifier(cfg.WebhookURL, cfg.WebhookSecret, cfg.WebhookEvents, logger)
+		if err != nil {
+			log.Fatalf("cannot load config: %v", err)
+		}
+
+		cache := marketdata.NewCache(time.Duration(cfg.MarketDataTTLSeconds) * time.Second)
+		watcher := &rates.Watcher{
+			Aggregator:    rates.NewAggregator(exchanges, cfg.Markets, cache),
+			Notifier:      notifications.Multi{telegram, slack, webhook},
+			MinAnnualDiff: minAnnualDiff,
+			Repeat:        repeat,
+			SizeUSD:       cfg.PositionSizeUSD,
+			Logger:        logger,
+		}
+		if once {
+			logger.Printf("Alerted %d spreads.", watcher.Check(cmd.Context(), time.Now()))
+			return
+		}
+		ctx, stop := signal.NotifyContext(cmd.Context(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+		logger.Printf("Watching for funding spreads of %.2f%% APR or more every %s.", minAnnualDiff*100, interval)
+		watcher.Run(ctx, interval)
+	},
+}
+
+func init() {
+	WatchCmd.Flags().StringVar(&configPath, "path", ".", "Path to the directory containing the .env file")
+	WatchCmd.Flags().Float64Var(&minAnnualDiff, "min-annual-diff", 0.2, "Annualized spread to alert on, e.g. 0.2 for 20% a year")
+	WatchCmd.Flags().DurationVar(&interval, "interval", 5*time.Minute, "How often to poll the exchanges")
+	WatchCmd.Flags().DurationVar(&repeat, "repeat", 4*time.Hour, "How often to alert again on a market that stays wide (0 alerts once per crossing)")
+	WatchCmd.Flags().BoolVar(&once, "once", false, "Check once and exit, e.g. from cron")
+}
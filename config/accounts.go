@@ -0,0 +1,182 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// DefaultAccount names the account configured by an exchange's own settings, e.g. EXTENDED_API_KEY.
+const DefaultAccount = "default"
+
+// Account is an account or subaccount on an exchange, listed in ACCOUNTS. Its settings are the
+// exchange's settings with the account name after the exchange's, e.g. EXTENDED_VAULT2_VAULT_ID
+// for the account vault2 on Extended, and default to the exchange's own.
+type Account struct {
+	// Exchange is lower-cased and Name as listed.
+	Exchange string
+	Name     string
+	// Markets are traded on this account only; none means any market not assigned to another.
+	Markets []string
+}
+
+// ParseAccounts parses ACCOUNTS entries of the form "EXCHANGE:ACCOUNT[=MARKET|MARKET...]", e.g.
+// "extended:vault2=BTC-USD|ETH-USD". Account names are letters and digits, so their settings can
+// be environment variables. Listing the default account only assigns it markets.
+func ParseAccounts(entries []string) ([]Account, error) {
+	var accounts []Account
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		target, markets, _ := strings.Cut(entry, "=")
+		exchangeName, name, ok := strings.Cut(strings.TrimSpace(target), ":")
+		exchangeName, name = strings.ToLower(strings.TrimSpace(exchangeName)), strings.TrimSpace(name)
+		if !ok || exchangeName == "" || !isAccountName(name) {
+			return nil, fmt.Errorf("invalid ACCOUNTS entry %q, expected EXCHANGE:ACCOUNT[=MARKET|MARKET], the account name in letters and digits", entry)
+		}
+		key := exchangeName + ":" + strings.ToLower(name)
+		if seen[key] {
+			return nil, fmt.Errorf("ACCOUNTS: %s is listed twice", key)
+		}
+		seen[key] = true
+		account := Account{Exchange: exchangeName, Name: name}
+		for _, market := range strings.Split(markets, "|") {
+			if market = strings.ToUpper(strings.TrimSpace(market)); market != "" {
+				account.Markets = append(account.Markets, market)
+			}
+		}
+		accounts = append(accounts, account)
+	}
+	return accounts, nil
+}
+
+func isAccountName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// IsDefault reports whether a is the account configured by the exchange's own settings.
+func (a Account) IsDefault() bool {
+	return strings.EqualFold(a.Name, DefaultAccount)
+}
+
+// setting returns the name of the account's setting overriding the exchange's setting, or ""
+// if setting doesn't belong to the exchange.
+func (a Account) setting(setting string) string {
+	prefix := strings.ToUpper(a.Exchange) + "_"
+	if a.IsDefault() || !strings.HasPrefix(setting, prefix) {
+		return ""
+	}
+	return prefix + strings.ToUpper(a.Name) + "_" + strings.TrimPrefix(setting, prefix)
+}
+
+// readAccountSettings reads the settings of the accounts in ACCOUNTS from v into AccountSettings.
+// They are read from .env, the environment or the secrets backend; the structured file has no
+// keys for them.
+func (c *Config) readAccountSettings(v *viper.Viper) error {
+	accounts, err := ParseAccounts(c.Accounts)
+	if err != nil {
+		return err
+	}
+	t := reflect.TypeOf(*c)
+	for _, account := range accounts {
+		for i := 0; i < t.NumField(); i++ {
+			key := account.setting(t.Field(i).Tag.Get("mapstructure"))
+			if key == "" {
+				continue
+			}
+			if err := v.BindEnv(key); err != nil {
+				return err
+			}
+			if v.IsSet(key) {
+				if c.AccountSettings == nil {
+					c.AccountSettings = make(map[string]string)
+				}
+				c.AccountSettings[key] = v.GetString(key)
+			}
+		}
+	}
+	return nil
+}
+
+// isAccountSetting reports whether setting is one of the settings of an account in ACCOUNTS.
+func (c Config) isAccountSetting(setting string) bool {
+	accounts, err := ParseAccounts(c.Accounts)
+	if err != nil {
+		return false
+	}
+	t := reflect.TypeOf(c)
+	for _, account := range accounts {
+		for i := 0; i < t.NumField(); i++ {
+			if key := account.setting(t.Field(i).Tag.Get("mapstructure")); key != "" && key == setting {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ForAccount returns the configuration the client of account is created with: the exchange's
+// settings overridden by the account's.
+func (c Config) ForAccount(account Account) (Config, error) {
+	v, t := reflect.ValueOf(&c).Elem(), reflect.TypeOf(c)
+	for i := 0; i < t.NumField(); i++ {
+		key := account.setting(t.Field(i).Tag.Get("mapstructure"))
+		value, ok := c.AccountSettings[key]
+		if key == "" || !ok {
+			continue
+		}
+		if err := setValue(v.Field(i), value); err != nil {
+			return Config{}, fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	return c, nil
+}
+
+// setValue sets a setting field from its text.
+func setValue(field reflect.Value, value string) error {
+	value = strings.TrimSpace(value)
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("expected true or false, got %q", value)
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("expected a whole number, got %q", value)
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("expected a whole number, got %q", value)
+		}
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("expected a number, got %q", value)
+		}
+		field.SetFloat(f)
+	default:
+		return fmt.Errorf("can't be set per account")
+	}
+	return nil
+}
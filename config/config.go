@@ -1,53 +1,245 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
+	"path/filepath"
 	"strings"
 
 	"github.com/spf13/viper"
 )
 
 // Config stores all configuration for the application.
-// The values are read by viper from a config file or environment variables.
+// The values are read by viper from config files or environment variables. In the structured
+// config file each setting is the lower-cased key in the section named by its section tag, e.g.
+// MARKETS is strategy.markets.
 type Config struct {
-	LighterAPIKey      string   `mapstructure:"LIGHTER_API_KEY"`
-	LighterPrivateKey  string   `mapstructure:"LIGHTER_PRIVATE_KEY"`
-	ExtendedAPIKey     string   `mapstructure:"EXTENDED_API_KEY"`
-	ExtendedPrivateKey string   `mapstructure:"EXTENDED_PRIVATE_KEY"`
-	ExtendedPublicKey  string   `mapstructure:"EXTENDED_PUBLIC_KEY"`
-	ExtendedVaultID    int      `mapstructure:"EXTENDED_VAULT_ID"`
-	Testnet            bool     `mapstructure:"TESTNET"`
-	Markets            []string `mapstructure:"MARKETS"`
-	MinFundingRateDiff float64  `mapstructure:"MIN_FUNDING_RATE_DIFF"`
-	PositionSizeUSD    float64  `mapstructure:"POSITION_SIZE_USD"`
-	MaxPositionUSD     float64  `mapstructure:"MAX_POSITION_USD"`
-	TelegramBotToken   string   `mapstructure:"TELEGRAM_BOT_TOKEN"`
-	TelegramChatID     int64    `mapstructure:"TELEGRAM_CHAT_ID"`
+	LighterAPIKey               string   `mapstructure:"LIGHTER_API_KEY" section:"exchanges"`
+	LighterPrivateKey           string   `mapstructure:"LIGHTER_PRIVATE_KEY" section:"exchanges"`
+	LighterAccountIndex         int64    `mapstructure:"LIGHTER_ACCOUNT_INDEX" section:"exchanges"`
+	LighterAPIKeyIndex          uint8    `mapstructure:"LIGHTER_API_KEY_INDEX" section:"exchanges"`
+	LighterSignerCmd            string   `mapstructure:"LIGHTER_SIGNER_CMD" section:"exchanges"`
+	ExtendedAPIKey              string   `mapstructure:"EXTENDED_API_KEY" section:"exchanges"`
+	ExtendedPrivateKey          string   `mapstructure:"EXTENDED_PRIVATE_KEY" section:"exchanges"`
+	ExtendedPublicKey           string   `mapstructure:"EXTENDED_PUBLIC_KEY" section:"exchanges"`
+	ExtendedVaultID             int      `mapstructure:"EXTENDED_VAULT_ID" section:"exchanges"`
+	DydxAddress                 string   `mapstructure:"DYDX_ADDRESS" section:"exchanges"`
+	DydxMnemonic                string   `mapstructure:"DYDX_MNEMONIC" section:"exchanges"`
+	DydxSubaccount              int      `mapstructure:"DYDX_SUBACCOUNT" section:"exchanges"`
+	DydxSignerCmd               string   `mapstructure:"DYDX_SIGNER_CMD" section:"exchanges"`
+	DydxValidatorURL            string   `mapstructure:"DYDX_VALIDATOR_URL" section:"exchanges"`
+	BybitAPIKey                 string   `mapstructure:"BYBIT_API_KEY" section:"exchanges"`
+	BybitSecretKey              string   `mapstructure:"BYBIT_SECRET_KEY" section:"exchanges"`
+	AsterAPIKey                 string   `mapstructure:"ASTER_API_KEY" section:"exchanges"`
+	AsterSecretKey              string   `mapstructure:"ASTER_SECRET_KEY" section:"exchanges"`
+	ParadexAccount              string   `mapstructure:"PARADEX_ACCOUNT" section:"exchanges"`
+	ParadexPrivateKey           string   `mapstructure:"PARADEX_PRIVATE_KEY" section:"exchanges"`
+	ParadexHashCmd              string   `mapstructure:"PARADEX_HASH_CMD" section:"exchanges"`
+	DriftGatewayURL             string   `mapstructure:"DRIFT_GATEWAY_URL" section:"exchanges"`
+	DriftSubaccount             int      `mapstructure:"DRIFT_SUBACCOUNT" section:"exchanges"`
+	ApexAPIKey                  string   `mapstructure:"APEX_API_KEY" section:"exchanges"`
+	ApexSecretKey               string   `mapstructure:"APEX_SECRET_KEY" section:"exchanges"`
+	ApexPassphrase              string   `mapstructure:"APEX_PASSPHRASE" section:"exchanges"`
+	ApexZKSeeds                 string   `mapstructure:"APEX_ZK_SEEDS" section:"exchanges"`
+	ApexSignerCmd               string   `mapstructure:"APEX_SIGNER_CMD" section:"exchanges"`
+	AevoAccount                 string   `mapstructure:"AEVO_ACCOUNT" section:"exchanges"`
+	AevoAPIKey                  string   `mapstructure:"AEVO_API_KEY" section:"exchanges"`
+	AevoSecretKey               string   `mapstructure:"AEVO_SECRET_KEY" section:"exchanges"`
+	AevoSigningKey              string   `mapstructure:"AEVO_SIGNING_KEY" section:"exchanges"`
+	AevoSignerCmd               string   `mapstructure:"AEVO_SIGNER_CMD" section:"exchanges"`
+	OrderlyAccountID            string   `mapstructure:"ORDERLY_ACCOUNT_ID" section:"exchanges"`
+	OrderlySecretKey            string   `mapstructure:"ORDERLY_SECRET_KEY" section:"exchanges"`
+	OKXAPIKey                   string   `mapstructure:"OKX_API_KEY" section:"exchanges"`
+	OKXSecretKey                string   `mapstructure:"OKX_SECRET_KEY" section:"exchanges"`
+	OKXPassphrase               string   `mapstructure:"OKX_PASSPHRASE" section:"exchanges"`
+	GmxAccount                  string   `mapstructure:"GMX_ACCOUNT" section:"exchanges"`
+	GmxPrivateKey               string   `mapstructure:"GMX_PRIVATE_KEY" section:"exchanges"`
+	GmxSignerCmd                string   `mapstructure:"GMX_SIGNER_CMD" section:"exchanges"`
+	GmxRPCURL                   string   `mapstructure:"GMX_RPC_URL" section:"exchanges"`
+	VenueDescriptors            []string `mapstructure:"VENUE_DESCRIPTORS" section:"exchanges"`
+	RemoteExchanges             []string `mapstructure:"REMOTE_EXCHANGES" section:"exchanges"`
+	Exchanges                   []string `mapstructure:"EXCHANGES" section:"exchanges"`
+	Accounts                    []string `mapstructure:"ACCOUNTS" section:"exchanges"`
+	RateLimits                  []string `mapstructure:"RATE_LIMITS" section:"exchanges"`
+	SymbolMap                   []string `mapstructure:"SYMBOL_MAP" section:"exchanges"`
+	Strategy                    string   `mapstructure:"STRATEGY" section:"strategy"`
+	StrategyBudgets             []string `mapstructure:"STRATEGY_BUDGETS" section:"strategy"`
+	Testnet                     bool     `mapstructure:"TESTNET" section:"exchanges"`
+	Markets                     []string `mapstructure:"MARKETS" section:"strategy"`
+	MinFundingRateDiff          float64  `mapstructure:"MIN_FUNDING_RATE_DIFF" section:"strategy"`
+	PositionSizeUSD             float64  `mapstructure:"POSITION_SIZE_USD" section:"strategy"`
+	MaxPositionUSD              float64  `mapstructure:"MAX_POSITION_USD" section:"risk"`
+	MinVolume24hUSD             float64  `mapstructure:"MIN_VOLUME_24H_USD" section:"risk"`
+	MinOpenInterestUSD          float64  `mapstructure:"MIN_OPEN_INTEREST_USD" section:"risk"`
+	Oracle                      string   `mapstructure:"ORACLE" section:"risk"`
+	OracleMaxDeviation          float64  `mapstructure:"ORACLE_MAX_DEVIATION" section:"risk"`
+	OracleFeeds                 []string `mapstructure:"ORACLE_FEEDS" section:"risk"`
+	MaxEntryImpactBps           float64  `mapstructure:"MAX_ENTRY_IMPACT_BPS" section:"risk"`
+	MakerEntry                  []string `mapstructure:"MAKER_ENTRY" section:"strategy"`
+	EntryWindowMinutes          float64  `mapstructure:"ENTRY_WINDOW_MINUTES" section:"strategy"`
+	ExitAfterFunding            bool     `mapstructure:"EXIT_AFTER_FUNDING" section:"strategy"`
+	LiquidationAlertDistance    float64  `mapstructure:"LIQUIDATION_ALERT_DISTANCE" section:"risk"`
+	DeleverageTargetDistance    float64  `mapstructure:"DELEVERAGE_TARGET_DISTANCE" section:"risk"`
+	TakeProfitMultiple          float64  `mapstructure:"TAKE_PROFIT_MULTIPLE" section:"strategy"`
+	TakerFeeBps                 float64  `mapstructure:"TAKER_FEE_BPS" section:"strategy"`
+	UsePredictedRates           bool     `mapstructure:"USE_PREDICTED_RATES" section:"strategy"`
+	ExecutionCosts              []string `mapstructure:"EXECUTION_COSTS" section:"strategy"`
+	EntryHorizonHours           float64  `mapstructure:"ENTRY_HORIZON_HOURS" section:"strategy"`
+	EntryCostMargin             float64  `mapstructure:"ENTRY_COST_MARGIN" section:"strategy"`
+	ExitMinAnnualDiff           float64  `mapstructure:"EXIT_MIN_ANNUAL_DIFF" section:"strategy"`
+	ExitHysteresis              float64  `mapstructure:"EXIT_HYSTERESIS" section:"strategy"`
+	MinHoldMinutes              float64  `mapstructure:"MIN_HOLD_MINUTES" section:"strategy"`
+	ReentryCooldownMinutes      float64  `mapstructure:"REENTRY_COOLDOWN_MINUTES" section:"strategy"`
+	MarketBlacklist             []string `mapstructure:"MARKET_BLACKLIST" section:"strategy"`
+	MarketBlackouts             []string `mapstructure:"MARKET_BLACKOUTS" section:"strategy"`
+	MaxDrawdownUSD              float64  `mapstructure:"MAX_DRAWDOWN_USD" section:"risk"`
+	MaxNetDeltaUSD              float64  `mapstructure:"MAX_NET_DELTA_USD" section:"risk"`
+	KillSwitchUnwind            bool     `mapstructure:"KILL_SWITCH_UNWIND" section:"risk"`
+	NetDeltaAlertUSD            float64  `mapstructure:"NET_DELTA_ALERT_USD" section:"risk"`
+	NetDeltaRebalance           bool     `mapstructure:"NET_DELTA_REBALANCE" section:"risk"`
+	BasisStopUSD                float64  `mapstructure:"BASIS_STOP_USD" section:"risk"`
+	TelegramBotToken            string   `mapstructure:"TELEGRAM_BOT_TOKEN" section:"notifications"`
+	TelegramChatID              int64    `mapstructure:"TELEGRAM_CHAT_ID" section:"notifications"`
+	SlackBotToken               string   `mapstructure:"SLACK_BOT_TOKEN" section:"notifications"`
+	SlackChannel                string   `mapstructure:"SLACK_CHANNEL" section:"notifications"`
+	SlackWebhookURL             string   `mapstructure:"SLACK_WEBHOOK_URL" section:"notifications"`
+	WebhookURL                  string   `mapstructure:"WEBHOOK_URL" section:"notifications"`
+	WebhookSecret               string   `mapstructure:"WEBHOOK_SECRET" section:"notifications"`
+	WebhookEvents               []string `mapstructure:"WEBHOOK_EVENTS" section:"notifications"`
+	MarketDataTTLSeconds        int      `mapstructure:"MARKET_DATA_TTL_SECONDS" section:"exchanges"`
+	MarketInfoTTLMinutes        float64  `mapstructure:"MARKET_INFO_TTL_MINUTES" section:"exchanges"`
+	FundingFetchTimeoutSeconds  float64  `mapstructure:"FUNDING_FETCH_TIMEOUT_SECONDS" section:"exchanges"`
+	PrebuildOrders              bool     `mapstructure:"PREBUILD_ORDERS" section:"exchanges"`
+	LockDir                     string   `mapstructure:"LOCK_DIR" section:"runtime"`
+	LogLevel                    string   `mapstructure:"LOG_LEVEL" section:"runtime"`
+	LogFormat                   string   `mapstructure:"LOG_FORMAT" section:"runtime"`
+	ShutdownTimeoutSeconds      int      `mapstructure:"SHUTDOWN_TIMEOUT_SECONDS" section:"runtime"`
+	ShutdownPolicy              string   `mapstructure:"SHUTDOWN_POLICY" section:"runtime"`
+	ShutdownAskTimeoutSeconds   float64  `mapstructure:"SHUTDOWN_ASK_TIMEOUT_SECONDS" section:"runtime"`
+	SkipSelfTest                bool     `mapstructure:"SKIP_SELF_TEST" section:"runtime"`
+	MaxClockSkewMs              int      `mapstructure:"MAX_CLOCK_SKEW_MS" section:"runtime"`
+	RollbackAttempts            int      `mapstructure:"ROLLBACK_ATTEMPTS" section:"risk"`
+	RollbackRetryDelayMs        int      `mapstructure:"ROLLBACK_RETRY_DELAY_MS" section:"risk"`
+	HedgeToleranceUSD           float64  `mapstructure:"HEDGE_TOLERANCE_USD" section:"risk"`
+	HedgeRepairAttempts         int      `mapstructure:"HEDGE_REPAIR_ATTEMPTS" section:"risk"`
+	CircuitBreakerFailures      int      `mapstructure:"CIRCUIT_BREAKER_FAILURES" section:"risk"`
+	CircuitBreakerProbeSeconds  int      `mapstructure:"CIRCUIT_BREAKER_PROBE_SECONDS" section:"risk"`
+	GoogleSheetsCredentialsFile string   `mapstructure:"GOOGLE_SHEETS_CREDENTIALS_FILE" section:"notifications"`
+	GoogleSheetsSpreadsheetID   string   `mapstructure:"GOOGLE_SHEETS_SPREADSHEET_ID" section:"notifications"`
+	JournalFile                 string   `mapstructure:"JOURNAL_FILE" section:"runtime"`
+	StateFile                   string   `mapstructure:"STATE_FILE" section:"runtime"`
+	ReconcileRepair             bool     `mapstructure:"RECONCILE_REPAIR" section:"risk"`
+	PaperBalanceUSD             float64  `mapstructure:"PAPER_BALANCE_USD" section:"exchanges"`
+	PaperSlippageBps            float64  `mapstructure:"PAPER_SLIPPAGE_BPS" section:"exchanges"`
+	PaperFeeBps                 float64  `mapstructure:"PAPER_FEE_BPS" section:"exchanges"`
+	Streaming                   bool     `mapstructure:"STREAMING" section:"exchanges"`
+	CheckIntervalSeconds        float64  `mapstructure:"CHECK_INTERVAL_SECONDS" section:"strategy"`
+	CheckJitterSeconds          float64  `mapstructure:"CHECK_JITTER_SECONDS" section:"strategy"`
+	CheckOnStart                bool     `mapstructure:"CHECK_ON_START" section:"strategy"`
+	MetricsAddr                 string   `mapstructure:"METRICS_ADDR" section:"notifications"`
+	APIAddr                     string   `mapstructure:"API_ADDR" section:"notifications"`
+	ExecutionReportHours        float64  `mapstructure:"EXECUTION_REPORT_HOURS" section:"notifications"`
+	PnlReportHours              float64  `mapstructure:"PNL_REPORT_HOURS" section:"notifications"`
+	DailySummaryCron            string   `mapstructure:"DAILY_SUMMARY_CRON" section:"notifications"`
+	FundingSchedules            []string `mapstructure:"FUNDING_SCHEDULES" section:"strategy"`
+	AdaptiveThreshold           bool     `mapstructure:"ADAPTIVE_THRESHOLD" section:"strategy"`
+	AdaptiveThresholdFloor      float64  `mapstructure:"ADAPTIVE_THRESHOLD_FLOOR" section:"strategy"`
+	AdaptiveThresholdCeiling    float64  `mapstructure:"ADAPTIVE_THRESHOLD_CEILING" section:"strategy"`
+	AdaptiveThresholdWindow     int      `mapstructure:"ADAPTIVE_THRESHOLD_WINDOW" section:"strategy"`
+	ChaosLatencyMs              int      `mapstructure:"CHAOS_LATENCY_MS" section:"exchanges"`
+	ChaosJitterMs               int      `mapstructure:"CHAOS_JITTER_MS" section:"exchanges"`
+	ChaosErrorRate              float64  `mapstructure:"CHAOS_ERROR_RATE" section:"exchanges"`
+	ChaosTimeoutRate            float64  `mapstructure:"CHAOS_TIMEOUT_RATE" section:"exchanges"`
+	ChaosPartialFillRate        float64  `mapstructure:"CHAOS_PARTIAL_FILL_RATE" section:"exchanges"`
+	ChaosSeed                   int64    `mapstructure:"CHAOS_SEED" section:"exchanges"`
+	Leverage                    float64  `mapstructure:"LEVERAGE" section:"risk"`
+	MarginModes                 []string `mapstructure:"MARGIN_MODES" section:"risk"`
+	SetLeverage                 bool     `mapstructure:"SET_LEVERAGE" section:"risk"`
+	Leverages                   []string `mapstructure:"LEVERAGES" section:"risk"`
+	AllocatorEnabled            bool     `mapstructure:"ALLOCATOR_ENABLED" section:"strategy"`
+	PerMarketCapUSD             float64  `mapstructure:"PER_MARKET_CAP_USD" section:"strategy"`
+	MinPositionSizeUSD          float64  `mapstructure:"MIN_POSITION_SIZE_USD" section:"strategy"`
+	DynamicSizing               bool     `mapstructure:"DYNAMIC_SIZING" section:"strategy"`
+	SizingFullAnnualDiff        float64  `mapstructure:"SIZING_FULL_ANNUAL_DIFF" section:"strategy"`
+	SizingTargetVolatility      float64  `mapstructure:"SIZING_TARGET_VOLATILITY" section:"strategy"`
+	MarketCorrelations          []string `mapstructure:"MARKET_CORRELATIONS" section:"strategy"`
+	CorrelationPenalty          float64  `mapstructure:"CORRELATION_PENALTY" section:"strategy"`
+	RotationMinAnnualGain       float64  `mapstructure:"ROTATION_MIN_ANNUAL_GAIN" section:"strategy"`
+	RebalanceIntervalMinutes    float64  `mapstructure:"REBALANCE_INTERVAL_MINUTES" section:"strategy"`
+	RebalanceEdge               float64  `mapstructure:"REBALANCE_EDGE" section:"strategy"`
+	CollateralPrices            []string `mapstructure:"COLLATERAL_PRICES" section:"exchanges"`
+	SpotExchange                string   `mapstructure:"SPOT_EXCHANGE" section:"exchanges"`
+	SpotQuoteAsset              string   `mapstructure:"SPOT_QUOTE_ASSET" section:"exchanges"`
+	SpotHedgePerpExchange       string   `mapstructure:"SPOT_HEDGE_PERP_EXCHANGE" section:"strategy"`
+	BinanceAPIKey               string   `mapstructure:"BINANCE_API_KEY" section:"exchanges"`
+	BinanceSecretKey            string   `mapstructure:"BINANCE_SECRET_KEY" section:"exchanges"`
+	SecretsBackend              string   `mapstructure:"SECRETS_BACKEND" section:"secrets"`
+	VaultAddr                   string   `mapstructure:"VAULT_ADDR" section:"secrets"`
+	VaultToken                  string   `mapstructure:"VAULT_TOKEN" section:"secrets"`
+	VaultSecretPath             string   `mapstructure:"VAULT_SECRET_PATH" section:"secrets"`
+	AWSRegion                   string   `mapstructure:"AWS_REGION" section:"secrets"`
+	AWSSecretID                 string   `mapstructure:"AWS_SECRET_ID" section:"secrets"`
+	SecretsFile                 string   `mapstructure:"SECRETS_FILE" section:"secrets"`
+	SecretsAgeIdentity          string   `mapstructure:"SECRETS_AGE_IDENTITY" section:"secrets"`
+
+	// AccountSettings holds the settings of the accounts in ACCOUNTS, such as
+	// EXTENDED_VAULT2_API_KEY, keyed by name. See Account.
+	AccountSettings map[string]string `mapstructure:"-"`
 }
 
-// LoadConfig reads configuration from file or environment variables.
+// listKeys are the settings given as comma-separated lists.
+var listKeys = []string{"MARKETS", "MARKET_CORRELATIONS", "COLLATERAL_PRICES", "FUNDING_SCHEDULES", "STRATEGY_BUDGETS", "ORACLE_FEEDS", "MARGIN_MODES", "LEVERAGES", "EXCHANGES", "VENUE_DESCRIPTORS", "REMOTE_EXCHANGES", "MAKER_ENTRY", "WEBHOOK_EVENTS", "RATE_LIMITS", "SYMBOL_MAP", "EXECUTION_COSTS", "MARKET_BLACKLIST", "MARKET_BLACKOUTS", "ACCOUNTS"}
+
+// LoadConfig reads configuration from the .env file and the structured config file (config.yaml,
+// config.yml or config.json) in path, and from environment variables. Settings in the structured
+// file take precedence over the .env file, and environment variables over both. Either file may
+// be missing. Unknown keys in the structured file are an error. With SECRETS_BACKEND, the
+// credentials are then read from the secrets backend, which takes precedence over all of them.
 func LoadConfig(path string) (config Config, err error) {
-	viper.SetConfigFile(path + "/.env")
+	v := viper.New()
+	v.SetConfigFile(filepath.Join(path, ".env"))
 
-	viper.AutomaticEnv()
+	v.AutomaticEnv()
 
-	err = viper.ReadInConfig()
+	envMissing := false
+	err = v.ReadInConfig()
 	if err != nil {
-		if _, ok := err.(viper.ConfigFileNotFoundError); ok {
+		var notFound viper.ConfigFileNotFoundError
+		if errors.As(err, &notFound) || errors.Is(err, fs.ErrNotExist) {
 			// Config file not found; ignore error if desired
-			fmt.Println("config file not found, using environment variables")
+			envMissing = true
 		} else {
 			// Config file was found but another error was produced
 			return
 		}
 	}
 
+	settings, err := readStructured(path)
+	if err != nil {
+		return
+	}
+	if settings != nil {
+		if err = v.MergeConfigMap(settings); err != nil {
+			return
+		}
+	} else if envMissing {
+		fmt.Println("config file not found, using environment variables")
+	}
+
 	// Workaround for viper not splitting comma-separated strings from .env files
-	if viper.IsSet("MARKETS") {
-		markets := viper.GetString("MARKETS")
-		viper.Set("MARKETS", strings.Split(markets, ","))
+	for _, key := range listKeys {
+		if value, ok := v.Get(key).(string); ok {
+			v.Set(key, strings.Split(value, ","))
+		}
 	}
 
-	err = viper.Unmarshal(&config)
+	if err = v.Unmarshal(&config); err != nil {
+		return
+	}
+	if err = config.readAccountSettings(v); err != nil {
+		return
+	}
+	err = config.loadSecrets()
 	return
 }
@@ -0,0 +1,218 @@
+package config
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadStructuredConfig(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, ".env", "POSITION_SIZE_USD=100\nMIN_FUNDING_RATE_DIFF=0.0002\n")
+	writeFile(t, dir, "config.yaml", `
+exchanges:
+  exchanges: [lighter, dydx]
+strategy:
+  markets: BTC-USD,ETH-USD
+  position_size_usd: 250
+risk:
+  max_position_usd: 1000
+  margin_modes: [Lighter=isolated]
+`)
+	t.Setenv("MAX_POSITION_USD", "5000")
+
+	cfg, err := LoadConfig(dir)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if cfg.PositionSizeUSD != 250 {
+		t.Errorf("expected the config file to override .env, got %f", cfg.PositionSizeUSD)
+	}
+	if cfg.MinFundingRateDiff != 0.0002 {
+		t.Errorf("expected settings missing from the config file to come from .env, got %f", cfg.MinFundingRateDiff)
+	}
+	if cfg.MaxPositionUSD != 5000 {
+		t.Errorf("expected the environment to override the config file, got %f", cfg.MaxPositionUSD)
+	}
+	if len(cfg.Markets) != 2 || len(cfg.Exchanges) != 2 || cfg.MarginModes[0] != "Lighter=isolated" {
+		t.Errorf("expected lists as sequences or comma-separated strings, got %v, %v, %v", cfg.Markets, cfg.Exchanges, cfg.MarginModes)
+	}
+}
+
+func TestUnknownKeysAreReported(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "config.json", `{
+		"strategy": {"markets": ["BTC-USD"], "min_funding_rate": 0.001},
+		"risk": {"position_size_usd": 100},
+		"alerts": {"slack_webhook": "https://hooks.example.com"}
+	}`)
+
+	_, err := LoadConfig(dir)
+	if err == nil {
+		t.Fatal("expected unknown keys to be rejected")
+	}
+	for _, want := range []string{
+		`unknown section "alerts"`,
+		`"position_size_usd" belongs in the strategy section, not risk`,
+		`unknown key "min_funding_rate" in the strategy section (did you mean "min_funding_rate_diff"?)`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in the error, got:\n%v", want, err)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cfg := Config{Markets: []string{"BTC-USD"}, PositionSizeUSD: 600, MaxPositionUSD: 10000}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("expected a valid config, got %v", err)
+	}
+
+	cfg.MaxPositionUSD = 500
+	cfg.ChaosErrorRate = 2
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("expected problems to be reported")
+	}
+	for _, want := range []string{"MAX_POSITION_USD (risk.max_position_usd): 500 is below POSITION_SIZE_USD 600", "CHAOS_ERROR_RATE"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in the error, got:\n%v", want, err)
+		}
+	}
+}
+
+func TestSettingsRedactSecrets(t *testing.T) {
+	cfg := Config{BinanceAPIKey: "key", OKXPassphrase: "pass", TelegramBotToken: "token", LighterAPIKeyIndex: 3, Markets: []string{"BTC-USD"}}
+	settings := cfg.Settings()
+	for _, secret := range []string{"BINANCE_API_KEY", "OKX_PASSPHRASE", "TELEGRAM_BOT_TOKEN"} {
+		if settings[secret] != Redacted {
+			t.Errorf("%s = %v, want it redacted", secret, settings[secret])
+		}
+	}
+	if settings["BYBIT_API_KEY"] != "" {
+		t.Errorf("expected unset secrets to stay empty, got %v", settings["BYBIT_API_KEY"])
+	}
+	if settings["LIGHTER_API_KEY_INDEX"] != uint8(3) || len(settings["MARKETS"].([]string)) != 1 {
+		t.Errorf("expected other settings as they are, got %v and %v", settings["LIGHTER_API_KEY_INDEX"], settings["MARKETS"])
+	}
+}
+
+func TestSecretsFromVault(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/secret/data/arb-bot" || r.Header.Get("X-Vault-Token") != "vault-token" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+		w.Write([]byte(`{"data":{"data":{"EXTENDED_PRIVATE_KEY":"0xstark","binance_api_key":"key"},"metadata":{"version":3}}}`))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	writeFile(t, dir, ".env", "EXTENDED_PRIVATE_KEY=plaintext\nSECRETS_BACKEND=vault\nVAULT_ADDR="+server.URL+"\nVAULT_TOKEN=vault-token\nVAULT_SECRET_PATH=secret/data/arb-bot\n")
+	cfg, err := LoadConfig(dir)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if cfg.ExtendedPrivateKey != "0xstark" || cfg.BinanceAPIKey != "key" {
+		t.Errorf("expected the secrets from Vault to override .env, got %q and %q", cfg.ExtendedPrivateKey, cfg.BinanceAPIKey)
+	}
+	if cfg.Settings()["VAULT_TOKEN"] != Redacted {
+		t.Error("expected the Vault token to be redacted")
+	}
+
+	writeFile(t, dir, ".env", "SECRETS_BACKEND=vault\nVAULT_ADDR="+server.URL+"\nVAULT_TOKEN=wrong\nVAULT_SECRET_PATH=secret/data/arb-bot\n")
+	if _, err := LoadConfig(dir); err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Errorf("expected Vault's error, got %v", err)
+	}
+}
+
+func TestSecretsFromAWS(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if r.Header.Get("X-Amz-Target") != "secretsmanager.GetSecretValue" ||
+			!strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKID/") || !strings.Contains(auth, "/eu-west-1/secretsmanager/aws4_request") {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"SecretString":"{\"BYBIT_SECRET_KEY\":\"s3cret\"}"}`))
+	}))
+	defer server.Close()
+	t.Setenv("AWS_ENDPOINT_URL", server.URL)
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKID")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "aws-secret")
+
+	cfg := Config{SecretsBackend: "aws", AWSRegion: "eu-west-1", AWSSecretID: "arb-bot"}
+	if err := cfg.loadSecrets(); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.BybitSecretKey != "s3cret" {
+		t.Errorf("expected the secret from AWS, got %q", cfg.BybitSecretKey)
+	}
+}
+
+func TestSecretsOnlySetCredentials(t *testing.T) {
+	var cfg Config
+	err := cfg.setSecrets(map[string]string{"POSITION_SIZE_USD": "1000000"})
+	if err == nil || strings.Contains(err.Error(), "1000000") {
+		t.Errorf("expected a non-secret setting to be rejected without showing its value, got %v", err)
+	}
+	if err := cfg.setSecrets(map[string]string{"NOT_A_SETTING": "x"}); err == nil {
+		t.Error("expected an unknown setting to be rejected")
+	}
+
+	secrets, err := parseSecrets(bytes.NewBufferString("# keys\nAPEX_API_KEY=\"apex\"\n\nexport APEX_SECRET_KEY='secret'\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secrets["APEX_API_KEY"] != "apex" || secrets["APEX_SECRET_KEY"] != "secret" {
+		t.Errorf("unexpected secrets %v", secrets)
+	}
+	if _, err := parseSecrets(bytes.NewBufferString("APEX_API_KEY\n")); err == nil || strings.Contains(err.Error(), "APEX") {
+		t.Errorf("expected a malformed line to be reported by number only, got %v", err)
+	}
+}
+
+func TestAccountsOverrideExchangeSettings(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, ".env", "ACCOUNTS=extended:vault2=btc-usd|ETH-USD,extended:default=SOL-USD\nEXTENDED_VAULT_ID=1\nEXTENDED_PRIVATE_KEY=0xone\nEXTENDED_VAULT2_VAULT_ID=2\nEXTENDED_VAULT2_PRIVATE_KEY=0xtwo\n")
+	cfg, err := LoadConfig(dir)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	accounts, err := ParseAccounts(cfg.Accounts)
+	if err != nil || len(accounts) != 2 {
+		t.Fatalf("ParseAccounts = %v, %v", accounts, err)
+	}
+	vault2 := accounts[0]
+	if vault2.Exchange != "extended" || vault2.Name != "vault2" || len(vault2.Markets) != 2 || vault2.Markets[0] != "BTC-USD" || !accounts[1].IsDefault() {
+		t.Errorf("unexpected accounts %+v", accounts)
+	}
+	accountCfg, err := cfg.ForAccount(vault2)
+	if err != nil {
+		t.Fatalf("ForAccount: %v", err)
+	}
+	if accountCfg.ExtendedVaultID != 2 || accountCfg.ExtendedPrivateKey != "0xtwo" || cfg.ExtendedVaultID != 1 {
+		t.Errorf("expected vault2's settings to override the exchange's only for vault2, got %d, %q and %d", accountCfg.ExtendedVaultID, accountCfg.ExtendedPrivateKey, cfg.ExtendedVaultID)
+	}
+	if cfg.Settings()["EXTENDED_VAULT2_PRIVATE_KEY"] != Redacted {
+		t.Error("expected the account's private key to be redacted")
+	}
+
+	for _, entries := range [][]string{{"extended"}, {"extended:vault-2"}, {"extended:a", "EXTENDED:A"}} {
+		if _, err := ParseAccounts(entries); err == nil {
+			t.Errorf("expected %v to be rejected", entries)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// structuredFiles are the names of the structured config files, in the order they are looked for.
+var structuredFiles = []string{"config.yaml", "config.yml", "config.json"}
+
+// fileKeys maps each "section.key" of the structured config file to its setting.
+func fileKeys() map[string]string {
+	keys := make(map[string]string)
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		setting, section := field.Tag.Get("mapstructure"), field.Tag.Get("section")
+		if setting == "-" {
+			continue
+		}
+		keys[section+"."+strings.ToLower(setting)] = setting
+	}
+	return keys
+}
+
+// readStructured reads the structured config file in dir and returns its settings keyed by
+// setting name, or nil when there is none. Every unknown section or key is reported, with a hint
+// when it looks like a known one.
+func readStructured(dir string) (map[string]interface{}, error) {
+	var found []string
+	for _, name := range structuredFiles {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			found = append(found, name)
+		}
+	}
+	if len(found) == 0 {
+		return nil, nil
+	}
+	if len(found) > 1 {
+		return nil, fmt.Errorf("found %s in %s, keep only one", strings.Join(found, " and "), dir)
+	}
+	name := found[0]
+
+	v := viper.New()
+	v.SetConfigFile(filepath.Join(dir, name))
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	keys := fileKeys()
+	sections := make(map[string]bool)
+	settingKeys := make(map[string]string)
+	for key, setting := range keys {
+		section, _, _ := strings.Cut(key, ".")
+		sections[section] = true
+		settingKeys[strings.ToLower(setting)] = key
+	}
+	sectionNames := make([]string, 0, len(sections))
+	for section := range sections {
+		sectionNames = append(sectionNames, section)
+	}
+	sort.Strings(sectionNames)
+
+	settings := make(map[string]interface{})
+	var errs []error
+	raw := v.AllSettings()
+	for _, section := range sortedKeys(raw) {
+		if !sections[section] {
+			errs = append(errs, fmt.Errorf("%s: unknown section %q (sections: %s)", name, section, strings.Join(sectionNames, ", ")))
+			continue
+		}
+		values, ok := raw[section].(map[string]interface{})
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: %q must be a section of settings, not a value", name, section))
+			continue
+		}
+		for _, key := range sortedKeys(values) {
+			setting, ok := keys[section+"."+key]
+			if ok {
+				settings[setting] = values[key]
+				continue
+			}
+			if known, elsewhere := settingKeys[key]; elsewhere {
+				errs = append(errs, fmt.Errorf("%s: %q belongs in the %s section, not %s", name, key, strings.Split(known, ".")[0], section))
+				continue
+			}
+			err := fmt.Errorf("%s: unknown key %q in the %s section", name, key, section)
+			if suggestion := closest(key, section, keys); suggestion != "" {
+				err = fmt.Errorf("%w (did you mean %q?)", err, suggestion)
+			}
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return settings, nil
+}
+
+// closest returns the known key of section nearest to key by edit distance, or "" when none is
+// close enough to be a likely typo.
+func closest(key, section string, keys map[string]string) string {
+	best, bestDistance := "", len(key)/3+1
+	for known := range keys {
+		knownSection, knownKey, _ := strings.Cut(known, ".")
+		if knownSection != section {
+			continue
+		}
+		if d := editDistance(key, knownKey); d < bestDistance || (d == bestDistance && knownKey < best) {
+			best, bestDistance = knownKey, d
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+// sortedKeys returns the keys of m in sorted order, so errors are reported deterministically.
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Redacted is the value secrets are replaced with.
+const Redacted = "***"
+
+// secretSuffixes end the names of the settings that hold credentials.
+var secretSuffixes = []string{"_API_KEY", "_SECRET_KEY", "_PRIVATE_KEY", "_SIGNING_KEY", "_MNEMONIC", "_PASSPHRASE", "_ZK_SEEDS", "_SECRET", "_BOT_TOKEN", "VAULT_TOKEN", "WEBHOOK_URL"}
+
+// IsSecret reports whether setting holds a credential that must not be shown.
+func IsSecret(setting string) bool {
+	for _, suffix := range secretSuffixes {
+		if strings.HasSuffix(setting, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Settings returns every setting keyed by its name, with the secrets that are set replaced by
+// Redacted, so the configuration can be shown without leaking credentials.
+func (c Config) Settings() map[string]interface{} {
+	settings := make(map[string]interface{})
+	v, t := reflect.ValueOf(c), reflect.TypeOf(c)
+	for i := 0; i < t.NumField(); i++ {
+		setting := t.Field(i).Tag.Get("mapstructure")
+		if setting == "-" {
+			continue
+		}
+		value := v.Field(i).Interface()
+		if IsSecret(setting) && !v.Field(i).IsZero() {
+			value = Redacted
+		}
+		settings[setting] = value
+	}
+	for setting, value := range c.AccountSettings {
+		if IsSecret(setting) && value != "" {
+			value = Redacted
+		}
+		settings[setting] = value
+	}
+	return settings
+}
+
+// Secrets returns the values of the secrets that are set, for redacting them from logs.
+func (c Config) Secrets() []string {
+	var secrets []string
+	v, t := reflect.ValueOf(c), reflect.TypeOf(c)
+	for i := 0; i < t.NumField(); i++ {
+		if IsSecret(t.Field(i).Tag.Get("mapstructure")) && v.Field(i).Kind() == reflect.String && v.Field(i).String() != "" {
+			secrets = append(secrets, v.Field(i).String())
+		}
+	}
+	for setting, value := range c.AccountSettings {
+		if IsSecret(setting) && value != "" {
+			secrets = append(secrets, value)
+		}
+	}
+	return secrets
+}
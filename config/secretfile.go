@@ -0,0 +1,80 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// encryptedFileProvider reads the credentials from a file of SETTING=value lines encrypted with
+// age (.age) or PGP (.gpg, .pgp or .asc). It is decrypted in memory by the age or gpg command,
+// so the plaintext never touches the disk.
+type encryptedFileProvider struct {
+	path     string
+	identity string
+}
+
+func newEncryptedFileProvider(path, identity string) (*encryptedFileProvider, error) {
+	if path == "" {
+		return nil, errors.New("SECRETS_BACKEND file requires SECRETS_FILE")
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".age":
+		if identity == "" {
+			return nil, errors.New("an age encrypted SECRETS_FILE requires SECRETS_AGE_IDENTITY")
+		}
+	case ".gpg", ".pgp", ".asc":
+	default:
+		return nil, fmt.Errorf("SECRETS_FILE %s must be encrypted with age (.age) or PGP (.gpg, .pgp or .asc)", path)
+	}
+	return &encryptedFileProvider{path: path, identity: identity}, nil
+}
+
+func (p *encryptedFileProvider) Name() string {
+	return p.path
+}
+
+func (p *encryptedFileProvider) Secrets(ctx context.Context) (map[string]string, error) {
+	var cmd *exec.Cmd
+	if strings.EqualFold(filepath.Ext(p.path), ".age") {
+		cmd = exec.CommandContext(ctx, "age", "--decrypt", "--identity", p.identity, p.path)
+	} else {
+		// The passphrase or key comes from gpg-agent; there is no terminal to ask on.
+		cmd = exec.CommandContext(ctx, "gpg", "--quiet", "--batch", "--decrypt", p.path)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%s: %w: %s", cmd.Args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return parseSecrets(&stdout)
+}
+
+// parseSecrets parses SETTING=value lines, skipping blank lines and # comments. Values may be
+// quoted. Errors give the line number, never its content.
+func parseSecrets(data *bytes.Buffer) (map[string]string, error) {
+	secrets := make(map[string]string)
+	scanner := bufio.NewScanner(data)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, value, ok := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("line %d: expected SETTING=value", n)
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		secrets[name] = value
+	}
+	return secrets, scanner.Err()
+}
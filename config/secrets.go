@@ -0,0 +1,92 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// secretsTimeout bounds fetching the credentials from the secrets backend.
+const secretsTimeout = 30 * time.Second
+
+// SecretsProvider fetches credentials from outside the config files, so API keys and private keys
+// don't have to be kept in a plaintext .env. Secrets are keyed by setting name, e.g.
+// BINANCE_API_KEY, and only settings that hold credentials may be set this way.
+type SecretsProvider interface {
+	// Name describes the backend in errors, without any credentials.
+	Name() string
+	Secrets(ctx context.Context) (map[string]string, error)
+}
+
+// NewSecretsProvider returns the provider selected by SECRETS_BACKEND, or nil when none is.
+func NewSecretsProvider(c Config) (SecretsProvider, error) {
+	switch backend := strings.ToLower(strings.TrimSpace(c.SecretsBackend)); backend {
+	case "":
+		return nil, nil
+	case "vault":
+		return newVaultProvider(c.VaultAddr, c.VaultToken, c.VaultSecretPath)
+	case "aws":
+		return newAWSSecretsProvider(c.AWSRegion, c.AWSSecretID)
+	case "file":
+		return newEncryptedFileProvider(c.SecretsFile, c.SecretsAgeIdentity)
+	default:
+		return nil, fmt.Errorf("unknown secrets backend %q, expected vault, aws or file", c.SecretsBackend)
+	}
+}
+
+// loadSecrets fetches the credentials from the configured secrets backend, if any, and sets them.
+// They take precedence over the files and the environment.
+func (c *Config) loadSecrets() error {
+	provider, err := NewSecretsProvider(*c)
+	if err != nil || provider == nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), secretsTimeout)
+	defer cancel()
+	secrets, err := provider.Secrets(ctx)
+	if err != nil {
+		return fmt.Errorf("cannot read secrets from %s: %w", provider.Name(), err)
+	}
+	if err := c.setSecrets(secrets); err != nil {
+		return fmt.Errorf("secrets from %s: %w", provider.Name(), err)
+	}
+	return nil
+}
+
+// setSecrets sets each secret on the setting it is keyed by. Errors name the settings, never
+// the values.
+func (c *Config) setSecrets(secrets map[string]string) error {
+	fields := make(map[string]reflect.Value)
+	v, t := reflect.ValueOf(c).Elem(), reflect.TypeOf(*c)
+	for i := 0; i < t.NumField(); i++ {
+		fields[t.Field(i).Tag.Get("mapstructure")] = v.Field(i)
+	}
+
+	names := make([]string, 0, len(secrets))
+	for name := range secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		setting := strings.ToUpper(strings.TrimSpace(name))
+		field, ok := fields[setting]
+		if !ok && IsSecret(setting) && c.isAccountSetting(setting) {
+			if c.AccountSettings == nil {
+				c.AccountSettings = make(map[string]string)
+			}
+			c.AccountSettings[setting] = secrets[name]
+			continue
+		}
+		if !ok {
+			return fmt.Errorf("unknown setting %q", name)
+		}
+		if !IsSecret(setting) || field.Kind() != reflect.String {
+			return fmt.Errorf("%s is not a credential, set it in the config files instead", setting)
+		}
+		field.SetString(secrets[name])
+	}
+	return nil
+}
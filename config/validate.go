@@ -0,0 +1,181 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/blackout"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/cron"
+)
+
+// Validate checks the settings for values the bot can't trade with, such as an empty market list
+// or a position size larger than the position cap, and returns every problem found. Settings
+// parsed by other packages, such as MARGIN_MODES, are checked where they are parsed.
+func (c Config) Validate() error {
+	var errs []error
+	fail := func(setting, format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("%s: %s", describe(setting), fmt.Sprintf(format, args...)))
+	}
+
+	if len(nonEmpty(c.Markets)) == 0 {
+		fail("MARKETS", "no markets to trade, list at least one, e.g. BTC-USD")
+	}
+	if c.PositionSizeUSD <= 0 {
+		fail("POSITION_SIZE_USD", "must be positive, got %g", c.PositionSizeUSD)
+	}
+	if c.MaxPositionUSD < c.PositionSizeUSD {
+		fail("MAX_POSITION_USD", "%g is below POSITION_SIZE_USD %g, so no position could ever be opened", c.MaxPositionUSD, c.PositionSizeUSD)
+	}
+	if c.MinFundingRateDiff < 0 {
+		fail("MIN_FUNDING_RATE_DIFF", "must not be negative, got %g", c.MinFundingRateDiff)
+	}
+	// Entry thresholds are hourly rate differences; the exit floor is annualized.
+	if entry := c.MinFundingRateDiff * 24 * 365; c.ExitMinAnnualDiff > 0 && c.ExitMinAnnualDiff >= entry {
+		fail("EXIT_MIN_ANNUAL_DIFF", "%g is not below MIN_FUNDING_RATE_DIFF annualized (%g), so positions would be closed as soon as they open", c.ExitMinAnnualDiff, entry)
+	}
+	if c.DynamicSizing && c.AllocatorEnabled {
+		fail("DYNAMIC_SIZING", "can't be combined with ALLOCATOR_ENABLED, which sizes positions itself")
+	}
+	if c.DynamicSizing && c.MinPositionSizeUSD > c.PositionSizeUSD {
+		fail("MIN_POSITION_SIZE_USD", "%g is above POSITION_SIZE_USD %g, the largest size DYNAMIC_SIZING scales to", c.MinPositionSizeUSD, c.PositionSizeUSD)
+	}
+	if c.AdaptiveThresholdCeiling > 0 && c.AdaptiveThresholdFloor > c.AdaptiveThresholdCeiling {
+		fail("ADAPTIVE_THRESHOLD_FLOOR", "%g is above ADAPTIVE_THRESHOLD_CEILING %g", c.AdaptiveThresholdFloor, c.AdaptiveThresholdCeiling)
+	}
+
+	for setting, value := range map[string]float64{
+		"MIN_VOLUME_24H_USD":            c.MinVolume24hUSD,
+		"MIN_OPEN_INTEREST_USD":         c.MinOpenInterestUSD,
+		"MAX_ENTRY_IMPACT_BPS":          c.MaxEntryImpactBps,
+		"ENTRY_WINDOW_MINUTES":          c.EntryWindowMinutes,
+		"LEVERAGE":                      c.Leverage,
+		"PAPER_SLIPPAGE_BPS":            c.PaperSlippageBps,
+		"PAPER_FEE_BPS":                 c.PaperFeeBps,
+		"MARKET_DATA_TTL_SECONDS":       float64(c.MarketDataTTLSeconds),
+		"MARKET_INFO_TTL_MINUTES":       c.MarketInfoTTLMinutes,
+		"FUNDING_FETCH_TIMEOUT_SECONDS": c.FundingFetchTimeoutSeconds,
+		"SHUTDOWN_TIMEOUT_SECONDS":      float64(c.ShutdownTimeoutSeconds),
+		"SHUTDOWN_ASK_TIMEOUT_SECONDS":  c.ShutdownAskTimeoutSeconds,
+		"MAX_CLOCK_SKEW_MS":             float64(c.MaxClockSkewMs),
+		"ROLLBACK_ATTEMPTS":             float64(c.RollbackAttempts),
+		"HEDGE_TOLERANCE_USD":           c.HedgeToleranceUSD,
+		"HEDGE_REPAIR_ATTEMPTS":         float64(c.HedgeRepairAttempts),
+		"CIRCUIT_BREAKER_FAILURES":      float64(c.CircuitBreakerFailures),
+		"CIRCUIT_BREAKER_PROBE_SECONDS": float64(c.CircuitBreakerProbeSeconds),
+		"EXECUTION_REPORT_HOURS":        c.ExecutionReportHours,
+		"PNL_REPORT_HOURS":              c.PnlReportHours,
+		"MAX_DRAWDOWN_USD":              c.MaxDrawdownUSD,
+		"TAKE_PROFIT_MULTIPLE":          c.TakeProfitMultiple,
+		"TAKER_FEE_BPS":                 c.TakerFeeBps,
+		"ENTRY_HORIZON_HOURS":           c.EntryHorizonHours,
+		"ENTRY_COST_MARGIN":             c.EntryCostMargin,
+		"SIZING_FULL_ANNUAL_DIFF":       c.SizingFullAnnualDiff,
+		"SIZING_TARGET_VOLATILITY":      c.SizingTargetVolatility,
+		"EXIT_MIN_ANNUAL_DIFF":          c.ExitMinAnnualDiff,
+		"EXIT_HYSTERESIS":               c.ExitHysteresis,
+		"ROTATION_MIN_ANNUAL_GAIN":      c.RotationMinAnnualGain,
+		"REBALANCE_INTERVAL_MINUTES":    c.RebalanceIntervalMinutes,
+		"REBALANCE_EDGE":                c.RebalanceEdge,
+		"MIN_HOLD_MINUTES":              c.MinHoldMinutes,
+		"REENTRY_COOLDOWN_MINUTES":      c.ReentryCooldownMinutes,
+		"MAX_NET_DELTA_USD":             c.MaxNetDeltaUSD,
+		"NET_DELTA_ALERT_USD":           c.NetDeltaAlertUSD,
+		"BASIS_STOP_USD":                c.BasisStopUSD,
+		"CHECK_INTERVAL_SECONDS":        c.CheckIntervalSeconds,
+		"CHECK_JITTER_SECONDS":          c.CheckJitterSeconds,
+	} {
+		if value < 0 {
+			fail(setting, "must not be negative, got %g", value)
+		}
+	}
+	for setting, value := range map[string]float64{
+		"ORACLE_MAX_DEVIATION":       c.OracleMaxDeviation,
+		"LIQUIDATION_ALERT_DISTANCE": c.LiquidationAlertDistance,
+		"DELEVERAGE_TARGET_DISTANCE": c.DeleverageTargetDistance,
+		"CORRELATION_PENALTY":        c.CorrelationPenalty,
+		"CHAOS_ERROR_RATE":           c.ChaosErrorRate,
+		"CHAOS_TIMEOUT_RATE":         c.ChaosTimeoutRate,
+		"CHAOS_PARTIAL_FILL_RATE":    c.ChaosPartialFillRate,
+	} {
+		if value < 0 || value > 1 {
+			fail(setting, "must be a fraction between 0 and 1, got %g", value)
+		}
+	}
+
+	if c.DeleverageTargetDistance > 0 && c.DeleverageTargetDistance <= c.LiquidationAlertDistance {
+		fail("DELEVERAGE_TARGET_DISTANCE", "%g must be above LIQUIDATION_ALERT_DISTANCE %g, or deleveraging would not leave the alert zone", c.DeleverageTargetDistance, c.LiquidationAlertDistance)
+	}
+	if c.DeleverageTargetDistance > 0 && c.LiquidationAlertDistance == 0 {
+		fail("DELEVERAGE_TARGET_DISTANCE", "requires LIQUIDATION_ALERT_DISTANCE, which decides when to deleverage")
+	}
+	if c.CheckIntervalSeconds > 0 && c.CheckIntervalSeconds < 10 && !c.Streaming {
+		fail("CHECK_INTERVAL_SECONDS", "%g is below 10 seconds, which requires STREAMING; polling every exchange that often would run into their rate limits", c.CheckIntervalSeconds)
+	}
+	if c.KillSwitchUnwind && c.MaxDrawdownUSD == 0 && c.MaxNetDeltaUSD == 0 {
+		fail("KILL_SWITCH_UNWIND", "requires MAX_DRAWDOWN_USD or MAX_NET_DELTA_USD, which decide when to unwind")
+	}
+	if c.NetDeltaRebalance && c.NetDeltaAlertUSD == 0 {
+		fail("NET_DELTA_REBALANCE", "requires NET_DELTA_ALERT_USD, which decides when to rebalance")
+	}
+	if c.TelegramBotToken != "" && c.TelegramChatID == 0 {
+		fail("TELEGRAM_CHAT_ID", "must be set when TELEGRAM_BOT_TOKEN is, or notifications have nowhere to go")
+	}
+	if _, err := ParseAccounts(c.Accounts); err != nil {
+		fail("ACCOUNTS", "%v", err)
+	}
+	if _, err := NewSecretsProvider(c); err != nil {
+		fail("SECRETS_BACKEND", "%v", err)
+	}
+	switch strings.ToLower(strings.TrimSpace(c.ShutdownPolicy)) {
+	case "", "preserve", "flatten":
+	case "ask-telegram":
+		if c.TelegramBotToken == "" {
+			fail("SHUTDOWN_POLICY", "ask-telegram requires TELEGRAM_BOT_TOKEN, or there is no one to ask")
+		}
+	default:
+		fail("SHUTDOWN_POLICY", "unknown policy %q, expected preserve, flatten or ask-telegram", c.ShutdownPolicy)
+	}
+	if c.SlackBotToken != "" && c.SlackChannel == "" {
+		fail("SLACK_CHANNEL", "must be set when SLACK_BOT_TOKEN is, or notifications have nowhere to go")
+	}
+	if _, err := blackout.ParseWindows(c.MarketBlackouts); err != nil {
+		fail("MARKET_BLACKOUTS", "%v", err)
+	}
+	if c.DailySummaryCron != "" {
+		if _, err := cron.Parse(c.DailySummaryCron); err != nil {
+			fail("DAILY_SUMMARY_CRON", "%v", err)
+		}
+	}
+	if (c.GoogleSheetsCredentialsFile == "") != (c.GoogleSheetsSpreadsheetID == "") {
+		fail("GOOGLE_SHEETS_SPREADSHEET_ID", "GOOGLE_SHEETS_CREDENTIALS_FILE and GOOGLE_SHEETS_SPREADSHEET_ID must be set together")
+	}
+
+	// Some checks run in map order; sort so the report is stable.
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Error() < errs[j].Error() })
+	return errors.Join(errs...)
+}
+
+// describe names setting as both the environment variable and the structured file key.
+func describe(setting string) string {
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < t.NumField(); i++ {
+		if field := t.Field(i); field.Tag.Get("mapstructure") == setting {
+			return fmt.Sprintf("%s (%s.%s)", setting, field.Tag.Get("section"), strings.ToLower(setting))
+		}
+	}
+	return setting
+}
+
+// nonEmpty returns the entries of a list setting that aren't blank.
+func nonEmpty(entries []string) []string {
+	var kept []string
+	for _, entry := range entries {
+		if strings.TrimSpace(entry) != "" {
+			kept = append(kept, entry)
+		}
+	}
+	return kept
+}
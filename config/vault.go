@@ -0,0 +1,73 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// vaultProvider reads the credentials from a HashiCorp Vault KV secret, version 1 or 2, whose
+// fields are the setting names.
+type vaultProvider struct {
+	addr   string
+	token  string
+	path   string
+	client *http.Client
+}
+
+func newVaultProvider(addr, token, path string) (*vaultProvider, error) {
+	if addr == "" || token == "" || path == "" {
+		return nil, errors.New("SECRETS_BACKEND vault requires VAULT_ADDR, VAULT_TOKEN and VAULT_SECRET_PATH")
+	}
+	return &vaultProvider{
+		addr:   strings.TrimRight(addr, "/"),
+		token:  token,
+		path:   strings.Trim(path, "/"),
+		client: &http.Client{Timeout: secretsTimeout},
+	}, nil
+}
+
+func (p *vaultProvider) Name() string {
+	return fmt.Sprintf("Vault secret %s", p.path)
+}
+
+func (p *vaultProvider) Secrets(ctx context.Context) (map[string]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.addr+"/v1/"+p.path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", p.token)
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []string        `json:"errors"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil && resp.StatusCode == http.StatusOK {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d: %s", resp.StatusCode, strings.Join(body.Errors, "; "))
+	}
+
+	// KV version 2 nests the fields under data.data, next to data.metadata.
+	var v2 struct {
+		Data     map[string]string `json:"data"`
+		Metadata json.RawMessage   `json:"metadata"`
+	}
+	if err := json.Unmarshal(body.Data, &v2); err == nil && v2.Metadata != nil {
+		return v2.Data, nil
+	}
+	var v1 map[string]string
+	if err := json.Unmarshal(body.Data, &v1); err != nil {
+		return nil, errors.New("the secret's fields must all be strings")
+	}
+	return v1, nil
+}
@@ -0,0 +1,112 @@
+package allocator
+
+import (
+	"math"
+	"sort"
+)
+
+// Opportunity is a candidate arbitrage to be sized.
+type Opportunity struct {
+	Market        string
+	LongExchange  string
+	ShortExchange string
+	// Score ranks opportunities; higher is better (e.g. annualized rate diff).
+	Score float64
+}
+
+// Constraints bound the allocation.
+type Constraints struct {
+	// TotalCapitalUSD is the maximum notional across all positions.
+	TotalCapitalUSD float64
+	// PerMarketCapUSD limits the notional of a single market. Zero means no cap.
+	PerMarketCapUSD float64
+	// MinSizeUSD drops allocations smaller than this amount. Zero means no minimum.
+	MinSizeUSD float64
+	// Leverage converts notional into required margin on each venue. Values below 1 are treated as 1.
+	Leverage float64
+	// VenueMarginUSD is the free margin available per exchange. Exchanges missing from the map are unconstrained.
+	VenueMarginUSD map[string]float64
+	// Correlations holds the pairwise correlation of markets, keyed by market pair in either order.
+	Correlations map[[2]string]float64
+	// CorrelationPenalty scales how strongly correlation with already-allocated markets reduces a new allocation (0..1).
+	CorrelationPenalty float64
+}
+
+// Allocation is the notional assigned to an opportunity.
+type Allocation struct {
+	Opportunity
+	SizeUSD float64
+}
+
+// Allocate distributes capital across opportunities in score order. Each allocation is limited by the
+// per-market cap, the remaining total capital and the remaining margin on both legs' venues, and is
+// shrunk when the market is correlated with markets already allocated to.
+func Allocate(opportunities []Opportunity, c Constraints) []Allocation {
+	ranked := make([]Opportunity, len(opportunities))
+	copy(ranked, opportunities)
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].Score > ranked[j].Score })
+
+	leverage := c.Leverage
+	if leverage < 1 {
+		leverage = 1
+	}
+	margin := make(map[string]float64, len(c.VenueMarginUSD))
+	for venue, m := range c.VenueMarginUSD {
+		margin[venue] = m
+	}
+	remaining := c.TotalCapitalUSD
+
+	var allocations []Allocation
+	var allocatedMarkets []string
+	for _, opp := range ranked {
+		if remaining <= 0 {
+			break
+		}
+
+		size := remaining
+		if c.PerMarketCapUSD > 0 {
+			size = math.Min(size, c.PerMarketCapUSD)
+		}
+		for _, venue := range []string{opp.LongExchange, opp.ShortExchange} {
+			if m, ok := margin[venue]; ok {
+				size = math.Min(size, m*leverage)
+			}
+		}
+
+		if c.CorrelationPenalty > 0 {
+			maxCorr := 0.0
+			for _, other := range allocatedMarkets {
+				maxCorr = math.Max(maxCorr, correlation(c.Correlations, opp.Market, other))
+			}
+			size *= 1 - math.Min(c.CorrelationPenalty, 1)*maxCorr
+		}
+
+		if size <= 0 || size < c.MinSizeUSD {
+			continue
+		}
+
+		remaining -= size
+		for _, venue := range []string{opp.LongExchange, opp.ShortExchange} {
+			if _, ok := margin[venue]; ok {
+				margin[venue] -= size / leverage
+			}
+		}
+		allocations = append(allocations, Allocation{Opportunity: opp, SizeUSD: size})
+		allocatedMarkets = append(allocatedMarkets, opp.Market)
+	}
+	return allocations
+}
+
+// correlation looks up the correlation of two markets. Identical markets are fully correlated.
+func correlation(table map[[2]string]float64, a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	if v, ok := table[[2]string{a, b}]; ok {
+		return math.Max(v, 0)
+	}
+	if v, ok := table[[2]string{b, a}]; ok {
+		return math.Max(v, 0)
+	}
+	return 0
+}
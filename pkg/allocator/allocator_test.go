@@ -0,0 +1,50 @@
+package allocator
+
+import (
+	"math"
+	"testing"
+)
+
+func TestAllocatePrefersHigherScoreAndRespectsCaps(t *testing.T) {
+	opps := []Opportunity{
+		{Market: "ETH-USD", LongExchange: "A", ShortExchange: "B", Score: 0.1},
+		{Market: "BTC-USD", LongExchange: "A", ShortExchange: "B", Score: 0.3},
+		{Market: "SOL-USD", LongExchange: "A", ShortExchange: "B", Score: 0.2},
+	}
+	allocs := Allocate(opps, Constraints{
+		TotalCapitalUSD: 250,
+		PerMarketCapUSD: 100,
+	})
+
+	if len(allocs) != 3 {
+		t.Fatalf("expected 3 allocations, got %d", len(allocs))
+	}
+	if allocs[0].Market != "BTC-USD" || allocs[0].SizeUSD != 100 {
+		t.Errorf("unexpected first allocation: %+v", allocs[0])
+	}
+	if allocs[2].Market != "ETH-USD" || allocs[2].SizeUSD != 50 {
+		t.Errorf("expected the remaining 50 USD on ETH-USD, got %+v", allocs[2])
+	}
+}
+
+func TestAllocateRespectsVenueMarginAndCorrelation(t *testing.T) {
+	opps := []Opportunity{
+		{Market: "BTC-USD", LongExchange: "A", ShortExchange: "B", Score: 0.3},
+		{Market: "ETH-USD", LongExchange: "A", ShortExchange: "C", Score: 0.2},
+	}
+	allocs := Allocate(opps, Constraints{
+		TotalCapitalUSD:    1000,
+		PerMarketCapUSD:    500,
+		Leverage:           2,
+		VenueMarginUSD:     map[string]float64{"A": 200},
+		Correlations:       map[[2]string]float64{{"BTC-USD", "ETH-USD"}: 0.5},
+		CorrelationPenalty: 1,
+	})
+
+	if len(allocs) != 1 {
+		t.Fatalf("expected venue A margin to be exhausted after one allocation, got %+v", allocs)
+	}
+	if math.Abs(allocs[0].SizeUSD-400) > 1e-9 {
+		t.Errorf("expected 400 USD (200 margin at 2x), got %f", allocs[0].SizeUSD)
+	}
+}
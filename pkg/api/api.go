@@ -0,0 +1,190 @@
+// Package api serves the state of a running bot as JSON over HTTP, for monitoring and dashboards:
+// its health, open positions, PnL, the current funding rates, the market data the strategies
+// trade on and the configuration in use.
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/marketdata"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/pnl"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/rates"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/state"
+)
+
+// Source is implemented by strategies whose positions and PnL are served.
+type Source interface {
+	Positions() []state.Position
+	PnL() pnl.Summary
+}
+
+// Server answers the API requests. Its handler is safe for concurrent use as long as the sources
+// are.
+type Server struct {
+	sources    []Source
+	aggregator *rates.Aggregator
+	cache      *marketdata.Cache
+	settings   map[string]interface{}
+	startedAt  time.Time
+}
+
+// New creates a server reporting on sources, with funding rates collected by aggregator, the
+// market data held by cache and settings served as the configuration. Secrets must already be
+// redacted from settings.
+func New(sources []Source, aggregator *rates.Aggregator, cache *marketdata.Cache, settings map[string]interface{}) *Server {
+	return &Server{sources: sources, aggregator: aggregator, cache: cache, settings: settings, startedAt: time.Now()}
+}
+
+// Handler returns the HTTP handler serving:
+//
+//	GET /healthz    liveness and uptime
+//	GET /positions  open positions of every strategy
+//	GET /rates      annualized funding rates and spreads
+//	GET /marketdata cached rates, prices and order book tops, with their age
+//	GET /pnl        realized and unrealized PnL
+//	GET /config     configuration, with secrets redacted
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", s.health)
+	mux.HandleFunc("GET /positions", s.positions)
+	mux.HandleFunc("GET /rates", s.rates)
+	mux.HandleFunc("GET /marketdata", s.marketData)
+	mux.HandleFunc("GET /pnl", s.pnl)
+	mux.HandleFunc("GET /config", func(w http.ResponseWriter, r *http.Request) { writeJSON(w, s.settings) })
+	return mux
+}
+
+func (s *Server) health(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, struct {
+		Status        string    `json:"status"`
+		StartedAt     time.Time `json:"startedAt"`
+		UptimeSeconds int64     `json:"uptimeSeconds"`
+	}{"ok", s.startedAt, int64(time.Since(s.startedAt).Seconds())})
+}
+
+func (s *Server) positions(w http.ResponseWriter, r *http.Request) {
+	positions := []state.Position{}
+	for _, source := range s.sources {
+		positions = append(positions, source.Positions()...)
+	}
+	writeJSON(w, struct {
+		Positions []state.Position `json:"positions"`
+	}{positions})
+}
+
+func (s *Server) rates(w http.ResponseWriter, r *http.Request) {
+	if s.aggregator == nil {
+		http.Error(w, "funding rates are not collected", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, s.aggregator.Collect(r.Context()))
+}
+
+// snapshot is a marketdata.Snapshot as served by /marketdata.
+type snapshot struct {
+	Exchange     string    `json:"exchange"`
+	Market       string    `json:"market"`
+	FundingRate  string    `json:"fundingRate"`
+	NextFunding  int64     `json:"nextFunding,omitempty"`
+	MarkPrice    float64   `json:"markPrice,omitempty"`
+	BestBid      float64   `json:"bestBid,omitempty"`
+	BestAsk      float64   `json:"bestAsk,omitempty"`
+	RateUpdated  time.Time `json:"rateUpdated,omitzero"`
+	PriceUpdated time.Time `json:"priceUpdated,omitzero"`
+	BookUpdated  time.Time `json:"bookUpdated,omitzero"`
+	Stale        bool      `json:"stale"`
+}
+
+// marketData serves every snapshot held by the market data cache the strategies read through. A
+// snapshot is stale when its funding rate is older than the cache's TTL.
+func (s *Server) marketData(w http.ResponseWriter, r *http.Request) {
+	if s.cache == nil {
+		http.Error(w, "market data is not cached", http.StatusNotFound)
+		return
+	}
+	snapshots := []snapshot{}
+	for _, cached := range s.cache.Snapshots() {
+		snapshots = append(snapshots, snapshot{
+			Exchange:     cached.Exchange,
+			Market:       cached.Market,
+			FundingRate:  cached.FundingRate.String(),
+			NextFunding:  cached.NextFunding,
+			MarkPrice:    cached.MarkPrice,
+			BestBid:      cached.BestBid,
+			BestAsk:      cached.BestAsk,
+			RateUpdated:  cached.RateUpdated,
+			PriceUpdated: cached.PriceUpdated,
+			BookUpdated:  cached.BookUpdated,
+			Stale:        cached.IsStale(s.cache.TTL()),
+		})
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		if snapshots[i].Market != snapshots[j].Market {
+			return snapshots[i].Market < snapshots[j].Market
+		}
+		return snapshots[i].Exchange < snapshots[j].Exchange
+	})
+	writeJSON(w, struct {
+		Snapshots []snapshot `json:"snapshots"`
+	}{snapshots})
+}
+
+// position is the PnL of an open position as served by /pnl.
+type position struct {
+	Market        string    `json:"market"`
+	LongExchange  string    `json:"longExchange"`
+	ShortExchange string    `json:"shortExchange"`
+	SizeUSD       float64   `json:"sizeUsd"`
+	Funding       float64   `json:"funding"`
+	PricePnL      float64   `json:"pricePnl"`
+	NetPnL        float64   `json:"netPnl"`
+	OpenedAt      time.Time `json:"openedAt"`
+}
+
+// summary is pnl.Summary as served by /pnl, summed over every strategy.
+type summary struct {
+	Open               []position `json:"open"`
+	Closed             int        `json:"closed"`
+	RealizedFunding    float64    `json:"realizedFunding"`
+	RealizedPricePnL   float64    `json:"realizedPricePnl"`
+	UnrealizedFunding  float64    `json:"unrealizedFunding"`
+	UnrealizedPricePnL float64    `json:"unrealizedPricePnl"`
+	Total              float64    `json:"total"`
+}
+
+func (s *Server) pnl(w http.ResponseWriter, r *http.Request) {
+	total := summary{Open: []position{}}
+	for _, source := range s.sources {
+		sum := source.PnL()
+		for _, p := range sum.Open {
+			total.Open = append(total.Open, position{
+				Market:        p.Market,
+				LongExchange:  p.Long.Exchange,
+				ShortExchange: p.Short.Exchange,
+				SizeUSD:       p.SizeUSD,
+				Funding:       p.Funding,
+				PricePnL:      p.PricePnL(),
+				NetPnL:        p.NetPnL(),
+				OpenedAt:      p.OpenedAt,
+			})
+		}
+		total.Closed += sum.Closed
+		total.RealizedFunding += sum.RealizedFunding
+		total.RealizedPricePnL += sum.RealizedPricePnL
+		total.UnrealizedFunding += sum.UnrealizedFunding
+		total.UnrealizedPricePnL += sum.UnrealizedPricePnL
+		total.Total += sum.Total()
+	}
+	writeJSON(w, total)
+}
+
+// writeJSON encodes v as the JSON response body.
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
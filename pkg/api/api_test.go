@@ -0,0 +1,109 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/exchange"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/marketdata"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/pnl"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/state"
+)
+
+// fakeSource holds one open position.
+type fakeSource struct{}
+
+func (fakeSource) Positions() []state.Position {
+	return []state.Position{{Market: "BTC-USD", LongExchange: "A", ShortExchange: "B", SizeUSD: 1000}}
+}
+
+func (fakeSource) PnL() pnl.Summary {
+	return pnl.Summary{
+		Open: []pnl.Position{{
+			Market: "BTC-USD", Amount: 0.01, SizeUSD: 1000, Funding: 2,
+			Long:  pnl.Leg{Exchange: "A", EntryPrice: 100000, Price: 101000},
+			Short: pnl.Leg{Exchange: "B", EntryPrice: 100000, Price: 101000},
+		}},
+		Closed:            1,
+		RealizedFunding:   5,
+		UnrealizedFunding: 2,
+	}
+}
+
+func get(t *testing.T, handler http.Handler, path string, v interface{}) int {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", path, nil))
+	if recorder.Code == http.StatusOK {
+		if err := json.NewDecoder(recorder.Body).Decode(v); err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+	}
+	return recorder.Code
+}
+
+func TestServerEndpoints(t *testing.T) {
+	server := New([]Source{fakeSource{}}, nil, nil, map[string]interface{}{"BINANCE_API_KEY": "***"})
+	server.startedAt = time.Now().Add(-time.Minute)
+	handler := server.Handler()
+
+	var health struct {
+		Status        string `json:"status"`
+		UptimeSeconds int64  `json:"uptimeSeconds"`
+	}
+	if code := get(t, handler, "/healthz", &health); code != http.StatusOK || health.Status != "ok" || health.UptimeSeconds < 60 {
+		t.Errorf("/healthz = %d %+v", code, health)
+	}
+
+	var positions struct {
+		Positions []state.Position `json:"positions"`
+	}
+	if get(t, handler, "/positions", &positions); len(positions.Positions) != 1 || positions.Positions[0].Market != "BTC-USD" {
+		t.Errorf("/positions = %+v", positions)
+	}
+
+	var summary summary
+	get(t, handler, "/pnl", &summary)
+	if summary.Closed != 1 || summary.Total != 7 || len(summary.Open) != 1 || summary.Open[0].NetPnL != 2 {
+		t.Errorf("/pnl = %+v", summary)
+	}
+
+	var settings map[string]interface{}
+	if get(t, handler, "/config", &settings); settings["BINANCE_API_KEY"] != "***" {
+		t.Errorf("/config = %v", settings)
+	}
+
+	if code := get(t, handler, "/rates", nil); code != http.StatusNotFound {
+		t.Errorf("/rates without an aggregator = %d, want 404", code)
+	}
+	if code := get(t, handler, "/marketdata", nil); code != http.StatusNotFound {
+		t.Errorf("/marketdata without a cache = %d, want 404", code)
+	}
+}
+
+func TestMarketDataServesTheCachedSnapshots(t *testing.T) {
+	cache := marketdata.NewCache(time.Minute)
+	cache.StoreFundingRates("B", []*exchange.FundingRate{{Market: "BTC-USD", Rate: decimal.NewFromFloat(0.0001)}})
+	cache.StoreOrderbookTop("B", "BTC-USD", 99990, 100010)
+	cache.StoreMarkPrice("A", "BTC-USD", 100000)
+	handler := New(nil, nil, cache, nil).Handler()
+
+	var served struct {
+		Snapshots []snapshot `json:"snapshots"`
+	}
+	if code := get(t, handler, "/marketdata", &served); code != http.StatusOK || len(served.Snapshots) != 2 {
+		t.Fatalf("/marketdata = %d %+v", code, served)
+	}
+	a, b := served.Snapshots[0], served.Snapshots[1]
+	if a.Exchange != "A" || a.MarkPrice != 100000 || !a.Stale {
+		t.Errorf("expected A priced without a rate to be stale, got %+v", a)
+	}
+	if b.Exchange != "B" || b.FundingRate != "0.0001" || b.BestBid != 99990 || b.BestAsk != 100010 || b.Stale {
+		t.Errorf("expected B's fresh rate and book top, got %+v", b)
+	}
+}
@@ -0,0 +1,189 @@
+// Package backtest replays historical funding rates through the funding rate arbitrage strategy
+// and measures what it would have earned.
+package backtest
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/config"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/exchange"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/strategy"
+)
+
+// Options controls a backtest run.
+type Options struct {
+	// Exchanges are the exchanges to trade between, as named in the samples. Every exchange in
+	// the data is used when empty.
+	Exchanges []string
+	// FeeBps is charged on the notional of every fill.
+	FeeBps float64
+	// Logger receives the strategy's log output. Nil discards it.
+	Logger *log.Logger
+}
+
+// MarketResult is the performance of the strategy on one market, in USD. NetAPR is the net PnL
+// per year of capital deployed, where a position's capital is the notional of one leg.
+type MarketResult struct {
+	Market      string
+	Trades      int
+	Funding     float64
+	Fees        float64
+	PricePnL    float64
+	NetPnL      float64
+	NetAPR      float64
+	MaxDrawdown float64
+	// DeployedUSDHours is the notional held, integrated over time.
+	DeployedUSDHours float64
+}
+
+// Result is the outcome of a backtest. Total aggregates every market.
+type Result struct {
+	From      time.Time
+	To        time.Time
+	Exchanges []string
+	Markets   []MarketResult
+	Total     MarketResult
+}
+
+// Run replays samples through the strategy configured by cfg. At every timestamp, funding is
+// first settled on the positions held since the previous timestamp, then the new rates are
+// published and the strategy decides, so it never acts on a rate before it is known.
+func Run(cfg config.Config, samples []Sample, opts Options) (*Result, error) {
+	if len(samples) == 0 {
+		return nil, errors.New("no funding history to replay")
+	}
+	names := opts.Exchanges
+	if len(names) == 0 {
+		names = exchangesIn(samples)
+	}
+	if len(names) < 2 {
+		return nil, fmt.Errorf("the funding history must cover at least two exchanges, found %v", names)
+	}
+	if len(cfg.Markets) == 0 {
+		cfg.Markets = marketsIn(samples)
+	}
+
+	// Side effects that only make sense live are disabled. The adaptive threshold measures how
+	// long spreads last in wall-clock time, which a replay compresses, and entry windows are
+	// timed against the wall clock rather than the replayed funding times.
+	cfg.StateFile = ""
+	cfg.Oracle = ""
+	cfg.AdaptiveThreshold = false
+	cfg.EntryWindowMinutes = 0
+	cfg.ExitAfterFunding = false
+	logger := opts.Logger
+	if logger == nil {
+		logger = log.New(io.Discard, "", 0)
+	}
+
+	eng := newEngine(opts.FeeBps)
+	venues := make([]exchange.Exchange, len(names))
+	for i, name := range names {
+		venues[i] = eng.venue(name)
+	}
+	strat := strategy.NewFundingRateArb(cfg, venues, logger, nil)
+
+	for start := 0; start < len(samples); {
+		at := samples[start].Time
+		end := start
+		for end < len(samples) && samples[end].Time.Equal(at) {
+			end++
+		}
+		batch := samples[start:end]
+		start = end
+
+		eng.settle(batch)
+		eng.publish(batch, strat.MarketData())
+		held := eng.heldMarkets()
+		strat.Check()
+		eng.countTrades(held)
+
+		var next time.Time
+		if end < len(samples) {
+			next = samples[end].Time
+		}
+		eng.mark(at, next)
+	}
+
+	result := &Result{
+		From:      samples[0].Time,
+		To:        samples[len(samples)-1].Time,
+		Exchanges: names,
+		Markets:   eng.results(),
+	}
+	result.Total = total(result.Markets)
+	return result, nil
+}
+
+// exchangesIn returns the exchanges in samples in order of first appearance.
+func exchangesIn(samples []Sample) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, s := range samples {
+		if !seen[s.Exchange] {
+			seen[s.Exchange] = true
+			names = append(names, s.Exchange)
+		}
+	}
+	return names
+}
+
+// marketsIn returns the markets in samples, sorted.
+func marketsIn(samples []Sample) []string {
+	var markets []string
+	seen := make(map[string]bool)
+	for _, s := range samples {
+		if !seen[s.Market] {
+			seen[s.Market] = true
+			markets = append(markets, s.Market)
+		}
+	}
+	sort.Strings(markets)
+	return markets
+}
+
+// total aggregates market results. The drawdown of the total is the largest drawdown of any
+// market, a lower bound on the drawdown of the combined equity.
+func total(markets []MarketResult) MarketResult {
+	t := MarketResult{Market: "TOTAL"}
+	for _, m := range markets {
+		t.Trades += m.Trades
+		t.Funding += m.Funding
+		t.Fees += m.Fees
+		t.PricePnL += m.PricePnL
+		t.NetPnL += m.NetPnL
+		t.DeployedUSDHours += m.DeployedUSDHours
+		t.MaxDrawdown = math.Max(t.MaxDrawdown, m.MaxDrawdown)
+	}
+	t.NetAPR = apr(t.NetPnL, t.DeployedUSDHours)
+	return t
+}
+
+// apr annualizes pnl over the capital deployed.
+func apr(pnl, usdHours float64) float64 {
+	if usdHours <= 0 {
+		return 0
+	}
+	return pnl / (usdHours / (365 * 24))
+}
+
+// WriteReport prints result as a table.
+func WriteReport(w io.Writer, result *Result) error {
+	fmt.Fprintf(w, "Backtest %s from %s to %s\n\n", strings.Join(result.Exchanges, " / "),
+		result.From.Format(time.RFC3339), result.To.Format(time.RFC3339))
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "Market\tTrades\tFunding\tFees\tPrice PnL\tNet PnL\tNet APR\tMax drawdown\t")
+	for _, m := range append(result.Markets, result.Total) {
+		fmt.Fprintf(tw, "%s\t%d\t%.2f\t%.2f\t%.2f\t%.2f\t%.2f%%\t%.2f\t\n",
+			m.Market, m.Trades, m.Funding, m.Fees, m.PricePnL, m.NetPnL, m.NetAPR*100, m.MaxDrawdown)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,70 @@
+package backtest
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/config"
+)
+
+const history = `time,exchange,market,rate
+2024-01-01T00:00:00Z,A,BTC-USD,0.001
+2024-01-01T00:00:00Z,B,BTC-USD,0
+2024-01-01T01:00:00Z,A,BTC-USD,0.001
+2024-01-01T01:00:00Z,B,BTC-USD,0
+2024-01-01T02:00:00Z,A,BTC-USD,0
+2024-01-01T02:00:00Z,B,BTC-USD,0.0005
+`
+
+func TestRunReplaysFundingThroughTheStrategy(t *testing.T) {
+	samples, err := LoadCSV(strings.NewReader(history))
+	if err != nil {
+		t.Fatalf("LoadCSV: %v", err)
+	}
+	cfg := config.Config{MinFundingRateDiff: 0.0001, PositionSizeUSD: 1000, MaxPositionUSD: 10000}
+	result, err := Run(cfg, samples, Options{FeeBps: 5})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(result.Markets) != 1 {
+		t.Fatalf("expected one market, got %+v", result.Markets)
+	}
+
+	// Opened at 00:00 short A, long B. The short collects 0.1% at 01:00, the long pays 0.05% at
+	// 02:00 and the inverted spread closes the position. Four fills pay 5 bps each.
+	m := result.Markets[0]
+	near := func(got, want float64) bool { return math.Abs(got-want) < 1e-9 }
+	if m.Trades != 1 || !near(m.Funding, 0.5) || !near(m.Fees, 2) || !near(m.NetPnL, -1.5) {
+		t.Errorf("unexpected result %+v", m)
+	}
+	if !near(m.MaxDrawdown, 1.5) || !near(m.DeployedUSDHours, 2000) || !near(m.NetAPR, -1.5/(2000.0/8760)) {
+		t.Errorf("unexpected drawdown or APR %+v", m)
+	}
+
+	var report bytes.Buffer
+	if err := WriteReport(&report, result); err != nil || !strings.Contains(report.String(), "TOTAL") {
+		t.Errorf("expected a report with a total row, got %q, %v", report.String(), err)
+	}
+}
+
+func TestCSVRoundTrip(t *testing.T) {
+	samples, err := LoadCSV(strings.NewReader("time,exchange,market,rate,price\n1704070800,dYdX,ETH-USD,0.00001,2300.5\n"))
+	if err != nil || len(samples) != 1 || samples[0].Price != 2300.5 || samples[0].Time.Unix() != 1704070800 {
+		t.Fatalf("unexpected samples %+v, %v", samples, err)
+	}
+
+	var out bytes.Buffer
+	if err := WriteCSV(&out, samples); err != nil {
+		t.Fatalf("WriteCSV: %v", err)
+	}
+	again, err := LoadCSV(&out)
+	if err != nil || len(again) != 1 || again[0] != samples[0] {
+		t.Errorf("expected the samples to survive a round trip, got %+v, %v", again, err)
+	}
+
+	if _, err := LoadCSV(strings.NewReader("time,market,rate\n")); err == nil {
+		t.Error("expected a missing exchange column to be rejected")
+	}
+}
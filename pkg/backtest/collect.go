@@ -0,0 +1,146 @@
+package backtest
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"time"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/exchange"
+)
+
+// Collector grows a funding history file in the format read by LoadCSV, by polling the exchanges
+// for the rates paid since the last one recorded for each market. Exchanges that don't serve
+// their funding history are skipped.
+type Collector struct {
+	Exchanges []exchange.Exchange
+	Markets   []string
+	// Path is the CSV file appended to. It is created, with a header, if it doesn't exist.
+	Path string
+	// Lookback is how far back a market missing from the file is fetched on the first poll.
+	Lookback time.Duration
+	Logger   *log.Logger
+
+	// last is when the latest rate recorded for each exchange and market was paid.
+	last map[string]time.Time
+}
+
+// Collect fetches the rates paid up to now that the file doesn't hold yet, appends them and
+// returns how many were written. An exchange that fails is logged and retried on the next poll.
+func (c *Collector) Collect(ctx context.Context, now time.Time) (int, error) {
+	if c.last == nil {
+		if err := c.loadLast(); err != nil {
+			return 0, err
+		}
+	}
+	var samples []Sample
+	for _, ex := range c.Exchanges {
+		historian, ok := ex.(exchange.FundingHistorian)
+		if !ok {
+			continue
+		}
+		for _, market := range c.Markets {
+			key := ex.Name() + "/" + market
+			from, ok := c.last[key]
+			if ok {
+				from = from.Add(time.Second)
+			} else {
+				from = now.Add(-c.Lookback)
+			}
+			history, err := historian.GetFundingHistory(ctx, market, from, now)
+			if err != nil {
+				c.Logger.Printf("Could not collect the %s funding history from %s: %v", market, ex.Name(), err)
+				continue
+			}
+			for _, rate := range history {
+				paid := time.Unix(rate.NextTime, 0).UTC()
+				if last, ok := c.last[key]; ok && !paid.After(last) {
+					continue
+				}
+				samples = append(samples, Sample{Time: paid, Exchange: ex.Name(), Market: market, Rate: rate.Rate.InexactFloat64()})
+				c.last[key] = paid
+			}
+		}
+	}
+	if len(samples) == 0 {
+		return 0, nil
+	}
+	if err := appendCSV(c.Path, samples); err != nil {
+		return 0, fmt.Errorf("cannot write %s: %w", c.Path, err)
+	}
+	return len(samples), nil
+}
+
+// Run collects every interval until ctx is done.
+func (c *Collector) Run(ctx context.Context, every time.Duration) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		written, err := c.Collect(ctx, time.Now())
+		if err != nil {
+			c.Logger.Printf("Funding history collection failed: %v", err)
+		} else {
+			c.Logger.Printf("Collected %d funding rates into %s.", written, c.Path)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// loadLast reads when the latest rate of each exchange and market in the file was paid.
+func (c *Collector) loadLast() error {
+	c.last = make(map[string]time.Time)
+	f, err := os.Open(c.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	samples, err := LoadCSV(f)
+	if err != nil {
+		return fmt.Errorf("cannot read %s: %w", c.Path, err)
+	}
+	for _, s := range samples {
+		key := s.Exchange + "/" + s.Market
+		if s.Time.After(c.last[key]) {
+			c.last[key] = s.Time
+		}
+	}
+	return nil
+}
+
+// appendCSV appends samples to the file at path, writing the header first if the file is new or
+// empty.
+func appendCSV(path string, samples []Sample) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	writer := csv.NewWriter(f)
+	if info.Size() == 0 {
+		if err := writer.Write(csvHeader); err != nil {
+			return err
+		}
+	}
+	for _, s := range samples {
+		if err := writer.Write(sampleRecord(s)); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
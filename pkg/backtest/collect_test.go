@@ -0,0 +1,73 @@
+package backtest
+
+import (
+	"context"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/exchange"
+)
+
+// historyVenue serves a fixed funding history; the rest of exchange.Exchange is left unimplemented.
+type historyVenue struct {
+	exchange.Exchange
+	name    string
+	history []*exchange.FundingRate
+}
+
+func (v *historyVenue) Name() string { return v.name }
+
+func (v *historyVenue) GetFundingHistory(_ context.Context, _ string, from, to time.Time) ([]*exchange.FundingRate, error) {
+	var rates []*exchange.FundingRate
+	for _, r := range v.history {
+		if paid := time.Unix(r.NextTime, 0); !paid.Before(from) && paid.Before(to) {
+			rates = append(rates, r)
+		}
+	}
+	return rates, nil
+}
+
+func TestCollectorAppendsOnlyNewRates(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	venue := &historyVenue{name: "A", history: []*exchange.FundingRate{
+		{Rate: decimal.NewFromFloat(0.001), NextTime: start.Unix()},
+		{Rate: decimal.NewFromFloat(0.002), NextTime: start.Add(time.Hour).Unix()},
+	}}
+	path := filepath.Join(t.TempDir(), "history.csv")
+	newCollector := func() *Collector {
+		return &Collector{Exchanges: []exchange.Exchange{venue}, Markets: []string{"BTC-USD"}, Path: path, Lookback: 24 * time.Hour, Logger: log.New(io.Discard, "", 0)}
+	}
+
+	c := newCollector()
+	if written, err := c.Collect(context.Background(), start.Add(90*time.Minute)); err != nil || written != 2 {
+		t.Fatalf("first poll wrote %d rates (%v), want 2", written, err)
+	}
+	if written, _ := c.Collect(context.Background(), start.Add(100*time.Minute)); written != 0 {
+		t.Errorf("a poll without new payments wrote %d rates", written)
+	}
+
+	// A restarted collector picks up after the last rate in the file.
+	venue.history = append(venue.history, &exchange.FundingRate{Rate: decimal.NewFromFloat(0.003), NextTime: start.Add(2 * time.Hour).Unix()})
+	if written, err := newCollector().Collect(context.Background(), start.Add(150*time.Minute)); err != nil || written != 1 {
+		t.Fatalf("poll after a restart wrote %d rates (%v), want 1", written, err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	samples, err := LoadCSV(f)
+	if err != nil {
+		t.Fatalf("the collected history can't be loaded: %v", err)
+	}
+	if len(samples) != 3 || samples[2].Rate != 0.003 || !samples[2].Time.Equal(start.Add(2*time.Hour)) {
+		t.Errorf("unexpected history %+v", samples)
+	}
+}
@@ -0,0 +1,138 @@
+package backtest
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/exchange"
+)
+
+// csvHeader is the column layout read by LoadCSV and written by WriteCSV. Price is optional.
+var csvHeader = []string{"time", "exchange", "market", "rate", "price"}
+
+// Sample is a funding rate paid on one market of one exchange. Rate is per funding interval of
+// the exchange, as it was paid at Time. Price is the mark or oracle price at that time, or zero
+// when unknown.
+type Sample struct {
+	Time     time.Time
+	Exchange string
+	Market   string
+	Rate     float64
+	Price    float64
+}
+
+// LoadCSV reads samples from CSV with a header of time,exchange,market,rate[,price]. Times are
+// RFC 3339 or Unix seconds. The samples are returned sorted by time.
+func LoadCSV(r io.Reader) ([]Sample, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read funding history: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	columns := make(map[string]int)
+	for i, name := range records[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range csvHeader[:4] {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("funding history is missing the %q column", name)
+		}
+	}
+	field := func(record []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	samples := make([]Sample, 0, len(records)-1)
+	for line, record := range records[1:] {
+		at, err := parseTime(field(record, "time"))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line+2, err)
+		}
+		rate, err := strconv.ParseFloat(field(record, "rate"), 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid rate: %w", line+2, err)
+		}
+		sample := Sample{Time: at, Exchange: field(record, "exchange"), Market: field(record, "market"), Rate: rate}
+		if price := field(record, "price"); price != "" {
+			if sample.Price, err = strconv.ParseFloat(price, 64); err != nil {
+				return nil, fmt.Errorf("line %d: invalid price: %w", line+2, err)
+			}
+		}
+		samples = append(samples, sample)
+	}
+	sort.SliceStable(samples, func(i, j int) bool { return samples[i].Time.Before(samples[j].Time) })
+	return samples, nil
+}
+
+func parseTime(value string) (time.Time, error) {
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0).UTC(), nil
+	}
+	at, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q, expected RFC 3339 or Unix seconds", value)
+	}
+	return at, nil
+}
+
+// WriteCSV writes samples in the format read by LoadCSV.
+func WriteCSV(w io.Writer, samples []Sample) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, s := range samples {
+		if err := writer.Write(sampleRecord(s)); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// sampleRecord formats a sample as a CSV record in the order of csvHeader.
+func sampleRecord(s Sample) []string {
+	price := ""
+	if s.Price > 0 {
+		price = strconv.FormatFloat(s.Price, 'f', -1, 64)
+	}
+	return []string{s.Time.UTC().Format(time.RFC3339), s.Exchange, s.Market, strconv.FormatFloat(s.Rate, 'f', -1, 64), price}
+}
+
+// Download fetches the funding rates paid on markets between from and to from every exchange
+// that serves its funding history.
+func Download(ctx context.Context, exchanges []exchange.Exchange, markets []string, from, to time.Time) ([]Sample, error) {
+	var samples []Sample
+	for _, ex := range exchanges {
+		historian, ok := ex.(exchange.FundingHistorian)
+		if !ok {
+			return nil, fmt.Errorf("%s does not provide funding history", ex.Name())
+		}
+		for _, market := range markets {
+			history, err := historian.GetFundingHistory(ctx, market, from, to)
+			if err != nil {
+				return nil, err
+			}
+			for _, rate := range history {
+				samples = append(samples, Sample{Time: time.Unix(rate.NextTime, 0).UTC(), Exchange: ex.Name(), Market: market, Rate: rate.Rate.InexactFloat64()})
+			}
+		}
+	}
+	sort.SliceStable(samples, func(i, j int) bool { return samples[i].Time.Before(samples[j].Time) })
+	return samples, nil
+}
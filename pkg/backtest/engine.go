@@ -0,0 +1,325 @@
+package backtest
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/exchange"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/marketdata"
+)
+
+// replayBalance is the collateral every replayed venue reports, large enough that margin never
+// constrains the strategy.
+const replayBalance = 1e12
+
+// ledger accumulates the results of one market.
+type ledger struct {
+	trades   int
+	funding  float64
+	fees     float64
+	cash     float64
+	deployed float64
+	peak     float64
+	drawdown float64
+}
+
+// engine holds the replayed venues and the books of every market.
+type engine struct {
+	feeBps float64
+
+	mu      sync.Mutex
+	venues  map[string]*venue
+	ledgers map[string]*ledger
+	// lastPrice is the most recent price of a market on any venue, used for venues whose
+	// history has no prices.
+	lastPrice map[string]float64
+}
+
+func newEngine(feeBps float64) *engine {
+	return &engine{
+		feeBps:    feeBps,
+		venues:    make(map[string]*venue),
+		ledgers:   make(map[string]*ledger),
+		lastPrice: make(map[string]float64),
+	}
+}
+
+// venue returns the replayed venue called name, creating it if needed.
+func (e *engine) venue(name string) *venue {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	v, ok := e.venues[name]
+	if !ok {
+		v = &venue{engine: e, name: name, rates: make(map[string]float64), prices: make(map[string]float64), positions: make(map[string]float64)}
+		e.venues[name] = v
+	}
+	return v
+}
+
+// ledgerFor returns the ledger of market. The caller must hold e.mu.
+func (e *engine) ledgerFor(market string) *ledger {
+	l, ok := e.ledgers[market]
+	if !ok {
+		l = &ledger{}
+		e.ledgers[market] = l
+	}
+	return l
+}
+
+// priceOf returns the price of market on v. Markets that were never priced trade at 1, which
+// keeps notionals in USD. The caller must hold e.mu.
+func (e *engine) priceOf(v *venue, market string) float64 {
+	if price, ok := v.prices[market]; ok {
+		return price
+	}
+	if price, ok := e.lastPrice[market]; ok {
+		return price
+	}
+	return 1
+}
+
+// settle pays the funding in batch on the positions held on the venues that paid it.
+func (e *engine) settle(batch []Sample) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, s := range batch {
+		v, ok := e.venues[s.Exchange]
+		if !ok {
+			continue
+		}
+		size := v.positions[s.Market]
+		if size == 0 {
+			continue
+		}
+		price := s.Price
+		if price <= 0 {
+			price = e.priceOf(v, s.Market)
+		}
+		// Longs pay shorts when the rate is positive.
+		e.ledgerFor(s.Market).funding -= size * price * s.Rate
+	}
+}
+
+// publish makes batch the current market data of the venues and stores it in the strategy's
+// cache, so the strategy reads the replayed values rather than anything cached earlier.
+func (e *engine) publish(batch []Sample, cache *marketdata.Cache) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, s := range batch {
+		v, ok := e.venues[s.Exchange]
+		if !ok {
+			continue
+		}
+		v.rates[s.Market] = s.Rate
+		if s.Price > 0 {
+			v.prices[s.Market] = s.Price
+			e.lastPrice[s.Market] = s.Price
+		}
+	}
+	for _, v := range e.venues {
+		cache.StoreFundingRates(v.name, v.fundingRates())
+		for market := range v.rates {
+			cache.StoreMarkPrice(v.name, market, e.priceOf(v, market))
+		}
+	}
+}
+
+// heldMarkets returns the markets with a position on any venue.
+func (e *engine) heldMarkets() map[string]bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	held := make(map[string]bool)
+	for _, v := range e.venues {
+		for market, size := range v.positions {
+			if size != 0 {
+				held[market] = true
+			}
+		}
+	}
+	return held
+}
+
+// countTrades counts a trade for every market that was flat before the strategy's decision.
+func (e *engine) countTrades(before map[string]bool) {
+	for market := range e.heldMarkets() {
+		if !before[market] {
+			e.mu.Lock()
+			e.ledgerFor(market).trades++
+			e.mu.Unlock()
+		}
+	}
+}
+
+// mark values every market at at, updating its drawdown, and accrues the capital deployed until
+// next. A zero next ends the replay.
+func (e *engine) mark(at, next time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	hours := 0.0
+	if !next.IsZero() {
+		hours = next.Sub(at).Hours()
+	}
+
+	notional := make(map[string]float64)
+	value := make(map[string]float64)
+	for _, v := range e.venues {
+		for market, size := range v.positions {
+			price := e.priceOf(v, market)
+			notional[market] += math.Abs(size) * price
+			value[market] += size * price
+			e.ledgerFor(market)
+		}
+	}
+	for market, l := range e.ledgers {
+		// Both legs are counted in notional, but only one leg's worth of capital is at work.
+		l.deployed += notional[market] / 2 * hours
+		equity := l.funding - l.fees + l.cash + value[market]
+		l.peak = math.Max(l.peak, equity)
+		l.drawdown = math.Max(l.drawdown, l.peak-equity)
+	}
+}
+
+// results returns the result of every market that was traded or held, sorted by market.
+func (e *engine) results() []MarketResult {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	value := make(map[string]float64)
+	for _, v := range e.venues {
+		for market, size := range v.positions {
+			value[market] += size * e.priceOf(v, market)
+		}
+	}
+
+	results := make([]MarketResult, 0, len(e.ledgers))
+	for market, l := range e.ledgers {
+		r := MarketResult{
+			Market:           market,
+			Trades:           l.trades,
+			Funding:          l.funding,
+			Fees:             l.fees,
+			PricePnL:         l.cash + value[market],
+			MaxDrawdown:      l.drawdown,
+			DeployedUSDHours: l.deployed,
+		}
+		r.NetPnL = r.Funding - r.Fees + r.PricePnL
+		r.NetAPR = apr(r.NetPnL, r.DeployedUSDHours)
+		results = append(results, r)
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Market < results[j].Market })
+	return results
+}
+
+// venue is an exchange replayed from funding history. Orders fill immediately at the current
+// price, charged the engine's fee.
+type venue struct {
+	engine *engine
+	name   string
+	// rates, prices and positions are guarded by engine.mu.
+	rates     map[string]float64
+	prices    map[string]float64
+	positions map[string]float64
+	nextID    int
+}
+
+func (v *venue) Name() string { return v.name }
+
+func (v *venue) SetTestnet(bool) {}
+
+// fundingRates returns the current rates. The caller must hold engine.mu.
+func (v *venue) fundingRates() []*exchange.FundingRate {
+	rates := make([]*exchange.FundingRate, 0, len(v.rates))
+	for market, rate := range v.rates {
+		rates = append(rates, &exchange.FundingRate{Market: market, Rate: decimal.NewFromFloat(rate)})
+	}
+	return rates
+}
+
+func (v *venue) GetFundingRates(ctx context.Context) ([]*exchange.FundingRate, error) {
+	v.engine.mu.Lock()
+	defer v.engine.mu.Unlock()
+	return v.fundingRates(), nil
+}
+
+func (v *venue) GetOrderbook(ctx context.Context, market string) (*exchange.Orderbook, error) {
+	return nil, fmt.Errorf("%s has no order book in a backtest: %w", v.name, exchange.ErrOrderbookUnsupported)
+}
+
+func (v *venue) GetMarkPrice(ctx context.Context, market string) (float64, error) {
+	v.engine.mu.Lock()
+	defer v.engine.mu.Unlock()
+	return v.engine.priceOf(v, market), nil
+}
+
+func (v *venue) PlaceOrder(ctx context.Context, market string, side exchange.OrderSide, orderType exchange.OrderType, amount, price decimal.Decimal) (*exchange.Order, error) {
+	e := v.engine
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	fill := e.priceOf(v, market)
+	size := amount.InexactFloat64()
+	if side == exchange.Sell {
+		size = -size
+	}
+	v.positions[market] += size
+	if math.Abs(v.positions[market]) < 1e-12 {
+		delete(v.positions, market)
+	}
+	l := e.ledgerFor(market)
+	l.cash -= size * fill
+	l.fees += math.Abs(size) * fill * e.feeBps / 10000
+
+	v.nextID++
+	return &exchange.Order{
+		ID:     "backtest-" + strconv.Itoa(v.nextID),
+		Market: market,
+		Side:   side,
+		Type:   orderType,
+		Price:  decimal.NewFromFloat(fill),
+		Amount: amount,
+		Filled: amount,
+		Status: "FILLED",
+	}, nil
+}
+
+func (v *venue) GetOrderStatus(ctx context.Context, orderID string, market string) (*exchange.Order, error) {
+	return nil, fmt.Errorf("order %s not found", orderID)
+}
+
+func (v *venue) CancelOrder(ctx context.Context, orderID string, market string) error {
+	return fmt.Errorf("order %s not found", orderID)
+}
+
+func (v *venue) GetBalance(ctx context.Context, asset string) (float64, error) {
+	return replayBalance, nil
+}
+
+func (v *venue) GetPositions(ctx context.Context) ([]exchange.Position, error) {
+	v.engine.mu.Lock()
+	defer v.engine.mu.Unlock()
+	positions := make([]exchange.Position, 0, len(v.positions))
+	for market, size := range v.positions {
+		side := exchange.Buy
+		if size < 0 {
+			side = exchange.Sell
+		}
+		positions = append(positions, exchange.Position{Market: market, Side: side, Size: math.Abs(size), EntryPrice: v.engine.priceOf(v, market)})
+	}
+	return positions, nil
+}
+
+func (v *venue) ClosePosition(ctx context.Context, market string, side exchange.OrderSide, amount decimal.Decimal) (*exchange.Order, error) {
+	// To close a position, we place an order on the opposite side.
+	closeSide := exchange.Sell
+	if side == exchange.Sell {
+		closeSide = exchange.Buy
+	}
+	return v.PlaceOrder(ctx, market, closeSide, exchange.Market, amount, decimal.Zero)
+}
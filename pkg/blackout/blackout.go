@@ -0,0 +1,137 @@
+// Package blackout decides which markets may not be entered, and when: blacklisted markets
+// never, and any market during its blackout windows, e.g. around a token unlock or a listing.
+package blackout
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Window is a period during which a market may not be entered. It is either a single period from
+// Start to End, or, when Daily is set, the same time of day every day from From to To in UTC,
+// wrapping past midnight if To is before From. A Market of "*" covers every market.
+type Window struct {
+	Market     string
+	Start, End time.Time
+	Daily      bool
+	From, To   time.Duration
+}
+
+// Contains reports whether t falls in the window.
+func (w Window) Contains(t time.Time) bool {
+	if !w.Daily {
+		return !t.Before(w.Start) && t.Before(w.End)
+	}
+	t = t.UTC()
+	of := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+	if w.From <= w.To {
+		return of >= w.From && of < w.To
+	}
+	return of >= w.From || of < w.To
+}
+
+// String describes the window, e.g. "2024-03-16T00:00:00Z/2024-03-17T00:00:00Z" or "daily
+// 23:30-00:30 UTC".
+func (w Window) String() string {
+	if !w.Daily {
+		return w.Start.Format(time.RFC3339) + "/" + w.End.Format(time.RFC3339)
+	}
+	clock := func(d time.Duration) string { return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60) }
+	return "daily " + clock(w.From) + "-" + clock(w.To) + " UTC"
+}
+
+// ParseWindows parses entries of the form "MARKET=START/END", with RFC 3339 times, or
+// "MARKET=HH:MM-HH:MM" for a daily window in UTC, e.g. "ARB-USD=2024-03-16T00:00:00Z/2024-03-17T00:00:00Z"
+// or "*=23:55-00:05".
+func ParseWindows(entries []string) ([]Window, error) {
+	var windows []Window
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		market, spec, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid blackout window %q, expected MARKET=START/END or MARKET=HH:MM-HH:MM", entry)
+		}
+		w := Window{Market: strings.TrimSpace(market)}
+		spec = strings.TrimSpace(spec)
+		if start, end, ok := strings.Cut(spec, "/"); ok {
+			var err error
+			if w.Start, err = time.Parse(time.RFC3339, strings.TrimSpace(start)); err != nil {
+				return nil, fmt.Errorf("invalid start in blackout window %q: %w", entry, err)
+			}
+			if w.End, err = time.Parse(time.RFC3339, strings.TrimSpace(end)); err != nil {
+				return nil, fmt.Errorf("invalid end in blackout window %q: %w", entry, err)
+			}
+			if !w.End.After(w.Start) {
+				return nil, fmt.Errorf("blackout window %q ends before it starts", entry)
+			}
+		} else {
+			from, to, ok := strings.Cut(spec, "-")
+			if !ok {
+				return nil, fmt.Errorf("invalid blackout window %q, expected MARKET=START/END or MARKET=HH:MM-HH:MM", entry)
+			}
+			var err error
+			w.Daily = true
+			if w.From, err = parseClock(from); err != nil {
+				return nil, fmt.Errorf("invalid blackout window %q: %w", entry, err)
+			}
+			if w.To, err = parseClock(to); err != nil {
+				return nil, fmt.Errorf("invalid blackout window %q: %w", entry, err)
+			}
+			if w.From == w.To {
+				return nil, fmt.Errorf("blackout window %q is empty", entry)
+			}
+		}
+		windows = append(windows, w)
+	}
+	return windows, nil
+}
+
+// parseClock parses a time of day as HH:MM into the time since midnight.
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q, expected HH:MM", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// Rules are the blacklisted markets and the blackout windows. A nil *Rules blocks nothing.
+type Rules struct {
+	blacklist map[string]bool
+	windows   []Window
+}
+
+// New returns the rules blocking the markets in blacklist at all times and the markets of windows
+// during them, or nil if there are none.
+func New(blacklist []string, windows []Window) *Rules {
+	r := &Rules{blacklist: make(map[string]bool), windows: windows}
+	for _, market := range blacklist {
+		if market = strings.TrimSpace(market); market != "" {
+			r.blacklist[market] = true
+		}
+	}
+	if len(r.blacklist) == 0 && len(r.windows) == 0 {
+		return nil
+	}
+	return r
+}
+
+// Blocked returns why market may not be entered at t, or "" if it may.
+func (r *Rules) Blocked(market string, t time.Time) string {
+	if r == nil {
+		return ""
+	}
+	if r.blacklist[market] {
+		return market + " is blacklisted"
+	}
+	for _, w := range r.windows {
+		if (w.Market == market || w.Market == "*") && w.Contains(t) {
+			return fmt.Sprintf("%s is in its blackout window %s", market, w)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,43 @@
+package blackout
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRulesBlockBlacklistedMarketsAndWindows(t *testing.T) {
+	windows, err := ParseWindows([]string{"ARB-USD=2024-03-16T00:00:00Z/2024-03-17T00:00:00Z", " * = 23:30-00:30 "})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules := New([]string{"LUNA-USD"}, windows)
+	noon := time.Date(2024, 3, 16, 12, 0, 0, 0, time.UTC)
+
+	for _, c := range []struct {
+		market  string
+		at      time.Time
+		blocked bool
+	}{
+		{"LUNA-USD", noon.AddDate(1, 0, 0), true},
+		{"ARB-USD", noon, true},
+		{"ARB-USD", noon.Add(13 * time.Hour), false},
+		{"BTC-USD", noon, false},
+		{"BTC-USD", time.Date(2024, 3, 16, 23, 45, 0, 0, time.UTC), true},
+		{"BTC-USD", time.Date(2024, 3, 17, 0, 15, 0, 0, time.UTC), true},
+		{"BTC-USD", time.Date(2024, 3, 17, 0, 30, 0, 0, time.UTC), false},
+	} {
+		if reason := rules.Blocked(c.market, c.at); (reason != "") != c.blocked {
+			t.Errorf("Blocked(%s, %s) = %q, want blocked %v", c.market, c.at.Format(time.RFC3339), reason, c.blocked)
+		}
+	}
+
+	var none *Rules
+	if New(nil, nil) != nil || none.Blocked("BTC-USD", noon) != "" {
+		t.Error("no rules must block nothing")
+	}
+	for _, entry := range []string{"ARB-USD", "ARB-USD=2024-03-17T00:00:00Z/2024-03-16T00:00:00Z", "ARB-USD=25:00-01:00", "ARB-USD=10:00-10:00"} {
+		if _, err := ParseWindows([]string{entry}); err == nil {
+			t.Errorf("expected %q to be rejected", entry)
+		}
+	}
+}
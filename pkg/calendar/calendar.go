@@ -0,0 +1,138 @@
+// Package calendar tracks when each venue pays funding.
+package calendar
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Schedule describes a venue's funding cadence: payments happen every Interval,
+// offset by Anchor from midnight UTC (e.g. 8h@0h pays at 00:00, 08:00 and 16:00).
+type Schedule struct {
+	Interval time.Duration
+	Anchor   time.Duration
+}
+
+// Next returns the first funding time strictly after t.
+func (s Schedule) Next(t time.Time) time.Time {
+	if s.Interval <= 0 {
+		return time.Time{}
+	}
+	t = t.UTC()
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	base := midnight.Add(s.Anchor % s.Interval)
+	if base.After(t) {
+		base = base.Add(-s.Interval)
+	}
+	elapsed := t.Sub(base)
+	return base.Add((elapsed/s.Interval + 1) * s.Interval)
+}
+
+// ParseSchedules parses entries of the form "NAME=INTERVAL[@ANCHOR]", e.g. "Binance=8h@0h".
+func ParseSchedules(entries []string) (map[string]Schedule, error) {
+	schedules := make(map[string]Schedule)
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, spec, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid funding schedule %q, expected NAME=INTERVAL[@ANCHOR]", entry)
+		}
+		intervalSpec, anchorSpec, _ := strings.Cut(spec, "@")
+		interval, err := time.ParseDuration(strings.TrimSpace(intervalSpec))
+		if err != nil || interval <= 0 {
+			return nil, fmt.Errorf("invalid funding interval in %q", entry)
+		}
+		var anchor time.Duration
+		if anchorSpec != "" {
+			anchor, err = time.ParseDuration(strings.TrimSpace(anchorSpec))
+			if err != nil {
+				return nil, fmt.Errorf("invalid funding anchor in %q: %w", entry, err)
+			}
+		}
+		schedules[strings.TrimSpace(name)] = Schedule{Interval: interval, Anchor: anchor}
+	}
+	return schedules, nil
+}
+
+// Calendar knows the funding schedule of each venue and the next funding times the venues
+// themselves have reported. Reported times take precedence over the schedule.
+type Calendar struct {
+	mu        sync.RWMutex
+	schedules map[string]Schedule
+	reported  map[[2]string]time.Time
+}
+
+// New creates an empty calendar.
+func New() *Calendar {
+	return &Calendar{
+		schedules: make(map[string]Schedule),
+		reported:  make(map[[2]string]time.Time),
+	}
+}
+
+// SetSchedule sets the funding schedule for a venue.
+func (c *Calendar) SetSchedule(exchange string, schedule Schedule) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.schedules[exchange] = schedule
+}
+
+// Schedule returns the funding schedule configured for a venue.
+func (c *Calendar) Schedule(exchange string) (Schedule, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	s, ok := c.schedules[exchange]
+	return s, ok
+}
+
+// Observe records a next funding time reported by a venue for a market.
+func (c *Calendar) Observe(exchange, market string, next time.Time) {
+	if next.IsZero() {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.reported[[2]string{exchange, market}] = next
+}
+
+// NextFunding returns the next funding time for a market after now, or the zero time
+// if the venue has neither reported one nor has a schedule.
+func (c *Calendar) NextFunding(exchange, market string, now time.Time) time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if next, ok := c.reported[[2]string{exchange, market}]; ok && next.After(now) {
+		return next
+	}
+	if schedule, ok := c.schedules[exchange]; ok {
+		return schedule.Next(now)
+	}
+	return time.Time{}
+}
+
+// TimeUntil returns how long until the next funding payment for a market, or -1 if unknown.
+func (c *Calendar) TimeUntil(exchange, market string, now time.Time) time.Duration {
+	next := c.NextFunding(exchange, market, now)
+	if next.IsZero() {
+		return -1
+	}
+	return next.Sub(now)
+}
+
+// Soonest returns the earliest next funding time across the given venues and markets.
+func (c *Calendar) Soonest(exchanges, markets []string, now time.Time) time.Time {
+	var soonest time.Time
+	for _, ex := range exchanges {
+		for _, market := range markets {
+			next := c.NextFunding(ex, market, now)
+			if !next.IsZero() && (soonest.IsZero() || next.Before(soonest)) {
+				soonest = next
+			}
+		}
+	}
+	return soonest
+}
@@ -0,0 +1,50 @@
+package calendar
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleNext(t *testing.T) {
+	eightHourly := Schedule{Interval: 8 * time.Hour}
+	at := time.Date(2024, 6, 1, 9, 30, 0, 0, time.UTC)
+	if got, want := eightHourly.Next(at), time.Date(2024, 6, 1, 16, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Next(%s) = %s, want %s", at, got, want)
+	}
+	// Exactly on a funding time, the next one is returned.
+	at = time.Date(2024, 6, 1, 16, 0, 0, 0, time.UTC)
+	if got, want := eightHourly.Next(at), time.Date(2024, 6, 2, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Next(%s) = %s, want %s", at, got, want)
+	}
+
+	anchored := Schedule{Interval: 4 * time.Hour, Anchor: 2 * time.Hour}
+	at = time.Date(2024, 6, 1, 1, 0, 0, 0, time.UTC)
+	if got, want := anchored.Next(at), time.Date(2024, 6, 1, 2, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Next(%s) = %s, want %s", at, got, want)
+	}
+}
+
+func TestCalendarPrefersReportedTimes(t *testing.T) {
+	c := New()
+	c.SetSchedule("A", Schedule{Interval: time.Hour})
+	now := time.Date(2024, 6, 1, 9, 30, 0, 0, time.UTC)
+
+	if got := c.TimeUntil("A", "BTC-USD", now); got != 30*time.Minute {
+		t.Errorf("expected 30m from the schedule, got %s", got)
+	}
+	c.Observe("A", "BTC-USD", now.Add(10*time.Minute))
+	if got := c.TimeUntil("A", "BTC-USD", now); got != 10*time.Minute {
+		t.Errorf("expected the reported time to win, got %s", got)
+	}
+	if got := c.TimeUntil("B", "BTC-USD", now); got != -1 {
+		t.Errorf("expected -1 for an unknown venue, got %s", got)
+	}
+
+	schedules, err := ParseSchedules([]string{"Binance=8h@0h", " Extended=1h"})
+	if err != nil || schedules["Binance"].Interval != 8*time.Hour || schedules["Extended"].Interval != time.Hour {
+		t.Errorf("unexpected schedules %v, %v", schedules, err)
+	}
+	if _, err := ParseSchedules([]string{"Binance"}); err == nil {
+		t.Error("expected an error for a schedule without an interval")
+	}
+}
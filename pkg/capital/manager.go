@@ -0,0 +1,129 @@
+// Package capital assigns capital budgets to strategies running in the same process.
+package capital
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// MarginFunc returns the total free margin across all accounts in USD.
+type MarginFunc func() (float64, error)
+
+// Usage is a strategy's budget and the notional it currently has deployed.
+type Usage struct {
+	Strategy  string
+	BudgetUSD float64
+	UsedUSD   float64
+}
+
+// Manager tracks the notional deployed by each strategy and refuses reservations that would
+// exceed the strategy's budget or, at the configured leverage, the margin available on the accounts.
+// A nil Manager accepts every reservation.
+type Manager struct {
+	budgets  map[string]float64
+	leverage float64
+	margin   MarginFunc
+
+	mu   sync.Mutex
+	used map[string]float64
+}
+
+// NewManager creates a capital manager. Strategies without a budget are only limited by the
+// account margin. margin may be nil to skip the account-level check.
+func NewManager(budgets map[string]float64, leverage float64, margin MarginFunc) *Manager {
+	if leverage < 1 {
+		leverage = 1
+	}
+	return &Manager{budgets: budgets, leverage: leverage, margin: margin, used: make(map[string]float64)}
+}
+
+// ParseBudgets parses entries of the form "strategy=USD".
+func ParseBudgets(entries []string) (map[string]float64, error) {
+	budgets := make(map[string]float64)
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid strategy budget %q, expected NAME=USD", entry)
+		}
+		budget, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || budget < 0 {
+			return nil, fmt.Errorf("invalid strategy budget %q", entry)
+		}
+		budgets[strings.TrimSpace(name)] = budget
+	}
+	return budgets, nil
+}
+
+// Reserve claims notionalUSD of capital for strategy.
+func (m *Manager) Reserve(strategy string, notionalUSD float64) error {
+	if m == nil {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if budget, ok := m.budgets[strategy]; ok && m.used[strategy]+notionalUSD > budget {
+		return fmt.Errorf("%s budget of %.2f USD exceeded (%.2f USD in use)", strategy, budget, m.used[strategy])
+	}
+
+	if m.margin != nil {
+		free, err := m.margin()
+		if err != nil {
+			return fmt.Errorf("cannot check account margin: %w", err)
+		}
+		total := 0.0
+		for _, used := range m.used {
+			total += used
+		}
+		// Margin already backing open positions is not free, so compare against the margin
+		// needed for the new position only.
+		if notionalUSD/m.leverage > free {
+			return fmt.Errorf("insufficient account margin: %.2f USD free, %.2f USD needed (%.2f USD deployed across strategies)", free, notionalUSD/m.leverage, total)
+		}
+	}
+
+	m.used[strategy] += notionalUSD
+	return nil
+}
+
+// Release returns notionalUSD of capital previously reserved by strategy.
+func (m *Manager) Release(strategy string, notionalUSD float64) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.used[strategy] -= notionalUSD
+	if m.used[strategy] < 1e-9 {
+		delete(m.used, strategy)
+	}
+}
+
+// Usage returns the budget and deployed notional of every strategy, sorted by name.
+func (m *Manager) Usage() []Usage {
+	if m == nil {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	names := make(map[string]bool)
+	for name := range m.budgets {
+		names[name] = true
+	}
+	for name := range m.used {
+		names[name] = true
+	}
+	usage := make([]Usage, 0, len(names))
+	for name := range names {
+		usage = append(usage, Usage{Strategy: name, BudgetUSD: m.budgets[name], UsedUSD: m.used[name]})
+	}
+	sort.Slice(usage, func(i, j int) bool { return usage[i].Strategy < usage[j].Strategy })
+	return usage
+}
@@ -0,0 +1,47 @@
+package capital
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestManagerEnforcesBudgetsAndMargin(t *testing.T) {
+	free := 1000.0
+	m := NewManager(map[string]float64{"arb": 1500, "hedge": 500}, 2, func() (float64, error) { return free, nil })
+
+	if err := m.Reserve("arb", 1000); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := m.Reserve("arb", 600); err == nil {
+		t.Error("expected the arb budget to be exceeded")
+	}
+	if err := m.Reserve("hedge", 500); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Free margin shrinks as positions are opened on the accounts.
+	free = 100
+	m.Release("hedge", 500)
+	if err := m.Reserve("hedge", 400); err == nil {
+		t.Error("expected insufficient margin at 2x leverage")
+	}
+	if err := m.Reserve("unbudgeted", 200); err != nil {
+		t.Errorf("strategies without a budget should only be limited by margin: %v", err)
+	}
+
+	free = 0
+	m.margin = func() (float64, error) { return 0, errors.New("down") }
+	if err := m.Reserve("arb", 1); err == nil {
+		t.Error("expected reservations to fail when margin is unknown")
+	}
+
+	usage := m.Usage()
+	if len(usage) != 3 || usage[0].Strategy != "arb" || usage[0].UsedUSD != 1000 {
+		t.Errorf("unexpected usage %+v", usage)
+	}
+
+	var nilManager *Manager
+	if err := nilManager.Reserve("arb", 1e9); err != nil {
+		t.Error("a nil manager should accept every reservation")
+	}
+}
@@ -0,0 +1,137 @@
+package collateral
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/exchange"
+)
+
+// USD is the unit all collateral is converted into.
+const USD = "USD"
+
+// Asseter is implemented by exchanges that report which asset their margin is held in.
+type Asseter interface {
+	CollateralAsset() string
+}
+
+// AssetOf returns the collateral asset of ex, defaulting to USD.
+func AssetOf(ex exchange.Exchange) string {
+	if a, ok := ex.(Asseter); ok && a.CollateralAsset() != "" {
+		return a.CollateralAsset()
+	}
+	return USD
+}
+
+// PriceSource returns the USD price of one unit of an asset.
+type PriceSource interface {
+	USDPrice(ctx context.Context, asset string) (float64, error)
+}
+
+// StaticPrices is a fixed price table, e.g. from configuration.
+type StaticPrices map[string]float64
+
+// USDPrice implements PriceSource.
+func (p StaticPrices) USDPrice(_ context.Context, asset string) (float64, error) {
+	if price, ok := p[strings.ToUpper(asset)]; ok {
+		return price, nil
+	}
+	return 0, fmt.Errorf("no static price for %s", asset)
+}
+
+// ParseStaticPrices parses entries of the form "USDT=0.999".
+func ParseStaticPrices(entries []string) (StaticPrices, error) {
+	prices := StaticPrices{}
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		asset, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid collateral price %q, expected ASSET=PRICE", entry)
+		}
+		price, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid collateral price %q: %w", entry, err)
+		}
+		prices[strings.ToUpper(strings.TrimSpace(asset))] = price
+	}
+	return prices, nil
+}
+
+// MarkPriceSource prices an asset from the "<ASSET>-USD" mark price of an exchange.
+type MarkPriceSource struct {
+	Exchange interface {
+		GetMarkPrice(ctx context.Context, market string) (float64, error)
+	}
+}
+
+// USDPrice implements PriceSource.
+func (m MarkPriceSource) USDPrice(ctx context.Context, asset string) (float64, error) {
+	return m.Exchange.GetMarkPrice(ctx, strings.ToUpper(asset)+"-USD")
+}
+
+// Converter converts balances between collateral assets and USD.
+// USD itself is always worth 1; USDC and USDT default to 1 unless a source prices them.
+type Converter struct {
+	sources []PriceSource
+}
+
+// NewConverter creates a converter that consults sources in order.
+func NewConverter(sources ...PriceSource) *Converter {
+	return &Converter{sources: sources}
+}
+
+// USDPrice returns the USD price of one unit of asset.
+func (c *Converter) USDPrice(ctx context.Context, asset string) (float64, error) {
+	asset = strings.ToUpper(asset)
+	if asset == USD {
+		return 1, nil
+	}
+	var lastErr error
+	for _, source := range c.sources {
+		price, err := source.USDPrice(ctx, asset)
+		if err == nil && price > 0 {
+			return price, nil
+		}
+		lastErr = err
+	}
+	if asset == "USDC" || asset == "USDT" {
+		return 1, nil
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no price source for %s", asset)
+	}
+	return 0, fmt.Errorf("cannot convert %s to USD: %w", asset, lastErr)
+}
+
+// ToUSD converts an amount of asset into USD.
+func (c *Converter) ToUSD(ctx context.Context, asset string, amount float64) (float64, error) {
+	price, err := c.USDPrice(ctx, asset)
+	if err != nil {
+		return 0, err
+	}
+	return amount * price, nil
+}
+
+// FromUSD converts a USD amount into units of asset.
+func (c *Converter) FromUSD(ctx context.Context, asset string, usd float64) (float64, error) {
+	price, err := c.USDPrice(ctx, asset)
+	if err != nil {
+		return 0, err
+	}
+	return usd / price, nil
+}
+
+// BalanceUSD fetches the collateral balance of ex in its own asset and converts it to USD.
+func (c *Converter) BalanceUSD(ctx context.Context, ex exchange.Exchange) (float64, error) {
+	asset := AssetOf(ex)
+	balance, err := ex.GetBalance(ctx, asset)
+	if err != nil {
+		return 0, err
+	}
+	return c.ToUSD(ctx, asset, balance)
+}
@@ -0,0 +1,92 @@
+package collateral
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/exchange"
+)
+
+func TestConverterUsesSourcesThenStablecoinDefaults(t *testing.T) {
+	prices, err := ParseStaticPrices([]string{"USDT=0.998", "ETH=3000"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c := NewConverter(prices)
+
+	cases := map[string]float64{"USD": 1, "USDT": 0.998, "USDC": 1, "eth": 3000}
+	for asset, want := range cases {
+		got, err := c.USDPrice(context.Background(), asset)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", asset, err)
+		}
+		if got != want {
+			t.Errorf("%s: expected %f, got %f", asset, want, got)
+		}
+	}
+
+	if _, err := c.USDPrice(context.Background(), "SOL"); err == nil {
+		t.Error("expected an error for an unpriced asset")
+	}
+
+	usd, _ := c.ToUSD(context.Background(), "ETH", 2)
+	if usd != 6000 {
+		t.Errorf("expected 6000 USD, got %f", usd)
+	}
+}
+
+func TestParseStaticPricesRejectsMalformedEntries(t *testing.T) {
+	if _, err := ParseStaticPrices([]string{"USDT"}); err == nil {
+		t.Error("expected an error for an entry without a price")
+	}
+}
+
+// balanceExchange is a minimal exchange holding a fixed balance of its collateral asset.
+type balanceExchange struct {
+	exchange.Exchange
+	name, asset string
+	balance     float64
+	err         error
+}
+
+func (b *balanceExchange) Name() string            { return b.name }
+func (b *balanceExchange) CollateralAsset() string { return b.asset }
+
+func (b *balanceExchange) GetBalance(context.Context, string) (float64, error) {
+	return b.balance, b.err
+}
+
+// summaryExchange also reports how much of its balance is free.
+type summaryExchange struct {
+	balanceExchange
+	free float64
+}
+
+func (s *summaryExchange) GetAccountSummary(context.Context) (*exchange.AccountSummary, error) {
+	return &exchange.AccountSummary{Asset: s.asset, Equity: s.balance, Free: s.free, Used: s.balance - s.free}, nil
+}
+
+func TestBalancesConvertEachVenueAndKeepFailures(t *testing.T) {
+	c := NewConverter(StaticPrices{"ETH": 3000})
+	balances := c.Balances(context.Background(), []exchange.Exchange{
+		&summaryExchange{balanceExchange{name: "A", asset: "USDT", balance: 1000}, 600},
+		&balanceExchange{name: "B", asset: "ETH", balance: 0.5},
+		&balanceExchange{name: "C", asset: "USDC", err: errors.New("unauthorized")},
+	})
+	if len(balances) != 3 {
+		t.Fatalf("expected a balance per exchange, got %+v", balances)
+	}
+	if a := balances[0]; a.EquityUSD != 1000 || a.Free == nil || *a.Free != 600 || *a.Used != 400 {
+		t.Errorf("unexpected summary balance %+v", a)
+	}
+	if b := balances[1]; b.EquityUSD != 1500 || b.Free != nil || b.Error != "" {
+		t.Errorf("unexpected plain balance %+v", b)
+	}
+	if c := balances[2]; c.Error != "unauthorized" || c.Asset != "USDC" {
+		t.Errorf("expected the failure to be kept, got %+v", c)
+	}
+	if total := TotalUSD(balances); total != 2500 {
+		t.Errorf("TotalUSD = %f, want 2500", total)
+	}
+}
@@ -0,0 +1,69 @@
+package collateral
+
+import (
+	"context"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/exchange"
+)
+
+// VenueBalance is the collateral of one exchange, in its own asset and in USD. Free and Used are
+// only known for exchanges that report an account summary.
+type VenueBalance struct {
+	Exchange  string   `json:"exchange"`
+	Asset     string   `json:"asset"`
+	Equity    float64  `json:"equity"`
+	Free      *float64 `json:"free,omitempty"`
+	Used      *float64 `json:"used,omitempty"`
+	EquityUSD float64  `json:"equityUsd"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// Balances fetches the collateral of every exchange, from its account summary if it reports one
+// and from GetBalance otherwise, and converts the equity to USD. Exchanges that fail have Error
+// set, so one unreachable venue doesn't hide the others.
+func (c *Converter) Balances(ctx context.Context, exchanges []exchange.Exchange) []VenueBalance {
+	balances := make([]VenueBalance, 0, len(exchanges))
+	for _, ex := range exchanges {
+		balance := VenueBalance{Exchange: ex.Name(), Asset: AssetOf(ex)}
+		if err := c.fill(ctx, ex, &balance); err != nil {
+			balance.Error = err.Error()
+		}
+		balances = append(balances, balance)
+	}
+	return balances
+}
+
+func (c *Converter) fill(ctx context.Context, ex exchange.Exchange, balance *VenueBalance) error {
+	if s, ok := ex.(exchange.AccountSummarizer); ok {
+		summary, err := s.GetAccountSummary(ctx)
+		if err != nil {
+			return err
+		}
+		if summary.Asset != "" {
+			balance.Asset = summary.Asset
+		}
+		balance.Equity = summary.Equity
+		balance.Free, balance.Used = &summary.Free, &summary.Used
+	} else {
+		equity, err := ex.GetBalance(ctx, balance.Asset)
+		if err != nil {
+			return err
+		}
+		balance.Equity = equity
+	}
+	usd, err := c.ToUSD(ctx, balance.Asset, balance.Equity)
+	if err != nil {
+		return err
+	}
+	balance.EquityUSD = usd
+	return nil
+}
+
+// TotalUSD sums the USD equity of the balances that were fetched.
+func TotalUSD(balances []VenueBalance) float64 {
+	total := 0.0
+	for _, b := range balances {
+		total += b.EquityUSD
+	}
+	return total
+}
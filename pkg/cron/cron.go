@@ -0,0 +1,133 @@
+// Package cron parses standard five-field cron expressions and finds the times they match.
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a parsed cron expression. Times are matched in UTC.
+type Schedule struct {
+	minutes, hours, days, months, weekdays uint64
+	// anyDay and anyWeekday record whether the day of month and day of week fields are "*":
+	// when both are restricted, a time matches if either does, as in standard cron.
+	anyDay, anyWeekday bool
+}
+
+// shortcuts are the named schedules accepted instead of five fields.
+var shortcuts = map[string]string{
+	"@hourly":   "0 * * * *",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@weekly":   "0 0 * * 0",
+	"@monthly":  "0 0 1 * *",
+}
+
+// Parse parses "MINUTE HOUR DAY-OF-MONTH MONTH DAY-OF-WEEK", e.g. "0 8 * * *" for 08:00 UTC
+// every day. Each field is "*", a value, a range "a-b" or a list of them, each optionally with a
+// step "/n". Days of the week run from 0 (Sunday) to 6; 7 is Sunday too. The shortcuts @hourly,
+// @daily, @midnight, @weekly and @monthly are accepted.
+func Parse(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if expanded, ok := shortcuts[spec]; ok {
+		spec = expanded
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return Schedule{}, fmt.Errorf("invalid cron expression %q, expected five fields: minute hour day-of-month month day-of-week", spec)
+	}
+	var s Schedule
+	var err error
+	if s.minutes, err = parseField(fields[0], 0, 59); err != nil {
+		return Schedule{}, fmt.Errorf("invalid minute in %q: %w", spec, err)
+	}
+	if s.hours, err = parseField(fields[1], 0, 23); err != nil {
+		return Schedule{}, fmt.Errorf("invalid hour in %q: %w", spec, err)
+	}
+	if s.days, err = parseField(fields[2], 1, 31); err != nil {
+		return Schedule{}, fmt.Errorf("invalid day of month in %q: %w", spec, err)
+	}
+	if s.months, err = parseField(fields[3], 1, 12); err != nil {
+		return Schedule{}, fmt.Errorf("invalid month in %q: %w", spec, err)
+	}
+	if s.weekdays, err = parseField(fields[4], 0, 7); err != nil {
+		return Schedule{}, fmt.Errorf("invalid day of week in %q: %w", spec, err)
+	}
+	if s.weekdays&(1<<7) != 0 {
+		s.weekdays |= 1
+	}
+	s.anyDay, s.anyWeekday = fields[2] == "*", fields[4] == "*"
+	return s, nil
+}
+
+// parseField returns the values field matches as a bit set.
+func parseField(field string, min, max int) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		rangeSpec, stepSpec, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepSpec)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step %q", stepSpec)
+			}
+			step = n
+		}
+		lo, hi := min, max
+		if rangeSpec != "*" {
+			from, to, isRange := strings.Cut(rangeSpec, "-")
+			var err error
+			if lo, err = strconv.Atoi(from); err != nil {
+				return 0, fmt.Errorf("invalid value %q", from)
+			}
+			hi = lo
+			if isRange {
+				if hi, err = strconv.Atoi(to); err != nil {
+					return 0, fmt.Errorf("invalid value %q", to)
+				}
+			} else if hasStep {
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("%q is outside %d-%d", part, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			set |= 1 << v
+		}
+	}
+	return set, nil
+}
+
+// Next returns the first time after t that the schedule matches, truncated to the minute. It
+// returns the zero time if none matches within five years, e.g. for February 30th.
+func (s Schedule) Next(t time.Time) time.Time {
+	t = t.UTC().Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case s.months&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+		case s.hours&(1<<uint(t.Hour())) == 0:
+			t = t.Truncate(time.Hour).Add(time.Hour)
+		case s.minutes&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func (s Schedule) dayMatches(t time.Time) bool {
+	day := s.days&(1<<uint(t.Day())) != 0
+	weekday := s.weekdays&(1<<uint(t.Weekday())) != 0
+	if !s.anyDay && !s.anyWeekday {
+		return day || weekday
+	}
+	return day && weekday
+}
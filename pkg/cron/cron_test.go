@@ -0,0 +1,39 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNext(t *testing.T) {
+	from := time.Date(2024, 3, 15, 10, 30, 0, 0, time.UTC) // a Friday
+	cases := map[string]time.Time{
+		"0 8 * * *":        time.Date(2024, 3, 16, 8, 0, 0, 0, time.UTC),
+		"@daily":           time.Date(2024, 3, 16, 0, 0, 0, 0, time.UTC),
+		"*/15 * * * *":     time.Date(2024, 3, 15, 10, 45, 0, 0, time.UTC),
+		"0 9-17/4 * * *":   time.Date(2024, 3, 15, 13, 0, 0, 0, time.UTC),
+		"0 0 * * 1":        time.Date(2024, 3, 18, 0, 0, 0, 0, time.UTC),
+		"0 0 1 * 1":        time.Date(2024, 3, 18, 0, 0, 0, 0, time.UTC),
+		"30 10 15 3 *":     time.Date(2025, 3, 15, 10, 30, 0, 0, time.UTC),
+		"0 12 29 2 *":      time.Date(2028, 2, 29, 12, 0, 0, 0, time.UTC),
+		"0 0 * * 7":        time.Date(2024, 3, 17, 0, 0, 0, 0, time.UTC),
+		"0,45 10,11 * * *": time.Date(2024, 3, 15, 10, 45, 0, 0, time.UTC),
+	}
+	for spec, want := range cases {
+		schedule, err := Parse(spec)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", spec, err)
+		}
+		if got := schedule.Next(from); !got.Equal(want) {
+			t.Errorf("%q: Next = %s, want %s", spec, got, want)
+		}
+	}
+}
+
+func TestParseRejectsInvalidExpressions(t *testing.T) {
+	for _, spec := range []string{"", "0 8 * *", "60 * * * *", "* 24 * * *", "0 0 0 * *", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("Parse(%q): expected an error", spec)
+		}
+	}
+}
@@ -0,0 +1,444 @@
+package exchange
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Account is one of the accounts or subaccounts an Accounts trades a venue through.
+type Account struct {
+	Name     string
+	Exchange Exchange
+	// Markets are traded on this account only. None means any market not assigned to another.
+	Markets []string
+}
+
+// AccountBalancer is implemented by exchanges that trade through several accounts, to report the
+// balance of each, keyed by account name.
+type AccountBalancer interface {
+	GetAccountBalances(ctx context.Context, asset string) (map[string]float64, error)
+}
+
+// Accounts trades one venue through several accounts, e.g. two Extended vaults, so positions can
+// grow beyond the limits of one account and markets can be kept apart for risk. Market data comes
+// from the first account. An order goes to the account its market is assigned to, otherwise to the
+// account already holding the market, otherwise to the one with the most collateral; balances,
+// positions and funding payments are summed over the accounts.
+type Accounts struct {
+	accounts []Account
+	assigned map[string]int
+
+	mu sync.Mutex
+	// orders holds the account each order was placed on, by order ID.
+	orders map[string]int
+}
+
+// NewAccounts wraps accounts of the same venue, the first of which serves the market data.
+func NewAccounts(accounts []Account) (*Accounts, error) {
+	if len(accounts) == 0 {
+		return nil, errors.New("no accounts")
+	}
+	a := &Accounts{accounts: accounts, assigned: make(map[string]int), orders: make(map[string]int)}
+	for i, account := range accounts {
+		for _, market := range account.Markets {
+			if other, ok := a.assigned[market]; ok {
+				return nil, fmt.Errorf("%s is assigned to both the %s and %s accounts", market, accounts[other].Name, account.Name)
+			}
+			a.assigned[market] = i
+		}
+	}
+	return a, nil
+}
+
+// Primary returns the first account's exchange, which serves the market data.
+func (a *Accounts) Primary() Exchange {
+	return a.accounts[0].Exchange
+}
+
+func (a *Accounts) Name() string {
+	return a.accounts[0].Exchange.Name()
+}
+
+func (a *Accounts) SetTestnet(testnet bool) {
+	for _, account := range a.accounts {
+		account.Exchange.SetTestnet(testnet)
+	}
+}
+
+// FundingInterval forwards to the first account.
+func (a *Accounts) FundingInterval() time.Duration {
+	return FundingIntervalOf(a.accounts[0].Exchange)
+}
+
+// balanceAsset is the asset the accounts' balances are compared and checked in.
+func (a *Accounts) balanceAsset() string {
+	if asset := a.CollateralAsset(); asset != "" {
+		return asset
+	}
+	return "USD"
+}
+
+// CollateralAsset forwards to the first account.
+func (a *Accounts) CollateralAsset() string {
+	if asseter, ok := a.accounts[0].Exchange.(interface{ CollateralAsset() string }); ok {
+		return asseter.CollateralAsset()
+	}
+	return ""
+}
+
+func (a *Accounts) GetFundingRates(ctx context.Context) ([]*FundingRate, error) {
+	return a.accounts[0].Exchange.GetFundingRates(ctx)
+}
+
+func (a *Accounts) GetOrderbook(ctx context.Context, market string) (*Orderbook, error) {
+	return a.accounts[0].Exchange.GetOrderbook(ctx, market)
+}
+
+func (a *Accounts) GetMarkPrice(ctx context.Context, market string) (float64, error) {
+	return a.accounts[0].Exchange.GetMarkPrice(ctx, market)
+}
+
+// tradable returns the accounts that may trade market: the one it is assigned to, or every
+// account without assigned markets.
+func (a *Accounts) tradable(market string) []int {
+	if i, ok := a.assigned[market]; ok {
+		return []int{i}
+	}
+	var indexes []int
+	for i, account := range a.accounts {
+		if len(account.Markets) == 0 {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
+// route returns the account a new order on market goes to.
+func (a *Accounts) route(ctx context.Context, market string) (int, error) {
+	candidates := a.tradable(market)
+	switch len(candidates) {
+	case 0:
+		return 0, fmt.Errorf("no %s account may trade %s", a.Name(), market)
+	case 1:
+		return candidates[0], nil
+	}
+	// Adding to a position keeps it on one account, so it is closed in one order.
+	for _, i := range candidates {
+		positions, err := a.accounts[i].Exchange.GetPositions(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("%s account: %w", a.accounts[i].Name, err)
+		}
+		for _, p := range positions {
+			if p.Market == market {
+				return i, nil
+			}
+		}
+	}
+	best, bestBalance := -1, 0.0
+	for _, i := range candidates {
+		balance, err := a.accounts[i].Exchange.GetBalance(ctx, a.balanceAsset())
+		if err != nil {
+			continue
+		}
+		if best < 0 || balance > bestBalance {
+			best, bestBalance = i, balance
+		}
+	}
+	if best < 0 {
+		return 0, fmt.Errorf("no %s account reports its balance", a.Name())
+	}
+	return best, nil
+}
+
+func (a *Accounts) PlaceOrder(ctx context.Context, market string, side OrderSide, orderType OrderType, amount, price decimal.Decimal) (*Order, error) {
+	i, err := a.route(ctx, market)
+	if err != nil {
+		return nil, err
+	}
+	order, err := a.accounts[i].Exchange.PlaceOrder(ctx, market, side, orderType, amount, price)
+	a.remember(order, i)
+	return order, err
+}
+
+// remember records the account order was placed on.
+func (a *Accounts) remember(order *Order, i int) {
+	if order == nil || order.ID == "" {
+		return
+	}
+	a.mu.Lock()
+	a.orders[order.ID] = i
+	a.mu.Unlock()
+}
+
+// placedOn returns the account an order was placed on, or the first account that may trade its
+// market if it wasn't placed through a.
+func (a *Accounts) placedOn(orderID, market string) Exchange {
+	a.mu.Lock()
+	i, ok := a.orders[orderID]
+	a.mu.Unlock()
+	if !ok {
+		if candidates := a.tradable(market); len(candidates) > 0 {
+			i = candidates[0]
+		}
+	}
+	return a.accounts[i].Exchange
+}
+
+func (a *Accounts) GetOrderStatus(ctx context.Context, orderID string, market string) (*Order, error) {
+	return a.placedOn(orderID, market).GetOrderStatus(ctx, orderID, market)
+}
+
+func (a *Accounts) CancelOrder(ctx context.Context, orderID string, market string) error {
+	return a.placedOn(orderID, market).CancelOrder(ctx, orderID, market)
+}
+
+// GetBalance returns the balance of asset summed over the accounts.
+func (a *Accounts) GetBalance(ctx context.Context, asset string) (float64, error) {
+	balances, err := a.GetAccountBalances(ctx, asset)
+	if err != nil {
+		return 0, err
+	}
+	total := 0.0
+	for _, balance := range balances {
+		total += balance
+	}
+	return total, nil
+}
+
+// GetAccountBalances returns the balance of asset on each account.
+func (a *Accounts) GetAccountBalances(ctx context.Context, asset string) (map[string]float64, error) {
+	balances := make(map[string]float64, len(a.accounts))
+	for _, account := range a.accounts {
+		balance, err := account.Exchange.GetBalance(ctx, asset)
+		if err != nil {
+			return nil, fmt.Errorf("%s account: %w", account.Name, err)
+		}
+		balances[account.Name] = balance
+	}
+	return balances, nil
+}
+
+// GetPositions returns the positions of every account, those on the same market and side merged.
+func (a *Accounts) GetPositions(ctx context.Context) ([]Position, error) {
+	var merged []Position
+	index := make(map[string]int)
+	for _, account := range a.accounts {
+		positions, err := account.Exchange.GetPositions(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("%s account: %w", account.Name, err)
+		}
+		for _, p := range positions {
+			key := p.Market + ":" + string(p.Side)
+			i, ok := index[key]
+			if !ok {
+				index[key] = len(merged)
+				merged = append(merged, p)
+				continue
+			}
+			m := &merged[i]
+			if size := m.Size + p.Size; size > 0 {
+				m.EntryPrice = (m.EntryPrice*m.Size + p.EntryPrice*p.Size) / size
+				m.Size = size
+			}
+		}
+	}
+	return merged, nil
+}
+
+// ClosePosition closes amount of the side position on market, taking it from the accounts that
+// hold it in turn. The order returned sums the fills of the closes.
+func (a *Accounts) ClosePosition(ctx context.Context, market string, side OrderSide, amount decimal.Decimal) (*Order, error) {
+	var closed *Order
+	var errs []error
+	remaining := amount
+	for i, account := range a.accounts {
+		if !remaining.IsPositive() {
+			break
+		}
+		positions, err := account.Exchange.GetPositions(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s account: %w", account.Name, err))
+			continue
+		}
+		for _, p := range positions {
+			if p.Market != market || p.Side != side || p.Size <= 0 {
+				continue
+			}
+			size := decimal.Min(decimal.NewFromFloat(p.Size), remaining)
+			order, err := account.Exchange.ClosePosition(ctx, market, side, size)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s account: %w", account.Name, err))
+				break
+			}
+			remaining = remaining.Sub(size)
+			a.remember(order, i)
+			closed = mergeOrders(closed, order)
+			break
+		}
+	}
+	if closed == nil && len(errs) == 0 {
+		// No account reports the position, e.g. a venue that doesn't list positions: close it
+		// where it would have been opened.
+		i, err := a.route(ctx, market)
+		if err != nil {
+			return nil, err
+		}
+		order, err := a.accounts[i].Exchange.ClosePosition(ctx, market, side, amount)
+		a.remember(order, i)
+		return order, err
+	}
+	return closed, errors.Join(errs...)
+}
+
+// mergeOrders adds the amounts of order to total, so closes on several accounts are reported as
+// one order under the ID of the first.
+func mergeOrders(total, order *Order) *Order {
+	if order == nil {
+		return total
+	}
+	if total == nil {
+		copied := *order
+		return &copied
+	}
+	total.Amount = total.Amount.Add(order.Amount)
+	total.Filled = total.Filled.Add(order.Filled)
+	total.Fee += order.Fee
+	return total
+}
+
+// SetMarginMode applies mode on every account that may trade market.
+func (a *Accounts) SetMarginMode(ctx context.Context, market string, mode MarginMode) error {
+	for _, i := range a.tradable(market) {
+		setter, ok := a.accounts[i].Exchange.(MarginModeSetter)
+		if !ok {
+			return fmt.Errorf("%s does not support selecting the margin mode", a.Name())
+		}
+		if err := setter.SetMarginMode(ctx, market, mode); err != nil {
+			return fmt.Errorf("%s account: %w", a.accounts[i].Name, err)
+		}
+	}
+	return nil
+}
+
+// SetLeverage applies leverage on every account that may trade market.
+func (a *Accounts) SetLeverage(ctx context.Context, market string, leverage float64) error {
+	for _, i := range a.tradable(market) {
+		setter, ok := a.accounts[i].Exchange.(LeverageSetter)
+		if !ok {
+			return fmt.Errorf("%s does not support setting the leverage", a.Name())
+		}
+		if err := setter.SetLeverage(ctx, market, leverage); err != nil {
+			return fmt.Errorf("%s account: %w", a.accounts[i].Name, err)
+		}
+	}
+	return nil
+}
+
+// GetMarketInfo forwards to the first account.
+func (a *Accounts) GetMarketInfo(ctx context.Context, market string) (*MarketInfo, error) {
+	provider, ok := a.accounts[0].Exchange.(MarketInfoProvider)
+	if !ok {
+		return nil, ErrMarketInfoUnsupported
+	}
+	return provider.GetMarketInfo(ctx, market)
+}
+
+// GetMarketStats forwards to the first account.
+func (a *Accounts) GetMarketStats(ctx context.Context, markets []string) (map[string]MarketStats, error) {
+	statser, ok := a.accounts[0].Exchange.(MarketStatser)
+	if !ok {
+		return nil, fmt.Errorf("%s does not report market statistics", a.Name())
+	}
+	return statser.GetMarketStats(ctx, markets)
+}
+
+// Stream forwards to the first account, whose market data the other accounts share.
+func (a *Accounts) Stream(markets []string, handler StreamHandler, stop <-chan struct{}) error {
+	streamer, ok := a.accounts[0].Exchange.(StreamingExchange)
+	if !ok {
+		return ErrStreamingUnsupported
+	}
+	return streamer.Stream(markets, handler, stop)
+}
+
+// GetFundingPayments returns the payments on market of every account, oldest first.
+func (a *Accounts) GetFundingPayments(ctx context.Context, market string, since time.Time) ([]FundingPayment, error) {
+	var payments []FundingPayment
+	for _, account := range a.accounts {
+		reporter, ok := account.Exchange.(FundingPaymentReporter)
+		if !ok {
+			return nil, ErrFundingPaymentsUnsupported
+		}
+		paid, err := reporter.GetFundingPayments(ctx, market, since)
+		if err != nil {
+			return nil, fmt.Errorf("%s account: %w", account.Name, err)
+		}
+		payments = append(payments, paid...)
+	}
+	sort.SliceStable(payments, func(i, j int) bool { return payments[i].Time.Before(payments[j].Time) })
+	return payments, nil
+}
+
+// GetForcedCloses returns the forced closes on market of every account, oldest first.
+func (a *Accounts) GetForcedCloses(ctx context.Context, market string, since time.Time) ([]ForcedClose, error) {
+	var closes []ForcedClose
+	for _, account := range a.accounts {
+		reporter, ok := account.Exchange.(LiquidationReporter)
+		if !ok {
+			return nil, ErrForcedClosesUnsupported
+		}
+		forced, err := reporter.GetForcedCloses(ctx, market, since)
+		if err != nil {
+			return nil, fmt.Errorf("%s account: %w", account.Name, err)
+		}
+		closes = append(closes, forced...)
+	}
+	sort.SliceStable(closes, func(i, j int) bool { return closes[i].Time.Before(closes[j].Time) })
+	return closes, nil
+}
+
+// GetPositionRisk returns the risk of the position on market of the first account holding one.
+func (a *Accounts) GetPositionRisk(ctx context.Context, market string) (*PositionRisk, error) {
+	var errs []error
+	for _, account := range a.accounts {
+		reporter, ok := account.Exchange.(PositionRiskReporter)
+		if !ok {
+			return nil, ErrPositionRiskUnsupported
+		}
+		risk, err := reporter.GetPositionRisk(ctx, market)
+		if err == nil {
+			return risk, nil
+		}
+		errs = append(errs, fmt.Errorf("%s account: %w", account.Name, err))
+	}
+	return nil, errors.Join(errs...)
+}
+
+// Ping checks the credentials of every account, with GetBalance where the venue has no Ping.
+func (a *Accounts) Ping(ctx context.Context) error {
+	var failed []string
+	var errs []error
+	for _, account := range a.accounts {
+		var err error
+		if pinger, ok := account.Exchange.(Pinger); ok {
+			err = pinger.Ping(ctx)
+		} else {
+			_, err = account.Exchange.GetBalance(ctx, a.balanceAsset())
+		}
+		if err != nil {
+			failed = append(failed, account.Name)
+			errs = append(errs, fmt.Errorf("%s account: %w", account.Name, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("accounts %s: %w", strings.Join(failed, ", "), errors.Join(errs...))
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package exchange
+
+import (
+	"context"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+type accountExchange struct {
+	fakeExchange
+	balance   float64
+	positions []Position
+	closed    float64
+}
+
+func (a *accountExchange) GetBalance(context.Context, string) (float64, error) {
+	return a.balance, nil
+}
+func (a *accountExchange) GetPositions(context.Context) ([]Position, error) {
+	return a.positions, nil
+}
+func (a *accountExchange) ClosePosition(ctx context.Context, market string, side OrderSide, amount decimal.Decimal) (*Order, error) {
+	a.closed += amount.InexactFloat64()
+	return &Order{ID: "close", Market: market, Amount: amount, Filled: amount, Status: "FILLED"}, nil
+}
+
+func TestAccountsRouteOrders(t *testing.T) {
+	main := &accountExchange{balance: 100}
+	vault := &accountExchange{balance: 500}
+	segregated := &accountExchange{balance: 50}
+	accounts, err := NewAccounts([]Account{
+		{Name: "default", Exchange: main},
+		{Name: "vault", Exchange: vault},
+		{Name: "segregated", Exchange: segregated, Markets: []string{"DOGE-USD"}},
+	})
+	if err != nil {
+		t.Fatalf("NewAccounts: %v", err)
+	}
+	ctx := context.Background()
+
+	if _, err := accounts.PlaceOrder(ctx, "DOGE-USD", Buy, Market, decimal.NewFromInt(10), decimal.Zero); err != nil || len(segregated.placed) != 1 {
+		t.Errorf("expected the assigned market on its account, got %v and %v", segregated.placed, err)
+	}
+	if _, err := accounts.PlaceOrder(ctx, "BTC-USD", Buy, Market, decimal.NewFromInt(1), decimal.Zero); err != nil || len(vault.placed) != 1 {
+		t.Errorf("expected an unassigned market on the account with the most collateral, got %v and %v", vault.placed, err)
+	}
+	main.positions = []Position{{Market: "ETH-USD", Side: Buy, Size: 2, EntryPrice: 100}}
+	if _, err := accounts.PlaceOrder(ctx, "ETH-USD", Buy, Market, decimal.NewFromInt(1), decimal.Zero); err != nil || len(main.placed) != 1 {
+		t.Errorf("expected an order to add to the account holding the market, got %v and %v", main.placed, err)
+	}
+
+	if balance, err := accounts.GetBalance(ctx, "USD"); err != nil || balance != 650 {
+		t.Errorf("GetBalance = %v, %v, want the sum 650", balance, err)
+	}
+	balances, err := accounts.GetAccountBalances(ctx, "USD")
+	if err != nil || balances["vault"] != 500 || balances["segregated"] != 50 {
+		t.Errorf("GetAccountBalances = %v, %v", balances, err)
+	}
+
+	vault.positions = []Position{{Market: "ETH-USD", Side: Buy, Size: 2, EntryPrice: 200}}
+	positions, err := accounts.GetPositions(ctx)
+	if err != nil || len(positions) != 1 || positions[0].Size != 4 || positions[0].EntryPrice != 150 {
+		t.Errorf("expected the accounts' positions merged, got %+v and %v", positions, err)
+	}
+	order, err := accounts.ClosePosition(ctx, "ETH-USD", Buy, decimal.NewFromInt(3))
+	if err != nil || !order.Filled.Equal(decimal.NewFromInt(3)) || main.closed != 2 || vault.closed != 1 {
+		t.Errorf("expected the close taken from both accounts, got %+v, %v, %v and %v", order, err, main.closed, vault.closed)
+	}
+
+	if _, err := NewAccounts([]Account{{Name: "a", Exchange: main, Markets: []string{"BTC-USD"}}, {Name: "b", Exchange: vault, Markets: []string{"BTC-USD"}}}); err == nil {
+		t.Error("expected a market assigned to two accounts to be rejected")
+	}
+}
+
+func TestClientsUnwrapsAccountsAndPaper(t *testing.T) {
+	main, vault := &accountExchange{}, &accountExchange{}
+	accounts, err := NewAccounts([]Account{{Name: "default", Exchange: main}, {Name: "vault", Exchange: vault}})
+	if err != nil {
+		t.Fatalf("NewAccounts: %v", err)
+	}
+	clients := Clients(NewPaper(accounts, PaperConfig{Balance: 1000}))
+	if len(clients) != 2 || clients[0] != Exchange(main) || clients[1] != Exchange(vault) {
+		t.Errorf("Clients = %v, want both accounts", clients)
+	}
+}
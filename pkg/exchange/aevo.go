@@ -0,0 +1,634 @@
+package exchange
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/httpclient"
+)
+
+const (
+	AevoMainnetBaseURL = "https://api.aevo.xyz"
+	AevoTestnetBaseURL = "https://api-testnet.aevo.xyz"
+)
+
+const (
+	// aevoMarketSuffix turns a market name into the name of its perpetual, e.g. ETH-PERP.
+	aevoMarketSuffix = "-PERP"
+	// aevoOrderDecimals is the precision of amounts and prices in signed orders.
+	aevoOrderDecimals = 6
+	// aevoMarketSlippage bounds the price of market orders, which Aevo sends as immediate-or-cancel
+	// limit orders, relative to the mark price.
+	aevoMarketSlippage = 0.01
+)
+
+// ErrAevoSignerRequired is returned when placing an Aevo order without a signer, since every
+// order carries an EIP-712 signature of the account's signing key.
+var ErrAevoSignerRequired = errors.New("aevo orders need AEVO_SIGNER_CMD to sign")
+
+// AevoOrderMessage is the EIP-712 Order message of an Aevo order. LimitPrice and Amount are
+// integers scaled by 10^6 and Timestamp is in seconds.
+type AevoOrderMessage struct {
+	Maker      string `json:"maker"`
+	IsBuy      bool   `json:"isBuy"`
+	LimitPrice string `json:"limitPrice"`
+	Amount     string `json:"amount"`
+	Salt       string `json:"salt"`
+	Instrument string `json:"instrument"`
+	Timestamp  int64  `json:"timestamp"`
+}
+
+// AevoSigner signs Aevo orders with the account's signing key and returns the signature as hex.
+type AevoSigner interface {
+	SignOrder(ctx context.Context, order AevoOrderMessage) (string, error)
+}
+
+// AevoCommandSigner delegates EIP-712 signing to an external program, e.g. a wrapper around
+// eth_account's sign_typed_data, since the module has no Ethereum signing dependency. The program
+// receives {"signing_key", "chain_id", "order"} as JSON on stdin and must print the signature on
+// stdout.
+type AevoCommandSigner struct {
+	command    []string
+	signingKey string
+	chainID    int
+}
+
+// NewAevoCommandSigner creates a signer running command, split on whitespace.
+func NewAevoCommandSigner(command, signingKey string, testnet bool) (*AevoCommandSigner, error) {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return nil, errors.New("empty Aevo signer command")
+	}
+	chainID := 1
+	if testnet {
+		chainID = 11155111
+	}
+	return &AevoCommandSigner{command: args, signingKey: signingKey, chainID: chainID}, nil
+}
+
+// SignOrder runs the signer program for one order.
+func (c *AevoCommandSigner) SignOrder(ctx context.Context, order AevoOrderMessage) (string, error) {
+	input, err := json.Marshal(struct {
+		SigningKey string           `json:"signing_key"`
+		ChainID    int              `json:"chain_id"`
+		Order      AevoOrderMessage `json:"order"`
+	}{c.signingKey, c.chainID, order})
+	if err != nil {
+		return "", err
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, c.command[0], c.command[1:]...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("aevo signer failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	signature := strings.TrimSpace(stdout.String())
+	if _, err := hex.DecodeString(strings.TrimPrefix(signature, "0x")); err != nil || signature == "" {
+		return "", fmt.Errorf("aevo signer returned an invalid signature: %q", signature)
+	}
+	return signature, nil
+}
+
+// Aevo is the implementation for Aevo perpetuals. Markets such as "ETH-USD" trade as the
+// "ETH-PERP" instrument, margined in USDC. Requests are authenticated with an API key and
+// secret; orders are additionally signed by the account's signing key through an AevoSigner.
+type Aevo struct {
+	client    *http.Client
+	account   string
+	apiKey    string
+	secretKey string
+	baseURL   string
+	testnet   bool
+	signer    AevoSigner
+
+	mu          sync.Mutex
+	instruments map[string]aevoInstrument
+}
+
+// NewAevo creates a new Aevo client for the account wallet address and its API credentials.
+func NewAevo(account, apiKey, secretKey string, testnet bool) *Aevo {
+	baseURL := AevoMainnetBaseURL
+	if testnet {
+		baseURL = AevoTestnetBaseURL
+	}
+	return &Aevo{
+		client:    &http.Client{Timeout: 10 * time.Second},
+		account:   account,
+		apiKey:    apiKey,
+		secretKey: secretKey,
+		baseURL:   baseURL,
+		testnet:   testnet,
+	}
+}
+
+// SetSigner sets the signer that orders are signed with.
+func (a *Aevo) SetSigner(signer AevoSigner) {
+	a.signer = signer
+}
+
+// SetRateLimiter makes every Aevo API call wait for limiter.
+func (a *Aevo) SetRateLimiter(limiter *httpclient.Limiter) {
+	a.client = limiter.Client(a.client)
+}
+
+func (a *Aevo) Name() string {
+	return "Aevo"
+}
+
+func (a *Aevo) CollateralAsset() string {
+	return "USDC"
+}
+
+func (a *Aevo) SetTestnet(testnet bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.testnet = testnet
+	if testnet {
+		a.baseURL = AevoTestnetBaseURL
+	} else {
+		a.baseURL = AevoMainnetBaseURL
+	}
+	a.instruments = nil
+}
+
+// SetTransport replaces the HTTP transport used for REST requests, e.g. with a recorder in tests.
+func (a *Aevo) SetTransport(rt http.RoundTripper) {
+	a.client.Transport = rt
+}
+
+// Symbol converts "ETH-USD" into "ETH-PERP".
+func (a *Aevo) Symbol(market string) string {
+	base, _, _ := strings.Cut(market, "-")
+	return strings.ToUpper(base) + aevoMarketSuffix
+}
+
+// market converts a perpetual name back into a market name. It reports false for options.
+func (a *Aevo) market(symbol string) (string, bool) {
+	base, ok := strings.CutSuffix(symbol, aevoMarketSuffix)
+	if !ok || base == "" {
+		return "", false
+	}
+	return base + "-USD", true
+}
+
+// GetMarketInfo returns the trading rules of market.
+func (a *Aevo) GetMarketInfo(ctx context.Context, market string) (*MarketInfo, error) {
+	rules, err := a.instrument(ctx, market)
+	if err != nil {
+		return nil, err
+	}
+	return &MarketInfo{
+		Market:             market,
+		TickSize:           rules.priceStep.step,
+		LotSize:            rules.amountStep.step,
+		MinNotional:        rules.minOrderValue,
+		MaxLeverage:        rules.maxLeverage,
+		ContractMultiplier: 1,
+	}, nil
+}
+
+// aevoInstrument holds the ID and trading rules of a perpetual.
+type aevoInstrument struct {
+	id            string
+	amountStep    decimalStep
+	priceStep     decimalStep
+	minOrderValue float64
+	maxLeverage   float64
+}
+
+// loadInstruments returns the active perpetuals, keyed by name. They are fetched on first use
+// and cached.
+func (a *Aevo) loadInstruments(ctx context.Context) (map[string]aevoInstrument, error) {
+	a.mu.Lock()
+	instruments := a.instruments
+	a.mu.Unlock()
+	if instruments != nil {
+		return instruments, nil
+	}
+	var response []struct {
+		InstrumentID   string `json:"instrument_id"`
+		InstrumentName string `json:"instrument_name"`
+		AmountStep     string `json:"amount_step"`
+		PriceStep      string `json:"price_step"`
+		MinOrderValue  string `json:"min_order_value"`
+		MaxLeverage    string `json:"max_leverage"`
+		IsActive       bool   `json:"is_active"`
+	}
+	params := url.Values{"instrument_type": {"PERPETUAL"}}
+	if err := a.sendRequest(ctx, "GET", "/markets", params, nil, false, &response); err != nil {
+		return nil, fmt.Errorf("failed to get markets from Aevo: %w", err)
+	}
+	instruments = make(map[string]aevoInstrument, len(response))
+	for _, m := range response {
+		if !m.IsActive {
+			continue
+		}
+		instruments[m.InstrumentName] = aevoInstrument{
+			id:            m.InstrumentID,
+			amountStep:    parseDecimalStep(m.AmountStep),
+			priceStep:     parseDecimalStep(m.PriceStep),
+			minOrderValue: parseRule(m.MinOrderValue),
+			maxLeverage:   parseRule(m.MaxLeverage),
+		}
+	}
+	a.mu.Lock()
+	a.instruments = instruments
+	a.mu.Unlock()
+	return instruments, nil
+}
+
+// instrument returns the ID and trading rules of market.
+func (a *Aevo) instrument(ctx context.Context, market string) (aevoInstrument, error) {
+	instruments, err := a.loadInstruments(ctx)
+	if err != nil {
+		return aevoInstrument{}, err
+	}
+	instrument, ok := instruments[a.Symbol(market)]
+	if !ok {
+		return aevoInstrument{}, fmt.Errorf("market %s not found on Aevo", market)
+	}
+	return instrument, nil
+}
+
+// GetFundingRates fetches the hourly funding rate of every active perpetual. The funding
+// endpoint serves one instrument at a time, so the rates are fetched concurrently.
+func (a *Aevo) GetFundingRates(ctx context.Context) ([]*FundingRate, error) {
+	instruments, err := a.loadInstruments(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var markets []string
+	for name := range instruments {
+		if market, ok := a.market(name); ok {
+			markets = append(markets, market)
+		}
+	}
+	sort.Strings(markets)
+
+	fundingRates, err := fetchRates(markets, func(market string) (*FundingRate, error) {
+		var response struct {
+			FundingRate string `json:"funding_rate"`
+			NextEpoch   string `json:"next_epoch"`
+		}
+		if err := a.sendRequest(ctx, "GET", "/funding", url.Values{"instrument_name": {a.Symbol(market)}}, nil, false, &response); err != nil {
+			return nil, err
+		}
+		rate, err := decimal.NewFromString(response.FundingRate)
+		if err != nil {
+			return nil, nil
+		}
+		// next_epoch is in nanoseconds.
+		next, _ := strconv.ParseInt(response.NextEpoch, 10, 64)
+		return &FundingRate{Market: market, Rate: rate, NextTime: next / int64(time.Second)}, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get funding rates from Aevo: %w", err)
+	}
+	return fundingRates, nil
+}
+
+// GetMarkPrice returns the mark price of market.
+func (a *Aevo) GetMarkPrice(ctx context.Context, market string) (float64, error) {
+	var response struct {
+		MarkPrice string `json:"mark_price"`
+	}
+	if err := a.sendRequest(ctx, "GET", "/instrument/"+a.Symbol(market), nil, nil, false, &response); err != nil {
+		return 0, fmt.Errorf("failed to get mark price from Aevo: %w", err)
+	}
+	price, err := strconv.ParseFloat(response.MarkPrice, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse mark price for %s from Aevo: %w", market, err)
+	}
+	return price, nil
+}
+
+// GetOrderbook returns the order book of market.
+func (a *Aevo) GetOrderbook(ctx context.Context, market string) (*Orderbook, error) {
+	// Levels are [price, amount, implied volatility]; the volatility is only set for options.
+	var response struct {
+		Bids [][]string `json:"bids"`
+		Asks [][]string `json:"asks"`
+	}
+	if err := a.sendRequest(ctx, "GET", "/orderbook", url.Values{"instrument_name": {a.Symbol(market)}}, nil, false, &response); err != nil {
+		return nil, fmt.Errorf("failed to get orderbook from Aevo: %w", err)
+	}
+	bids, err := aevoLevels(response.Bids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s orderbook from Aevo: %w", market, err)
+	}
+	asks, err := aevoLevels(response.Asks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s orderbook from Aevo: %w", market, err)
+	}
+	book := &Orderbook{Market: market, Bids: bids, Asks: asks}
+	book.sortLevels()
+	return book, nil
+}
+
+func aevoLevels(levels [][]string) ([]PriceLevel, error) {
+	parsed := make([]PriceLevel, 0, len(levels))
+	for _, l := range levels {
+		if len(l) < 2 {
+			return nil, fmt.Errorf("malformed level %v", l)
+		}
+		level, err := parseLevel(l[0], l[1])
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, level)
+	}
+	return parsed, nil
+}
+
+// AevoOrder is an order as reported by the order endpoints. Aevo reports an order as opened,
+// partial, filled, cancelled or expired.
+type AevoOrder struct {
+	OrderID          string `json:"order_id"`
+	Side             string `json:"side"`
+	OrderType        string `json:"order_type"`
+	Amount           string `json:"amount"`
+	Filled           string `json:"filled"`
+	Price            string `json:"price"`
+	AvgPrice         string `json:"avg_price"`
+	OrderStatus      string `json:"order_status"`
+	CreatedTimestamp string `json:"created_timestamp"`
+}
+
+// order converts the response into an Order on market, with the status upper-cased.
+func (o AevoOrder) order(market string) *Order {
+	price := parseDecimal(o.AvgPrice)
+	if price.IsZero() {
+		price = parseDecimal(o.Price)
+	}
+	created, _ := strconv.ParseInt(o.CreatedTimestamp, 10, 64)
+	return &Order{
+		ID:        o.OrderID,
+		Market:    market,
+		Side:      OrderSide(strings.ToUpper(o.Side)),
+		Type:      OrderType(strings.ToUpper(o.OrderType)),
+		Price:     price,
+		Amount:    parseDecimal(o.Amount),
+		Filled:    parseDecimal(o.Filled),
+		Status:    strings.ToUpper(o.OrderStatus),
+		Timestamp: created / int64(time.Second),
+	}
+}
+
+// PlaceOrder signs and sends an order, rounding amount down to the market's amount step and a
+// limit price to its price step. Limit orders rest until cancelled; market orders are sent as
+// immediate-or-cancel limit orders at worst 1% from the mark price.
+func (a *Aevo) PlaceOrder(ctx context.Context, market string, side OrderSide, orderType OrderType, amount, price decimal.Decimal) (*Order, error) {
+	return a.placeOrder(ctx, market, side, orderType, amount, price, false)
+}
+
+func (a *Aevo) placeOrder(ctx context.Context, market string, side OrderSide, orderType OrderType, amount, price decimal.Decimal, reduceOnly bool) (*Order, error) {
+	if a.signer == nil {
+		return nil, ErrAevoSignerRequired
+	}
+	instrument, err := a.instrument(ctx, market)
+	if err != nil {
+		return nil, err
+	}
+	size := instrument.amountStep.floor(amount)
+	if !size.IsPositive() {
+		return nil, fmt.Errorf("order amount %s is below the Aevo amount step %s for %s", amount, instrument.amountStep, market)
+	}
+	timeInForce := "GTC"
+	if orderType == Market {
+		mark, err := a.GetMarkPrice(ctx, market)
+		if err != nil {
+			return nil, err
+		}
+		price = decimal.NewFromFloat(mark * (1 + aevoMarketSlippage))
+		if side == Sell {
+			price = decimal.NewFromFloat(mark * (1 - aevoMarketSlippage))
+		}
+		timeInForce = "IOC"
+	}
+	price = instrument.priceStep.round(price)
+
+	message := AevoOrderMessage{
+		Maker:      a.account,
+		IsBuy:      side == Buy,
+		LimitPrice: aevoOrderAmount(price),
+		Amount:     aevoOrderAmount(size),
+		Salt:       strconv.FormatInt(rand.Int63(), 10),
+		Instrument: instrument.id,
+		Timestamp:  time.Now().Unix(),
+	}
+	signature, err := a.signer.SignOrder(ctx, message)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign order for Aevo: %w", err)
+	}
+	instrumentID, _ := strconv.Atoi(instrument.id)
+	request := map[string]interface{}{
+		"instrument":    instrumentID,
+		"maker":         message.Maker,
+		"is_buy":        message.IsBuy,
+		"amount":        message.Amount,
+		"limit_price":   message.LimitPrice,
+		"salt":          message.Salt,
+		"signature":     signature,
+		"timestamp":     strconv.FormatInt(message.Timestamp, 10),
+		"post_only":     false,
+		"reduce_only":   reduceOnly,
+		"time_in_force": timeInForce,
+	}
+	var response AevoOrder
+	if err := a.sendRequest(ctx, "POST", "/orders", nil, request, true, &response); err != nil {
+		return nil, fmt.Errorf("failed to place order on Aevo: %w", err)
+	}
+	return response.order(market), nil
+}
+
+// aevoOrderAmount scales an amount or price to the fixed-point integer signed in orders.
+func aevoOrderAmount(v decimal.Decimal) string {
+	return v.Shift(aevoOrderDecimals).Round(0).String()
+}
+
+func (a *Aevo) GetOrderStatus(ctx context.Context, orderID string, market string) (*Order, error) {
+	var response AevoOrder
+	if err := a.sendRequest(ctx, "GET", "/orders/"+url.PathEscape(orderID), nil, nil, true, &response); err != nil {
+		return nil, fmt.Errorf("failed to get order status from Aevo: %w", err)
+	}
+	return response.order(market), nil
+}
+
+func (a *Aevo) CancelOrder(ctx context.Context, orderID string, market string) error {
+	if err := a.sendRequest(ctx, "DELETE", "/orders/"+url.PathEscape(orderID), nil, nil, true, nil); err != nil {
+		return fmt.Errorf("failed to cancel order on Aevo: %w", err)
+	}
+	return nil
+}
+
+// ClosePosition reduces the position in market with a reduce-only market order on the opposite
+// side, so it can never flip the position.
+func (a *Aevo) ClosePosition(ctx context.Context, market string, side OrderSide, amount decimal.Decimal) (*Order, error) {
+	closeSide := Sell
+	if side == Sell {
+		closeSide = Buy
+	}
+	return a.placeOrder(ctx, market, closeSide, Market, amount, decimal.Zero, true)
+}
+
+// AevoPosition is a position as listed with the account. Side is buy or sell.
+type AevoPosition struct {
+	InstrumentName   string `json:"instrument_name"`
+	InstrumentType   string `json:"instrument_type"`
+	Side             string `json:"side"`
+	Amount           string `json:"amount"`
+	AvgEntryPrice    string `json:"avg_entry_price"`
+	MarkPrice        string `json:"mark_price"`
+	LiquidationPrice string `json:"liquidation_price"`
+}
+
+// aevoAccount is the account as returned by the account endpoint.
+type aevoAccount struct {
+	Equity    string         `json:"equity"`
+	Positions []AevoPosition `json:"positions"`
+}
+
+func (a *Aevo) fetchAccount(ctx context.Context) (*aevoAccount, error) {
+	var response aevoAccount
+	if err := a.sendRequest(ctx, "GET", "/account", nil, nil, true, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// GetBalance returns the equity of the account, its USDC collateral plus the unrealized PnL of
+// the open positions. Aevo margins every market in USDC, so asset is only checked against it.
+func (a *Aevo) GetBalance(ctx context.Context, asset string) (float64, error) {
+	if asset != "" && !strings.EqualFold(asset, a.CollateralAsset()) {
+		return 0, nil
+	}
+	account, err := a.fetchAccount(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get balance from Aevo: %w", err)
+	}
+	equity, err := strconv.ParseFloat(account.Equity, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse balance float from Aevo: %w", err)
+	}
+	return equity, nil
+}
+
+// perpPositions returns the open perpetual positions of account, keyed by market.
+func (a *Aevo) perpPositions(account *aevoAccount) map[string]AevoPosition {
+	open := make(map[string]AevoPosition)
+	for _, position := range account.Positions {
+		market, ok := a.market(position.InstrumentName)
+		if !ok || position.InstrumentType != "PERPETUAL" {
+			continue
+		}
+		if amount, _ := strconv.ParseFloat(position.Amount, 64); amount != 0 {
+			open[market] = position
+		}
+	}
+	return open
+}
+
+// side returns Buy for longs and Sell for shorts.
+func (p AevoPosition) side() OrderSide {
+	if strings.EqualFold(p.Side, "sell") {
+		return Sell
+	}
+	return Buy
+}
+
+// GetPositions fetches the open perpetual positions of the account.
+func (a *Aevo) GetPositions(ctx context.Context) ([]Position, error) {
+	account, err := a.fetchAccount(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get positions from Aevo: %w", err)
+	}
+	var positions []Position
+	for market, position := range a.perpPositions(account) {
+		amount, err := strconv.ParseFloat(position.Amount, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse position size for %s from Aevo: %w", market, err)
+		}
+		entry, _ := strconv.ParseFloat(position.AvgEntryPrice, 64)
+		positions = append(positions, Position{Market: market, Side: position.side(), Size: math.Abs(amount), EntryPrice: entry})
+	}
+	sort.Slice(positions, func(i, j int) bool { return positions[i].Market < positions[j].Market })
+	return positions, nil
+}
+
+// GetPositionRisk fetches the liquidation price of the open position in market. Aevo accounts
+// are cross-margined, so the position is backed by the account equity.
+func (a *Aevo) GetPositionRisk(ctx context.Context, market string) (*PositionRisk, error) {
+	account, err := a.fetchAccount(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the %s position from Aevo: %w", market, err)
+	}
+	position, ok := a.perpPositions(account)[market]
+	if !ok {
+		return nil, fmt.Errorf("no open %s position on Aevo", market)
+	}
+	amount, err := strconv.ParseFloat(position.Amount, 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse position size for %s from Aevo: %w", market, err)
+	}
+	mark, _ := strconv.ParseFloat(position.MarkPrice, 64)
+	liquidation, _ := strconv.ParseFloat(position.LiquidationPrice, 64)
+	equity, _ := strconv.ParseFloat(account.Equity, 64)
+	return &PositionRisk{Market: market, Side: position.side(), Size: math.Abs(amount), MarkPrice: mark, LiquidationPrice: liquidation, Margin: equity}, nil
+}
+
+// sendRequest sends a request to the Aevo API, with params in the query string and body as JSON.
+// Private requests are signed with an HMAC-SHA256 of the API key, a nanosecond timestamp, the
+// method, path and body.
+func (a *Aevo) sendRequest(ctx context.Context, method, endpoint string, params url.Values, body interface{}, private bool, out interface{}) error {
+	var payload []byte
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		payload = encoded
+		reader = bytes.NewReader(encoded)
+	}
+	target := a.baseURL + endpoint
+	if len(params) > 0 {
+		target += "?" + params.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if private {
+		timestamp := strconv.FormatInt(time.Now().UnixNano(), 10)
+		mac := hmac.New(sha256.New, []byte(a.secretKey))
+		mac.Write([]byte(strings.Join([]string{a.apiKey, timestamp, method, endpoint, string(payload)}, ",")))
+		req.Header.Set("AEVO-KEY", a.apiKey)
+		req.Header.Set("AEVO-TIMESTAMP", timestamp)
+		req.Header.Set("AEVO-SIGNATURE", hex.EncodeToString(mac.Sum(nil)))
+	}
+	return doJSON(a.client, req, out)
+}
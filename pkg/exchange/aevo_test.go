@@ -0,0 +1,137 @@
+package exchange
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+// recordingAevoSigner returns a fixed signature and keeps the order it was asked to sign.
+type recordingAevoSigner struct {
+	order AevoOrderMessage
+}
+
+func (s *recordingAevoSigner) SignOrder(ctx context.Context, order AevoOrderMessage) (string, error) {
+	s.order = order
+	return "0xabcd", nil
+}
+
+const aevoMarkets = `[
+	{"instrument_id":"1","instrument_name":"ETH-PERP","instrument_type":"PERPETUAL","amount_step":"0.01","price_step":"0.01","is_active":true},
+	{"instrument_id":"3396","instrument_name":"BTC-PERP","instrument_type":"PERPETUAL","amount_step":"0.001","price_step":"0.5","is_active":true},
+	{"instrument_id":"77","instrument_name":"OLD-PERP","instrument_type":"PERPETUAL","amount_step":"1","price_step":"0.0001","is_active":false}]`
+
+func TestAevoFundingRates(t *testing.T) {
+	api := newFakeAPI(t)
+	api.respond("GET", "/markets", http.StatusOK, aevoMarkets)
+	api.handle("GET", "/funding", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("instrument_name") {
+		case "ETH-PERP":
+			_, _ = w.Write([]byte(`{"funding_rate":"0.000015","next_epoch":"1700003600000000000"}`))
+		case "BTC-PERP":
+			_, _ = w.Write([]byte(`{"funding_rate":"-0.000004","next_epoch":"1700003600000000000"}`))
+		default:
+			t.Errorf("unexpected funding request for %q", r.URL.Query().Get("instrument_name"))
+		}
+	})
+	ex := newTestAevo(api, nil)
+
+	rates, err := ex.GetFundingRates(context.Background())
+	if err != nil {
+		t.Fatalf("GetFundingRates: %v", err)
+	}
+	if len(rates) != 2 {
+		t.Fatalf("expected the two active perpetuals, got %d rates", len(rates))
+	}
+	if rates[0].Market != "BTC-USD" || !rates[0].Rate.Equal(decimal.NewFromFloat(-0.000004)) || rates[0].NextTime != 1700003600 || rates[1].Market != "ETH-USD" || !rates[1].Rate.Equal(decimal.NewFromFloat(0.000015)) {
+		t.Errorf("unexpected rates %+v %+v", rates[0], rates[1])
+	}
+	if got := api.lastRequest("/markets").URL.Query().Get("instrument_type"); got != "PERPETUAL" {
+		t.Errorf("instrument_type = %q, want PERPETUAL", got)
+	}
+}
+
+func TestAevoSignedOrders(t *testing.T) {
+	api := newFakeAPI(t)
+	api.respond("GET", "/markets", http.StatusOK, aevoMarkets)
+	api.respond("GET", "/instrument/ETH-PERP", http.StatusOK, `{"instrument_name":"ETH-PERP","mark_price":"3500"}`)
+	var body []byte
+	var headers http.Header
+	api.handle("POST", "/orders", func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		headers = r.Header.Clone()
+		_, _ = w.Write([]byte(`{"order_id":"0xorder","side":"buy","order_type":"limit","amount":"1.23","filled":"0","price":"3499.99","order_status":"opened","created_timestamp":"1700000000000000000"}`))
+	})
+	signer := &recordingAevoSigner{}
+	ex := newTestAevo(api, signer)
+
+	order, err := ex.PlaceOrder(context.Background(), "ETH-USD", Buy, Limit, decimal.NewFromFloat(1.234), decimal.NewFromFloat(3499.987))
+	if err != nil {
+		t.Fatalf("PlaceOrder: %v", err)
+	}
+	if order.ID != "0xorder" || order.Side != Buy || order.Status != "OPENED" || order.Timestamp != 1700000000 {
+		t.Errorf("unexpected order %+v", order)
+	}
+	if o := signer.order; o.Maker != "0xmaker" || !o.IsBuy || o.Amount != "1230000" || o.LimitPrice != "3499990000" || o.Instrument != "1" {
+		t.Errorf("unexpected signed order %+v", o)
+	}
+	var request map[string]interface{}
+	if err := json.Unmarshal(body, &request); err != nil {
+		t.Fatalf("order body is not JSON: %v", err)
+	}
+	if request["instrument"] != float64(1) || request["signature"] != "0xabcd" || request["amount"] != "1230000" ||
+		request["salt"] != signer.order.Salt || request["time_in_force"] != "GTC" || request["reduce_only"] != false {
+		t.Errorf("unexpected order request %s", body)
+	}
+	mac := hmac.New(sha256.New, []byte("test-secret"))
+	mac.Write([]byte("test-key," + headers.Get("AEVO-TIMESTAMP") + ",POST,/orders," + string(body)))
+	if headers.Get("AEVO-KEY") != "test-key" || headers.Get("AEVO-SIGNATURE") != hex.EncodeToString(mac.Sum(nil)) {
+		t.Errorf("expected an HMAC-SHA256 signature over the key, timestamp, method, path and body, got %v", headers)
+	}
+
+	if _, err := ex.ClosePosition(context.Background(), "ETH-USD", Buy, decimal.NewFromFloat(1.23)); err != nil {
+		t.Fatalf("ClosePosition: %v", err)
+	}
+	if err := json.Unmarshal(body, &request); err != nil || request["is_buy"] != false || request["reduce_only"] != true ||
+		request["time_in_force"] != "IOC" || request["limit_price"] != "3465000000" {
+		t.Errorf("expected a reduce-only IOC sell at most 1%% below the mark, got %s", body)
+	}
+
+	if _, err := newTestAevo(api, nil).PlaceOrder(context.Background(), "ETH-USD", Buy, Limit, decimal.NewFromInt(1), decimal.NewFromInt(3500)); !errors.Is(err, ErrAevoSignerRequired) {
+		t.Errorf("expected ErrAevoSignerRequired without a signer, got %v", err)
+	}
+}
+
+func TestAevoPositions(t *testing.T) {
+	api := newFakeAPI(t)
+	api.respond("GET", "/account", http.StatusOK, `{"equity":"2500.5","positions":[
+		{"instrument_name":"ETH-PERP","instrument_type":"PERPETUAL","side":"sell","amount":"2","avg_entry_price":"3510","mark_price":"3500","liquidation_price":"4200"},
+		{"instrument_name":"ETH-30DEC22-1600-C","instrument_type":"OPTION","side":"buy","amount":"1","avg_entry_price":"50"}]}`)
+	ex := newTestAevo(api, nil)
+
+	positions, err := ex.GetPositions(context.Background())
+	if err != nil {
+		t.Fatalf("GetPositions: %v", err)
+	}
+	if len(positions) != 1 || positions[0] != (Position{Market: "ETH-USD", Side: Sell, Size: 2, EntryPrice: 3510}) {
+		t.Errorf("expected only the ETH perpetual short, got %+v", positions)
+	}
+	risk, err := ex.GetPositionRisk(context.Background(), "ETH-USD")
+	if err != nil {
+		t.Fatalf("GetPositionRisk: %v", err)
+	}
+	if distance, ok := risk.LiquidationDistance(); risk.Margin != 2500.5 || !ok || distance != 0.2 {
+		t.Errorf("unexpected risk %+v (distance %f)", risk, distance)
+	}
+	if balance, err := ex.GetBalance(context.Background(), "USDC"); err != nil || balance != 2500.5 {
+		t.Errorf("GetBalance = %f, %v, want the equity 2500.5", balance, err)
+	}
+}
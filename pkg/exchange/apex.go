@@ -0,0 +1,655 @@
+package exchange
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/httpclient"
+)
+
+const (
+	ApexMainnetBaseURL = "https://omni.apex.exchange/api"
+	ApexTestnetBaseURL = "https://qa.omni.apex.exchange/api"
+)
+
+const (
+	// apexQuoteAsset is the asset ApeX Omni perpetuals are quoted and margined in.
+	apexQuoteAsset = "USDT"
+	// apexMarketSlippage bounds the worst price of market orders, which ApeX requires, relative
+	// to the mark price.
+	apexMarketSlippage = 0.01
+	// apexOrderLifetime is how long a signed order stays valid. Resting orders are cancelled by
+	// the strategy long before.
+	apexOrderLifetime = 28 * 24 * time.Hour
+)
+
+// ErrApexSignerRequired is returned when placing an ApeX order without a signer, since every
+// order carries a zkLink signature.
+var ErrApexSignerRequired = errors.New("apex orders need APEX_SIGNER_CMD to sign")
+
+// ApexZKOrder is the part of an ApeX Omni order covered by its zkLink signature. Size and price
+// are decimal strings, fee rates are fractions and Expiration is in milliseconds.
+type ApexZKOrder struct {
+	AccountID    string `json:"account_id"`
+	SubAccountID string `json:"sub_account_id"`
+	PairID       string `json:"pair_id"`
+	Side         string `json:"side"`
+	Size         string `json:"size"`
+	Price        string `json:"price"`
+	TakerFeeRate string `json:"taker_fee_rate"`
+	MakerFeeRate string `json:"maker_fee_rate"`
+	ClientID     string `json:"client_id"`
+	Expiration   int64  `json:"expiration"`
+}
+
+// ApexSigner signs ApeX Omni orders with the account's zkLink key and returns the signature.
+type ApexSigner interface {
+	SignOrder(ctx context.Context, order ApexZKOrder) (string, error)
+}
+
+// ApexCommandSigner delegates order signing to an external program, e.g. a wrapper around the
+// zkLink signer of ApeX's apexomni SDK. The program receives {"seeds", "order"} as JSON on stdin
+// and must print the signature on stdout.
+type ApexCommandSigner struct {
+	command []string
+	seeds   string
+}
+
+// NewApexCommandSigner creates a signer running command, split on whitespace, for the zkLink
+// seeds of the account.
+func NewApexCommandSigner(command, seeds string) (*ApexCommandSigner, error) {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return nil, errors.New("empty ApeX signer command")
+	}
+	return &ApexCommandSigner{command: args, seeds: seeds}, nil
+}
+
+// SignOrder runs the signer program for one order.
+func (c *ApexCommandSigner) SignOrder(ctx context.Context, order ApexZKOrder) (string, error) {
+	input, err := json.Marshal(struct {
+		Seeds string      `json:"seeds"`
+		Order ApexZKOrder `json:"order"`
+	}{c.seeds, order})
+	if err != nil {
+		return "", err
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, c.command[0], c.command[1:]...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("apex signer failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	signature := strings.TrimSpace(stdout.String())
+	if signature == "" {
+		return "", errors.New("apex signer returned an empty signature")
+	}
+	return signature, nil
+}
+
+// Apex is the implementation for ApeX Omni perpetuals. Markets such as "BTC-USD" trade as the
+// "BTC-USDT" contract, margined in USDT. Requests are authenticated with an API key, secret and
+// passphrase; orders are additionally signed with the account's zkLink key by an ApexSigner.
+type Apex struct {
+	client     *http.Client
+	apiKey     string
+	secretKey  string
+	passphrase string
+	baseURL    string
+	testnet    bool
+	signer     ApexSigner
+
+	mu      sync.Mutex
+	symbols map[string]apexSymbol
+	account *apexAccount
+}
+
+// NewApex creates a new ApeX Omni client for the given API credentials.
+func NewApex(apiKey, secretKey, passphrase string, testnet bool) *Apex {
+	baseURL := ApexMainnetBaseURL
+	if testnet {
+		baseURL = ApexTestnetBaseURL
+	}
+	return &Apex{
+		client:     &http.Client{Timeout: 10 * time.Second},
+		apiKey:     apiKey,
+		secretKey:  secretKey,
+		passphrase: passphrase,
+		baseURL:    baseURL,
+		testnet:    testnet,
+	}
+}
+
+// SetSigner sets the signer that orders are signed with.
+func (a *Apex) SetSigner(signer ApexSigner) {
+	a.signer = signer
+}
+
+// SetRateLimiter makes every ApeX API call wait for limiter.
+func (a *Apex) SetRateLimiter(limiter *httpclient.Limiter) {
+	a.client = limiter.Client(a.client)
+}
+
+func (a *Apex) Name() string {
+	return "ApeX"
+}
+
+func (a *Apex) CollateralAsset() string {
+	return apexQuoteAsset
+}
+
+func (a *Apex) SetTestnet(testnet bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.testnet = testnet
+	if testnet {
+		a.baseURL = ApexTestnetBaseURL
+	} else {
+		a.baseURL = ApexMainnetBaseURL
+	}
+	a.symbols, a.account = nil, nil
+}
+
+// SetTransport replaces the HTTP transport used for REST requests, e.g. with a recorder in tests.
+func (a *Apex) SetTransport(rt http.RoundTripper) {
+	a.client.Transport = rt
+}
+
+// Symbol converts "BTC-USD" into the order symbol "BTC-USDT".
+func (a *Apex) Symbol(market string) string {
+	base, _, _ := strings.Cut(market, "-")
+	return strings.ToUpper(base) + "-" + apexQuoteAsset
+}
+
+// tickerSymbol converts "BTC-USD" into the market data symbol "BTCUSDT".
+func (a *Apex) tickerSymbol(market string) string {
+	return strings.Replace(a.Symbol(market), "-", "", 1)
+}
+
+// market converts an order symbol back into a market name. It reports false for contracts
+// quoted in another asset.
+func (a *Apex) market(symbol string) (string, bool) {
+	base, ok := strings.CutSuffix(symbol, "-"+apexQuoteAsset)
+	if !ok || base == "" {
+		return "", false
+	}
+	return base + "-USD", true
+}
+
+// GetMarketInfo returns the trading rules of market.
+func (a *Apex) GetMarketInfo(ctx context.Context, market string) (*MarketInfo, error) {
+	rules, err := a.symbol(ctx, market)
+	if err != nil {
+		return nil, err
+	}
+	return &MarketInfo{
+		Market:             market,
+		TickSize:           rules.tickSize.step,
+		LotSize:            rules.stepSize.step,
+		MinSize:            rules.minSize,
+		MaxLeverage:        rules.maxLeverage,
+		ContractMultiplier: 1,
+	}, nil
+}
+
+// apexSymbol holds the pair ID and trading rules of a perpetual.
+type apexSymbol struct {
+	pairID      string
+	stepSize    decimalStep
+	tickSize    decimalStep
+	minSize     decimal.Decimal
+	maxLeverage float64
+}
+
+// loadSymbols returns the tradable perpetuals, keyed by order symbol. They are fetched on first
+// use and cached.
+func (a *Apex) loadSymbols(ctx context.Context) (map[string]apexSymbol, error) {
+	a.mu.Lock()
+	symbols := a.symbols
+	a.mu.Unlock()
+	if symbols != nil {
+		return symbols, nil
+	}
+	var response struct {
+		ContractConfig struct {
+			PerpetualContract []struct {
+				Symbol       string `json:"symbol"`
+				L2PairID     string `json:"l2PairId"`
+				StepSize     string `json:"stepSize"`
+				TickSize     string `json:"tickSize"`
+				MinOrderSize string `json:"minOrderSize"`
+				MaxLeverage  string `json:"maxLeverage"`
+				EnableTrade  bool   `json:"enableTrade"`
+			} `json:"perpetualContract"`
+		} `json:"contractConfig"`
+	}
+	if err := a.sendRequest(ctx, "GET", "/v3/symbols", nil, false, &response); err != nil {
+		return nil, fmt.Errorf("failed to get symbols from ApeX: %w", err)
+	}
+	symbols = make(map[string]apexSymbol)
+	for _, s := range response.ContractConfig.PerpetualContract {
+		if !s.EnableTrade {
+			continue
+		}
+		symbols[s.Symbol] = apexSymbol{
+			pairID:      s.L2PairID,
+			stepSize:    parseDecimalStep(s.StepSize),
+			tickSize:    parseDecimalStep(s.TickSize),
+			minSize:     parseDecimal(s.MinOrderSize),
+			maxLeverage: parseRule(s.MaxLeverage),
+		}
+	}
+	a.mu.Lock()
+	a.symbols = symbols
+	a.mu.Unlock()
+	return symbols, nil
+}
+
+// symbol returns the pair ID and trading rules of market.
+func (a *Apex) symbol(ctx context.Context, market string) (apexSymbol, error) {
+	symbols, err := a.loadSymbols(ctx)
+	if err != nil {
+		return apexSymbol{}, err
+	}
+	s, ok := symbols[a.Symbol(market)]
+	if !ok {
+		return apexSymbol{}, fmt.Errorf("market %s not found on ApeX", market)
+	}
+	return s, nil
+}
+
+// ApexTicker is the mark price and funding of a perpetual. FundingRate is the current hourly
+// rate and NextFundingTime is an RFC 3339 time.
+type ApexTicker struct {
+	Symbol          string `json:"symbol"`
+	MarkPrice       string `json:"markPrice"`
+	FundingRate     string `json:"fundingRate"`
+	NextFundingTime string `json:"nextFundingTime"`
+}
+
+func (a *Apex) ticker(ctx context.Context, market string) (*ApexTicker, error) {
+	var response []ApexTicker
+	if err := a.sendRequest(ctx, "GET", "/v3/ticker", url.Values{"symbol": {a.tickerSymbol(market)}}, false, &response); err != nil {
+		return nil, err
+	}
+	if len(response) == 0 {
+		return nil, fmt.Errorf("market %s not found on ApeX", market)
+	}
+	return &response[0], nil
+}
+
+// GetFundingRates fetches the hourly funding rate of every tradable perpetual. The ticker
+// endpoint serves one symbol at a time, so the tickers are fetched concurrently.
+func (a *Apex) GetFundingRates(ctx context.Context) ([]*FundingRate, error) {
+	symbols, err := a.loadSymbols(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var markets []string
+	for symbol := range symbols {
+		if market, ok := a.market(symbol); ok {
+			markets = append(markets, market)
+		}
+	}
+	sort.Strings(markets)
+
+	fundingRates, err := fetchRates(markets, func(market string) (*FundingRate, error) {
+		ticker, err := a.ticker(ctx, market)
+		if err != nil {
+			return nil, err
+		}
+		rate, err := decimal.NewFromString(ticker.FundingRate)
+		if err != nil {
+			return nil, nil
+		}
+		var next int64
+		if t, err := time.Parse(time.RFC3339, ticker.NextFundingTime); err == nil {
+			next = t.Unix()
+		}
+		return &FundingRate{Market: market, Rate: rate, NextTime: next}, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get funding rates from ApeX: %w", err)
+	}
+	return fundingRates, nil
+}
+
+// GetMarkPrice returns the mark price of market.
+func (a *Apex) GetMarkPrice(ctx context.Context, market string) (float64, error) {
+	ticker, err := a.ticker(ctx, market)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get mark price from ApeX: %w", err)
+	}
+	price, err := strconv.ParseFloat(ticker.MarkPrice, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse mark price for %s from ApeX: %w", market, err)
+	}
+	return price, nil
+}
+
+// GetOrderbook returns the order book of market.
+func (a *Apex) GetOrderbook(ctx context.Context, market string) (*Orderbook, error) {
+	var response struct {
+		Bids [][2]string `json:"b"`
+		Asks [][2]string `json:"a"`
+	}
+	params := url.Values{"symbol": {a.tickerSymbol(market)}, "limit": {"100"}}
+	if err := a.sendRequest(ctx, "GET", "/v3/depth", params, false, &response); err != nil {
+		return nil, fmt.Errorf("failed to get orderbook from ApeX: %w", err)
+	}
+	bids, err := parseLevels(response.Bids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s orderbook from ApeX: %w", market, err)
+	}
+	asks, err := parseLevels(response.Asks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s orderbook from ApeX: %w", market, err)
+	}
+	book := &Orderbook{Market: market, Bids: bids, Asks: asks}
+	book.sortLevels()
+	return book, nil
+}
+
+// ApexPosition is an open position as listed with the account. Side is LONG or SHORT.
+type ApexPosition struct {
+	Symbol     string `json:"symbol"`
+	Side       string `json:"side"`
+	Size       string `json:"size"`
+	EntryPrice string `json:"entryPrice"`
+}
+
+// apexAccount is the account as returned by the account endpoint: the zkLink IDs and fee rates
+// signed into orders, and the open positions.
+type apexAccount struct {
+	SpotAccount struct {
+		ZKAccountID         string `json:"zkAccountId"`
+		DefaultSubAccountID string `json:"defaultSubAccountId"`
+	} `json:"spotAccount"`
+	ContractAccount struct {
+		TakerFeeRate string `json:"takerFeeRate"`
+		MakerFeeRate string `json:"makerFeeRate"`
+	} `json:"contractAccount"`
+	Positions []ApexPosition `json:"positions"`
+}
+
+func (a *Apex) fetchAccount(ctx context.Context) (*apexAccount, error) {
+	var response apexAccount
+	if err := a.sendRequest(ctx, "GET", "/v3/account", nil, true, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// orderAccount returns the account IDs and fee rates signed into orders. They are fetched on
+// first use and cached.
+func (a *Apex) orderAccount(ctx context.Context) (*apexAccount, error) {
+	a.mu.Lock()
+	account := a.account
+	a.mu.Unlock()
+	if account != nil {
+		return account, nil
+	}
+	account, err := a.fetchAccount(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account from ApeX: %w", err)
+	}
+	a.mu.Lock()
+	a.account = account
+	a.mu.Unlock()
+	return account, nil
+}
+
+// ApexOrder is an order as reported by the order endpoints.
+type ApexOrder struct {
+	ID                 string `json:"id"`
+	Symbol             string `json:"symbol"`
+	Side               string `json:"side"`
+	Type               string `json:"type"`
+	Size               string `json:"size"`
+	Price              string `json:"price"`
+	CumSuccessFillSize string `json:"cumSuccessFillSize"`
+	Status             string `json:"status"`
+	CreatedAt          int64  `json:"createdAt"`
+}
+
+// order converts the response into an Order on market. ApeX reports PENDING, OPEN, FILLED,
+// CANCELED and UNTRIGGERED; a pending order is accepted but not yet on the book, so it is
+// reported as NEW.
+func (o ApexOrder) order(market string) *Order {
+	status := o.Status
+	if status == "PENDING" {
+		status = "NEW"
+	}
+	return &Order{
+		ID:        o.ID,
+		Market:    market,
+		Side:      OrderSide(o.Side),
+		Type:      OrderType(o.Type),
+		Price:     parseDecimal(o.Price),
+		Amount:    parseDecimal(o.Size),
+		Filled:    parseDecimal(o.CumSuccessFillSize),
+		Status:    status,
+		Timestamp: o.CreatedAt / 1000,
+	}
+}
+
+// PlaceOrder signs and sends an order, rounding amount down to the market's step size and a limit
+// price to its tick size. Limit orders rest until cancelled; market orders fill immediately or are
+// cancelled, at worst 1% from the mark price.
+func (a *Apex) PlaceOrder(ctx context.Context, market string, side OrderSide, orderType OrderType, amount, price decimal.Decimal) (*Order, error) {
+	return a.placeOrder(ctx, market, side, orderType, amount, price, false)
+}
+
+func (a *Apex) placeOrder(ctx context.Context, market string, side OrderSide, orderType OrderType, amount, price decimal.Decimal, reduceOnly bool) (*Order, error) {
+	if a.signer == nil {
+		return nil, ErrApexSignerRequired
+	}
+	rules, err := a.symbol(ctx, market)
+	if err != nil {
+		return nil, err
+	}
+	size := rules.stepSize.floor(amount)
+	if !size.IsPositive() {
+		return nil, fmt.Errorf("order amount %s is below the ApeX step size %s for %s", amount, rules.stepSize, market)
+	}
+	timeInForce := "GOOD_TIL_CANCEL"
+	if orderType == Market {
+		mark, err := a.GetMarkPrice(ctx, market)
+		if err != nil {
+			return nil, err
+		}
+		price = decimal.NewFromFloat(mark * (1 + apexMarketSlippage))
+		if side == Sell {
+			price = decimal.NewFromFloat(mark * (1 - apexMarketSlippage))
+		}
+		timeInForce = "IMMEDIATE_OR_CANCEL"
+	}
+	price = rules.tickSize.round(price)
+	account, err := a.orderAccount(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	clientID := strconv.FormatInt(time.Now().UnixNano(), 10)
+	expiration := time.Now().Add(apexOrderLifetime).UnixMilli()
+	signature, err := a.signer.SignOrder(ctx, ApexZKOrder{
+		AccountID:    account.SpotAccount.ZKAccountID,
+		SubAccountID: account.SpotAccount.DefaultSubAccountID,
+		PairID:       rules.pairID,
+		Side:         string(side),
+		Size:         rules.stepSize.format(size),
+		Price:        rules.tickSize.format(price),
+		TakerFeeRate: account.ContractAccount.TakerFeeRate,
+		MakerFeeRate: account.ContractAccount.MakerFeeRate,
+		ClientID:     clientID,
+		Expiration:   expiration,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign order for ApeX: %w", err)
+	}
+
+	params := url.Values{
+		"symbol":       {a.Symbol(market)},
+		"side":         {string(side)},
+		"type":         {string(orderType)},
+		"size":         {rules.stepSize.format(size)},
+		"price":        {rules.tickSize.format(price)},
+		"limitFeeRate": {account.ContractAccount.TakerFeeRate},
+		"timeInForce":  {timeInForce},
+		"reduceOnly":   {strconv.FormatBool(reduceOnly)},
+		"clientId":     {clientID},
+		"expiration":   {strconv.FormatInt(expiration, 10)},
+		"signature":    {signature},
+	}
+	var response ApexOrder
+	if err := a.sendRequest(ctx, "POST", "/v3/order", params, true, &response); err != nil {
+		return nil, fmt.Errorf("failed to place order on ApeX: %w", err)
+	}
+	return response.order(market), nil
+}
+
+func (a *Apex) GetOrderStatus(ctx context.Context, orderID string, market string) (*Order, error) {
+	var response ApexOrder
+	if err := a.sendRequest(ctx, "GET", "/v3/order", url.Values{"id": {orderID}}, true, &response); err != nil {
+		return nil, fmt.Errorf("failed to get order status from ApeX: %w", err)
+	}
+	return response.order(market), nil
+}
+
+func (a *Apex) CancelOrder(ctx context.Context, orderID string, market string) error {
+	if err := a.sendRequest(ctx, "POST", "/v3/delete-order", url.Values{"id": {orderID}}, true, nil); err != nil {
+		return fmt.Errorf("failed to cancel order on ApeX: %w", err)
+	}
+	return nil
+}
+
+// ClosePosition reduces the position in market with a reduce-only market order on the opposite
+// side, so it can never flip the position.
+func (a *Apex) ClosePosition(ctx context.Context, market string, side OrderSide, amount decimal.Decimal) (*Order, error) {
+	closeSide := Sell
+	if side == Sell {
+		closeSide = Buy
+	}
+	return a.placeOrder(ctx, market, closeSide, Market, amount, decimal.Zero, true)
+}
+
+// GetBalance returns the total equity of the account, its USDT collateral plus the unrealized
+// PnL of the open positions. ApeX margins every market in USDT, so asset is only checked against
+// it.
+func (a *Apex) GetBalance(ctx context.Context, asset string) (float64, error) {
+	if asset != "" && !strings.EqualFold(asset, a.CollateralAsset()) {
+		return 0, nil
+	}
+	var response struct {
+		TotalEquityValue string `json:"totalEquityValue"`
+	}
+	if err := a.sendRequest(ctx, "GET", "/v3/account-balance", nil, true, &response); err != nil {
+		return 0, fmt.Errorf("failed to get balance from ApeX: %w", err)
+	}
+	equity, err := strconv.ParseFloat(response.TotalEquityValue, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse balance float from ApeX: %w", err)
+	}
+	return equity, nil
+}
+
+// GetPositions fetches the open positions of the account.
+func (a *Apex) GetPositions(ctx context.Context) ([]Position, error) {
+	account, err := a.fetchAccount(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get positions from ApeX: %w", err)
+	}
+	var positions []Position
+	for _, position := range account.Positions {
+		market, ok := a.market(position.Symbol)
+		if !ok {
+			continue
+		}
+		size, err := strconv.ParseFloat(position.Size, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse position size for %s from ApeX: %w", market, err)
+		}
+		if size == 0 {
+			continue
+		}
+		side := Buy
+		if position.Side == "SHORT" {
+			side = Sell
+		}
+		entry, _ := strconv.ParseFloat(position.EntryPrice, 64)
+		positions = append(positions, Position{Market: market, Side: side, Size: math.Abs(size), EntryPrice: entry})
+	}
+	sort.Slice(positions, func(i, j int) bool { return positions[i].Market < positions[j].Market })
+	return positions, nil
+}
+
+// sendRequest sends a request to the ApeX API, with params in the query string of GET requests
+// and form-encoded in the body of the others. Private requests are signed with an HMAC-SHA256 of
+// the timestamp, method, path and body, keyed with the base64 of the API secret.
+func (a *Apex) sendRequest(ctx context.Context, method, endpoint string, params url.Values, private bool, out interface{}) error {
+	path := "/api" + endpoint
+	target := a.baseURL + endpoint
+	var body string
+	if method == "GET" {
+		if len(params) > 0 {
+			query := "?" + params.Encode()
+			path += query
+			target += query
+		}
+	} else {
+		body = params.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, strings.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if method != "GET" {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	if private {
+		timestamp := strconv.FormatInt(time.Now().UnixMilli(), 10)
+		mac := hmac.New(sha256.New, []byte(base64.StdEncoding.EncodeToString([]byte(a.secretKey))))
+		mac.Write([]byte(timestamp + method + path + body))
+		req.Header.Set("APEX-API-KEY", a.apiKey)
+		req.Header.Set("APEX-PASSPHRASE", a.passphrase)
+		req.Header.Set("APEX-TIMESTAMP", timestamp)
+		req.Header.Set("APEX-SIGNATURE", base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+	}
+
+	var response struct {
+		Code int             `json:"code"`
+		Msg  string          `json:"msg"`
+		Data json.RawMessage `json:"data"`
+	}
+	if err := doJSON(a.client, req, &response); err != nil {
+		return err
+	}
+	if response.Code != 0 {
+		return fmt.Errorf("API error: code %d - %s", response.Code, response.Msg)
+	}
+	if out == nil || len(response.Data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(response.Data, out)
+}
@@ -0,0 +1,141 @@
+package exchange
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+// recordingApexSigner returns a fixed signature and keeps the order it was asked to sign.
+type recordingApexSigner struct {
+	order ApexZKOrder
+}
+
+func (s *recordingApexSigner) SignOrder(ctx context.Context, order ApexZKOrder) (string, error) {
+	s.order = order
+	return "0xzksig", nil
+}
+
+const apexSymbols = `{"data":{"contractConfig":{"perpetualContract":[
+	{"symbol":"BTC-USDT","l2PairId":"50001","stepSize":"0.001","tickSize":"0.1","enableTrade":true},
+	{"symbol":"ETH-USDT","l2PairId":"50002","stepSize":"0.01","tickSize":"0.01","enableTrade":true},
+	{"symbol":"OLD-USDT","l2PairId":"50003","stepSize":"1","tickSize":"0.0001","enableTrade":false}]}}}`
+
+func TestApexFundingRates(t *testing.T) {
+	api := newFakeAPI(t)
+	api.respond("GET", "/v3/symbols", http.StatusOK, apexSymbols)
+	api.handle("GET", "/v3/ticker", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("symbol") {
+		case "BTCUSDT":
+			_, _ = w.Write([]byte(`{"data":[{"symbol":"BTCUSDT","markPrice":"65000.1","fundingRate":"0.0000125","nextFundingTime":"2023-11-14T23:00:00Z"}]}`))
+		case "ETHUSDT":
+			_, _ = w.Write([]byte(`{"data":[{"symbol":"ETHUSDT","markPrice":"3500","fundingRate":"-0.00002","nextFundingTime":"2023-11-14T23:00:00Z"}]}`))
+		default:
+			t.Errorf("unexpected ticker request for %q", r.URL.Query().Get("symbol"))
+		}
+	})
+	ex := newTestApex(api, nil)
+
+	rates, err := ex.GetFundingRates(context.Background())
+	if err != nil {
+		t.Fatalf("GetFundingRates: %v", err)
+	}
+	if len(rates) != 2 {
+		t.Fatalf("expected the two tradable perpetuals, got %d rates", len(rates))
+	}
+	if rates[0].Market != "BTC-USD" || !rates[0].Rate.Equal(decimal.NewFromFloat(0.0000125)) || rates[0].NextTime != 1700002800 || rates[1].Market != "ETH-USD" || !rates[1].Rate.Equal(decimal.NewFromFloat(-0.00002)) {
+		t.Errorf("unexpected rates %+v %+v", rates[0], rates[1])
+	}
+}
+
+func TestApexSignedOrders(t *testing.T) {
+	api := newFakeAPI(t)
+	api.respond("GET", "/v3/symbols", http.StatusOK, apexSymbols)
+	api.respond("GET", "/v3/account", http.StatusOK, `{"data":{"spotAccount":{"zkAccountId":"777","defaultSubAccountId":"0"},
+		"contractAccount":{"takerFeeRate":"0.0005","makerFeeRate":"0.0002"},"positions":[]}}`)
+	var body []byte
+	var headers http.Header
+	api.handle("POST", "/v3/order", func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		headers = r.Header.Clone()
+		_, _ = w.Write([]byte(`{"data":{"id":"1234","symbol":"BTC-USDT","side":"SELL","type":"LIMIT","size":"0.012","price":"64999.9","status":"PENDING","createdAt":1700000000000}}`))
+	})
+	signer := &recordingApexSigner{}
+	ex := newTestApex(api, signer)
+
+	order, err := ex.PlaceOrder(context.Background(), "BTC-USD", Sell, Limit, decimal.NewFromFloat(0.01234), decimal.NewFromFloat(64999.87))
+	if err != nil {
+		t.Fatalf("PlaceOrder: %v", err)
+	}
+	if order.ID != "1234" || order.Status != "NEW" || !order.Amount.Equal(decimal.NewFromFloat(0.012)) {
+		t.Errorf("unexpected order %+v", order)
+	}
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		t.Fatalf("order body is not a form: %v", err)
+	}
+	if form.Get("symbol") != "BTC-USDT" || form.Get("side") != "SELL" || form.Get("type") != "LIMIT" || form.Get("size") != "0.012" ||
+		form.Get("price") != "64999.9" || form.Get("timeInForce") != "GOOD_TIL_CANCEL" || form.Get("signature") != "0xzksig" {
+		t.Errorf("unexpected order request %s", body)
+	}
+	if o := signer.order; o.AccountID != "777" || o.PairID != "50001" || o.Size != "0.012" || o.Price != "64999.9" ||
+		o.TakerFeeRate != "0.0005" || o.ClientID != form.Get("clientId") || o.Expiration == 0 {
+		t.Errorf("unexpected signed order %+v", o)
+	}
+	key := base64.StdEncoding.EncodeToString([]byte("test-secret"))
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(headers.Get("APEX-TIMESTAMP") + "POST/api/v3/order" + string(body)))
+	if headers.Get("APEX-API-KEY") != "test-key" || headers.Get("APEX-PASSPHRASE") != "test-pass" ||
+		headers.Get("APEX-SIGNATURE") != base64.StdEncoding.EncodeToString(mac.Sum(nil)) {
+		t.Errorf("expected an HMAC-SHA256 signature over the timestamp, method, path and body, got %v", headers)
+	}
+
+	api.respond("GET", "/v3/ticker", http.StatusOK, `{"data":[{"symbol":"BTCUSDT","markPrice":"65000","fundingRate":"0"}]}`)
+	if _, err := ex.ClosePosition(context.Background(), "BTC-USD", Sell, decimal.NewFromFloat(0.012)); err != nil {
+		t.Fatalf("ClosePosition: %v", err)
+	}
+	form, _ = url.ParseQuery(string(body))
+	if form.Get("side") != "BUY" || form.Get("type") != "MARKET" || form.Get("reduceOnly") != "true" ||
+		form.Get("timeInForce") != "IMMEDIATE_OR_CANCEL" || form.Get("price") != "65650.0" {
+		t.Errorf("expected a reduce-only IOC buy at most 1%% above the mark, got %s", body)
+	}
+
+	if _, err := newTestApex(api, nil).PlaceOrder(context.Background(), "BTC-USD", Buy, Limit, decimal.NewFromFloat(0.01), decimal.NewFromInt(65000)); !errors.Is(err, ErrApexSignerRequired) {
+		t.Errorf("expected ErrApexSignerRequired without a signer, got %v", err)
+	}
+}
+
+func TestApexPositionsAndBalance(t *testing.T) {
+	api := newFakeAPI(t)
+	api.respond("GET", "/v3/account", http.StatusOK, `{"data":{"positions":[
+		{"symbol":"ETH-USDT","side":"SHORT","size":"1.5","entryPrice":"3510"},
+		{"symbol":"BTC-USDT","side":"LONG","size":"0","entryPrice":"0"}]}}`)
+	api.respond("GET", "/v3/account-balance", http.StatusOK, `{"data":{"totalEquityValue":"1520.75","availableBalance":"900"}}`)
+	api.respond("GET", "/v3/order", http.StatusBadRequest, `{"code":20016,"msg":"order not found"}`)
+	ex := newTestApex(api, nil)
+
+	positions, err := ex.GetPositions(context.Background())
+	if err != nil {
+		t.Fatalf("GetPositions: %v", err)
+	}
+	if len(positions) != 1 || positions[0] != (Position{Market: "ETH-USD", Side: Sell, Size: 1.5, EntryPrice: 3510}) {
+		t.Errorf("expected the ETH short, got %+v", positions)
+	}
+	if got := api.lastRequest("/v3/account").Header.Get("APEX-SIGNATURE"); got == "" {
+		t.Error("expected the account request to be signed")
+	}
+	if balance, err := ex.GetBalance(context.Background(), "USDT"); err != nil || balance != 1520.75 {
+		t.Errorf("GetBalance = %f, %v, want the total equity 1520.75", balance, err)
+	}
+	if _, err := ex.GetOrderStatus(context.Background(), "1", "ETH-USD"); err == nil {
+		t.Error("expected an API error for an unknown order")
+	}
+}
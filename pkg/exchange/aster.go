@@ -0,0 +1,23 @@
+package exchange
+
+import (
+	"net/http"
+	"time"
+)
+
+// AsterBaseURL is the Aster perpetuals API. Aster has no public testnet.
+const AsterBaseURL = "https://fapi.asterdex.com"
+
+// NewAster creates a client for Aster perpetuals. Aster serves the Binance USDⓈ-M futures API,
+// HMAC signing included, so the Binance implementation is used with Aster's host and API key.
+func NewAster(apiKey, secretKey string) *Binance {
+	return &Binance{
+		client:     &http.Client{Timeout: 10 * time.Second},
+		name:       "Aster",
+		apiKey:     apiKey,
+		secretKey:  secretKey,
+		baseURL:    AsterBaseURL,
+		mainnetURL: AsterBaseURL,
+		testnetURL: AsterBaseURL,
+	}
+}
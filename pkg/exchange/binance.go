@@ -0,0 +1,714 @@
+package exchange
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/httpclient"
+)
+
+const (
+	BinanceMainnetBaseURL = "https://fapi.binance.com"
+	BinanceTestnetBaseURL = "https://testnet.binancefuture.com"
+)
+
+// binanceQuoteAsset is the margin and quote asset of the USDⓈ-M contracts the bot trades.
+const binanceQuoteAsset = "USDT"
+
+// binanceHistoryPageSize is the most records the funding and income endpoints return per request.
+const binanceHistoryPageSize = 1000
+
+// binanceFundingInterval is the funding interval rates are quoted in. Contracts that settle more
+// often have their rates scaled to it.
+const binanceFundingInterval = 8 * time.Hour
+
+// Binance is the implementation for Binance USDⓈ-M futures, and for venues that serve the same
+// API such as Aster. Markets such as "BTC-USD" trade as the USDT-margined "BTCUSDT" contract. The
+// account is expected to be in one-way position mode.
+type Binance struct {
+	client     *http.Client
+	name       string
+	apiKey     string
+	secretKey  string
+	baseURL    string
+	mainnetURL string
+	testnetURL string
+	testnet    bool
+
+	symbols   map[string]binanceSymbol
+	symbolsMu sync.Mutex
+}
+
+// NewBinance creates a new Binance USDⓈ-M futures client.
+func NewBinance(apiKey, secretKey string, testnet bool) *Binance {
+	baseURL := BinanceMainnetBaseURL
+	if testnet {
+		baseURL = BinanceTestnetBaseURL
+	}
+	return &Binance{
+		client:     &http.Client{Timeout: 10 * time.Second},
+		name:       "Binance",
+		apiKey:     apiKey,
+		secretKey:  secretKey,
+		baseURL:    baseURL,
+		mainnetURL: BinanceMainnetBaseURL,
+		testnetURL: BinanceTestnetBaseURL,
+		testnet:    testnet,
+	}
+}
+
+// SetRateLimiter makes every Binance and Aster API call wait for limiter.
+func (b *Binance) SetRateLimiter(limiter *httpclient.Limiter) {
+	b.client = limiter.Client(b.client)
+}
+
+func (b *Binance) Name() string {
+	return b.name
+}
+
+// FundingInterval returns the interval funding rates are quoted in, which is how often most
+// contracts pay funding. GetFundingRates scales the rates of contracts that settle more often to
+// it, and the next funding time reported with every rate lets the calendar follow them.
+func (b *Binance) FundingInterval() time.Duration {
+	return binanceFundingInterval
+}
+
+func (b *Binance) CollateralAsset() string {
+	return binanceQuoteAsset
+}
+
+func (b *Binance) SetTestnet(testnet bool) {
+	b.testnet = testnet
+	if testnet {
+		b.baseURL = b.testnetURL
+	} else {
+		b.baseURL = b.mainnetURL
+	}
+}
+
+// SetTransport replaces the HTTP transport used for REST requests, e.g. with a recorder in tests.
+func (b *Binance) SetTransport(rt http.RoundTripper) {
+	b.client.Transport = rt
+}
+
+// Symbol converts "BTC-USD" into "BTCUSDT".
+func (b *Binance) Symbol(market string) string {
+	base, _, _ := strings.Cut(market, "-")
+	return strings.ToUpper(base) + binanceQuoteAsset
+}
+
+// market converts a USDT-margined symbol back into a market name. It reports false for other
+// contracts, such as USDC-margined ones.
+func (b *Binance) market(symbol string) (string, bool) {
+	base, ok := strings.CutSuffix(symbol, binanceQuoteAsset)
+	if !ok || base == "" {
+		return "", false
+	}
+	return base + "-USD", true
+}
+
+// BinancePremiumIndex is the mark price and funding of a contract. Times are in milliseconds.
+type BinancePremiumIndex struct {
+	Symbol          string `json:"symbol"`
+	MarkPrice       string `json:"markPrice"`
+	LastFundingRate string `json:"lastFundingRate"`
+	NextFundingTime int64  `json:"nextFundingTime"`
+}
+
+// GetFundingRates fetches the funding rate of every USDT-margined contract with the time it is
+// paid, quoted per 8 hours whatever interval the contract settles on.
+func (b *Binance) GetFundingRates(ctx context.Context) ([]*FundingRate, error) {
+	intervals, err := b.fundingIntervals(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var response []BinancePremiumIndex
+	if err := b.sendRequest(ctx, "GET", "/fapi/v1/premiumIndex", url.Values{}, false, &response); err != nil {
+		return nil, fmt.Errorf("failed to get funding rates from %s: %w", b.name, err)
+	}
+	var fundingRates []*FundingRate
+	for _, index := range response {
+		market, ok := b.market(index.Symbol)
+		if !ok {
+			continue
+		}
+		rate, err := decimal.NewFromString(index.LastFundingRate)
+		if err != nil {
+			continue
+		}
+		if interval, ok := intervals[index.Symbol]; ok {
+			rate = rate.Mul(rateScale(binanceFundingInterval, interval))
+		}
+		fundingRates = append(fundingRates, &FundingRate{Market: market, Rate: rate, NextTime: index.NextFundingTime / 1000})
+	}
+	return fundingRates, nil
+}
+
+// fundingIntervals returns the funding interval of the contracts that don't settle every 8 hours.
+// The interval of a contract changes when its rate hits the cap, so it is fetched every time.
+func (b *Binance) fundingIntervals(ctx context.Context) (map[string]time.Duration, error) {
+	var response []struct {
+		Symbol               string `json:"symbol"`
+		FundingIntervalHours int    `json:"fundingIntervalHours"`
+	}
+	if err := b.sendRequest(ctx, "GET", "/fapi/v1/fundingInfo", url.Values{}, false, &response); err != nil {
+		return nil, fmt.Errorf("failed to get funding intervals from %s: %w", b.name, err)
+	}
+	intervals := make(map[string]time.Duration)
+	for _, info := range response {
+		if interval := time.Duration(info.FundingIntervalHours) * time.Hour; interval > 0 && interval != binanceFundingInterval {
+			intervals[info.Symbol] = interval
+		}
+	}
+	return intervals, nil
+}
+
+// GetMarkPrice returns the mark price of market.
+func (b *Binance) GetMarkPrice(ctx context.Context, market string) (float64, error) {
+	var response BinancePremiumIndex
+	if err := b.sendRequest(ctx, "GET", "/fapi/v1/premiumIndex", url.Values{"symbol": {b.Symbol(market)}}, false, &response); err != nil {
+		return 0, fmt.Errorf("failed to get mark price from %s: %w", b.name, err)
+	}
+	price, err := strconv.ParseFloat(response.MarkPrice, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse mark price for %s from %s: %w", market, b.name, err)
+	}
+	return price, nil
+}
+
+// GetFundingHistory returns the funding rates paid on market between from and to, walking the
+// pages forwards from from. Rates are scaled to 8 hours by the contract's current interval, like
+// GetFundingRates.
+func (b *Binance) GetFundingHistory(ctx context.Context, market string, from, to time.Time) ([]*FundingRate, error) {
+	intervals, err := b.fundingIntervals(ctx)
+	if err != nil {
+		return nil, err
+	}
+	scale := decimal.NewFromInt(1)
+	if interval, ok := intervals[b.Symbol(market)]; ok {
+		scale = rateScale(binanceFundingInterval, interval)
+	}
+
+	var history []*FundingRate
+	start := from
+	for {
+		params := url.Values{
+			"symbol":    {b.Symbol(market)},
+			"startTime": {strconv.FormatInt(start.UnixMilli(), 10)},
+			"endTime":   {strconv.FormatInt(to.UnixMilli(), 10)},
+			"limit":     {strconv.Itoa(binanceHistoryPageSize)},
+		}
+		var response []struct {
+			FundingRate string `json:"fundingRate"`
+			FundingTime int64  `json:"fundingTime"`
+		}
+		if err := b.sendRequest(ctx, "GET", "/fapi/v1/fundingRate", params, false, &response); err != nil {
+			return nil, fmt.Errorf("failed to get funding history from %s: %w", b.name, err)
+		}
+		for _, h := range response {
+			rate, err := decimal.NewFromString(h.FundingRate)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse historical funding rate for %s from %s: %w", market, b.name, err)
+			}
+			history = append(history, &FundingRate{Market: market, Rate: rate.Mul(scale), NextTime: h.FundingTime / 1000})
+		}
+		if len(response) < binanceHistoryPageSize {
+			break
+		}
+		start = time.UnixMilli(response[len(response)-1].FundingTime + 1)
+	}
+	return history, nil
+}
+
+// GetFundingPayments returns the funding fees paid and received by the account on market since
+// the given time.
+func (b *Binance) GetFundingPayments(ctx context.Context, market string, since time.Time) ([]FundingPayment, error) {
+	var payments []FundingPayment
+	start := since
+	for {
+		params := url.Values{
+			"symbol":     {b.Symbol(market)},
+			"incomeType": {"FUNDING_FEE"},
+			"startTime":  {strconv.FormatInt(start.UnixMilli(), 10)},
+			"limit":      {strconv.Itoa(binanceHistoryPageSize)},
+		}
+		var response []struct {
+			Income string `json:"income"`
+			Time   int64  `json:"time"`
+		}
+		if err := b.sendRequest(ctx, "GET", "/fapi/v1/income", params, true, &response); err != nil {
+			return nil, fmt.Errorf("failed to get funding payments from %s: %w", b.name, err)
+		}
+		for _, p := range response {
+			amount, err := strconv.ParseFloat(p.Income, 64)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse funding payment for %s from %s: %w", market, b.name, err)
+			}
+			payments = append(payments, FundingPayment{Market: market, Time: time.UnixMilli(p.Time), Amount: amount})
+		}
+		if len(response) < binanceHistoryPageSize {
+			break
+		}
+		start = time.UnixMilli(response[len(response)-1].Time + 1)
+	}
+	sort.Slice(payments, func(i, j int) bool { return payments[i].Time.Before(payments[j].Time) })
+	return payments, nil
+}
+
+// binanceForceOrdersPageSize is the most orders the force orders endpoint returns per request.
+const binanceForceOrdersPageSize = 100
+
+// GetForcedCloses returns the liquidation and auto-deleveraging orders the exchange placed on the
+// account's market position since the given time.
+func (b *Binance) GetForcedCloses(ctx context.Context, market string, since time.Time) ([]ForcedClose, error) {
+	var closes []ForcedClose
+	for _, kind := range []ForcedCloseKind{Liquidation, ADL} {
+		start := since
+		for {
+			params := url.Values{
+				"symbol":        {b.Symbol(market)},
+				"autoCloseType": {string(kind)},
+				"startTime":     {strconv.FormatInt(start.UnixMilli(), 10)},
+				"limit":         {strconv.Itoa(binanceForceOrdersPageSize)},
+			}
+			var response []struct {
+				Side        string `json:"side"`
+				AvgPrice    string `json:"avgPrice"`
+				ExecutedQty string `json:"executedQty"`
+				Time        int64  `json:"time"`
+			}
+			if err := b.sendRequest(ctx, "GET", "/fapi/v1/forceOrders", params, true, &response); err != nil {
+				return nil, fmt.Errorf("failed to get forced closes from %s: %w", b.name, err)
+			}
+			for _, o := range response {
+				amount, err := strconv.ParseFloat(o.ExecutedQty, 64)
+				if err != nil {
+					return nil, fmt.Errorf("failed to parse forced close for %s from %s: %w", market, b.name, err)
+				}
+				price, _ := strconv.ParseFloat(o.AvgPrice, 64)
+				// A sell closes a long, a buy a short.
+				side := Buy
+				if o.Side == "BUY" {
+					side = Sell
+				}
+				closes = append(closes, ForcedClose{Market: market, Kind: kind, Side: side, Amount: amount, Price: price, Time: time.UnixMilli(o.Time)})
+			}
+			if len(response) < binanceForceOrdersPageSize {
+				break
+			}
+			start = time.UnixMilli(response[len(response)-1].Time + 1)
+		}
+	}
+	sort.Slice(closes, func(i, j int) bool { return closes[i].Time.Before(closes[j].Time) })
+	return closes, nil
+}
+
+// binanceOrderbookDepth is the number of price levels requested on each side of the book.
+const binanceOrderbookDepth = 100
+
+// GetOrderbook returns the order book of market.
+func (b *Binance) GetOrderbook(ctx context.Context, market string) (*Orderbook, error) {
+	var response struct {
+		Bids [][2]string `json:"bids"`
+		Asks [][2]string `json:"asks"`
+	}
+	params := url.Values{"symbol": {b.Symbol(market)}, "limit": {strconv.Itoa(binanceOrderbookDepth)}}
+	if err := b.sendRequest(ctx, "GET", "/fapi/v1/depth", params, false, &response); err != nil {
+		return nil, fmt.Errorf("failed to get orderbook from %s: %w", b.name, err)
+	}
+	bids, err := parseLevels(response.Bids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s orderbook from %s: %w", market, b.name, err)
+	}
+	asks, err := parseLevels(response.Asks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s orderbook from %s: %w", market, b.name, err)
+	}
+	return &Orderbook{Market: market, Bids: bids, Asks: asks}, nil
+}
+
+// BinanceOrderResponse is an order as reported by the order endpoints.
+type BinanceOrderResponse struct {
+	OrderID     int64  `json:"orderId"`
+	Symbol      string `json:"symbol"`
+	Status      string `json:"status"`
+	Side        string `json:"side"`
+	Type        string `json:"type"`
+	Price       string `json:"price"`
+	AvgPrice    string `json:"avgPrice"`
+	OrigQty     string `json:"origQty"`
+	ExecutedQty string `json:"executedQty"`
+	UpdateTime  int64  `json:"updateTime"`
+}
+
+// order converts the response into an Order on market. Price is the average fill price once
+// anything has filled, and the limit price before.
+func (r BinanceOrderResponse) order(market string) *Order {
+	price := parseDecimal(r.AvgPrice)
+	if price.IsZero() {
+		price = parseDecimal(r.Price)
+	}
+	amount := parseDecimal(r.OrigQty)
+	filled := parseDecimal(r.ExecutedQty)
+	return &Order{
+		ID:        strconv.FormatInt(r.OrderID, 10),
+		Market:    market,
+		Side:      OrderSide(r.Side),
+		Type:      OrderType(r.Type),
+		Price:     price,
+		Amount:    amount,
+		Filled:    filled,
+		Status:    r.Status,
+		Timestamp: r.UpdateTime / 1000,
+	}
+}
+
+// PlaceOrder sends a signed order, rounding amount down to the contract's lot size and a limit
+// price to its tick size. Limit orders rest until cancelled.
+func (b *Binance) PlaceOrder(ctx context.Context, market string, side OrderSide, orderType OrderType, amount, price decimal.Decimal) (*Order, error) {
+	return b.placeOrder(ctx, market, side, orderType, amount, price, false)
+}
+
+func (b *Binance) placeOrder(ctx context.Context, market string, side OrderSide, orderType OrderType, amount, price decimal.Decimal, reduceOnly bool) (*Order, error) {
+	symbol, err := b.symbol(ctx, market)
+	if err != nil {
+		return nil, err
+	}
+	quantity := symbol.lotSize.floor(amount)
+	if !quantity.IsPositive() {
+		return nil, fmt.Errorf("order amount %s is below the %s lot size %s for %s", amount, b.name, symbol.lotSize, market)
+	}
+
+	params := url.Values{
+		"symbol":           {b.Symbol(market)},
+		"side":             {string(side)},
+		"type":             {string(orderType)},
+		"quantity":         {symbol.lotSize.format(quantity)},
+		"newOrderRespType": {"RESULT"},
+	}
+	if orderType == Limit {
+		params.Set("price", symbol.tickSize.format(symbol.tickSize.round(price)))
+		params.Set("timeInForce", "GTC")
+	}
+	if reduceOnly {
+		params.Set("reduceOnly", "true")
+	}
+	var response BinanceOrderResponse
+	if err := b.sendRequest(ctx, "POST", "/fapi/v1/order", params, true, &response); err != nil {
+		return nil, fmt.Errorf("failed to place order on %s: %w", b.name, err)
+	}
+	return response.order(market), nil
+}
+
+func (b *Binance) GetOrderStatus(ctx context.Context, orderID string, market string) (*Order, error) {
+	var response BinanceOrderResponse
+	params := url.Values{"symbol": {b.Symbol(market)}, "orderId": {orderID}}
+	if err := b.sendRequest(ctx, "GET", "/fapi/v1/order", params, true, &response); err != nil {
+		return nil, fmt.Errorf("failed to get order status from %s: %w", b.name, err)
+	}
+	return response.order(market), nil
+}
+
+func (b *Binance) CancelOrder(ctx context.Context, orderID string, market string) error {
+	params := url.Values{"symbol": {b.Symbol(market)}, "orderId": {orderID}}
+	if err := b.sendRequest(ctx, "DELETE", "/fapi/v1/order", params, true, nil); err != nil {
+		return fmt.Errorf("failed to cancel order on %s: %w", b.name, err)
+	}
+	return nil
+}
+
+// ClosePosition reduces the position in market with a reduce-only market order on the opposite
+// side, so it can never flip the position.
+func (b *Binance) ClosePosition(ctx context.Context, market string, side OrderSide, amount decimal.Decimal) (*Order, error) {
+	closeSide := Sell
+	if side == Sell {
+		closeSide = Buy
+	}
+	return b.placeOrder(ctx, market, closeSide, Market, amount, decimal.Zero, true)
+}
+
+// GetBalance returns the margin balance of asset: the wallet balance plus the unrealized PnL of
+// cross-margined positions.
+func (b *Binance) GetBalance(ctx context.Context, asset string) (float64, error) {
+	summary, err := b.summary(ctx, asset)
+	if err != nil {
+		return 0, err
+	}
+	return summary.Equity, nil
+}
+
+// GetAccountSummary reports the USDT collateral: its wallet balance plus unrealized PnL, and the
+// part still available for new orders.
+func (b *Binance) GetAccountSummary(ctx context.Context) (*AccountSummary, error) {
+	return b.summary(ctx, binanceQuoteAsset)
+}
+
+// summary reads the balance of asset, which is zero if the account holds none.
+func (b *Binance) summary(ctx context.Context, asset string) (*AccountSummary, error) {
+	if asset == "" {
+		asset = binanceQuoteAsset
+	}
+	var response []struct {
+		Asset            string `json:"asset"`
+		Balance          string `json:"balance"`
+		CrossUnPnl       string `json:"crossUnPnl"`
+		AvailableBalance string `json:"availableBalance"`
+	}
+	if err := b.sendRequest(ctx, "GET", "/fapi/v2/balance", url.Values{}, true, &response); err != nil {
+		return nil, fmt.Errorf("failed to get balance from %s: %w", b.name, err)
+	}
+	summary := &AccountSummary{Asset: strings.ToUpper(asset)}
+	for _, balance := range response {
+		if !strings.EqualFold(balance.Asset, asset) {
+			continue
+		}
+		wallet, err := strconv.ParseFloat(balance.Balance, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse balance float from %s: %w", b.name, err)
+		}
+		unrealized, _ := strconv.ParseFloat(balance.CrossUnPnl, 64)
+		summary.Equity = wallet + unrealized
+		summary.Free, _ = strconv.ParseFloat(balance.AvailableBalance, 64)
+		summary.Used = math.Max(summary.Equity-summary.Free, 0)
+		break
+	}
+	return summary, nil
+}
+
+// BinancePositionRisk is a position as listed by the position risk endpoint. PositionAmt is
+// negative for shorts.
+type BinancePositionRisk struct {
+	Symbol           string `json:"symbol"`
+	PositionAmt      string `json:"positionAmt"`
+	EntryPrice       string `json:"entryPrice"`
+	MarkPrice        string `json:"markPrice"`
+	LiquidationPrice string `json:"liquidationPrice"`
+	IsolatedMargin   string `json:"isolatedMargin"`
+	MarginType       string `json:"marginType"`
+}
+
+// positionRisk fetches the positions of the account, or only the one in market if it is set.
+// Flat positions are left out.
+func (b *Binance) positionRisk(ctx context.Context, market string) ([]BinancePositionRisk, error) {
+	params := url.Values{}
+	if market != "" {
+		params.Set("symbol", b.Symbol(market))
+	}
+	var response []BinancePositionRisk
+	if err := b.sendRequest(ctx, "GET", "/fapi/v2/positionRisk", params, true, &response); err != nil {
+		return nil, err
+	}
+	open := response[:0]
+	for _, p := range response {
+		if size, _ := strconv.ParseFloat(p.PositionAmt, 64); size != 0 {
+			open = append(open, p)
+		}
+	}
+	return open, nil
+}
+
+// GetPositions fetches the open positions of the account.
+func (b *Binance) GetPositions(ctx context.Context) ([]Position, error) {
+	risks, err := b.positionRisk(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get positions from %s: %w", b.name, err)
+	}
+	var positions []Position
+	for _, p := range risks {
+		market, ok := b.market(p.Symbol)
+		if !ok {
+			continue
+		}
+		size, err := strconv.ParseFloat(p.PositionAmt, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse position size for %s from %s: %w", market, b.name, err)
+		}
+		entry, _ := strconv.ParseFloat(p.EntryPrice, 64)
+		side := Buy
+		if size < 0 {
+			side = Sell
+		}
+		positions = append(positions, Position{Market: market, Side: side, Size: math.Abs(size), EntryPrice: entry})
+	}
+	return positions, nil
+}
+
+// GetPositionRisk fetches the margin and liquidation price of the open position in market.
+// Isolated positions are backed by their own margin, cross-margined ones by the account balance.
+func (b *Binance) GetPositionRisk(ctx context.Context, market string) (*PositionRisk, error) {
+	risks, err := b.positionRisk(ctx, market)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the %s position from %s: %w", market, b.name, err)
+	}
+	if len(risks) == 0 {
+		return nil, fmt.Errorf("no open %s position on %s", market, b.name)
+	}
+	p := risks[0]
+	size, err := strconv.ParseFloat(p.PositionAmt, 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse position size for %s from %s: %w", market, b.name, err)
+	}
+	mark, err := strconv.ParseFloat(p.MarkPrice, 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse mark price for %s from %s: %w", market, b.name, err)
+	}
+	liquidation, _ := strconv.ParseFloat(p.LiquidationPrice, 64)
+	var margin float64
+	if strings.EqualFold(p.MarginType, "isolated") {
+		margin, _ = strconv.ParseFloat(p.IsolatedMargin, 64)
+	} else if margin, err = b.GetBalance(ctx, binanceQuoteAsset); err != nil {
+		return nil, err
+	}
+	side := Buy
+	if size < 0 {
+		side = Sell
+	}
+	return &PositionRisk{Market: market, Side: side, Size: math.Abs(size), MarkPrice: mark, LiquidationPrice: liquidation, Margin: margin}, nil
+}
+
+// SetMarginMode sets the margin mode of market. Binance rejects a change to the mode already set,
+// which is not an error here.
+func (b *Binance) SetMarginMode(ctx context.Context, market string, mode MarginMode) error {
+	marginType := "CROSSED"
+	if mode == IsolatedMargin {
+		marginType = "ISOLATED"
+	}
+	params := url.Values{"symbol": {b.Symbol(market)}, "marginType": {marginType}}
+	err := b.sendRequest(ctx, "POST", "/fapi/v1/marginType", params, true, nil)
+	if err != nil && !strings.Contains(err.Error(), `"code":-4046`) {
+		return fmt.Errorf("failed to set %s margin for %s on %s: %w", mode, market, b.name, err)
+	}
+	return nil
+}
+
+// SetLeverage sets the leverage of market. Binance only accepts whole leverage.
+func (b *Binance) SetLeverage(ctx context.Context, market string, leverage float64) error {
+	if leverage != math.Trunc(leverage) {
+		return fmt.Errorf("%s only accepts whole leverage, got %g", b.name, leverage)
+	}
+	params := url.Values{"symbol": {b.Symbol(market)}, "leverage": {strconv.Itoa(int(leverage))}}
+	if err := b.sendRequest(ctx, "POST", "/fapi/v1/leverage", params, true, nil); err != nil {
+		return fmt.Errorf("failed to set %gx leverage for %s on %s: %w", leverage, market, b.name, err)
+	}
+	return nil
+}
+
+// ServerTime returns the time of the Binance server.
+func (b *Binance) ServerTime(ctx context.Context) (time.Time, error) {
+	var response struct {
+		ServerTime int64 `json:"serverTime"`
+	}
+	if err := b.sendRequest(ctx, "GET", "/fapi/v1/time", nil, false, &response); err != nil {
+		return time.Time{}, fmt.Errorf("failed to get the %s server time: %w", b.name, err)
+	}
+	return time.UnixMilli(response.ServerTime), nil
+}
+
+// GetMarketInfo returns the trading rules of the contract for market. Binance lists the maximum
+// leverage per account bracket only, so it is left unset.
+func (b *Binance) GetMarketInfo(ctx context.Context, market string) (*MarketInfo, error) {
+	rules, err := b.symbol(ctx, market)
+	if err != nil {
+		return nil, err
+	}
+	return &MarketInfo{
+		Market:             market,
+		TickSize:           rules.tickSize.step,
+		LotSize:            rules.lotSize.step,
+		MinSize:            rules.minQty,
+		MinNotional:        rules.minNotional,
+		ContractMultiplier: 1,
+	}, nil
+}
+
+// binanceSymbol holds the trading rules of a contract.
+type binanceSymbol struct {
+	lotSize     decimalStep
+	tickSize    decimalStep
+	minQty      decimal.Decimal
+	minNotional float64
+}
+
+// symbol returns the trading rules of the contract for market. The exchange info of every
+// contract is fetched on first use and cached.
+func (b *Binance) symbol(ctx context.Context, market string) (binanceSymbol, error) {
+	b.symbolsMu.Lock()
+	defer b.symbolsMu.Unlock()
+	if b.symbols == nil {
+		var response struct {
+			Symbols []struct {
+				Symbol  string `json:"symbol"`
+				Filters []struct {
+					FilterType string `json:"filterType"`
+					StepSize   string `json:"stepSize"`
+					TickSize   string `json:"tickSize"`
+					MinQty     string `json:"minQty"`
+					Notional   string `json:"notional"`
+				} `json:"filters"`
+			} `json:"symbols"`
+		}
+		if err := b.sendRequest(ctx, "GET", "/fapi/v1/exchangeInfo", url.Values{}, false, &response); err != nil {
+			return binanceSymbol{}, fmt.Errorf("failed to get symbol info from %s: %w", b.name, err)
+		}
+		symbols := make(map[string]binanceSymbol, len(response.Symbols))
+		for _, s := range response.Symbols {
+			var rules binanceSymbol
+			for _, filter := range s.Filters {
+				switch filter.FilterType {
+				case "LOT_SIZE":
+					rules.lotSize = parseDecimalStep(filter.StepSize)
+					rules.minQty = parseDecimal(filter.MinQty)
+				case "MIN_NOTIONAL":
+					rules.minNotional = parseRule(filter.Notional)
+				case "PRICE_FILTER":
+					rules.tickSize = parseDecimalStep(filter.TickSize)
+				}
+			}
+			symbols[s.Symbol] = rules
+		}
+		b.symbols = symbols
+	}
+	rules, ok := b.symbols[b.Symbol(market)]
+	if !ok {
+		return binanceSymbol{}, fmt.Errorf("market %s not found on %s", market, b.name)
+	}
+	return rules, nil
+}
+
+// sendRequest sends a request to the Binance API, signing the query string when signed is true.
+func (b *Binance) sendRequest(ctx context.Context, method, endpoint string, params url.Values, signed bool, out interface{}) error {
+	query := params.Encode()
+	if signed {
+		query = signBinanceQuery(params, b.secretKey)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, b.baseURL+endpoint+"?"+query, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-MBX-APIKEY", b.apiKey)
+	return doJSON(b.client, req, out)
+}
+
+// signBinanceQuery timestamps params and returns them encoded with an HMAC-SHA256 signature, as
+// Binance's spot and futures APIs both expect. The signature covers the query exactly as sent and
+// must be appended last.
+func signBinanceQuery(params url.Values, secretKey string) string {
+	params.Set("timestamp", strconv.FormatInt(time.Now().UnixMilli(), 10))
+	query := params.Encode()
+	mac := hmac.New(sha256.New, []byte(secretKey))
+	mac.Write([]byte(query))
+	return query + "&signature=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,234 @@
+package exchange
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/httpclient"
+)
+
+const (
+	BinanceSpotMainnetBaseURL = "https://api.binance.com"
+	BinanceSpotTestnetBaseURL = "https://testnet.binance.vision"
+)
+
+// SpotExchange is a venue where the underlying asset itself is bought and sold,
+// used to hedge a perpetual position without a second perp venue.
+type SpotExchange interface {
+	Name() string
+	// Symbol converts a perp market name such as "BTC-USD" into the venue's spot symbol.
+	Symbol(market string) string
+	// BaseAsset returns the asset bought when hedging market.
+	BaseAsset(market string) string
+	// QuoteAsset returns the asset spent when buying spot.
+	QuoteAsset() string
+	GetPrice(ctx context.Context, market string) (float64, error)
+	// PlaceSpotOrder sends a market order for amount units of the base asset and
+	// returns the order with the amount actually submitted after lot-size rounding.
+	PlaceSpotOrder(ctx context.Context, market string, side OrderSide, amount decimal.Decimal) (*Order, error)
+	GetBalance(ctx context.Context, asset string) (float64, error)
+}
+
+// BinanceSpot is a SpotExchange implementation for Binance spot.
+type BinanceSpot struct {
+	client     *http.Client
+	apiKey     string
+	secretKey  string
+	quoteAsset string
+	baseURL    string
+
+	stepSizes   map[string]decimalStep
+	stepSizesMu sync.Mutex
+}
+
+// NewBinanceSpot creates a Binance spot client. quoteAsset is the stablecoin spot
+// purchases are paid in (e.g. "USDT").
+func NewBinanceSpot(apiKey, secretKey, quoteAsset string, testnet bool) *BinanceSpot {
+	baseURL := BinanceSpotMainnetBaseURL
+	if testnet {
+		baseURL = BinanceSpotTestnetBaseURL
+	}
+	if quoteAsset == "" {
+		quoteAsset = "USDT"
+	}
+	return &BinanceSpot{
+		client:     &http.Client{Timeout: 10 * time.Second},
+		apiKey:     apiKey,
+		secretKey:  secretKey,
+		quoteAsset: strings.ToUpper(quoteAsset),
+		baseURL:    baseURL,
+		stepSizes:  make(map[string]decimalStep),
+	}
+}
+
+// SetTransport replaces the HTTP transport used for REST requests, e.g. with a recorder in tests.
+func (b *BinanceSpot) SetTransport(rt http.RoundTripper) {
+	b.client.Transport = rt
+}
+
+// SetRateLimiter makes every Binance spot API call wait for limiter.
+func (b *BinanceSpot) SetRateLimiter(limiter *httpclient.Limiter) {
+	b.client = limiter.Client(b.client)
+}
+
+// Name returns the name of the exchange
+func (b *BinanceSpot) Name() string {
+	return "BinanceSpot"
+}
+
+// BaseAsset returns the asset part of a market such as "BTC-USD".
+func (b *BinanceSpot) BaseAsset(market string) string {
+	base, _, _ := strings.Cut(market, "-")
+	return strings.ToUpper(base)
+}
+
+// QuoteAsset returns the asset spot purchases are paid in.
+func (b *BinanceSpot) QuoteAsset() string {
+	return b.quoteAsset
+}
+
+// Symbol converts "BTC-USD" into "BTCUSDT".
+func (b *BinanceSpot) Symbol(market string) string {
+	return b.BaseAsset(market) + b.quoteAsset
+}
+
+// GetPrice fetches the last traded spot price for market.
+func (b *BinanceSpot) GetPrice(ctx context.Context, market string) (float64, error) {
+	var response struct {
+		Price string `json:"price"`
+	}
+	if err := b.sendRequest(ctx, "GET", "/api/v3/ticker/price", url.Values{"symbol": {b.Symbol(market)}}, false, &response); err != nil {
+		return 0, fmt.Errorf("failed to get spot price from Binance: %w", err)
+	}
+	price, err := strconv.ParseFloat(response.Price, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse spot price from Binance: %w", err)
+	}
+	return price, nil
+}
+
+// PlaceSpotOrder sends a signed market order, rounding amount down to the symbol's lot size.
+func (b *BinanceSpot) PlaceSpotOrder(ctx context.Context, market string, side OrderSide, amount decimal.Decimal) (*Order, error) {
+	step, err := b.stepSize(ctx, market)
+	if err != nil {
+		return nil, err
+	}
+	quantity := step.floor(amount)
+	if !quantity.IsPositive() {
+		return nil, fmt.Errorf("order amount %s is below the Binance lot size %s for %s", amount, step, market)
+	}
+
+	params := url.Values{
+		"symbol":   {b.Symbol(market)},
+		"side":     {string(side)},
+		"type":     {"MARKET"},
+		"quantity": {step.format(quantity)},
+	}
+	var response struct {
+		OrderID             int64  `json:"orderId"`
+		Status              string `json:"status"`
+		ExecutedQty         string `json:"executedQty"`
+		CummulativeQuoteQty string `json:"cummulativeQuoteQty"`
+		TransactTime        int64  `json:"transactTime"`
+	}
+	if err := b.sendRequest(ctx, "POST", "/api/v3/order", params, true, &response); err != nil {
+		return nil, fmt.Errorf("failed to place spot order on Binance: %w", err)
+	}
+
+	filled := parseDecimal(response.ExecutedQty)
+	quote := parseDecimal(response.CummulativeQuoteQty)
+	var avgPrice decimal.Decimal
+	if filled.IsPositive() {
+		avgPrice = quote.Div(filled)
+	}
+	return &Order{
+		ID:        strconv.FormatInt(response.OrderID, 10),
+		Market:    market,
+		Side:      side,
+		Type:      Market,
+		Price:     avgPrice,
+		Amount:    quantity,
+		Filled:    filled,
+		Status:    response.Status,
+		Timestamp: response.TransactTime / 1000,
+	}, nil
+}
+
+// GetBalance returns the free balance of asset.
+func (b *BinanceSpot) GetBalance(ctx context.Context, asset string) (float64, error) {
+	var response struct {
+		Balances []struct {
+			Asset string `json:"asset"`
+			Free  string `json:"free"`
+		} `json:"balances"`
+	}
+	if err := b.sendRequest(ctx, "GET", "/api/v3/account", url.Values{}, true, &response); err != nil {
+		return 0, fmt.Errorf("failed to get balance from Binance: %w", err)
+	}
+	for _, balance := range response.Balances {
+		if strings.EqualFold(balance.Asset, asset) {
+			free, err := strconv.ParseFloat(balance.Free, 64)
+			if err != nil {
+				return 0, fmt.Errorf("failed to parse balance float from Binance: %w", err)
+			}
+			return free, nil
+		}
+	}
+	return 0, nil
+}
+
+// stepSize returns the LOT_SIZE step of the symbol for market, cached after the first lookup.
+func (b *BinanceSpot) stepSize(ctx context.Context, market string) (decimalStep, error) {
+	symbol := b.Symbol(market)
+	b.stepSizesMu.Lock()
+	defer b.stepSizesMu.Unlock()
+	if step, ok := b.stepSizes[symbol]; ok {
+		return step, nil
+	}
+
+	var response struct {
+		Symbols []struct {
+			Filters []struct {
+				FilterType string `json:"filterType"`
+				StepSize   string `json:"stepSize"`
+			} `json:"filters"`
+		} `json:"symbols"`
+	}
+	if err := b.sendRequest(ctx, "GET", "/api/v3/exchangeInfo", url.Values{"symbol": {symbol}}, false, &response); err != nil {
+		return decimalStep{}, fmt.Errorf("failed to get symbol info from Binance: %w", err)
+	}
+	var step decimalStep
+	if len(response.Symbols) > 0 {
+		for _, filter := range response.Symbols[0].Filters {
+			if filter.FilterType == "LOT_SIZE" {
+				step = parseDecimalStep(filter.StepSize)
+			}
+		}
+	}
+	b.stepSizes[symbol] = step
+	return step, nil
+}
+
+// sendRequest sends a request to the Binance API, signing the query string when signed is true.
+func (b *BinanceSpot) sendRequest(ctx context.Context, method, endpoint string, params url.Values, signed bool, out interface{}) error {
+	query := params.Encode()
+	if signed {
+		query = signBinanceQuery(params, b.secretKey)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, b.baseURL+endpoint+"?"+query, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-MBX-APIKEY", b.apiKey)
+
+	return doJSON(b.client, req, out)
+}
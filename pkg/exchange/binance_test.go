@@ -0,0 +1,208 @@
+package exchange
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestBinanceFundingRates(t *testing.T) {
+	api := newFakeAPI(t)
+	api.respond("GET", "/fapi/v1/fundingInfo", http.StatusOK, `[
+		{"symbol":"BTCUSDT","fundingIntervalHours":8},{"symbol":"NEWUSDT","fundingIntervalHours":4}]`)
+	api.respond("GET", "/fapi/v1/premiumIndex", http.StatusOK, `[
+		{"symbol":"BTCUSDT","markPrice":"65000.10","lastFundingRate":"0.00010000","nextFundingTime":1700006400000},
+		{"symbol":"BTCUSDC","markPrice":"65000.00","lastFundingRate":"0.00020000","nextFundingTime":1700006400000},
+		{"symbol":"NEWUSDT","markPrice":"0.5","lastFundingRate":"-0.00300000","nextFundingTime":1700006400000}]`)
+	ex := newTestBinance(api)
+
+	rates, err := ex.GetFundingRates(context.Background())
+	if err != nil {
+		t.Fatalf("GetFundingRates: %v", err)
+	}
+	if len(rates) != 2 {
+		t.Fatalf("expected only the USDT-margined contracts, got %d rates", len(rates))
+	}
+	if r := rates[0]; r.Market != "BTC-USD" || !r.Rate.Equal(decimal.NewFromFloat(0.0001)) || r.NextTime != 1700006400 {
+		t.Errorf("unexpected rate %+v", r)
+	}
+	if r := rates[1]; r.Market != "NEW-USD" || !r.Rate.Equal(decimal.NewFromFloat(-0.006)) {
+		t.Errorf("expected the 4-hourly rate scaled to 8 hours, got %+v", r)
+	}
+	if got := FundingIntervalOf(ex); got.Hours() != 8 {
+		t.Errorf("expected an 8h funding interval, got %s", got)
+	}
+}
+
+func TestBinanceSignedOrders(t *testing.T) {
+	api := newFakeAPI(t)
+	api.respond("GET", "/fapi/v1/exchangeInfo", http.StatusOK, `{"symbols":[{"symbol":"BTCUSDT","filters":[
+		{"filterType":"PRICE_FILTER","tickSize":"0.10"},{"filterType":"LOT_SIZE","stepSize":"0.001"}]}]}`)
+	api.respond("POST", "/fapi/v1/order", http.StatusOK,
+		`{"orderId":42,"symbol":"BTCUSDT","status":"NEW","side":"BUY","type":"LIMIT","price":"64999.90","avgPrice":"0.00","origQty":"0.012","executedQty":"0"}`)
+	ex := newTestBinance(api)
+
+	order, err := ex.PlaceOrder(context.Background(), "BTC-USD", Buy, Limit, decimal.NewFromFloat(0.01234), decimal.NewFromFloat(64999.87))
+	if err != nil {
+		t.Fatalf("PlaceOrder: %v", err)
+	}
+	if order.ID != "42" || !order.Price.Equal(decimal.NewFromFloat(64999.9)) || !order.Amount.Equal(decimal.NewFromFloat(0.012)) {
+		t.Errorf("unexpected order %+v", order)
+	}
+
+	req := api.lastRequest("/fapi/v1/order")
+	query := req.URL.Query()
+	if query.Get("quantity") != "0.012" || query.Get("price") != "64999.9" || query.Get("timeInForce") != "GTC" {
+		t.Errorf("expected the amount and price rounded to the symbol rules, got %s", req.URL.RawQuery)
+	}
+	if req.Header.Get("X-MBX-APIKEY") != "test-key" {
+		t.Error("expected the API key header")
+	}
+	signed, signature, _ := strings.Cut(req.URL.RawQuery, "&signature=")
+	mac := hmac.New(sha256.New, []byte("test-secret"))
+	mac.Write([]byte(signed))
+	if signature != hex.EncodeToString(mac.Sum(nil)) || query.Get("timestamp") == "" {
+		t.Errorf("expected an HMAC-SHA256 signature over the timestamped query, got %s", req.URL.RawQuery)
+	}
+
+	if _, err := ex.ClosePosition(context.Background(), "BTC-USD", Sell, decimal.NewFromFloat(0.012)); err != nil {
+		t.Fatalf("ClosePosition: %v", err)
+	}
+	if query := api.lastRequest("/fapi/v1/order").URL.Query(); query.Get("side") != "BUY" || query.Get("type") != "MARKET" || query.Get("reduceOnly") != "true" {
+		t.Errorf("expected a reduce-only market buy to close a short, got %v", query)
+	}
+}
+
+func TestBinancePositions(t *testing.T) {
+	api := newFakeAPI(t)
+	api.respond("GET", "/fapi/v2/positionRisk", http.StatusOK, `[
+		{"symbol":"BTCUSDT","positionAmt":"-0.020","entryPrice":"64000","markPrice":"65000","liquidationPrice":"78000","isolatedMargin":"300","marginType":"isolated"},
+		{"symbol":"ETHUSDT","positionAmt":"0.000","entryPrice":"0","markPrice":"3200","liquidationPrice":"0","isolatedMargin":"0","marginType":"cross"}]`)
+	ex := newTestBinance(api)
+
+	positions, err := ex.GetPositions(context.Background())
+	if err != nil {
+		t.Fatalf("GetPositions: %v", err)
+	}
+	if len(positions) != 1 || positions[0] != (Position{Market: "BTC-USD", Side: Sell, Size: 0.02, EntryPrice: 64000}) {
+		t.Errorf("expected only the open BTC short, got %+v", positions)
+	}
+
+	risk, err := ex.GetPositionRisk(context.Background(), "BTC-USD")
+	if err != nil {
+		t.Fatalf("GetPositionRisk: %v", err)
+	}
+	if distance, ok := risk.LiquidationDistance(); risk.Margin != 300 || !ok || distance != 0.2 {
+		t.Errorf("unexpected risk %+v (distance %f)", risk, distance)
+	}
+}
+
+func TestBinanceForcedCloses(t *testing.T) {
+	api := newFakeAPI(t)
+	api.handle("GET", "/fapi/v1/forceOrders", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("autoCloseType") {
+		case "LIQUIDATION":
+			w.Write([]byte(`[{"symbol":"BTCUSDT","side":"BUY","avgPrice":"66000","executedQty":"0.020","time":1700000200000}]`))
+		case "ADL":
+			w.Write([]byte(`[{"symbol":"BTCUSDT","side":"SELL","avgPrice":"65500","executedQty":"0.005","time":1700000100000}]`))
+		}
+	})
+	ex := newTestBinance(api)
+
+	closes, err := ex.GetForcedCloses(context.Background(), "BTC-USD", time.UnixMilli(1700000000000))
+	if err != nil {
+		t.Fatalf("GetForcedCloses: %v", err)
+	}
+	if len(closes) != 2 || closes[0].Kind != ADL || closes[0].Side != Buy || closes[0].Amount != 0.005 ||
+		closes[1].Kind != Liquidation || closes[1].Side != Sell || closes[1].Price != 66000 {
+		t.Errorf("expected the ADL of a long then the liquidation of a short, got %+v", closes)
+	}
+	if query := api.lastRequest("/fapi/v1/forceOrders").URL.Query(); query.Get("symbol") != "BTCUSDT" || query.Get("startTime") != "1700000000000" || query.Get("signature") == "" {
+		t.Errorf("unexpected query %v", query)
+	}
+}
+
+func TestBinanceAccountSummary(t *testing.T) {
+	api := newFakeAPI(t)
+	api.respond("GET", "/fapi/v2/balance", http.StatusOK, `[
+		{"asset":"BNB","balance":"1.5","crossUnPnl":"0","availableBalance":"1.5"},
+		{"asset":"USDT","balance":"1000","crossUnPnl":"-50","availableBalance":"700"}]`)
+	ex := newTestBinance(api)
+
+	if balance, err := ex.GetBalance(context.Background(), "USDT"); err != nil || balance != 950 {
+		t.Errorf("GetBalance = %f, %v, want the margin balance 950", balance, err)
+	}
+	summary, err := ex.GetAccountSummary(context.Background())
+	if err != nil {
+		t.Fatalf("GetAccountSummary: %v", err)
+	}
+	if *summary != (AccountSummary{Asset: "USDT", Equity: 950, Free: 700, Used: 250}) {
+		t.Errorf("unexpected summary %+v", summary)
+	}
+}
+
+func TestBinanceMarginModeAlreadySet(t *testing.T) {
+	api := newFakeAPI(t)
+	api.respond("POST", "/fapi/v1/marginType", http.StatusBadRequest, `{"code":-4046,"msg":"No need to change margin type."}`)
+	ex := newTestBinance(api)
+
+	if err := ex.SetMarginMode(context.Background(), "BTC-USD", IsolatedMargin); err != nil {
+		t.Fatalf("expected an unchanged margin mode to succeed, got %v", err)
+	}
+	if got := api.lastRequest("/fapi/v1/marginType").URL.Query().Get("marginType"); got != "ISOLATED" {
+		t.Errorf("marginType = %q, want ISOLATED", got)
+	}
+
+	api.respond("POST", "/fapi/v1/marginType", http.StatusBadRequest, `{"code":-4047,"msg":"Margin type cannot be changed if there exists position."}`)
+	if err := ex.SetMarginMode(context.Background(), "BTC-USD", CrossMargin); err == nil {
+		t.Error("expected other rejections to be reported")
+	}
+}
+
+func TestBinanceSetLeverage(t *testing.T) {
+	api := newFakeAPI(t)
+	api.respond("POST", "/fapi/v1/leverage", http.StatusOK, `{"leverage":3,"maxNotionalValue":"1000000","symbol":"BTCUSDT"}`)
+	ex := newTestBinance(api)
+
+	if err := ex.SetLeverage(context.Background(), "BTC-USD", 3); err != nil {
+		t.Fatalf("SetLeverage: %v", err)
+	}
+	query := api.lastRequest("/fapi/v1/leverage").URL.Query()
+	if query.Get("symbol") != "BTCUSDT" || query.Get("leverage") != "3" {
+		t.Errorf("unexpected leverage request %v", query)
+	}
+	if err := ex.SetLeverage(context.Background(), "BTC-USD", 2.5); err == nil {
+		t.Error("expected fractional leverage to be rejected")
+	}
+}
+
+func TestAsterUsesTheBinanceAPI(t *testing.T) {
+	api := newFakeAPI(t)
+	api.respond("GET", "/fapi/v1/premiumIndex", http.StatusOK, `{"symbol":"ASTERUSDT","markPrice":"1.25","lastFundingRate":"0.001","nextFundingTime":1700006400000}`)
+	ex := NewAster("aster-key", "aster-secret")
+	ex.SetTestnet(true)
+	if ex.Name() != "Aster" || ex.baseURL != AsterBaseURL {
+		t.Errorf("expected Aster to stay on its only network, got %s at %s", ex.Name(), ex.baseURL)
+	}
+	ex.client, ex.baseURL = api.Client(), api.URL
+
+	price, err := ex.GetMarkPrice(context.Background(), "ASTER-USD")
+	if err != nil || price != 1.25 {
+		t.Fatalf("GetMarkPrice = %f, %v", price, err)
+	}
+	if got := api.lastRequest("/fapi/v1/premiumIndex").URL.Query().Get("symbol"); got != "ASTERUSDT" {
+		t.Errorf("symbol = %q, want ASTERUSDT", got)
+	}
+
+	api.respond("GET", "/fapi/v2/balance", http.StatusUnauthorized, `{"code":-2015,"msg":"Invalid API-key"}`)
+	if _, err := ex.GetBalance(context.Background(), "USDT"); err == nil || !strings.Contains(err.Error(), "from Aster") {
+		t.Errorf("expected errors to name Aster, got %v", err)
+	}
+}
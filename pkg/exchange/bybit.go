@@ -0,0 +1,832 @@
+package exchange
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/httpclient"
+)
+
+const (
+	BybitMainnetBaseURL = "https://api.bybit.com"
+	BybitTestnetBaseURL = "https://api-testnet.bybit.com"
+)
+
+const (
+	// bybitCategory is the product category of the USDT-margined perpetuals the bot trades.
+	bybitCategory = "linear"
+	// bybitQuoteAsset is the margin and quote asset of those contracts.
+	bybitQuoteAsset = "USDT"
+	// bybitAccountType is the unified trading account, the only one v5 trades linear contracts from.
+	bybitAccountType = "UNIFIED"
+	// bybitRecvWindow is how long, in milliseconds, a signed request stays valid.
+	bybitRecvWindow = "5000"
+	// bybitFundingInterval is the funding interval rates are quoted in. Contracts that settle more
+	// often have their rates scaled to it.
+	bybitFundingInterval = 8 * time.Hour
+	// bybitTransactionLogWindow is the longest time range the transaction log and the execution
+	// list serve per query.
+	bybitTransactionLogWindow = 7 * 24 * time.Hour
+)
+
+// Bybit is the implementation for Bybit v5 USDT perpetuals. Markets such as "BTC-USD" trade as
+// the linear "BTCUSDT" contract from a unified trading account in one-way position mode.
+type Bybit struct {
+	client    *http.Client
+	apiKey    string
+	secretKey string
+	baseURL   string
+	testnet   bool
+
+	instruments   map[string]bybitInstrument
+	instrumentsMu sync.Mutex
+}
+
+// NewBybit creates a new Bybit client.
+func NewBybit(apiKey, secretKey string, testnet bool) *Bybit {
+	baseURL := BybitMainnetBaseURL
+	if testnet {
+		baseURL = BybitTestnetBaseURL
+	}
+	return &Bybit{
+		client:    &http.Client{Timeout: 10 * time.Second},
+		apiKey:    apiKey,
+		secretKey: secretKey,
+		baseURL:   baseURL,
+		testnet:   testnet,
+	}
+}
+
+// SetRateLimiter makes every Bybit API call wait for limiter.
+func (b *Bybit) SetRateLimiter(limiter *httpclient.Limiter) {
+	b.client = limiter.Client(b.client)
+}
+
+func (b *Bybit) Name() string {
+	return "Bybit"
+}
+
+// FundingInterval returns the interval funding rates are quoted in. Long-tail contracts often
+// settle every 4 or 2 hours instead; GetFundingRates scales their rates to this interval.
+func (b *Bybit) FundingInterval() time.Duration {
+	return bybitFundingInterval
+}
+
+func (b *Bybit) CollateralAsset() string {
+	return bybitQuoteAsset
+}
+
+func (b *Bybit) SetTestnet(testnet bool) {
+	b.testnet = testnet
+	if testnet {
+		b.baseURL = BybitTestnetBaseURL
+	} else {
+		b.baseURL = BybitMainnetBaseURL
+	}
+}
+
+// SetTransport replaces the HTTP transport used for REST requests, e.g. with a recorder in tests.
+func (b *Bybit) SetTransport(rt http.RoundTripper) {
+	b.client.Transport = rt
+}
+
+// Symbol converts "BTC-USD" into "BTCUSDT".
+func (b *Bybit) Symbol(market string) string {
+	base, _, _ := strings.Cut(market, "-")
+	return strings.ToUpper(base) + bybitQuoteAsset
+}
+
+// market converts a USDT perpetual symbol back into a market name. It reports false for other
+// contracts, such as USDC perpetuals and dated futures.
+func (b *Bybit) market(symbol string) (string, bool) {
+	base, ok := strings.CutSuffix(symbol, bybitQuoteAsset)
+	if !ok || base == "" {
+		return "", false
+	}
+	return base + "-USD", true
+}
+
+// BybitTicker is the mark price and funding of a contract. NextFundingTime is in milliseconds.
+type BybitTicker struct {
+	Symbol          string `json:"symbol"`
+	MarkPrice       string `json:"markPrice"`
+	FundingRate     string `json:"fundingRate"`
+	NextFundingTime string `json:"nextFundingTime"`
+}
+
+// GetFundingRates fetches the funding rate of every USDT perpetual with the time it is paid,
+// quoted per 8 hours whatever interval the contract settles on.
+func (b *Bybit) GetFundingRates(ctx context.Context) ([]*FundingRate, error) {
+	instruments, err := b.loadInstruments(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var response struct {
+		List []BybitTicker `json:"list"`
+	}
+	if err := b.sendRequest(ctx, "GET", "/v5/market/tickers", url.Values{"category": {bybitCategory}}, nil, false, &response); err != nil {
+		return nil, fmt.Errorf("failed to get funding rates from Bybit: %w", err)
+	}
+	var fundingRates []*FundingRate
+	for _, ticker := range response.List {
+		market, ok := b.market(ticker.Symbol)
+		if !ok {
+			continue
+		}
+		rate, err := decimal.NewFromString(ticker.FundingRate)
+		if err != nil {
+			continue
+		}
+		if interval := instruments[ticker.Symbol].fundingInterval; interval > 0 {
+			rate = rate.Mul(rateScale(bybitFundingInterval, interval))
+		}
+		next, _ := strconv.ParseInt(ticker.NextFundingTime, 10, 64)
+		fundingRates = append(fundingRates, &FundingRate{Market: market, Rate: rate, NextTime: next / 1000})
+	}
+	return fundingRates, nil
+}
+
+// GetMarkPrice returns the mark price of market.
+func (b *Bybit) GetMarkPrice(ctx context.Context, market string) (float64, error) {
+	var response struct {
+		List []BybitTicker `json:"list"`
+	}
+	params := url.Values{"category": {bybitCategory}, "symbol": {b.Symbol(market)}}
+	if err := b.sendRequest(ctx, "GET", "/v5/market/tickers", params, nil, false, &response); err != nil {
+		return 0, fmt.Errorf("failed to get mark price from Bybit: %w", err)
+	}
+	if len(response.List) == 0 {
+		return 0, fmt.Errorf("market %s not found on Bybit", market)
+	}
+	price, err := strconv.ParseFloat(response.List[0].MarkPrice, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse mark price for %s from Bybit: %w", market, err)
+	}
+	return price, nil
+}
+
+// bybitHistoryPageSize is the most funding rates the history endpoint returns per request.
+const bybitHistoryPageSize = 200
+
+// GetFundingHistory returns the funding rates paid on market between from and to, each scaled
+// to 8 hours like GetFundingRates. Bybit serves the newest rates first, so the pages are walked
+// backwards from to.
+func (b *Bybit) GetFundingHistory(ctx context.Context, market string, from, to time.Time) ([]*FundingRate, error) {
+	instruments, err := b.loadInstruments(ctx)
+	if err != nil {
+		return nil, err
+	}
+	scale := decimal.NewFromInt(1)
+	if interval := instruments[b.Symbol(market)].fundingInterval; interval > 0 {
+		scale = rateScale(bybitFundingInterval, interval)
+	}
+
+	var history []*FundingRate
+	end := to
+	for !end.Before(from) {
+		params := url.Values{
+			"category":  {bybitCategory},
+			"symbol":    {b.Symbol(market)},
+			"startTime": {strconv.FormatInt(from.UnixMilli(), 10)},
+			"endTime":   {strconv.FormatInt(end.UnixMilli(), 10)},
+			"limit":     {strconv.Itoa(bybitHistoryPageSize)},
+		}
+		var response struct {
+			List []struct {
+				FundingRate          string `json:"fundingRate"`
+				FundingRateTimestamp string `json:"fundingRateTimestamp"`
+			} `json:"list"`
+		}
+		if err := b.sendRequest(ctx, "GET", "/v5/market/funding/history", params, nil, false, &response); err != nil {
+			return nil, fmt.Errorf("failed to get funding history from Bybit: %w", err)
+		}
+		oldest := end.UnixMilli()
+		for _, h := range response.List {
+			rate, err := decimal.NewFromString(h.FundingRate)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse historical funding rate for %s from Bybit: %w", market, err)
+			}
+			paid, err := strconv.ParseInt(h.FundingRateTimestamp, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse historical funding time for %s from Bybit: %w", market, err)
+			}
+			history = append(history, &FundingRate{Market: market, Rate: rate.Mul(scale), NextTime: paid / 1000})
+			if paid < oldest {
+				oldest = paid
+			}
+		}
+		if len(response.List) < bybitHistoryPageSize {
+			break
+		}
+		end = time.UnixMilli(oldest - 1)
+	}
+	sort.Slice(history, func(i, j int) bool { return history[i].NextTime < history[j].NextTime })
+	return history, nil
+}
+
+// GetFundingPayments returns the funding settled on the account's market position since the
+// given time, from the unified account's transaction log.
+func (b *Bybit) GetFundingPayments(ctx context.Context, market string, since time.Time) ([]FundingPayment, error) {
+	symbol := b.Symbol(market)
+	var payments []FundingPayment
+	for start := since; start.Before(time.Now()); start = start.Add(bybitTransactionLogWindow) {
+		cursor := ""
+		for {
+			params := url.Values{
+				"accountType": {bybitAccountType},
+				"category":    {bybitCategory},
+				"currency":    {bybitQuoteAsset},
+				"type":        {"SETTLEMENT"},
+				"startTime":   {strconv.FormatInt(start.UnixMilli(), 10)},
+				"endTime":     {strconv.FormatInt(start.Add(bybitTransactionLogWindow).UnixMilli(), 10)},
+				"limit":       {"50"},
+			}
+			if cursor != "" {
+				params.Set("cursor", cursor)
+			}
+			var response struct {
+				List []struct {
+					Symbol          string `json:"symbol"`
+					Change          string `json:"change"`
+					FeeRate         string `json:"feeRate"`
+					TransactionTime string `json:"transactionTime"`
+				} `json:"list"`
+				NextPageCursor string `json:"nextPageCursor"`
+			}
+			if err := b.sendRequest(ctx, "GET", "/v5/account/transaction-log", params, nil, true, &response); err != nil {
+				return nil, fmt.Errorf("failed to get funding payments from Bybit: %w", err)
+			}
+			for _, p := range response.List {
+				if p.Symbol != symbol {
+					continue
+				}
+				amount, err := strconv.ParseFloat(p.Change, 64)
+				if err != nil {
+					return nil, fmt.Errorf("failed to parse funding payment for %s from Bybit: %w", market, err)
+				}
+				rate, _ := strconv.ParseFloat(p.FeeRate, 64)
+				paid, _ := strconv.ParseInt(p.TransactionTime, 10, 64)
+				payments = append(payments, FundingPayment{Market: market, Time: time.UnixMilli(paid), Rate: rate, Amount: amount})
+			}
+			if response.NextPageCursor == "" || len(response.List) == 0 {
+				break
+			}
+			cursor = response.NextPageCursor
+		}
+	}
+	sort.Slice(payments, func(i, j int) bool { return payments[i].Time.Before(payments[j].Time) })
+	return payments, nil
+}
+
+// bybitForcedExecTypes maps the execution types of Bybit's forced fills to their kind.
+var bybitForcedExecTypes = map[string]ForcedCloseKind{"BustTrade": Liquidation, "AdlTrade": ADL}
+
+// GetForcedCloses returns the liquidation and auto-deleveraging fills on the account's market
+// position since the given time, from its execution list.
+func (b *Bybit) GetForcedCloses(ctx context.Context, market string, since time.Time) ([]ForcedClose, error) {
+	symbol := b.Symbol(market)
+	var closes []ForcedClose
+	for execType, kind := range bybitForcedExecTypes {
+		for start := since; start.Before(time.Now()); start = start.Add(bybitTransactionLogWindow) {
+			cursor := ""
+			for {
+				params := url.Values{
+					"category":  {bybitCategory},
+					"symbol":    {symbol},
+					"execType":  {execType},
+					"startTime": {strconv.FormatInt(start.UnixMilli(), 10)},
+					"endTime":   {strconv.FormatInt(start.Add(bybitTransactionLogWindow).UnixMilli(), 10)},
+					"limit":     {"100"},
+				}
+				if cursor != "" {
+					params.Set("cursor", cursor)
+				}
+				var response struct {
+					List []struct {
+						Side      string `json:"side"`
+						ExecQty   string `json:"execQty"`
+						ExecPrice string `json:"execPrice"`
+						ExecTime  string `json:"execTime"`
+					} `json:"list"`
+					NextPageCursor string `json:"nextPageCursor"`
+				}
+				if err := b.sendRequest(ctx, "GET", "/v5/execution/list", params, nil, true, &response); err != nil {
+					return nil, fmt.Errorf("failed to get forced closes from Bybit: %w", err)
+				}
+				for _, e := range response.List {
+					amount, err := strconv.ParseFloat(e.ExecQty, 64)
+					if err != nil {
+						return nil, fmt.Errorf("failed to parse forced close for %s from Bybit: %w", market, err)
+					}
+					price, _ := strconv.ParseFloat(e.ExecPrice, 64)
+					executed, _ := strconv.ParseInt(e.ExecTime, 10, 64)
+					// A sell closes a long, a buy a short.
+					side := Buy
+					if e.Side == "Buy" {
+						side = Sell
+					}
+					closes = append(closes, ForcedClose{Market: market, Kind: kind, Side: side, Amount: amount, Price: price, Time: time.UnixMilli(executed)})
+				}
+				if response.NextPageCursor == "" || len(response.List) == 0 {
+					break
+				}
+				cursor = response.NextPageCursor
+			}
+		}
+	}
+	sort.Slice(closes, func(i, j int) bool { return closes[i].Time.Before(closes[j].Time) })
+	return closes, nil
+}
+
+// bybitOrderbookDepth is the number of price levels requested on each side of the book.
+const bybitOrderbookDepth = 200
+
+// GetOrderbook returns the order book of market.
+func (b *Bybit) GetOrderbook(ctx context.Context, market string) (*Orderbook, error) {
+	var response struct {
+		Bids [][2]string `json:"b"`
+		Asks [][2]string `json:"a"`
+	}
+	params := url.Values{"category": {bybitCategory}, "symbol": {b.Symbol(market)}, "limit": {strconv.Itoa(bybitOrderbookDepth)}}
+	if err := b.sendRequest(ctx, "GET", "/v5/market/orderbook", params, nil, false, &response); err != nil {
+		return nil, fmt.Errorf("failed to get orderbook from Bybit: %w", err)
+	}
+	bids, err := parseLevels(response.Bids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s orderbook from Bybit: %w", market, err)
+	}
+	asks, err := parseLevels(response.Asks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s orderbook from Bybit: %w", market, err)
+	}
+	return &Orderbook{Market: market, Bids: bids, Asks: asks}, nil
+}
+
+// bybitOrderRequest is the body of an order placed on /v5/order/create.
+type bybitOrderRequest struct {
+	Category    string `json:"category"`
+	Symbol      string `json:"symbol"`
+	Side        string `json:"side"`
+	OrderType   string `json:"orderType"`
+	Qty         string `json:"qty"`
+	Price       string `json:"price,omitempty"`
+	TimeInForce string `json:"timeInForce,omitempty"`
+	ReduceOnly  bool   `json:"reduceOnly,omitempty"`
+}
+
+// bybitSide converts an order side into Bybit's "Buy" and "Sell".
+func bybitSide(side OrderSide) string {
+	if side == Sell {
+		return "Sell"
+	}
+	return "Buy"
+}
+
+// PlaceOrder sends a signed order, rounding amount down to the contract's lot size and a limit
+// price to its tick size. Limit orders rest until cancelled. Bybit only acknowledges the order, so
+// it is returned as NEW; its fills are reported by GetOrderStatus.
+func (b *Bybit) PlaceOrder(ctx context.Context, market string, side OrderSide, orderType OrderType, amount, price decimal.Decimal) (*Order, error) {
+	return b.placeOrder(ctx, market, side, orderType, amount, price, false)
+}
+
+func (b *Bybit) placeOrder(ctx context.Context, market string, side OrderSide, orderType OrderType, amount, price decimal.Decimal, reduceOnly bool) (*Order, error) {
+	instrument, err := b.instrument(ctx, market)
+	if err != nil {
+		return nil, err
+	}
+	quantity := instrument.lotSize.floor(amount)
+	if !quantity.IsPositive() {
+		return nil, fmt.Errorf("order amount %s is below the Bybit lot size %s for %s", amount, instrument.lotSize, market)
+	}
+
+	request := bybitOrderRequest{
+		Category:   bybitCategory,
+		Symbol:     b.Symbol(market),
+		Side:       bybitSide(side),
+		OrderType:  "Market",
+		Qty:        instrument.lotSize.format(quantity),
+		ReduceOnly: reduceOnly,
+	}
+	if orderType == Limit {
+		price = instrument.tickSize.round(price)
+		request.OrderType = "Limit"
+		request.Price = instrument.tickSize.format(price)
+		request.TimeInForce = "GTC"
+	}
+	var response struct {
+		OrderID string `json:"orderId"`
+	}
+	if err := b.sendRequest(ctx, "POST", "/v5/order/create", nil, request, true, &response); err != nil {
+		return nil, fmt.Errorf("failed to place order on Bybit: %w", err)
+	}
+	return &Order{
+		ID:        response.OrderID,
+		Market:    market,
+		Side:      side,
+		Type:      orderType,
+		Price:     price,
+		Amount:    quantity,
+		Status:    "NEW",
+		Timestamp: time.Now().Unix(),
+	}, nil
+}
+
+// SetLeverage sets the leverage of both sides of market. Bybit rejects a change to the leverage
+// already set, which is not an error here.
+func (b *Bybit) SetLeverage(ctx context.Context, market string, leverage float64) error {
+	value := strconv.FormatFloat(leverage, 'f', -1, 64)
+	request := map[string]string{"category": bybitCategory, "symbol": b.Symbol(market), "buyLeverage": value, "sellLeverage": value}
+	err := b.sendRequest(ctx, "POST", "/v5/position/set-leverage", nil, request, true, nil)
+	if err != nil && !strings.Contains(err.Error(), "retCode 110043") {
+		return fmt.Errorf("failed to set %gx leverage for %s on Bybit: %w", leverage, market, err)
+	}
+	return nil
+}
+
+// ServerTime returns the time of the Bybit server.
+func (b *Bybit) ServerTime(ctx context.Context) (time.Time, error) {
+	var response struct {
+		TimeNano string `json:"timeNano"`
+	}
+	if err := b.sendRequest(ctx, "GET", "/v5/market/time", nil, nil, false, &response); err != nil {
+		return time.Time{}, fmt.Errorf("failed to get the Bybit server time: %w", err)
+	}
+	nanos, err := strconv.ParseInt(response.TimeNano, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse the Bybit server time %q: %w", response.TimeNano, err)
+	}
+	return time.Unix(0, nanos), nil
+}
+
+// GetMarketInfo returns the trading rules of the contract for market.
+func (b *Bybit) GetMarketInfo(ctx context.Context, market string) (*MarketInfo, error) {
+	rules, err := b.instrument(ctx, market)
+	if err != nil {
+		return nil, err
+	}
+	return &MarketInfo{
+		Market:             market,
+		TickSize:           rules.tickSize.step,
+		LotSize:            rules.lotSize.step,
+		MinSize:            rules.minQty,
+		MinNotional:        rules.minNotional,
+		MaxLeverage:        rules.maxLeverage,
+		ContractMultiplier: 1,
+	}, nil
+}
+
+// BybitOrder is an order as listed by the order endpoints.
+type BybitOrder struct {
+	OrderID     string `json:"orderId"`
+	Side        string `json:"side"`
+	OrderType   string `json:"orderType"`
+	Price       string `json:"price"`
+	AvgPrice    string `json:"avgPrice"`
+	Qty         string `json:"qty"`
+	CumExecQty  string `json:"cumExecQty"`
+	OrderStatus string `json:"orderStatus"`
+	UpdatedTime string `json:"updatedTime"`
+}
+
+// order converts the listed order into an Order on market. Price is the average fill price once
+// anything has filled, and the limit price before. Statuses are upper-cased, e.g. "FILLED".
+func (o BybitOrder) order(market string) *Order {
+	price := parseDecimal(o.AvgPrice)
+	if price.IsZero() {
+		price = parseDecimal(o.Price)
+	}
+	amount := parseDecimal(o.Qty)
+	filled := parseDecimal(o.CumExecQty)
+	updated, _ := strconv.ParseInt(o.UpdatedTime, 10, 64)
+	return &Order{
+		ID:        o.OrderID,
+		Market:    market,
+		Side:      OrderSide(strings.ToUpper(o.Side)),
+		Type:      OrderType(strings.ToUpper(o.OrderType)),
+		Price:     price,
+		Amount:    amount,
+		Filled:    filled,
+		Status:    strings.ToUpper(o.OrderStatus),
+		Timestamp: updated / 1000,
+	}
+}
+
+// GetOrderStatus returns the state of an order. Open and recently closed orders are listed by
+// the realtime endpoint; older ones are looked up in the order history.
+func (b *Bybit) GetOrderStatus(ctx context.Context, orderID string, market string) (*Order, error) {
+	params := url.Values{"category": {bybitCategory}, "symbol": {b.Symbol(market)}, "orderId": {orderID}}
+	for _, endpoint := range []string{"/v5/order/realtime", "/v5/order/history"} {
+		var response struct {
+			List []BybitOrder `json:"list"`
+		}
+		if err := b.sendRequest(ctx, "GET", endpoint, params, nil, true, &response); err != nil {
+			return nil, fmt.Errorf("failed to get order status from Bybit: %w", err)
+		}
+		if len(response.List) > 0 {
+			return response.List[0].order(market), nil
+		}
+	}
+	return nil, fmt.Errorf("order %s not found on Bybit", orderID)
+}
+
+func (b *Bybit) CancelOrder(ctx context.Context, orderID string, market string) error {
+	request := map[string]string{"category": bybitCategory, "symbol": b.Symbol(market), "orderId": orderID}
+	if err := b.sendRequest(ctx, "POST", "/v5/order/cancel", nil, request, true, nil); err != nil {
+		return fmt.Errorf("failed to cancel order on Bybit: %w", err)
+	}
+	return nil
+}
+
+// ClosePosition reduces the position in market with a reduce-only market order on the opposite
+// side, so it can never flip the position.
+func (b *Bybit) ClosePosition(ctx context.Context, market string, side OrderSide, amount decimal.Decimal) (*Order, error) {
+	closeSide := Sell
+	if side == Sell {
+		closeSide = Buy
+	}
+	return b.placeOrder(ctx, market, closeSide, Market, amount, decimal.Zero, true)
+}
+
+// GetBalance returns the equity of asset in the unified account: its wallet balance plus the
+// unrealized PnL of the positions margined in it.
+func (b *Bybit) GetBalance(ctx context.Context, asset string) (float64, error) {
+	if asset == "" {
+		asset = bybitQuoteAsset
+	}
+	var response struct {
+		List []struct {
+			Coin []struct {
+				Coin   string `json:"coin"`
+				Equity string `json:"equity"`
+			} `json:"coin"`
+		} `json:"list"`
+	}
+	params := url.Values{"accountType": {bybitAccountType}, "coin": {strings.ToUpper(asset)}}
+	if err := b.sendRequest(ctx, "GET", "/v5/account/wallet-balance", params, nil, true, &response); err != nil {
+		return 0, fmt.Errorf("failed to get balance from Bybit: %w", err)
+	}
+	for _, account := range response.List {
+		for _, coin := range account.Coin {
+			if !strings.EqualFold(coin.Coin, asset) {
+				continue
+			}
+			equity, err := strconv.ParseFloat(coin.Equity, 64)
+			if err != nil {
+				return 0, fmt.Errorf("failed to parse balance float from Bybit: %w", err)
+			}
+			return equity, nil
+		}
+	}
+	return 0, nil
+}
+
+// BybitPosition is a position as listed by the position endpoint. Side is empty when flat, and
+// TradeMode is 1 for isolated margin.
+type BybitPosition struct {
+	Symbol          string `json:"symbol"`
+	Side            string `json:"side"`
+	Size            string `json:"size"`
+	AvgPrice        string `json:"avgPrice"`
+	MarkPrice       string `json:"markPrice"`
+	LiqPrice        string `json:"liqPrice"`
+	PositionBalance string `json:"positionBalance"`
+	TradeMode       int    `json:"tradeMode"`
+}
+
+// side returns Buy for longs and Sell for shorts.
+func (p BybitPosition) side() OrderSide {
+	if p.Side == "Sell" {
+		return Sell
+	}
+	return Buy
+}
+
+// positions fetches the open USDT perpetual positions of the account, or only the one in market
+// if it is set.
+func (b *Bybit) positions(ctx context.Context, market string) ([]BybitPosition, error) {
+	var open []BybitPosition
+	cursor := ""
+	for {
+		params := url.Values{"category": {bybitCategory}, "limit": {"200"}}
+		if market != "" {
+			params.Set("symbol", b.Symbol(market))
+		} else {
+			params.Set("settleCoin", bybitQuoteAsset)
+		}
+		if cursor != "" {
+			params.Set("cursor", cursor)
+		}
+		var response struct {
+			List           []BybitPosition `json:"list"`
+			NextPageCursor string          `json:"nextPageCursor"`
+		}
+		if err := b.sendRequest(ctx, "GET", "/v5/position/list", params, nil, true, &response); err != nil {
+			return nil, err
+		}
+		for _, p := range response.List {
+			if size, _ := strconv.ParseFloat(p.Size, 64); size != 0 && p.Side != "" {
+				open = append(open, p)
+			}
+		}
+		if response.NextPageCursor == "" || len(response.List) == 0 {
+			return open, nil
+		}
+		cursor = response.NextPageCursor
+	}
+}
+
+// GetPositions fetches the open positions of the account.
+func (b *Bybit) GetPositions(ctx context.Context) ([]Position, error) {
+	listed, err := b.positions(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get positions from Bybit: %w", err)
+	}
+	var positions []Position
+	for _, p := range listed {
+		market, ok := b.market(p.Symbol)
+		if !ok {
+			continue
+		}
+		size, err := strconv.ParseFloat(p.Size, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse position size for %s from Bybit: %w", market, err)
+		}
+		entry, _ := strconv.ParseFloat(p.AvgPrice, 64)
+		positions = append(positions, Position{Market: market, Side: p.side(), Size: size, EntryPrice: entry})
+	}
+	return positions, nil
+}
+
+// GetPositionRisk fetches the margin and liquidation price of the open position in market.
+// Isolated positions are backed by their own margin, cross-margined ones by the account equity.
+func (b *Bybit) GetPositionRisk(ctx context.Context, market string) (*PositionRisk, error) {
+	listed, err := b.positions(ctx, market)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the %s position from Bybit: %w", market, err)
+	}
+	if len(listed) == 0 {
+		return nil, fmt.Errorf("no open %s position on Bybit", market)
+	}
+	p := listed[0]
+	size, err := strconv.ParseFloat(p.Size, 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse position size for %s from Bybit: %w", market, err)
+	}
+	mark, err := strconv.ParseFloat(p.MarkPrice, 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse mark price for %s from Bybit: %w", market, err)
+	}
+	liquidation, _ := strconv.ParseFloat(p.LiqPrice, 64)
+	var margin float64
+	if p.TradeMode == 1 {
+		margin, _ = strconv.ParseFloat(p.PositionBalance, 64)
+	} else if margin, err = b.GetBalance(ctx, bybitQuoteAsset); err != nil {
+		return nil, err
+	}
+	return &PositionRisk{Market: market, Side: p.side(), Size: size, MarkPrice: mark, LiquidationPrice: liquidation, Margin: margin}, nil
+}
+
+// bybitInstrument holds the trading rules of a contract.
+type bybitInstrument struct {
+	lotSize         decimalStep
+	tickSize        decimalStep
+	minQty          decimal.Decimal
+	minNotional     float64
+	maxLeverage     float64
+	fundingInterval time.Duration
+}
+
+// loadInstruments returns the trading rules of every linear contract, fetched on first use and
+// cached.
+func (b *Bybit) loadInstruments(ctx context.Context) (map[string]bybitInstrument, error) {
+	b.instrumentsMu.Lock()
+	defer b.instrumentsMu.Unlock()
+	if b.instruments != nil {
+		return b.instruments, nil
+	}
+	instruments := make(map[string]bybitInstrument)
+	cursor := ""
+	for {
+		params := url.Values{"category": {bybitCategory}, "limit": {"1000"}}
+		if cursor != "" {
+			params.Set("cursor", cursor)
+		}
+		var response struct {
+			List []struct {
+				Symbol          string `json:"symbol"`
+				FundingInterval int    `json:"fundingInterval"`
+				LotSizeFilter   struct {
+					QtyStep          string `json:"qtyStep"`
+					MinOrderQty      string `json:"minOrderQty"`
+					MinNotionalValue string `json:"minNotionalValue"`
+				} `json:"lotSizeFilter"`
+				PriceFilter struct {
+					TickSize string `json:"tickSize"`
+				} `json:"priceFilter"`
+				LeverageFilter struct {
+					MaxLeverage string `json:"maxLeverage"`
+				} `json:"leverageFilter"`
+			} `json:"list"`
+			NextPageCursor string `json:"nextPageCursor"`
+		}
+		if err := b.sendRequest(ctx, "GET", "/v5/market/instruments-info", params, nil, false, &response); err != nil {
+			return nil, fmt.Errorf("failed to get instruments from Bybit: %w", err)
+		}
+		for _, i := range response.List {
+			instruments[i.Symbol] = bybitInstrument{
+				lotSize:         parseDecimalStep(i.LotSizeFilter.QtyStep),
+				tickSize:        parseDecimalStep(i.PriceFilter.TickSize),
+				minQty:          parseDecimal(i.LotSizeFilter.MinOrderQty),
+				minNotional:     parseRule(i.LotSizeFilter.MinNotionalValue),
+				maxLeverage:     parseRule(i.LeverageFilter.MaxLeverage),
+				fundingInterval: time.Duration(i.FundingInterval) * time.Minute,
+			}
+		}
+		if response.NextPageCursor == "" || len(response.List) == 0 {
+			break
+		}
+		cursor = response.NextPageCursor
+	}
+	b.instruments = instruments
+	return instruments, nil
+}
+
+// instrument returns the trading rules of the contract for market.
+func (b *Bybit) instrument(ctx context.Context, market string) (bybitInstrument, error) {
+	instruments, err := b.loadInstruments(ctx)
+	if err != nil {
+		return bybitInstrument{}, err
+	}
+	instrument, ok := instruments[b.Symbol(market)]
+	if !ok {
+		return bybitInstrument{}, fmt.Errorf("market %s not found on Bybit", market)
+	}
+	return instrument, nil
+}
+
+// sendRequest sends a request to the Bybit v5 API and decodes the result of the response into
+// out. GET requests carry params in the query string, POST requests carry body as JSON. Signed
+// requests are authenticated with an HMAC-SHA256 of the timestamp, API key, receive window and
+// the query or body. A non-zero retCode is returned as an error.
+func (b *Bybit) sendRequest(ctx context.Context, method, endpoint string, params url.Values, body interface{}, signed bool, out interface{}) error {
+	query := params.Encode()
+	payload := query
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		payload = string(encoded)
+		reader = bytes.NewReader(encoded)
+	}
+	target := b.baseURL + endpoint
+	if query != "" {
+		target += "?" + query
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if signed {
+		timestamp := strconv.FormatInt(time.Now().UnixMilli(), 10)
+		mac := hmac.New(sha256.New, []byte(b.secretKey))
+		mac.Write([]byte(timestamp + b.apiKey + bybitRecvWindow + payload))
+		req.Header.Set("X-BAPI-API-KEY", b.apiKey)
+		req.Header.Set("X-BAPI-TIMESTAMP", timestamp)
+		req.Header.Set("X-BAPI-RECV-WINDOW", bybitRecvWindow)
+		req.Header.Set("X-BAPI-SIGN", hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	var response struct {
+		RetCode int             `json:"retCode"`
+		RetMsg  string          `json:"retMsg"`
+		Result  json.RawMessage `json:"result"`
+	}
+	if err := doJSON(b.client, req, &response); err != nil {
+		return err
+	}
+	if response.RetCode != 0 {
+		return fmt.Errorf("API error: retCode %d - %s", response.RetCode, response.RetMsg)
+	}
+	if out == nil || len(response.Result) == 0 {
+		return nil
+	}
+	return json.Unmarshal(response.Result, out)
+}
@@ -0,0 +1,144 @@
+package exchange
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// bybitInstruments lists BTCUSDT settling every 8 hours and a long-tail contract every 4.
+const bybitInstruments = `{"retCode":0,"retMsg":"OK","result":{"nextPageCursor":"","list":[
+	{"symbol":"BTCUSDT","fundingInterval":480,"lotSizeFilter":{"qtyStep":"0.001"},"priceFilter":{"tickSize":"0.10"}},
+	{"symbol":"WIFUSDT","fundingInterval":240,"lotSizeFilter":{"qtyStep":"1"},"priceFilter":{"tickSize":"0.0001"}}]}}`
+
+func TestBybitFundingRates(t *testing.T) {
+	api := newFakeAPI(t)
+	api.respond("GET", "/v5/market/instruments-info", http.StatusOK, bybitInstruments)
+	api.respond("GET", "/v5/market/tickers", http.StatusOK, `{"retCode":0,"retMsg":"OK","result":{"category":"linear","list":[
+		{"symbol":"BTCUSDT","markPrice":"65000.10","fundingRate":"0.0001","nextFundingTime":"1700006400000"},
+		{"symbol":"WIFUSDT","markPrice":"2.5","fundingRate":"0.0005","nextFundingTime":"1700006400000"},
+		{"symbol":"BTCPERP","markPrice":"65000","fundingRate":"0.0002","nextFundingTime":"1700006400000"}]}}`)
+	ex := newTestBybit(api)
+
+	rates, err := ex.GetFundingRates(context.Background())
+	if err != nil {
+		t.Fatalf("GetFundingRates: %v", err)
+	}
+	if len(rates) != 2 {
+		t.Fatalf("expected only the USDT perpetuals, got %d rates", len(rates))
+	}
+	if r := rates[0]; r.Market != "BTC-USD" || !r.Rate.Equal(decimal.NewFromFloat(0.0001)) || r.NextTime != 1700006400 {
+		t.Errorf("unexpected BTC rate %+v", r)
+	}
+	if r := rates[1]; r.Market != "WIF-USD" || !r.Rate.Equal(decimal.NewFromFloat(0.001)) {
+		t.Errorf("expected the 4-hourly WIF rate scaled to 8 hours, got %+v", r)
+	}
+}
+
+func TestBybitSignedOrders(t *testing.T) {
+	api := newFakeAPI(t)
+	api.respond("GET", "/v5/market/instruments-info", http.StatusOK, bybitInstruments)
+	var body []byte
+	var headers http.Header
+	api.handle("POST", "/v5/order/create", func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		headers = r.Header.Clone()
+		_, _ = w.Write([]byte(`{"retCode":0,"retMsg":"OK","result":{"orderId":"1321003749386327552","orderLinkId":""}}`))
+	})
+	ex := newTestBybit(api)
+
+	order, err := ex.PlaceOrder(context.Background(), "BTC-USD", Sell, Limit, decimal.NewFromFloat(0.01234), decimal.NewFromFloat(64999.87))
+	if err != nil {
+		t.Fatalf("PlaceOrder: %v", err)
+	}
+	if order.ID != "1321003749386327552" || order.Status != "NEW" || !order.Amount.Equal(decimal.NewFromFloat(0.012)) {
+		t.Errorf("unexpected order %+v", order)
+	}
+	var request map[string]interface{}
+	if err := json.Unmarshal(body, &request); err != nil {
+		t.Fatalf("order body is not JSON: %v", err)
+	}
+	if request["category"] != "linear" || request["symbol"] != "BTCUSDT" || request["side"] != "Sell" || request["orderType"] != "Limit" ||
+		request["qty"] != "0.012" || request["price"] != "64999.9" || request["timeInForce"] != "GTC" {
+		t.Errorf("unexpected order request %s", body)
+	}
+	mac := hmac.New(sha256.New, []byte("test-secret"))
+	mac.Write([]byte(headers.Get("X-BAPI-TIMESTAMP") + "test-key" + headers.Get("X-BAPI-RECV-WINDOW") + string(body)))
+	if headers.Get("X-BAPI-API-KEY") != "test-key" || headers.Get("X-BAPI-SIGN") != hex.EncodeToString(mac.Sum(nil)) {
+		t.Errorf("expected an HMAC-SHA256 signature over the timestamp, key, window and body, got %v", headers)
+	}
+
+	if _, err := ex.ClosePosition(context.Background(), "BTC-USD", Buy, decimal.NewFromFloat(0.012)); err != nil {
+		t.Fatalf("ClosePosition: %v", err)
+	}
+	if err := json.Unmarshal(body, &request); err != nil || request["side"] != "Sell" || request["orderType"] != "Market" || request["reduceOnly"] != true {
+		t.Errorf("expected a reduce-only market sell to close a long, got %s", body)
+	}
+}
+
+func TestBybitOrderStatusFallsBackToHistory(t *testing.T) {
+	api := newFakeAPI(t)
+	api.respond("GET", "/v5/order/realtime", http.StatusOK, `{"retCode":0,"retMsg":"OK","result":{"list":[]}}`)
+	api.respond("GET", "/v5/order/history", http.StatusOK, `{"retCode":0,"retMsg":"OK","result":{"list":[
+		{"orderId":"7","side":"Buy","orderType":"Limit","price":"65000","avgPrice":"64990","qty":"0.01","cumExecQty":"0.01","orderStatus":"Filled","updatedTime":"1700000000000"}]}}`)
+	ex := newTestBybit(api)
+
+	order, err := ex.GetOrderStatus(context.Background(), "7", "BTC-USD")
+	if err != nil {
+		t.Fatalf("GetOrderStatus: %v", err)
+	}
+	if order.ID != "7" || order.Market != "BTC-USD" || order.Side != Buy || order.Type != Limit || !order.Price.Equal(decimal.NewFromInt(64990)) || !order.Amount.Equal(decimal.NewFromFloat(0.01)) || !order.Filled.Equal(decimal.NewFromFloat(0.01)) || order.Status != "FILLED" || order.Timestamp != 1700000000 {
+		t.Errorf("unexpected order %+v", *order)
+	}
+}
+
+func TestBybitPositionsAndErrors(t *testing.T) {
+	api := newFakeAPI(t)
+	api.respond("GET", "/v5/position/list", http.StatusOK, `{"retCode":0,"retMsg":"OK","result":{"nextPageCursor":"","list":[
+		{"symbol":"BTCUSDT","side":"Sell","size":"0.02","avgPrice":"64000","markPrice":"65000","liqPrice":"78000","positionBalance":"300","tradeMode":1},
+		{"symbol":"ETHUSDT","side":"","size":"0","avgPrice":"0","markPrice":"3200","liqPrice":"","positionBalance":"0","tradeMode":0}]}}`)
+	api.respond("GET", "/v5/account/wallet-balance", http.StatusOK, `{"retCode":10003,"retMsg":"API key is invalid.","result":{}}`)
+	ex := newTestBybit(api)
+
+	positions, err := ex.GetPositions(context.Background())
+	if err != nil {
+		t.Fatalf("GetPositions: %v", err)
+	}
+	if len(positions) != 1 || positions[0] != (Position{Market: "BTC-USD", Side: Sell, Size: 0.02, EntryPrice: 64000}) {
+		t.Errorf("expected only the open BTC short, got %+v", positions)
+	}
+	risk, err := ex.GetPositionRisk(context.Background(), "BTC-USD")
+	if err != nil {
+		t.Fatalf("GetPositionRisk: %v", err)
+	}
+	if distance, ok := risk.LiquidationDistance(); risk.Margin != 300 || !ok || distance != 0.2 {
+		t.Errorf("unexpected risk %+v (distance %f)", risk, distance)
+	}
+
+	if _, err := ex.GetBalance(context.Background(), "USDT"); err == nil {
+		t.Error("expected a non-zero retCode to be reported as an error")
+	}
+}
+
+func TestBybitServerTimeAndLeverage(t *testing.T) {
+	api := newFakeAPI(t)
+	api.respond("GET", "/v5/market/time", http.StatusOK, `{"retCode":0,"retMsg":"OK","result":{"timeSecond":"1700000000","timeNano":"1700000000123456789"}}`)
+	api.respond("POST", "/v5/position/set-leverage", http.StatusOK, `{"retCode":110043,"retMsg":"leverage not modified","result":{}}`)
+	ex := newTestBybit(api)
+
+	server, err := ex.ServerTime(context.Background())
+	if err != nil || !server.Equal(time.Unix(1700000000, 123456789)) {
+		t.Errorf("ServerTime = %s, %v", server, err)
+	}
+	if err := ex.SetLeverage(context.Background(), "BTC-USD", 3); err != nil {
+		t.Errorf("expected an unchanged leverage to succeed, got %v", err)
+	}
+}
@@ -0,0 +1,277 @@
+package exchange
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// ChaosConfig controls the faults injected by a Chaos wrapper. Rates are probabilities in [0, 1]
+// evaluated independently for every call.
+type ChaosConfig struct {
+	// Latency is added to every call, plus a random extra delay of up to Jitter.
+	Latency time.Duration
+	Jitter  time.Duration
+	// ErrorRate is the probability that a call fails with one of ErrorCodes (503 if empty).
+	ErrorRate  float64
+	ErrorCodes []int
+	// TimeoutRate is the probability that a call blocks for Timeout and then fails.
+	TimeoutRate float64
+	Timeout     time.Duration
+	// PartialFillRate is the probability that an order only fills PartialFillRatio of its amount.
+	PartialFillRate  float64
+	PartialFillRatio float64
+	// Seed makes the injected faults reproducible. Zero uses the current time.
+	Seed int64
+}
+
+// Chaos wraps an Exchange and injects latency, timeouts, partial fills and API errors,
+// so compensation and recovery paths can be exercised locally.
+type Chaos struct {
+	Exchange
+	cfg ChaosConfig
+	mu  sync.Mutex
+	rnd *rand.Rand
+}
+
+// NewChaos wraps ex with the faults described by cfg.
+func NewChaos(ex Exchange, cfg ChaosConfig) *Chaos {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	if cfg.PartialFillRatio <= 0 || cfg.PartialFillRatio >= 1 {
+		cfg.PartialFillRatio = 0.5
+	}
+	if len(cfg.ErrorCodes) == 0 {
+		cfg.ErrorCodes = []int{http.StatusServiceUnavailable}
+	}
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &Chaos{Exchange: ex, cfg: cfg, rnd: rand.New(rand.NewSource(seed))}
+}
+
+// Enabled reports whether cfg injects any fault at all.
+func (cfg ChaosConfig) Enabled() bool {
+	return cfg.Latency > 0 || cfg.Jitter > 0 || cfg.ErrorRate > 0 || cfg.TimeoutRate > 0 || cfg.PartialFillRate > 0
+}
+
+func (c *Chaos) Name() string {
+	return c.Exchange.Name()
+}
+
+// FundingInterval forwards to the wrapped exchange.
+func (c *Chaos) FundingInterval() time.Duration {
+	return FundingIntervalOf(c.Exchange)
+}
+
+// CollateralAsset forwards to the wrapped exchange.
+func (c *Chaos) CollateralAsset() string {
+	if a, ok := c.Exchange.(interface{ CollateralAsset() string }); ok {
+		return a.CollateralAsset()
+	}
+	return ""
+}
+
+// SetMarginMode forwards to the wrapped exchange.
+func (c *Chaos) SetMarginMode(ctx context.Context, market string, mode MarginMode) error {
+	setter, ok := c.Exchange.(MarginModeSetter)
+	if !ok {
+		return fmt.Errorf("%s does not support selecting the margin mode", c.Exchange.Name())
+	}
+	return setter.SetMarginMode(ctx, market, mode)
+}
+
+// SetLeverage forwards to the wrapped exchange.
+func (c *Chaos) SetLeverage(ctx context.Context, market string, leverage float64) error {
+	setter, ok := c.Exchange.(LeverageSetter)
+	if !ok {
+		return fmt.Errorf("%s does not support setting the leverage", c.Exchange.Name())
+	}
+	return setter.SetLeverage(ctx, market, leverage)
+}
+
+// GetMarketInfo forwards to the wrapped exchange.
+func (c *Chaos) GetMarketInfo(ctx context.Context, market string) (*MarketInfo, error) {
+	provider, ok := c.Exchange.(MarketInfoProvider)
+	if !ok {
+		return nil, ErrMarketInfoUnsupported
+	}
+	return provider.GetMarketInfo(ctx, market)
+}
+
+// Stream forwards to the wrapped exchange. Pushed events are not delayed or dropped.
+func (c *Chaos) Stream(markets []string, handler StreamHandler, stop <-chan struct{}) error {
+	streamer, ok := c.Exchange.(StreamingExchange)
+	if !ok {
+		return ErrStreamingUnsupported
+	}
+	return streamer.Stream(markets, handler, stop)
+}
+
+// GetFundingPayments forwards to the wrapped exchange.
+func (c *Chaos) GetFundingPayments(ctx context.Context, market string, since time.Time) ([]FundingPayment, error) {
+	reporter, ok := c.Exchange.(FundingPaymentReporter)
+	if !ok {
+		return nil, ErrFundingPaymentsUnsupported
+	}
+	if err := c.inject(ctx, "GetFundingPayments"); err != nil {
+		return nil, err
+	}
+	return reporter.GetFundingPayments(ctx, market, since)
+}
+
+// GetForcedCloses forwards to the wrapped exchange.
+func (c *Chaos) GetForcedCloses(ctx context.Context, market string, since time.Time) ([]ForcedClose, error) {
+	reporter, ok := c.Exchange.(LiquidationReporter)
+	if !ok {
+		return nil, ErrForcedClosesUnsupported
+	}
+	if err := c.inject(ctx, "GetForcedCloses"); err != nil {
+		return nil, err
+	}
+	return reporter.GetForcedCloses(ctx, market, since)
+}
+
+// GetPositionRisk forwards to the wrapped exchange.
+func (c *Chaos) GetPositionRisk(ctx context.Context, market string) (*PositionRisk, error) {
+	reporter, ok := c.Exchange.(PositionRiskReporter)
+	if !ok {
+		return nil, ErrPositionRiskUnsupported
+	}
+	if err := c.inject(ctx, "GetPositionRisk"); err != nil {
+		return nil, err
+	}
+	return reporter.GetPositionRisk(ctx, market)
+}
+
+func (c *Chaos) chance(p float64) bool {
+	if p <= 0 {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.rnd.Float64() < p
+}
+
+// inject applies latency and decides whether op fails. Injected delays end early when ctx is done.
+func (c *Chaos) inject(ctx context.Context, op string) error {
+	delay := c.cfg.Latency
+	if c.cfg.Jitter > 0 {
+		c.mu.Lock()
+		delay += time.Duration(c.rnd.Int63n(int64(c.cfg.Jitter)))
+		c.mu.Unlock()
+	}
+	if err := sleep(ctx, delay); err != nil {
+		return err
+	}
+	if c.chance(c.cfg.TimeoutRate) {
+		if err := sleep(ctx, c.cfg.Timeout); err != nil {
+			return err
+		}
+		return fmt.Errorf("%s %s: injected timeout after %s: %w", c.Name(), op, c.cfg.Timeout, context.DeadlineExceeded)
+	}
+	if c.chance(c.cfg.ErrorRate) {
+		c.mu.Lock()
+		code := c.cfg.ErrorCodes[c.rnd.Intn(len(c.cfg.ErrorCodes))]
+		c.mu.Unlock()
+		return fmt.Errorf("%s %s: API error: %d %s - injected failure", c.Name(), op, code, http.StatusText(code))
+	}
+	return nil
+}
+
+// sleep waits for d, or returns the context's error if it is done first.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *Chaos) GetFundingRates(ctx context.Context) ([]*FundingRate, error) {
+	if err := c.inject(ctx, "GetFundingRates"); err != nil {
+		return nil, err
+	}
+	return c.Exchange.GetFundingRates(ctx)
+}
+
+func (c *Chaos) GetOrderbook(ctx context.Context, market string) (*Orderbook, error) {
+	if err := c.inject(ctx, "GetOrderbook"); err != nil {
+		return nil, err
+	}
+	return c.Exchange.GetOrderbook(ctx, market)
+}
+
+func (c *Chaos) GetMarkPrice(ctx context.Context, market string) (float64, error) {
+	if err := c.inject(ctx, "GetMarkPrice"); err != nil {
+		return 0, err
+	}
+	return c.Exchange.GetMarkPrice(ctx, market)
+}
+
+// PlaceOrder may submit only part of the amount and report the order as partially filled.
+func (c *Chaos) PlaceOrder(ctx context.Context, market string, side OrderSide, orderType OrderType, amount, price decimal.Decimal) (*Order, error) {
+	if err := c.inject(ctx, "PlaceOrder"); err != nil {
+		return nil, err
+	}
+	if !c.chance(c.cfg.PartialFillRate) {
+		return c.Exchange.PlaceOrder(ctx, market, side, orderType, amount, price)
+	}
+	filled := amount.Mul(decimal.NewFromFloat(c.cfg.PartialFillRatio))
+	order, err := c.Exchange.PlaceOrder(ctx, market, side, orderType, filled, price)
+	if err != nil {
+		return nil, err
+	}
+	order.Amount = amount
+	order.Filled = filled
+	order.Status = "PARTIALLY_FILLED"
+	return order, nil
+}
+
+func (c *Chaos) GetOrderStatus(ctx context.Context, orderID string, market string) (*Order, error) {
+	if err := c.inject(ctx, "GetOrderStatus"); err != nil {
+		return nil, err
+	}
+	return c.Exchange.GetOrderStatus(ctx, orderID, market)
+}
+
+func (c *Chaos) CancelOrder(ctx context.Context, orderID string, market string) error {
+	if err := c.inject(ctx, "CancelOrder"); err != nil {
+		return err
+	}
+	return c.Exchange.CancelOrder(ctx, orderID, market)
+}
+
+func (c *Chaos) GetBalance(ctx context.Context, asset string) (float64, error) {
+	if err := c.inject(ctx, "GetBalance"); err != nil {
+		return 0, err
+	}
+	return c.Exchange.GetBalance(ctx, asset)
+}
+
+func (c *Chaos) GetPositions(ctx context.Context) ([]Position, error) {
+	if err := c.inject(ctx, "GetPositions"); err != nil {
+		return nil, err
+	}
+	return c.Exchange.GetPositions(ctx)
+}
+
+func (c *Chaos) ClosePosition(ctx context.Context, market string, side OrderSide, amount decimal.Decimal) (*Order, error) {
+	if err := c.inject(ctx, "ClosePosition"); err != nil {
+		return nil, err
+	}
+	return c.Exchange.ClosePosition(ctx, market, side, amount)
+}
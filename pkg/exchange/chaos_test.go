@@ -0,0 +1,82 @@
+package exchange
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+type fakeExchange struct {
+	placed []decimal.Decimal
+	// mark overrides the default mark price of 100.
+	mark  float64
+	rates []*FundingRate
+}
+
+func (f *fakeExchange) Name() string    { return "fake" }
+func (f *fakeExchange) SetTestnet(bool) {}
+func (f *fakeExchange) GetFundingRates(ctx context.Context) ([]*FundingRate, error) {
+	return f.rates, nil
+}
+func (f *fakeExchange) GetOrderbook(context.Context, string) (*Orderbook, error) {
+	return nil, ErrOrderbookUnsupported
+}
+func (f *fakeExchange) GetMarkPrice(context.Context, string) (float64, error) {
+	if f.mark > 0 {
+		return f.mark, nil
+	}
+	return 100, nil
+}
+func (f *fakeExchange) PlaceOrder(ctx context.Context, market string, side OrderSide, orderType OrderType, amount, price decimal.Decimal) (*Order, error) {
+	f.placed = append(f.placed, amount)
+	return &Order{ID: "1", Market: market, Side: side, Amount: amount, Filled: amount, Status: "FILLED"}, nil
+}
+func (f *fakeExchange) GetOrderStatus(context.Context, string, string) (*Order, error) {
+	return nil, nil
+}
+func (f *fakeExchange) CancelOrder(context.Context, string, string) error    { return nil }
+func (f *fakeExchange) GetBalance(context.Context, string) (float64, error)  { return 100, nil }
+func (f *fakeExchange) GetPositions(ctx context.Context) ([]Position, error) { return nil, nil }
+func (f *fakeExchange) ClosePosition(context.Context, string, OrderSide, decimal.Decimal) (*Order, error) {
+	return nil, nil
+}
+
+func TestChaosInjectsFaults(t *testing.T) {
+	fake := &fakeExchange{}
+
+	failing := NewChaos(fake, ChaosConfig{ErrorRate: 1, ErrorCodes: []int{429}, Seed: 1})
+	if _, err := failing.GetBalance(context.Background(), "USDC"); err == nil {
+		t.Fatal("expected an injected error")
+	}
+
+	timingOut := NewChaos(fake, ChaosConfig{TimeoutRate: 1, Timeout: time.Millisecond, Seed: 1})
+	if _, err := timingOut.GetFundingRates(context.Background()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected a deadline error, got %v", err)
+	}
+
+	partial := NewChaos(fake, ChaosConfig{PartialFillRate: 1, PartialFillRatio: 0.25, Seed: 1})
+	order, err := partial.PlaceOrder(context.Background(), "BTC-USD", Buy, Market, decimal.NewFromInt(1), decimal.NewFromInt(100))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !order.Amount.Equal(decimal.NewFromInt(1)) || !order.Filled.Equal(decimal.NewFromFloat(0.25)) || !fake.placed[len(fake.placed)-1].Equal(decimal.NewFromFloat(0.25)) {
+		t.Errorf("expected a 25%% partial fill, got %+v (submitted %v)", order, fake.placed)
+	}
+
+	clean := NewChaos(fake, ChaosConfig{Seed: 1})
+	if balance, err := clean.GetBalance(context.Background(), "USDC"); err != nil || balance != 100 {
+		t.Errorf("expected calls to pass through, got %f, %v", balance, err)
+	}
+}
+
+func TestChaosLatencyEndsWithTheContext(t *testing.T) {
+	slow := NewChaos(&fakeExchange{}, ChaosConfig{Latency: time.Hour, Seed: 1})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := slow.GetBalance(ctx, "USDC"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the injected latency to be cut short, got %v", err)
+	}
+}
@@ -0,0 +1,43 @@
+package exchange
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Costs is what trading on an exchange costs besides the price moving: the taker fee charged on
+// the notional of each fill, and a fixed gas cost per order on venues that settle on chain.
+type Costs struct {
+	TakerFeeBps float64
+	GasUSD      float64
+}
+
+// ParseCosts parses entries of the form "EXCHANGE=FEE_BPS[:GAS_USD]", e.g. "Lighter=0" or
+// "Drift=3.5:0.01".
+func ParseCosts(entries []string) (map[string]Costs, error) {
+	costs := make(map[string]Costs)
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid execution cost %q, expected EXCHANGE=FEE_BPS[:GAS_USD]", entry)
+		}
+		fee, gas, hasGas := strings.Cut(value, ":")
+		var c Costs
+		var err error
+		if c.TakerFeeBps, err = strconv.ParseFloat(strings.TrimSpace(fee), 64); err != nil || c.TakerFeeBps < 0 {
+			return nil, fmt.Errorf("invalid taker fee in execution cost %q", entry)
+		}
+		if hasGas {
+			if c.GasUSD, err = strconv.ParseFloat(strings.TrimSpace(gas), 64); err != nil || c.GasUSD < 0 {
+				return nil, fmt.Errorf("invalid gas cost in execution cost %q", entry)
+			}
+		}
+		costs[strings.TrimSpace(name)] = c
+	}
+	return costs, nil
+}
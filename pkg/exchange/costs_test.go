@@ -0,0 +1,22 @@
+package exchange
+
+import "testing"
+
+func TestParseCosts(t *testing.T) {
+	costs, err := ParseCosts([]string{"Lighter=0", " Drift = 3.5:0.01 "})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := costs["Lighter"]; c != (Costs{}) {
+		t.Errorf("Lighter costs = %+v, want none", c)
+	}
+	if c := costs["Drift"]; c != (Costs{TakerFeeBps: 3.5, GasUSD: 0.01}) {
+		t.Errorf("Drift costs = %+v, want 3.5 bps and 0.01 USD of gas", c)
+	}
+
+	for _, entry := range []string{"Lighter", "Lighter=-1", "Lighter=2:gas"} {
+		if _, err := ParseCosts([]string{entry}); err == nil {
+			t.Errorf("expected %q to be rejected", entry)
+		}
+	}
+}
@@ -0,0 +1,572 @@
+package exchange
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/httpclient"
+)
+
+const (
+	DriftMainnetDataURL = "https://data.api.drift.trade"
+	DriftTestnetDataURL = "https://master-data.drift.trade"
+	DriftMainnetDLOBURL = "https://dlob.drift.trade"
+	DriftTestnetDLOBURL = "https://master.dlob.drift.trade"
+	// DriftDefaultGatewayURL is where a locally run Drift Gateway listens by default.
+	DriftDefaultGatewayURL = "http://localhost:8080"
+)
+
+const (
+	// driftMarketSuffix turns a market name into the symbol of its perpetual, e.g. SOL-PERP.
+	driftMarketSuffix = "-PERP"
+	// driftPricePrecision and driftBasePrecision scale the integer prices and sizes of the DLOB.
+	driftPricePrecision = 1e6
+	driftBasePrecision  = 1e9
+)
+
+// Drift is the implementation for Drift Protocol perpetuals on Solana. Markets such as "SOL-USD"
+// trade as the "SOL-PERP" contract, margined in USDC. Funding rates and order books are read
+// from Drift's public data API and DLOB server. Account requests and orders go through a Drift
+// Gateway, Drift's self-hosted HTTP API that holds the Solana keypair and signs the transactions,
+// so the keypair is never handled by the bot.
+type Drift struct {
+	client     *http.Client
+	dataURL    string
+	dlobURL    string
+	gatewayURL string
+	subaccount int
+	testnet    bool
+
+	// nextOrderID numbers orders, since the gateway identifies them by a user order ID from 1
+	// to 255.
+	nextOrderID atomic.Uint32
+
+	mu      sync.Mutex
+	markets map[string]driftMarket
+}
+
+// NewDrift creates a new Drift client trading through the gateway at gatewayURL, or the default
+// local gateway when it is empty.
+func NewDrift(gatewayURL string, subaccount int, testnet bool) *Drift {
+	if gatewayURL == "" {
+		gatewayURL = DriftDefaultGatewayURL
+	}
+	d := &Drift{
+		client:     &http.Client{Timeout: 10 * time.Second},
+		gatewayURL: strings.TrimRight(gatewayURL, "/"),
+		subaccount: subaccount,
+	}
+	d.SetTestnet(testnet)
+	return d
+}
+
+// SetRateLimiter makes every Drift gateway API call wait for limiter.
+func (d *Drift) SetRateLimiter(limiter *httpclient.Limiter) {
+	d.client = limiter.Client(d.client)
+}
+
+func (d *Drift) Name() string {
+	return "Drift"
+}
+
+func (d *Drift) CollateralAsset() string {
+	return "USDC"
+}
+
+// SetTestnet switches the public data to devnet or mainnet. The network the gateway trades on is
+// chosen when the gateway is started.
+func (d *Drift) SetTestnet(testnet bool) {
+	d.testnet = testnet
+	if testnet {
+		d.dataURL, d.dlobURL = DriftTestnetDataURL, DriftTestnetDLOBURL
+	} else {
+		d.dataURL, d.dlobURL = DriftMainnetDataURL, DriftMainnetDLOBURL
+	}
+}
+
+// SetTransport replaces the HTTP transport used for REST requests, e.g. with a recorder in tests.
+func (d *Drift) SetTransport(rt http.RoundTripper) {
+	d.client.Transport = rt
+}
+
+// Symbol converts "SOL-USD" into "SOL-PERP".
+func (d *Drift) Symbol(market string) string {
+	base, _, _ := strings.Cut(market, "-")
+	return strings.ToUpper(base) + driftMarketSuffix
+}
+
+// market converts a perpetual symbol back into a market name. It reports false for spot markets.
+func (d *Drift) market(symbol string) (string, bool) {
+	base, ok := strings.CutSuffix(symbol, driftMarketSuffix)
+	if !ok || base == "" {
+		return "", false
+	}
+	return base + "-USD", true
+}
+
+// DriftContract is a perpetual as listed by the data API. FundingRate is the last hourly rate,
+// NextFundingRate the predicted one and NextFundingRateTimestamp is in milliseconds.
+type DriftContract struct {
+	TickerID                 string `json:"ticker_id"`
+	ProductType              string `json:"product_type"`
+	IndexPrice               string `json:"index_price"`
+	FundingRate              string `json:"funding_rate"`
+	NextFundingRate          string `json:"next_funding_rate"`
+	NextFundingRateTimestamp string `json:"next_funding_rate_timestamp"`
+}
+
+// GetFundingRates fetches the hourly funding rate of every perpetual, with the predicted one.
+func (d *Drift) GetFundingRates(ctx context.Context) ([]*FundingRate, error) {
+	var response struct {
+		Contracts []DriftContract `json:"contracts"`
+	}
+	if err := d.sendRequest(ctx, "GET", d.dataURL+"/contracts", nil, nil, &response); err != nil {
+		return nil, fmt.Errorf("failed to get funding rates from Drift: %w", err)
+	}
+	var fundingRates []*FundingRate
+	for _, contract := range response.Contracts {
+		market, ok := d.market(contract.TickerID)
+		if !ok || contract.ProductType != "PERP" {
+			continue
+		}
+		rate, err := decimal.NewFromString(contract.FundingRate)
+		if err != nil {
+			continue
+		}
+		next, _ := strconv.ParseInt(contract.NextFundingRateTimestamp, 10, 64)
+		predicted, err := decimal.NewFromString(contract.NextFundingRate)
+		fundingRates = append(fundingRates, &FundingRate{Market: market, Rate: rate, NextTime: next / 1000, PredictedRate: predicted, HasPredicted: err == nil})
+	}
+	return fundingRates, nil
+}
+
+// driftL2 is an order book snapshot of the DLOB server. Prices and sizes are integers scaled by
+// driftPricePrecision and driftBasePrecision.
+type driftL2 struct {
+	Bids   []driftLevel `json:"bids"`
+	Asks   []driftLevel `json:"asks"`
+	Oracle int64        `json:"oracle"`
+}
+
+type driftLevel struct {
+	Price string `json:"price"`
+	Size  string `json:"size"`
+}
+
+func (d *Drift) l2(ctx context.Context, market string, depth int) (*driftL2, error) {
+	params := url.Values{
+		"marketName":    {d.Symbol(market)},
+		"depth":         {strconv.Itoa(depth)},
+		"includeOracle": {"true"},
+		"includeVamm":   {"true"},
+	}
+	var response driftL2
+	if err := d.sendRequest(ctx, "GET", d.dlobURL+"/l2", params, nil, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// GetMarkPrice returns the oracle price of market, which Drift values positions and margin at.
+func (d *Drift) GetMarkPrice(ctx context.Context, market string) (float64, error) {
+	book, err := d.l2(ctx, market, 1)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get mark price from Drift: %w", err)
+	}
+	if book.Oracle <= 0 {
+		return 0, fmt.Errorf("no oracle price for %s on Drift", market)
+	}
+	return float64(book.Oracle) / driftPricePrecision, nil
+}
+
+// GetOrderbook returns the order book of market, including the liquidity of Drift's AMM.
+func (d *Drift) GetOrderbook(ctx context.Context, market string) (*Orderbook, error) {
+	book, err := d.l2(ctx, market, 100)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get orderbook from Drift: %w", err)
+	}
+	bids, err := driftLevels(book.Bids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s orderbook from Drift: %w", market, err)
+	}
+	asks, err := driftLevels(book.Asks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s orderbook from Drift: %w", market, err)
+	}
+	return &Orderbook{Market: market, Bids: bids, Asks: asks}, nil
+}
+
+func driftLevels(levels []driftLevel) ([]PriceLevel, error) {
+	parsed := make([]PriceLevel, 0, len(levels))
+	for _, l := range levels {
+		level, err := parseLevel(l.Price, l.Size)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, PriceLevel{Price: level.Price / driftPricePrecision, Size: level.Size / driftBasePrecision})
+	}
+	return parsed, nil
+}
+
+// GetMarketInfo returns the trading rules of market.
+func (d *Drift) GetMarketInfo(ctx context.Context, market string) (*MarketInfo, error) {
+	rules, err := d.perpMarket(ctx, market)
+	if err != nil {
+		return nil, err
+	}
+	return &MarketInfo{
+		Market:             market,
+		TickSize:           rules.priceStep.step,
+		LotSize:            rules.amountStep.step,
+		MinSize:            rules.minSize,
+		ContractMultiplier: 1,
+	}, nil
+}
+
+// driftMarket holds the index and trading rules of a perpetual.
+type driftMarket struct {
+	index      int
+	amountStep decimalStep
+	priceStep  decimalStep
+	minSize    decimal.Decimal
+}
+
+// loadMarkets returns the index and trading rules of every perpetual, keyed by symbol, as listed
+// by the gateway. The markets are fetched on first use and cached.
+func (d *Drift) loadMarkets(ctx context.Context) (map[string]driftMarket, error) {
+	d.mu.Lock()
+	markets := d.markets
+	d.mu.Unlock()
+	if markets == nil {
+		var response struct {
+			Perp []struct {
+				MarketIndex  int    `json:"marketIndex"`
+				Symbol       string `json:"symbol"`
+				AmountStep   string `json:"amountStep"`
+				PriceStep    string `json:"priceStep"`
+				MinOrderSize string `json:"minOrderSize"`
+			} `json:"perp"`
+		}
+		if err := d.sendRequest(ctx, "GET", d.gatewayURL+"/v2/markets", nil, nil, &response); err != nil {
+			return nil, fmt.Errorf("failed to get markets from the Drift gateway: %w", err)
+		}
+		markets = make(map[string]driftMarket, len(response.Perp))
+		for _, m := range response.Perp {
+			markets[m.Symbol] = driftMarket{
+				index:      m.MarketIndex,
+				amountStep: parseDecimalStep(m.AmountStep),
+				priceStep:  parseDecimalStep(m.PriceStep),
+				minSize:    parseDecimal(m.MinOrderSize),
+			}
+		}
+		d.mu.Lock()
+		d.markets = markets
+		d.mu.Unlock()
+	}
+	return markets, nil
+}
+
+// perpMarket returns the index and trading rules of market.
+func (d *Drift) perpMarket(ctx context.Context, market string) (driftMarket, error) {
+	markets, err := d.loadMarkets(ctx)
+	if err != nil {
+		return driftMarket{}, err
+	}
+	m, ok := markets[d.Symbol(market)]
+	if !ok {
+		return driftMarket{}, fmt.Errorf("market %s not found on Drift", market)
+	}
+	return m, nil
+}
+
+// account returns the query selecting the subaccount on gateway requests.
+func (d *Drift) account() url.Values {
+	return url.Values{"subAccountId": {strconv.Itoa(d.subaccount)}}
+}
+
+// driftOrderRequest is an order as the gateway places it. Amount is in the base asset, negative
+// for sells.
+type driftOrderRequest struct {
+	MarketIndex int         `json:"marketIndex"`
+	MarketType  string      `json:"marketType"`
+	Amount      json.Number `json:"amount"`
+	Price       json.Number `json:"price,omitempty"`
+	OrderType   string      `json:"orderType"`
+	PostOnly    bool        `json:"postOnly"`
+	ReduceOnly  bool        `json:"reduceOnly"`
+	UserOrderID uint32      `json:"userOrderId"`
+}
+
+// PlaceOrder sends an order through the gateway, rounding amount down to the market's amount
+// step and a limit price to its price step. The returned order is identified by its user order
+// ID and reported as NEW; the transaction signature is not an order ID.
+func (d *Drift) PlaceOrder(ctx context.Context, market string, side OrderSide, orderType OrderType, amount, price decimal.Decimal) (*Order, error) {
+	return d.placeOrder(ctx, market, side, orderType, amount, price, false)
+}
+
+func (d *Drift) placeOrder(ctx context.Context, market string, side OrderSide, orderType OrderType, amount, price decimal.Decimal, reduceOnly bool) (*Order, error) {
+	m, err := d.perpMarket(ctx, market)
+	if err != nil {
+		return nil, err
+	}
+	size := m.amountStep.floor(amount)
+	if !size.IsPositive() {
+		return nil, fmt.Errorf("order amount %s is below the Drift amount step %s for %s", amount, m.amountStep, market)
+	}
+
+	request := driftOrderRequest{
+		MarketIndex: m.index,
+		MarketType:  "perp",
+		Amount:      json.Number(size.String()),
+		OrderType:   strings.ToLower(string(orderType)),
+		ReduceOnly:  reduceOnly,
+		UserOrderID: d.nextOrderID.Add(1)%255 + 1,
+	}
+	if side == Sell {
+		request.Amount = json.Number(size.Neg().String())
+	}
+	if orderType == Limit {
+		price = m.priceStep.round(price)
+		request.Price = json.Number(price.String())
+	}
+	body := map[string]interface{}{"orders": []driftOrderRequest{request}}
+	if err := d.sendRequest(ctx, "POST", d.gatewayURL+"/v2/orders", d.account(), body, nil); err != nil {
+		return nil, fmt.Errorf("failed to place order on Drift: %w", err)
+	}
+	return &Order{
+		ID:        strconv.FormatUint(uint64(request.UserOrderID), 10),
+		Market:    market,
+		Side:      side,
+		Type:      orderType,
+		Price:     price,
+		Amount:    size,
+		Status:    "NEW",
+		Timestamp: time.Now().Unix(),
+	}, nil
+}
+
+// DriftOrder is an open order as listed by the gateway. Amount and Filled are negative for sells.
+type DriftOrder struct {
+	MarketIndex int    `json:"marketIndex"`
+	MarketType  string `json:"marketType"`
+	OrderType   string `json:"orderType"`
+	Amount      string `json:"amount"`
+	Filled      string `json:"filled"`
+	Price       string `json:"price"`
+	UserOrderID int    `json:"userOrderId"`
+}
+
+// GetOrderStatus returns the state of an order while it is open. The gateway only lists open
+// orders, so an order that is no longer listed is reported as an error: it has either filled or
+// been cancelled, and the position tells which.
+func (d *Drift) GetOrderStatus(ctx context.Context, orderID string, market string) (*Order, error) {
+	var response struct {
+		Orders []DriftOrder `json:"orders"`
+	}
+	if err := d.sendRequest(ctx, "GET", d.gatewayURL+"/v2/orders", d.account(), nil, &response); err != nil {
+		return nil, fmt.Errorf("failed to get order status from Drift: %w", err)
+	}
+	for _, o := range response.Orders {
+		if o.MarketType != "perp" || strconv.Itoa(o.UserOrderID) != orderID {
+			continue
+		}
+		amount := parseDecimal(o.Amount)
+		filled := parseDecimal(o.Filled)
+		price := parseDecimal(o.Price)
+		side := Buy
+		if amount.IsNegative() {
+			side = Sell
+		}
+		return &Order{
+			ID:     orderID,
+			Market: market,
+			Side:   side,
+			Type:   OrderType(strings.ToUpper(o.OrderType)),
+			Price:  price,
+			Amount: amount.Abs(),
+			Filled: filled.Abs(),
+			Status: "OPEN",
+		}, nil
+	}
+	return nil, fmt.Errorf("order %s is no longer open on Drift", orderID)
+}
+
+func (d *Drift) CancelOrder(ctx context.Context, orderID string, market string) error {
+	id, err := strconv.Atoi(orderID)
+	if err != nil {
+		return fmt.Errorf("invalid Drift order ID %q: %w", orderID, err)
+	}
+	body := map[string]interface{}{"userIds": []int{id}}
+	if err := d.sendRequest(ctx, "DELETE", d.gatewayURL+"/v2/orders", d.account(), body, nil); err != nil {
+		return fmt.Errorf("failed to cancel order on Drift: %w", err)
+	}
+	return nil
+}
+
+// ClosePosition reduces the position in market with a reduce-only market order on the opposite
+// side, so it can never flip the position.
+func (d *Drift) ClosePosition(ctx context.Context, market string, side OrderSide, amount decimal.Decimal) (*Order, error) {
+	closeSide := Sell
+	if side == Sell {
+		closeSide = Buy
+	}
+	return d.placeOrder(ctx, market, closeSide, Market, amount, decimal.Zero, true)
+}
+
+// GetBalance returns the total collateral of the subaccount, including unrealized PnL. Drift
+// values all collateral in USDC, so asset is only checked against it.
+func (d *Drift) GetBalance(ctx context.Context, asset string) (float64, error) {
+	if asset != "" && !strings.EqualFold(asset, d.CollateralAsset()) {
+		return 0, nil
+	}
+	var response struct {
+		Total string `json:"total"`
+	}
+	if err := d.sendRequest(ctx, "GET", d.gatewayURL+"/v2/collateral", d.account(), nil, &response); err != nil {
+		return 0, fmt.Errorf("failed to get balance from Drift: %w", err)
+	}
+	total, err := strconv.ParseFloat(response.Total, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse balance float from Drift: %w", err)
+	}
+	return total, nil
+}
+
+// DriftPositionInfo is the state of a perpetual position as reported by the gateway. Amount is
+// negative for shorts.
+type DriftPositionInfo struct {
+	Amount           string `json:"amount"`
+	AverageEntry     string `json:"averageEntry"`
+	LiquidationPrice string `json:"liquidationPrice"`
+	OraclePrice      string `json:"oraclePrice"`
+}
+
+// GetPositions fetches the open perpetual positions of the subaccount.
+func (d *Drift) GetPositions(ctx context.Context) ([]Position, error) {
+	var response struct {
+		Perp []struct {
+			MarketIndex int `json:"marketIndex"`
+		} `json:"perp"`
+	}
+	if err := d.sendRequest(ctx, "GET", d.gatewayURL+"/v2/positions", d.account(), nil, &response); err != nil {
+		return nil, fmt.Errorf("failed to get positions from Drift: %w", err)
+	}
+	if len(response.Perp) == 0 {
+		return nil, nil
+	}
+	markets, err := d.loadMarkets(ctx)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[int]string, len(markets))
+	for symbol, m := range markets {
+		if market, ok := d.market(symbol); ok {
+			names[m.index] = market
+		}
+	}
+
+	var positions []Position
+	for _, p := range response.Perp {
+		market, ok := names[p.MarketIndex]
+		if !ok {
+			continue
+		}
+		info, err := d.positionInfo(ctx, p.MarketIndex)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the %s position from Drift: %w", market, err)
+		}
+		size, err := strconv.ParseFloat(info.Amount, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse position size for %s from Drift: %w", market, err)
+		}
+		if size == 0 {
+			continue
+		}
+		entry, _ := strconv.ParseFloat(info.AverageEntry, 64)
+		side := Buy
+		if size < 0 {
+			side = Sell
+		}
+		positions = append(positions, Position{Market: market, Side: side, Size: math.Abs(size), EntryPrice: entry})
+	}
+	sort.Slice(positions, func(i, j int) bool { return positions[i].Market < positions[j].Market })
+	return positions, nil
+}
+
+func (d *Drift) positionInfo(ctx context.Context, marketIndex int) (*DriftPositionInfo, error) {
+	var response DriftPositionInfo
+	if err := d.sendRequest(ctx, "GET", d.gatewayURL+"/v2/positionInfo/"+strconv.Itoa(marketIndex), d.account(), nil, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// GetPositionRisk fetches the liquidation price of the open position in market. Drift subaccounts
+// are cross-margined, so the position is backed by the subaccount's total collateral.
+func (d *Drift) GetPositionRisk(ctx context.Context, market string) (*PositionRisk, error) {
+	m, err := d.perpMarket(ctx, market)
+	if err != nil {
+		return nil, err
+	}
+	info, err := d.positionInfo(ctx, m.index)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the %s position from Drift: %w", market, err)
+	}
+	size, err := strconv.ParseFloat(info.Amount, 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse position size for %s from Drift: %w", market, err)
+	}
+	if size == 0 {
+		return nil, fmt.Errorf("no open %s position on Drift", market)
+	}
+	mark, err := strconv.ParseFloat(info.OraclePrice, 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse oracle price for %s from Drift: %w", market, err)
+	}
+	margin, err := d.GetBalance(ctx, d.CollateralAsset())
+	if err != nil {
+		return nil, err
+	}
+	liquidation, _ := strconv.ParseFloat(info.LiquidationPrice, 64)
+	side := Buy
+	if size < 0 {
+		side = Sell
+	}
+	return &PositionRisk{Market: market, Side: side, Size: math.Abs(size), MarkPrice: mark, LiquidationPrice: liquidation, Margin: margin}, nil
+}
+
+// sendRequest sends a request to target, one of the data API, DLOB server or gateway URLs, with
+// params in the query string and body as JSON.
+func (d *Drift) sendRequest(ctx context.Context, method, target string, params url.Values, body interface{}, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		buf, err := encodeJSON(body)
+		if err != nil {
+			return err
+		}
+		defer putBuffer(buf)
+		reader = buf
+	}
+	if len(params) > 0 {
+		target += "?" + params.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return doJSON(d.client, req, out)
+}
@@ -0,0 +1,115 @@
+package exchange
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+const driftMarkets = `{"spot":[{"marketIndex":0,"symbol":"USDC"}],"perp":[
+	{"marketIndex":0,"symbol":"SOL-PERP","amountStep":"0.01","priceStep":"0.0001"},
+	{"marketIndex":1,"symbol":"BTC-PERP","amountStep":"0.0001","priceStep":"0.1"}]}`
+
+func TestDriftMarketData(t *testing.T) {
+	api := newFakeAPI(t)
+	api.respond("GET", "/contracts", http.StatusOK, `{"contracts":[
+		{"ticker_id":"SOL-PERP","product_type":"PERP","index_price":"150.1","funding_rate":"0.00002","next_funding_rate":"0.00003","next_funding_rate_timestamp":"1700003600000"},
+		{"ticker_id":"SOL","product_type":"SPOT","index_price":"150.1","funding_rate":"","next_funding_rate_timestamp":""}]}`)
+	api.respond("GET", "/l2", http.StatusOK, `{"bids":[{"price":"150050000","size":"2000000000"}],"asks":[{"price":"150150000","size":"1500000000"}],"oracle":150100000}`)
+	ex := newTestDrift(api)
+
+	rates, err := ex.GetFundingRates(context.Background())
+	if err != nil {
+		t.Fatalf("GetFundingRates: %v", err)
+	}
+	if len(rates) != 1 || rates[0].Market != "SOL-USD" || !rates[0].Rate.Equal(decimal.NewFromFloat(0.00002)) || rates[0].NextTime != 1700003600 || !rates[0].PredictedRate.Equal(decimal.NewFromFloat(0.00003)) || !rates[0].HasPredicted {
+		t.Errorf("expected only the SOL perpetual, got %+v", rates)
+	}
+
+	book, err := ex.GetOrderbook(context.Background(), "SOL-USD")
+	if err != nil {
+		t.Fatalf("GetOrderbook: %v", err)
+	}
+	if book.Bids[0] != (PriceLevel{Price: 150.05, Size: 2}) || book.Asks[0] != (PriceLevel{Price: 150.15, Size: 1.5}) {
+		t.Errorf("expected levels scaled from the DLOB precision, got %+v", book)
+	}
+	if got := api.lastRequest("/l2").URL.Query().Get("marketName"); got != "SOL-PERP" {
+		t.Errorf("marketName = %q, want SOL-PERP", got)
+	}
+	if price, err := ex.GetMarkPrice(context.Background(), "SOL-USD"); err != nil || price != 150.1 {
+		t.Errorf("GetMarkPrice = %f, %v, want the oracle price 150.1", price, err)
+	}
+}
+
+func TestDriftOrdersThroughTheGateway(t *testing.T) {
+	api := newFakeAPI(t)
+	api.respond("GET", "/v2/markets", http.StatusOK, driftMarkets)
+	var placed struct {
+		Orders []driftOrderRequest `json:"orders"`
+	}
+	api.handle("POST", "/v2/orders", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &placed)
+		_, _ = w.Write([]byte(`{"tx":"5sig"}`))
+	})
+	ex := newTestDrift(api)
+	ex.subaccount = 2
+
+	order, err := ex.PlaceOrder(context.Background(), "SOL-USD", Sell, Limit, decimal.NewFromFloat(1.234), decimal.NewFromFloat(150.12346))
+	if err != nil {
+		t.Fatalf("PlaceOrder: %v", err)
+	}
+	if len(placed.Orders) != 1 {
+		t.Fatalf("expected one order, got %+v", placed)
+	}
+	want := driftOrderRequest{MarketIndex: 0, MarketType: "perp", Amount: "-1.23", Price: "150.1235", OrderType: "limit", UserOrderID: 2}
+	if placed.Orders[0] != want {
+		t.Errorf("order request = %+v, want %+v", placed.Orders[0], want)
+	}
+	if order.ID != "2" || !order.Amount.Equal(decimal.NewFromFloat(1.23)) || order.Status != "NEW" {
+		t.Errorf("unexpected order %+v", order)
+	}
+	if got := api.lastRequest("/v2/orders").URL.Query().Get("subAccountId"); got != "2" {
+		t.Errorf("subAccountId = %q, want 2", got)
+	}
+
+	api.respond("GET", "/v2/orders", http.StatusOK, `{"orders":[{"marketIndex":0,"marketType":"perp","orderType":"limit","amount":"-1.23","filled":"-0.5","price":"150.1235","userOrderId":2}]}`)
+	status, err := ex.GetOrderStatus(context.Background(), "2", "SOL-USD")
+	if err != nil {
+		t.Fatalf("GetOrderStatus: %v", err)
+	}
+	if status.Side != Sell || !status.Amount.Equal(decimal.NewFromFloat(1.23)) || !status.Filled.Equal(decimal.NewFromFloat(0.5)) || status.Status != "OPEN" {
+		t.Errorf("unexpected status %+v", status)
+	}
+	if _, err := ex.GetOrderStatus(context.Background(), "3", "SOL-USD"); err == nil {
+		t.Error("expected an order that is no longer listed to be reported as unknown")
+	}
+}
+
+func TestDriftPositions(t *testing.T) {
+	api := newFakeAPI(t)
+	api.respond("GET", "/v2/markets", http.StatusOK, driftMarkets)
+	api.respond("GET", "/v2/positions", http.StatusOK, `{"spot":[{"amount":"1000","type":"deposit","marketIndex":0}],"perp":[{"amount":"-2","type":"perp","marketIndex":0}]}`)
+	api.respond("GET", "/v2/positionInfo/0", http.StatusOK, `{"amount":"-2","averageEntry":"148.5","liquidationPrice":"180","oraclePrice":"150"}`)
+	api.respond("GET", "/v2/collateral", http.StatusOK, `{"total":"1000.5","free":"700"}`)
+	ex := newTestDrift(api)
+
+	positions, err := ex.GetPositions(context.Background())
+	if err != nil {
+		t.Fatalf("GetPositions: %v", err)
+	}
+	if len(positions) != 1 || positions[0] != (Position{Market: "SOL-USD", Side: Sell, Size: 2, EntryPrice: 148.5}) {
+		t.Errorf("expected the SOL short, got %+v", positions)
+	}
+	risk, err := ex.GetPositionRisk(context.Background(), "SOL-USD")
+	if err != nil {
+		t.Fatalf("GetPositionRisk: %v", err)
+	}
+	if distance, ok := risk.LiquidationDistance(); risk.Margin != 1000.5 || !ok || distance != 0.2 {
+		t.Errorf("unexpected risk %+v (distance %f)", risk, distance)
+	}
+}
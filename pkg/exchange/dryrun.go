@@ -0,0 +1,52 @@
+package exchange
+
+import (
+	"context"
+
+	"github.com/shopspring/decimal"
+)
+
+// DryRun wraps an Exchange so the strategy runs its full decision loop against live market data
+// and the real account balance, on mainnet as well as testnet, without ever sending an order.
+// Each would-be order is reported to a callback and filled virtually at the mark price, without
+// slippage or fees, so the strategy goes on to manage the position it believes it opened.
+type DryRun struct {
+	*Paper
+	onOrder func(order Order)
+}
+
+// NewDryRun wraps ex so that orders are passed to onOrder instead of being sent.
+func NewDryRun(ex Exchange, onOrder func(order Order)) *DryRun {
+	return &DryRun{Paper: NewPaper(ex, PaperConfig{}), onOrder: onOrder}
+}
+
+// CollateralAsset forwards to the wrapped exchange, whose real balance is reported.
+func (d *DryRun) CollateralAsset() string {
+	if a, ok := d.Exchange.(interface{ CollateralAsset() string }); ok {
+		return a.CollateralAsset()
+	}
+	return "USDC"
+}
+
+// GetBalance returns the real balance, so would-be orders are checked against the funds the
+// account actually has.
+func (d *DryRun) GetBalance(ctx context.Context, asset string) (float64, error) {
+	return d.Exchange.GetBalance(ctx, asset)
+}
+
+// PlaceOrder reports the order and fills it virtually.
+func (d *DryRun) PlaceOrder(ctx context.Context, market string, side OrderSide, orderType OrderType, amount, price decimal.Decimal) (*Order, error) {
+	order, err := d.Paper.PlaceOrder(ctx, market, side, orderType, amount, price)
+	if err == nil && d.onOrder != nil {
+		d.onOrder(*order)
+	}
+	return order, err
+}
+
+func (d *DryRun) ClosePosition(ctx context.Context, market string, side OrderSide, amount decimal.Decimal) (*Order, error) {
+	closeSide := Sell
+	if side == Sell {
+		closeSide = Buy
+	}
+	return d.PlaceOrder(ctx, market, closeSide, Market, amount, decimal.Zero)
+}
@@ -0,0 +1,975 @@
+package exchange
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/httpclient"
+)
+
+const (
+	DydxMainnetIndexerURL = "https://indexer.dydx.trade/v4"
+	DydxTestnetIndexerURL = "https://indexer.v4testnet.dydx.exchange/v4"
+
+	DydxMainnetValidatorURL = "https://dydx-rest.publicnode.com"
+	DydxTestnetValidatorURL = "https://dydx-testnet-rest.publicnode.com"
+)
+
+const (
+	// dydxMarketSlippage bounds the price of market orders, which dYdX executes as
+	// immediate-or-cancel limit orders, relative to the oracle price.
+	dydxMarketSlippage = 0.01
+	// dydxShortTermBlocks is how many blocks a short-term order stays valid for; the protocol
+	// allows at most 20.
+	dydxShortTermBlocks = 20
+	// dydxLongTermTTL is how long a limit order rests before the protocol expires it.
+	dydxLongTermTTL = 28 * 24 * time.Hour
+	// dydxQuoteResolution is the atomic resolution of USDC, the quote asset of every market.
+	dydxQuoteResolution = -6
+)
+
+// Order flags of a dYdX order ID. Short-term orders live in the memory of the validators for a
+// few blocks; long-term orders are stored on chain until they expire.
+const (
+	DydxOrderFlagsShortTerm = 0
+	DydxOrderFlagsLongTerm  = 64
+)
+
+// ErrDydxSignerRequired is returned when placing or cancelling a dYdX order without a signer,
+// since orders are Cosmos transactions signed with the account's key.
+var ErrDydxSignerRequired = errors.New("dYdX orders need DYDX_SIGNER_CMD to sign")
+
+// DydxSubaccountID identifies a subaccount of an address.
+type DydxSubaccountID struct {
+	Owner  string `json:"owner"`
+	Number uint32 `json:"number"`
+}
+
+// DydxOrderID identifies an order of a subaccount by the client ID it was placed with.
+type DydxOrderID struct {
+	SubaccountID DydxSubaccountID `json:"subaccount_id"`
+	ClientID     uint32           `json:"client_id"`
+	OrderFlags   uint32           `json:"order_flags"`
+	ClobPairID   uint32           `json:"clob_pair_id"`
+}
+
+// DydxOrderMsg is an order of a MsgPlaceOrder. Quantums and Subticks are the size and price in
+// the market's integer units. Short-term orders set GoodTilBlock, long-term orders
+// GoodTilBlockTime, in seconds.
+type DydxOrderMsg struct {
+	OrderID          DydxOrderID `json:"order_id"`
+	Side             string      `json:"side"`
+	Quantums         uint64      `json:"quantums,string"`
+	Subticks         uint64      `json:"subticks,string"`
+	GoodTilBlock     uint32      `json:"good_til_block,omitempty"`
+	GoodTilBlockTime uint32      `json:"good_til_block_time,omitempty"`
+	TimeInForce      string      `json:"time_in_force"`
+	ReduceOnly       bool        `json:"reduce_only"`
+}
+
+// DydxPlaceOrderMsg is a /dydxprotocol.clob.MsgPlaceOrder in its protobuf JSON form.
+type DydxPlaceOrderMsg struct {
+	Type  string       `json:"@type"`
+	Order DydxOrderMsg `json:"order"`
+}
+
+// DydxCancelOrderMsg is a /dydxprotocol.clob.MsgCancelOrder in its protobuf JSON form.
+type DydxCancelOrderMsg struct {
+	Type             string      `json:"@type"`
+	OrderID          DydxOrderID `json:"order_id"`
+	GoodTilBlock     uint32      `json:"good_til_block,omitempty"`
+	GoodTilBlockTime uint32      `json:"good_til_block_time,omitempty"`
+}
+
+// DydxTx is an unsigned transaction of the account, with its account number and sequence as
+// reported by the validators.
+type DydxTx struct {
+	AccountNumber uint64        `json:"account_number,string"`
+	Sequence      uint64        `json:"sequence,string"`
+	Msgs          []interface{} `json:"msgs"`
+}
+
+// DydxSigner signs dYdX transactions with the account's Cosmos key and returns the signed TxRaw
+// bytes, base64 encoded as broadcast to the validators.
+type DydxSigner interface {
+	Sign(ctx context.Context, tx DydxTx) (string, error)
+}
+
+// DydxCommandSigner delegates signing to an external program, e.g. a wrapper around the wallet of
+// dYdX's v4 client, since the module has no Cosmos signing dependency. The program receives
+// {"mnemonic", "chain_id", "tx"} as JSON on stdin and must print the base64 TxRaw on stdout.
+type DydxCommandSigner struct {
+	command  []string
+	mnemonic string
+	chainID  string
+}
+
+// NewDydxCommandSigner creates a signer running command, split on whitespace.
+func NewDydxCommandSigner(command, mnemonic string, testnet bool) (*DydxCommandSigner, error) {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return nil, errors.New("empty dYdX signer command")
+	}
+	chainID := "dydx-mainnet-1"
+	if testnet {
+		chainID = "dydx-testnet-4"
+	}
+	return &DydxCommandSigner{command: args, mnemonic: mnemonic, chainID: chainID}, nil
+}
+
+// Sign runs the signer program for one transaction.
+func (c *DydxCommandSigner) Sign(ctx context.Context, tx DydxTx) (string, error) {
+	input, err := json.Marshal(struct {
+		Mnemonic string `json:"mnemonic"`
+		ChainID  string `json:"chain_id"`
+		Tx       DydxTx `json:"tx"`
+	}{c.mnemonic, c.chainID, tx})
+	if err != nil {
+		return "", err
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, c.command[0], c.command[1:]...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("dYdX signer failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	txBytes := strings.TrimSpace(stdout.String())
+	if _, err := base64.StdEncoding.DecodeString(txBytes); err != nil || txBytes == "" {
+		return "", fmt.Errorf("dYdX signer returned an invalid transaction: %q", txBytes)
+	}
+	return txBytes, nil
+}
+
+// Dydx is the implementation for the dYdX v4 exchange. Market data and account state are read
+// from the indexer; orders are signed through a DydxSigner and broadcast to the validators.
+type Dydx struct {
+	client       *http.Client
+	address      string
+	mnemonic     string
+	subaccount   int
+	baseURL      string
+	validatorURL string
+	testnet      bool
+	signer       DydxSigner
+
+	// txMu serializes transactions, which must use consecutive account sequences.
+	txMu sync.Mutex
+}
+
+// NewDydx creates a new dYdX client for the subaccount of address. The mnemonic is the Cosmos
+// key the address was derived from and is used to sign transactions.
+func NewDydx(address, mnemonic string, subaccount int, testnet bool) *Dydx {
+	baseURL, validatorURL := DydxMainnetIndexerURL, DydxMainnetValidatorURL
+	if testnet {
+		baseURL, validatorURL = DydxTestnetIndexerURL, DydxTestnetValidatorURL
+	}
+	return &Dydx{
+		client:       &http.Client{Timeout: 10 * time.Second},
+		address:      address,
+		mnemonic:     mnemonic,
+		subaccount:   subaccount,
+		baseURL:      baseURL,
+		validatorURL: validatorURL,
+		testnet:      testnet,
+	}
+}
+
+// SetSigner sets the signer that transactions are signed with.
+func (d *Dydx) SetSigner(signer DydxSigner) {
+	d.signer = signer
+}
+
+// SetValidatorURL replaces the REST endpoint of the validator node transactions are broadcast to.
+func (d *Dydx) SetValidatorURL(validatorURL string) {
+	d.validatorURL = strings.TrimSuffix(validatorURL, "/")
+}
+
+// SetRateLimiter makes every dYdX indexer API call wait for limiter.
+func (d *Dydx) SetRateLimiter(limiter *httpclient.Limiter) {
+	d.client = limiter.Client(d.client)
+}
+
+func (d *Dydx) Name() string {
+	return "dYdX"
+}
+
+// FundingInterval returns how often dYdX pays funding.
+func (d *Dydx) FundingInterval() time.Duration {
+	return time.Hour
+}
+
+func (d *Dydx) CollateralAsset() string {
+	return "USDC"
+}
+
+func (d *Dydx) SetTestnet(testnet bool) {
+	d.testnet = testnet
+	if testnet {
+		d.baseURL, d.validatorURL = DydxTestnetIndexerURL, DydxTestnetValidatorURL
+	} else {
+		d.baseURL, d.validatorURL = DydxMainnetIndexerURL, DydxMainnetValidatorURL
+	}
+}
+
+// DydxPerpetualMarket is a market as listed by the indexer. Numbers are decimal strings.
+type DydxPerpetualMarket struct {
+	Ticker          string `json:"ticker"`
+	Status          string `json:"status"`
+	OraclePrice     string `json:"oraclePrice"`
+	NextFundingRate string `json:"nextFundingRate"`
+	Volume24H       string `json:"volume24H"`
+	OpenInterest    string `json:"openInterest"`
+	// MaintenanceMarginFraction is the share of a position's value that must be kept as equity.
+	MaintenanceMarginFraction string `json:"maintenanceMarginFraction"`
+	// ClobPairID and the resolutions below convert sizes and prices into the integer quantums
+	// and subticks of orders.
+	ClobPairID                string `json:"clobPairId"`
+	AtomicResolution          int32  `json:"atomicResolution"`
+	QuantumConversionExponent int32  `json:"quantumConversionExponent"`
+	StepBaseQuantums          uint64 `json:"stepBaseQuantums"`
+	SubticksPerTick           uint64 `json:"subticksPerTick"`
+}
+
+// DydxPerpetualMarketsResponse is the response structure for the perpetual markets endpoint.
+type DydxPerpetualMarketsResponse struct {
+	Markets map[string]DydxPerpetualMarket `json:"markets"`
+}
+
+// getMarkets fetches one market, or all markets if ticker is empty.
+func (d *Dydx) getMarkets(ctx context.Context, ticker string) (map[string]DydxPerpetualMarket, error) {
+	endpoint := "/perpetualMarkets"
+	if ticker != "" {
+		endpoint += "?" + url.Values{"ticker": {ticker}}.Encode()
+	}
+	var response DydxPerpetualMarketsResponse
+	if err := d.sendRequest(ctx, "GET", endpoint, &response); err != nil {
+		return nil, fmt.Errorf("failed to get perpetual markets from dYdX: %w", err)
+	}
+	return response.Markets, nil
+}
+
+// GetFundingRates returns the predicted funding rate of every active market for the current
+// hour, which is paid at the top of the next hour. Being a prediction, it is reported as both the
+// rate and the predicted rate.
+func (d *Dydx) GetFundingRates(ctx context.Context) ([]*FundingRate, error) {
+	markets, err := d.getMarkets(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	next := time.Now().Truncate(time.Hour).Add(time.Hour).Unix()
+	var fundingRates []*FundingRate
+	for _, market := range markets {
+		if market.Status != "ACTIVE" {
+			continue
+		}
+		rate, err := decimal.NewFromString(market.NextFundingRate)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse funding rate for %s from dYdX: %w", market.Ticker, err)
+		}
+		fundingRates = append(fundingRates, &FundingRate{Market: market.Ticker, Rate: rate, NextTime: next, PredictedRate: rate, HasPredicted: true})
+	}
+	return fundingRates, nil
+}
+
+// dydxHistoryPageSize is the number of historical funding rates requested per page.
+const dydxHistoryPageSize = 100
+
+// DydxHistoricalFundingResponse is the response structure for the historical funding endpoint.
+type DydxHistoricalFundingResponse struct {
+	HistoricalFunding []struct {
+		Ticker      string    `json:"ticker"`
+		Rate        string    `json:"rate"`
+		EffectiveAt time.Time `json:"effectiveAt"`
+	} `json:"historicalFunding"`
+}
+
+// GetFundingHistory returns the hourly funding rates paid on market between from and to. The
+// indexer serves them newest first, so pages are walked backwards from to.
+func (d *Dydx) GetFundingHistory(ctx context.Context, market string, from, to time.Time) ([]*FundingRate, error) {
+	var history []*FundingRate
+	before := to
+	for {
+		query := url.Values{
+			"effectiveBeforeOrAt": {before.UTC().Format(time.RFC3339Nano)},
+			"limit":               {strconv.Itoa(dydxHistoryPageSize)},
+		}
+		var response DydxHistoricalFundingResponse
+		if err := d.sendRequest(ctx, "GET", "/historicalFunding/"+url.PathEscape(market)+"?"+query.Encode(), &response); err != nil {
+			return nil, fmt.Errorf("failed to get funding history from dYdX: %w", err)
+		}
+
+		for _, h := range response.HistoricalFunding {
+			if h.EffectiveAt.Before(from) {
+				continue
+			}
+			rate, err := decimal.NewFromString(h.Rate)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse historical funding rate for %s from dYdX: %w", market, err)
+			}
+			history = append(history, &FundingRate{Market: market, Rate: rate, NextTime: h.EffectiveAt.Unix()})
+		}
+
+		page := response.HistoricalFunding
+		if len(page) < dydxHistoryPageSize || !page[len(page)-1].EffectiveAt.After(from) {
+			break
+		}
+		before = page[len(page)-1].EffectiveAt.Add(-time.Millisecond)
+	}
+
+	// Reverse into chronological order.
+	for i, j := 0, len(history)-1; i < j; i, j = i+1, j-1 {
+		history[i], history[j] = history[j], history[i]
+	}
+	return history, nil
+}
+
+// DydxFundingPaymentsResponse is the response structure for the funding payments endpoint.
+type DydxFundingPaymentsResponse struct {
+	FundingPayments []struct {
+		Ticker    string    `json:"ticker"`
+		Rate      string    `json:"rate"`
+		Payment   string    `json:"payment"`
+		CreatedAt time.Time `json:"createdAt"`
+	} `json:"fundingPayments"`
+}
+
+// GetFundingPayments returns the funding paid and received by the subaccount on market since the
+// given time. Like funding history, the indexer serves payments newest first.
+func (d *Dydx) GetFundingPayments(ctx context.Context, market string, since time.Time) ([]FundingPayment, error) {
+	var payments []FundingPayment
+	before := time.Now()
+	for {
+		query := url.Values{
+			"address":           {d.address},
+			"subaccountNumber":  {strconv.Itoa(d.subaccount)},
+			"ticker":            {market},
+			"createdBeforeOrAt": {before.UTC().Format(time.RFC3339Nano)},
+			"limit":             {strconv.Itoa(dydxHistoryPageSize)},
+		}
+		var response DydxFundingPaymentsResponse
+		if err := d.sendRequest(ctx, "GET", "/fundingPayments?"+query.Encode(), &response); err != nil {
+			return nil, fmt.Errorf("failed to get funding payments from dYdX: %w", err)
+		}
+
+		for _, p := range response.FundingPayments {
+			if p.CreatedAt.Before(since) {
+				continue
+			}
+			amount, err := strconv.ParseFloat(p.Payment, 64)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse funding payment for %s from dYdX: %w", market, err)
+			}
+			rate, _ := strconv.ParseFloat(p.Rate, 64)
+			payments = append(payments, FundingPayment{Market: market, Time: p.CreatedAt, Rate: rate, Amount: amount})
+		}
+
+		page := response.FundingPayments
+		if len(page) < dydxHistoryPageSize || !page[len(page)-1].CreatedAt.After(since) {
+			break
+		}
+		before = page[len(page)-1].CreatedAt.Add(-time.Millisecond)
+	}
+
+	sort.Slice(payments, func(i, j int) bool { return payments[i].Time.Before(payments[j].Time) })
+	return payments, nil
+}
+
+// GetMarkPrice returns the oracle price of market, which dYdX uses as its mark price.
+func (d *Dydx) GetMarkPrice(ctx context.Context, market string) (float64, error) {
+	markets, err := d.getMarkets(ctx, market)
+	if err != nil {
+		return 0, err
+	}
+	m, ok := markets[market]
+	if !ok {
+		return 0, fmt.Errorf("market %s not found on dYdX", market)
+	}
+	price, err := strconv.ParseFloat(m.OraclePrice, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse oracle price for %s from dYdX: %w", market, err)
+	}
+	return price, nil
+}
+
+// GetMarketStats returns 24h volume and open interest in USD. The indexer reports open interest
+// in the base asset, so it is valued at the oracle price.
+func (d *Dydx) GetMarketStats(ctx context.Context, markets []string) (map[string]MarketStats, error) {
+	all, err := d.getMarkets(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make(map[string]MarketStats, len(markets))
+	for _, market := range markets {
+		m, ok := all[market]
+		if !ok {
+			continue
+		}
+		volume, err := strconv.ParseFloat(m.Volume24H, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse 24h volume for %s from dYdX: %w", market, err)
+		}
+		openInterest, err := strconv.ParseFloat(m.OpenInterest, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse open interest for %s from dYdX: %w", market, err)
+		}
+		price, err := strconv.ParseFloat(m.OraclePrice, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse oracle price for %s from dYdX: %w", market, err)
+		}
+		stats[market] = MarketStats{Market: market, Volume24hUSD: volume, OpenInterestUSD: openInterest * price}
+	}
+	return stats, nil
+}
+
+// DydxOrderbookResponse is the order book of a market as reported by the indexer.
+type DydxOrderbookResponse struct {
+	Bids []DydxOrderbookLevel `json:"bids"`
+	Asks []DydxOrderbookLevel `json:"asks"`
+}
+
+// DydxOrderbookLevel is one price level of a dYdX order book.
+type DydxOrderbookLevel struct {
+	Price string `json:"price"`
+	Size  string `json:"size"`
+}
+
+// GetOrderbook returns the order book of market as aggregated by the indexer.
+func (d *Dydx) GetOrderbook(ctx context.Context, market string) (*Orderbook, error) {
+	var response DydxOrderbookResponse
+	if err := d.sendRequest(ctx, "GET", "/orderbooks/perpetualMarket/"+url.PathEscape(market), &response); err != nil {
+		return nil, fmt.Errorf("failed to get orderbook from dYdX: %w", err)
+	}
+	bids, err := dydxLevels(response.Bids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s orderbook from dYdX: %w", market, err)
+	}
+	asks, err := dydxLevels(response.Asks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s orderbook from dYdX: %w", market, err)
+	}
+	book := &Orderbook{Market: market, Bids: bids, Asks: asks}
+	book.sortLevels()
+	return book, nil
+}
+
+func dydxLevels(levels []DydxOrderbookLevel) ([]PriceLevel, error) {
+	parsed := make([]PriceLevel, 0, len(levels))
+	for _, l := range levels {
+		level, err := parseLevel(l.Price, l.Size)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, level)
+	}
+	return parsed, nil
+}
+
+// PlaceOrder signs and broadcasts an order, rounding amount down to the market's step size and
+// the price to its tick size. Market orders are short-term immediate-or-cancel orders at worst 1%
+// from the oracle price; limit orders are long-term orders resting for up to 28 days. The order
+// ID is the client ID the order was placed with.
+func (d *Dydx) PlaceOrder(ctx context.Context, market string, side OrderSide, orderType OrderType, amount, price decimal.Decimal) (*Order, error) {
+	return d.placeOrder(ctx, market, side, orderType, amount, price, false)
+}
+
+func (d *Dydx) placeOrder(ctx context.Context, market string, side OrderSide, orderType OrderType, amount, price decimal.Decimal, reduceOnly bool) (*Order, error) {
+	if d.signer == nil {
+		return nil, ErrDydxSignerRequired
+	}
+	m, err := d.market(ctx, market)
+	if err != nil {
+		return nil, err
+	}
+	clobPairID, err := strconv.ParseUint(m.ClobPairID, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the CLOB pair of %s from dYdX: %w", market, err)
+	}
+	quantums := dydxQuantums(amount, m)
+	if quantums == 0 {
+		return nil, fmt.Errorf("order amount %s is below the dYdX step size for %s", amount, market)
+	}
+	if orderType == Market {
+		oracle, err := decimal.NewFromString(m.OraclePrice)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse oracle price for %s from dYdX: %w", market, err)
+		}
+		price = oracle.Mul(decimal.NewFromFloat(1 + dydxMarketSlippage))
+		if side == Sell {
+			price = oracle.Mul(decimal.NewFromFloat(1 - dydxMarketSlippage))
+		}
+	}
+
+	order := DydxOrderMsg{
+		OrderID: DydxOrderID{
+			SubaccountID: DydxSubaccountID{Owner: d.address, Number: uint32(d.subaccount)},
+			ClientID:     rand.Uint32(),
+			ClobPairID:   uint32(clobPairID),
+		},
+		Side:       "SIDE_BUY",
+		Quantums:   quantums,
+		Subticks:   dydxSubticks(price, m),
+		ReduceOnly: reduceOnly,
+	}
+	if side == Sell {
+		order.Side = "SIDE_SELL"
+	}
+	if orderType == Market {
+		height, err := d.height(ctx)
+		if err != nil {
+			return nil, err
+		}
+		order.GoodTilBlock = height + dydxShortTermBlocks
+		order.TimeInForce = "TIME_IN_FORCE_IOC"
+	} else {
+		order.OrderID.OrderFlags = DydxOrderFlagsLongTerm
+		order.GoodTilBlockTime = uint32(time.Now().Add(dydxLongTermTTL).Unix())
+		order.TimeInForce = "TIME_IN_FORCE_UNSPECIFIED"
+	}
+
+	if err := d.broadcast(ctx, DydxPlaceOrderMsg{Type: "/dydxprotocol.clob.MsgPlaceOrder", Order: order}); err != nil {
+		return nil, fmt.Errorf("failed to place order on dYdX: %w", err)
+	}
+	return &Order{
+		ID:        strconv.FormatUint(uint64(order.OrderID.ClientID), 10),
+		Market:    market,
+		Side:      side,
+		Type:      orderType,
+		Price:     price,
+		Amount:    amount,
+		Status:    "NEW",
+		Timestamp: time.Now().Unix(),
+	}, nil
+}
+
+// dydxQuantums converts a size into quantums, rounded down to the market's step.
+func dydxQuantums(amount decimal.Decimal, m DydxPerpetualMarket) uint64 {
+	if !amount.IsPositive() {
+		return 0
+	}
+	quantums := uint64(amount.Shift(-m.AtomicResolution).IntPart())
+	if m.StepBaseQuantums > 0 {
+		quantums -= quantums % m.StepBaseQuantums
+	}
+	return quantums
+}
+
+// dydxSubticks converts a price into subticks, rounded to the market's tick.
+func dydxSubticks(price decimal.Decimal, m DydxPerpetualMarket) uint64 {
+	raw := price.Shift(m.AtomicResolution - m.QuantumConversionExponent - dydxQuoteResolution)
+	perTick := m.SubticksPerTick
+	if perTick == 0 {
+		perTick = 1
+	}
+	ticks := raw.Div(decimal.NewFromInt(int64(perTick))).Round(0).IntPart()
+	if ticks < 1 {
+		ticks = 1
+	}
+	return uint64(ticks) * perTick
+}
+
+// market returns the indexer's listing of market.
+func (d *Dydx) market(ctx context.Context, market string) (DydxPerpetualMarket, error) {
+	markets, err := d.getMarkets(ctx, market)
+	if err != nil {
+		return DydxPerpetualMarket{}, err
+	}
+	m, ok := markets[market]
+	if !ok {
+		return DydxPerpetualMarket{}, fmt.Errorf("market %s not found on dYdX", market)
+	}
+	return m, nil
+}
+
+// height returns the latest block height processed by the indexer.
+func (d *Dydx) height(ctx context.Context) (uint32, error) {
+	var response struct {
+		Height string `json:"height"`
+	}
+	if err := d.sendRequest(ctx, "GET", "/height", &response); err != nil {
+		return 0, fmt.Errorf("failed to get the block height from dYdX: %w", err)
+	}
+	height, err := strconv.ParseUint(response.Height, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse the block height from dYdX: %w", err)
+	}
+	return uint32(height), nil
+}
+
+// broadcast signs a transaction carrying msg with the account's current sequence and broadcasts
+// it to the validator, returning once it has passed the validator's checks.
+func (d *Dydx) broadcast(ctx context.Context, msg interface{}) error {
+	d.txMu.Lock()
+	defer d.txMu.Unlock()
+
+	var account struct {
+		Account struct {
+			AccountNumber string `json:"account_number"`
+			Sequence      string `json:"sequence"`
+		} `json:"account"`
+	}
+	if err := d.validatorRequest(ctx, "GET", "/cosmos/auth/v1beta1/accounts/"+url.PathEscape(d.address), nil, &account); err != nil {
+		return fmt.Errorf("failed to get the account sequence: %w", err)
+	}
+	number, _ := strconv.ParseUint(account.Account.AccountNumber, 10, 64)
+	sequence, _ := strconv.ParseUint(account.Account.Sequence, 10, 64)
+
+	txBytes, err := d.signer.Sign(ctx, DydxTx{AccountNumber: number, Sequence: sequence, Msgs: []interface{}{msg}})
+	if err != nil {
+		return fmt.Errorf("failed to sign the transaction: %w", err)
+	}
+	var response struct {
+		TxResponse struct {
+			TxHash string `json:"txhash"`
+			Code   int    `json:"code"`
+			RawLog string `json:"raw_log"`
+		} `json:"tx_response"`
+	}
+	body := map[string]string{"tx_bytes": txBytes, "mode": "BROADCAST_MODE_SYNC"}
+	if err := d.validatorRequest(ctx, "POST", "/cosmos/tx/v1beta1/txs", body, &response); err != nil {
+		return err
+	}
+	if response.TxResponse.Code != 0 {
+		return fmt.Errorf("transaction %s rejected with code %d: %s", response.TxResponse.TxHash, response.TxResponse.Code, response.TxResponse.RawLog)
+	}
+	return nil
+}
+
+// DydxOrderResponse is an order as reported by the indexer.
+type DydxOrderResponse struct {
+	ID               string `json:"id"`
+	ClientID         string `json:"clientId"`
+	ClobPairID       string `json:"clobPairId"`
+	OrderFlags       string `json:"orderFlags"`
+	Ticker           string `json:"ticker"`
+	Side             string `json:"side"`
+	Type             string `json:"type"`
+	Size             string `json:"size"`
+	Price            string `json:"price"`
+	TotalFilled      string `json:"totalFilled"`
+	Status           string `json:"status"`
+	GoodTilBlockTime string `json:"goodTilBlockTime"`
+}
+
+// order converts the response into an Order.
+func (o DydxOrderResponse) order(id string) *Order {
+	return &Order{
+		ID:     id,
+		Market: o.Ticker,
+		Side:   OrderSide(o.Side),
+		Type:   OrderType(o.Type),
+		Price:  parseDecimal(o.Price),
+		Amount: parseDecimal(o.Size),
+		Filled: parseDecimal(o.TotalFilled),
+		Status: o.Status,
+	}
+}
+
+// findOrder looks up the order of the subaccount on market placed with clientID. It reports false
+// when the indexer hasn't seen it yet, which takes a block.
+func (d *Dydx) findOrder(ctx context.Context, market, clientID string) (DydxOrderResponse, bool, error) {
+	query := url.Values{
+		"address":          {d.address},
+		"subaccountNumber": {strconv.Itoa(d.subaccount)},
+		"ticker":           {market},
+		"limit":            {strconv.Itoa(dydxHistoryPageSize)},
+	}
+	var orders []DydxOrderResponse
+	if err := d.sendRequest(ctx, "GET", "/orders?"+query.Encode(), &orders); err != nil {
+		return DydxOrderResponse{}, false, err
+	}
+	for _, o := range orders {
+		if o.ClientID == clientID {
+			return o, true, nil
+		}
+	}
+	return DydxOrderResponse{}, false, nil
+}
+
+// GetOrderStatus returns the order placed with the client ID orderID, as reported by the indexer.
+// An order the indexer hasn't seen yet is reported NEW with nothing filled. IDs assigned by the
+// indexer are looked up directly.
+func (d *Dydx) GetOrderStatus(ctx context.Context, orderID string, market string) (*Order, error) {
+	if _, err := strconv.ParseUint(orderID, 10, 32); err != nil {
+		var response DydxOrderResponse
+		if err := d.sendRequest(ctx, "GET", "/orders/"+url.PathEscape(orderID), &response); err != nil {
+			return nil, fmt.Errorf("failed to get order status from dYdX: %w", err)
+		}
+		return response.order(orderID), nil
+	}
+	o, ok, err := d.findOrder(ctx, market, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order status from dYdX: %w", err)
+	}
+	if !ok {
+		return &Order{ID: orderID, Market: market, Status: "NEW"}, nil
+	}
+	return o.order(orderID), nil
+}
+
+// CancelOrder cancels the order placed with the client ID orderID. The order is looked up on the
+// indexer for its flags and expiry, so it must have been indexed.
+func (d *Dydx) CancelOrder(ctx context.Context, orderID string, market string) error {
+	if d.signer == nil {
+		return ErrDydxSignerRequired
+	}
+	clientID, err := strconv.ParseUint(orderID, 10, 32)
+	if err != nil {
+		return fmt.Errorf("invalid dYdX order ID %q: %w", orderID, err)
+	}
+	o, ok, err := d.findOrder(ctx, market, orderID)
+	if err != nil {
+		return fmt.Errorf("failed to cancel order on dYdX: %w", err)
+	}
+	if !ok {
+		return fmt.Errorf("failed to cancel order on dYdX: %s order %s not found", market, orderID)
+	}
+	flags, _ := strconv.ParseUint(o.OrderFlags, 10, 32)
+	clobPairID, _ := strconv.ParseUint(o.ClobPairID, 10, 32)
+	msg := DydxCancelOrderMsg{
+		Type: "/dydxprotocol.clob.MsgCancelOrder",
+		OrderID: DydxOrderID{
+			SubaccountID: DydxSubaccountID{Owner: d.address, Number: uint32(d.subaccount)},
+			ClientID:     uint32(clientID),
+			OrderFlags:   uint32(flags),
+			ClobPairID:   uint32(clobPairID),
+		},
+	}
+	if flags == DydxOrderFlagsShortTerm {
+		height, err := d.height(ctx)
+		if err != nil {
+			return err
+		}
+		msg.GoodTilBlock = height + dydxShortTermBlocks
+	} else {
+		expiry, err := time.Parse(time.RFC3339, o.GoodTilBlockTime)
+		if err != nil {
+			return fmt.Errorf("failed to parse the expiry of %s order %s from dYdX: %w", market, orderID, err)
+		}
+		msg.GoodTilBlockTime = uint32(expiry.Unix())
+	}
+	if err := d.broadcast(ctx, msg); err != nil {
+		return fmt.Errorf("failed to cancel order on dYdX: %w", err)
+	}
+	return nil
+}
+
+// ReadOnly reports that dYdX can't be traded without a signer, so the strategy never opens a leg
+// on it.
+func (d *Dydx) ReadOnly() bool {
+	return d.signer == nil
+}
+
+// DydxSubaccountResponse is the response structure for the subaccount endpoint.
+type DydxSubaccountResponse struct {
+	Subaccount struct {
+		Equity         string `json:"equity"`
+		FreeCollateral string `json:"freeCollateral"`
+		AssetPositions map[string]struct {
+			Size string `json:"size"`
+		} `json:"assetPositions"`
+		// OpenPerpetualPositions are keyed by market. Their size is negative for shorts.
+		OpenPerpetualPositions map[string]struct {
+			Size string `json:"size"`
+		} `json:"openPerpetualPositions"`
+	} `json:"subaccount"`
+}
+
+// GetBalance returns the equity of the subaccount for its collateral asset, or the size of
+// another asset position.
+func (d *Dydx) GetBalance(ctx context.Context, asset string) (float64, error) {
+	endpoint := fmt.Sprintf("/addresses/%s/subaccountNumber/%d", url.PathEscape(d.address), d.subaccount)
+	var response DydxSubaccountResponse
+	if err := d.sendRequest(ctx, "GET", endpoint, &response); err != nil {
+		return 0, fmt.Errorf("failed to get balance from dYdX: %w", err)
+	}
+
+	value := response.Subaccount.Equity
+	if asset != "" && asset != d.CollateralAsset() {
+		position, ok := response.Subaccount.AssetPositions[asset]
+		if !ok {
+			return 0, nil
+		}
+		value = position.Size
+	}
+	balance, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse balance float from dYdX: %w", err)
+	}
+	return balance, nil
+}
+
+// GetPositionRisk computes the liquidation price of the open position in market. The indexer
+// doesn't report one, so it is derived from the subaccount equity and the market's maintenance
+// margin fraction, treating the position as the only one in the subaccount.
+func (d *Dydx) GetPositionRisk(ctx context.Context, market string) (*PositionRisk, error) {
+	endpoint := fmt.Sprintf("/addresses/%s/subaccountNumber/%d", url.PathEscape(d.address), d.subaccount)
+	var response DydxSubaccountResponse
+	if err := d.sendRequest(ctx, "GET", endpoint, &response); err != nil {
+		return nil, fmt.Errorf("failed to get the %s position from dYdX: %w", market, err)
+	}
+	position, ok := response.Subaccount.OpenPerpetualPositions[market]
+	if !ok {
+		return nil, fmt.Errorf("no open %s position on dYdX", market)
+	}
+	size, err := strconv.ParseFloat(position.Size, 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse position size for %s from dYdX: %w", market, err)
+	}
+	equity, err := strconv.ParseFloat(response.Subaccount.Equity, 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse equity from dYdX: %w", err)
+	}
+
+	markets, err := d.getMarkets(ctx, market)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := markets[market]
+	if !ok {
+		return nil, fmt.Errorf("market %s not found on dYdX", market)
+	}
+	oracle, err := strconv.ParseFloat(m.OraclePrice, 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse oracle price for %s from dYdX: %w", market, err)
+	}
+	maintenance, err := strconv.ParseFloat(m.MaintenanceMarginFraction, 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse maintenance margin fraction for %s from dYdX: %w", market, err)
+	}
+
+	side := Buy
+	if size < 0 {
+		side = Sell
+	}
+	return &PositionRisk{
+		Market:           market,
+		Side:             side,
+		Size:             math.Abs(size),
+		MarkPrice:        oracle,
+		LiquidationPrice: dydxLiquidationPrice(size, oracle, equity, maintenance),
+		Margin:           equity,
+	}, nil
+}
+
+// dydxLiquidationPrice solves for the price at which equity, moving with the position's PnL,
+// falls to the maintenance margin: equity + size*(p-price) = |size|*p*maintenance. It returns 0
+// when no positive price liquidates the position.
+func dydxLiquidationPrice(size, price, equity, maintenance float64) float64 {
+	denominator := size - math.Abs(size)*maintenance
+	if size == 0 || denominator == 0 {
+		return 0
+	}
+	liquidation := (size*price - equity) / denominator
+	if liquidation <= 0 {
+		return 0
+	}
+	return liquidation
+}
+
+// DydxPerpetualPositionsResponse is the response structure for the perpetual positions endpoint.
+type DydxPerpetualPositionsResponse struct {
+	Positions []struct {
+		Market     string `json:"market"`
+		Side       string `json:"side"`
+		Size       string `json:"size"`
+		EntryPrice string `json:"entryPrice"`
+	} `json:"positions"`
+}
+
+// GetPositions fetches the open perpetual positions of the subaccount.
+func (d *Dydx) GetPositions(ctx context.Context) ([]Position, error) {
+	query := url.Values{
+		"address":          {d.address},
+		"subaccountNumber": {strconv.Itoa(d.subaccount)},
+		"status":           {"OPEN"},
+	}
+	var response DydxPerpetualPositionsResponse
+	if err := d.sendRequest(ctx, "GET", "/perpetualPositions?"+query.Encode(), &response); err != nil {
+		return nil, fmt.Errorf("failed to get positions from dYdX: %w", err)
+	}
+
+	positions := make([]Position, 0, len(response.Positions))
+	for _, p := range response.Positions {
+		size, err := strconv.ParseFloat(p.Size, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse position size for %s from dYdX: %w", p.Market, err)
+		}
+		entry, _ := strconv.ParseFloat(p.EntryPrice, 64)
+		side := Buy
+		if p.Side == "SHORT" {
+			side = Sell
+		}
+		positions = append(positions, Position{Market: p.Market, Side: side, Size: math.Abs(size), EntryPrice: entry})
+	}
+	return positions, nil
+}
+
+// ClosePosition reduces the position in market with a reduce-only market order on the opposite
+// side, so it can never flip the position.
+func (d *Dydx) ClosePosition(ctx context.Context, market string, side OrderSide, amount decimal.Decimal) (*Order, error) {
+	closeSide := Sell
+	if side == Sell {
+		closeSide = Buy
+	}
+	return d.placeOrder(ctx, market, closeSide, Market, amount, decimal.Zero, true)
+}
+
+// SetTransport replaces the HTTP transport used for REST requests, e.g. with a recorder in tests.
+func (d *Dydx) SetTransport(rt http.RoundTripper) {
+	d.client.Transport = rt
+}
+
+// sendRequest makes an unauthenticated request to the indexer, which serves public data only.
+func (d *Dydx) sendRequest(ctx context.Context, method, endpoint string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, d.baseURL+endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "FundingRateArbBot/1.0")
+	return doJSON(d.client, req, out)
+}
+
+// validatorRequest makes a request to the validator's REST endpoint, sending body as JSON.
+func (d *Dydx) validatorRequest(ctx context.Context, method, endpoint string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, d.validatorURL+endpoint, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return doJSON(d.client, req, out)
+}
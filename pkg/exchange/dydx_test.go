@@ -0,0 +1,227 @@
+package exchange
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestDydxFundingRatesAndStats(t *testing.T) {
+	api := newFakeAPI(t)
+	api.respond("GET", "/perpetualMarkets", http.StatusOK, `{"markets":{
+		"BTC-USD":{"ticker":"BTC-USD","status":"ACTIVE","oraclePrice":"65000","nextFundingRate":"0.0000125","volume24H":"150000000","openInterest":"500"},
+		"XYZ-USD":{"ticker":"XYZ-USD","status":"PAUSED","oraclePrice":"1","nextFundingRate":"0.01","volume24H":"0","openInterest":"0"}}}`)
+	ex := newTestDydx(api)
+
+	rates, err := ex.GetFundingRates(context.Background())
+	if err != nil {
+		t.Fatalf("GetFundingRates: %v", err)
+	}
+	if len(rates) != 1 || rates[0].Market != "BTC-USD" || !rates[0].Rate.Equal(decimal.NewFromFloat(0.0000125)) || rates[0].NextTime == 0 {
+		t.Errorf("expected only the active BTC-USD market with its next funding time, got %+v", rates)
+	}
+
+	stats, err := ex.GetMarketStats(context.Background(), []string{"BTC-USD"})
+	if err != nil {
+		t.Fatalf("GetMarketStats: %v", err)
+	}
+	if got := stats["BTC-USD"].OpenInterestUSD; got != 500*65000 {
+		t.Errorf("expected open interest valued at the oracle price, got %f", got)
+	}
+}
+
+func TestDydxBalance(t *testing.T) {
+	api := newFakeAPI(t)
+	api.respond("GET", "/addresses/dydx1test/subaccountNumber/0", http.StatusOK,
+		`{"subaccount":{"equity":"1234.5","freeCollateral":"1000","assetPositions":{"USDC":{"size":"1300"}}}}`)
+	ex := newTestDydx(api)
+
+	balance, err := ex.GetBalance(context.Background(), "USDC")
+	if err != nil {
+		t.Fatalf("GetBalance: %v", err)
+	}
+	if balance != 1234.5 {
+		t.Errorf("expected the subaccount equity, got %f", balance)
+	}
+}
+
+func TestDydxPositionRisk(t *testing.T) {
+	api := newFakeAPI(t)
+	api.respond("GET", "/addresses/dydx1test/subaccountNumber/0", http.StatusOK,
+		`{"subaccount":{"equity":"1000","openPerpetualPositions":{"BTC-USD":{"size":"-0.1"}}}}`)
+	api.respond("GET", "/perpetualMarkets", http.StatusOK,
+		`{"markets":{"BTC-USD":{"ticker":"BTC-USD","oraclePrice":"60000","maintenanceMarginFraction":"0.03"}}}`)
+	ex := newTestDydx(api)
+
+	risk, err := ex.GetPositionRisk(context.Background(), "BTC-USD")
+	if err != nil {
+		t.Fatalf("GetPositionRisk: %v", err)
+	}
+	// Equity falls to the maintenance margin where 1000 - 0.1*(p-60000) = 0.1*p*0.03.
+	want := (0.1*60000 + 1000) / (0.1 * 1.03)
+	if risk.Side != Sell || risk.Size != 0.1 || math.Abs(risk.LiquidationPrice-want) > 1e-6 {
+		t.Errorf("unexpected risk %+v, want a short liquidated at %f", risk, want)
+	}
+	if distance, ok := risk.LiquidationDistance(); !ok || math.Abs(distance-(want-60000)/60000) > 1e-9 {
+		t.Errorf("LiquidationDistance = %f, %v", distance, ok)
+	}
+
+	if _, err := ex.GetPositionRisk(context.Background(), "ETH-USD"); err == nil {
+		t.Error("expected an error for a market without a position")
+	}
+}
+
+func TestDydxOrdersRequireASigner(t *testing.T) {
+	ex := newTestDydx(newFakeAPI(t))
+	if _, err := ex.PlaceOrder(context.Background(), "BTC-USD", Buy, Market, decimal.NewFromFloat(0.01), decimal.NewFromInt(0)); !errors.Is(err, ErrDydxSignerRequired) {
+		t.Errorf("expected orders to be refused without a signer, got %v", err)
+	}
+	if !IsReadOnly(ex) {
+		t.Error("expected dYdX without a signer to be reported read-only so it is not traded")
+	}
+	ex.SetSigner(&recordingDydxSigner{})
+	if IsReadOnly(ex) {
+		t.Error("expected dYdX with a signer to be tradable")
+	}
+}
+
+// recordingDydxSigner records the transactions it is asked to sign.
+type recordingDydxSigner struct {
+	txs []DydxTx
+}
+
+func (s *recordingDydxSigner) Sign(ctx context.Context, tx DydxTx) (string, error) {
+	s.txs = append(s.txs, tx)
+	return "c2lnbmVk", nil
+}
+
+// dydxTradingAPI serves the BTC-USD market, the block height, the account and the broadcast
+// endpoint.
+func dydxTradingAPI(t *testing.T) *fakeAPI {
+	api := newFakeAPI(t)
+	api.respond("GET", "/perpetualMarkets", http.StatusOK, `{"markets":{"BTC-USD":{"ticker":"BTC-USD","status":"ACTIVE",
+		"oraclePrice":"60000","clobPairId":"0","atomicResolution":-10,"quantumConversionExponent":-9,
+		"stepBaseQuantums":1000000,"subticksPerTick":100000}}}`)
+	api.respond("GET", "/height", http.StatusOK, `{"height":"1000","time":"2024-01-01T00:00:00.000Z"}`)
+	api.respond("GET", "/cosmos/auth/v1beta1/accounts/dydx1test", http.StatusOK,
+		`{"account":{"@type":"/cosmos.auth.v1beta1.BaseAccount","address":"dydx1test","account_number":"42","sequence":"7"}}`)
+	api.respond("POST", "/cosmos/tx/v1beta1/txs", http.StatusOK, `{"tx_response":{"txhash":"ABC","code":0}}`)
+	return api
+}
+
+func TestDydxMarketOrdersAreSignedAndBroadcast(t *testing.T) {
+	api := dydxTradingAPI(t)
+	var body []byte
+	api.handle("POST", "/cosmos/tx/v1beta1/txs", func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		_, _ = w.Write([]byte(`{"tx_response":{"txhash":"ABC","code":0}}`))
+	})
+	ex := newTestDydx(api)
+	signer := &recordingDydxSigner{}
+	ex.SetSigner(signer)
+
+	order, err := ex.PlaceOrder(context.Background(), "BTC-USD", Buy, Market, decimal.RequireFromString("0.01234"), decimal.Zero)
+	if err != nil {
+		t.Fatalf("PlaceOrder: %v", err)
+	}
+	if len(signer.txs) != 1 || signer.txs[0].AccountNumber != 42 || signer.txs[0].Sequence != 7 {
+		t.Fatalf("expected one transaction with the account number and sequence, got %+v", signer.txs)
+	}
+	msg := signer.txs[0].Msgs[0].(DydxPlaceOrderMsg)
+	// 0.01234 BTC is 123400000 quantums, rounded down to the 1000000 step; 1% above the oracle
+	// price is 60600 USD, 6060000000 subticks.
+	if msg.Type != "/dydxprotocol.clob.MsgPlaceOrder" || msg.Order.Side != "SIDE_BUY" || msg.Order.Quantums != 123000000 ||
+		msg.Order.Subticks != 6060000000 || msg.Order.TimeInForce != "TIME_IN_FORCE_IOC" || msg.Order.GoodTilBlock != 1020 ||
+		msg.Order.OrderID.OrderFlags != DydxOrderFlagsShortTerm || msg.Order.OrderID.SubaccountID.Owner != "dydx1test" || msg.Order.ReduceOnly {
+		t.Errorf("unexpected order message %+v", msg)
+	}
+	if order.ID != strconv.FormatUint(uint64(msg.Order.OrderID.ClientID), 10) || order.Status != "NEW" {
+		t.Errorf("expected the order to be identified by its client ID, got %+v", order)
+	}
+	if !strings.Contains(string(body), `"tx_bytes":"c2lnbmVk"`) || !strings.Contains(string(body), "BROADCAST_MODE_SYNC") {
+		t.Errorf("expected the signed transaction to be broadcast, got %s", body)
+	}
+
+	if _, err := ex.ClosePosition(context.Background(), "BTC-USD", Buy, decimal.RequireFromString("0.01")); err != nil {
+		t.Fatalf("ClosePosition: %v", err)
+	}
+	if msg := signer.txs[1].Msgs[0].(DydxPlaceOrderMsg); msg.Order.Side != "SIDE_SELL" || !msg.Order.ReduceOnly {
+		t.Errorf("expected a reduce-only sell to close a long, got %+v", msg)
+	}
+}
+
+func TestDydxLimitOrdersRestAndCanBeCancelled(t *testing.T) {
+	api := dydxTradingAPI(t)
+	ex := newTestDydx(api)
+	signer := &recordingDydxSigner{}
+	ex.SetSigner(signer)
+
+	order, err := ex.PlaceOrder(context.Background(), "BTC-USD", Sell, Limit, decimal.RequireFromString("0.01"), decimal.RequireFromString("61234.4"))
+	if err != nil {
+		t.Fatalf("PlaceOrder: %v", err)
+	}
+	msg := signer.txs[0].Msgs[0].(DydxPlaceOrderMsg)
+	if msg.Order.OrderID.OrderFlags != DydxOrderFlagsLongTerm || msg.Order.GoodTilBlockTime == 0 || msg.Order.Subticks != 6123400000 || msg.Order.Side != "SIDE_SELL" {
+		t.Errorf("expected a long-term sell at the rounded price, got %+v", msg)
+	}
+
+	// Until the indexer has seen it the order is reported new; then it is found by its client ID.
+	api.respond("GET", "/orders", http.StatusOK, `[]`)
+	status, err := ex.GetOrderStatus(context.Background(), order.ID, "BTC-USD")
+	if err != nil || status.Status != "NEW" || !status.Filled.IsZero() {
+		t.Fatalf("GetOrderStatus before indexing = %+v, %v", status, err)
+	}
+	api.respond("GET", "/orders", http.StatusOK, `[{"id":"uuid","clientId":"`+order.ID+`","clobPairId":"0","orderFlags":"64",
+		"ticker":"BTC-USD","side":"SELL","type":"LIMIT","size":"0.01","price":"61234","totalFilled":"0.004","status":"OPEN",
+		"goodTilBlockTime":"2024-02-01T00:00:00.000Z"}]`)
+	status, err = ex.GetOrderStatus(context.Background(), order.ID, "BTC-USD")
+	if err != nil || status.Status != "OPEN" || !status.Filled.Equal(decimal.RequireFromString("0.004")) {
+		t.Fatalf("GetOrderStatus = %+v, %v", status, err)
+	}
+
+	if err := ex.CancelOrder(context.Background(), order.ID, "BTC-USD"); err != nil {
+		t.Fatalf("CancelOrder: %v", err)
+	}
+	cancel := signer.txs[1].Msgs[0].(DydxCancelOrderMsg)
+	if cancel.Type != "/dydxprotocol.clob.MsgCancelOrder" || cancel.OrderID != msg.Order.OrderID ||
+		cancel.GoodTilBlockTime != uint32(time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC).Unix()) {
+		t.Errorf("unexpected cancel message %+v", cancel)
+	}
+}
+
+func TestDydxRejectedTransactionsAreErrors(t *testing.T) {
+	api := dydxTradingAPI(t)
+	api.respond("POST", "/cosmos/tx/v1beta1/txs", http.StatusOK, `{"tx_response":{"txhash":"ABC","code":2000,"raw_log":"insufficient collateral"}}`)
+	ex := newTestDydx(api)
+	ex.SetSigner(&recordingDydxSigner{})
+
+	if _, err := ex.PlaceOrder(context.Background(), "BTC-USD", Buy, Market, decimal.RequireFromString("0.01"), decimal.Zero); err == nil || !strings.Contains(err.Error(), "insufficient collateral") {
+		t.Errorf("expected the rejection to be reported, got %v", err)
+	}
+}
+
+func TestDydxFundingHistory(t *testing.T) {
+	api := newFakeAPI(t)
+	api.respond("GET", "/historicalFunding/BTC-USD", http.StatusOK, `{"historicalFunding":[
+		{"ticker":"BTC-USD","rate":"0.00002","price":"65000","effectiveAt":"2024-01-01T02:00:00.000Z"},
+		{"ticker":"BTC-USD","rate":"0.00001","price":"64900","effectiveAt":"2024-01-01T01:00:00.000Z"},
+		{"ticker":"BTC-USD","rate":"0.00003","price":"64800","effectiveAt":"2023-12-31T23:00:00.000Z"}]}`)
+	ex := newTestDydx(api)
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	history, err := ex.GetFundingHistory(context.Background(), "BTC-USD", from, from.Add(3*time.Hour))
+	if err != nil {
+		t.Fatalf("GetFundingHistory: %v", err)
+	}
+	if len(history) != 2 || !history[0].Rate.Equal(decimal.NewFromFloat(0.00001)) || history[1].NextTime != from.Add(2*time.Hour).Unix() {
+		t.Errorf("expected the two rates in range in chronological order, got %+v %+v", history[0], history[len(history)-1])
+	}
+}
@@ -1,5 +1,15 @@
 package exchange
 
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/httpclient"
+)
+
 type OrderSide string
 
 const (
@@ -14,32 +24,254 @@ const (
 	Market OrderType = "MARKET"
 )
 
+// Order is an order as placed on or reported by an exchange. Prices and amounts are decimals, as
+// venues list them, so they are sent and compared without binary rounding.
 type Order struct {
 	ID        string
 	Market    string
 	Side      OrderSide
 	Type      OrderType
-	Price     float64
-	Amount    float64
-	Filled    float64
+	Price     decimal.Decimal
+	Amount    decimal.Decimal
+	Filled    decimal.Decimal
 	Status    string
 	Timestamp int64
+	// Fee is the trading fee charged on the fill in USD, 0 when the exchange doesn't report it.
+	Fee float64
 }
 
+// Position is an open perpetual position as reported by an exchange. Side is Buy for longs and
+// Sell for shorts; Size is always positive, in the base asset.
+type Position struct {
+	Market     string
+	Side       OrderSide
+	Size       float64
+	EntryPrice float64
+}
+
+// FundingRate is the funding rate of a market, per funding interval of its exchange, and the Unix
+// time it is next paid. PredictedRate is the venue's forecast of the rate for the next interval,
+// in the same units, and is only known when HasPredicted is set.
 type FundingRate struct {
-	Market   string
-	Rate     float64
-	NextTime int64
+	Market        string
+	Rate          decimal.Decimal
+	NextTime      int64
+	PredictedRate decimal.Decimal
+	HasPredicted  bool
 }
 
+// Exchange is a perpetual futures venue. Methods that call the venue take a context, so requests
+// can be cancelled, e.g. when the bot shuts down.
 type Exchange interface {
 	Name() string
 	SetTestnet(testnet bool)
-	GetFundingRates() ([]*FundingRate, error)
-	GetOrderbook(market string) (map[string]interface{}, error)
-	PlaceOrder(market string, side OrderSide, orderType OrderType, amount, price float64) (*Order, error)
-	GetOrderStatus(orderID string, market string) (*Order, error)
-	CancelOrder(orderID string, market string) error
-	GetBalance(asset string) (float64, error)
-	ClosePosition(market string, side OrderSide, amount float64) (*Order, error)
+	GetFundingRates(ctx context.Context) ([]*FundingRate, error)
+	GetOrderbook(ctx context.Context, market string) (*Orderbook, error)
+	GetMarkPrice(ctx context.Context, market string) (float64, error)
+	PlaceOrder(ctx context.Context, market string, side OrderSide, orderType OrderType, amount, price decimal.Decimal) (*Order, error)
+	GetOrderStatus(ctx context.Context, orderID string, market string) (*Order, error)
+	CancelOrder(ctx context.Context, orderID string, market string) error
+	GetBalance(ctx context.Context, asset string) (float64, error)
+	GetPositions(ctx context.Context) ([]Position, error)
+	ClosePosition(ctx context.Context, market string, side OrderSide, amount decimal.Decimal) (*Order, error)
+}
+
+// DefaultFundingInterval is assumed for exchanges that do not report their funding interval.
+const DefaultFundingInterval = time.Hour
+
+// FundingIntervaler is implemented by exchanges that report how often funding is paid.
+type FundingIntervaler interface {
+	FundingInterval() time.Duration
+}
+
+// FundingIntervalOf returns the funding interval of ex, falling back to DefaultFundingInterval.
+func FundingIntervalOf(ex Exchange) time.Duration {
+	if fi, ok := ex.(FundingIntervaler); ok && fi.FundingInterval() > 0 {
+		return fi.FundingInterval()
+	}
+	return DefaultFundingInterval
+}
+
+// rateScale returns the factor that converts a rate paid every from into the rate paid every to.
+func rateScale(to, from time.Duration) decimal.Decimal {
+	return decimal.NewFromInt(int64(to)).Div(decimal.NewFromInt(int64(from)))
+}
+
+// MarketStats is the liquidity of a market over the last 24 hours, in USD.
+type MarketStats struct {
+	Market          string
+	Volume24hUSD    float64
+	OpenInterestUSD float64
+}
+
+// MarketStatser is implemented by exchanges that report volume and open interest per market.
+type MarketStatser interface {
+	GetMarketStats(ctx context.Context, markets []string) (map[string]MarketStats, error)
+}
+
+// FundingHistorian is implemented by exchanges that serve the funding rates paid in the past.
+// The returned rates are sorted by time, with NextTime set to when each rate was paid.
+type FundingHistorian interface {
+	GetFundingHistory(ctx context.Context, market string, from, to time.Time) ([]*FundingRate, error)
+}
+
+// FundingPayment is a funding payment on one of the account's positions. Amount is in the
+// collateral asset, positive when funding was received and negative when it was paid.
+type FundingPayment struct {
+	Market string
+	Time   time.Time
+	Rate   float64
+	Amount float64
+}
+
+// ErrFundingPaymentsUnsupported is returned by GetFundingPayments on wrappers whose exchange
+// doesn't report funding payments.
+var ErrFundingPaymentsUnsupported = errors.New("funding payments are not reported")
+
+// FundingPaymentReporter is implemented by exchanges that report the funding paid and received
+// on the account's positions.
+type FundingPaymentReporter interface {
+	// GetFundingPayments returns the payments on market made at or after since, oldest first.
+	GetFundingPayments(ctx context.Context, market string, since time.Time) ([]FundingPayment, error)
+}
+
+// ForcedCloseKind says why the exchange closed a position itself.
+type ForcedCloseKind string
+
+const (
+	// Liquidation closes a position whose margin no longer covers it.
+	Liquidation ForcedCloseKind = "LIQUIDATION"
+	// ADL is auto-deleveraging: the exchange reduces profitable positions to absorb a
+	// liquidation its insurance fund couldn't.
+	ADL ForcedCloseKind = "ADL"
+)
+
+// ForcedClose is a fill the exchange made to close or reduce one of the account's positions.
+type ForcedClose struct {
+	Market string
+	Kind   ForcedCloseKind
+	// Side is the side of the position that was reduced, Buy for a long.
+	Side   OrderSide
+	Amount float64
+	Price  float64
+	Time   time.Time
+}
+
+// ErrForcedClosesUnsupported is returned by GetForcedCloses on wrappers whose exchange doesn't
+// report liquidations and auto-deleveraging.
+var ErrForcedClosesUnsupported = errors.New("liquidations are not reported")
+
+// LiquidationReporter is implemented by exchanges whose trade history marks the fills of
+// liquidations and auto-deleveraging.
+type LiquidationReporter interface {
+	// GetForcedCloses returns the forced closes on market made at or after since, oldest first.
+	GetForcedCloses(ctx context.Context, market string, since time.Time) ([]ForcedClose, error)
+}
+
+// StreamHandler receives the events pushed by a StreamingExchange. Nil callbacks are skipped,
+// and streams whose callbacks are all nil are not opened.
+type StreamHandler struct {
+	// OnFundingRates receives updated funding rates, typically one market at a time.
+	OnFundingRates func(rates []*FundingRate)
+	// OnMarkPrice receives mark price updates.
+	OnMarkPrice func(market string, price float64)
+	// OnOrder receives updates to the account's orders.
+	OnOrder func(order *Order)
+	// OnError receives connection errors. The stream reconnects after each one.
+	OnError func(err error)
+}
+
+// ErrStreamingUnsupported is returned by Stream on wrappers whose exchange doesn't stream.
+var ErrStreamingUnsupported = errors.New("streaming is not supported")
+
+// StreamingExchange is implemented by exchanges that push market data and order updates over a
+// persistent connection, instead of requiring them to be polled.
+type StreamingExchange interface {
+	// Stream delivers events for markets to handler until stop is closed, reconnecting as needed.
+	Stream(markets []string, handler StreamHandler, stop <-chan struct{}) error
+}
+
+// AccountSummary is the collateral of an account, in Asset. Equity includes the unrealized PnL
+// of open positions; Used is the margin held by positions and open orders, and Free what is left
+// to open new ones.
+type AccountSummary struct {
+	Asset  string
+	Equity float64
+	Free   float64
+	Used   float64
+}
+
+// AccountSummarizer is implemented by exchanges that report how much of their collateral is in
+// use, beyond the equity GetBalance returns.
+type AccountSummarizer interface {
+	GetAccountSummary(ctx context.Context) (*AccountSummary, error)
+}
+
+// RateLimited is implemented by exchanges whose API calls can be throttled, so polling many markets
+// stays within the venue's rate limits.
+type RateLimited interface {
+	SetRateLimiter(limiter *httpclient.Limiter)
+}
+
+// Fauceter is implemented by exchanges whose testnet can credit test funds on request.
+type Fauceter interface {
+	RequestTestFunds(ctx context.Context) error
+}
+
+// Pinger is implemented by exchanges that check their credentials with an authenticated call that
+// changes nothing. Exchanges without it are checked with GetBalance.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// ReadOnlyVenue is implemented by exchanges that report market data and account state but can't
+// submit orders, such as dYdX until its transactions can be signed. They are not traded.
+type ReadOnlyVenue interface {
+	ReadOnly() bool
+}
+
+// IsReadOnly reports whether ex can't submit orders. Exchanges traded through several ACCOUNTS
+// are as read-only as their first account.
+func IsReadOnly(ex Exchange) bool {
+	if accounts, ok := ex.(*Accounts); ok {
+		ex = accounts.Primary()
+	}
+	venue, ok := ex.(ReadOnlyVenue)
+	return ok && venue.ReadOnly()
+}
+
+// Clients returns the venue clients behind ex: ex itself, or the clients it wraps, such as the
+// accounts of an Accounts or the exchange behind a Paper, Chaos or Renamed.
+func Clients(ex Exchange) []Exchange {
+	switch w := ex.(type) {
+	case *Accounts:
+		var clients []Exchange
+		for _, account := range w.accounts {
+			clients = append(clients, Clients(account.Exchange)...)
+		}
+		return clients
+	case *DryRun:
+		return Clients(w.Paper.Exchange)
+	case *Paper:
+		return Clients(w.Exchange)
+	case *Chaos:
+		return Clients(w.Exchange)
+	case *Renamed:
+		return Clients(w.Exchange)
+	}
+	return []Exchange{ex}
+}
+
+// ServerClock is implemented by exchanges that report their server time, so a drifting local
+// clock, which gets signed requests rejected, is caught before trading.
+type ServerClock interface {
+	ServerTime(ctx context.Context) (time.Time, error)
+}
+
+// Annualize converts a per-interval funding rate into an annual rate.
+func Annualize(rate float64, interval time.Duration) float64 {
+	if interval <= 0 {
+		interval = DefaultFundingInterval
+	}
+	return rate * float64(365*24*time.Hour) / float64(interval)
 }
@@ -1,18 +1,27 @@
 package exchange
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io/ioutil"
-	"log"
+	"io"
+	"log/slog"
+	"math"
 	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	sdk "github.com/extended-protocol/extended-sdk-golang/src"
 	"github.com/shopspring/decimal"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/httpclient"
 )
 
 const (
@@ -25,13 +34,28 @@ type Extended struct {
 	client     *sdk.APIClient
 	account    *sdk.StarkPerpetualAccount
 	httpClient *http.Client // for requests not in the SDK
+	limiter    *httpclient.Limiter
 	apiKey     string
 	baseURL    string
 	testnet    bool
+
+	// templates holds pre-built order parameters per market for the fast entry path.
+	templates   map[string]sdk.CreateOrderObjectParams
+	templatesMu sync.RWMutex
+	// marketRules caches the lot and tick size per market.
+	marketRules map[string]extendedRules
+	rulesMu     sync.Mutex
+	// nonces counts the orders signed, from a seed in milliseconds, so orders placed within the
+	// same second get distinct nonces.
+	nonces atomic.Int64
 }
 
-// NewExtended creates a new Extended exchange client
-func NewExtended(apiKey, privateKey, publicKey string, vaultID int, testnet bool) *Extended {
+// extendedMaxNonce is the largest order nonce Extended accepts.
+const extendedMaxNonce = math.MaxInt32
+
+// NewExtended creates a new Extended exchange client. It fails when the Stark keys don't form a
+// valid account.
+func NewExtended(apiKey, privateKey, publicKey string, vaultID int, testnet bool) (*Extended, error) {
 	baseURL := ExtendedMainnetBaseURL
 	if testnet {
 		baseURL = ExtendedTestnetBaseURL
@@ -48,21 +72,35 @@ func NewExtended(apiKey, privateKey, publicKey string, vaultID int, testnet bool
 		apiKey,
 	)
 	if err != nil {
-		// Using log.Fatal here because returning an error would require changing the function signature
-		// across multiple files, which is a larger refactor. For this bot, exiting is acceptable.
-		log.Fatalf("Failed to create extended sdk account: %v", err)
+		return nil, fmt.Errorf("failed to create the Extended account: %w", err)
 	}
 
 	client := sdk.NewAPIClient(cfg, account.APIKey(), account, 30*time.Second)
 
-	return &Extended{
-		client:     client,
-		account:    account,
-		httpClient: &http.Client{Timeout: 10 * time.Second},
-		apiKey:     apiKey,
-		baseURL:    baseURL,
-		testnet:    testnet,
+	e := &Extended{
+		client:      client,
+		account:     account,
+		httpClient:  &http.Client{Timeout: 10 * time.Second},
+		apiKey:      apiKey,
+		baseURL:     baseURL,
+		testnet:     testnet,
+		templates:   make(map[string]sdk.CreateOrderObjectParams),
+		marketRules: make(map[string]extendedRules),
 	}
+	e.nonces.Store(time.Now().UnixMilli() % extendedMaxNonce)
+	return e, nil
+}
+
+// nextNonce returns the nonce of the next order, one more than the last, wrapping around within
+// the range Extended accepts.
+func (e *Extended) nextNonce() int {
+	return int(e.nonces.Add(1)%extendedMaxNonce) + 1
+}
+
+// SetRateLimiter makes every Extended API call wait for limiter, SDK calls included.
+func (e *Extended) SetRateLimiter(limiter *httpclient.Limiter) {
+	e.limiter = limiter
+	e.httpClient = limiter.Client(e.httpClient)
 }
 
 // Name returns the name of the exchange
@@ -70,6 +108,16 @@ func (e *Extended) Name() string {
 	return "Extended"
 }
 
+// FundingInterval returns how often funding is paid on Extended.
+func (e *Extended) FundingInterval() time.Duration {
+	return time.Hour
+}
+
+// CollateralAsset returns the asset Extended accounts are margined in.
+func (e *Extended) CollateralAsset() string {
+	return "USDC"
+}
+
 // SetTestnet switches between testnet and mainnet
 func (e *Extended) SetTestnet(testnet bool) {
 	e.testnet = testnet
@@ -78,31 +126,117 @@ func (e *Extended) SetTestnet(testnet bool) {
 	} else {
 		e.baseURL = ExtendedMainnetBaseURL
 	}
+
+	// Templates embed the Starknet domain, which depends on the network.
+	e.templatesMu.Lock()
+	e.templates = make(map[string]sdk.CreateOrderObjectParams)
+	e.templatesMu.Unlock()
+}
+
+// sdkMarkets fetches market details through the SDK, retrying transient failures. No names means
+// every market.
+func (e *Extended) sdkMarkets(ctx context.Context, names []string) ([]sdk.MarketModel, error) {
+	var markets []sdk.MarketModel
+	err := httpclient.Retry(ctx, func() error {
+		if err := e.limiter.Wait(ctx); err != nil {
+			return err
+		}
+		var err error
+		markets, err = e.client.GetMarkets(ctx, names)
+		return sdkStatusError(err)
+	})
+	return markets, err
+}
+
+// sdkStatusFailure matches the message of an SDK error for a 4xx/5xx response.
+var sdkStatusFailure = regexp.MustCompile(`^API request failed with status (\d{3}): `)
+
+// sdkStatusError turns an SDK error for a 4xx/5xx response into an *httpclient.StatusError, so its
+// status decides whether it is retried. Other errors are returned unchanged.
+func sdkStatusError(err error) error {
+	if err == nil {
+		return nil
+	}
+	match := sdkStatusFailure.FindStringSubmatch(err.Error())
+	if match == nil {
+		return err
+	}
+	code, _ := strconv.Atoi(match[1])
+	body := strings.TrimPrefix(err.Error(), match[0])
+	return &httpclient.StatusError{StatusCode: code, Status: strconv.Itoa(code) + " " + http.StatusText(code), Body: body}
 }
 
 // GetFundingRates fetches funding rates for all markets
-func (e *Extended) GetFundingRates() ([]*FundingRate, error) {
-	markets, err := e.client.GetMarkets(context.Background(), nil)
+func (e *Extended) GetFundingRates(ctx context.Context) ([]*FundingRate, error) {
+	markets, err := e.sdkMarkets(ctx, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get markets from Extended SDK: %w", err)
 	}
 
+	// Extended pays funding every hour on the hour.
+	next := time.Now().Truncate(time.Hour).Add(time.Hour).Unix()
 	var fundingRates []*FundingRate
 	for _, market := range markets {
-		rate, _ := market.MarketStats.FundingRate.Float64()
 		fundingRates = append(fundingRates, &FundingRate{
-			Market: market.Name,
-			Rate:   rate,
-			// NextTime is not directly in this response, would require another call or parsing from marketStats
+			Market:   market.Name,
+			Rate:     market.MarketStats.FundingRate,
+			NextTime: next,
 		})
 	}
 
 	return fundingRates, nil
 }
 
-// GetOrderbook is a placeholder
-func (e *Extended) GetOrderbook(market string) (map[string]interface{}, error) {
-	return nil, fmt.Errorf("GetOrderbook not implemented for Extended")
+// ExtendedOrderbookResponse is the response structure for the order book of a market.
+type ExtendedOrderbookResponse struct {
+	Status string `json:"status"`
+	Data   struct {
+		Market string                   `json:"market"`
+		Bid    []ExtendedOrderbookLevel `json:"bid"`
+		Ask    []ExtendedOrderbookLevel `json:"ask"`
+	} `json:"data"`
+}
+
+// ExtendedOrderbookLevel is one price level of an Extended order book.
+type ExtendedOrderbookLevel struct {
+	Qty   string `json:"qty"`
+	Price string `json:"price"`
+}
+
+// GetOrderbook fetches the order book of market.
+func (e *Extended) GetOrderbook(ctx context.Context, market string) (*Orderbook, error) {
+	endpoint := fmt.Sprintf("/api/v1/info/markets/%s/orderbook", url.PathEscape(market))
+	var response ExtendedOrderbookResponse
+	if err := e.sendRequest(ctx, "GET", endpoint, nil, &response); err != nil {
+		return nil, fmt.Errorf("failed to get orderbook from Extended: %w", err)
+	}
+	if response.Status != "OK" {
+		return nil, fmt.Errorf("Extended API returned non-OK status for orderbook: %s", response.Status)
+	}
+
+	bids, err := extendedLevels(response.Data.Bid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s orderbook from Extended: %w", market, err)
+	}
+	asks, err := extendedLevels(response.Data.Ask)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s orderbook from Extended: %w", market, err)
+	}
+	book := &Orderbook{Market: market, Bids: bids, Asks: asks}
+	book.sortLevels()
+	return book, nil
+}
+
+func extendedLevels(levels []ExtendedOrderbookLevel) ([]PriceLevel, error) {
+	parsed := make([]PriceLevel, 0, len(levels))
+	for _, l := range levels {
+		level, err := parseLevel(l.Price, l.Qty)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, level)
+	}
+	return parsed, nil
 }
 
 // ExtendedMarketStatsResponse is the response structure for market stats
@@ -114,19 +248,14 @@ type ExtendedMarketStatsResponse struct {
 }
 
 // GetMarkPrice fetches the current mark price for a given market.
-func (e *Extended) GetMarkPrice(market string) (float64, error) {
+func (e *Extended) GetMarkPrice(ctx context.Context, market string) (float64, error) {
 	endpoint := fmt.Sprintf("/api/v1/info/markets/%s/stats", market)
-	body, err := e.sendRequest("GET", endpoint, nil)
-	if err != nil {
-		return 0, fmt.Errorf("failed to get market stats from Extended: %w", err)
-	}
-
 	var response ExtendedMarketStatsResponse
-	if err := json.Unmarshal(body, &response); err != nil {
-		return 0, fmt.Errorf("failed to unmarshal market stats response from Extended: %w", err)
+	if err := e.sendRequest(ctx, "GET", endpoint, nil, &response); err != nil {
+		return 0, fmt.Errorf("failed to get market stats from Extended: %w", err)
 	}
 	if response.Status != "OK" {
-		return 0, fmt.Errorf("Extended API returned non-OK status for market stats: %s", string(body))
+		return 0, fmt.Errorf("Extended API returned non-OK status for market stats: %s", response.Status)
 	}
 
 	markPrice, err := strconv.ParseFloat(response.Data.MarkPrice, 64)
@@ -137,6 +266,271 @@ func (e *Extended) GetMarkPrice(market string) (float64, error) {
 	return markPrice, nil
 }
 
+// extendedHistoryPageSize is the number of historical funding rates requested per page.
+const extendedHistoryPageSize = 1000
+
+// ExtendedFundingHistoryResponse is the response structure for the funding history endpoint.
+type ExtendedFundingHistoryResponse struct {
+	Status string `json:"status"`
+	Data   []struct {
+		Market      string `json:"m"`
+		Timestamp   int64  `json:"T"`
+		FundingRate string `json:"f"`
+	} `json:"data"`
+	Pagination struct {
+		Cursor int64 `json:"cursor"`
+		Count  int   `json:"count"`
+	} `json:"pagination"`
+}
+
+// GetFundingHistory returns the hourly funding rates paid on market between from and to.
+func (e *Extended) GetFundingHistory(ctx context.Context, market string, from, to time.Time) ([]*FundingRate, error) {
+	var history []*FundingRate
+	var cursor int64
+	for {
+		query := url.Values{
+			"startTime": {strconv.FormatInt(from.UnixMilli(), 10)},
+			"endTime":   {strconv.FormatInt(to.UnixMilli(), 10)},
+			"limit":     {strconv.Itoa(extendedHistoryPageSize)},
+		}
+		if cursor != 0 {
+			query.Set("cursor", strconv.FormatInt(cursor, 10))
+		}
+		var response ExtendedFundingHistoryResponse
+		endpoint := fmt.Sprintf("/api/v1/info/%s/funding?%s", url.PathEscape(market), query.Encode())
+		if err := e.sendRequest(ctx, "GET", endpoint, nil, &response); err != nil {
+			return nil, fmt.Errorf("failed to get funding history from Extended: %w", err)
+		}
+		if response.Status != "OK" {
+			return nil, fmt.Errorf("Extended API returned non-OK status for funding history: %s", response.Status)
+		}
+
+		for _, f := range response.Data {
+			rate, err := decimal.NewFromString(f.FundingRate)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse historical funding rate for %s from Extended: %w", market, err)
+			}
+			history = append(history, &FundingRate{Market: market, Rate: rate, NextTime: f.Timestamp / 1000})
+		}
+
+		if response.Pagination.Count < extendedHistoryPageSize || response.Pagination.Cursor == 0 {
+			break
+		}
+		cursor = response.Pagination.Cursor
+	}
+
+	sort.Slice(history, func(i, j int) bool { return history[i].NextTime < history[j].NextTime })
+	return history, nil
+}
+
+// ExtendedFundingPaymentsResponse is the response structure for the account's funding history.
+// FundingFee is signed from the account's point of view: positive when funding was received.
+type ExtendedFundingPaymentsResponse struct {
+	Status string `json:"status"`
+	Data   []struct {
+		Market      string `json:"market"`
+		FundingFee  string `json:"fundingFee"`
+		FundingRate string `json:"fundingRate"`
+		PaidTime    int64  `json:"paidTime"`
+	} `json:"data"`
+	Pagination struct {
+		Cursor int64 `json:"cursor"`
+		Count  int   `json:"count"`
+	} `json:"pagination"`
+}
+
+// GetFundingPayments returns the funding paid and received by the account on market since the
+// given time.
+func (e *Extended) GetFundingPayments(ctx context.Context, market string, since time.Time) ([]FundingPayment, error) {
+	var payments []FundingPayment
+	var cursor int64
+	for {
+		query := url.Values{
+			"market":   {market},
+			"fromTime": {strconv.FormatInt(since.UnixMilli(), 10)},
+			"limit":    {strconv.Itoa(extendedHistoryPageSize)},
+		}
+		if cursor != 0 {
+			query.Set("cursor", strconv.FormatInt(cursor, 10))
+		}
+		var response ExtendedFundingPaymentsResponse
+		if err := e.sendRequest(ctx, "GET", "/api/v1/user/funding/history?"+query.Encode(), nil, &response); err != nil {
+			return nil, fmt.Errorf("failed to get funding payments from Extended: %w", err)
+		}
+		if response.Status != "OK" {
+			return nil, fmt.Errorf("Extended API returned non-OK status for funding payments: %s", response.Status)
+		}
+
+		for _, p := range response.Data {
+			amount, err := strconv.ParseFloat(p.FundingFee, 64)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse funding payment for %s from Extended: %w", market, err)
+			}
+			rate, _ := strconv.ParseFloat(p.FundingRate, 64)
+			paid := time.UnixMilli(p.PaidTime)
+			if paid.Before(since) {
+				continue
+			}
+			payments = append(payments, FundingPayment{Market: p.Market, Time: paid, Rate: rate, Amount: amount})
+		}
+
+		if response.Pagination.Count < extendedHistoryPageSize || response.Pagination.Cursor == 0 {
+			break
+		}
+		cursor = response.Pagination.Cursor
+	}
+
+	sort.Slice(payments, func(i, j int) bool { return payments[i].Time.Before(payments[j].Time) })
+	return payments, nil
+}
+
+// ExtendedMarketStats holds the per-market statistics returned with the market list.
+type ExtendedMarketStats struct {
+	MarkPrice    string `json:"markPrice"`
+	IndexPrice   string `json:"indexPrice"`
+	FundingRate  string `json:"fundingRate"`
+	DailyVolume  string `json:"dailyVolume"`
+	OpenInterest string `json:"openInterest"`
+}
+
+// ExtendedMarketsResponse is the response structure for the markets endpoint
+type ExtendedMarketsResponse struct {
+	Status string `json:"status"`
+	Data   []struct {
+		Name          string                `json:"name"`
+		MarketStats   ExtendedMarketStats   `json:"marketStats"`
+		TradingConfig ExtendedTradingConfig `json:"tradingConfig"`
+	} `json:"data"`
+}
+
+// ExtendedTradingConfig holds the order rules of a market: the lot and tick sizes, the minimum
+// order size and the maximum leverage.
+type ExtendedTradingConfig struct {
+	MinOrderSize       string `json:"minOrderSize"`
+	MinOrderSizeChange string `json:"minOrderSizeChange"`
+	MinPriceChange     string `json:"minPriceChange"`
+	MaxLeverage        string `json:"maxLeverage"`
+}
+
+// extendedRules holds the order rules of a market.
+type extendedRules struct {
+	lotSize     decimalStep
+	tickSize    decimalStep
+	minSize     decimal.Decimal
+	maxLeverage float64
+}
+
+// rules returns the order rules of market, fetched on first use and cached.
+func (e *Extended) rules(ctx context.Context, market string) (extendedRules, error) {
+	e.rulesMu.Lock()
+	rules, ok := e.marketRules[market]
+	e.rulesMu.Unlock()
+	if ok {
+		return rules, nil
+	}
+	response, err := e.getMarkets(ctx, []string{market})
+	if err != nil {
+		return extendedRules{}, err
+	}
+	for _, m := range response.Data {
+		if m.Name != market {
+			continue
+		}
+		rules = extendedRules{
+			lotSize:     parseDecimalStep(m.TradingConfig.MinOrderSizeChange),
+			tickSize:    parseDecimalStep(m.TradingConfig.MinPriceChange),
+			minSize:     parseDecimal(m.TradingConfig.MinOrderSize),
+			maxLeverage: parseRule(m.TradingConfig.MaxLeverage),
+		}
+		e.rulesMu.Lock()
+		e.marketRules[market] = rules
+		e.rulesMu.Unlock()
+		return rules, nil
+	}
+	return extendedRules{}, fmt.Errorf("market %s not found on Extended", market)
+}
+
+// GetMarketInfo returns the trading rules of market.
+func (e *Extended) GetMarketInfo(ctx context.Context, market string) (*MarketInfo, error) {
+	rules, err := e.rules(ctx, market)
+	if err != nil {
+		return nil, err
+	}
+	return &MarketInfo{
+		Market:             market,
+		TickSize:           rules.tickSize.step,
+		LotSize:            rules.lotSize.step,
+		MinSize:            rules.minSize,
+		MaxLeverage:        rules.maxLeverage,
+		ContractMultiplier: 1,
+	}, nil
+}
+
+// getMarkets fetches the market list with statistics for the given markets, or all markets if empty.
+func (e *Extended) getMarkets(ctx context.Context, markets []string) (*ExtendedMarketsResponse, error) {
+	query := url.Values{}
+	for _, market := range markets {
+		query.Add("market", market)
+	}
+	endpoint := "/api/v1/info/markets"
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	var response ExtendedMarketsResponse
+	if err := e.sendRequest(ctx, "GET", endpoint, nil, &response); err != nil {
+		return nil, fmt.Errorf("failed to get markets from Extended: %w", err)
+	}
+	if response.Status != "OK" {
+		return nil, fmt.Errorf("Extended API returned non-OK status for markets: %s", response.Status)
+	}
+	return &response, nil
+}
+
+// GetMarkPrices fetches mark prices for several markets in a single request.
+// If markets is empty, every market listed on the exchange is priced.
+func (e *Extended) GetMarkPrices(ctx context.Context, markets []string) (map[string]float64, error) {
+	response, err := e.getMarkets(ctx, markets)
+	if err != nil {
+		return nil, err
+	}
+
+	prices := make(map[string]float64, len(response.Data))
+	for _, market := range response.Data {
+		markPrice, err := strconv.ParseFloat(market.MarketStats.MarkPrice, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse mark price float for %s from Extended: %w", market.Name, err)
+		}
+		prices[market.Name] = markPrice
+	}
+
+	return prices, nil
+}
+
+// GetMarketStats fetches 24h volume and open interest for several markets in a single request.
+// Both are reported by Extended in the collateral asset (USD).
+func (e *Extended) GetMarketStats(ctx context.Context, markets []string) (map[string]MarketStats, error) {
+	response, err := e.getMarkets(ctx, markets)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make(map[string]MarketStats, len(response.Data))
+	for _, market := range response.Data {
+		volume, err := strconv.ParseFloat(market.MarketStats.DailyVolume, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse daily volume for %s from Extended: %w", market.Name, err)
+		}
+		openInterest, err := strconv.ParseFloat(market.MarketStats.OpenInterest, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse open interest for %s from Extended: %w", market.Name, err)
+		}
+		stats[market.Name] = MarketStats{Market: market.Name, Volume24hUSD: volume, OpenInterestUSD: openInterest}
+	}
+
+	return stats, nil
+}
+
 func (e *Extended) getStarknetDomain() sdk.StarknetDomain {
 	if e.testnet {
 		return sdk.StarknetDomain{
@@ -154,44 +548,97 @@ func (e *Extended) getStarknetDomain() sdk.StarknetDomain {
 	}
 }
 
-// PlaceOrder sends a real, signed order to the Extended exchange using the SDK.
-func (e *Extended) PlaceOrder(market string, side OrderSide, orderType OrderType, amount, price float64) (*Order, error) {
+// PrepareOrderTemplates fetches market details for the given markets in one request and caches
+// pre-built order parameters (market info, account, signer and Starknet domain), so that
+// PlaceOrder only has to fill in side, size, price and nonce at decision time.
+func (e *Extended) PrepareOrderTemplates(markets []string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
 
-	// 1. Get market details from the exchange
-	markets, err := e.client.GetMarkets(ctx, []string{market})
+	marketInfos, err := e.sdkMarkets(ctx, markets)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get market details for %s: %w", market, err)
+		return fmt.Errorf("failed to get market details for order templates: %w", err)
 	}
-	if len(markets) == 0 {
-		return nil, fmt.Errorf("market %s not found on Extended", market)
-	}
-	marketInfo := markets[0]
 
-	// 2. Prepare order parameters
-	orderSide := sdk.OrderSideBuy
-	if side == Sell {
-		orderSide = sdk.OrderSideSell
+	e.templatesMu.Lock()
+	defer e.templatesMu.Unlock()
+	for _, marketInfo := range marketInfos {
+		params := e.baseOrderParams()
+		params.Market = marketInfo
+		e.templates[marketInfo.Name] = params
 	}
+	return nil
+}
 
-	nonce := int(time.Now().Unix())
-	params := sdk.CreateOrderObjectParams{
-		Market:                   marketInfo,
+// baseOrderParams builds the order parameters that do not change between orders.
+func (e *Extended) baseOrderParams() sdk.CreateOrderObjectParams {
+	return sdk.CreateOrderObjectParams{
 		Account:                  *e.account,
-		SyntheticAmount:          decimal.NewFromFloat(amount),
-		Side:                     orderSide,
 		Signer:                   e.account.Sign,
 		StarknetDomain:           e.getStarknetDomain(),
 		SelfTradeProtectionLevel: sdk.SelfTradeProtectionAccount,
-		Nonce:                    &nonce,
+	}
+}
+
+// orderTemplate returns the pre-built order parameters for market, fetching market details on a cache miss.
+func (e *Extended) orderTemplate(ctx context.Context, market string) (sdk.CreateOrderObjectParams, error) {
+	e.templatesMu.RLock()
+	params, ok := e.templates[market]
+	e.templatesMu.RUnlock()
+	if ok {
+		return params, nil
+	}
+
+	markets, err := e.sdkMarkets(ctx, []string{market})
+	if err != nil {
+		return sdk.CreateOrderObjectParams{}, fmt.Errorf("failed to get market details for %s: %w", market, err)
+	}
+	if len(markets) == 0 {
+		return sdk.CreateOrderObjectParams{}, fmt.Errorf("market %s not found on Extended", market)
+	}
+	params = e.baseOrderParams()
+	params.Market = markets[0]
+	return params, nil
+}
+
+// PlaceOrder sends a real, signed order to the Extended exchange using the SDK.
+func (e *Extended) PlaceOrder(ctx context.Context, market string, side OrderSide, orderType OrderType, amount, price decimal.Decimal) (*Order, error) {
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	start := time.Now()
+
+	// 1. Start from a pre-built template, or fetch market details from the exchange
+	params, err := e.orderTemplate(ctx, market)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. Fill in the per-order parameters, rounded to the market's lot and tick size
+	rules, err := e.rules(ctx, market)
+	if err != nil {
+		return nil, err
+	}
+	quantity := rules.lotSize.floor(amount)
+	if !quantity.IsPositive() {
+		return nil, fmt.Errorf("order amount %s is below the Extended lot size %s for %s", amount, rules.lotSize, market)
+	}
+
+	orderSide := sdk.OrderSideBuy
+	if side == Sell {
+		orderSide = sdk.OrderSideSell
 	}
 
+	nonce := e.nextNonce()
+	params.SyntheticAmount = quantity
+	params.Side = orderSide
+	params.Nonce = &nonce
+
 	if orderType == Market {
 		params.TimeInForce = sdk.TimeInForceIOC
 		// For market orders, the price field is still required for slippage protection.
 		// We'll calculate a price with a 5% buffer.
-		markPrice, err := e.GetMarkPrice(market)
+		markPrice, err := e.GetMarkPrice(ctx, market)
 		if err != nil {
 			return nil, fmt.Errorf("could not get mark price for market order: %w", err)
 		}
@@ -201,30 +648,35 @@ func (e *Extended) PlaceOrder(market string, side OrderSide, orderType OrderType
 		} else {
 			orderPrice = markPrice * 0.95
 		}
-		params.Price = decimal.NewFromFloat(orderPrice)
+		params.Price = rules.tickSize.round(decimal.NewFromFloat(orderPrice))
 	} else {
 		params.TimeInForce = sdk.TimeInForceGTT
-		params.Price = decimal.NewFromFloat(price)
+		price = rules.tickSize.round(price)
+		params.Price = price
 	}
 
 	// 3. Create and sign the order object
-	fmt.Printf("\n==> Creating and signing Extended order for %s...\n", market)
 	order, err := sdk.CreateOrderObject(params)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create SDK order object: %w", err)
 	}
-	orderJSON, _ := json.Marshal(order)
-	fmt.Printf("    Signed Order Payload: %s\n", string(orderJSON))
+	if slog.Default().Enabled(ctx, slog.LevelDebug) {
+		orderJSON, _ := json.Marshal(order)
+		slog.DebugContext(ctx, "Submitting signed Extended order", "market", market, "payload", string(orderJSON))
+	}
 
 	// 4. Submit the order
-	fmt.Println("    Submitting order to Extended API...")
+	if err := e.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
 	response, err := e.client.SubmitOrder(ctx, order)
 	if err != nil {
-		fmt.Printf("<== Extended Raw Error Response: %v\n", err)
 		return nil, fmt.Errorf("failed to submit order via SDK: %w", err)
 	}
-	respJSON, _ := json.Marshal(response)
-	fmt.Printf("<== Extended Raw Success Response: %s\n", string(respJSON))
+	if slog.Default().Enabled(ctx, slog.LevelDebug) {
+		respJSON, _ := json.Marshal(response)
+		slog.DebugContext(ctx, "Extended order submitted", "market", market, "response", string(respJSON), "latency", time.Since(start))
+	}
 
 	// 5. Return a standardized Order object
 	return &Order{
@@ -233,20 +685,103 @@ func (e *Extended) PlaceOrder(market string, side OrderSide, orderType OrderType
 		Side:      side,
 		Type:      orderType,
 		Price:     price,
-		Amount:    amount,
+		Amount:    quantity,
 		Status:    "NEW", // The SDK response doesn't include status, assuming NEW.
 		Timestamp: time.Now().Unix(),
 	}, nil
 }
 
-// GetOrderStatus is a placeholder
-func (e *Extended) GetOrderStatus(orderID string, market string) (*Order, error) {
-	return nil, fmt.Errorf("GetOrderStatus not implemented for Extended")
+// ExtendedOrder is an order as reported by the order endpoints. Status is NEW, PARTIALLY_FILLED,
+// FILLED, CANCELLED, REJECTED or EXPIRED; conditional orders are UNTRIGGERED until triggered.
+type ExtendedOrder struct {
+	ID           int64  `json:"id"`
+	Market       string `json:"market"`
+	Type         string `json:"type"`
+	Side         string `json:"side"`
+	Status       string `json:"status"`
+	Price        string `json:"price"`
+	AveragePrice string `json:"averagePrice"`
+	Qty          string `json:"qty"`
+	FilledQty    string `json:"filledQty"`
+	PayedFee     string `json:"payedFee"`
+	CreatedTime  int64  `json:"createdTime"`
+}
+
+// order converts the reported order into an Order. Price is the average fill price once anything
+// has filled, and the limit price before; conditional orders waiting for their trigger are NEW.
+func (o ExtendedOrder) order() *Order {
+	price := parseDecimal(o.AveragePrice)
+	if price.IsZero() {
+		price = parseDecimal(o.Price)
+	}
+	amount := parseDecimal(o.Qty)
+	filled := parseDecimal(o.FilledQty)
+	fee, _ := strconv.ParseFloat(o.PayedFee, 64)
+	status := strings.ToUpper(o.Status)
+	if status == "UNTRIGGERED" || status == "TRIGGERED" {
+		status = "NEW"
+	}
+	return &Order{
+		ID:        strconv.FormatInt(o.ID, 10),
+		Market:    o.Market,
+		Side:      OrderSide(strings.ToUpper(o.Side)),
+		Type:      OrderType(strings.ToUpper(o.Type)),
+		Price:     price,
+		Amount:    amount,
+		Filled:    filled,
+		Status:    status,
+		Timestamp: o.CreatedTime / 1000,
+		Fee:       fee,
+	}
 }
 
-// CancelOrder is a placeholder
-func (e *Extended) CancelOrder(orderID string, market string) error {
-	fmt.Printf("Simulating cancelling order on Extended: %s\n", orderID)
+// GetOrderStatus fetches an order by the ID PlaceOrder returned.
+func (e *Extended) GetOrderStatus(ctx context.Context, orderID string, market string) (*Order, error) {
+	var response struct {
+		Status string        `json:"status"`
+		Data   ExtendedOrder `json:"data"`
+	}
+	if err := e.sendRequest(ctx, "GET", "/api/v1/user/orders/"+url.PathEscape(orderID), nil, &response); err != nil {
+		return nil, fmt.Errorf("failed to get order %s from Extended: %w", orderID, err)
+	}
+	if response.Status != "OK" {
+		return nil, fmt.Errorf("Extended API returned non-OK status for order %s: %s", orderID, response.Status)
+	}
+	if market != "" && response.Data.Market != "" && response.Data.Market != market {
+		return nil, fmt.Errorf("Extended order %s is on %s, not %s", orderID, response.Data.Market, market)
+	}
+	return response.Data.order(), nil
+}
+
+// CancelOrder cancels an open order by the ID PlaceOrder returned.
+func (e *Extended) CancelOrder(ctx context.Context, orderID string, market string) error {
+	var response struct {
+		Status string `json:"status"`
+	}
+	if err := e.sendRequest(ctx, "DELETE", "/api/v1/user/order/"+url.PathEscape(orderID), nil, &response); err != nil {
+		return fmt.Errorf("failed to cancel order %s on Extended: %w", orderID, err)
+	}
+	if response.Status != "OK" {
+		return fmt.Errorf("Extended API returned non-OK status cancelling order %s: %s", orderID, response.Status)
+	}
+	return nil
+}
+
+// CancelAllOrders cancels every open order on market, or on every market if market is "".
+func (e *Extended) CancelAllOrders(ctx context.Context, market string) error {
+	request := map[string]interface{}{"cancelAll": true}
+	if market != "" {
+		request = map[string]interface{}{"markets": []string{market}}
+	}
+	var response struct {
+		Status string `json:"status"`
+	}
+	if err := e.sendRequest(ctx, "POST", "/api/v1/user/order/massCancel", request, &response); err != nil {
+		return fmt.Errorf("failed to cancel the orders on Extended: %w", err)
+	}
+	if response.Status != "OK" {
+		return fmt.Errorf("Extended API returned non-OK status cancelling orders: %s", response.Status)
+	}
 	return nil
 }
 
@@ -262,19 +797,14 @@ type ExtendedBalanceResponse struct {
 }
 
 // GetBalance fetches the balance for a specific asset
-func (e *Extended) GetBalance(asset string) (float64, error) {
+func (e *Extended) GetBalance(ctx context.Context, asset string) (float64, error) {
 	endpoint := "/api/v1/user/balance"
-	body, err := e.sendRequest("GET", endpoint, nil)
-	if err != nil {
-		return 0, fmt.Errorf("failed to get balance from Extended: %w", err)
-	}
-
 	var response ExtendedBalanceResponse
-	if err := json.Unmarshal(body, &response); err != nil {
-		return 0, fmt.Errorf("failed to unmarshal balance response from Extended: %w", err)
+	if err := e.sendRequest(ctx, "GET", endpoint, nil, &response); err != nil {
+		return 0, fmt.Errorf("failed to get balance from Extended: %w", err)
 	}
 	if response.Status != "OK" {
-		return 0, fmt.Errorf("Extended API returned non-OK status for balance: %s", string(body))
+		return 0, fmt.Errorf("Extended API returned non-OK status for balance: %s", response.Status)
 	}
 
 	balance, err := strconv.ParseFloat(response.Data.Balance, 64)
@@ -285,37 +815,152 @@ func (e *Extended) GetBalance(asset string) (float64, error) {
 	return balance, nil
 }
 
-// sendRequest is a helper function to make HTTP requests to the Extended API
-func (e *Extended) sendRequest(method, endpoint string, data []byte) ([]byte, error) {
-	url := e.baseURL + endpoint
-	req, err := http.NewRequest(method, url, bytes.NewBuffer(data))
-	if err != nil {
-		return nil, err
+// SetMarginMode selects the margin mode of market. Extended only offers cross margin, so isolated
+// margin is rejected rather than silently ignored.
+func (e *Extended) SetMarginMode(ctx context.Context, market string, mode MarginMode) error {
+	if mode != CrossMargin {
+		return fmt.Errorf("Extended only supports %s margin", CrossMargin)
 	}
+	return nil
+}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-Api-Key", e.apiKey)
-	req.Header.Set("User-Agent", "FundingRateArbBot/1.0")
+// SetLeverage sets the leverage of market.
+func (e *Extended) SetLeverage(ctx context.Context, market string, leverage float64) error {
+	request := map[string]string{"market": market, "leverage": strconv.FormatFloat(leverage, 'f', -1, 64)}
+	var response struct {
+		Status string `json:"status"`
+	}
+	if err := e.sendRequest(ctx, "PATCH", "/api/v1/user/leverage", request, &response); err != nil {
+		return fmt.Errorf("failed to set %gx leverage for %s on Extended: %w", leverage, market, err)
+	}
+	if response.Status != "OK" {
+		return fmt.Errorf("Extended API returned non-OK status setting leverage: %s", response.Status)
+	}
+	return nil
+}
 
-	resp, err := e.httpClient.Do(req)
-	if err != nil {
-		return nil, err
+// ExtendedPositionsResponse is the response structure for the positions endpoint
+type ExtendedPositionsResponse struct {
+	Status string `json:"status"`
+	Data   []struct {
+		Market           string `json:"market"`
+		Side             string `json:"side"`
+		Size             string `json:"size"`
+		OpenPrice        string `json:"openPrice"`
+		MarkPrice        string `json:"markPrice"`
+		LiquidationPrice string `json:"liquidationPrice"`
+		Margin           string `json:"margin"`
+	} `json:"data"`
+}
+
+// GetPositions fetches the open positions of the account.
+func (e *Extended) GetPositions(ctx context.Context) ([]Position, error) {
+	var response ExtendedPositionsResponse
+	if err := e.sendRequest(ctx, "GET", "/api/v1/user/positions", nil, &response); err != nil {
+		return nil, fmt.Errorf("failed to get positions from Extended: %w", err)
+	}
+	if response.Status != "OK" {
+		return nil, fmt.Errorf("Extended API returned non-OK status for positions: %s", response.Status)
 	}
-	defer resp.Body.Close()
 
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
+	positions := make([]Position, 0, len(response.Data))
+	for _, p := range response.Data {
+		size, err := strconv.ParseFloat(p.Size, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse position size for %s from Extended: %w", p.Market, err)
+		}
+		entry, _ := strconv.ParseFloat(p.OpenPrice, 64)
+		side := Buy
+		if p.Side == "SHORT" {
+			side = Sell
+		}
+		positions = append(positions, Position{Market: p.Market, Side: side, Size: math.Abs(size), EntryPrice: entry})
 	}
+	return positions, nil
+}
 
-	if resp.StatusCode >= 400 {
-		return nil, fmt.Errorf("API error: %s - %s", resp.Status, string(body))
+// GetPositionRisk fetches the margin and liquidation price of the open position in market.
+func (e *Extended) GetPositionRisk(ctx context.Context, market string) (*PositionRisk, error) {
+	var response ExtendedPositionsResponse
+	query := url.Values{"market": {market}}
+	if err := e.sendRequest(ctx, "GET", "/api/v1/user/positions?"+query.Encode(), nil, &response); err != nil {
+		return nil, fmt.Errorf("failed to get the %s position from Extended: %w", market, err)
 	}
+	if response.Status != "OK" {
+		return nil, fmt.Errorf("Extended API returned non-OK status for positions: %s", response.Status)
+	}
+	for _, p := range response.Data {
+		if p.Market != market {
+			continue
+		}
+		size, err := strconv.ParseFloat(p.Size, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse position size for %s from Extended: %w", market, err)
+		}
+		mark, err := strconv.ParseFloat(p.MarkPrice, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse mark price for %s from Extended: %w", market, err)
+		}
+		// The liquidation price and margin are empty for positions that can't be liquidated.
+		liquidation, _ := strconv.ParseFloat(p.LiquidationPrice, 64)
+		margin, _ := strconv.ParseFloat(p.Margin, 64)
+		side := Buy
+		if p.Side == "SHORT" {
+			side = Sell
+		}
+		return &PositionRisk{Market: market, Side: side, Size: math.Abs(size), MarkPrice: mark, LiquidationPrice: liquidation, Margin: margin}, nil
+	}
+	return nil, fmt.Errorf("no open %s position on Extended", market)
+}
+
+// RequestTestFunds claims test USDC from the Extended testnet faucet for the account behind the API key.
+func (e *Extended) RequestTestFunds(ctx context.Context) error {
+	if !e.testnet {
+		return errors.New("test funds can only be requested on testnet")
+	}
+	var response struct {
+		Status string `json:"status"`
+	}
+	if err := e.sendRequest(ctx, "POST", "/api/v1/user/claim", nil, &response); err != nil {
+		return fmt.Errorf("failed to claim testnet funds from Extended: %w", err)
+	}
+	if response.Status != "OK" {
+		return fmt.Errorf("Extended API returned non-OK status for faucet claim: %s", response.Status)
+	}
+	return nil
+}
+
+// SetTransport replaces the HTTP transport used for REST requests outside the SDK, e.g. with a recorder in tests.
+func (e *Extended) SetTransport(rt http.RoundTripper) {
+	e.httpClient.Transport = rt
+}
+
+// sendRequest is a helper function to make HTTP requests to the Extended API.
+// The JSON response is decoded directly into out.
+func (e *Extended) sendRequest(ctx context.Context, method, endpoint string, payload interface{}, out interface{}) error {
+	var body io.Reader
+	if payload != nil {
+		buf, err := encodeJSON(payload)
+		if err != nil {
+			return err
+		}
+		defer putBuffer(buf)
+		body = buf
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, e.baseURL+endpoint, body)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Api-Key", e.apiKey)
+	req.Header.Set("User-Agent", "FundingRateArbBot/1.0")
 
-	return body, nil
+	return doJSON(e.httpClient, req, out)
 }
 
-func (e *Extended) ClosePosition(market string, side OrderSide, amount float64) (*Order, error) {
+func (e *Extended) ClosePosition(ctx context.Context, market string, side OrderSide, amount decimal.Decimal) (*Order, error) {
 	// To close a position, we place an order on the opposite side.
 	closeSide := Sell
 	if side == Sell {
@@ -323,5 +968,5 @@ func (e *Extended) ClosePosition(market string, side OrderSide, amount float64)
 	}
 
 	// Using a market order to close, so price is irrelevant (can be 0).
-	return e.PlaceOrder(market, closeSide, Market, amount, 0)
+	return e.PlaceOrder(ctx, market, closeSide, Market, amount, decimal.Zero)
 }
@@ -0,0 +1,130 @@
+package exchange
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/shopspring/decimal"
+)
+
+// extendedStreamPath is where Extended serves its websocket streams, relative to the API host.
+const extendedStreamPath = "/stream.extended.exchange/v1"
+
+// ExtendedFundingMessage is a funding rate update from the funding stream.
+type ExtendedFundingMessage struct {
+	Data struct {
+		Market      string `json:"m"`
+		Timestamp   int64  `json:"T"`
+		FundingRate string `json:"f"`
+	} `json:"data"`
+}
+
+// ExtendedMarkPriceMessage is a price update from the mark price stream.
+type ExtendedMarkPriceMessage struct {
+	Type string `json:"type"`
+	Data struct {
+		Market string `json:"m"`
+		Price  string `json:"p"`
+	} `json:"data"`
+}
+
+// ExtendedAccountMessage is an update from the private account stream. Only order updates are
+// decoded; balance and position updates are ignored.
+type ExtendedAccountMessage struct {
+	Type string `json:"type"`
+	Data struct {
+		Orders []struct {
+			ID           json.Number `json:"id"`
+			Market       string      `json:"market"`
+			Type         string      `json:"type"`
+			Side         string      `json:"side"`
+			Status       string      `json:"status"`
+			Price        string      `json:"price"`
+			AveragePrice string      `json:"averagePrice"`
+			Qty          string      `json:"qty"`
+			FilledQty    string      `json:"filledQty"`
+			CreatedTime  int64       `json:"createdTime"`
+		} `json:"orders"`
+	} `json:"data"`
+}
+
+// streamURL returns the websocket URL of an Extended stream on the configured network.
+func (e *Extended) streamURL(stream string) string {
+	base := strings.Replace(e.baseURL, "https://", "wss://", 1)
+	base = strings.Replace(base, "http://", "ws://", 1)
+	return base + extendedStreamPath + stream
+}
+
+// Stream subscribes to Extended's funding rate, mark price and account streams for markets.
+// The account stream carries order updates and needs the API key.
+func (e *Extended) Stream(markets []string, handler StreamHandler, stop <-chan struct{}) error {
+	wanted := make(map[string]bool, len(markets))
+	for _, market := range markets {
+		wanted[market] = true
+	}
+
+	var wg sync.WaitGroup
+	run := func(stream string, header http.Header, handle func([]byte)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			streamLoop(e.streamURL(stream), header, stop, handle, handler.OnError)
+		}()
+	}
+
+	if handler.OnFundingRates != nil {
+		run("/funding", nil, func(message []byte) {
+			var update ExtendedFundingMessage
+			if json.Unmarshal(message, &update) != nil || !wanted[update.Data.Market] {
+				return
+			}
+			rate, err := decimal.NewFromString(update.Data.FundingRate)
+			if err != nil {
+				return
+			}
+			handler.OnFundingRates([]*FundingRate{{Market: update.Data.Market, Rate: rate}})
+		})
+	}
+	if handler.OnMarkPrice != nil {
+		run("/prices/mark", nil, func(message []byte) {
+			var update ExtendedMarkPriceMessage
+			if json.Unmarshal(message, &update) != nil || !wanted[update.Data.Market] {
+				return
+			}
+			if price, err := strconv.ParseFloat(update.Data.Price, 64); err == nil && price > 0 {
+				handler.OnMarkPrice(update.Data.Market, price)
+			}
+		})
+	}
+	if handler.OnOrder != nil && e.apiKey != "" {
+		run("/account", http.Header{"X-Api-Key": {e.apiKey}}, func(message []byte) {
+			var update ExtendedAccountMessage
+			if json.Unmarshal(message, &update) != nil || update.Type != "ORDER" {
+				return
+			}
+			for _, o := range update.Data.Orders {
+				price := parseDecimal(o.AveragePrice)
+				if price.IsZero() {
+					price = parseDecimal(o.Price)
+				}
+				handler.OnOrder(&Order{
+					ID:        o.ID.String(),
+					Market:    o.Market,
+					Side:      OrderSide(o.Side),
+					Type:      OrderType(o.Type),
+					Price:     price,
+					Amount:    parseDecimal(o.Qty),
+					Filled:    parseDecimal(o.FilledQty),
+					Status:    o.Status,
+					Timestamp: o.CreatedTime,
+				})
+			}
+		})
+	}
+
+	wg.Wait()
+	return nil
+}
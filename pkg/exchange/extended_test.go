@@ -0,0 +1,175 @@
+package exchange
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestExtendedMarketData(t *testing.T) {
+	api := newFakeAPI(t)
+	api.respond("GET", "/api/v1/info/markets/BTC-USD/stats", http.StatusOK, `{"status":"OK","data":{"markPrice":"65000.5"}}`)
+	api.respond("GET", "/api/v1/info/markets", http.StatusOK, `{"status":"OK","data":[
+		{"name":"BTC-USD","marketStats":{"markPrice":"65000.5","fundingRate":"0.0001"}},
+		{"name":"ETH-USD","marketStats":{"markPrice":"3200","fundingRate":"-0.00002"}}]}`)
+	ex := newTestExtended(api)
+
+	price, err := ex.GetMarkPrice(context.Background(), "BTC-USD")
+	if err != nil || price != 65000.5 {
+		t.Fatalf("GetMarkPrice = %f, %v; want 65000.5", price, err)
+	}
+	if got := api.lastRequest("/api/v1/info/markets/").Header.Get("X-Api-Key"); got != "test-key" {
+		t.Errorf("expected the API key header, got %q", got)
+	}
+
+	prices, err := ex.GetMarkPrices(context.Background(), []string{"BTC-USD", "ETH-USD"})
+	if err != nil {
+		t.Fatalf("GetMarkPrices: %v", err)
+	}
+	if prices["ETH-USD"] != 3200 || len(prices) != 2 {
+		t.Errorf("unexpected prices %v", prices)
+	}
+	if got := api.lastRequest("/api/v1/info/markets").URL.Query()["market"]; len(got) != 2 {
+		t.Errorf("expected both markets in the query, got %v", got)
+	}
+}
+
+func TestExtendedOrderbook(t *testing.T) {
+	api := newFakeAPI(t)
+	api.respond("GET", "/api/v1/info/markets/BTC-USD/orderbook", http.StatusOK, `{"status":"OK","data":{"market":"BTC-USD",
+		"bid":[{"qty":"0.5","price":"64990"},{"qty":"2","price":"64980"}],
+		"ask":[{"qty":"1","price":"65010"}]}}`)
+	ex := newTestExtended(api)
+
+	book, err := ex.GetOrderbook(context.Background(), "BTC-USD")
+	if err != nil {
+		t.Fatalf("GetOrderbook: %v", err)
+	}
+	if len(book.Bids) != 2 || book.Bids[0] != (PriceLevel{Price: 64990, Size: 0.5}) || book.Asks[0] != (PriceLevel{Price: 65010, Size: 1}) {
+		t.Errorf("unexpected book %+v", book)
+	}
+}
+
+func TestExtendedPositionRisk(t *testing.T) {
+	api := newFakeAPI(t)
+	api.respond("GET", "/api/v1/user/positions", http.StatusOK, `{"status":"OK","data":[
+		{"market":"BTC-USD","side":"SHORT","size":"0.5","openPrice":"64000","markPrice":"65000","liquidationPrice":"78000","margin":"3250"}]}`)
+	ex := newTestExtended(api)
+
+	risk, err := ex.GetPositionRisk(context.Background(), "BTC-USD")
+	if err != nil {
+		t.Fatalf("GetPositionRisk: %v", err)
+	}
+	if distance, ok := risk.LiquidationDistance(); risk.Side != Sell || risk.Margin != 3250 || !ok || distance != 0.2 {
+		t.Errorf("unexpected risk %+v (distance %f)", risk, distance)
+	}
+	if got := api.lastRequest("/api/v1/user/positions").URL.Query().Get("market"); got != "BTC-USD" {
+		t.Errorf("expected the market in the query, got %q", got)
+	}
+}
+
+func TestExtendedBalance(t *testing.T) {
+	api := newFakeAPI(t)
+	api.respond("GET", "/api/v1/user/balance", http.StatusOK, `{"status":"OK","data":{"balance":"1234.56"}}`)
+	ex := newTestExtended(api)
+
+	balance, err := ex.GetBalance(context.Background(), "USDC")
+	if err != nil || balance != 1234.56 {
+		t.Fatalf("GetBalance = %f, %v; want 1234.56", balance, err)
+	}
+}
+
+func TestExtendedErrorHandling(t *testing.T) {
+	api := newFakeAPI(t)
+	api.respond("GET", "/api/v1/user/balance", http.StatusUnauthorized, `{"status":"ERROR","error":{"code":1006,"message":"invalid api key"}}`)
+	api.respond("GET", "/api/v1/info/markets/BTC-USD/stats", http.StatusOK, `{"status":"ERROR"}`)
+	api.respond("GET", "/api/v1/info/markets/ETH-USD/stats", http.StatusOK, `{"status":"OK","data":{"markPrice":"not-a-number"}}`)
+	api.respond("POST", "/api/v1/user/claim", http.StatusOK, `{"status":"OK"}`)
+	ex := newTestExtended(api)
+
+	if _, err := ex.GetBalance(context.Background(), "USDC"); err == nil || !strings.Contains(err.Error(), "401") || !strings.Contains(err.Error(), "invalid api key") {
+		t.Errorf("expected the HTTP status and body in the error, got %v", err)
+	}
+	if _, err := ex.GetMarkPrice(context.Background(), "BTC-USD"); err == nil || !strings.Contains(err.Error(), "non-OK") {
+		t.Errorf("expected a non-OK status error, got %v", err)
+	}
+	if _, err := ex.GetMarkPrice(context.Background(), "ETH-USD"); err == nil {
+		t.Error("expected a parse error for a malformed mark price")
+	}
+	if _, err := ex.GetMarkPrice(context.Background(), "SOL-USD"); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("expected a 404 for an unknown market, got %v", err)
+	}
+	if err := ex.RequestTestFunds(context.Background()); err != nil {
+		t.Errorf("RequestTestFunds: %v", err)
+	}
+}
+
+func TestExtendedFundingPayments(t *testing.T) {
+	api := newFakeAPI(t)
+	api.respond("GET", "/api/v1/user/funding/history", http.StatusOK, `{"status":"OK","data":[
+		{"market":"BTC-USD","side":"SHORT","size":"0.1","fundingFee":"0.65","fundingRate":"0.0001","paidTime":1704074400000},
+		{"market":"BTC-USD","side":"SHORT","size":"0.1","fundingFee":"-0.13","fundingRate":"-0.00002","paidTime":1704070800000}],
+		"pagination":{"cursor":0,"count":2}}`)
+	ex := newTestExtended(api)
+
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	payments, err := ex.GetFundingPayments(context.Background(), "BTC-USD", since)
+	if err != nil {
+		t.Fatalf("GetFundingPayments: %v", err)
+	}
+	if len(payments) != 2 || payments[0].Amount != -0.13 || payments[1].Amount != 0.65 || !payments[1].Time.Equal(since.Add(2*time.Hour)) {
+		t.Errorf("expected both payments oldest first, got %+v", payments)
+	}
+	query := api.lastRequest("/api/v1/user/funding/history").URL.Query()
+	if query.Get("market") != "BTC-USD" || query.Get("fromTime") != "1704067200000" {
+		t.Errorf("unexpected query %v", query)
+	}
+}
+
+func TestExtendedOrderStatusAndCancel(t *testing.T) {
+	api := newFakeAPI(t)
+	api.respond("GET", "/api/v1/user/orders/1001", http.StatusOK, `{"status":"OK","data":{"id":1001,"market":"BTC-USD",
+		"type":"LIMIT","side":"BUY","status":"PARTIALLY_FILLED","price":"65000","averagePrice":"64990","qty":"0.2",
+		"filledQty":"0.05","payedFee":"0.81","createdTime":1700000000000}}`)
+	api.respond("DELETE", "/api/v1/user/order/1001", http.StatusOK, `{"status":"OK"}`)
+	api.respond("POST", "/api/v1/user/order/massCancel", http.StatusOK, `{"status":"OK"}`)
+	ex := newTestExtended(api)
+
+	order, err := ex.GetOrderStatus(context.Background(), "1001", "BTC-USD")
+	if err != nil {
+		t.Fatalf("GetOrderStatus: %v", err)
+	}
+	if order.ID != "1001" || order.Market != "BTC-USD" || order.Side != Buy || order.Type != Limit || !order.Price.Equal(decimal.NewFromInt(64990)) || !order.Amount.Equal(decimal.NewFromFloat(0.2)) || !order.Filled.Equal(decimal.NewFromFloat(0.05)) || order.Status != "PARTIALLY_FILLED" || order.Timestamp != 1700000000 || order.Fee != 0.81 {
+		t.Errorf("unexpected order %+v", order)
+	}
+	if _, err := ex.GetOrderStatus(context.Background(), "1001", "ETH-USD"); err == nil {
+		t.Error("expected an order on another market to be reported")
+	}
+
+	if err := ex.CancelOrder(context.Background(), "1001", "BTC-USD"); err != nil {
+		t.Errorf("CancelOrder: %v", err)
+	}
+	if err := ex.CancelAllOrders(context.Background(), "BTC-USD"); err != nil {
+		t.Errorf("CancelAllOrders: %v", err)
+	}
+	if err := ex.CancelOrder(context.Background(), "404", "BTC-USD"); err == nil {
+		t.Error("expected cancelling an unknown order to fail")
+	}
+}
+
+func TestExtendedNoncesAreDistinctWithinASecond(t *testing.T) {
+	ex := newTestExtended(newFakeAPI(t))
+	ex.nonces.Store(extendedMaxNonce - 2)
+	seen := make(map[int]bool)
+	for i := 0; i < 5; i++ {
+		nonce := ex.nextNonce()
+		if nonce < 1 || nonce > extendedMaxNonce || seen[nonce] {
+			t.Fatalf("nonce %d is out of range or repeated, got %v before", nonce, seen)
+		}
+		seen[nonce] = true
+	}
+}
@@ -0,0 +1,157 @@
+package exchange
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	sdk "github.com/extended-protocol/extended-sdk-golang/src"
+)
+
+// fakeAPI is an in-process stand-in for a venue's REST API. Handlers are keyed by
+// "METHOD /path" and every request is recorded for assertions.
+type fakeAPI struct {
+	*httptest.Server
+	mu       sync.Mutex
+	handlers map[string]http.HandlerFunc
+	requests []*http.Request
+}
+
+func newFakeAPI(t *testing.T) *fakeAPI {
+	api := &fakeAPI{handlers: make(map[string]http.HandlerFunc)}
+	api.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		api.mu.Lock()
+		api.requests = append(api.requests, r)
+		handler, ok := api.handlers[r.Method+" "+r.URL.Path]
+		api.mu.Unlock()
+		if !ok {
+			http.Error(w, `{"status":"ERROR","error":"not found"}`, http.StatusNotFound)
+			return
+		}
+		handler(w, r)
+	}))
+	t.Cleanup(api.Close)
+	return api
+}
+
+// handle registers a handler for a method and path.
+func (a *fakeAPI) handle(method, path string, handler http.HandlerFunc) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.handlers[method+" "+path] = handler
+}
+
+// respond registers a handler replying with status and v encoded as JSON.
+func (a *fakeAPI) respond(method, path string, status int, v interface{}) {
+	a.handle(method, path, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		if s, ok := v.(string); ok {
+			_, _ = w.Write([]byte(s))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(v)
+	})
+}
+
+// lastRequest returns the most recent request whose path starts with prefix.
+func (a *fakeAPI) lastRequest(prefix string) *http.Request {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for i := len(a.requests) - 1; i >= 0; i-- {
+		if strings.HasPrefix(a.requests[i].URL.Path, prefix) {
+			return a.requests[i]
+		}
+	}
+	return nil
+}
+
+// newTestExtended returns an Extended client whose REST calls go to api.
+func newTestExtended(api *fakeAPI) *Extended {
+	return &Extended{
+		httpClient:  api.Client(),
+		apiKey:      "test-key",
+		baseURL:     api.URL,
+		testnet:     true,
+		templates:   make(map[string]sdk.CreateOrderObjectParams),
+		marketRules: make(map[string]extendedRules),
+	}
+}
+
+// newTestLighter returns a Lighter client whose REST calls go to api.
+func newTestLighter(api *fakeAPI) *Lighter {
+	return &Lighter{client: api.Client(), apiKey: "test-key", baseURL: api.URL, testnet: true}
+}
+
+// newTestDydx returns a dYdX client whose indexer and validator calls go to api.
+func newTestDydx(api *fakeAPI) *Dydx {
+	return &Dydx{client: api.Client(), address: "dydx1test", baseURL: api.URL, validatorURL: api.URL, testnet: true}
+}
+
+// newTestGmx returns a GMX client whose API and indexer calls go to api.
+func newTestGmx(api *fakeAPI) *Gmx {
+	return &Gmx{client: api.Client(), account: "0xAccount", baseURL: api.URL, squidURL: api.URL + "/graphql",
+		rpcURL: api.URL + "/rpc", receiptPoll: time.Millisecond, leverage: make(map[string]float64)}
+}
+
+// newTestBinance returns a Binance client whose REST calls go to api.
+func newTestBinance(api *fakeAPI) *Binance {
+	return &Binance{client: api.Client(), name: "Binance", apiKey: "test-key", secretKey: "test-secret", baseURL: api.URL, testnet: true}
+}
+
+// newTestBybit returns a Bybit client whose REST calls go to api.
+func newTestBybit(api *fakeAPI) *Bybit {
+	return &Bybit{client: api.Client(), apiKey: "test-key", secretKey: "test-secret", baseURL: api.URL, testnet: true}
+}
+
+// newTestParadex returns a Paradex client whose REST calls go to api, hashing typed data with
+// hasher and signing with a fixed signature.
+func newTestParadex(api *fakeAPI, hasher ParadexHasher) *Paradex {
+	return &Paradex{
+		client:     api.Client(),
+		account:    "0xacc",
+		privateKey: "0x1",
+		baseURL:    api.URL,
+		testnet:    true,
+		hasher:     hasher,
+		sign: func(hash, privateKey string) (string, error) {
+			return fmt.Sprintf("%064x%064x%064x", 1, 2, 0), nil
+		},
+	}
+}
+
+// newTestDrift returns a Drift client whose data API, DLOB server and gateway calls all go to api.
+func newTestDrift(api *fakeAPI) *Drift {
+	return &Drift{client: api.Client(), dataURL: api.URL, dlobURL: api.URL, gatewayURL: api.URL, testnet: true}
+}
+
+// newTestApex returns an ApeX client whose REST calls go to api, signing orders with signer.
+func newTestApex(api *fakeAPI, signer ApexSigner) *Apex {
+	return &Apex{client: api.Client(), apiKey: "test-key", secretKey: "test-secret", passphrase: "test-pass", baseURL: api.URL, testnet: true, signer: signer}
+}
+
+// newTestAevo returns an Aevo client whose REST calls go to api, signing orders with signer.
+func newTestAevo(api *fakeAPI, signer AevoSigner) *Aevo {
+	return &Aevo{client: api.Client(), account: "0xmaker", apiKey: "test-key", secretKey: "test-secret", baseURL: api.URL, testnet: true, signer: signer}
+}
+
+// newTestOrderly returns an Orderly client whose REST calls go to api, signing with key unless
+// it is nil.
+func newTestOrderly(api *fakeAPI, key ed25519.PrivateKey) *Orderly {
+	o := &Orderly{client: api.Client(), accountID: "0xaccount", baseURL: api.URL, testnet: true}
+	if key != nil {
+		o.setKey(key)
+	}
+	return o
+}
+
+// newTestOKX returns an OKX client whose REST calls go to api, in demo trading.
+func newTestOKX(api *fakeAPI) *OKX {
+	return &OKX{client: api.Client(), apiKey: "test-key", secretKey: "test-secret", passphrase: "test-pass", baseURL: api.URL, testnet: true}
+}
@@ -0,0 +1,170 @@
+// Package generic monitors the funding rates of venues described by a descriptor file instead of
+// a dedicated adapter. A descriptor gives the endpoint paths, where the fields are in the JSON
+// responses and how requests are authenticated, which covers most REST APIs closely enough to
+// watch their rates next to the trading venues.
+package generic
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Auth schemes a descriptor can use.
+const (
+	// AuthNone sends requests unauthenticated, which is enough for public market data.
+	AuthNone = "none"
+	// AuthHeader sends the API key in a header.
+	AuthHeader = "header"
+	// AuthHMACSHA256 adds a millisecond timestamp to the query and signs the query string with
+	// an HMAC-SHA256 of the secret, in hex, as Binance-style APIs do. The API key is sent in a
+	// header if one is named.
+	AuthHMACSHA256 = "hmac-sha256"
+)
+
+// Time units of the next funding time in a response.
+const (
+	UnitSeconds      = "s"
+	UnitMilliseconds = "ms"
+	UnitNanoseconds  = "ns"
+	UnitRFC3339      = "rfc3339"
+)
+
+// Descriptor describes a venue to the generic adapter. It is read from a YAML or JSON file.
+type Descriptor struct {
+	// Name is the venue name, as listed in EXCHANGES and shown in the rate tables.
+	Name string `mapstructure:"name"`
+	// BaseURL and TestnetURL are the API roots on mainnet and testnet. Without a TestnetURL the
+	// venue is monitored on mainnet either way, which is safe since it is read-only.
+	BaseURL    string `mapstructure:"base_url"`
+	TestnetURL string `mapstructure:"testnet_url"`
+	// SymbolFormat turns a market into the venue's symbol: {BASE} is replaced by the upper-case
+	// base asset and {base} by the lower-case one, e.g. "{BASE}USDT" or "{base}_usdc_perp".
+	SymbolFormat string `mapstructure:"symbol_format"`
+	// FundingInterval is the period the rates are quoted over, e.g. "8h". Defaults to 1h.
+	FundingInterval time.Duration `mapstructure:"funding_interval"`
+	// Collateral is the asset positions would be margined in, e.g. "USDT". Defaults to USDC.
+	Collateral string `mapstructure:"collateral"`
+
+	FundingRates Endpoint `mapstructure:"funding_rates"`
+	// MarkPrice is optional. Without it mark prices are read from the funding rates endpoint,
+	// which then needs a mark_price field.
+	MarkPrice *Endpoint `mapstructure:"mark_price"`
+
+	Auth Auth `mapstructure:"auth"`
+}
+
+// Endpoint is a request and the fields to read from its response. Field paths are separated by
+// dots and may index arrays, e.g. "result.list" or "data.0.rate".
+type Endpoint struct {
+	Method string `mapstructure:"method"`
+	// Path is appended to the base URL. {symbol} is replaced by the market's symbol, for
+	// endpoints that serve one market at a time.
+	Path  string            `mapstructure:"path"`
+	Query map[string]string `mapstructure:"query"`
+	// Body is sent as is, e.g. a JSON object for APIs queried with POST requests.
+	Body string `mapstructure:"body"`
+
+	// Items is the path to the array of entries, or to a single entry, in the response. Empty
+	// means the response itself.
+	Items string `mapstructure:"items"`
+	// Symbol is the field of an entry holding its symbol. Entries whose symbol doesn't match
+	// SymbolFormat are skipped.
+	Symbol string `mapstructure:"symbol"`
+	// Rate is the field holding the funding rate, multiplied by RateScale if it is set, e.g.
+	// 0.01 for rates given in percent.
+	Rate      string  `mapstructure:"rate"`
+	RateScale float64 `mapstructure:"rate_scale"`
+	// PredictedRate is the optional field holding the predicted rate of the next interval,
+	// scaled like Rate.
+	PredictedRate string `mapstructure:"predicted_rate"`
+	// NextTime is the optional field holding the next funding time, in NextTimeUnit (one of s,
+	// ms, ns and rfc3339; ms by default).
+	NextTime     string `mapstructure:"next_time"`
+	NextTimeUnit string `mapstructure:"next_time_unit"`
+	// MarkPrice is the field holding the mark price.
+	MarkPrice string `mapstructure:"mark_price"`
+}
+
+// Auth is how requests are authenticated. Values of the form ${VAR} are read from the
+// environment, so keys can stay out of the descriptor.
+type Auth struct {
+	Scheme string `mapstructure:"scheme"`
+	APIKey string `mapstructure:"api_key"`
+	Secret string `mapstructure:"secret"`
+	// KeyHeader is the header the API key is sent in.
+	KeyHeader string `mapstructure:"key_header"`
+	// TimestampParam and SignatureParam name the query parameters of hmac-sha256; they default
+	// to "timestamp" and "signature".
+	TimestampParam string `mapstructure:"timestamp_param"`
+	SignatureParam string `mapstructure:"signature_param"`
+}
+
+// LoadDescriptor reads a descriptor from a YAML or JSON file, chosen by its extension, and
+// validates it.
+func LoadDescriptor(path string) (*Descriptor, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("failed to read venue descriptor %s: %w", path, err)
+	}
+	var d Descriptor
+	if err := v.Unmarshal(&d); err != nil {
+		return nil, fmt.Errorf("failed to parse venue descriptor %s: %w", path, err)
+	}
+	d.Auth.APIKey = os.ExpandEnv(d.Auth.APIKey)
+	d.Auth.Secret = os.ExpandEnv(d.Auth.Secret)
+	if err := d.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid venue descriptor %s: %w", path, err)
+	}
+	return &d, nil
+}
+
+// Validate checks that the descriptor has what the adapter needs to fetch funding rates.
+func (d *Descriptor) Validate() error {
+	var errs []error
+	if d.Name == "" {
+		errs = append(errs, errors.New("name is required"))
+	}
+	if d.BaseURL == "" {
+		errs = append(errs, errors.New("base_url is required"))
+	}
+	if !strings.Contains(strings.ToLower(d.SymbolFormat), "{base}") {
+		errs = append(errs, errors.New("symbol_format must contain {BASE} or {base}"))
+	}
+	if d.FundingRates.Path == "" || d.FundingRates.Symbol == "" || d.FundingRates.Rate == "" {
+		errs = append(errs, errors.New("funding_rates needs path, symbol and rate"))
+	}
+	if err := validUnit(d.FundingRates.NextTimeUnit); err != nil {
+		errs = append(errs, fmt.Errorf("funding_rates: %w", err))
+	}
+	if d.MarkPrice != nil && (d.MarkPrice.Path == "" || d.MarkPrice.MarkPrice == "") {
+		errs = append(errs, errors.New("mark_price needs path and mark_price"))
+	}
+	switch d.Auth.Scheme {
+	case "", AuthNone:
+	case AuthHeader:
+		if d.Auth.KeyHeader == "" {
+			errs = append(errs, errors.New("auth scheme header needs key_header"))
+		}
+	case AuthHMACSHA256:
+		if d.Auth.Secret == "" {
+			errs = append(errs, errors.New("auth scheme hmac-sha256 needs secret"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("unknown auth scheme %q (available: none, header, hmac-sha256)", d.Auth.Scheme))
+	}
+	return errors.Join(errs...)
+}
+
+func validUnit(unit string) error {
+	switch unit {
+	case "", UnitSeconds, UnitMilliseconds, UnitNanoseconds, UnitRFC3339:
+		return nil
+	}
+	return fmt.Errorf("unknown next_time_unit %q (available: s, ms, ns, rfc3339)", unit)
+}
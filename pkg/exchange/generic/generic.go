@@ -0,0 +1,360 @@
+package generic
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/exchange"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/httpclient"
+)
+
+// ErrReadOnly is returned by the trading and account methods: descriptor venues are only
+// monitored. Trades are never opened on them, since the balance check fails before any order.
+var ErrReadOnly = errors.New("venues described by a descriptor are read-only")
+
+// maxErrorBodySize caps how much of an error response body is kept for error messages.
+const maxErrorBodySize = 4 << 10
+
+// Exchange monitors the funding rates and mark prices of a venue described by a Descriptor.
+type Exchange struct {
+	client  *http.Client
+	d       Descriptor
+	baseURL string
+	// symbolPattern matches the venue's symbols, capturing the base asset.
+	symbolPattern *regexp.Regexp
+}
+
+// New creates a client for the venue described by d.
+func New(d Descriptor, testnet bool) *Exchange {
+	pattern := regexp.QuoteMeta(d.SymbolFormat)
+	pattern = strings.NewReplacer(`\{BASE\}`, `(.+)`, `\{base\}`, `(.+)`).Replace(pattern)
+	e := &Exchange{
+		client:        &http.Client{Timeout: 10 * time.Second},
+		d:             d,
+		symbolPattern: regexp.MustCompile("(?i)^" + pattern + "$"),
+	}
+	e.SetTestnet(testnet)
+	return e
+}
+
+// SetRateLimiter makes every call to the venue wait for limiter.
+func (e *Exchange) SetRateLimiter(limiter *httpclient.Limiter) {
+	e.client = limiter.Client(e.client)
+}
+
+func (e *Exchange) Name() string {
+	return e.d.Name
+}
+
+// FundingInterval returns the period the descriptor says rates are quoted over.
+func (e *Exchange) FundingInterval() time.Duration {
+	if e.d.FundingInterval > 0 {
+		return e.d.FundingInterval
+	}
+	return exchange.DefaultFundingInterval
+}
+
+func (e *Exchange) CollateralAsset() string {
+	if e.d.Collateral != "" {
+		return e.d.Collateral
+	}
+	return "USDC"
+}
+
+// SetTestnet switches to the testnet URL, if the descriptor has one.
+func (e *Exchange) SetTestnet(testnet bool) {
+	e.baseURL = e.d.BaseURL
+	if testnet && e.d.TestnetURL != "" {
+		e.baseURL = e.d.TestnetURL
+	}
+	e.baseURL = strings.TrimRight(e.baseURL, "/")
+}
+
+// SetTransport replaces the HTTP transport used for REST requests, e.g. with a recorder in tests.
+func (e *Exchange) SetTransport(rt http.RoundTripper) {
+	e.client.Transport = rt
+}
+
+// Symbol converts "BTC-USD" into the venue's symbol following the descriptor's symbol format.
+func (e *Exchange) Symbol(market string) string {
+	base, _, _ := strings.Cut(market, "-")
+	return strings.NewReplacer("{BASE}", strings.ToUpper(base), "{base}", strings.ToLower(base)).Replace(e.d.SymbolFormat)
+}
+
+// market converts a symbol back into a market name. It reports false for symbols that don't
+// follow the symbol format.
+func (e *Exchange) market(symbol string) (string, bool) {
+	match := e.symbolPattern.FindStringSubmatch(symbol)
+	if match == nil || match[1] == "" {
+		return "", false
+	}
+	return strings.ToUpper(match[1]) + "-USD", true
+}
+
+// GetFundingRates fetches the funding rates of every market the funding rates endpoint lists.
+func (e *Exchange) GetFundingRates(ctx context.Context) ([]*exchange.FundingRate, error) {
+	endpoint := e.d.FundingRates
+	items, err := e.fetchItems(ctx, endpoint, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get funding rates from %s: %w", e.d.Name, err)
+	}
+	var fundingRates []*exchange.FundingRate
+	for _, item := range items {
+		symbol, _ := lookup(item, endpoint.Symbol)
+		market, ok := e.market(fmt.Sprint(symbol))
+		if !ok {
+			continue
+		}
+		rate, ok := decimalNumber(item, endpoint.Rate)
+		if !ok {
+			continue
+		}
+		predicted, hasPredicted := decimalNumber(item, endpoint.PredictedRate)
+		if endpoint.RateScale != 0 {
+			scale := decimal.NewFromFloat(endpoint.RateScale)
+			rate, predicted = rate.Mul(scale), predicted.Mul(scale)
+		}
+		fundingRates = append(fundingRates, &exchange.FundingRate{Market: market, Rate: rate, NextTime: nextTime(item, endpoint),
+			PredictedRate: predicted, HasPredicted: hasPredicted})
+	}
+	return fundingRates, nil
+}
+
+// GetMarkPrice returns the mark price of market, from the mark price endpoint if the descriptor
+// has one and from the funding rates endpoint otherwise.
+func (e *Exchange) GetMarkPrice(ctx context.Context, market string) (float64, error) {
+	endpoint := e.d.FundingRates
+	if e.d.MarkPrice != nil {
+		endpoint = *e.d.MarkPrice
+	}
+	if endpoint.MarkPrice == "" {
+		return 0, fmt.Errorf("the %s descriptor has no mark price field", e.d.Name)
+	}
+	items, err := e.fetchItems(ctx, endpoint, market)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get mark price from %s: %w", e.d.Name, err)
+	}
+	for _, item := range items {
+		if endpoint.Symbol != "" {
+			if symbol, _ := lookup(item, endpoint.Symbol); fmt.Sprint(symbol) != e.Symbol(market) {
+				continue
+			}
+		}
+		if price, ok := number(item, endpoint.MarkPrice); ok && price > 0 {
+			return price, nil
+		}
+	}
+	return 0, fmt.Errorf("market %s not found on %s", market, e.d.Name)
+}
+
+func (e *Exchange) GetOrderbook(ctx context.Context, market string) (*exchange.Orderbook, error) {
+	return nil, ErrReadOnly
+}
+
+func (e *Exchange) PlaceOrder(ctx context.Context, market string, side exchange.OrderSide, orderType exchange.OrderType, amount, price decimal.Decimal) (*exchange.Order, error) {
+	return nil, ErrReadOnly
+}
+
+func (e *Exchange) GetOrderStatus(ctx context.Context, orderID string, market string) (*exchange.Order, error) {
+	return nil, ErrReadOnly
+}
+
+func (e *Exchange) CancelOrder(ctx context.Context, orderID string, market string) error {
+	return ErrReadOnly
+}
+
+func (e *Exchange) GetBalance(ctx context.Context, asset string) (float64, error) {
+	return 0, ErrReadOnly
+}
+
+// ReadOnly reports that descriptor venues are never traded.
+func (e *Exchange) ReadOnly() bool {
+	return true
+}
+
+// Ping succeeds without a call, since read-only venues use no credentials.
+func (e *Exchange) Ping(ctx context.Context) error {
+	return nil
+}
+
+// GetPositions reports no positions, since none are ever opened on a read-only venue.
+func (e *Exchange) GetPositions(ctx context.Context) ([]exchange.Position, error) {
+	return nil, nil
+}
+
+func (e *Exchange) ClosePosition(ctx context.Context, market string, side exchange.OrderSide, amount decimal.Decimal) (*exchange.Order, error) {
+	return nil, ErrReadOnly
+}
+
+// fetchItems sends the endpoint's request, for market if its path or query names a symbol, and
+// returns the entries at its items path. A single object is returned as one entry.
+func (e *Exchange) fetchItems(ctx context.Context, endpoint Endpoint, market string) ([]interface{}, error) {
+	symbol := ""
+	if market != "" {
+		symbol = e.Symbol(market)
+	}
+	expand := strings.NewReplacer("{symbol}", symbol).Replace
+	params := url.Values{}
+	for key, value := range endpoint.Query {
+		params.Set(key, expand(value))
+	}
+	var response interface{}
+	if err := e.sendRequest(ctx, endpoint.Method, expand(endpoint.Path), params, expand(endpoint.Body), &response); err != nil {
+		return nil, err
+	}
+	items, ok := lookup(response, endpoint.Items)
+	if !ok {
+		return nil, fmt.Errorf("no %q in the response", endpoint.Items)
+	}
+	if list, ok := items.([]interface{}); ok {
+		return list, nil
+	}
+	return []interface{}{items}, nil
+}
+
+// sendRequest sends a request authenticated as the descriptor says and decodes the JSON response,
+// keeping numbers as json.Number so large integers such as timestamps stay exact.
+func (e *Exchange) sendRequest(ctx context.Context, method, path string, params url.Values, body string, out interface{}) error {
+	if method == "" {
+		method = http.MethodGet
+	}
+	auth := e.d.Auth
+	if auth.Scheme == AuthHMACSHA256 {
+		timestampParam, signatureParam := auth.TimestampParam, auth.SignatureParam
+		if timestampParam == "" {
+			timestampParam = "timestamp"
+		}
+		if signatureParam == "" {
+			signatureParam = "signature"
+		}
+		params.Set(timestampParam, strconv.FormatInt(time.Now().UnixMilli(), 10))
+		mac := hmac.New(sha256.New, []byte(auth.Secret))
+		mac.Write([]byte(params.Encode()))
+		params.Set(signatureParam, hex.EncodeToString(mac.Sum(nil)))
+	}
+	target := e.baseURL + path
+	if len(params) > 0 {
+		target += "?" + params.Encode()
+	}
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return err
+	}
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if auth.KeyHeader != "" && (auth.Scheme == AuthHeader || auth.Scheme == AuthHMACSHA256) {
+		req.Header.Set(auth.KeyHeader, auth.APIKey)
+	}
+
+	resp, err := httpclient.Do(e.client, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+		return &httpclient.StatusError{StatusCode: resp.StatusCode, Status: resp.Status, Body: string(message)}
+	}
+	decoder := json.NewDecoder(resp.Body)
+	decoder.UseNumber()
+	return decoder.Decode(out)
+}
+
+// lookup follows a dot-separated path through decoded JSON, indexing arrays by number. An empty
+// path returns v itself.
+func lookup(v interface{}, path string) (interface{}, bool) {
+	if path == "" {
+		return v, true
+	}
+	for _, key := range strings.Split(path, ".") {
+		switch node := v.(type) {
+		case map[string]interface{}:
+			child, ok := node[key]
+			if !ok {
+				return nil, false
+			}
+			v = child
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, false
+			}
+			v = node[i]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// number reads the field at path as a number, given either as a JSON number or a string.
+func number(v interface{}, path string) (float64, bool) {
+	d, ok := decimalNumber(v, path)
+	return d.InexactFloat64(), ok
+}
+
+// decimalNumber reads the field at path like number, without rounding it to a float.
+func decimalNumber(v interface{}, path string) (decimal.Decimal, bool) {
+	field, ok := lookup(v, path)
+	if !ok {
+		return decimal.Zero, false
+	}
+	var s string
+	switch value := field.(type) {
+	case json.Number:
+		s = value.String()
+	case string:
+		s = value
+	default:
+		return decimal.Zero, false
+	}
+	d, err := decimal.NewFromString(s)
+	return d, err == nil
+}
+
+// nextTime reads the next funding time of an entry as a Unix time in seconds, or 0 if the
+// endpoint doesn't give it.
+func nextTime(item interface{}, endpoint Endpoint) int64 {
+	if endpoint.NextTime == "" {
+		return 0
+	}
+	if endpoint.NextTimeUnit == UnitRFC3339 {
+		field, _ := lookup(item, endpoint.NextTime)
+		t, err := time.Parse(time.RFC3339, fmt.Sprint(field))
+		if err != nil {
+			return 0
+		}
+		return t.Unix()
+	}
+	value, ok := number(item, endpoint.NextTime)
+	if !ok {
+		return 0
+	}
+	switch endpoint.NextTimeUnit {
+	case UnitSeconds:
+		return int64(value)
+	case UnitNanoseconds:
+		return int64(value / 1e9)
+	default:
+		return int64(value / 1e3)
+	}
+}
@@ -0,0 +1,158 @@
+package generic
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/exchange"
+)
+
+func writeDescriptor(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadDescriptor(t *testing.T) {
+	t.Setenv("VENUE_SECRET", "s3cret")
+	path := writeDescriptor(t, "venue.yaml", `
+name: venue
+base_url: https://api.venue.test/
+symbol_format: "{BASE}_USDT"
+funding_interval: 8h
+funding_rates:
+  path: /contracts
+  items: result.list
+  symbol: name
+  rate: rate
+  next_time_unit: rfc3339
+auth:
+  scheme: hmac-sha256
+  secret: ${VENUE_SECRET}
+`)
+	d, err := LoadDescriptor(path)
+	if err != nil {
+		t.Fatalf("LoadDescriptor: %v", err)
+	}
+	if d.Name != "venue" || d.FundingInterval != 8*time.Hour || d.FundingRates.Items != "result.list" || d.Auth.Secret != "s3cret" {
+		t.Errorf("unexpected descriptor %+v", d)
+	}
+	if _, err := LoadDescriptor(filepath.Join("..", "..", "..", "example.venue.yaml")); err != nil {
+		t.Errorf("the example descriptor doesn't load: %v", err)
+	}
+
+	path = writeDescriptor(t, "venue.json", `{"name":"venue","base_url":"https://api.venue.test","symbol_format":"BTC",
+		"funding_rates":{"path":"/rates","symbol":"s"},"auth":{"scheme":"oauth"}}`)
+	_, err = LoadDescriptor(path)
+	for _, want := range []string{"symbol_format", "funding_rates needs", "unknown auth scheme"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected an error about %s, got %v", want, err)
+		}
+	}
+}
+
+func TestFundingRates(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/funding" || r.URL.Query().Get("type") != "perp" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		_, _ = w.Write([]byte(`{"data":{"list":[
+			{"instrument":{"name":"btc_usdc_perp"},"rate":"0.01","next":"2023-11-14T23:00:00Z","mark":"65000.5"},
+			{"instrument":{"name":"eth_usdc_perp"},"rate":-0.002,"next":"2023-11-14T23:00:00Z","mark":3500},
+			{"instrument":{"name":"btc_usdc_spot"},"rate":"0.5"},
+			{"instrument":{"name":"sol_usdc_perp"}}]}}`))
+	}))
+	defer server.Close()
+	ex := New(Descriptor{
+		Name:         "venue",
+		BaseURL:      server.URL,
+		SymbolFormat: "{base}_usdc_perp",
+		FundingRates: Endpoint{
+			Path: "/v1/funding", Query: map[string]string{"type": "perp"}, Items: "data.list",
+			Symbol: "instrument.name", Rate: "rate", RateScale: 0.01, NextTime: "next", NextTimeUnit: UnitRFC3339, MarkPrice: "mark",
+		},
+	}, false)
+
+	rates, err := ex.GetFundingRates(context.Background())
+	if err != nil {
+		t.Fatalf("GetFundingRates: %v", err)
+	}
+	if len(rates) != 2 {
+		t.Fatalf("expected the BTC and ETH perpetuals, got %d rates", len(rates))
+	}
+	if rates[0].Market != "BTC-USD" || !rates[0].Rate.Equal(decimal.NewFromFloat(0.0001)) || rates[0].NextTime != 1700002800 || rates[0].HasPredicted ||
+		rates[1].Market != "ETH-USD" || !rates[1].Rate.Equal(decimal.NewFromFloat(-0.00002)) {
+		t.Errorf("unexpected rates %+v %+v", rates[0], rates[1])
+	}
+	if price, err := ex.GetMarkPrice(context.Background(), "ETH-USD"); err != nil || price != 3500 {
+		t.Errorf("GetMarkPrice = %f, %v, want 3500 from the funding rates endpoint", price, err)
+	}
+	if _, err := ex.GetMarkPrice(context.Background(), "DOGE-USD"); err == nil {
+		t.Error("expected an error for a market the venue doesn't list")
+	}
+	if ex.FundingInterval() != exchange.DefaultFundingInterval || ex.CollateralAsset() != "USDC" {
+		t.Errorf("unexpected defaults %s %s", ex.FundingInterval(), ex.CollateralAsset())
+	}
+}
+
+func TestMarkPriceEndpointAndSigning(t *testing.T) {
+	var request *http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request = r
+		_, _ = w.Write([]byte(`[{"symbol":"BTCUSDT","markPrice":"65000","nextFundingTime":1700003600000}]`))
+	}))
+	defer server.Close()
+	ex := New(Descriptor{
+		Name:         "venue",
+		BaseURL:      "https://unused.test",
+		TestnetURL:   server.URL,
+		SymbolFormat: "{BASE}USDT",
+		FundingRates: Endpoint{Path: "/rates", Symbol: "symbol", Rate: "rate"},
+		MarkPrice:    &Endpoint{Path: "/premium/{symbol}", Query: map[string]string{"symbol": "{symbol}"}, Items: "0", MarkPrice: "markPrice"},
+		Auth:         Auth{Scheme: AuthHMACSHA256, APIKey: "key", Secret: "secret", KeyHeader: "X-KEY"},
+	}, true)
+
+	price, err := ex.GetMarkPrice(context.Background(), "BTC-USD")
+	if err != nil || price != 65000 {
+		t.Fatalf("GetMarkPrice = %f, %v, want 65000", price, err)
+	}
+	if request.URL.Path != "/premium/BTCUSDT" || request.URL.Query().Get("symbol") != "BTCUSDT" || request.Header.Get("X-KEY") != "key" {
+		t.Errorf("unexpected request %s %v", request.URL, request.Header)
+	}
+	query := request.URL.Query()
+	signature := query.Get("signature")
+	query.Del("signature")
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte(query.Encode()))
+	if query.Get("timestamp") == "" || signature != hex.EncodeToString(mac.Sum(nil)) {
+		t.Errorf("expected an HMAC-SHA256 signature of the query with its timestamp, got %s", request.URL.RawQuery)
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	ex := New(Descriptor{Name: "venue", BaseURL: "https://unused.test", SymbolFormat: "{BASE}"}, false)
+	if _, err := ex.GetBalance(context.Background(), "USDC"); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("GetBalance: expected ErrReadOnly, got %v", err)
+	}
+	if _, err := ex.PlaceOrder(context.Background(), "BTC-USD", exchange.Buy, exchange.Market, decimal.NewFromInt(1), decimal.NewFromInt(0)); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("PlaceOrder: expected ErrReadOnly, got %v", err)
+	}
+	if positions, err := ex.GetPositions(context.Background()); err != nil || positions != nil {
+		t.Errorf("GetPositions = %v, %v, want none", positions, err)
+	}
+}
@@ -0,0 +1,683 @@
+package exchange
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/httpclient"
+)
+
+const (
+	// GmxAPIURL is the GMX v2 API on Arbitrum, which serves the market and price data of the
+	// contracts.
+	GmxAPIURL = "https://arbitrum-api.gmxinfra.io"
+	// GmxSquidURL is the GraphQL indexer of the GMX v2 contracts on Arbitrum, which serves the
+	// positions of an account.
+	GmxSquidURL = "https://gmx.squids.live/gmx-synthetics-arbitrum:prod/api/graphql"
+)
+
+// gmxPrecision is the number of decimals of the fixed-point USD amounts, prices and rates of GMX.
+const gmxPrecision = 30
+
+const (
+	// gmxMarketSlippage bounds the acceptable price of market orders, from the oracle price.
+	gmxMarketSlippage = 0.01
+	// gmxExecutionGasLimit is the gas the execution fee of an order pays the keeper for, at the
+	// current gas price. Whatever the keeper doesn't spend is refunded to the wallet.
+	gmxExecutionGasLimit = 5_000_000
+)
+
+// The GMX order types that increase a position, as numbered by the contracts and the indexer.
+const (
+	gmxMarketIncrease = 2
+	gmxLimitIncrease  = 3
+)
+
+// hoursPerYear converts the annualized rates of GMX into hourly ones.
+var hoursPerYear = decimal.NewFromInt(365 * 24)
+
+// Gmx is the implementation for GMX v2 on Arbitrum. Rates and prices are read from the GMX API
+// and the positions and orders of the account from its indexer. Orders are Arbitrum transactions
+// encoded and signed by a GmxWallet, sent from the account and executed by GMX keepers.
+type Gmx struct {
+	client      *http.Client
+	account     string
+	baseURL     string
+	squidURL    string
+	rpcURL      string
+	wallet      GmxWallet
+	receiptPoll time.Duration
+
+	// txMu serializes transactions, which must use consecutive nonces.
+	txMu sync.Mutex
+
+	mu       sync.Mutex
+	leverage map[string]float64
+}
+
+// NewGmx creates a new GMX client for the wallet address account, if set.
+func NewGmx(account string) *Gmx {
+	return &Gmx{
+		client:      &http.Client{Timeout: 10 * time.Second},
+		account:     account,
+		baseURL:     GmxAPIURL,
+		squidURL:    GmxSquidURL,
+		rpcURL:      GmxRPCURL,
+		receiptPoll: gmxReceiptPoll,
+		leverage:    make(map[string]float64),
+	}
+}
+
+// SetWallet sets the wallet that orders are encoded and signed with.
+func (g *Gmx) SetWallet(wallet GmxWallet) {
+	g.wallet = wallet
+}
+
+// SetRPCURL replaces the Arbitrum RPC endpoint transactions are sent to.
+func (g *Gmx) SetRPCURL(rpcURL string) {
+	g.rpcURL = rpcURL
+}
+
+// SetLeverage sets the leverage of the positions opened on market: orders send their size over the
+// leverage as collateral. GMX has no account setting for it, so it takes effect on the next order.
+func (g *Gmx) SetLeverage(ctx context.Context, market string, leverage float64) error {
+	if leverage <= 0 {
+		return fmt.Errorf("invalid GMX leverage %v for %s", leverage, market)
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.leverage[market] = leverage
+	return nil
+}
+
+// leverageOf returns the leverage set for market, 1 by default.
+func (g *Gmx) leverageOf(market string) float64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if leverage, ok := g.leverage[market]; ok {
+		return leverage
+	}
+	return 1
+}
+
+// SetRateLimiter makes every GMX API, indexer and RPC call wait for limiter.
+func (g *Gmx) SetRateLimiter(limiter *httpclient.Limiter) {
+	g.client = limiter.Client(g.client)
+}
+
+func (g *Gmx) Name() string {
+	return "GMX"
+}
+
+// FundingInterval returns the period of the rates reported for GMX. Its fees accrue every second,
+// so they are reported per hour.
+func (g *Gmx) FundingInterval() time.Duration {
+	return time.Hour
+}
+
+func (g *Gmx) CollateralAsset() string {
+	return "USDC"
+}
+
+// SetTestnet does nothing: GMX is only read and traded on Arbitrum mainnet.
+func (g *Gmx) SetTestnet(testnet bool) {}
+
+// GmxMarketInfo is a market as listed by the API. USD amounts and rates have 30 decimals, and
+// rates are annualized, positive when the side receives.
+type GmxMarketInfo struct {
+	// Name is e.g. "ETH/USD [WETH-USDC]": the index market and the tokens of its pool.
+	Name              string          `json:"name"`
+	MarketToken       string          `json:"marketToken"`
+	IndexToken        string          `json:"indexToken"`
+	ShortToken        string          `json:"shortToken"`
+	IsListed          bool            `json:"isListed"`
+	OpenInterestLong  decimal.Decimal `json:"openInterestLong"`
+	OpenInterestShort decimal.Decimal `json:"openInterestShort"`
+	// NetRateLong and NetRateShort are the funding of each side less its borrowing fee.
+	NetRateLong  decimal.Decimal `json:"netRateLong"`
+	NetRateShort decimal.Decimal `json:"netRateShort"`
+}
+
+// GmxMarketsInfoResponse is the response structure for the markets info endpoint.
+type GmxMarketsInfoResponse struct {
+	Markets []GmxMarketInfo `json:"markets"`
+}
+
+// gmxMarket returns the market symbol of a GMX market name, e.g. ETH-USD for
+// "ETH/USD [WETH-USDC]". Swap-only markets have none.
+func gmxMarket(name string) (string, bool) {
+	i := strings.Index(name, "/USD")
+	if i <= 0 {
+		return "", false
+	}
+	return name[:i] + "-USD", true
+}
+
+// getMarketsInfo fetches every market of the API.
+func (g *Gmx) getMarketsInfo(ctx context.Context) ([]GmxMarketInfo, error) {
+	var response GmxMarketsInfoResponse
+	if err := g.sendRequest(ctx, "/markets/info", &response); err != nil {
+		return nil, fmt.Errorf("failed to get markets from GMX: %w", err)
+	}
+	return response.Markets, nil
+}
+
+// markets returns the listed perpetual markets by symbol. An index traded in several pools is
+// represented by the pool collateralized in USDC, which orders are sent with, with the most open
+// interest.
+func (g *Gmx) markets(ctx context.Context) (map[string]GmxMarketInfo, error) {
+	all, err := g.getMarketsInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	markets := make(map[string]GmxMarketInfo)
+	for _, m := range all {
+		symbol, ok := gmxMarket(m.Name)
+		if !ok || !m.IsListed {
+			continue
+		}
+		if best, ok := markets[symbol]; ok {
+			if m.usdc() != best.usdc() {
+				if best.usdc() {
+					continue
+				}
+			} else if !m.openInterest().GreaterThan(best.openInterest()) {
+				continue
+			}
+		}
+		markets[symbol] = m
+	}
+	return markets, nil
+}
+
+// usdc reports whether the short token of the pool is USDC.
+func (m GmxMarketInfo) usdc() bool {
+	return strings.EqualFold(m.ShortToken, GmxUSDC)
+}
+
+// openInterest is the open interest of both sides, with 30 decimals.
+func (m GmxMarketInfo) openInterest() decimal.Decimal {
+	return m.OpenInterestLong.Add(m.OpenInterestShort)
+}
+
+// GetFundingRates returns the hourly rate a long pays on every market: its funding and its
+// borrowing fee. Unlike on other venues the sides' rates don't mirror each other, since both sides
+// pay borrowing fees, so the rate is an approximation for shorts. Fees accrue every second; the
+// next funding time is the next hour.
+func (g *Gmx) GetFundingRates(ctx context.Context) ([]*FundingRate, error) {
+	markets, err := g.markets(ctx)
+	if err != nil {
+		return nil, err
+	}
+	next := time.Now().Truncate(time.Hour).Add(time.Hour).Unix()
+	fundingRates := make([]*FundingRate, 0, len(markets))
+	for symbol, m := range markets {
+		rate := m.NetRateLong.Neg().Shift(-gmxPrecision).Div(hoursPerYear)
+		fundingRates = append(fundingRates, &FundingRate{Market: symbol, Rate: rate, NextTime: next})
+	}
+	return fundingRates, nil
+}
+
+// GmxToken is a token as listed by the API.
+type GmxToken struct {
+	Symbol   string `json:"symbol"`
+	Address  string `json:"address"`
+	Decimals int32  `json:"decimals"`
+}
+
+// GmxTokensResponse is the response structure for the tokens endpoint.
+type GmxTokensResponse struct {
+	Tokens []GmxToken `json:"tokens"`
+}
+
+// tokenDecimals returns the decimals of every token by lower-cased address.
+func (g *Gmx) tokenDecimals(ctx context.Context) (map[string]int32, error) {
+	var response GmxTokensResponse
+	if err := g.sendRequest(ctx, "/tokens", &response); err != nil {
+		return nil, fmt.Errorf("failed to get tokens from GMX: %w", err)
+	}
+	decimals := make(map[string]int32, len(response.Tokens))
+	for _, token := range response.Tokens {
+		decimals[strings.ToLower(token.Address)] = token.Decimals
+	}
+	return decimals, nil
+}
+
+// GmxTicker is the oracle price range of a token. Prices are per smallest unit of the token, with
+// 30 decimals.
+type GmxTicker struct {
+	TokenAddress string          `json:"tokenAddress"`
+	MinPrice     decimal.Decimal `json:"minPrice"`
+	MaxPrice     decimal.Decimal `json:"maxPrice"`
+}
+
+// GetMarkPrice returns the middle of the oracle price range of the index token of market.
+func (g *Gmx) GetMarkPrice(ctx context.Context, market string) (float64, error) {
+	m, err := g.market(ctx, market)
+	if err != nil {
+		return 0, err
+	}
+	decimals, err := g.tokenDecimals(ctx)
+	if err != nil {
+		return 0, err
+	}
+	price, err := g.indexPrice(ctx, market, m, decimals)
+	if err != nil {
+		return 0, err
+	}
+	return price.InexactFloat64(), nil
+}
+
+// market returns the pool market is traded in.
+func (g *Gmx) market(ctx context.Context, market string) (GmxMarketInfo, error) {
+	markets, err := g.markets(ctx)
+	if err != nil {
+		return GmxMarketInfo{}, err
+	}
+	m, ok := markets[market]
+	if !ok {
+		return GmxMarketInfo{}, fmt.Errorf("market %s not found on GMX", market)
+	}
+	return m, nil
+}
+
+// indexPrice returns the middle of the oracle price range of the index token of m, per whole
+// token.
+func (g *Gmx) indexPrice(ctx context.Context, market string, m GmxMarketInfo, decimals map[string]int32) (decimal.Decimal, error) {
+	var tickers []GmxTicker
+	if err := g.sendRequest(ctx, "/prices/tickers", &tickers); err != nil {
+		return decimal.Zero, fmt.Errorf("failed to get prices from GMX: %w", err)
+	}
+	for _, ticker := range tickers {
+		if !strings.EqualFold(ticker.TokenAddress, m.IndexToken) {
+			continue
+		}
+		mid := ticker.MinPrice.Add(ticker.MaxPrice).Div(decimal.NewFromInt(2))
+		return mid.Shift(decimals[strings.ToLower(m.IndexToken)] - gmxPrecision), nil
+	}
+	return decimal.Zero, fmt.Errorf("no price for %s on GMX", market)
+}
+
+// GetOrderbook reports that GMX has no order book: it trades against its liquidity pools.
+func (g *Gmx) GetOrderbook(ctx context.Context, market string) (*Orderbook, error) {
+	return nil, fmt.Errorf("GMX trades against pools: %w", ErrOrderbookUnsupported)
+}
+
+// PlaceOrder opens or adds to a position on the pool of market, sending its size over the leverage
+// set for market as USDC collateral. GMX orders execute at the oracle price once a keeper picks
+// them up: market orders accept a price at worst 1% from the oracle price and limit orders their
+// price. The order ID is the order key, once the transaction creating it is mined.
+func (g *Gmx) PlaceOrder(ctx context.Context, market string, side OrderSide, orderType OrderType, amount, price decimal.Decimal) (*Order, error) {
+	if g.ReadOnly() {
+		return nil, ErrGmxWalletRequired
+	}
+	m, err := g.market(ctx, market)
+	if err != nil {
+		return nil, err
+	}
+	if !m.usdc() {
+		return nil, fmt.Errorf("GMX market %s has no USDC pool", market)
+	}
+	request, acceptable, err := g.orderRequest(ctx, market, m, side, side == Buy, orderType, amount, price)
+	if err != nil {
+		return nil, err
+	}
+	request.OrderType = "MarketIncrease"
+	size, _ := decimal.NewFromString(request.SizeDeltaUSD)
+	collateral := size.Shift(6 - gmxPrecision).Div(decimal.NewFromFloat(g.leverageOf(market))).Ceil()
+	request.CollateralToken = GmxUSDC
+	request.CollateralDelta = collateral.String()
+	if err := g.approve(ctx, GmxUSDC, collateral.BigInt()); err != nil {
+		return nil, fmt.Errorf("failed to place order on GMX: %w", err)
+	}
+	return g.createOrder(ctx, market, side, orderType, amount, acceptable, request)
+}
+
+// ClosePosition reduces the side position of market by amount, or closes it when amount covers it,
+// withdrawing a proportional share of its collateral.
+func (g *Gmx) ClosePosition(ctx context.Context, market string, side OrderSide, amount decimal.Decimal) (*Order, error) {
+	if g.ReadOnly() {
+		return nil, ErrGmxWalletRequired
+	}
+	m, err := g.market(ctx, market)
+	if err != nil {
+		return nil, err
+	}
+	positions, err := g.positions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to close position on GMX: %w", err)
+	}
+	var position *gmxPosition
+	for i, p := range positions {
+		if strings.EqualFold(p.Market, m.MarketToken) && p.IsLong == (side == Buy) && p.SizeInTokens.IsPositive() {
+			position = &positions[i]
+		}
+	}
+	if position == nil {
+		return nil, fmt.Errorf("no %s %s position to close on GMX", side, market)
+	}
+	closeSide := Sell
+	if side == Sell {
+		closeSide = Buy
+	}
+	request, acceptable, err := g.orderRequest(ctx, market, m, closeSide, side == Buy, Market, amount, decimal.Zero)
+	if err != nil {
+		return nil, err
+	}
+	request.OrderType = "MarketDecrease"
+	request.CollateralToken = position.CollateralToken
+	size, collateral := position.SizeInUSD, position.CollateralAmount
+	decimals, err := g.tokenDecimals(ctx)
+	if err != nil {
+		return nil, err
+	}
+	held := position.SizeInTokens.Shift(-decimals[strings.ToLower(m.IndexToken)])
+	if amount.LessThan(held) {
+		share := amount.Div(held)
+		size = size.Mul(share).Floor()
+		collateral = collateral.Mul(share).Floor()
+	}
+	request.SizeDeltaUSD = size.String()
+	request.CollateralDelta = collateral.String()
+	return g.createOrder(ctx, market, closeSide, Market, amount, acceptable, request)
+}
+
+// orderRequest prices an order of amount on m, the side of the trade being side and that of the
+// position isLong: its size in USD, acceptable price and execution fee. The acceptable price is
+// also returned per whole token.
+func (g *Gmx) orderRequest(ctx context.Context, market string, m GmxMarketInfo, side OrderSide, isLong bool, orderType OrderType, amount, price decimal.Decimal) (GmxOrderRequest, decimal.Decimal, error) {
+	if !amount.IsPositive() {
+		return GmxOrderRequest{}, decimal.Zero, fmt.Errorf("invalid GMX order amount %s", amount)
+	}
+	decimals, err := g.tokenDecimals(ctx)
+	if err != nil {
+		return GmxOrderRequest{}, decimal.Zero, err
+	}
+	oracle, err := g.indexPrice(ctx, market, m, decimals)
+	if err != nil {
+		return GmxOrderRequest{}, decimal.Zero, err
+	}
+	acceptable := price
+	if orderType == Market {
+		acceptable = oracle.Mul(decimal.NewFromFloat(1 + gmxMarketSlippage))
+		if side == Sell {
+			acceptable = oracle.Mul(decimal.NewFromFloat(1 - gmxMarketSlippage))
+		}
+	}
+	gasPrice, err := g.rpcQuantity(ctx, "eth_gasPrice")
+	if err != nil {
+		return GmxOrderRequest{}, decimal.Zero, fmt.Errorf("failed to get the gas price: %w", err)
+	}
+	indexDecimals := decimals[strings.ToLower(m.IndexToken)]
+	return GmxOrderRequest{
+		Account:         g.account,
+		Market:          m.MarketToken,
+		IsLong:          isLong,
+		SizeDeltaUSD:    amount.Mul(oracle).Shift(gmxPrecision).Floor().String(),
+		AcceptablePrice: acceptable.Shift(gmxPrecision - indexDecimals).Floor().String(),
+		ExecutionFee:    new(big.Int).Mul(gasPrice, big.NewInt(gmxExecutionGasLimit)).String(),
+	}, acceptable, nil
+}
+
+// createOrder sends the transaction creating request and reads the order key from its receipt. The
+// order is reported at price, the acceptable price.
+func (g *Gmx) createOrder(ctx context.Context, market string, side OrderSide, orderType OrderType, amount, price decimal.Decimal, request GmxOrderRequest) (*Order, error) {
+	call, err := g.wallet.EncodeOrder(ctx, request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to place order on GMX: %w", err)
+	}
+	receipt, err := g.sendTx(ctx, call)
+	if err != nil {
+		return nil, fmt.Errorf("failed to place order on GMX: %w", err)
+	}
+	key, ok := receipt.orderKey()
+	if !ok {
+		return nil, fmt.Errorf("failed to place order on GMX: transaction %s created no order", receipt.TransactionHash)
+	}
+	return &Order{
+		ID:        key,
+		Market:    market,
+		Side:      side,
+		Type:      orderType,
+		Price:     price,
+		Amount:    amount,
+		Status:    "NEW",
+		Timestamp: time.Now().Unix(),
+	}, nil
+}
+
+// gmxTradeActionsQuery selects what happened to an order: its creation, and its execution or
+// cancellation by a keeper.
+const gmxTradeActionsQuery = `query($key: String!) {
+  tradeActions(where: {orderKey_eq: $key}) { eventName orderType isLong sizeDeltaUsd executionPrice }
+}`
+
+// gmxTradeAction is an event of an order as indexed. The execution price is per smallest unit of
+// the index token, with 30 decimals.
+type gmxTradeAction struct {
+	EventName      string          `json:"eventName"`
+	OrderType      int             `json:"orderType"`
+	IsLong         bool            `json:"isLong"`
+	SizeDeltaUSD   decimal.Decimal `json:"sizeDeltaUsd"`
+	ExecutionPrice decimal.Decimal `json:"executionPrice"`
+}
+
+// GetOrderStatus returns the order with the key orderID as indexed: NEW until a keeper executes it,
+// then FILLED at the execution price, or CANCELED when the keeper cancels it, e.g. because the
+// price moved past the acceptable price.
+func (g *Gmx) GetOrderStatus(ctx context.Context, orderID string, market string) (*Order, error) {
+	var data struct {
+		TradeActions []gmxTradeAction `json:"tradeActions"`
+	}
+	if err := g.query(ctx, gmxTradeActionsQuery, map[string]string{"key": orderID}, &data); err != nil {
+		return nil, fmt.Errorf("failed to get order status from GMX: %w", err)
+	}
+	order := &Order{ID: orderID, Market: market, Status: "NEW"}
+	for _, action := range data.TradeActions {
+		increase := action.OrderType == gmxMarketIncrease || action.OrderType == gmxLimitIncrease
+		order.Side = Sell
+		if increase == action.IsLong {
+			order.Side = Buy
+		}
+		switch action.EventName {
+		case "OrderExecuted":
+			m, err := g.market(ctx, market)
+			if err != nil {
+				return nil, err
+			}
+			decimals, err := g.tokenDecimals(ctx)
+			if err != nil {
+				return nil, err
+			}
+			if action.ExecutionPrice.IsPositive() {
+				indexDecimals := decimals[strings.ToLower(m.IndexToken)]
+				order.Filled = action.SizeDeltaUSD.Div(action.ExecutionPrice).Shift(-indexDecimals)
+				order.Amount = order.Filled
+				order.Price = action.ExecutionPrice.Shift(indexDecimals - gmxPrecision)
+			}
+			order.Status = "FILLED"
+			return order, nil
+		case "OrderCancelled":
+			order.Status = "CANCELED"
+			return order, nil
+		}
+	}
+	return order, nil
+}
+
+// CancelOrder cancels the order with the key orderID. GMX only lets the account cancel market
+// orders a few minutes after creating them, if no keeper has executed them.
+func (g *Gmx) CancelOrder(ctx context.Context, orderID string, market string) error {
+	if g.ReadOnly() {
+		return ErrGmxWalletRequired
+	}
+	call, err := g.wallet.EncodeCancel(ctx, orderID)
+	if err != nil {
+		return fmt.Errorf("failed to cancel order on GMX: %w", err)
+	}
+	if _, err := g.sendTx(ctx, call); err != nil {
+		return fmt.Errorf("failed to cancel order on GMX: %w", err)
+	}
+	return nil
+}
+
+// GetBalance returns the USDC held by the wallet, which orders send as collateral. Other assets
+// have no balance.
+func (g *Gmx) GetBalance(ctx context.Context, asset string) (float64, error) {
+	if g.account == "" {
+		return 0, errors.New("GMX balance needs GMX_ACCOUNT")
+	}
+	if !strings.EqualFold(asset, "USDC") {
+		return 0, nil
+	}
+	balance, err := g.callQuantity(ctx, GmxUSDC, erc20BalanceOf+abiAddress(g.account))
+	if err != nil {
+		return 0, fmt.Errorf("failed to get balance from GMX: %w", err)
+	}
+	return decimal.NewFromBigInt(balance, -6).InexactFloat64(), nil
+}
+
+// ReadOnly reports that GMX can't be traded without a wallet sending its transactions, so the
+// strategy never opens a leg on it.
+func (g *Gmx) ReadOnly() bool {
+	return g.wallet == nil || g.account == ""
+}
+
+// Ping reads the positions of the account, which checks the address, or succeeds without a call
+// when none is set.
+func (g *Gmx) Ping(ctx context.Context) error {
+	if g.account == "" {
+		return nil
+	}
+	_, err := g.GetPositions(ctx)
+	return err
+}
+
+// gmxPositionsQuery selects the open positions of an account. Closed positions remain indexed
+// with a size of zero, and snapshots of them are kept for the leaderboard.
+const gmxPositionsQuery = `query($account: String!) {
+  positions(where: {account_eq: $account, isSnapshot_eq: false}) { market isLong sizeInUsd sizeInTokens collateralToken collateralAmount }
+}`
+
+// gmxPosition is a position as indexed. Sizes in USD have 30 decimals, sizes in tokens those of
+// the index token and the collateral amount those of the collateral token.
+type gmxPosition struct {
+	Market           string          `json:"market"`
+	IsLong           bool            `json:"isLong"`
+	SizeInUSD        decimal.Decimal `json:"sizeInUsd"`
+	SizeInTokens     decimal.Decimal `json:"sizeInTokens"`
+	CollateralToken  string          `json:"collateralToken"`
+	CollateralAmount decimal.Decimal `json:"collateralAmount"`
+}
+
+// positions fetches the positions of the account from the indexer.
+func (g *Gmx) positions(ctx context.Context) ([]gmxPosition, error) {
+	var data struct {
+		Positions []gmxPosition `json:"positions"`
+	}
+	if err := g.query(ctx, gmxPositionsQuery, map[string]string{"account": g.account}, &data); err != nil {
+		return nil, err
+	}
+	return data.Positions, nil
+}
+
+// query runs a GraphQL query on the indexer and decodes its data into out.
+func (g *Gmx) query(ctx context.Context, query string, variables map[string]string, out interface{}) error {
+	body, err := json.Marshal(map[string]interface{}{"query": query, "variables": variables})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", g.squidURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	var response struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := doJSON(g.client, req, &response); err != nil {
+		return err
+	}
+	if len(response.Errors) > 0 {
+		return errors.New(response.Errors[0].Message)
+	}
+	return json.Unmarshal(response.Data, out)
+}
+
+// GetPositions fetches the open positions of the account from the indexer, with their entry price
+// as their size in USD over their size in the index token. Without an account there are none.
+func (g *Gmx) GetPositions(ctx context.Context) ([]Position, error) {
+	if g.account == "" {
+		return nil, nil
+	}
+	indexed, err := g.positions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get positions from GMX: %w", err)
+	}
+	if len(indexed) == 0 {
+		return nil, nil
+	}
+
+	all, err := g.getMarketsInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byAddress := make(map[string]GmxMarketInfo, len(all))
+	for _, m := range all {
+		byAddress[strings.ToLower(m.MarketToken)] = m
+	}
+	decimals, err := g.tokenDecimals(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var positions []Position
+	for _, p := range indexed {
+		if !p.SizeInTokens.IsPositive() {
+			continue
+		}
+		m, ok := byAddress[strings.ToLower(p.Market)]
+		symbol, isPerp := gmxMarket(m.Name)
+		if !ok || !isPerp {
+			return nil, fmt.Errorf("position in unknown GMX market %s", p.Market)
+		}
+		size := p.SizeInTokens.Shift(-decimals[strings.ToLower(m.IndexToken)])
+		side := Buy
+		if !p.IsLong {
+			side = Sell
+		}
+		positions = append(positions, Position{Market: symbol, Side: side, Size: size.InexactFloat64(),
+			EntryPrice: p.SizeInUSD.Shift(-gmxPrecision).Div(size).InexactFloat64()})
+	}
+	return positions, nil
+}
+
+// SetTransport replaces the HTTP transport used for API, indexer and RPC requests, e.g. with a
+// recorder in tests.
+func (g *Gmx) SetTransport(rt http.RoundTripper) {
+	g.client.Transport = rt
+}
+
+// sendRequest makes an unauthenticated GET request to the API, which serves public data only.
+func (g *Gmx) sendRequest(ctx context.Context, endpoint string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", g.baseURL+endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "FundingRateArbBot/1.0")
+	return doJSON(g.client, req, out)
+}
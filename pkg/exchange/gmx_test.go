@@ -0,0 +1,395 @@
+package exchange
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+// gmxMarketsInfo lists two BTC pools, the first with the most open interest, an unlisted market
+// and a swap-only one. Longs on the first BTC pool pay 8.76% a year.
+const gmxMarketsInfo = `{"markets":[
+	{"name":"BTC/USD [BTC-USDC]","marketToken":"0xBtcUsdc","indexToken":"0xBtc","shortToken":"0xaf88d065e77c8cC2239327C5EDb3A432268e5831","isListed":true,
+		"openInterestLong":"6000000000000000000000000000000000000","openInterestShort":"4000000000000000000000000000000000000",
+		"netRateLong":"-87600000000000000000000000000","netRateShort":"-10000000000000000000000000000"},
+	{"name":"BTC/USD [BTC-BTC]","marketToken":"0xBtcBtc","indexToken":"0xBtc","isListed":true,
+		"openInterestLong":"1000000000000000000000000000000000000","openInterestShort":"1000000000000000000000000000000000000",
+		"netRateLong":"50000000000000000000000000000","netRateShort":"-60000000000000000000000000000"},
+	{"name":"OLD/USD [OLD-USDC]","marketToken":"0xOld","indexToken":"0xOldToken","isListed":false,
+		"openInterestLong":"0","openInterestShort":"0","netRateLong":"0","netRateShort":"0"},
+	{"name":"SWAP-ONLY [USDC-USDT]","marketToken":"0xSwap","isListed":true,
+		"openInterestLong":"0","openInterestShort":"0","netRateLong":"0","netRateShort":"0"}]}`
+
+func TestGmxFundingRates(t *testing.T) {
+	api := newFakeAPI(t)
+	api.respond("GET", "/markets/info", http.StatusOK, gmxMarketsInfo)
+	ex := newTestGmx(api)
+
+	rates, err := ex.GetFundingRates(context.Background())
+	if err != nil {
+		t.Fatalf("GetFundingRates: %v", err)
+	}
+	if len(rates) != 1 || rates[0].Market != "BTC-USD" || !rates[0].Rate.Equal(decimal.NewFromFloat(0.00001)) || rates[0].NextTime == 0 {
+		t.Errorf("expected the hourly rate longs pay on the deepest BTC pool only, got %+v", rates)
+	}
+}
+
+func TestGmxMarkPrice(t *testing.T) {
+	api := newFakeAPI(t)
+	api.respond("GET", "/markets/info", http.StatusOK, gmxMarketsInfo)
+	api.respond("GET", "/tokens", http.StatusOK, `{"tokens":[{"symbol":"BTC","address":"0xBTC","decimals":8}]}`)
+	api.respond("GET", "/prices/tickers", http.StatusOK, `[
+		{"tokenAddress":"0xBTC","minPrice":"649990000000000000000000000","maxPrice":"650010000000000000000000000"}]`)
+	ex := newTestGmx(api)
+
+	price, err := ex.GetMarkPrice(context.Background(), "BTC-USD")
+	if err != nil {
+		t.Fatalf("GetMarkPrice: %v", err)
+	}
+	if price != 65000 {
+		t.Errorf("expected the middle of the oracle range per whole BTC, got %f", price)
+	}
+	if _, err := ex.GetMarkPrice(context.Background(), "OLD-USD"); err == nil {
+		t.Error("expected an error for an unlisted market")
+	}
+}
+
+func TestGmxPositions(t *testing.T) {
+	api := newFakeAPI(t)
+	api.respond("GET", "/markets/info", http.StatusOK, gmxMarketsInfo)
+	api.respond("GET", "/tokens", http.StatusOK, `{"tokens":[{"symbol":"BTC","address":"0xBtc","decimals":8}]}`)
+	var query string
+	api.handle("POST", "/graphql", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		query = string(body)
+		w.Write([]byte(`{"data":{"positions":[
+			{"market":"0xbtcbtc","isLong":false,"sizeInUsd":"6500000000000000000000000000000000","sizeInTokens":"10000000"},
+			{"market":"0xBtcUsdc","isLong":true,"sizeInUsd":"0","sizeInTokens":"0"}]}}`))
+	})
+	ex := newTestGmx(api)
+
+	positions, err := ex.GetPositions(context.Background())
+	if err != nil {
+		t.Fatalf("GetPositions: %v", err)
+	}
+	if !strings.Contains(query, `"account":"0xAccount"`) {
+		t.Errorf("expected the positions of the account to be queried, got %s", query)
+	}
+	if len(positions) != 1 || positions[0] != (Position{Market: "BTC-USD", Side: Sell, Size: 0.1, EntryPrice: 65000}) {
+		t.Errorf("expected the open BTC short only, got %+v", positions)
+	}
+
+	ex.account = ""
+	if positions, err := ex.GetPositions(context.Background()); err != nil || positions != nil {
+		t.Errorf("expected no positions without an account, got %+v, %v", positions, err)
+	}
+}
+
+func TestGmxIsReadOnlyWithoutAWallet(t *testing.T) {
+	ex := newTestGmx(newFakeAPI(t))
+	if _, err := ex.PlaceOrder(context.Background(), "BTC-USD", Buy, Market, decimal.NewFromFloat(0.01), decimal.Zero); !errors.Is(err, ErrGmxWalletRequired) {
+		t.Errorf("expected orders to be refused, got %v", err)
+	}
+	if _, err := ex.GetOrderbook(context.Background(), "BTC-USD"); !errors.Is(err, ErrOrderbookUnsupported) {
+		t.Errorf("expected no order book, got %v", err)
+	}
+	if !IsReadOnly(ex) {
+		t.Error("expected GMX to be reported read-only so it is not traded")
+	}
+	ex.SetWallet(&recordingWallet{})
+	if IsReadOnly(ex) {
+		t.Error("expected GMX to be tradable with a wallet")
+	}
+}
+
+// recordingWallet encodes fixed router calls sending the execution fee and signs transactions as
+// their nonce, prefixed with aa for approvals, recording both.
+type recordingWallet struct {
+	orders []GmxOrderRequest
+	txs    []GmxTx
+}
+
+func (w *recordingWallet) EncodeOrder(ctx context.Context, order GmxOrderRequest) (GmxCall, error) {
+	w.orders = append(w.orders, order)
+	fee, _ := new(big.Int).SetString(order.ExecutionFee, 10)
+	return GmxCall{To: "0xExchangeRouter", Data: "0xorder", Value: hexQuantity(fee)}, nil
+}
+
+func (w *recordingWallet) EncodeCancel(ctx context.Context, key string) (GmxCall, error) {
+	return GmxCall{To: "0xExchangeRouter", Data: "0xcancel" + strings.TrimPrefix(key, "0x"), Value: "0x0"}, nil
+}
+
+func (w *recordingWallet) SignTx(ctx context.Context, tx GmxTx) (string, error) {
+	w.txs = append(w.txs, tx)
+	if strings.HasPrefix(tx.Data, erc20Approve) {
+		return fmt.Sprintf("0xaa%02x", tx.Nonce), nil
+	}
+	return fmt.Sprintf("0x%02x", tx.Nonce), nil
+}
+
+// fakeChain answers the JSON-RPC calls of an Arbitrum node. Transactions are mined on the second
+// receipt poll, those other than approvals creating the order 0xkey.
+type fakeChain struct {
+	mu        sync.Mutex
+	nonce     int
+	allowance string
+	status    string
+	calls     []string
+	sent      []string
+	polls     map[string]int
+}
+
+func newFakeChain(api *fakeAPI) *fakeChain {
+	chain := &fakeChain{nonce: 7, allowance: "0x0", status: "0x1", polls: make(map[string]int)}
+	api.handle("POST", "/rpc", func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		chain.mu.Lock()
+		defer chain.mu.Unlock()
+		var result interface{}
+		switch request.Method {
+		case "eth_gasPrice":
+			result = "0x5f5e100"
+		case "eth_getTransactionCount":
+			result = fmt.Sprintf("0x%x", chain.nonce)
+		case "eth_estimateGas":
+			result = "0x186a0"
+		case "eth_call":
+			var call GmxCall
+			_ = json.Unmarshal(request.Params[0], &call)
+			chain.calls = append(chain.calls, call.Data)
+			switch {
+			case strings.HasPrefix(call.Data, erc20Allowance):
+				result = chain.allowance
+			case strings.HasPrefix(call.Data, erc20BalanceOf):
+				result = "0x000000000000000000000000000000000000000000000000000000003b9aca00"
+			}
+		case "eth_sendRawTransaction":
+			var raw string
+			_ = json.Unmarshal(request.Params[0], &raw)
+			chain.sent = append(chain.sent, raw)
+			chain.nonce++
+			result = "0xhash" + strings.TrimPrefix(raw, "0x")
+		case "eth_getTransactionReceipt":
+			var hash string
+			_ = json.Unmarshal(request.Params[0], &hash)
+			if chain.polls[hash]++; chain.polls[hash] == 1 {
+				break
+			}
+			receipt := map[string]interface{}{"transactionHash": hash, "status": chain.status, "logs": []interface{}{}}
+			if !strings.HasPrefix(hash, "0xhashaa") {
+				receipt["logs"] = []interface{}{map[string]interface{}{"topics": []string{
+					"0x468a25a7ba624ceea6e540ad6f49171b52495b648417ae91bca21676d8a24dc5", gmxOrderCreatedTopic, "0xkey", abiAddress("0xAccount")}}}
+			}
+			result = receipt
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": 1, "result": result})
+	})
+	return chain
+}
+
+// respondGmxMarket serves the BTC markets, token and oracle price of 65,000.
+func respondGmxMarket(api *fakeAPI) {
+	api.respond("GET", "/markets/info", http.StatusOK, gmxMarketsInfo)
+	api.respond("GET", "/tokens", http.StatusOK, `{"tokens":[{"symbol":"BTC","address":"0xBtc","decimals":8}]}`)
+	api.respond("GET", "/prices/tickers", http.StatusOK, `[
+		{"tokenAddress":"0xBtc","minPrice":"649990000000000000000000000","maxPrice":"650010000000000000000000000"}]`)
+}
+
+func TestGmxPlaceOrder(t *testing.T) {
+	api := newFakeAPI(t)
+	respondGmxMarket(api)
+	chain := newFakeChain(api)
+	ex := newTestGmx(api)
+	wallet := &recordingWallet{}
+	ex.SetWallet(wallet)
+	if err := ex.SetLeverage(context.Background(), "BTC-USD", 2); err != nil {
+		t.Fatal(err)
+	}
+
+	order, err := ex.PlaceOrder(context.Background(), "BTC-USD", Buy, Market, decimal.NewFromFloat(0.01), decimal.Zero)
+	if err != nil {
+		t.Fatalf("PlaceOrder: %v", err)
+	}
+	if order.ID != "0xkey" || order.Status != "NEW" || !order.Price.Equal(decimal.NewFromInt(65650)) {
+		t.Errorf("expected the order key at the acceptable price, got %+v", order)
+	}
+	want := GmxOrderRequest{
+		OrderType:       "MarketIncrease",
+		Account:         "0xAccount",
+		Market:          "0xBtcUsdc",
+		CollateralToken: GmxUSDC,
+		IsLong:          true,
+		SizeDeltaUSD:    "650" + strings.Repeat("0", 30),
+		CollateralDelta: "325000000",
+		AcceptablePrice: "65650" + strings.Repeat("0", 22),
+		ExecutionFee:    "500000000000000",
+	}
+	if len(wallet.orders) != 1 || wallet.orders[0] != want {
+		t.Errorf("expected a 650 USD long with 325 USDC at 2x, 1%% slippage and a 5M gas execution fee, got %+v", wallet.orders)
+	}
+
+	// The router is approved first, then the order is sent with the next nonce.
+	if len(wallet.txs) != 2 {
+		t.Fatalf("expected an approval and an order, got %+v", wallet.txs)
+	}
+	approval, create := wallet.txs[0], wallet.txs[1]
+	if approval.To != GmxUSDC || approval.Data != erc20Approve+abiAddress(GmxRouter)+strings.Repeat("f", 64) || approval.Nonce != 7 {
+		t.Errorf("expected an unlimited USDC approval of the router, got %+v", approval)
+	}
+	if create.To != "0xExchangeRouter" || create.Nonce != 8 || create.ChainID != GmxChainID {
+		t.Errorf("expected the router call with the next nonce, got %+v", create)
+	}
+	if create.Gas != 120000 || create.MaxFeePerGas != "0xbebc200" || create.MaxPriorityFeePerGas != "0x0" {
+		t.Errorf("expected the gas estimate plus 20%% and twice the gas price, got %+v", create)
+	}
+	chain.mu.Lock()
+	if len(chain.sent) != 2 || chain.polls["0xhash08"] != 2 {
+		t.Errorf("expected both transactions sent and the order polled until mined, got %v, %v", chain.sent, chain.polls)
+	}
+	chain.allowance = "0x" + strings.Repeat("f", 64)
+	chain.mu.Unlock()
+
+	// An approved router isn't approved again.
+	if _, err := ex.PlaceOrder(context.Background(), "BTC-USD", Sell, Limit, decimal.NewFromFloat(0.01), decimal.NewFromInt(66000)); err != nil {
+		t.Fatalf("PlaceOrder: %v", err)
+	}
+	if len(wallet.txs) != 3 || wallet.orders[1].IsLong || wallet.orders[1].AcceptablePrice != "66000"+strings.Repeat("0", 22) {
+		t.Errorf("expected a short bounded by the limit price without another approval, got %+v", wallet.orders)
+	}
+}
+
+func TestGmxRevertedTransaction(t *testing.T) {
+	api := newFakeAPI(t)
+	respondGmxMarket(api)
+	chain := newFakeChain(api)
+	chain.allowance = "0x" + strings.Repeat("f", 64)
+	chain.status = "0x0"
+	ex := newTestGmx(api)
+	ex.SetWallet(&recordingWallet{})
+
+	_, err := ex.PlaceOrder(context.Background(), "BTC-USD", Buy, Market, decimal.NewFromFloat(0.01), decimal.Zero)
+	if err == nil || !strings.Contains(err.Error(), "0xhash07 reverted") {
+		t.Errorf("expected the reverted transaction to fail the order, got %v", err)
+	}
+	if err := ex.CancelOrder(context.Background(), "0xkey", "BTC-USD"); err == nil {
+		t.Error("expected a reverted cancellation to fail")
+	}
+}
+
+func TestGmxClosePosition(t *testing.T) {
+	api := newFakeAPI(t)
+	respondGmxMarket(api)
+	newFakeChain(api)
+	api.respond("POST", "/graphql", http.StatusOK, `{"data":{"positions":[
+		{"market":"0xbtcusdc","isLong":true,"sizeInUsd":"1300000000000000000000000000000000","sizeInTokens":"2000000",
+			"collateralToken":"`+GmxUSDC+`","collateralAmount":"650000000"}]}}`)
+	ex := newTestGmx(api)
+	wallet := &recordingWallet{}
+	ex.SetWallet(wallet)
+
+	order, err := ex.ClosePosition(context.Background(), "BTC-USD", Buy, decimal.NewFromFloat(0.01))
+	if err != nil {
+		t.Fatalf("ClosePosition: %v", err)
+	}
+	if order.Side != Sell || order.ID != "0xkey" {
+		t.Errorf("expected a sell closing the long, got %+v", order)
+	}
+	want := GmxOrderRequest{
+		OrderType:       "MarketDecrease",
+		Account:         "0xAccount",
+		Market:          "0xBtcUsdc",
+		CollateralToken: GmxUSDC,
+		IsLong:          true,
+		SizeDeltaUSD:    "650" + strings.Repeat("0", 30),
+		CollateralDelta: "325000000",
+		AcceptablePrice: "64350" + strings.Repeat("0", 22),
+		ExecutionFee:    "500000000000000",
+	}
+	if len(wallet.orders) != 1 || wallet.orders[0] != want {
+		t.Errorf("expected half the long and its collateral decreased, got %+v", wallet.orders)
+	}
+	if len(wallet.txs) != 1 {
+		t.Errorf("expected no approval for a decrease, got %+v", wallet.txs)
+	}
+
+	if _, err := ex.ClosePosition(context.Background(), "BTC-USD", Sell, decimal.NewFromFloat(0.01)); err == nil {
+		t.Error("expected an error closing a short that isn't open")
+	}
+}
+
+func TestGmxOrderStatus(t *testing.T) {
+	api := newFakeAPI(t)
+	respondGmxMarket(api)
+	var actions string
+	api.handle("POST", "/graphql", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !strings.Contains(string(body), `"key":"0xkey"`) {
+			http.Error(w, "unexpected query", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"data":{"tradeActions":[` + actions + `]}}`))
+	})
+	ex := newTestGmx(api)
+	created := `{"eventName":"OrderCreated","orderType":4,"isLong":false,"sizeDeltaUsd":"650000000000000000000000000000000","executionPrice":null}`
+
+	actions = created
+	order, err := ex.GetOrderStatus(context.Background(), "0xkey", "BTC-USD")
+	if err != nil {
+		t.Fatalf("GetOrderStatus: %v", err)
+	}
+	if order.Status != "NEW" || order.Side != Buy || !order.Filled.IsZero() {
+		t.Errorf("expected a pending buy closing a short, got %+v", order)
+	}
+
+	actions = created + `,{"eventName":"OrderExecuted","orderType":4,"isLong":false,"sizeDeltaUsd":"650000000000000000000000000000000","executionPrice":"650000000000000000000000000"}`
+	order, err = ex.GetOrderStatus(context.Background(), "0xkey", "BTC-USD")
+	if err != nil {
+		t.Fatalf("GetOrderStatus: %v", err)
+	}
+	if order.Status != "FILLED" || !order.Filled.Equal(decimal.NewFromFloat(0.01)) || !order.Price.Equal(decimal.NewFromInt(65000)) {
+		t.Errorf("expected 0.01 BTC filled at 65,000, got %+v", order)
+	}
+
+	actions = created + `,{"eventName":"OrderCancelled","orderType":4,"isLong":false,"sizeDeltaUsd":"650000000000000000000000000000000","executionPrice":null}`
+	if order, err := ex.GetOrderStatus(context.Background(), "0xkey", "BTC-USD"); err != nil || order.Status != "CANCELED" {
+		t.Errorf("expected the cancelled order, got %+v, %v", order, err)
+	}
+}
+
+func TestGmxBalance(t *testing.T) {
+	api := newFakeAPI(t)
+	chain := newFakeChain(api)
+	ex := newTestGmx(api)
+
+	balance, err := ex.GetBalance(context.Background(), "USDC")
+	if err != nil {
+		t.Fatalf("GetBalance: %v", err)
+	}
+	if balance != 1000 {
+		t.Errorf("expected the wallet's 1,000 USDC, got %f", balance)
+	}
+	chain.mu.Lock()
+	if len(chain.calls) != 1 || chain.calls[0] != erc20BalanceOf+abiAddress("0xAccount") {
+		t.Errorf("expected balanceOf the account, got %v", chain.calls)
+	}
+	chain.mu.Unlock()
+	if balance, err := ex.GetBalance(context.Background(), "ETH"); err != nil || balance != 0 {
+		t.Errorf("expected no balance of other assets, got %f, %v", balance, err)
+	}
+}
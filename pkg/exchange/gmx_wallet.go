@@ -0,0 +1,348 @@
+package exchange
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// GmxChainID is the chain ID of Arbitrum One, where GMX v2 is deployed.
+const GmxChainID = 42161
+
+const (
+	// GmxRPCURL is the public Arbitrum One RPC endpoint transactions are sent to by default.
+	GmxRPCURL = "https://arb1.arbitrum.io/rpc"
+	// GmxRouter is the GMX v2 router, which moves the collateral of new orders from the wallet and
+	// so must be approved to spend it.
+	GmxRouter = "0x7452c558d45f8afC8c83dAe62C3f8A5BE19c71f6"
+	// GmxUSDC is native USDC on Arbitrum, the collateral orders are sent with.
+	GmxUSDC = "0xaf88d065e77c8cC2239327C5EDb3A432268e5831"
+)
+
+const (
+	// gmxGasBuffer is added to the gas estimate of a transaction, since the state it was estimated
+	// against may have changed by the time it is mined.
+	gmxGasBuffer = 0.2
+	// gmxConfirmationTimeout bounds the wait for a transaction to be mined.
+	gmxConfirmationTimeout = 2 * time.Minute
+	// gmxReceiptPoll is how often the receipt of a pending transaction is polled.
+	gmxReceiptPoll = 2 * time.Second
+)
+
+// ERC-20 function selectors, and the topic of the GMX event log announcing a new order:
+// keccak256("OrderCreated"), the event name hash of the EventEmitter's EventLog2.
+const (
+	erc20BalanceOf       = "0x70a08231"
+	erc20Allowance       = "0xdd62ed3e"
+	erc20Approve         = "0x095ea7b3"
+	gmxOrderCreatedTopic = "0xa7427759bfd3b941f14e687e129519da3c9b0046c5b9aaa290bb1dede63753b3"
+)
+
+// ErrGmxWalletRequired is returned when sending a GMX transaction without a wallet, since orders
+// are Arbitrum transactions signed with its key.
+var ErrGmxWalletRequired = errors.New("GMX orders need GMX_SIGNER_CMD and GMX_ACCOUNT to send")
+
+// GmxCall is a contract call: the address called, its ABI-encoded calldata and the wei it sends,
+// both 0x-hex.
+type GmxCall struct {
+	To    string `json:"to"`
+	Data  string `json:"data"`
+	Value string `json:"value"`
+}
+
+// GmxTx is an unsigned EIP-1559 transaction of the wallet. Fees are in wei, 0x-hex.
+type GmxTx struct {
+	GmxCall
+	ChainID              int64  `json:"chainId"`
+	Nonce                uint64 `json:"nonce"`
+	Gas                  uint64 `json:"gas"`
+	MaxFeePerGas         string `json:"maxFeePerGas"`
+	MaxPriorityFeePerGas string `json:"maxPriorityFeePerGas"`
+}
+
+// GmxOrderRequest is an order created through the exchange router. Amounts are integers in the
+// contracts' units: SizeDeltaUSD has 30 decimals, CollateralDelta those of the collateral token,
+// AcceptablePrice is per smallest unit of the index token with 30 decimals, and ExecutionFee is
+// the wei paid to the keeper executing it.
+type GmxOrderRequest struct {
+	// OrderType is "MarketIncrease" or "MarketDecrease".
+	OrderType       string `json:"orderType"`
+	Account         string `json:"account"`
+	Market          string `json:"market"`
+	CollateralToken string `json:"initialCollateralToken"`
+	IsLong          bool   `json:"isLong"`
+	SizeDeltaUSD    string `json:"sizeDeltaUsd"`
+	CollateralDelta string `json:"initialCollateralDeltaAmount"`
+	AcceptablePrice string `json:"acceptablePrice"`
+	ExecutionFee    string `json:"executionFee"`
+}
+
+// GmxWallet encodes the exchange router calls of GMX orders and signs the wallet's transactions.
+type GmxWallet interface {
+	// EncodeOrder returns the exchange router multicall sending the execution fee and collateral
+	// of order and creating it.
+	EncodeOrder(ctx context.Context, order GmxOrderRequest) (GmxCall, error)
+	// EncodeCancel returns the exchange router call cancelling the order with key.
+	EncodeCancel(ctx context.Context, key string) (GmxCall, error)
+	// SignTx returns the signed raw transaction, 0x-hex.
+	SignTx(ctx context.Context, tx GmxTx) (string, error)
+}
+
+// GmxCommandWallet delegates contract encoding and signing to an external program, e.g. a wrapper
+// around the GMX SDK, since the module has no Ethereum dependency. The program receives
+// {"action", "chain_id", ...} as JSON on stdin: "create_order" with "order" and "cancel_order" with
+// "key" must print the call as {"to", "data", "value"}; "sign" with "private_key" and "tx" must
+// print the raw transaction.
+type GmxCommandWallet struct {
+	command    []string
+	privateKey string
+}
+
+// NewGmxCommandWallet creates a wallet running command, split on whitespace.
+func NewGmxCommandWallet(command, privateKey string) (*GmxCommandWallet, error) {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return nil, errors.New("empty GMX signer command")
+	}
+	return &GmxCommandWallet{command: args, privateKey: privateKey}, nil
+}
+
+// EncodeOrder runs the program to encode the creation of order.
+func (c *GmxCommandWallet) EncodeOrder(ctx context.Context, order GmxOrderRequest) (GmxCall, error) {
+	return c.encode(ctx, map[string]interface{}{"action": "create_order", "chain_id": GmxChainID, "order": order})
+}
+
+// EncodeCancel runs the program to encode the cancellation of the order with key.
+func (c *GmxCommandWallet) EncodeCancel(ctx context.Context, key string) (GmxCall, error) {
+	return c.encode(ctx, map[string]interface{}{"action": "cancel_order", "chain_id": GmxChainID, "key": key})
+}
+
+func (c *GmxCommandWallet) encode(ctx context.Context, input interface{}) (GmxCall, error) {
+	out, err := c.run(ctx, input)
+	if err != nil {
+		return GmxCall{}, err
+	}
+	var call GmxCall
+	if err := json.Unmarshal(out, &call); err != nil || call.To == "" || !strings.HasPrefix(call.Data, "0x") {
+		return GmxCall{}, fmt.Errorf("GMX signer returned an invalid call: %q", out)
+	}
+	return call, nil
+}
+
+// SignTx runs the program to sign tx.
+func (c *GmxCommandWallet) SignTx(ctx context.Context, tx GmxTx) (string, error) {
+	out, err := c.run(ctx, map[string]interface{}{"action": "sign", "chain_id": GmxChainID, "private_key": c.privateKey, "tx": tx})
+	if err != nil {
+		return "", err
+	}
+	raw := string(out)
+	if _, err := hex.DecodeString(strings.TrimPrefix(raw, "0x")); err != nil || !strings.HasPrefix(raw, "0x") || len(raw) <= 2 {
+		return "", fmt.Errorf("GMX signer returned an invalid transaction: %q", raw)
+	}
+	return raw, nil
+}
+
+// run passes input to the program and returns what it printed.
+func (c *GmxCommandWallet) run(ctx context.Context, input interface{}) ([]byte, error) {
+	payload, err := json.Marshal(input)
+	if err != nil {
+		return nil, err
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, c.command[0], c.command[1:]...)
+	cmd.Stdin = bytes.NewReader(payload)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("GMX signer failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return bytes.TrimSpace(stdout.Bytes()), nil
+}
+
+// gmxReceipt is the receipt of a mined transaction, with the logs the order key is read from.
+type gmxReceipt struct {
+	TransactionHash string `json:"transactionHash"`
+	Status          string `json:"status"`
+	Logs            []struct {
+		Topics []string `json:"topics"`
+	} `json:"logs"`
+}
+
+// orderKey returns the key of the order created by the transaction.
+func (r *gmxReceipt) orderKey() (string, bool) {
+	for _, log := range r.Logs {
+		if len(log.Topics) >= 3 && strings.EqualFold(log.Topics[1], gmxOrderCreatedTopic) {
+			return log.Topics[2], true
+		}
+	}
+	return "", false
+}
+
+// rpc makes a JSON-RPC call to the Arbitrum node and decodes its result into out.
+func (g *Gmx) rpc(ctx context.Context, method string, params []interface{}, out interface{}) error {
+	if params == nil {
+		params = []interface{}{}
+	}
+	body, err := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "id": 1, "method": method, "params": params})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", g.rpcURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	var response struct {
+		Result json.RawMessage `json:"result"`
+		Error  *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := doJSON(g.client, req, &response); err != nil {
+		return fmt.Errorf("%s: %w", method, err)
+	}
+	if response.Error != nil {
+		return fmt.Errorf("%s: %s (code %d)", method, response.Error.Message, response.Error.Code)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(response.Result, out); err != nil {
+		return fmt.Errorf("%s: %w", method, err)
+	}
+	return nil
+}
+
+// rpcQuantity makes a JSON-RPC call returning a 0x-hex quantity.
+func (g *Gmx) rpcQuantity(ctx context.Context, method string, params ...interface{}) (*big.Int, error) {
+	var result string
+	if err := g.rpc(ctx, method, params, &result); err != nil {
+		return nil, err
+	}
+	return parseHexQuantity(result)
+}
+
+// callQuantity reads a uint256 returned by a contract call at the latest block.
+func (g *Gmx) callQuantity(ctx context.Context, to, data string) (*big.Int, error) {
+	return g.rpcQuantity(ctx, "eth_call", map[string]string{"to": to, "data": data}, "latest")
+}
+
+// sendTx estimates the gas of call, signs it with the wallet's next nonce and waits for it to be
+// mined, returning its receipt. Transactions are sent one at a time so nonces don't collide.
+func (g *Gmx) sendTx(ctx context.Context, call GmxCall) (*gmxReceipt, error) {
+	g.txMu.Lock()
+	defer g.txMu.Unlock()
+
+	nonce, err := g.rpcQuantity(ctx, "eth_getTransactionCount", g.account, "pending")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the wallet nonce: %w", err)
+	}
+	gasPrice, err := g.rpcQuantity(ctx, "eth_gasPrice")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the gas price: %w", err)
+	}
+	estimate, err := g.rpcQuantity(ctx, "eth_estimateGas", map[string]string{"from": g.account, "to": call.To, "data": call.Data, "value": call.Value})
+	if err != nil {
+		return nil, fmt.Errorf("failed to estimate gas: %w", err)
+	}
+	gas := estimate.Uint64() + uint64(float64(estimate.Uint64())*gmxGasBuffer)
+
+	// Arbitrum has no priority auction, so no tip is paid; the fee cap leaves room for the base
+	// fee to double before the transaction is mined.
+	tx := GmxTx{
+		GmxCall:              call,
+		ChainID:              GmxChainID,
+		Nonce:                nonce.Uint64(),
+		Gas:                  gas,
+		MaxFeePerGas:         hexQuantity(new(big.Int).Mul(gasPrice, big.NewInt(2))),
+		MaxPriorityFeePerGas: "0x0",
+	}
+	raw, err := g.wallet.SignTx(ctx, tx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign the transaction: %w", err)
+	}
+	var hash string
+	if err := g.rpc(ctx, "eth_sendRawTransaction", []interface{}{raw}, &hash); err != nil {
+		return nil, err
+	}
+	return g.waitForReceipt(ctx, hash)
+}
+
+// waitForReceipt polls the receipt of the transaction hash until it is mined, failing if it
+// reverted or isn't mined in time. The transaction is already sent, so failed polls are retried.
+func (g *Gmx) waitForReceipt(ctx context.Context, hash string) (*gmxReceipt, error) {
+	ctx, cancel := context.WithTimeout(ctx, gmxConfirmationTimeout)
+	defer cancel()
+	ticker := time.NewTicker(g.receiptPoll)
+	defer ticker.Stop()
+	var lastErr error
+	for {
+		var receipt *gmxReceipt
+		err := g.rpc(ctx, "eth_getTransactionReceipt", []interface{}{hash}, &receipt)
+		switch {
+		case err != nil:
+			lastErr = err
+		case receipt == nil:
+		case receipt.Status != "0x1":
+			return nil, fmt.Errorf("transaction %s reverted", hash)
+		default:
+			return receipt, nil
+		}
+		select {
+		case <-ctx.Done():
+			if lastErr != nil {
+				return nil, fmt.Errorf("transaction %s not confirmed: %w (last poll: %v)", hash, ctx.Err(), lastErr)
+			}
+			return nil, fmt.Errorf("transaction %s not confirmed: %w", hash, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// approve lets the router spend amount of token from the wallet, unless it already may.
+func (g *Gmx) approve(ctx context.Context, token string, amount *big.Int) error {
+	allowance, err := g.callQuantity(ctx, token, erc20Allowance+abiAddress(g.account)+abiAddress(GmxRouter))
+	if err != nil {
+		return fmt.Errorf("failed to read the router allowance: %w", err)
+	}
+	if allowance.Cmp(amount) >= 0 {
+		return nil
+	}
+	unlimited := strings.Repeat("f", 64)
+	if _, err := g.sendTx(ctx, GmxCall{To: token, Data: erc20Approve + abiAddress(GmxRouter) + unlimited, Value: "0x0"}); err != nil {
+		return fmt.Errorf("failed to approve the router: %w", err)
+	}
+	return nil
+}
+
+// abiAddress encodes an address as a 32-byte ABI word, without the 0x prefix.
+func abiAddress(address string) string {
+	return strings.Repeat("0", 24) + strings.ToLower(strings.TrimPrefix(address, "0x"))
+}
+
+// hexQuantity formats n as a 0x-hex quantity.
+func hexQuantity(n *big.Int) string {
+	return "0x" + n.Text(16)
+}
+
+// parseHexQuantity parses a 0x-hex quantity.
+func parseHexQuantity(s string) (*big.Int, error) {
+	digits := strings.TrimPrefix(s, "0x")
+	if digits == "" {
+		return new(big.Int), nil
+	}
+	n, ok := new(big.Int).SetString(digits, 16)
+	if !ok {
+		return nil, fmt.Errorf("invalid hex quantity %q", s)
+	}
+	return n, nil
+}
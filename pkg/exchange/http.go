@@ -0,0 +1,88 @@
+package exchange
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/httpclient"
+)
+
+// maxErrorBodySize caps how much of an error response body is kept for error messages.
+const maxErrorBodySize = 4 << 10
+
+// bufferPool recycles buffers used for request payloads and error bodies.
+var bufferPool = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
+// encodeJSON serialises v into a pooled buffer. The caller must release the buffer with putBuffer.
+func encodeJSON(v interface{}) (*bytes.Buffer, error) {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	if err := json.NewEncoder(buf).Encode(v); err != nil {
+		putBuffer(buf)
+		return nil, err
+	}
+	return buf, nil
+}
+
+// putBuffer returns a buffer to the pool.
+func putBuffer(buf *bytes.Buffer) {
+	bufferPool.Put(buf)
+}
+
+// doJSON executes req, retrying transient failures, and streams the JSON response body into out.
+// Responses with a 4xx/5xx status are returned as *httpclient.StatusError carrying a truncated
+// copy of the body. If out is nil the body is drained and discarded.
+func doJSON(client *http.Client, req *http.Request, out interface{}) error {
+	resp, err := httpclient.Do(client, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		buf := bufferPool.Get().(*bytes.Buffer)
+		buf.Reset()
+		defer putBuffer(buf)
+		_, _ = io.Copy(buf, io.LimitReader(resp.Body, maxErrorBodySize))
+		return &httpclient.StatusError{StatusCode: resp.StatusCode, Status: resp.Status, Body: buf.String()}
+	}
+
+	if out == nil {
+		_, err = io.Copy(io.Discard, resp.Body)
+		return err
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// fetchRates calls fetch for every market concurrently, for venues that only serve funding rates
+// one market at a time. Rates are returned in the order of markets, leaving out the nil ones, or
+// the error of the first market that failed.
+func fetchRates(markets []string, fetch func(market string) (*FundingRate, error)) ([]*FundingRate, error) {
+	rates := make([]*FundingRate, len(markets))
+	errs := make([]error, len(markets))
+	var wg sync.WaitGroup
+	for i, market := range markets {
+		wg.Add(1)
+		go func(i int, market string) {
+			defer wg.Done()
+			rates[i], errs[i] = fetch(market)
+		}(i, market)
+	}
+	wg.Wait()
+
+	var fundingRates []*FundingRate
+	for i, rate := range rates {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		if rate != nil {
+			fundingRates = append(fundingRates, rate)
+		}
+	}
+	return fundingRates, nil
+}
@@ -1,13 +1,22 @@
 package exchange
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io/ioutil"
+	"io"
+	"math"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/httpclient"
 )
 
 const (
@@ -15,12 +24,43 @@ const (
 	LighterTestnetBaseURL = "https://testnet.zklighter.elliot.ai"
 )
 
+// ErrLighterSignerRequired is returned by the Lighter calls that submit transactions when no
+// signer is set, since Lighter only accepts signed orders.
+var ErrLighterSignerRequired = errors.New("Lighter trading needs LIGHTER_SIGNER_CMD to sign transactions")
+
+// DefaultLighterSlippage bounds the execution price of Lighter market orders, which are sent as
+// immediate-or-cancel orders with a worst acceptable price.
+const DefaultLighterSlippage = 0.01
+
 type Lighter struct {
 	client     *http.Client
 	apiKey     string
 	privateKey string
 	baseURL    string
 	testnet    bool
+
+	// signer signs every transaction; without it the client is read-only.
+	signer       LighterSigner
+	accountIndex int64
+	apiKeyIndex  uint8
+	slippage     float64
+
+	nonceMu    sync.Mutex
+	nonce      int64
+	nonceValid bool
+
+	marketsMu sync.Mutex
+	markets   map[string]LighterMarket
+
+	authMu     sync.Mutex
+	auth       string
+	authExpiry time.Time
+
+	// marginMu guards the margin mode and leverage last set per market, which Lighter updates
+	// together in one transaction.
+	marginMu    sync.Mutex
+	marginModes map[string]MarginMode
+	leverages   map[string]float64
 }
 
 func NewLighter(apiKey, privateKey string, testnet bool) *Lighter {
@@ -37,10 +77,31 @@ func NewLighter(apiKey, privateKey string, testnet bool) *Lighter {
 	}
 }
 
+// SetSigner enables real order submission for the account at accountIndex, signing with the API
+// key at apiKeyIndex.
+func (l *Lighter) SetSigner(signer LighterSigner, accountIndex int64, apiKeyIndex uint8) {
+	l.signer = signer
+	l.accountIndex = accountIndex
+	l.apiKeyIndex = apiKeyIndex
+}
+
+// SetRateLimiter makes every Lighter API call wait for limiter.
+func (l *Lighter) SetRateLimiter(limiter *httpclient.Limiter) {
+	l.client = limiter.Client(l.client)
+}
+
 func (l *Lighter) Name() string {
 	return "Lighter"
 }
 
+func (l *Lighter) FundingInterval() time.Duration {
+	return time.Hour
+}
+
+func (l *Lighter) CollateralAsset() string {
+	return "USDC"
+}
+
 func (l *Lighter) SetTestnet(testnet bool) {
 	l.testnet = testnet
 	if testnet {
@@ -48,112 +109,760 @@ func (l *Lighter) SetTestnet(testnet bool) {
 	} else {
 		l.baseURL = LighterMainnetBaseURL
 	}
+	l.marketsMu.Lock()
+	l.markets = nil
+	l.marketsMu.Unlock()
+}
+
+// lighterRateBasis is the period the funding-rates endpoint quotes rates over. Lighter pays
+// funding hourly, so rates are scaled down to the per-hour rate that FundingInterval describes.
+const lighterRateBasis = 8 * time.Hour
+
+// LighterFundingRate is a rate as listed by the funding-rates endpoint, which also lists the
+// rates of other exchanges for comparison.
+type LighterFundingRate struct {
+	MarketID uint8           `json:"market_id"`
+	Exchange string          `json:"exchange"`
+	Symbol   string          `json:"symbol"`
+	Rate     decimal.Decimal `json:"rate"`
+}
+
+// GetFundingRates fetches the current funding rate of every Lighter market. Funding is paid at
+// the top of every hour.
+func (l *Lighter) GetFundingRates(ctx context.Context) ([]*FundingRate, error) {
+	var response struct {
+		FundingRates []LighterFundingRate `json:"funding_rates"`
+	}
+	if err := l.callAPI(ctx, http.MethodGet, "/api/v1/funding-rates", nil, &response); err != nil {
+		return nil, fmt.Errorf("failed to get funding rates from Lighter: %w", err)
+	}
+
+	scale := rateScale(l.FundingInterval(), lighterRateBasis)
+	next := time.Now().Truncate(time.Hour).Add(time.Hour).Unix()
+	var fundingRates []*FundingRate
+	for _, r := range response.FundingRates {
+		if r.Exchange != "lighter" {
+			continue
+		}
+		fundingRates = append(fundingRates, &FundingRate{
+			Market:   r.Symbol + "-USD",
+			Rate:     r.Rate.Mul(scale),
+			NextTime: next,
+		})
+	}
+	return fundingRates, nil
+}
+
+// lighterOrderbookDepth is the number of resting orders requested on each side of the book.
+const lighterOrderbookDepth = 100
+
+// LighterOrderbookResponse is the response structure for the resting orders of an order book.
+type LighterOrderbookResponse struct {
+	Asks []LighterOrderbookOrder `json:"asks"`
+	Bids []LighterOrderbookOrder `json:"bids"`
 }
 
-func (l *Lighter) GetFundingRates() ([]*FundingRate, error) {
-	// The provided Lighter documentation does not have a specific endpoint for funding rates.
-	// This is a placeholder. You would need to find the correct endpoint or method
-	// to get this data. For now, it will return an error.
-	return nil, errors.New("funding rate endpoint not available in Lighter documentation")
+// LighterOrderbookOrder is a resting order in a Lighter order book.
+type LighterOrderbookOrder struct {
+	Price               string `json:"price"`
+	RemainingBaseAmount string `json:"remaining_base_amount"`
 }
 
-func (l *Lighter) GetOrderbook(market string) (map[string]interface{}, error) {
-	// The documentation mentions OrderApi's order_book_details but doesn't provide a clear REST endpoint.
-	// This is a placeholder.
-	url := fmt.Sprintf("%s/order_book_details?market=%s", l.baseURL, market)
-	req, err := http.NewRequest("GET", url, nil)
+// GetOrderbook returns the resting orders of market. Lighter lists individual orders rather than
+// aggregated levels, so a price may appear more than once.
+func (l *Lighter) GetOrderbook(ctx context.Context, market string) (*Orderbook, error) {
+	m, err := l.market(ctx, market)
 	if err != nil {
 		return nil, err
 	}
+	query := url.Values{
+		"market_id": {strconv.Itoa(int(m.MarketID))},
+		"limit":     {strconv.Itoa(lighterOrderbookDepth)},
+	}
+	var response LighterOrderbookResponse
+	if err := l.callAPI(ctx, http.MethodGet, "/api/v1/orderBookOrders?"+query.Encode(), nil, &response); err != nil {
+		return nil, fmt.Errorf("failed to get orderbook from Lighter: %w", err)
+	}
 
-	resp, err := l.client.Do(req)
+	bids, err := lighterLevels(response.Bids)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to parse %s orderbook from Lighter: %w", market, err)
+	}
+	asks, err := lighterLevels(response.Asks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s orderbook from Lighter: %w", market, err)
 	}
-	defer resp.Body.Close()
+	book := &Orderbook{Market: market, Bids: bids, Asks: asks}
+	book.sortLevels()
+	return book, nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to get orderbook: %s", resp.Status)
+func lighterLevels(orders []LighterOrderbookOrder) ([]PriceLevel, error) {
+	parsed := make([]PriceLevel, 0, len(orders))
+	for _, o := range orders {
+		level, err := parseLevel(o.Price, o.RemainingBaseAmount)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, level)
 	}
+	return parsed, nil
+}
 
-	var orderbook map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&orderbook); err != nil {
-		return nil, err
+// GetMarkPrice returns the last trade price of market, which the order book details report in
+// place of a mark price.
+func (l *Lighter) GetMarkPrice(ctx context.Context, market string) (float64, error) {
+	m, err := l.freshMarket(ctx, market)
+	if err != nil {
+		return 0, err
 	}
-	return orderbook, nil
+	if m.LastTradePrice <= 0 {
+		return 0, fmt.Errorf("no trade price for %s on Lighter", market)
+	}
+	return m.LastTradePrice, nil
 }
 
-func (l *Lighter) PlaceOrder(market string, side OrderSide, orderType OrderType, amount, price float64) (*Order, error) {
-	// NOTE: This function is a SIMULATION.
-	// The Lighter exchange API requires a complex signed transaction that is not fully
-	// documented for a non-Python implementation. This function logs the intent to trade
-	// but does not send a real order to the Lighter exchange.
-	fmt.Printf("\n==> [SIMULATED] Lighter Request:\n    Action: Place %s %s order\n    Market: %s\n    Amount: %f\n", orderType, side, market, amount)
-	fmt.Printf("<== [SIMULATED] Lighter Response: OK (No real order was sent)\n")
+func (l *Lighter) PlaceOrder(ctx context.Context, market string, side OrderSide, orderType OrderType, amount, price decimal.Decimal) (*Order, error) {
+	if l.signer == nil {
+		return nil, ErrLighterSignerRequired
+	}
+	return l.placeSignedOrder(ctx, market, side, orderType, amount, price, false)
+}
 
+// LighterOrder is an order of the account as listed by the active and inactive order endpoints.
+// Amounts and prices are decimal strings.
+type LighterOrder struct {
+	OrderIndex        int64  `json:"order_index"`
+	ClientOrderIndex  int64  `json:"client_order_index"`
+	InitialBaseAmount string `json:"initial_base_amount"`
+	FilledBaseAmount  string `json:"filled_base_amount"`
+	FilledQuoteAmount string `json:"filled_quote_amount"`
+	Price             string `json:"price"`
+	IsAsk             bool   `json:"is_ask"`
+	Type              string `json:"type"`
+	Status            string `json:"status"`
+	Timestamp         int64  `json:"timestamp"`
+}
+
+// order converts the listing into an Order on market, priced at the average fill when it has
+// filled, with the status upper-cased.
+func (o LighterOrder) order(id, market string) *Order {
+	filled := parseDecimal(o.FilledBaseAmount)
+	price := parseDecimal(o.Price)
+	if filled.IsPositive() {
+		price = parseDecimal(o.FilledQuoteAmount).Div(filled)
+	}
+	side := Buy
+	if o.IsAsk {
+		side = Sell
+	}
 	return &Order{
-		ID:        fmt.Sprintf("lighter-simulated-%d", time.Now().UnixNano()),
+		ID:        id,
 		Market:    market,
 		Side:      side,
-		Type:      orderType,
+		Type:      OrderType(strings.ToUpper(o.Type)),
 		Price:     price,
-		Amount:    amount,
-		Status:    "NEW",
-		Timestamp: time.Now().Unix(),
-	}, nil
+		Amount:    parseDecimal(o.InitialBaseAmount),
+		Filled:    filled,
+		Status:    strings.ToUpper(o.Status),
+		Timestamp: o.Timestamp,
+	}
+}
+
+// lighterInactiveOrdersLimit is how many of the latest inactive orders are searched for an order
+// that is no longer active.
+const lighterInactiveOrdersLimit = 100
+
+// GetOrderStatus returns the order placed with the client order index orderID, looked up among the
+// active orders of market and then among the latest inactive ones. Reading orders needs an auth
+// token from the signer.
+func (l *Lighter) GetOrderStatus(ctx context.Context, orderID string, market string) (*Order, error) {
+	if l.signer == nil {
+		return nil, ErrLighterSignerRequired
+	}
+	index, err := strconv.ParseInt(orderID, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Lighter order ID %q: %w", orderID, err)
+	}
+	m, err := l.market(ctx, market)
+	if err != nil {
+		return nil, err
+	}
+	token, err := l.authToken(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order status from Lighter: %w", err)
+	}
+	query := url.Values{
+		"account_index": {strconv.FormatInt(l.accountIndex, 10)},
+		"market_id":     {strconv.Itoa(int(m.MarketID))},
+		"auth":          {token},
+	}
+	for _, endpoint := range []string{"/api/v1/accountActiveOrders", "/api/v1/accountInactiveOrders"} {
+		if endpoint == "/api/v1/accountInactiveOrders" {
+			query.Set("limit", strconv.Itoa(lighterInactiveOrdersLimit))
+		}
+		var response struct {
+			Orders []LighterOrder `json:"orders"`
+		}
+		if err := l.callAPI(ctx, http.MethodGet, endpoint+"?"+query.Encode(), nil, &response); err != nil {
+			return nil, fmt.Errorf("failed to get order status from Lighter: %w", err)
+		}
+		for _, o := range response.Orders {
+			if o.ClientOrderIndex == index {
+				return o.order(orderID, market), nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("%s order %s not found on Lighter", market, orderID)
 }
 
-func (l *Lighter) GetOrderStatus(orderID string, market string) (*Order, error) {
-	// Placeholder. The documentation doesn't provide a clear REST endpoint to get order status by ID.
-	return nil, errors.New("get order status endpoint not available in Lighter documentation")
+// lighterAuthTokenTTL is how long the auth tokens requested from the signer are valid for; Lighter
+// accepts at most 8 hours.
+const lighterAuthTokenTTL = time.Hour
+
+// authToken returns the cached auth token of the API key, requesting a new one from the signer a
+// minute before it expires.
+func (l *Lighter) authToken(ctx context.Context) (string, error) {
+	l.authMu.Lock()
+	defer l.authMu.Unlock()
+	if l.auth != "" && time.Now().Before(l.authExpiry.Add(-time.Minute)) {
+		return l.auth, nil
+	}
+	deadline := time.Now().Add(lighterAuthTokenTTL)
+	token, err := l.signer.AuthToken(ctx, l.accountIndex, l.apiKeyIndex, deadline)
+	if err != nil {
+		return "", err
+	}
+	l.auth, l.authExpiry = token, deadline
+	return token, nil
 }
 
-func (l *Lighter) CancelOrder(orderID string, market string) error {
-	// Placeholder. This would also require a signed transaction.
-	fmt.Printf("Simulating cancelling order on Lighter: %s\n", orderID)
+func (l *Lighter) CancelOrder(ctx context.Context, orderID string, market string) error {
+	if l.signer == nil {
+		return ErrLighterSignerRequired
+	}
+	return l.cancelSignedOrder(ctx, orderID, market)
+}
+
+// SetMarginMode selects the margin mode of market. Lighter sets the margin mode and the leverage
+// in one transaction, so the leverage is the one last set with SetLeverage, or else the one of the
+// open position. Without either there is no leverage to send with the mode, which is an error.
+func (l *Lighter) SetMarginMode(ctx context.Context, market string, mode MarginMode) error {
+	if l.signer == nil {
+		return ErrLighterSignerRequired
+	}
+	l.marginMu.Lock()
+	leverage := l.leverages[market]
+	l.marginMu.Unlock()
+	if leverage <= 0 {
+		var err error
+		if leverage, err = l.positionLeverage(ctx, market); err != nil {
+			return err
+		}
+	}
+	if err := l.updateLeverage(ctx, market, leverage, mode); err != nil {
+		return err
+	}
+	l.marginMu.Lock()
+	if l.marginModes == nil {
+		l.marginModes = make(map[string]MarginMode)
+	}
+	l.marginModes[market] = mode
+	l.marginMu.Unlock()
 	return nil
 }
 
-func (l *Lighter) GetBalance(asset string) (float64, error) {
-	// Placeholder. The documentation mentions AccountApi but no clear REST endpoint.
-	return 0, errors.New("get balance endpoint not available in Lighter documentation")
+// SetLeverage sets the leverage of market, keeping the margin mode last set with SetMarginMode,
+// cross by default.
+func (l *Lighter) SetLeverage(ctx context.Context, market string, leverage float64) error {
+	if l.signer == nil {
+		return ErrLighterSignerRequired
+	}
+	l.marginMu.Lock()
+	mode, ok := l.marginModes[market]
+	l.marginMu.Unlock()
+	if !ok {
+		mode = CrossMargin
+	}
+	if err := l.updateLeverage(ctx, market, leverage, mode); err != nil {
+		return err
+	}
+	l.marginMu.Lock()
+	if l.leverages == nil {
+		l.leverages = make(map[string]float64)
+	}
+	l.leverages[market] = leverage
+	l.marginMu.Unlock()
+	return nil
 }
 
-func (l *Lighter) sendRequest(method, endpoint string, data []byte) ([]byte, error) {
-	url := l.baseURL + endpoint
-	req, err := http.NewRequest(method, url, bytes.NewBuffer(data))
+// Ping fetches the account set with SetSigner. Without a signer the client can't trade, which is
+// an error.
+func (l *Lighter) Ping(ctx context.Context) error {
+	if l.signer == nil {
+		return ErrLighterSignerRequired
+	}
+	_, err := l.account(ctx)
+	return err
+}
+
+// GetBalance returns the USDC collateral of the account set with SetSigner. Lighter margins every
+// market in USDC, so asset is only checked against it.
+func (l *Lighter) GetBalance(ctx context.Context, asset string) (float64, error) {
+	if l.signer == nil {
+		return 0, ErrLighterSignerRequired
+	}
+	if asset != "" && !strings.EqualFold(asset, l.CollateralAsset()) {
+		return 0, nil
+	}
+	account, err := l.account(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get balance from Lighter: %w", err)
+	}
+	collateral, err := strconv.ParseFloat(account.Collateral, 64)
 	if err != nil {
+		return 0, fmt.Errorf("failed to parse balance float from Lighter: %w", err)
+	}
+	return collateral, nil
+}
+
+// LighterAccountPosition is a position as listed in the account endpoint. Sign is 1 for longs and
+// -1 for shorts.
+type LighterAccountPosition struct {
+	Symbol           string `json:"symbol"`
+	Sign             int    `json:"sign"`
+	Position         string `json:"position"`
+	AvgEntryPrice    string `json:"avg_entry_price"`
+	PositionValue    string `json:"position_value"`
+	LiquidationPrice string `json:"liquidation_price"`
+	AllocatedMargin  string `json:"allocated_margin"`
+	// InitialMarginFraction is the position's initial margin in percent, 20 at 5x.
+	InitialMarginFraction string `json:"initial_margin_fraction"`
+}
+
+// LighterAccount is the part of an account used by the bot: its cross-margin collateral and
+// positions.
+type LighterAccount struct {
+	Collateral string                   `json:"collateral"`
+	Positions  []LighterAccountPosition `json:"positions"`
+}
+
+// account fetches the account set with SetSigner.
+func (l *Lighter) account(ctx context.Context) (*LighterAccount, error) {
+	query := url.Values{"by": {"index"}, "value": {strconv.FormatInt(l.accountIndex, 10)}}
+	var response struct {
+		Accounts []LighterAccount `json:"accounts"`
+	}
+	if err := l.callAPI(ctx, http.MethodGet, "/api/v1/account?"+query.Encode(), nil, &response); err != nil {
 		return nil, err
 	}
-	req.Header.Set("Content-Type", "application/json")
-	// Authentication headers would go here if specified in the API docs.
+	if len(response.Accounts) == 0 {
+		return nil, fmt.Errorf("Lighter account %d not found", l.accountIndex)
+	}
+	return &response.Accounts[0], nil
+}
 
-	resp, err := l.client.Do(req)
+// GetPositions fetches the open positions of the account set with SetSigner.
+func (l *Lighter) GetPositions(ctx context.Context) ([]Position, error) {
+	account, err := l.account(ctx)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to get positions from Lighter: %w", err)
 	}
-	defer resp.Body.Close()
 
-	body, err := ioutil.ReadAll(resp.Body)
+	var positions []Position
+	for _, p := range account.Positions {
+		size, err := strconv.ParseFloat(p.Position, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse position size for %s from Lighter: %w", p.Symbol, err)
+		}
+		if size == 0 {
+			continue
+		}
+		entry, _ := strconv.ParseFloat(p.AvgEntryPrice, 64)
+		side := Buy
+		if p.Sign < 0 {
+			side = Sell
+		}
+		positions = append(positions, Position{Market: p.Symbol + "-USD", Side: side, Size: math.Abs(size), EntryPrice: entry})
+	}
+	return positions, nil
+}
+
+// GetPositionRisk fetches the margin and liquidation price of the open position in market. The
+// mark price is derived from the position value. Cross-margined positions are backed by the
+// account's collateral, isolated ones by the margin allocated to them.
+func (l *Lighter) GetPositionRisk(ctx context.Context, market string) (*PositionRisk, error) {
+	account, err := l.account(ctx)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to get the %s position from Lighter: %w", market, err)
+	}
+	for _, p := range account.Positions {
+		if p.Symbol+"-USD" != market {
+			continue
+		}
+		size, err := strconv.ParseFloat(p.Position, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse position size for %s from Lighter: %w", market, err)
+		}
+		if size == 0 {
+			break
+		}
+		value, err := strconv.ParseFloat(p.PositionValue, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse position value for %s from Lighter: %w", market, err)
+		}
+		liquidation, _ := strconv.ParseFloat(p.LiquidationPrice, 64)
+		margin, _ := strconv.ParseFloat(p.AllocatedMargin, 64)
+		if margin == 0 {
+			margin, _ = strconv.ParseFloat(account.Collateral, 64)
+		}
+		side := Buy
+		if p.Sign < 0 {
+			side = Sell
+		}
+		size = math.Abs(size)
+		return &PositionRisk{Market: market, Side: side, Size: size, MarkPrice: math.Abs(value) / size, LiquidationPrice: liquidation, Margin: margin}, nil
+	}
+	return nil, fmt.Errorf("no open %s position on Lighter", market)
+}
+
+// SetTransport replaces the HTTP transport used for REST requests, e.g. with a recorder in tests.
+func (l *Lighter) SetTransport(rt http.RoundTripper) {
+	l.client.Transport = rt
+}
+
+func (l *Lighter) sendRequest(ctx context.Context, method, endpoint string, payload interface{}, out interface{}) error {
+	var body io.Reader
+	if payload != nil {
+		buf, err := encodeJSON(payload)
+		if err != nil {
+			return err
+		}
+		defer putBuffer(buf)
+		body = buf
 	}
 
-	if resp.StatusCode >= 400 {
-		return nil, fmt.Errorf("API error: %s - %s", resp.Status, string(body))
+	url := l.baseURL + endpoint
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return err
 	}
+	req.Header.Set("Content-Type", "application/json")
+	// Authentication headers would go here if specified in the API docs.
 
-	return body, nil
+	return doJSON(l.client, req, out)
 }
 
-func (l *Lighter) ClosePosition(market string, side OrderSide, amount float64) (*Order, error) {
+func (l *Lighter) ClosePosition(ctx context.Context, market string, side OrderSide, amount decimal.Decimal) (*Order, error) {
 	// To close a position, we place an order on the opposite side.
 	closeSide := Sell
 	if side == Sell {
 		closeSide = Buy
 	}
 
-	fmt.Printf("Simulating closing %s position on Lighter for %s\n", side, market)
-	// Using a market order to close, so price is irrelevant (can be 0).
-	return l.PlaceOrder(market, closeSide, Market, amount, 0)
+	if l.signer == nil {
+		return nil, ErrLighterSignerRequired
+	}
+	return l.placeSignedOrder(ctx, market, closeSide, Market, amount, decimal.Zero, true)
+}
+
+// LighterMarket is the order book metadata needed to encode orders for a market.
+type LighterMarket struct {
+	Symbol         string  `json:"symbol"`
+	MarketID       uint8   `json:"market_id"`
+	SizeDecimals   int     `json:"size_decimals"`
+	PriceDecimals  int     `json:"price_decimals"`
+	LastTradePrice float64 `json:"last_trade_price"`
+}
+
+// LighterAPIError is an error reported by the Lighter API in its response body.
+type LighterAPIError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// failed reports whether the code is an error rather than success.
+func (e *LighterAPIError) failed() bool {
+	return e.Code != 0 && e.Code != http.StatusOK
+}
+
+func (e *LighterAPIError) Error() string {
+	return fmt.Sprintf("Lighter API error %d: %s", e.Code, e.Message)
+}
+
+// lighterSymbol maps a bot market such as BTC-USD to the Lighter order book symbol.
+func lighterSymbol(market string) string {
+	return strings.TrimSuffix(market, "-USD")
+}
+
+// market returns the order book metadata of market, fetched on first use and cached. Its last
+// trade price is as old as the cache; freshMarket refreshes it.
+func (l *Lighter) market(ctx context.Context, market string) (LighterMarket, error) {
+	l.marketsMu.Lock()
+	m, ok := l.markets[lighterSymbol(market)]
+	l.marketsMu.Unlock()
+	if ok {
+		return m, nil
+	}
+	return l.freshMarket(ctx, market)
+}
+
+// freshMarket fetches the order book details of every market into the cache and returns those of
+// market, with its current last trade price.
+func (l *Lighter) freshMarket(ctx context.Context, market string) (LighterMarket, error) {
+	var response struct {
+		OrderBookDetails []LighterMarket `json:"order_book_details"`
+	}
+	if err := l.callAPI(ctx, http.MethodGet, "/api/v1/orderBookDetails", nil, &response); err != nil {
+		return LighterMarket{}, fmt.Errorf("failed to get order book details from Lighter: %w", err)
+	}
+
+	l.marketsMu.Lock()
+	defer l.marketsMu.Unlock()
+	if l.markets == nil {
+		l.markets = make(map[string]LighterMarket)
+	}
+	for _, m := range response.OrderBookDetails {
+		l.markets[m.Symbol] = m
+	}
+	m, ok := l.markets[lighterSymbol(market)]
+	if !ok {
+		return LighterMarket{}, fmt.Errorf("market %s not found on Lighter", market)
+	}
+	return m, nil
+}
+
+// nextNonce returns the nonce for the next transaction of the API key. The nonce is fetched once
+// and then incremented locally; invalidateNonce forces a refetch after a rejected transaction.
+func (l *Lighter) nextNonce(ctx context.Context) (int64, error) {
+	l.nonceMu.Lock()
+	defer l.nonceMu.Unlock()
+	if !l.nonceValid {
+		query := url.Values{
+			"account_index": {strconv.FormatInt(l.accountIndex, 10)},
+			"api_key_index": {strconv.Itoa(int(l.apiKeyIndex))},
+		}
+		var response struct {
+			Nonce int64 `json:"nonce"`
+		}
+		if err := l.callAPI(ctx, http.MethodGet, "/api/v1/nextNonce?"+query.Encode(), nil, &response); err != nil {
+			return 0, fmt.Errorf("failed to get nonce from Lighter: %w", err)
+		}
+		l.nonce, l.nonceValid = response.Nonce, true
+	}
+	nonce := l.nonce
+	l.nonce++
+	return nonce, nil
+}
+
+func (l *Lighter) invalidateNonce() {
+	l.nonceMu.Lock()
+	l.nonceValid = false
+	l.nonceMu.Unlock()
+}
+
+// placeSignedOrder submits a signed order. Market orders are immediate-or-cancel with a worst
+// price slippage away from price, or from the last trade price when price is zero.
+func (l *Lighter) placeSignedOrder(ctx context.Context, market string, side OrderSide, orderType OrderType, amount, price decimal.Decimal, reduceOnly bool) (*Order, error) {
+	m, err := l.market(ctx, market)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := price
+	if orderType == Market {
+		reference := price
+		if !reference.IsPositive() {
+			fresh, err := l.freshMarket(ctx, market)
+			if err != nil {
+				return nil, err
+			}
+			reference = decimal.NewFromFloat(fresh.LastTradePrice)
+		}
+		if !reference.IsPositive() {
+			return nil, fmt.Errorf("no reference price for a %s market order on Lighter", market)
+		}
+		slippage := l.slippage
+		if slippage <= 0 {
+			slippage = DefaultLighterSlippage
+		}
+		if side == Buy {
+			limit = reference.Mul(decimal.NewFromFloat(1 + slippage))
+		} else {
+			limit = reference.Mul(decimal.NewFromFloat(1 - slippage))
+		}
+	}
+	baseAmount := amount.Shift(int32(m.SizeDecimals)).Round(0).IntPart()
+	if baseAmount <= 0 {
+		return nil, fmt.Errorf("order amount %s is below the %s size precision on Lighter", amount, market)
+	}
+
+	tx := LighterCreateOrderTx{
+		AccountIndex:     l.accountIndex,
+		ApiKeyIndex:      l.apiKeyIndex,
+		MarketIndex:      m.MarketID,
+		ClientOrderIndex: time.Now().UnixMicro() & (1<<48 - 1),
+		BaseAmount:       baseAmount,
+		Price:            uint32(limit.Shift(int32(m.PriceDecimals)).Round(0).IntPart()),
+	}
+	if side == Sell {
+		tx.IsAsk = 1
+	}
+	if reduceOnly {
+		tx.ReduceOnly = 1
+	}
+	if orderType == Market {
+		tx.Type = 1 // market, immediate-or-cancel
+	} else {
+		tx.TimeInForce = 1 // good-till-time
+		tx.OrderExpiry = time.Now().Add(28 * 24 * time.Hour).UnixMilli()
+	}
+
+	if err := l.sendSignedTx(ctx, LighterTxCreateOrder, func(nonce int64) interface{} {
+		tx.Nonce = nonce
+		return tx
+	}); err != nil {
+		return nil, fmt.Errorf("failed to place order on Lighter: %w", err)
+	}
+
+	return &Order{
+		ID:        strconv.FormatInt(tx.ClientOrderIndex, 10),
+		Market:    market,
+		Side:      side,
+		Type:      orderType,
+		Price:     price,
+		Amount:    amount,
+		Status:    "NEW",
+		Timestamp: time.Now().Unix(),
+	}, nil
+}
+
+// cancelSignedOrder cancels an order by the client order index returned as its ID.
+func (l *Lighter) cancelSignedOrder(ctx context.Context, orderID, market string) error {
+	index, err := strconv.ParseInt(orderID, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid Lighter order ID %q: %w", orderID, err)
+	}
+	m, err := l.market(ctx, market)
+	if err != nil {
+		return err
+	}
+	tx := LighterCancelOrderTx{AccountIndex: l.accountIndex, ApiKeyIndex: l.apiKeyIndex, MarketIndex: m.MarketID, Index: index}
+	if err := l.sendSignedTx(ctx, LighterTxCancelOrder, func(nonce int64) interface{} {
+		tx.Nonce = nonce
+		return tx
+	}); err != nil {
+		return fmt.Errorf("failed to cancel order on Lighter: %w", err)
+	}
+	return nil
+}
+
+// positionLeverage returns the leverage of the open position in market.
+func (l *Lighter) positionLeverage(ctx context.Context, market string) (float64, error) {
+	account, err := l.account(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get the %s leverage from Lighter: %w", market, err)
+	}
+	for _, p := range account.Positions {
+		if p.Symbol+"-USD" != market {
+			continue
+		}
+		if fraction, err := strconv.ParseFloat(p.InitialMarginFraction, 64); err == nil && fraction > 0 {
+			return 100 / fraction, nil
+		}
+	}
+	return 0, fmt.Errorf("Lighter sets the margin mode together with the leverage, set a leverage for %s in LEVERAGES or with SET_LEVERAGE", market)
+}
+
+// updateLeverage submits the signed transaction setting the leverage and margin mode of market.
+func (l *Lighter) updateLeverage(ctx context.Context, market string, leverage float64, mode MarginMode) error {
+	if leverage < 1 {
+		return fmt.Errorf("invalid leverage %g for %s on Lighter", leverage, market)
+	}
+	m, err := l.market(ctx, market)
+	if err != nil {
+		return err
+	}
+	tx := LighterUpdateLeverageTx{AccountIndex: l.accountIndex, ApiKeyIndex: l.apiKeyIndex, MarketIndex: m.MarketID,
+		InitialMarginFraction: uint16(math.Round(10000 / leverage))}
+	if mode == IsolatedMargin {
+		tx.MarginMode = 1
+	}
+	if err := l.sendSignedTx(ctx, LighterTxUpdateLeverage, func(nonce int64) interface{} {
+		tx.Nonce = nonce
+		return tx
+	}); err != nil {
+		return fmt.Errorf("failed to set the %s leverage on Lighter: %w", market, err)
+	}
+	return nil
+}
+
+// sendSignedTx assigns the next nonce to the transaction built by build, signs it and submits it.
+// A rejected transaction invalidates the local nonce, since the API may not have consumed it.
+func (l *Lighter) sendSignedTx(ctx context.Context, txType int, build func(nonce int64) interface{}) error {
+	nonce, err := l.nextNonce(ctx)
+	if err != nil {
+		return err
+	}
+	txInfo, err := l.signer.Sign(ctx, txType, build(nonce))
+	if err != nil {
+		l.invalidateNonce()
+		return err
+	}
+
+	form := url.Values{"tx_type": {strconv.Itoa(txType)}, "tx_info": {txInfo}}
+	var response struct {
+		TxHash string `json:"tx_hash"`
+	}
+	if err := l.callAPI(ctx, http.MethodPost, "/api/v1/sendTx", form, &response); err != nil {
+		l.invalidateNonce()
+		return err
+	}
+	return nil
+}
+
+// callAPI makes a request to the Lighter API, sending form as a URL-encoded body if set. Lighter
+// reports failures as {"code", "message"} in the body, with or without an error status, so the
+// code is decoded along with out.
+func (l *Lighter) callAPI(ctx context.Context, method, endpoint string, form url.Values, out interface{}) error {
+	var body io.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	}
+	req, err := http.NewRequestWithContext(ctx, method, l.baseURL+endpoint, body)
+	if err != nil {
+		return err
+	}
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	response := lighterResponse{out: out}
+	if err := doJSON(l.client, req, &response); err != nil {
+		var statusErr *httpclient.StatusError
+		if errors.As(err, &statusErr) && json.Unmarshal([]byte(statusErr.Body), &response.status) == nil && response.status.failed() {
+			return &response.status
+		}
+		return err
+	}
+	if response.status.failed() {
+		return &response.status
+	}
+	return nil
+}
+
+// lighterResponse decodes a response body into out and the status code Lighter adds to it.
+type lighterResponse struct {
+	status LighterAPIError
+	out    interface{}
+}
+
+func (r *lighterResponse) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &r.status); err != nil {
+		return err
+	}
+	if r.out == nil {
+		return nil
+	}
+	return json.Unmarshal(data, r.out)
 }
@@ -0,0 +1,148 @@
+package exchange
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Lighter L2 transaction types, as expected by the sendTx endpoint.
+const (
+	LighterTxCreateOrder    = 14
+	LighterTxCancelOrder    = 15
+	LighterTxUpdateLeverage = 20
+)
+
+// LighterAuthTokenRequest is the tx_type CommandSigner sends to request an auth token instead of
+// a signed transaction.
+const LighterAuthTokenRequest = 0
+
+// LighterAuthTokenTx is the request for an auth token of an API key, valid until Deadline in Unix
+// seconds.
+type LighterAuthTokenTx struct {
+	AccountIndex int64 `json:"AccountIndex"`
+	ApiKeyIndex  uint8 `json:"ApiKeyIndex"`
+	Deadline     int64 `json:"Deadline"`
+}
+
+// LighterCreateOrderTx is an unsigned Lighter order. Amounts and prices are integers scaled by the
+// market's size and price decimals.
+type LighterCreateOrderTx struct {
+	AccountIndex     int64  `json:"AccountIndex"`
+	ApiKeyIndex      uint8  `json:"ApiKeyIndex"`
+	MarketIndex      uint8  `json:"MarketIndex"`
+	ClientOrderIndex int64  `json:"ClientOrderIndex"`
+	BaseAmount       int64  `json:"BaseAmount"`
+	Price            uint32 `json:"Price"`
+	IsAsk            uint8  `json:"IsAsk"`
+	Type             uint8  `json:"Type"`
+	TimeInForce      uint8  `json:"TimeInForce"`
+	ReduceOnly       uint8  `json:"ReduceOnly"`
+	TriggerPrice     uint32 `json:"TriggerPrice"`
+	OrderExpiry      int64  `json:"OrderExpiry"`
+	Nonce            int64  `json:"Nonce"`
+}
+
+// LighterCancelOrderTx is an unsigned cancellation of an order by its client order index.
+type LighterCancelOrderTx struct {
+	AccountIndex int64 `json:"AccountIndex"`
+	ApiKeyIndex  uint8 `json:"ApiKeyIndex"`
+	MarketIndex  uint8 `json:"MarketIndex"`
+	Index        int64 `json:"Index"`
+	Nonce        int64 `json:"Nonce"`
+}
+
+// LighterUpdateLeverageTx is an unsigned change of a market's leverage and margin mode.
+// InitialMarginFraction is in hundredths of a percent, 10000 divided by the leverage, and
+// MarginMode is 0 for cross and 1 for isolated.
+type LighterUpdateLeverageTx struct {
+	AccountIndex          int64  `json:"AccountIndex"`
+	ApiKeyIndex           uint8  `json:"ApiKeyIndex"`
+	MarketIndex           uint8  `json:"MarketIndex"`
+	InitialMarginFraction uint16 `json:"InitialMarginFraction"`
+	MarginMode            uint8  `json:"MarginMode"`
+	Nonce                 int64  `json:"Nonce"`
+}
+
+// LighterSigner signs Lighter L2 transactions with the account's API private key and returns the
+// signed transaction as the JSON tx_info submitted to sendTx. AuthToken creates the token that
+// authenticates reads of the account's orders.
+type LighterSigner interface {
+	Sign(ctx context.Context, txType int, tx interface{}) (string, error)
+	AuthToken(ctx context.Context, accountIndex int64, apiKeyIndex uint8, deadline time.Time) (string, error)
+}
+
+// CommandSigner delegates signing to an external program, such as a wrapper around Lighter's
+// reference signer. The program receives {"tx_type", "private_key", "chain_id", "tx"} as JSON on
+// stdin and must print the signed tx_info JSON on stdout, or the auth token for a tx_type of
+// LighterAuthTokenRequest.
+type CommandSigner struct {
+	command    []string
+	privateKey string
+	chainID    int
+}
+
+// NewCommandSigner creates a signer running command, split on whitespace.
+func NewCommandSigner(command, privateKey string, testnet bool) (*CommandSigner, error) {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return nil, errors.New("empty Lighter signer command")
+	}
+	chainID := 304
+	if testnet {
+		chainID = 300
+	}
+	return &CommandSigner{command: args, privateKey: privateKey, chainID: chainID}, nil
+}
+
+// Sign runs the signer program for one transaction.
+func (c *CommandSigner) Sign(ctx context.Context, txType int, tx interface{}) (string, error) {
+	txInfo, err := c.run(ctx, txType, tx)
+	if err != nil {
+		return "", err
+	}
+	if !json.Valid([]byte(txInfo)) {
+		return "", fmt.Errorf("lighter signer returned invalid tx_info: %q", txInfo)
+	}
+	return txInfo, nil
+}
+
+// AuthToken runs the signer program for an auth token valid until deadline.
+func (c *CommandSigner) AuthToken(ctx context.Context, accountIndex int64, apiKeyIndex uint8, deadline time.Time) (string, error) {
+	token, err := c.run(ctx, LighterAuthTokenRequest, LighterAuthTokenTx{AccountIndex: accountIndex, ApiKeyIndex: apiKeyIndex, Deadline: deadline.Unix()})
+	if err != nil {
+		return "", err
+	}
+	if token == "" {
+		return "", errors.New("lighter signer returned an empty auth token")
+	}
+	return token, nil
+}
+
+// run runs the signer program and returns its trimmed output.
+func (c *CommandSigner) run(ctx context.Context, txType int, tx interface{}) (string, error) {
+	input, err := json.Marshal(struct {
+		TxType     int         `json:"tx_type"`
+		PrivateKey string      `json:"private_key"`
+		ChainID    int         `json:"chain_id"`
+		Tx         interface{} `json:"tx"`
+	}{txType, c.privateKey, c.chainID, tx})
+	if err != nil {
+		return "", err
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, c.command[0], c.command[1:]...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("lighter signer failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
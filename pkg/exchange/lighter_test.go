@@ -0,0 +1,341 @@
+package exchange
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestLighterOrderbook(t *testing.T) {
+	api := newFakeAPI(t)
+	api.respond("GET", "/api/v1/orderBookDetails", http.StatusOK,
+		`{"code":200,"order_book_details":[{"symbol":"BTC","market_id":1,"size_decimals":5,"price_decimals":1,"last_trade_price":65000}]}`)
+	api.respond("GET", "/api/v1/orderBookOrders", http.StatusOK, `{"code":200,
+		"asks":[{"price":"65020","remaining_base_amount":"2"},{"price":"65010","remaining_base_amount":"0.5"}],
+		"bids":[{"price":"64990","remaining_base_amount":"1.5"}]}`)
+	ex := newTestLighter(api)
+
+	book, err := ex.GetOrderbook(context.Background(), "BTC-USD")
+	if err != nil {
+		t.Fatalf("GetOrderbook: %v", err)
+	}
+	if len(book.Bids) != 1 || book.Bids[0] != (PriceLevel{Price: 64990, Size: 1.5}) {
+		t.Errorf("unexpected bids %+v", book.Bids)
+	}
+	if len(book.Asks) != 2 || book.Asks[0].Price != 65010 {
+		t.Errorf("expected the asks sorted from the best price, got %+v", book.Asks)
+	}
+	if got := api.lastRequest("/api/v1/orderBookOrders").URL.Query().Get("market_id"); got != "1" {
+		t.Errorf("expected market_id 1, got %q", got)
+	}
+
+	api.respond("GET", "/api/v1/orderBookOrders", http.StatusInternalServerError, "upstream unavailable")
+	if _, err := ex.GetOrderbook(context.Background(), "BTC-USD"); err == nil || !strings.Contains(err.Error(), "upstream unavailable") {
+		t.Errorf("expected the error body to be surfaced, got %v", err)
+	}
+}
+
+func TestLighterRequiresSigner(t *testing.T) {
+	ex := newTestLighter(newFakeAPI(t))
+	ctx := context.Background()
+
+	if _, err := ex.PlaceOrder(ctx, "BTC-USD", Sell, Market, decimal.NewFromFloat(0.01), decimal.NewFromInt(65000)); !errors.Is(err, ErrLighterSignerRequired) {
+		t.Errorf("PlaceOrder without a signer: got %v, want ErrLighterSignerRequired", err)
+	}
+	if _, err := ex.ClosePosition(ctx, "BTC-USD", Sell, decimal.NewFromFloat(0.01)); !errors.Is(err, ErrLighterSignerRequired) {
+		t.Errorf("ClosePosition without a signer: got %v, want ErrLighterSignerRequired", err)
+	}
+	if err := ex.SetLeverage(ctx, "BTC-USD", 5); !errors.Is(err, ErrLighterSignerRequired) {
+		t.Errorf("SetLeverage without a signer: got %v, want ErrLighterSignerRequired", err)
+	}
+	if err := ex.SetMarginMode(ctx, "BTC-USD", IsolatedMargin); !errors.Is(err, ErrLighterSignerRequired) {
+		t.Errorf("SetMarginMode without a signer: got %v, want ErrLighterSignerRequired", err)
+	}
+	if err := ex.Ping(ctx); !errors.Is(err, ErrLighterSignerRequired) {
+		t.Errorf("Ping without a signer: got %v, want ErrLighterSignerRequired", err)
+	}
+}
+
+// recordingSigner returns the unsigned transaction as tx_info so tests can inspect it, and counts
+// the auth tokens it creates.
+type recordingSigner struct {
+	txs    []interface{}
+	tokens int
+}
+
+func (s *recordingSigner) AuthToken(ctx context.Context, accountIndex int64, apiKeyIndex uint8, deadline time.Time) (string, error) {
+	s.tokens++
+	return fmt.Sprintf("%d:%d:%d:sig", deadline.Unix(), accountIndex, apiKeyIndex), nil
+}
+
+func (s *recordingSigner) Sign(ctx context.Context, txType int, tx interface{}) (string, error) {
+	s.txs = append(s.txs, tx)
+	data, err := json.Marshal(tx)
+	return string(data), err
+}
+
+func TestLighterSignedOrders(t *testing.T) {
+	api := newFakeAPI(t)
+	api.respond("GET", "/api/v1/orderBookDetails", http.StatusOK,
+		`{"code":200,"order_book_details":[{"symbol":"BTC","market_id":1,"size_decimals":5,"price_decimals":1,"last_trade_price":65000}]}`)
+	api.respond("GET", "/api/v1/nextNonce", http.StatusOK, `{"code":200,"nonce":41}`)
+	api.respond("POST", "/api/v1/sendTx", http.StatusOK, `{"code":200,"tx_hash":"0xabc"}`)
+	ex := newTestLighter(api)
+	signer := &recordingSigner{}
+	ex.SetSigner(signer, 7, 2)
+
+	if _, err := ex.PlaceOrder(context.Background(), "BTC-USD", Buy, Market, decimal.NewFromFloat(0.01), decimal.NewFromInt(0)); err != nil {
+		t.Fatalf("PlaceOrder: %v", err)
+	}
+	if _, err := ex.ClosePosition(context.Background(), "BTC-USD", Buy, decimal.NewFromFloat(0.01)); err != nil {
+		t.Fatalf("ClosePosition: %v", err)
+	}
+
+	opening, closing := signer.txs[0].(LighterCreateOrderTx), signer.txs[1].(LighterCreateOrderTx)
+	if opening.Nonce != 41 || closing.Nonce != 42 {
+		t.Errorf("expected consecutive nonces 41 and 42, got %d and %d", opening.Nonce, closing.Nonce)
+	}
+	if opening.MarketIndex != 1 || opening.BaseAmount != 1000 || opening.IsAsk != 0 || opening.Price != 656500 {
+		t.Errorf("unexpected order encoding %+v", opening)
+	}
+	if closing.IsAsk != 1 || closing.ReduceOnly != 1 || closing.Price != 643500 {
+		t.Errorf("expected a reduce-only sell bounded below the last trade, got %+v", closing)
+	}
+	if got := api.lastRequest("/api/v1/sendTx"); got == nil || got.Header.Get("Content-Type") != "application/x-www-form-urlencoded" {
+		t.Errorf("expected sendTx to be called with a form body, got %v", got)
+	}
+
+	api.respond("POST", "/api/v1/sendTx", http.StatusBadRequest, `{"code":21104,"message":"invalid nonce"}`)
+	_, err := ex.PlaceOrder(context.Background(), "BTC-USD", Sell, Market, decimal.NewFromFloat(0.01), decimal.NewFromInt(65000))
+	var apiErr *LighterAPIError
+	if !errors.As(err, &apiErr) || apiErr.Code != 21104 {
+		t.Fatalf("expected the Lighter error to be parsed, got %v", err)
+	}
+	if ex.nonceValid {
+		t.Error("expected a rejected transaction to invalidate the cached nonce")
+	}
+}
+
+func TestLighterLeverageAndMarginMode(t *testing.T) {
+	api := newFakeAPI(t)
+	api.respond("GET", "/api/v1/orderBookDetails", http.StatusOK,
+		`{"code":200,"order_book_details":[{"symbol":"BTC","market_id":1,"size_decimals":5,"price_decimals":1,"last_trade_price":65000}]}`)
+	api.respond("GET", "/api/v1/nextNonce", http.StatusOK, `{"code":200,"nonce":1}`)
+	api.respond("POST", "/api/v1/sendTx", http.StatusOK, `{"code":200,"tx_hash":"0xabc"}`)
+	api.respond("GET", "/api/v1/account", http.StatusOK, `{"code":200,"accounts":[{"positions":[]}]}`)
+	ex := newTestLighter(api)
+	signer := &recordingSigner{}
+	ex.SetSigner(signer, 7, 2)
+	ctx := context.Background()
+
+	// With no leverage set and no position there is no leverage to send with the mode.
+	if err := ex.SetMarginMode(ctx, "BTC-USD", IsolatedMargin); err == nil {
+		t.Error("expected selecting the margin mode without a leverage to fail")
+	}
+	if err := ex.SetLeverage(ctx, "BTC-USD", 5); err != nil {
+		t.Fatalf("SetLeverage: %v", err)
+	}
+	if err := ex.SetMarginMode(ctx, "BTC-USD", IsolatedMargin); err != nil {
+		t.Fatalf("SetMarginMode: %v", err)
+	}
+	if len(signer.txs) != 2 {
+		t.Fatalf("expected 2 signed transactions, got %d", len(signer.txs))
+	}
+	leverage, mode := signer.txs[0].(LighterUpdateLeverageTx), signer.txs[1].(LighterUpdateLeverageTx)
+	if leverage.MarketIndex != 1 || leverage.InitialMarginFraction != 2000 || leverage.MarginMode != 0 {
+		t.Errorf("expected 5x cross margin, got %+v", leverage)
+	}
+	if mode.InitialMarginFraction != 2000 || mode.MarginMode != 1 {
+		t.Errorf("expected isolated margin at the 5x set before, got %+v", mode)
+	}
+}
+
+func TestLighterFundingRates(t *testing.T) {
+	api := newFakeAPI(t)
+	api.respond("GET", "/api/v1/funding-rates", http.StatusOK, `{"code":200,"funding_rates":[
+		{"market_id":1,"exchange":"lighter","symbol":"BTC","rate":0.0008},
+		{"market_id":1,"exchange":"binance","symbol":"BTC","rate":0.0001}]}`)
+	ex := newTestLighter(api)
+
+	rates, err := ex.GetFundingRates(context.Background())
+	if err != nil {
+		t.Fatalf("GetFundingRates: %v", err)
+	}
+	if len(rates) != 1 || rates[0].Market != "BTC-USD" {
+		t.Fatalf("expected only the Lighter BTC-USD rate, got %+v", rates)
+	}
+	if !rates[0].Rate.Equal(decimal.NewFromFloat(0.0001)) {
+		t.Errorf("expected the 8h rate to be normalized to hourly, got %s", rates[0].Rate)
+	}
+	if next := time.Unix(rates[0].NextTime, 0); next.Minute() != 0 || time.Until(next) > time.Hour {
+		t.Errorf("expected the next funding at the top of the hour, got %s", next)
+	}
+}
+
+func TestLighterPositions(t *testing.T) {
+	api := newFakeAPI(t)
+	api.respond("GET", "/api/v1/account", http.StatusOK, `{"code":200,"accounts":[{"positions":[
+		{"symbol":"BTC","sign":-1,"position":"0.0100","avg_entry_price":"60000"},
+		{"symbol":"ETH","sign":1,"position":"0.0000","avg_entry_price":"0"}]}]}`)
+	ex := newTestLighter(api)
+
+	positions, err := ex.GetPositions(context.Background())
+	if err != nil {
+		t.Fatalf("GetPositions: %v", err)
+	}
+	if len(positions) != 1 {
+		t.Fatalf("expected flat positions to be skipped, got %+v", positions)
+	}
+	if p := positions[0]; p.Market != "BTC-USD" || p.Side != Sell || p.Size != 0.01 || p.EntryPrice != 60000 {
+		t.Errorf("unexpected position %+v", p)
+	}
+}
+
+func TestLighterBalance(t *testing.T) {
+	api := newFakeAPI(t)
+	api.respond("GET", "/api/v1/account", http.StatusOK, `{"code":200,"accounts":[{"collateral":"1234.5","positions":[]}]}`)
+	ex := newTestLighter(api)
+	ctx := context.Background()
+	if _, err := ex.GetBalance(ctx, "USDC"); !errors.Is(err, ErrLighterSignerRequired) {
+		t.Errorf("GetBalance without a signer: got %v, want ErrLighterSignerRequired", err)
+	}
+	ex.SetSigner(&recordingSigner{}, 7, 2)
+
+	balance, err := ex.GetBalance(ctx, "USDC")
+	if err != nil {
+		t.Fatalf("GetBalance: %v", err)
+	}
+	if balance != 1234.5 {
+		t.Errorf("expected the account collateral, got %f", balance)
+	}
+	if got := api.lastRequest("/api/v1/account").URL.Query(); got.Get("by") != "index" || got.Get("value") != "7" {
+		t.Errorf("expected the account set with the signer to be read, got %v", got)
+	}
+	if balance, err := ex.GetBalance(ctx, "ETH"); err != nil || balance != 0 {
+		t.Errorf("GetBalance of another asset = %f, %v, want 0", balance, err)
+	}
+}
+
+func TestLighterPositionRisk(t *testing.T) {
+	api := newFakeAPI(t)
+	api.respond("GET", "/api/v1/account", http.StatusOK, `{"code":200,"accounts":[{"collateral":"500","positions":[
+		{"symbol":"BTC","sign":1,"position":"0.0100","position_value":"610","liquidation_price":"15000","allocated_margin":"0"}]}]}`)
+	ex := newTestLighter(api)
+
+	risk, err := ex.GetPositionRisk(context.Background(), "BTC-USD")
+	if err != nil {
+		t.Fatalf("GetPositionRisk: %v", err)
+	}
+	if risk.Side != Buy || risk.MarkPrice != 61000 || risk.LiquidationPrice != 15000 || risk.Margin != 500 {
+		t.Errorf("unexpected risk %+v, want the mark from the position value and the account collateral as margin", risk)
+	}
+}
+
+func TestLighterOrderStatus(t *testing.T) {
+	api := newFakeAPI(t)
+	api.respond("GET", "/api/v1/orderBookDetails", http.StatusOK,
+		`{"code":200,"order_book_details":[{"symbol":"BTC","market_id":1,"size_decimals":5,"price_decimals":1,"last_trade_price":65000}]}`)
+	api.respond("GET", "/api/v1/accountActiveOrders", http.StatusOK, `{"code":200,"orders":[
+		{"order_index":9,"client_order_index":111,"initial_base_amount":"0.02","filled_base_amount":"0.01","filled_quote_amount":"650","price":"65100","is_ask":false,"type":"limit","status":"open"}]}`)
+	api.respond("GET", "/api/v1/accountInactiveOrders", http.StatusOK, `{"code":200,"orders":[
+		{"order_index":8,"client_order_index":222,"initial_base_amount":"0.01","filled_base_amount":"0.01","filled_quote_amount":"649","price":"64000","is_ask":true,"type":"market","status":"filled"}]}`)
+	ex := newTestLighter(api)
+	ctx := context.Background()
+	if _, err := ex.GetOrderStatus(ctx, "111", "BTC-USD"); !errors.Is(err, ErrLighterSignerRequired) {
+		t.Errorf("GetOrderStatus without a signer: got %v, want ErrLighterSignerRequired", err)
+	}
+	signer := &recordingSigner{}
+	ex.SetSigner(signer, 7, 2)
+
+	open, err := ex.GetOrderStatus(ctx, "111", "BTC-USD")
+	if err != nil {
+		t.Fatalf("GetOrderStatus: %v", err)
+	}
+	if open.Status != "OPEN" || open.Side != Buy || !open.Filled.Equal(decimal.RequireFromString("0.01")) || !open.Price.Equal(decimal.NewFromInt(65000)) {
+		t.Errorf("unexpected active order %+v", open)
+	}
+	query := api.lastRequest("/api/v1/accountActiveOrders").URL.Query()
+	if query.Get("account_index") != "7" || query.Get("market_id") != "1" || !strings.HasSuffix(query.Get("auth"), ":7:2:sig") {
+		t.Errorf("expected the account's orders to be read with an auth token, got %v", query)
+	}
+
+	filled, err := ex.GetOrderStatus(ctx, "222", "BTC-USD")
+	if err != nil {
+		t.Fatalf("GetOrderStatus: %v", err)
+	}
+	if filled.Status != "FILLED" || filled.Side != Sell || !filled.Amount.Equal(decimal.RequireFromString("0.01")) {
+		t.Errorf("unexpected inactive order %+v", filled)
+	}
+	if signer.tokens != 1 {
+		t.Errorf("created %d auth tokens, want one reused for both lookups", signer.tokens)
+	}
+	if _, err := ex.GetOrderStatus(ctx, "333", "BTC-USD"); err == nil {
+		t.Error("expected an error for an unknown order")
+	}
+}
+
+func TestLighterMarketMetadataIsCached(t *testing.T) {
+	api := newFakeAPI(t)
+	api.respond("GET", "/api/v1/orderBookDetails", http.StatusOK,
+		`{"code":200,"order_book_details":[{"symbol":"BTC","market_id":1,"size_decimals":5,"price_decimals":1,"last_trade_price":65000}]}`)
+	api.respond("GET", "/api/v1/orderBookOrders", http.StatusOK, `{"code":200,"asks":[],"bids":[]}`)
+	ex := newTestLighter(api)
+	ctx := context.Background()
+	details := func() int {
+		api.mu.Lock()
+		defer api.mu.Unlock()
+		n := 0
+		for _, r := range api.requests {
+			if r.URL.Path == "/api/v1/orderBookDetails" {
+				n++
+			}
+		}
+		return n
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := ex.GetOrderbook(ctx, "BTC-USD"); err != nil {
+			t.Fatalf("GetOrderbook: %v", err)
+		}
+	}
+	if n := details(); n != 1 {
+		t.Errorf("fetched the order book details %d times for 3 order books, want once", n)
+	}
+	// The mark price is the last trade price, so it is always fetched.
+	if _, err := ex.GetMarkPrice(ctx, "BTC-USD"); err != nil {
+		t.Fatalf("GetMarkPrice: %v", err)
+	}
+	if n := details(); n != 2 {
+		t.Errorf("fetched the order book details %d times, want a refresh for the mark price", n)
+	}
+}
+
+func TestLighterDecodesLargeResponses(t *testing.T) {
+	api := newFakeAPI(t)
+	var rates strings.Builder
+	rates.WriteString(`{"code":200,"funding_rates":[`)
+	for i := 0; rates.Len() < 2<<20; i++ {
+		if i > 0 {
+			rates.WriteString(",")
+		}
+		fmt.Fprintf(&rates, `{"market_id":%d,"exchange":"binance","symbol":"SYM%d","rate":0.0001}`, i%250, i)
+	}
+	rates.WriteString(`,{"market_id":1,"exchange":"lighter","symbol":"BTC","rate":0.0008}]}`)
+	api.respond("GET", "/api/v1/funding-rates", http.StatusOK, rates.String())
+	ex := newTestLighter(api)
+
+	got, err := ex.GetFundingRates(context.Background())
+	if err != nil {
+		t.Fatalf("GetFundingRates on a %d byte response: %v", rates.Len(), err)
+	}
+	if len(got) != 1 || got[0].Market != "BTC-USD" {
+		t.Errorf("expected the Lighter rate listed after 2 MB of other venues, got %+v", got)
+	}
+}
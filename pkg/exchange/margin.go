@@ -0,0 +1,109 @@
+package exchange
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// MarginMode is how collateral backs a position. Cross margin shares the account's collateral
+// between positions; isolated margin limits each position to the margin assigned to it, so a leg
+// can be liquidated while the account still holds free collateral.
+type MarginMode string
+
+const (
+	CrossMargin    MarginMode = "CROSS"
+	IsolatedMargin MarginMode = "ISOLATED"
+)
+
+// MarginModeSetter is implemented by exchanges that let the margin mode be chosen per market.
+type MarginModeSetter interface {
+	SetMarginMode(ctx context.Context, market string, mode MarginMode) error
+}
+
+// LeverageSetter is implemented by exchanges that let the leverage be chosen per market, so a leg
+// is margined at the leverage the bot accounts for rather than at the account's default.
+type LeverageSetter interface {
+	SetLeverage(ctx context.Context, market string, leverage float64) error
+}
+
+// ParseMarginMode parses "cross" or "isolated", case-insensitively.
+func ParseMarginMode(s string) (MarginMode, error) {
+	switch mode := MarginMode(strings.ToUpper(strings.TrimSpace(s))); mode {
+	case CrossMargin, IsolatedMargin:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown margin mode %q, expected cross or isolated", s)
+	}
+}
+
+// MarginModes holds the configured margin mode per exchange, optionally overridden per market.
+type MarginModes map[string]MarginMode
+
+// ParseMarginModes parses entries of the form "EXCHANGE[:MARKET]=MODE", e.g. "Lighter=isolated"
+// or "Lighter:BTC-USD=cross".
+func ParseMarginModes(entries []string) (MarginModes, error) {
+	modes := make(MarginModes)
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		target, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid margin mode %q, expected EXCHANGE[:MARKET]=MODE", entry)
+		}
+		mode, err := ParseMarginMode(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid margin mode %q: %w", entry, err)
+		}
+		modes[strings.TrimSpace(target)] = mode
+	}
+	return modes, nil
+}
+
+// For returns the margin mode configured for market on exchangeName. A market-specific entry
+// takes precedence over the exchange-wide one.
+func (m MarginModes) For(exchangeName, market string) (MarginMode, bool) {
+	if mode, ok := m[exchangeName+":"+market]; ok {
+		return mode, true
+	}
+	mode, ok := m[exchangeName]
+	return mode, ok
+}
+
+// Leverages holds the configured leverage per exchange, optionally overridden per market.
+type Leverages map[string]float64
+
+// ParseLeverages parses entries of the form "EXCHANGE[:MARKET]=LEVERAGE", e.g. "Binance=3" or
+// "Binance:BTC-USD=5". Leverage must be at least 1.
+func ParseLeverages(entries []string) (Leverages, error) {
+	leverages := make(Leverages)
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		target, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid leverage %q, expected EXCHANGE[:MARKET]=LEVERAGE", entry)
+		}
+		leverage, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || leverage < 1 {
+			return nil, fmt.Errorf("invalid leverage %q, expected a number of at least 1", entry)
+		}
+		leverages[strings.TrimSpace(target)] = leverage
+	}
+	return leverages, nil
+}
+
+// For returns the leverage configured for market on exchangeName. A market-specific entry takes
+// precedence over the exchange-wide one.
+func (l Leverages) For(exchangeName, market string) (float64, bool) {
+	if leverage, ok := l[exchangeName+":"+market]; ok {
+		return leverage, true
+	}
+	leverage, ok := l[exchangeName]
+	return leverage, ok
+}
@@ -0,0 +1,45 @@
+package exchange
+
+import "testing"
+
+func TestParseMarginModes(t *testing.T) {
+	modes, err := ParseMarginModes([]string{"Lighter=isolated", " Lighter:BTC-USD = Cross "})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode, _ := modes.For("Lighter", "ETH-USD"); mode != IsolatedMargin {
+		t.Errorf("expected the exchange-wide mode for ETH-USD, got %q", mode)
+	}
+	if mode, _ := modes.For("Lighter", "BTC-USD"); mode != CrossMargin {
+		t.Errorf("expected the market override for BTC-USD, got %q", mode)
+	}
+	if _, ok := modes.For("Extended", "BTC-USD"); ok {
+		t.Error("expected no mode for an unconfigured exchange")
+	}
+
+	if _, err := ParseMarginModes([]string{"Lighter=portfolio"}); err == nil {
+		t.Error("expected an unknown margin mode to be rejected")
+	}
+}
+
+func TestParseLeverages(t *testing.T) {
+	leverages, err := ParseLeverages([]string{"Binance=3", " Binance:BTC-USD = 5 "})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if leverage, _ := leverages.For("Binance", "ETH-USD"); leverage != 3 {
+		t.Errorf("expected the exchange-wide leverage for ETH-USD, got %g", leverage)
+	}
+	if leverage, _ := leverages.For("Binance", "BTC-USD"); leverage != 5 {
+		t.Errorf("expected the market override for BTC-USD, got %g", leverage)
+	}
+	if _, ok := leverages.For("OKX", "BTC-USD"); ok {
+		t.Error("expected no leverage for an unconfigured exchange")
+	}
+
+	for _, entry := range []string{"Binance", "Binance=high", "Binance=0.5"} {
+		if _, err := ParseLeverages([]string{entry}); err == nil {
+			t.Errorf("expected %q to be rejected", entry)
+		}
+	}
+}
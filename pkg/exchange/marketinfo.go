@@ -0,0 +1,76 @@
+package exchange
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// MarketInfo holds the trading rules of a market. Amounts are in the base asset, also on venues
+// that trade contracts. A zero value means the venue doesn't list the rule.
+type MarketInfo struct {
+	Market string
+	// TickSize and LotSize are the steps prices and amounts are rounded to.
+	TickSize decimal.Decimal
+	LotSize  decimal.Decimal
+	// MinSize is the smallest amount and MinNotional the smallest value in USD of an order.
+	MinSize     decimal.Decimal
+	MinNotional float64
+	MaxLeverage float64
+	// ContractMultiplier is the base amount of one contract, 1 on venues that trade the base asset.
+	ContractMultiplier float64
+}
+
+// ErrMarketInfoUnsupported is returned by GetMarketInfo on wrappers whose exchange doesn't list
+// the trading rules of its markets.
+var ErrMarketInfoUnsupported = errors.New("market rules are not listed")
+
+// MarketInfoProvider is implemented by exchanges that list the trading rules of their markets.
+// Sizing both legs to a multiple of both lots keeps the hedge from being rounded apart, and
+// checking the minimums before submitting keeps one leg from being rejected after the other filled.
+type MarketInfoProvider interface {
+	GetMarketInfo(ctx context.Context, market string) (*MarketInfo, error)
+}
+
+// RoundPrice rounds price to the nearest tick.
+func (i *MarketInfo) RoundPrice(price float64) float64 {
+	if !i.TickSize.IsPositive() {
+		return price
+	}
+	return decimal.NewFromFloat(price).Div(i.TickSize).Round(0).Mul(i.TickSize).InexactFloat64()
+}
+
+// Validate checks an order of amount at price against the minimum size and notional, and the
+// leverage it is opened with against the maximum. A zero leverage isn't checked.
+func (i *MarketInfo) Validate(amount decimal.Decimal, price, leverage float64) error {
+	if i.MinSize.IsPositive() && amount.LessThan(i.MinSize) {
+		return fmt.Errorf("%s %s is below the minimum order size of %s", amount, i.Market, i.MinSize)
+	}
+	if notional := amount.InexactFloat64() * price; i.MinNotional > 0 && notional < i.MinNotional {
+		return fmt.Errorf("%.2f USD of %s is below the minimum order value of %.2f USD", notional, i.Market, i.MinNotional)
+	}
+	if i.MaxLeverage > 0 && leverage > i.MaxLeverage {
+		return fmt.Errorf("leverage %gx on %s is above the maximum of %gx", leverage, i.Market, i.MaxLeverage)
+	}
+	return nil
+}
+
+// parseDecimal parses a rule, rate or amount as venues list it, returning zero if it is missing or
+// malformed.
+func parseDecimal(s string) decimal.Decimal {
+	d, err := decimal.NewFromString(strings.TrimSpace(s))
+	if err != nil {
+		return decimal.Zero
+	}
+	return d
+}
+
+// parseRule parses a numeric rule, returning zero if it is missing or malformed.
+func parseRule(s string) float64 {
+	v, _ := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	return v
+}
@@ -0,0 +1,672 @@
+package exchange
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/httpclient"
+)
+
+// OKXBaseURL serves both live and demo trading; demo requests are marked with a header.
+const OKXBaseURL = "https://www.okx.com"
+
+const (
+	// okxQuoteAsset is the margin and quote asset of the perpetual swaps the bot trades.
+	okxQuoteAsset = "USDT"
+	// okxSwapSuffix turns a market into the ID of its USDT perpetual swap, e.g. BTC-USDT-SWAP.
+	okxSwapSuffix = "-" + okxQuoteAsset + "-SWAP"
+	// okxFundingInterval is the funding interval rates are quoted in. Swaps that settle more
+	// often have their rates scaled to it.
+	okxFundingInterval = 8 * time.Hour
+	// okxTradeMode trades from the cross-margined account, which margins every swap in USDT.
+	okxTradeMode = "cross"
+	// okxOrderbookDepth is the number of price levels requested on each side of the book.
+	okxOrderbookDepth = 400
+)
+
+// OKX is the implementation for OKX USDT-margined perpetual swaps. Markets such as "BTC-USD" trade
+// as the "BTC-USDT-SWAP" contract, cross-margined from a single-currency or multi-currency
+// account in net (one-way) position mode. OKX sizes orders and positions in contracts of a fixed
+// amount of the base asset; the client converts them so amounts stay in the base asset. On
+// testnet, requests go to OKX demo trading.
+type OKX struct {
+	client     *http.Client
+	apiKey     string
+	secretKey  string
+	passphrase string
+	baseURL    string
+	testnet    bool
+
+	mu          sync.Mutex
+	instruments map[string]okxInstrument
+}
+
+// NewOKX creates a new OKX client for an API key, its secret and passphrase.
+func NewOKX(apiKey, secretKey, passphrase string, testnet bool) *OKX {
+	return &OKX{
+		client:     &http.Client{Timeout: 10 * time.Second},
+		apiKey:     apiKey,
+		secretKey:  secretKey,
+		passphrase: passphrase,
+		baseURL:    OKXBaseURL,
+		testnet:    testnet,
+	}
+}
+
+// SetRateLimiter makes every OKX API call wait for limiter.
+func (o *OKX) SetRateLimiter(limiter *httpclient.Limiter) {
+	o.client = limiter.Client(o.client)
+}
+
+func (o *OKX) Name() string {
+	return "OKX"
+}
+
+// FundingInterval returns the interval funding rates are quoted in. Some swaps settle every 4,
+// 2 or 1 hours instead; GetFundingRates scales their rates to this interval.
+func (o *OKX) FundingInterval() time.Duration {
+	return okxFundingInterval
+}
+
+func (o *OKX) CollateralAsset() string {
+	return okxQuoteAsset
+}
+
+// SetTestnet switches between live and demo trading.
+func (o *OKX) SetTestnet(testnet bool) {
+	o.testnet = testnet
+}
+
+// SetTransport replaces the HTTP transport used for REST requests, e.g. with a recorder in tests.
+func (o *OKX) SetTransport(rt http.RoundTripper) {
+	o.client.Transport = rt
+}
+
+// Symbol converts "BTC-USD" into "BTC-USDT-SWAP".
+func (o *OKX) Symbol(market string) string {
+	base, _, _ := strings.Cut(market, "-")
+	return strings.ToUpper(base) + okxSwapSuffix
+}
+
+// market converts a USDT swap ID back into a market name. It reports false for other contracts,
+// such as coin-margined swaps.
+func (o *OKX) market(instID string) (string, bool) {
+	base, ok := strings.CutSuffix(instID, okxSwapSuffix)
+	if !ok || base == "" {
+		return "", false
+	}
+	return base + "-USD", true
+}
+
+// okxInstrument holds the contract size and trading rules of a swap. Lot sizes are in contracts.
+type okxInstrument struct {
+	contractValue decimal.Decimal
+	lotSize       decimalStep
+	tickSize      decimalStep
+	minSize       decimal.Decimal
+	maxLeverage   float64
+}
+
+// loadInstruments returns the USDT swaps, keyed by instrument ID. They are fetched on first use
+// and cached.
+func (o *OKX) loadInstruments(ctx context.Context) (map[string]okxInstrument, error) {
+	o.mu.Lock()
+	instruments := o.instruments
+	o.mu.Unlock()
+	if instruments != nil {
+		return instruments, nil
+	}
+	var response []struct {
+		InstID    string `json:"instId"`
+		CtVal     string `json:"ctVal"`
+		LotSz     string `json:"lotSz"`
+		TickSz    string `json:"tickSz"`
+		MinSz     string `json:"minSz"`
+		Lever     string `json:"lever"`
+		SettleCcy string `json:"settleCcy"`
+	}
+	if err := o.sendRequest(ctx, "GET", "/api/v5/public/instruments", url.Values{"instType": {"SWAP"}}, nil, false, &response); err != nil {
+		return nil, fmt.Errorf("failed to get instruments from OKX: %w", err)
+	}
+	instruments = make(map[string]okxInstrument)
+	for _, i := range response {
+		contractValue := parseDecimal(i.CtVal)
+		if i.SettleCcy != okxQuoteAsset || !contractValue.IsPositive() {
+			continue
+		}
+		instruments[i.InstID] = okxInstrument{
+			contractValue: contractValue,
+			lotSize:       parseDecimalStep(i.LotSz),
+			tickSize:      parseDecimalStep(i.TickSz),
+			minSize:       parseDecimal(i.MinSz),
+			maxLeverage:   parseRule(i.Lever),
+		}
+	}
+	o.mu.Lock()
+	o.instruments = instruments
+	o.mu.Unlock()
+	return instruments, nil
+}
+
+// instrument returns the contract size and trading rules of the swap for market.
+func (o *OKX) instrument(ctx context.Context, market string) (okxInstrument, error) {
+	instruments, err := o.loadInstruments(ctx)
+	if err != nil {
+		return okxInstrument{}, err
+	}
+	instrument, ok := instruments[o.Symbol(market)]
+	if !ok {
+		return okxInstrument{}, fmt.Errorf("market %s not found on OKX", market)
+	}
+	return instrument, nil
+}
+
+// OKXFundingRate is the funding of a swap. FundingTime is when the current rate is paid and
+// NextFundingTime the settlement after it, both in milliseconds; their difference is the
+// interval the swap settles on.
+type OKXFundingRate struct {
+	InstID          string `json:"instId"`
+	FundingRate     string `json:"fundingRate"`
+	FundingTime     string `json:"fundingTime"`
+	NextFundingTime string `json:"nextFundingTime"`
+	// NextFundingRate is the predicted rate of the following interval. OKX leaves it empty for
+	// most swaps.
+	NextFundingRate string `json:"nextFundingRate"`
+}
+
+// GetFundingRates fetches the funding rate of every USDT swap with the time it is paid, quoted
+// per 8 hours whatever interval the swap settles on.
+func (o *OKX) GetFundingRates(ctx context.Context) ([]*FundingRate, error) {
+	var response []OKXFundingRate
+	if err := o.sendRequest(ctx, "GET", "/api/v5/public/funding-rate", url.Values{"instId": {"ANY"}}, nil, false, &response); err != nil {
+		return nil, fmt.Errorf("failed to get funding rates from OKX: %w", err)
+	}
+	var fundingRates []*FundingRate
+	for _, r := range response {
+		market, ok := o.market(r.InstID)
+		if !ok {
+			continue
+		}
+		rate, err := decimal.NewFromString(r.FundingRate)
+		if err != nil {
+			continue
+		}
+		predicted, predictedErr := decimal.NewFromString(r.NextFundingRate)
+		fundingTime, _ := strconv.ParseInt(r.FundingTime, 10, 64)
+		nextFundingTime, _ := strconv.ParseInt(r.NextFundingTime, 10, 64)
+		if interval := time.Duration(nextFundingTime-fundingTime) * time.Millisecond; fundingTime > 0 && interval > 0 {
+			rate = rate.Mul(rateScale(okxFundingInterval, interval))
+			predicted = predicted.Mul(rateScale(okxFundingInterval, interval))
+		}
+		fundingRates = append(fundingRates, &FundingRate{Market: market, Rate: rate, NextTime: fundingTime / 1000,
+			PredictedRate: predicted, HasPredicted: predictedErr == nil})
+	}
+	return fundingRates, nil
+}
+
+// GetMarkPrice returns the mark price of market.
+func (o *OKX) GetMarkPrice(ctx context.Context, market string) (float64, error) {
+	var response []struct {
+		MarkPx string `json:"markPx"`
+	}
+	params := url.Values{"instType": {"SWAP"}, "instId": {o.Symbol(market)}}
+	if err := o.sendRequest(ctx, "GET", "/api/v5/public/mark-price", params, nil, false, &response); err != nil {
+		return 0, fmt.Errorf("failed to get mark price from OKX: %w", err)
+	}
+	if len(response) == 0 {
+		return 0, fmt.Errorf("market %s not found on OKX", market)
+	}
+	price, err := strconv.ParseFloat(response[0].MarkPx, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse mark price for %s from OKX: %w", market, err)
+	}
+	return price, nil
+}
+
+// GetOrderbook returns the order book of market, with sizes converted from contracts.
+func (o *OKX) GetOrderbook(ctx context.Context, market string) (*Orderbook, error) {
+	instrument, err := o.instrument(ctx, market)
+	if err != nil {
+		return nil, err
+	}
+	// Levels are [price, contracts, deprecated, number of orders].
+	var response []struct {
+		Bids [][]string `json:"bids"`
+		Asks [][]string `json:"asks"`
+	}
+	params := url.Values{"instId": {o.Symbol(market)}, "sz": {strconv.Itoa(okxOrderbookDepth)}}
+	if err := o.sendRequest(ctx, "GET", "/api/v5/market/books", params, nil, false, &response); err != nil {
+		return nil, fmt.Errorf("failed to get orderbook from OKX: %w", err)
+	}
+	if len(response) == 0 {
+		return nil, fmt.Errorf("no orderbook for %s on OKX", market)
+	}
+	bids, err := okxLevels(response[0].Bids, instrument.contractValue.InexactFloat64())
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s orderbook from OKX: %w", market, err)
+	}
+	asks, err := okxLevels(response[0].Asks, instrument.contractValue.InexactFloat64())
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s orderbook from OKX: %w", market, err)
+	}
+	return &Orderbook{Market: market, Bids: bids, Asks: asks}, nil
+}
+
+func okxLevels(levels [][]string, contractValue float64) ([]PriceLevel, error) {
+	parsed := make([]PriceLevel, 0, len(levels))
+	for _, l := range levels {
+		if len(l) < 2 {
+			return nil, fmt.Errorf("malformed level %v", l)
+		}
+		level, err := parseLevel(l[0], l[1])
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, PriceLevel{Price: level.Price, Size: level.Size * contractValue})
+	}
+	return parsed, nil
+}
+
+// okxOrderRequest is the body of an order placed on /api/v5/trade/order. Sz is in contracts.
+type okxOrderRequest struct {
+	InstID     string `json:"instId"`
+	TdMode     string `json:"tdMode"`
+	Side       string `json:"side"`
+	OrdType    string `json:"ordType"`
+	Sz         string `json:"sz"`
+	Px         string `json:"px,omitempty"`
+	ReduceOnly bool   `json:"reduceOnly,omitempty"`
+}
+
+// PlaceOrder sends a signed order, converting amount into contracts rounded down to the lot size
+// and rounding a limit price to the tick size. Limit orders rest until cancelled. OKX only
+// acknowledges the order, so it is returned as NEW; its fills are reported by GetOrderStatus.
+func (o *OKX) PlaceOrder(ctx context.Context, market string, side OrderSide, orderType OrderType, amount, price decimal.Decimal) (*Order, error) {
+	return o.placeOrder(ctx, market, side, orderType, amount, price, false)
+}
+
+func (o *OKX) placeOrder(ctx context.Context, market string, side OrderSide, orderType OrderType, amount, price decimal.Decimal, reduceOnly bool) (*Order, error) {
+	instrument, err := o.instrument(ctx, market)
+	if err != nil {
+		return nil, err
+	}
+	contracts := instrument.lotSize.floor(amount.Div(instrument.contractValue))
+	if !contracts.IsPositive() {
+		return nil, fmt.Errorf("order amount %s is below one OKX lot of %s for %s", amount, instrument.lotSize.step.Mul(instrument.contractValue), market)
+	}
+
+	request := okxOrderRequest{
+		InstID:     o.Symbol(market),
+		TdMode:     okxTradeMode,
+		Side:       strings.ToLower(string(side)),
+		OrdType:    "market",
+		Sz:         instrument.lotSize.format(contracts),
+		ReduceOnly: reduceOnly,
+	}
+	if orderType == Limit {
+		price = instrument.tickSize.round(price)
+		request.OrdType = "limit"
+		request.Px = instrument.tickSize.format(price)
+	} else {
+		price = decimal.Zero
+	}
+	var response []struct {
+		OrdID string `json:"ordId"`
+		SCode string `json:"sCode"`
+		SMsg  string `json:"sMsg"`
+	}
+	if err := o.sendRequest(ctx, "POST", "/api/v5/trade/order", nil, request, true, &response); err != nil {
+		return nil, fmt.Errorf("failed to place order on OKX: %w", err)
+	}
+	if len(response) == 0 {
+		return nil, fmt.Errorf("failed to place order on OKX: empty response")
+	}
+	if response[0].SCode != "0" {
+		return nil, fmt.Errorf("failed to place order on OKX: code %s - %s", response[0].SCode, response[0].SMsg)
+	}
+	return &Order{
+		ID:        response[0].OrdID,
+		Market:    market,
+		Side:      side,
+		Type:      orderType,
+		Price:     price,
+		Amount:    contracts.Mul(instrument.contractValue),
+		Status:    "NEW",
+		Timestamp: time.Now().Unix(),
+	}, nil
+}
+
+// SetLeverage sets the leverage of market in the cross-margined account the bot trades from.
+func (o *OKX) SetLeverage(ctx context.Context, market string, leverage float64) error {
+	request := map[string]string{"instId": o.Symbol(market), "lever": strconv.FormatFloat(leverage, 'f', -1, 64), "mgnMode": okxTradeMode}
+	if err := o.sendRequest(ctx, "POST", "/api/v5/account/set-leverage", nil, request, true, nil); err != nil {
+		return fmt.Errorf("failed to set %gx leverage for %s on OKX: %w", leverage, market, err)
+	}
+	return nil
+}
+
+// ServerTime returns the time of the OKX server.
+func (o *OKX) ServerTime(ctx context.Context) (time.Time, error) {
+	var response []struct {
+		Ts string `json:"ts"`
+	}
+	if err := o.sendRequest(ctx, "GET", "/api/v5/public/time", nil, nil, false, &response); err != nil {
+		return time.Time{}, fmt.Errorf("failed to get the OKX server time: %w", err)
+	}
+	if len(response) == 0 {
+		return time.Time{}, fmt.Errorf("failed to get the OKX server time: empty response")
+	}
+	millis, err := strconv.ParseInt(response[0].Ts, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse the OKX server time %q: %w", response[0].Ts, err)
+	}
+	return time.UnixMilli(millis), nil
+}
+
+// GetMarketInfo returns the trading rules of the swap for market. OKX trades contracts, so the lot
+// and minimum sizes are converted to the base asset.
+func (o *OKX) GetMarketInfo(ctx context.Context, market string) (*MarketInfo, error) {
+	instrument, err := o.instrument(ctx, market)
+	if err != nil {
+		return nil, err
+	}
+	contractValue := instrument.contractValue
+	return &MarketInfo{
+		Market:             market,
+		TickSize:           instrument.tickSize.step,
+		LotSize:            instrument.lotSize.step.Mul(contractValue),
+		MinSize:            instrument.minSize.Mul(contractValue),
+		MaxLeverage:        instrument.maxLeverage,
+		ContractMultiplier: contractValue.InexactFloat64(),
+	}, nil
+}
+
+// OKXOrder is an order as reported by the order endpoint. Sizes are in contracts, and State is
+// live, partially_filled, filled or canceled.
+type OKXOrder struct {
+	OrdID     string `json:"ordId"`
+	Side      string `json:"side"`
+	OrdType   string `json:"ordType"`
+	Px        string `json:"px"`
+	AvgPx     string `json:"avgPx"`
+	Sz        string `json:"sz"`
+	AccFillSz string `json:"accFillSz"`
+	State     string `json:"state"`
+	CTime     string `json:"cTime"`
+}
+
+// order converts the response into an Order on market, with sizes converted from contracts and
+// the state upper-cased, e.g. "FILLED".
+func (oo OKXOrder) order(market string, contractValue decimal.Decimal) *Order {
+	price := parseDecimal(oo.AvgPx)
+	if price.IsZero() {
+		price = parseDecimal(oo.Px)
+	}
+	contracts := parseDecimal(oo.Sz)
+	filled := parseDecimal(oo.AccFillSz)
+	created, _ := strconv.ParseInt(oo.CTime, 10, 64)
+	return &Order{
+		ID:        oo.OrdID,
+		Market:    market,
+		Side:      OrderSide(strings.ToUpper(oo.Side)),
+		Type:      OrderType(strings.ToUpper(oo.OrdType)),
+		Price:     price,
+		Amount:    contracts.Mul(contractValue),
+		Filled:    filled.Mul(contractValue),
+		Status:    strings.ToUpper(oo.State),
+		Timestamp: created / 1000,
+	}
+}
+
+func (o *OKX) GetOrderStatus(ctx context.Context, orderID string, market string) (*Order, error) {
+	instrument, err := o.instrument(ctx, market)
+	if err != nil {
+		return nil, err
+	}
+	var response []OKXOrder
+	params := url.Values{"instId": {o.Symbol(market)}, "ordId": {orderID}}
+	if err := o.sendRequest(ctx, "GET", "/api/v5/trade/order", params, nil, true, &response); err != nil {
+		return nil, fmt.Errorf("failed to get order status from OKX: %w", err)
+	}
+	if len(response) == 0 {
+		return nil, fmt.Errorf("order %s not found on OKX", orderID)
+	}
+	return response[0].order(market, instrument.contractValue), nil
+}
+
+func (o *OKX) CancelOrder(ctx context.Context, orderID string, market string) error {
+	request := map[string]string{"instId": o.Symbol(market), "ordId": orderID}
+	if err := o.sendRequest(ctx, "POST", "/api/v5/trade/cancel-order", nil, request, true, nil); err != nil {
+		return fmt.Errorf("failed to cancel order on OKX: %w", err)
+	}
+	return nil
+}
+
+// ClosePosition reduces the position in market with a reduce-only market order on the opposite
+// side, so it can never flip the position.
+func (o *OKX) ClosePosition(ctx context.Context, market string, side OrderSide, amount decimal.Decimal) (*Order, error) {
+	closeSide := Sell
+	if side == Sell {
+		closeSide = Buy
+	}
+	return o.placeOrder(ctx, market, closeSide, Market, amount, decimal.Zero, true)
+}
+
+// GetBalance returns the equity of asset in the trading account: its balance plus the
+// unrealized PnL of the positions margined in it.
+func (o *OKX) GetBalance(ctx context.Context, asset string) (float64, error) {
+	summary, err := o.summary(ctx, asset)
+	if err != nil {
+		return 0, err
+	}
+	return summary.Equity, nil
+}
+
+// GetAccountSummary reports the USDT equity of the trading account, the part available for new
+// orders and the part frozen as margin of positions and orders.
+func (o *OKX) GetAccountSummary(ctx context.Context) (*AccountSummary, error) {
+	return o.summary(ctx, okxQuoteAsset)
+}
+
+// summary reads the balance of asset, which is zero if the account holds none.
+func (o *OKX) summary(ctx context.Context, asset string) (*AccountSummary, error) {
+	if asset == "" {
+		asset = okxQuoteAsset
+	}
+	var response []struct {
+		Details []struct {
+			Ccy       string `json:"ccy"`
+			Eq        string `json:"eq"`
+			AvailBal  string `json:"availBal"`
+			FrozenBal string `json:"frozenBal"`
+		} `json:"details"`
+	}
+	if err := o.sendRequest(ctx, "GET", "/api/v5/account/balance", url.Values{"ccy": {strings.ToUpper(asset)}}, nil, true, &response); err != nil {
+		return nil, fmt.Errorf("failed to get balance from OKX: %w", err)
+	}
+	summary := &AccountSummary{Asset: strings.ToUpper(asset)}
+	for _, account := range response {
+		for _, detail := range account.Details {
+			if !strings.EqualFold(detail.Ccy, asset) {
+				continue
+			}
+			equity, err := strconv.ParseFloat(detail.Eq, 64)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse balance float from OKX: %w", err)
+			}
+			summary.Equity = equity
+			summary.Free, _ = strconv.ParseFloat(detail.AvailBal, 64)
+			summary.Used, _ = strconv.ParseFloat(detail.FrozenBal, 64)
+			return summary, nil
+		}
+	}
+	return summary, nil
+}
+
+// OKXPosition is a position as listed by the positions endpoint. In net mode Pos is in contracts
+// and negative for shorts.
+type OKXPosition struct {
+	InstID  string `json:"instId"`
+	Pos     string `json:"pos"`
+	AvgPx   string `json:"avgPx"`
+	MarkPx  string `json:"markPx"`
+	LiqPx   string `json:"liqPx"`
+	MgnMode string `json:"mgnMode"`
+	Margin  string `json:"margin"`
+}
+
+// positions fetches the open USDT swap positions of the account, or only the one in market if
+// it is set.
+func (o *OKX) positions(ctx context.Context, market string) ([]OKXPosition, error) {
+	params := url.Values{"instType": {"SWAP"}}
+	if market != "" {
+		params.Set("instId", o.Symbol(market))
+	}
+	var response []OKXPosition
+	if err := o.sendRequest(ctx, "GET", "/api/v5/account/positions", params, nil, true, &response); err != nil {
+		return nil, err
+	}
+	var open []OKXPosition
+	for _, p := range response {
+		if _, ok := o.market(p.InstID); !ok {
+			continue
+		}
+		if pos, _ := strconv.ParseFloat(p.Pos, 64); pos != 0 {
+			open = append(open, p)
+		}
+	}
+	return open, nil
+}
+
+// GetPositions fetches the open positions of the account, with sizes converted from contracts.
+func (o *OKX) GetPositions(ctx context.Context) ([]Position, error) {
+	listed, err := o.positions(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get positions from OKX: %w", err)
+	}
+	instruments, err := o.loadInstruments(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var positions []Position
+	for _, p := range listed {
+		market, _ := o.market(p.InstID)
+		pos, err := strconv.ParseFloat(p.Pos, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse position size for %s from OKX: %w", market, err)
+		}
+		side := Buy
+		if pos < 0 {
+			side = Sell
+		}
+		entry, _ := strconv.ParseFloat(p.AvgPx, 64)
+		positions = append(positions, Position{Market: market, Side: side, Size: math.Abs(pos) * instruments[p.InstID].contractValue.InexactFloat64(), EntryPrice: entry})
+	}
+	return positions, nil
+}
+
+// GetPositionRisk fetches the margin and liquidation price of the open position in market.
+// Isolated positions are backed by their own margin, cross-margined ones by the account equity.
+func (o *OKX) GetPositionRisk(ctx context.Context, market string) (*PositionRisk, error) {
+	listed, err := o.positions(ctx, market)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the %s position from OKX: %w", market, err)
+	}
+	if len(listed) == 0 {
+		return nil, fmt.Errorf("no open %s position on OKX", market)
+	}
+	instrument, err := o.instrument(ctx, market)
+	if err != nil {
+		return nil, err
+	}
+	p := listed[0]
+	pos, err := strconv.ParseFloat(p.Pos, 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse position size for %s from OKX: %w", market, err)
+	}
+	side := Buy
+	if pos < 0 {
+		side = Sell
+	}
+	mark, err := strconv.ParseFloat(p.MarkPx, 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse mark price for %s from OKX: %w", market, err)
+	}
+	liquidation, _ := strconv.ParseFloat(p.LiqPx, 64)
+	var margin float64
+	if p.MgnMode == "isolated" {
+		margin, _ = strconv.ParseFloat(p.Margin, 64)
+	} else if margin, err = o.GetBalance(ctx, okxQuoteAsset); err != nil {
+		return nil, err
+	}
+	return &PositionRisk{Market: market, Side: side, Size: math.Abs(pos) * instrument.contractValue.InexactFloat64(), MarkPrice: mark, LiquidationPrice: liquidation, Margin: margin}, nil
+}
+
+// sendRequest sends a request to the OKX v5 API and decodes the data of the response into out.
+// GET requests carry params in the query string, POST requests carry body as JSON. Signed
+// requests are authenticated with a base64 HMAC-SHA256 of the timestamp, method, path with query
+// and body. A non-zero code is returned as an error.
+func (o *OKX) sendRequest(ctx context.Context, method, endpoint string, params url.Values, body interface{}, signed bool, out interface{}) error {
+	path := endpoint
+	if len(params) > 0 {
+		path += "?" + params.Encode()
+	}
+	var payload []byte
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		payload = encoded
+		reader = bytes.NewReader(encoded)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, o.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if o.testnet {
+		req.Header.Set("x-simulated-trading", "1")
+	}
+	if signed {
+		timestamp := time.Now().UTC().Format("2006-01-02T15:04:05.000Z")
+		mac := hmac.New(sha256.New, []byte(o.secretKey))
+		mac.Write([]byte(timestamp + method + path + string(payload)))
+		req.Header.Set("OK-ACCESS-KEY", o.apiKey)
+		req.Header.Set("OK-ACCESS-PASSPHRASE", o.passphrase)
+		req.Header.Set("OK-ACCESS-TIMESTAMP", timestamp)
+		req.Header.Set("OK-ACCESS-SIGN", base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+	}
+
+	var response struct {
+		Code string          `json:"code"`
+		Msg  string          `json:"msg"`
+		Data json.RawMessage `json:"data"`
+	}
+	if err := doJSON(o.client, req, &response); err != nil {
+		return err
+	}
+	if response.Code != "0" {
+		return fmt.Errorf("API error: code %s - %s", response.Code, response.Msg)
+	}
+	if out == nil || len(response.Data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(response.Data, out)
+}
@@ -0,0 +1,164 @@
+package exchange
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+// okxInstruments lists BTC with contracts of 0.01 BTC and DOGE with contracts of 1000 DOGE.
+const okxInstruments = `{"code":"0","msg":"","data":[
+	{"instId":"BTC-USDT-SWAP","ctVal":"0.01","lotSz":"0.1","tickSz":"0.1","minSz":"0.1","lever":"100","settleCcy":"USDT"},
+	{"instId":"DOGE-USDT-SWAP","ctVal":"1000","lotSz":"1","tickSz":"0.00001","settleCcy":"USDT"},
+	{"instId":"BTC-USD-SWAP","ctVal":"100","lotSz":"1","tickSz":"0.1","settleCcy":"BTC"}]}`
+
+func TestOKXFundingRates(t *testing.T) {
+	api := newFakeAPI(t)
+	api.respond("GET", "/api/v5/public/funding-rate", http.StatusOK, `{"code":"0","msg":"","data":[
+		{"instId":"BTC-USDT-SWAP","fundingRate":"0.0001","fundingTime":"1700006400000","nextFundingTime":"1700035200000","nextFundingRate":"0.00015"},
+		{"instId":"DOGE-USDT-SWAP","fundingRate":"0.0002","fundingTime":"1700006400000","nextFundingTime":"1700020800000"},
+		{"instId":"BTC-USD-SWAP","fundingRate":"0.0003","fundingTime":"1700006400000","nextFundingTime":"1700035200000"}]}`)
+	ex := newTestOKX(api)
+
+	rates, err := ex.GetFundingRates(context.Background())
+	if err != nil {
+		t.Fatalf("GetFundingRates: %v", err)
+	}
+	if len(rates) != 2 {
+		t.Fatalf("expected only the USDT swaps, got %d rates", len(rates))
+	}
+	if rates[0].Market != "BTC-USD" || !rates[0].Rate.Equal(decimal.NewFromFloat(0.0001)) || rates[0].NextTime != 1700006400 || !rates[0].PredictedRate.Equal(decimal.NewFromFloat(0.00015)) || !rates[0].HasPredicted {
+		t.Errorf("unexpected BTC rate %+v", rates[0])
+	}
+	if r := rates[1]; r.Market != "DOGE-USD" || !r.Rate.Equal(decimal.NewFromFloat(0.0004)) || r.HasPredicted {
+		t.Errorf("expected the 4-hourly DOGE rate scaled to 8 hours, got %+v", r)
+	}
+	if got := api.lastRequest("/api/v5/public/funding-rate").Header.Get("x-simulated-trading"); got != "1" {
+		t.Errorf("expected testnet requests to go to demo trading, got header %q", got)
+	}
+}
+
+func TestOKXSignedOrdersInContracts(t *testing.T) {
+	api := newFakeAPI(t)
+	api.respond("GET", "/api/v5/public/instruments", http.StatusOK, okxInstruments)
+	var body []byte
+	var headers http.Header
+	api.handle("POST", "/api/v5/trade/order", func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		headers = r.Header.Clone()
+		_, _ = w.Write([]byte(`{"code":"0","msg":"","data":[{"ordId":"312269865356374016","sCode":"0","sMsg":""}]}`))
+	})
+	ex := newTestOKX(api)
+
+	order, err := ex.PlaceOrder(context.Background(), "BTC-USD", Buy, Limit, decimal.NewFromFloat(0.01234), decimal.NewFromFloat(64999.87))
+	if err != nil {
+		t.Fatalf("PlaceOrder: %v", err)
+	}
+	if order.ID != "312269865356374016" || order.Status != "NEW" || !order.Amount.Equal(decimal.NewFromFloat(0.012)) {
+		t.Errorf("unexpected order %+v", order)
+	}
+	var request map[string]interface{}
+	if err := json.Unmarshal(body, &request); err != nil {
+		t.Fatalf("order body is not JSON: %v", err)
+	}
+	if request["instId"] != "BTC-USDT-SWAP" || request["tdMode"] != "cross" || request["side"] != "buy" || request["ordType"] != "limit" ||
+		request["sz"] != "1.2" || request["px"] != "64999.9" {
+		t.Errorf("unexpected order request %s", body)
+	}
+	mac := hmac.New(sha256.New, []byte("test-secret"))
+	mac.Write([]byte(headers.Get("OK-ACCESS-TIMESTAMP") + "POST/api/v5/trade/order" + string(body)))
+	if headers.Get("OK-ACCESS-KEY") != "test-key" || headers.Get("OK-ACCESS-PASSPHRASE") != "test-pass" ||
+		headers.Get("OK-ACCESS-SIGN") != base64.StdEncoding.EncodeToString(mac.Sum(nil)) {
+		t.Errorf("expected a base64 HMAC-SHA256 signature over the timestamp, method, path and body, got %v", headers)
+	}
+
+	if _, err := ex.ClosePosition(context.Background(), "BTC-USD", Buy, decimal.NewFromFloat(0.012)); err != nil {
+		t.Fatalf("ClosePosition: %v", err)
+	}
+	if err := json.Unmarshal(body, &request); err != nil || request["side"] != "sell" || request["ordType"] != "market" || request["reduceOnly"] != true {
+		t.Errorf("expected a reduce-only market sell, got %s", body)
+	}
+
+	api.respond("POST", "/api/v5/trade/order", http.StatusOK, `{"code":"1","msg":"","data":[{"ordId":"","sCode":"51008","sMsg":"Insufficient balance"}]}`)
+	if _, err := ex.PlaceOrder(context.Background(), "BTC-USD", Buy, Market, decimal.NewFromFloat(0.01), decimal.NewFromInt(0)); err == nil {
+		t.Error("expected a rejected order to be an error")
+	}
+	if _, err := ex.PlaceOrder(context.Background(), "DOGE-USD", Buy, Market, decimal.NewFromInt(500), decimal.NewFromInt(0)); err == nil {
+		t.Error("expected an amount below one contract to be refused")
+	}
+}
+
+func TestOKXMarketInfoInBaseAsset(t *testing.T) {
+	api := newFakeAPI(t)
+	api.respond("GET", "/api/v5/public/instruments", http.StatusOK, okxInstruments)
+	ex := newTestOKX(api)
+
+	info, err := ex.GetMarketInfo(context.Background(), "BTC-USD")
+	if err != nil {
+		t.Fatalf("GetMarketInfo: %v", err)
+	}
+	// 0.1 contracts of 0.01 BTC.
+	if info.LotSize.String() != "0.001" || info.MinSize.String() != "0.001" || info.TickSize.String() != "0.1" {
+		t.Errorf("lot %s, min %s, tick %s, want 0.001, 0.001 and 0.1", info.LotSize, info.MinSize, info.TickSize)
+	}
+	if info.ContractMultiplier != 0.01 || info.MaxLeverage != 100 {
+		t.Errorf("multiplier %v, max leverage %v, want 0.01 and 100", info.ContractMultiplier, info.MaxLeverage)
+	}
+	if err := info.Validate(info.MinSize.Div(decimal.NewFromInt(2)), 65000, 10); err == nil {
+		t.Error("expected half the minimum size to be rejected")
+	}
+	if err := info.Validate(info.MinSize, 65000, 125); err == nil {
+		t.Error("expected a leverage above the maximum to be rejected")
+	}
+	if got := info.RoundPrice(65000.04); got != 65000 {
+		t.Errorf("RoundPrice(65000.04) = %v, want 65000", got)
+	}
+}
+
+func TestOKXPositionsAndOrders(t *testing.T) {
+	api := newFakeAPI(t)
+	api.respond("GET", "/api/v5/public/instruments", http.StatusOK, okxInstruments)
+	api.respond("GET", "/api/v5/account/positions", http.StatusOK, `{"code":"0","msg":"","data":[
+		{"instId":"DOGE-USDT-SWAP","pos":"-3","avgPx":"0.08","markPx":"0.1","liqPx":"0.12","mgnMode":"isolated","margin":"45.5"},
+		{"instId":"BTC-USDT-SWAP","pos":"0","avgPx":"","markPx":"65000","liqPx":"","mgnMode":"cross","margin":""}]}`)
+	api.respond("GET", "/api/v5/trade/order", http.StatusOK, `{"code":"0","msg":"","data":[
+		{"ordId":"7","side":"sell","ordType":"limit","px":"0.1","avgPx":"0.1","sz":"3","accFillSz":"2","state":"partially_filled","cTime":"1700000000000"}]}`)
+	api.respond("GET", "/api/v5/account/balance", http.StatusOK, `{"code":"0","msg":"","data":[{"details":[{"ccy":"USDT","eq":"1234.5","availBal":"1000","frozenBal":"234.5"}]}]}`)
+	ex := newTestOKX(api)
+
+	positions, err := ex.GetPositions(context.Background())
+	if err != nil {
+		t.Fatalf("GetPositions: %v", err)
+	}
+	if len(positions) != 1 || positions[0] != (Position{Market: "DOGE-USD", Side: Sell, Size: 3000, EntryPrice: 0.08}) {
+		t.Errorf("expected the DOGE short in DOGE, got %+v", positions)
+	}
+	risk, err := ex.GetPositionRisk(context.Background(), "DOGE-USD")
+	if err != nil {
+		t.Fatalf("GetPositionRisk: %v", err)
+	}
+	if distance, ok := risk.LiquidationDistance(); risk.Margin != 45.5 || !ok || math.Abs(distance-0.2) > 1e-9 {
+		t.Errorf("expected the isolated margin and a 20%% distance, got %+v (distance %f)", risk, distance)
+	}
+	status, err := ex.GetOrderStatus(context.Background(), "7", "DOGE-USD")
+	if err != nil {
+		t.Fatalf("GetOrderStatus: %v", err)
+	}
+	if !status.Amount.Equal(decimal.NewFromInt(3000)) || !status.Filled.Equal(decimal.NewFromInt(2000)) || status.Status != "PARTIALLY_FILLED" || status.Side != Sell {
+		t.Errorf("unexpected status %+v", status)
+	}
+	if balance, err := ex.GetBalance(context.Background(), "USDT"); err != nil || balance != 1234.5 {
+		t.Errorf("GetBalance = %f, %v, want 1234.5", balance, err)
+	}
+	if summary, err := ex.GetAccountSummary(context.Background()); err != nil || *summary != (AccountSummary{Asset: "USDT", Equity: 1234.5, Free: 1000, Used: 234.5}) {
+		t.Errorf("GetAccountSummary = %+v, %v", summary, err)
+	}
+}
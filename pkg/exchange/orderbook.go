@@ -0,0 +1,82 @@
+package exchange
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// ErrOrderbookUnsupported is returned by GetOrderbook on venues that don't serve an order book,
+// such as simulated ones.
+var ErrOrderbookUnsupported = errors.New("order book is not available")
+
+// PriceLevel is the resting size at one price of an order book, in the base asset.
+type PriceLevel struct {
+	Price float64
+	Size  float64
+}
+
+// Orderbook is a snapshot of a market's order book. Bids are sorted from the highest price down
+// and asks from the lowest price up.
+type Orderbook struct {
+	Market string
+	Bids   []PriceLevel
+	Asks   []PriceLevel
+}
+
+// ImpactBps returns how far, in basis points of the best price, a market order on side for
+// notionalUSD would move through the book: a buy walks up the asks and a sell down the bids. It
+// reports false when the book is too thin to fill the order.
+func (b *Orderbook) ImpactBps(side OrderSide, notionalUSD float64) (float64, bool) {
+	levels := b.Asks
+	if side == Sell {
+		levels = b.Bids
+	}
+	if len(levels) == 0 || levels[0].Price <= 0 {
+		return 0, false
+	}
+	best := levels[0].Price
+	remaining := notionalUSD
+	for _, level := range levels {
+		remaining -= level.Price * level.Size
+		if remaining <= 0 {
+			return math.Abs(level.Price-best) / best * 10000, true
+		}
+	}
+	return 0, false
+}
+
+// parseLevel converts a price and size as venues report them into a price level.
+func parseLevel(price, size string) (PriceLevel, error) {
+	p, err := strconv.ParseFloat(price, 64)
+	if err != nil {
+		return PriceLevel{}, fmt.Errorf("invalid price %q: %w", price, err)
+	}
+	sz, err := strconv.ParseFloat(size, 64)
+	if err != nil {
+		return PriceLevel{}, fmt.Errorf("invalid size %q: %w", size, err)
+	}
+	return PriceLevel{Price: p, Size: sz}, nil
+}
+
+// parseLevels converts levels listed as [price, size] string pairs into price levels.
+func parseLevels(levels [][2]string) ([]PriceLevel, error) {
+	parsed := make([]PriceLevel, 0, len(levels))
+	for _, l := range levels {
+		level, err := parseLevel(l[0], l[1])
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, level)
+	}
+	return parsed, nil
+}
+
+// sortLevels orders the bids from the highest price down and the asks from the lowest price up,
+// for venues that don't guarantee the order.
+func (b *Orderbook) sortLevels() {
+	sort.SliceStable(b.Bids, func(i, j int) bool { return b.Bids[i].Price > b.Bids[j].Price })
+	sort.SliceStable(b.Asks, func(i, j int) bool { return b.Asks[i].Price < b.Asks[j].Price })
+}
@@ -0,0 +1,29 @@
+package exchange
+
+import (
+	"math"
+	"testing"
+)
+
+func TestOrderbookImpact(t *testing.T) {
+	book := &Orderbook{
+		Bids: []PriceLevel{{Price: 100, Size: 10}, {Price: 99, Size: 10}},
+		Asks: []PriceLevel{{Price: 101, Size: 10}, {Price: 102, Size: 10}},
+	}
+
+	if impact, ok := book.ImpactBps(Buy, 500); !ok || impact != 0 {
+		t.Errorf("expected a buy within the best ask to have no impact, got %f, %v", impact, ok)
+	}
+	if impact, ok := book.ImpactBps(Buy, 1500); !ok || math.Abs(impact-1/101.0*10000) > 1e-9 {
+		t.Errorf("expected a buy into the second ask to move %f bps, got %f, %v", 1/101.0*10000, impact, ok)
+	}
+	if impact, ok := book.ImpactBps(Sell, 1500); !ok || impact != 100 {
+		t.Errorf("expected a sell into the second bid to move 100 bps, got %f, %v", impact, ok)
+	}
+	if _, ok := book.ImpactBps(Sell, 5000); ok {
+		t.Error("expected a sell larger than the book to be reported as unfillable")
+	}
+	if _, ok := (&Orderbook{}).ImpactBps(Buy, 1); ok {
+		t.Error("expected an empty book to be reported as unfillable")
+	}
+}
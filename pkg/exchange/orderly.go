@@ -0,0 +1,557 @@
+package exchange
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/httpclient"
+)
+
+const (
+	OrderlyMainnetBaseURL = "https://api.orderly.org"
+	OrderlyTestnetBaseURL = "https://testnet-api.orderly.org"
+)
+
+const (
+	// orderlyFundingInterval is the period Orderly funding rates are quoted over and paid.
+	orderlyFundingInterval = 8 * time.Hour
+	// orderlyKeyPrefix marks the curve of Orderly keys in their text form.
+	orderlyKeyPrefix = "ed25519:"
+)
+
+// ErrOrderlyKeyRequired is returned by the account and order methods of an Orderly client without
+// an Orderly key, since every private request is signed with it.
+var ErrOrderlyKeyRequired = errors.New("orderly account requests need ORDERLY_ACCOUNT_ID and ORDERLY_SECRET_KEY")
+
+// Orderly is the implementation for Orderly Network perpetuals. Orderly is shared liquidity: an
+// account is registered through a broker, such as a front-end built on Orderly, and its ID already
+// identifies the broker, so the same client trades for any of them. Markets such as "BTC-USD"
+// trade as the "PERP_BTC_USDC" contract, margined in USDC. Private requests are signed with the
+// account's Orderly key, an ed25519 key registered for it.
+type Orderly struct {
+	client    *http.Client
+	accountID string
+	key       ed25519.PrivateKey
+	publicKey string
+	baseURL   string
+	testnet   bool
+
+	mu      sync.Mutex
+	symbols map[string]orderlySymbol
+}
+
+// NewOrderly creates a new Orderly client for the account, signing with the Orderly secret key
+// in its text form, the base58 of the ed25519 seed with or without the "ed25519:" prefix. A key
+// that can't be decoded leaves the client without account access.
+func NewOrderly(accountID, secretKey string, testnet bool) *Orderly {
+	baseURL := OrderlyMainnetBaseURL
+	if testnet {
+		baseURL = OrderlyTestnetBaseURL
+	}
+	o := &Orderly{
+		client:    &http.Client{Timeout: 10 * time.Second},
+		accountID: accountID,
+		baseURL:   baseURL,
+		testnet:   testnet,
+	}
+	if seed, err := base58Decode(strings.TrimPrefix(secretKey, orderlyKeyPrefix)); err == nil && len(seed) == ed25519.SeedSize {
+		o.setKey(ed25519.NewKeyFromSeed(seed))
+	}
+	return o
+}
+
+// setKey sets the Orderly key private requests are signed with.
+func (o *Orderly) setKey(key ed25519.PrivateKey) {
+	o.key = key
+	o.publicKey = orderlyKeyPrefix + base58Encode(key.Public().(ed25519.PublicKey))
+}
+
+// SetRateLimiter makes every Orderly API call wait for limiter.
+func (o *Orderly) SetRateLimiter(limiter *httpclient.Limiter) {
+	o.client = limiter.Client(o.client)
+}
+
+func (o *Orderly) Name() string {
+	return "Orderly"
+}
+
+// FundingInterval returns the period Orderly funding rates are quoted over.
+func (o *Orderly) FundingInterval() time.Duration {
+	return orderlyFundingInterval
+}
+
+func (o *Orderly) CollateralAsset() string {
+	return "USDC"
+}
+
+func (o *Orderly) SetTestnet(testnet bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.testnet = testnet
+	if testnet {
+		o.baseURL = OrderlyTestnetBaseURL
+	} else {
+		o.baseURL = OrderlyMainnetBaseURL
+	}
+	o.symbols = nil
+}
+
+// SetTransport replaces the HTTP transport used for REST requests, e.g. with a recorder in tests.
+func (o *Orderly) SetTransport(rt http.RoundTripper) {
+	o.client.Transport = rt
+}
+
+// Symbol converts "BTC-USD" into "PERP_BTC_USDC".
+func (o *Orderly) Symbol(market string) string {
+	base, _, _ := strings.Cut(market, "-")
+	return "PERP_" + strings.ToUpper(base) + "_USDC"
+}
+
+// market converts a contract symbol back into a market name. It reports false for symbols that
+// are not USDC perpetuals.
+func (o *Orderly) market(symbol string) (string, bool) {
+	base, ok := strings.CutPrefix(symbol, "PERP_")
+	if !ok {
+		return "", false
+	}
+	base, ok = strings.CutSuffix(base, "_USDC")
+	if !ok || base == "" {
+		return "", false
+	}
+	return base + "-USD", true
+}
+
+// OrderlyFundingRate is the funding of a contract. EstFundingRate is the rate of the coming
+// 8-hour period and NextFundingTime is in milliseconds.
+type OrderlyFundingRate struct {
+	Symbol          string          `json:"symbol"`
+	EstFundingRate  decimal.Decimal `json:"est_funding_rate"`
+	NextFundingTime int64           `json:"next_funding_time"`
+}
+
+// GetFundingRates fetches the estimated funding rate of every perpetual, quoted per 8 hours.
+func (o *Orderly) GetFundingRates(ctx context.Context) ([]*FundingRate, error) {
+	var response struct {
+		Rows []OrderlyFundingRate `json:"rows"`
+	}
+	if err := o.sendRequest(ctx, "GET", "/v1/public/funding_rates", nil, nil, false, &response); err != nil {
+		return nil, fmt.Errorf("failed to get funding rates from Orderly: %w", err)
+	}
+	var fundingRates []*FundingRate
+	for _, row := range response.Rows {
+		market, ok := o.market(row.Symbol)
+		if !ok {
+			continue
+		}
+		fundingRates = append(fundingRates, &FundingRate{Market: market, Rate: row.EstFundingRate, NextTime: row.NextFundingTime / 1000})
+	}
+	return fundingRates, nil
+}
+
+// GetMarkPrice returns the mark price of market.
+func (o *Orderly) GetMarkPrice(ctx context.Context, market string) (float64, error) {
+	var response struct {
+		MarkPrice float64 `json:"mark_price"`
+	}
+	if err := o.sendRequest(ctx, "GET", "/v1/public/futures/"+o.Symbol(market), nil, nil, false, &response); err != nil {
+		return 0, fmt.Errorf("failed to get mark price from Orderly: %w", err)
+	}
+	if response.MarkPrice <= 0 {
+		return 0, fmt.Errorf("no mark price for %s on Orderly", market)
+	}
+	return response.MarkPrice, nil
+}
+
+// orderlyLevel is an order book level. Orderly serves prices and quantities as JSON numbers.
+type orderlyLevel struct {
+	Price    float64 `json:"price"`
+	Quantity float64 `json:"quantity"`
+}
+
+// GetOrderbook returns the order book of market. Orderly only serves it to signed requests.
+func (o *Orderly) GetOrderbook(ctx context.Context, market string) (*Orderbook, error) {
+	var response struct {
+		Bids []orderlyLevel `json:"bids"`
+		Asks []orderlyLevel `json:"asks"`
+	}
+	if err := o.sendRequest(ctx, "GET", "/v1/orderbook/"+o.Symbol(market), url.Values{"max_level": {"100"}}, nil, true, &response); err != nil {
+		return nil, fmt.Errorf("failed to get orderbook from Orderly: %w", err)
+	}
+	book := &Orderbook{Market: market, Bids: make([]PriceLevel, 0, len(response.Bids)), Asks: make([]PriceLevel, 0, len(response.Asks))}
+	for _, l := range response.Bids {
+		book.Bids = append(book.Bids, PriceLevel{Price: l.Price, Size: l.Quantity})
+	}
+	for _, l := range response.Asks {
+		book.Asks = append(book.Asks, PriceLevel{Price: l.Price, Size: l.Quantity})
+	}
+	book.sortLevels()
+	return book, nil
+}
+
+// GetMarketInfo returns the trading rules of market.
+func (o *Orderly) GetMarketInfo(ctx context.Context, market string) (*MarketInfo, error) {
+	rules, err := o.symbolRules(ctx, market)
+	if err != nil {
+		return nil, err
+	}
+	return &MarketInfo{
+		Market:             market,
+		TickSize:           rules.quoteTick.step,
+		LotSize:            rules.baseTick.step,
+		MinSize:            rules.baseMin,
+		MinNotional:        rules.minNotional,
+		ContractMultiplier: 1,
+	}, nil
+}
+
+// orderlySymbol holds the trading rules of a perpetual.
+type orderlySymbol struct {
+	baseTick    decimalStep
+	quoteTick   decimalStep
+	baseMin     decimal.Decimal
+	minNotional float64
+}
+
+// symbolRules returns the trading rules of market. The rules of every market are fetched on
+// first use and cached.
+func (o *Orderly) symbolRules(ctx context.Context, market string) (orderlySymbol, error) {
+	o.mu.Lock()
+	symbols := o.symbols
+	o.mu.Unlock()
+	if symbols == nil {
+		var response struct {
+			Rows []struct {
+				Symbol      string      `json:"symbol"`
+				BaseTick    json.Number `json:"base_tick"`
+				QuoteTick   json.Number `json:"quote_tick"`
+				BaseMin     json.Number `json:"base_min"`
+				MinNotional json.Number `json:"min_notional"`
+			} `json:"rows"`
+		}
+		if err := o.sendRequest(ctx, "GET", "/v1/public/info", nil, nil, false, &response); err != nil {
+			return orderlySymbol{}, fmt.Errorf("failed to get symbols from Orderly: %w", err)
+		}
+		symbols = make(map[string]orderlySymbol, len(response.Rows))
+		for _, s := range response.Rows {
+			symbols[s.Symbol] = orderlySymbol{
+				baseTick:    parseDecimalStep(s.BaseTick.String()),
+				quoteTick:   parseDecimalStep(s.QuoteTick.String()),
+				baseMin:     parseDecimal(s.BaseMin.String()),
+				minNotional: parseRule(s.MinNotional.String()),
+			}
+		}
+		o.mu.Lock()
+		o.symbols = symbols
+		o.mu.Unlock()
+	}
+	rules, ok := symbols[o.Symbol(market)]
+	if !ok {
+		return orderlySymbol{}, fmt.Errorf("market %s not found on Orderly", market)
+	}
+	return rules, nil
+}
+
+// OrderlyOrder is an order as reported by the order endpoint. Orderly reports NEW,
+// PARTIAL_FILLED, FILLED, CANCELLED and REJECTED; CreatedTime is in milliseconds.
+type OrderlyOrder struct {
+	OrderID              int64           `json:"order_id"`
+	Side                 string          `json:"side"`
+	Type                 string          `json:"type"`
+	Quantity             decimal.Decimal `json:"quantity"`
+	Executed             decimal.Decimal `json:"executed"`
+	Price                decimal.Decimal `json:"price"`
+	AverageExecutedPrice decimal.Decimal `json:"average_executed_price"`
+	Status               string          `json:"status"`
+	CreatedTime          int64           `json:"created_time"`
+}
+
+// order converts the response into an Order on market.
+func (oo OrderlyOrder) order(market string) *Order {
+	price := oo.AverageExecutedPrice
+	if price.IsZero() {
+		price = oo.Price
+	}
+	return &Order{
+		ID:        strconv.FormatInt(oo.OrderID, 10),
+		Market:    market,
+		Side:      OrderSide(oo.Side),
+		Type:      OrderType(oo.Type),
+		Price:     price,
+		Amount:    oo.Quantity,
+		Filled:    oo.Executed,
+		Status:    oo.Status,
+		Timestamp: oo.CreatedTime / 1000,
+	}
+}
+
+// PlaceOrder sends an order, rounding amount down to the market's base tick and a limit price
+// to its quote tick. Limit orders rest until cancelled; market orders fill against the book.
+func (o *Orderly) PlaceOrder(ctx context.Context, market string, side OrderSide, orderType OrderType, amount, price decimal.Decimal) (*Order, error) {
+	return o.placeOrder(ctx, market, side, orderType, amount, price, false)
+}
+
+func (o *Orderly) placeOrder(ctx context.Context, market string, side OrderSide, orderType OrderType, amount, price decimal.Decimal, reduceOnly bool) (*Order, error) {
+	rules, err := o.symbolRules(ctx, market)
+	if err != nil {
+		return nil, err
+	}
+	quantity := rules.baseTick.floor(amount)
+	if !quantity.IsPositive() {
+		return nil, fmt.Errorf("order amount %s is below the Orderly base tick %s for %s", amount, rules.baseTick, market)
+	}
+	request := map[string]interface{}{
+		"symbol":         o.Symbol(market),
+		"side":           string(side),
+		"order_type":     string(orderType),
+		"order_quantity": json.Number(rules.baseTick.format(quantity)),
+		"reduce_only":    reduceOnly,
+	}
+	if orderType == Limit {
+		request["order_price"] = json.Number(rules.quoteTick.format(rules.quoteTick.round(price)))
+	}
+	var response struct {
+		OrderID int64 `json:"order_id"`
+	}
+	if err := o.sendRequest(ctx, "POST", "/v1/order", nil, request, true, &response); err != nil {
+		return nil, fmt.Errorf("failed to place order on Orderly: %w", err)
+	}
+	if orderType == Limit {
+		price = rules.quoteTick.round(price)
+	} else {
+		price = decimal.Zero
+	}
+	return &Order{
+		ID:        strconv.FormatInt(response.OrderID, 10),
+		Market:    market,
+		Side:      side,
+		Type:      orderType,
+		Price:     price,
+		Amount:    quantity,
+		Status:    "NEW",
+		Timestamp: time.Now().Unix(),
+	}, nil
+}
+
+func (o *Orderly) GetOrderStatus(ctx context.Context, orderID string, market string) (*Order, error) {
+	var response OrderlyOrder
+	if err := o.sendRequest(ctx, "GET", "/v1/order/"+url.PathEscape(orderID), nil, nil, true, &response); err != nil {
+		return nil, fmt.Errorf("failed to get order status from Orderly: %w", err)
+	}
+	return response.order(market), nil
+}
+
+func (o *Orderly) CancelOrder(ctx context.Context, orderID string, market string) error {
+	params := url.Values{"order_id": {orderID}, "symbol": {o.Symbol(market)}}
+	if err := o.sendRequest(ctx, "DELETE", "/v1/order", params, nil, true, nil); err != nil {
+		return fmt.Errorf("failed to cancel order on Orderly: %w", err)
+	}
+	return nil
+}
+
+// ClosePosition reduces the position in market with a reduce-only market order on the opposite
+// side, so it can never flip the position.
+func (o *Orderly) ClosePosition(ctx context.Context, market string, side OrderSide, amount decimal.Decimal) (*Order, error) {
+	closeSide := Sell
+	if side == Sell {
+		closeSide = Buy
+	}
+	return o.placeOrder(ctx, market, closeSide, Market, amount, decimal.Zero, true)
+}
+
+// OrderlyPosition is a position as listed by the positions endpoint. PositionQty is negative for
+// shorts.
+type OrderlyPosition struct {
+	Symbol           string  `json:"symbol"`
+	PositionQty      float64 `json:"position_qty"`
+	AverageOpenPrice float64 `json:"average_open_price"`
+	MarkPrice        float64 `json:"mark_price"`
+	EstLiqPrice      float64 `json:"est_liq_price"`
+}
+
+// orderlyPositions is the response of the positions endpoint, which also values the collateral.
+type orderlyPositions struct {
+	TotalCollateralValue float64           `json:"total_collateral_value"`
+	Rows                 []OrderlyPosition `json:"rows"`
+}
+
+func (o *Orderly) fetchPositions(ctx context.Context) (*orderlyPositions, error) {
+	var response orderlyPositions
+	if err := o.sendRequest(ctx, "GET", "/v1/positions", nil, nil, true, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// GetBalance returns the total collateral value of the account, its USDC holding plus the
+// unrealized PnL of the open positions. Orderly margins every market in USDC, so asset is only
+// checked against it.
+func (o *Orderly) GetBalance(ctx context.Context, asset string) (float64, error) {
+	if asset != "" && !strings.EqualFold(asset, o.CollateralAsset()) {
+		return 0, nil
+	}
+	positions, err := o.fetchPositions(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get balance from Orderly: %w", err)
+	}
+	return positions.TotalCollateralValue, nil
+}
+
+// GetPositions fetches the open positions of the account.
+func (o *Orderly) GetPositions(ctx context.Context) ([]Position, error) {
+	response, err := o.fetchPositions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get positions from Orderly: %w", err)
+	}
+	var positions []Position
+	for _, p := range response.Rows {
+		market, ok := o.market(p.Symbol)
+		if !ok || p.PositionQty == 0 {
+			continue
+		}
+		side := Buy
+		if p.PositionQty < 0 {
+			side = Sell
+		}
+		positions = append(positions, Position{Market: market, Side: side, Size: math.Abs(p.PositionQty), EntryPrice: p.AverageOpenPrice})
+	}
+	sort.Slice(positions, func(i, j int) bool { return positions[i].Market < positions[j].Market })
+	return positions, nil
+}
+
+// GetPositionRisk fetches the estimated liquidation price of the open position in market. Orderly
+// accounts are cross-margined, so the position is backed by the total collateral value.
+func (o *Orderly) GetPositionRisk(ctx context.Context, market string) (*PositionRisk, error) {
+	response, err := o.fetchPositions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the %s position from Orderly: %w", market, err)
+	}
+	for _, p := range response.Rows {
+		if p.Symbol != o.Symbol(market) || p.PositionQty == 0 {
+			continue
+		}
+		side := Buy
+		if p.PositionQty < 0 {
+			side = Sell
+		}
+		return &PositionRisk{Market: market, Side: side, Size: math.Abs(p.PositionQty), MarkPrice: p.MarkPrice, LiquidationPrice: p.EstLiqPrice, Margin: response.TotalCollateralValue}, nil
+	}
+	return nil, fmt.Errorf("no open %s position on Orderly", market)
+}
+
+// sendRequest sends a request to the Orderly API, with params in the query string and body as
+// JSON, and decodes the data of the response. Private requests are signed with the Orderly key
+// over the millisecond timestamp, method, path with query and body, as base64url.
+func (o *Orderly) sendRequest(ctx context.Context, method, endpoint string, params url.Values, body interface{}, private bool, out interface{}) error {
+	if private && (o.key == nil || o.accountID == "") {
+		return ErrOrderlyKeyRequired
+	}
+	path := endpoint
+	if len(params) > 0 {
+		path += "?" + params.Encode()
+	}
+	var payload []byte
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		payload = encoded
+		reader = bytes.NewReader(encoded)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, o.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	} else {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	if private {
+		timestamp := strconv.FormatInt(time.Now().UnixMilli(), 10)
+		signature := ed25519.Sign(o.key, []byte(timestamp+method+path+string(payload)))
+		req.Header.Set("orderly-account-id", o.accountID)
+		req.Header.Set("orderly-key", o.publicKey)
+		req.Header.Set("orderly-timestamp", timestamp)
+		req.Header.Set("orderly-signature", base64.URLEncoding.EncodeToString(signature))
+	}
+
+	var response struct {
+		Success bool            `json:"success"`
+		Code    int             `json:"code"`
+		Message string          `json:"message"`
+		Data    json.RawMessage `json:"data"`
+	}
+	if err := doJSON(o.client, req, &response); err != nil {
+		return err
+	}
+	if !response.Success {
+		return fmt.Errorf("API error: code %d - %s", response.Code, response.Message)
+	}
+	if out == nil || len(response.Data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(response.Data, out)
+}
+
+// base58Alphabet is the Bitcoin base58 alphabet Orderly writes keys in.
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// base58Encode encodes b in base58, keeping leading zero bytes as leading ones.
+func base58Encode(b []byte) string {
+	n := new(big.Int).SetBytes(b)
+	radix := big.NewInt(58)
+	mod := new(big.Int)
+	var encoded []byte
+	for n.Sign() > 0 {
+		n.DivMod(n, radix, mod)
+		encoded = append(encoded, base58Alphabet[mod.Int64()])
+	}
+	for _, c := range b {
+		if c != 0 {
+			break
+		}
+		encoded = append(encoded, base58Alphabet[0])
+	}
+	for i, j := 0, len(encoded)-1; i < j; i, j = i+1, j-1 {
+		encoded[i], encoded[j] = encoded[j], encoded[i]
+	}
+	return string(encoded)
+}
+
+// base58Decode decodes a base58 string encoded by base58Encode.
+func base58Decode(s string) ([]byte, error) {
+	n := new(big.Int)
+	radix := big.NewInt(58)
+	for _, c := range s {
+		digit := strings.IndexRune(base58Alphabet, c)
+		if digit < 0 {
+			return nil, fmt.Errorf("invalid base58 character %q", c)
+		}
+		n.Mul(n, radix)
+		n.Add(n, big.NewInt(int64(digit)))
+	}
+	zeros := 0
+	for zeros < len(s) && s[zeros] == base58Alphabet[0] {
+		zeros++
+	}
+	return append(make([]byte, zeros), n.Bytes()...), nil
+}
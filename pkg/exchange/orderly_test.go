@@ -0,0 +1,141 @@
+package exchange
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+// orderlyTestKey is a fixed Orderly key so signatures can be verified.
+var orderlyTestKey = ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize))
+
+const orderlyInfo = `{"success":true,"data":{"rows":[
+	{"symbol":"PERP_BTC_USDC","base_tick":0.00001,"quote_tick":0.1},
+	{"symbol":"PERP_ETH_USDC","base_tick":0.0001,"quote_tick":0.01}]}}`
+
+func TestOrderlyFundingRates(t *testing.T) {
+	api := newFakeAPI(t)
+	api.respond("GET", "/v1/public/funding_rates", http.StatusOK, `{"success":true,"data":{"rows":[
+		{"symbol":"PERP_BTC_USDC","est_funding_rate":0.0001,"next_funding_time":1700006400000},
+		{"symbol":"SPOT_BTC_USDC","est_funding_rate":0,"next_funding_time":0}]}}`)
+	api.respond("GET", "/v1/public/futures/PERP_BTC_USDC", http.StatusOK, `{"success":true,"data":{"symbol":"PERP_BTC_USDC","mark_price":65000.5}}`)
+	ex := newTestOrderly(api, nil)
+
+	rates, err := ex.GetFundingRates(context.Background())
+	if err != nil {
+		t.Fatalf("GetFundingRates: %v", err)
+	}
+	if len(rates) != 1 || rates[0].Market != "BTC-USD" || !rates[0].Rate.Equal(decimal.NewFromFloat(0.0001)) || rates[0].NextTime != 1700006400 {
+		t.Errorf("expected only the BTC perpetual, got %+v", rates)
+	}
+	if price, err := ex.GetMarkPrice(context.Background(), "BTC-USD"); err != nil || price != 65000.5 {
+		t.Errorf("GetMarkPrice = %f, %v, want 65000.5", price, err)
+	}
+	if FundingIntervalOf(ex).Hours() != 8 {
+		t.Errorf("expected an 8-hour funding interval, got %s", FundingIntervalOf(ex))
+	}
+	if _, err := ex.GetPositions(context.Background()); !errors.Is(err, ErrOrderlyKeyRequired) {
+		t.Errorf("expected ErrOrderlyKeyRequired without a key, got %v", err)
+	}
+}
+
+func TestOrderlySignedOrders(t *testing.T) {
+	api := newFakeAPI(t)
+	api.respond("GET", "/v1/public/info", http.StatusOK, orderlyInfo)
+	var body []byte
+	var headers http.Header
+	api.handle("POST", "/v1/order", func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		headers = r.Header.Clone()
+		_, _ = w.Write([]byte(`{"success":true,"data":{"order_id":13,"order_type":"LIMIT"}}`))
+	})
+	api.respond("DELETE", "/v1/order", http.StatusOK, `{"success":true,"data":{"status":"CANCEL_SENT"}}`)
+	ex := newTestOrderly(api, orderlyTestKey)
+
+	order, err := ex.PlaceOrder(context.Background(), "ETH-USD", Sell, Limit, decimal.NewFromFloat(1.23456), decimal.NewFromFloat(3500.016))
+	if err != nil {
+		t.Fatalf("PlaceOrder: %v", err)
+	}
+	if order.ID != "13" || !order.Amount.Equal(decimal.NewFromFloat(1.2345)) || !order.Price.Equal(decimal.NewFromFloat(3500.02)) || order.Status != "NEW" {
+		t.Errorf("unexpected order %+v", order)
+	}
+	if got := string(body); !strings.Contains(got, `"order_quantity":1.2345`) || !strings.Contains(got, `"order_price":3500.02`) ||
+		!strings.Contains(got, `"symbol":"PERP_ETH_USDC"`) || !strings.Contains(got, `"side":"SELL"`) {
+		t.Errorf("unexpected order request %s", got)
+	}
+	signature, err := base64.URLEncoding.DecodeString(headers.Get("orderly-signature"))
+	if err != nil || !ed25519.Verify(orderlyTestKey.Public().(ed25519.PublicKey), []byte(headers.Get("orderly-timestamp")+"POST/v1/order"+string(body)), signature) {
+		t.Errorf("expected an ed25519 signature over the timestamp, method, path and body, got %v", headers)
+	}
+	if headers.Get("orderly-account-id") != "0xaccount" || !strings.HasPrefix(headers.Get("orderly-key"), "ed25519:") {
+		t.Errorf("unexpected auth headers %v", headers)
+	}
+
+	if _, err := ex.ClosePosition(context.Background(), "ETH-USD", Sell, decimal.NewFromFloat(1.2345)); err != nil {
+		t.Fatalf("ClosePosition: %v", err)
+	}
+	var request map[string]interface{}
+	if err := json.Unmarshal(body, &request); err != nil || request["side"] != "BUY" || request["order_type"] != "MARKET" ||
+		request["reduce_only"] != true || request["order_price"] != nil {
+		t.Errorf("expected a reduce-only market buy without a price, got %s", body)
+	}
+
+	if err := ex.CancelOrder(context.Background(), "13", "ETH-USD"); err != nil {
+		t.Fatalf("CancelOrder: %v", err)
+	}
+	if q := api.lastRequest("/v1/order").URL.Query(); q.Get("order_id") != "13" || q.Get("symbol") != "PERP_ETH_USDC" {
+		t.Errorf("unexpected cancel query %v", q)
+	}
+}
+
+func TestOrderlyPositions(t *testing.T) {
+	api := newFakeAPI(t)
+	api.respond("GET", "/v1/positions", http.StatusOK, `{"success":true,"data":{"total_collateral_value":1200.5,"rows":[
+		{"symbol":"PERP_ETH_USDC","position_qty":-2,"average_open_price":3510,"mark_price":3500,"est_liq_price":4200},
+		{"symbol":"PERP_BTC_USDC","position_qty":0,"average_open_price":0}]}}`)
+	api.respond("GET", "/v1/order/13", http.StatusOK, `{"success":true,"data":{"order_id":13,"side":"SELL","type":"LIMIT","quantity":2,"executed":2,
+		"price":3510,"average_executed_price":3510,"status":"FILLED","created_time":1700000000000}}`)
+	ex := newTestOrderly(api, orderlyTestKey)
+
+	positions, err := ex.GetPositions(context.Background())
+	if err != nil {
+		t.Fatalf("GetPositions: %v", err)
+	}
+	if len(positions) != 1 || positions[0] != (Position{Market: "ETH-USD", Side: Sell, Size: 2, EntryPrice: 3510}) {
+		t.Errorf("expected the ETH short, got %+v", positions)
+	}
+	risk, err := ex.GetPositionRisk(context.Background(), "ETH-USD")
+	if err != nil {
+		t.Fatalf("GetPositionRisk: %v", err)
+	}
+	if distance, ok := risk.LiquidationDistance(); risk.Margin != 1200.5 || !ok || distance != 0.2 {
+		t.Errorf("unexpected risk %+v (distance %f)", risk, distance)
+	}
+	status, err := ex.GetOrderStatus(context.Background(), "13", "ETH-USD")
+	if err != nil {
+		t.Fatalf("GetOrderStatus: %v", err)
+	}
+	if status.Status != "FILLED" || !status.Filled.Equal(decimal.NewFromInt(2)) || status.Timestamp != 1700000000 {
+		t.Errorf("unexpected status %+v", status)
+	}
+}
+
+func TestOrderlyKeyFromBase58(t *testing.T) {
+	seed := make([]byte, ed25519.SeedSize)
+	seed[0], seed[31] = 0, 7
+	ex := NewOrderly("0xaccount", "ed25519:"+base58Encode(seed), true)
+	if ex.key == nil || !ex.key.Equal(ed25519.NewKeyFromSeed(seed)) {
+		t.Fatal("expected the key to be decoded from its base58 seed")
+	}
+	if decoded, err := base58Decode(strings.TrimPrefix(ex.publicKey, "ed25519:")); err != nil || !ed25519.PublicKey(decoded).Equal(ex.key.Public()) {
+		t.Errorf("public key %q does not round-trip: %v", ex.publicKey, err)
+	}
+}
@@ -0,0 +1,393 @@
+package exchange
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// PaperConfig controls the simulated execution of a Paper wrapper.
+type PaperConfig struct {
+	// Balance is the starting virtual collateral, in USD.
+	Balance float64
+	// SlippageBps moves every fill away from the mark price, against the order.
+	SlippageBps float64
+	// FeeBps is charged on the notional of every fill.
+	FeeBps float64
+}
+
+// PaperSummary is the virtual account of a Paper wrapper.
+type PaperSummary struct {
+	Exchange      string
+	StartBalance  float64
+	Balance       float64
+	Equity        float64
+	RealizedPnL   float64
+	UnrealizedPnL float64
+	Fees          float64
+	Funding       float64
+	Fills         int
+}
+
+// paperPosition is a net position in one market. Size is negative for shorts.
+type paperPosition struct {
+	size       float64
+	entryPrice float64
+}
+
+// paperRate is the last funding rate seen for a market, paid at nextTime.
+type paperRate struct {
+	rate     float64
+	nextTime int64
+}
+
+// Paper wraps an Exchange so market data comes from the live venue but orders are filled
+// virtually at the mark price, with slippage and fees, against a virtual USD balance. Funding is
+// settled on open positions at the rate the venue reported before each funding time.
+type Paper struct {
+	Exchange
+	cfg PaperConfig
+
+	mu        sync.Mutex
+	balance   float64
+	positions map[string]*paperPosition
+	orders    map[string]*Order
+	rates     map[string]paperRate
+	nextID    int
+	realized  float64
+	fees      float64
+	funding   float64
+	payments  []FundingPayment
+	fills     int
+}
+
+// NewPaper wraps ex with simulated execution described by cfg.
+func NewPaper(ex Exchange, cfg PaperConfig) *Paper {
+	return &Paper{
+		Exchange:  ex,
+		cfg:       cfg,
+		balance:   cfg.Balance,
+		positions: make(map[string]*paperPosition),
+		orders:    make(map[string]*Order),
+		rates:     make(map[string]paperRate),
+	}
+}
+
+func (p *Paper) Name() string {
+	return p.Exchange.Name()
+}
+
+// FundingInterval forwards to the wrapped exchange.
+func (p *Paper) FundingInterval() time.Duration {
+	return FundingIntervalOf(p.Exchange)
+}
+
+// CollateralAsset is USD: the virtual balance is kept in USD whatever the venue margins in.
+func (p *Paper) CollateralAsset() string {
+	return "USD"
+}
+
+// GetMarketStats forwards to the wrapped exchange, so paper runs apply the same liquidity floors.
+func (p *Paper) GetMarketStats(ctx context.Context, markets []string) (map[string]MarketStats, error) {
+	statser, ok := p.Exchange.(MarketStatser)
+	if !ok {
+		return nil, fmt.Errorf("%s does not report market statistics", p.Exchange.Name())
+	}
+	return statser.GetMarketStats(ctx, markets)
+}
+
+// SetMarginMode accepts any mode, since virtual positions are never liquidated.
+func (p *Paper) SetMarginMode(ctx context.Context, market string, mode MarginMode) error {
+	return nil
+}
+
+// SetLeverage accepts any leverage, since virtual positions are never liquidated.
+func (p *Paper) SetLeverage(ctx context.Context, market string, leverage float64) error {
+	return nil
+}
+
+// GetMarketInfo forwards to the wrapped exchange, so paper orders are sized as live ones would be.
+func (p *Paper) GetMarketInfo(ctx context.Context, market string) (*MarketInfo, error) {
+	provider, ok := p.Exchange.(MarketInfoProvider)
+	if !ok {
+		return nil, ErrMarketInfoUnsupported
+	}
+	return provider.GetMarketInfo(ctx, market)
+}
+
+// GetPositionRisk reports ErrPositionRiskUnsupported, since virtual positions are never liquidated.
+func (p *Paper) GetPositionRisk(ctx context.Context, market string) (*PositionRisk, error) {
+	return nil, ErrPositionRiskUnsupported
+}
+
+// GetForcedCloses reports ErrForcedClosesUnsupported, since virtual positions are never liquidated.
+func (p *Paper) GetForcedCloses(ctx context.Context, market string, since time.Time) ([]ForcedClose, error) {
+	return nil, ErrForcedClosesUnsupported
+}
+
+// Stream forwards the wrapped exchange's market data. Its order updates are dropped, since paper
+// orders never reach the venue.
+func (p *Paper) Stream(markets []string, handler StreamHandler, stop <-chan struct{}) error {
+	streamer, ok := p.Exchange.(StreamingExchange)
+	if !ok {
+		return ErrStreamingUnsupported
+	}
+	handler.OnOrder = nil
+	return streamer.Stream(markets, handler, stop)
+}
+
+// GetFundingPayments returns the funding settled on the virtual position in market.
+func (p *Paper) GetFundingPayments(ctx context.Context, market string, since time.Time) ([]FundingPayment, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var payments []FundingPayment
+	for _, payment := range p.payments {
+		if payment.Market == market && !payment.Time.Before(since) {
+			payments = append(payments, payment)
+		}
+	}
+	return payments, nil
+}
+
+// GetFundingRates returns the live rates and settles funding on virtual positions whose funding
+// time has passed since the previous call.
+func (p *Paper) GetFundingRates(ctx context.Context) ([]*FundingRate, error) {
+	rates, err := p.Exchange.GetFundingRates(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now().Unix()
+	for market, rate := range p.rates {
+		position, ok := p.positions[market]
+		if !ok || rate.nextTime == 0 || rate.nextTime > now {
+			continue
+		}
+		// Longs pay shorts when the rate is positive.
+		payment := -position.size * position.entryPrice * rate.rate
+		p.balance += payment
+		p.funding += payment
+		p.payments = append(p.payments, FundingPayment{Market: market, Time: time.Unix(rate.nextTime, 0), Rate: rate.rate, Amount: payment})
+		delete(p.rates, market)
+	}
+	for _, rate := range rates {
+		p.rates[rate.Market] = paperRate{rate: rate.Rate.InexactFloat64(), nextTime: rate.NextTime}
+	}
+	return rates, nil
+}
+
+// PlaceOrder fills a market order immediately at the mark price plus slippage. A limit order
+// fills at its limit price if that is marketable, and otherwise rests until GetOrderStatus finds
+// the mark has crossed it.
+func (p *Paper) PlaceOrder(ctx context.Context, market string, side OrderSide, orderType OrderType, amount, price decimal.Decimal) (*Order, error) {
+	if !amount.IsPositive() {
+		return nil, fmt.Errorf("invalid paper order amount %s", amount)
+	}
+	mark, err := p.Exchange.GetMarkPrice(ctx, market)
+	if err != nil {
+		return nil, fmt.Errorf("failed to price paper order: %w", err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.nextID++
+	order := &Order{
+		ID:        "paper-" + strconv.Itoa(p.nextID),
+		Market:    market,
+		Side:      side,
+		Type:      orderType,
+		Price:     price,
+		Amount:    amount,
+		Status:    "OPEN",
+		Timestamp: time.Now().Unix(),
+	}
+	p.orders[order.ID] = order
+	p.tryFill(order, mark)
+	copied := *order
+	return &copied, nil
+}
+
+// tryFill fills order if it is marketable at mark. The caller must hold p.mu.
+func (p *Paper) tryFill(order *Order, mark float64) {
+	slip := mark * p.cfg.SlippageBps / 10000
+	fill := mark + slip
+	if order.Side == Sell {
+		fill = mark - slip
+	}
+	if limit := order.Price.InexactFloat64(); order.Type == Limit && limit > 0 {
+		if (order.Side == Buy && limit < fill) || (order.Side == Sell && limit > fill) {
+			return
+		}
+		fill = limit
+	}
+
+	size := order.Amount.InexactFloat64()
+	if order.Side == Sell {
+		size = -size
+	}
+	p.apply(order.Market, size, fill)
+	fee := math.Abs(size) * fill * p.cfg.FeeBps / 10000
+	p.balance -= fee
+	p.fees += fee
+	p.fills++
+
+	order.Price = decimal.NewFromFloat(fill)
+	order.Filled = order.Amount
+	order.Status = "FILLED"
+	order.Fee = fee
+}
+
+// apply adds a signed fill to the position in market, realizing PnL on the part that reduces it.
+// The caller must hold p.mu.
+func (p *Paper) apply(market string, size, price float64) {
+	position, ok := p.positions[market]
+	if !ok {
+		p.positions[market] = &paperPosition{size: size, entryPrice: price}
+		return
+	}
+
+	if position.size*size > 0 {
+		total := position.size + size
+		position.entryPrice = (position.entryPrice*position.size + price*size) / total
+		position.size = total
+		return
+	}
+
+	closed := math.Min(math.Abs(size), math.Abs(position.size))
+	direction := 1.0
+	if position.size < 0 {
+		direction = -1
+	}
+	pnl := (price - position.entryPrice) * closed * direction
+	p.balance += pnl
+	p.realized += pnl
+
+	remaining := position.size + size
+	switch {
+	case math.Abs(remaining) < 1e-12:
+		delete(p.positions, market)
+	case remaining*position.size < 0:
+		// The fill flipped the position; the excess opens at the fill price.
+		position.size = remaining
+		position.entryPrice = price
+	default:
+		position.size = remaining
+	}
+}
+
+// GetOrderStatus reports a virtual order, filling a resting limit order if the mark has crossed it.
+func (p *Paper) GetOrderStatus(ctx context.Context, orderID string, market string) (*Order, error) {
+	p.mu.Lock()
+	order, ok := p.orders[orderID]
+	resting := ok && order.Status == "OPEN"
+	p.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("paper order %s not found", orderID)
+	}
+
+	if resting {
+		mark, err := p.Exchange.GetMarkPrice(ctx, order.Market)
+		if err != nil {
+			return nil, fmt.Errorf("failed to price paper order: %w", err)
+		}
+		p.mu.Lock()
+		if order.Status == "OPEN" {
+			p.tryFill(order, mark)
+		}
+		p.mu.Unlock()
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	copied := *order
+	return &copied, nil
+}
+
+// CancelOrder cancels a resting virtual order.
+func (p *Paper) CancelOrder(ctx context.Context, orderID string, market string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	order, ok := p.orders[orderID]
+	if !ok {
+		return fmt.Errorf("paper order %s not found", orderID)
+	}
+	if order.Status != "OPEN" {
+		return fmt.Errorf("paper order %s is %s", orderID, order.Status)
+	}
+	order.Status = "CANCELLED"
+	return nil
+}
+
+// GetBalance returns the virtual equity in USD, which also stands in for the venue's own
+// collateral asset. Other assets are never held.
+func (p *Paper) GetBalance(ctx context.Context, asset string) (float64, error) {
+	venueAsset := ""
+	if a, ok := p.Exchange.(interface{ CollateralAsset() string }); ok {
+		venueAsset = a.CollateralAsset()
+	}
+	if asset != "" && asset != p.CollateralAsset() && asset != venueAsset {
+		return 0, nil
+	}
+	return p.Summary().Equity, nil
+}
+
+// GetPositions returns the virtual positions.
+func (p *Paper) GetPositions(ctx context.Context) ([]Position, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	positions := make([]Position, 0, len(p.positions))
+	for market, position := range p.positions {
+		side := Buy
+		if position.size < 0 {
+			side = Sell
+		}
+		positions = append(positions, Position{Market: market, Side: side, Size: math.Abs(position.size), EntryPrice: position.entryPrice})
+	}
+	return positions, nil
+}
+
+func (p *Paper) ClosePosition(ctx context.Context, market string, side OrderSide, amount decimal.Decimal) (*Order, error) {
+	// To close a position, we place an order on the opposite side.
+	closeSide := Sell
+	if side == Sell {
+		closeSide = Buy
+	}
+	return p.PlaceOrder(ctx, market, closeSide, Market, amount, decimal.Zero)
+}
+
+// Summary values the virtual account. Open positions are marked at the live mark price, or at
+// their entry price when it can't be fetched.
+func (p *Paper) Summary() PaperSummary {
+	p.mu.Lock()
+	held := make(map[string]paperPosition, len(p.positions))
+	for market, position := range p.positions {
+		held[market] = *position
+	}
+	summary := PaperSummary{
+		Exchange:     p.Name(),
+		StartBalance: p.cfg.Balance,
+		Balance:      p.balance,
+		RealizedPnL:  p.realized,
+		Fees:         p.fees,
+		Funding:      p.funding,
+		Fills:        p.fills,
+	}
+	p.mu.Unlock()
+
+	for market, position := range held {
+		mark, err := p.Exchange.GetMarkPrice(context.Background(), market)
+		if err != nil {
+			mark = position.entryPrice
+		}
+		summary.UnrealizedPnL += (mark - position.entryPrice) * position.size
+	}
+	summary.Equity = summary.Balance + summary.UnrealizedPnL
+	return summary
+}
@@ -0,0 +1,107 @@
+package exchange
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestPaperFillsAndTracksPnL(t *testing.T) {
+	fake := &fakeExchange{}
+	paper := NewPaper(fake, PaperConfig{Balance: 1000, SlippageBps: 10, FeeBps: 5})
+
+	order, err := paper.PlaceOrder(context.Background(), "BTC-USD", Buy, Market, decimal.NewFromInt(2), decimal.NewFromInt(0))
+	if err != nil {
+		t.Fatalf("PlaceOrder: %v", err)
+	}
+	if order.Status != "FILLED" || !order.Filled.Equal(decimal.NewFromInt(2)) || !order.Price.Equal(decimal.NewFromFloat(100.1)) {
+		t.Errorf("expected a fill at the mark plus slippage, got %+v", order)
+	}
+	if len(fake.placed) != 0 {
+		t.Error("expected no order to reach the wrapped exchange")
+	}
+
+	fake.mark = 110
+	if _, err := paper.ClosePosition(context.Background(), "BTC-USD", Buy, decimal.NewFromInt(2)); err != nil {
+		t.Fatalf("ClosePosition: %v", err)
+	}
+	summary := paper.Summary()
+	// Bought at 100.1, sold at 109.89, fees on both notionals.
+	pnl := (109.89 - 100.1) * 2
+	fees := (100.1*2 + 109.89*2) * 5 / 10000
+	if math.Abs(summary.RealizedPnL-pnl) > 1e-9 || math.Abs(summary.Fees-fees) > 1e-9 {
+		t.Errorf("unexpected PnL %f or fees %f", summary.RealizedPnL, summary.Fees)
+	}
+	if math.Abs(summary.Equity-(1000+pnl-fees)) > 1e-9 || summary.Fills != 2 {
+		t.Errorf("unexpected summary %+v", summary)
+	}
+	if positions, _ := paper.GetPositions(context.Background()); len(positions) != 0 {
+		t.Errorf("expected the position to be closed, got %+v", positions)
+	}
+}
+
+func TestPaperRestingLimitOrders(t *testing.T) {
+	fake := &fakeExchange{}
+	paper := NewPaper(fake, PaperConfig{Balance: 1000})
+
+	order, err := paper.PlaceOrder(context.Background(), "BTC-USD", Sell, Limit, decimal.NewFromInt(1), decimal.NewFromInt(105))
+	if err != nil || order.Status != "OPEN" {
+		t.Fatalf("expected the limit order to rest, got %+v, %v", order, err)
+	}
+	fake.mark = 106
+	if order, _ = paper.GetOrderStatus(context.Background(), order.ID, "BTC-USD"); order.Status != "FILLED" || !order.Price.Equal(decimal.NewFromInt(105)) {
+		t.Errorf("expected the limit order to fill at its price once crossed, got %+v", order)
+	}
+	positions, _ := paper.GetPositions(context.Background())
+	if len(positions) != 1 || positions[0].Side != Sell || positions[0].Size != 1 {
+		t.Errorf("expected a short position, got %+v", positions)
+	}
+
+	resting, _ := paper.PlaceOrder(context.Background(), "BTC-USD", Buy, Limit, decimal.NewFromInt(1), decimal.NewFromInt(90))
+	if err := paper.CancelOrder(context.Background(), resting.ID, "BTC-USD"); err != nil {
+		t.Errorf("CancelOrder: %v", err)
+	}
+	if err := paper.CancelOrder(context.Background(), order.ID, "BTC-USD"); err == nil {
+		t.Error("expected cancelling a filled order to fail")
+	}
+}
+
+func TestPaperSettlesFunding(t *testing.T) {
+	fake := &fakeExchange{rates: []*FundingRate{{Market: "BTC-USD", Rate: decimal.NewFromFloat(0.001), NextTime: time.Now().Add(-time.Second).Unix()}}}
+	paper := NewPaper(fake, PaperConfig{Balance: 1000})
+	if _, err := paper.PlaceOrder(context.Background(), "BTC-USD", Sell, Market, decimal.NewFromInt(10), decimal.NewFromInt(0)); err != nil {
+		t.Fatalf("PlaceOrder: %v", err)
+	}
+
+	paper.GetFundingRates(context.Background())
+	paper.GetFundingRates(context.Background())
+	// The short receives 0.1% of its 1000 USD notional once.
+	if summary := paper.Summary(); math.Abs(summary.Funding-1) > 1e-9 {
+		t.Errorf("expected 1 USD of funding, got %f", summary.Funding)
+	}
+}
+
+func TestDryRunReportsOrdersWithoutSendingThem(t *testing.T) {
+	fake := &fakeExchange{}
+	var reported []Order
+	dry := NewDryRun(fake, func(order Order) { reported = append(reported, order) })
+
+	if _, err := dry.PlaceOrder(context.Background(), "BTC-USD", Buy, Market, decimal.NewFromInt(2), decimal.NewFromInt(0)); err != nil {
+		t.Fatalf("PlaceOrder: %v", err)
+	}
+	if _, err := dry.ClosePosition(context.Background(), "BTC-USD", Buy, decimal.NewFromInt(2)); err != nil {
+		t.Fatalf("ClosePosition: %v", err)
+	}
+	if len(fake.placed) != 0 {
+		t.Error("expected no order to reach the wrapped exchange")
+	}
+	if len(reported) != 2 || reported[0].Side != Buy || !reported[0].Price.Equal(decimal.NewFromInt(100)) || reported[1].Side != Sell || reported[1].Status != "FILLED" {
+		t.Errorf("expected the buy and the closing sell to be reported, filled at the mark, got %+v", reported)
+	}
+	if balance, err := dry.GetBalance(context.Background(), "USDC"); err != nil || balance != 100 {
+		t.Errorf("GetBalance = %f, %v, want the real balance", balance, err)
+	}
+}
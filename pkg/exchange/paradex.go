@@ -0,0 +1,755 @@
+package exchange
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	sdk "github.com/extended-protocol/extended-sdk-golang/src"
+	"github.com/shopspring/decimal"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/httpclient"
+)
+
+const (
+	ParadexMainnetBaseURL = "https://api.prod.paradex.trade"
+	ParadexTestnetBaseURL = "https://api.testnet.paradex.trade"
+)
+
+const (
+	// paradexFundingInterval is the period Paradex quotes funding rates over. Funding accrues
+	// continuously, so there is no next funding time to report.
+	paradexFundingInterval = 8 * time.Hour
+	// paradexMarketSuffix turns a market name into the symbol of its perpetual, e.g. BTC-USD-PERP.
+	paradexMarketSuffix = "-PERP"
+	// paradexChainDecimals is the precision of sizes and prices in signed orders.
+	paradexChainDecimals = 8
+	// paradexJWTLifetime is how long a JWT is reused. Paradex issues them for five minutes.
+	paradexJWTLifetime = 3 * time.Minute
+	// paradexSignatureLifetime is how long the signature of an auth request is valid.
+	paradexSignatureLifetime = 5 * time.Minute
+)
+
+// ErrParadexHasherRequired is returned by the account and order methods of a Paradex client
+// without a typed data hasher, since every private request is authenticated with a signature.
+var ErrParadexHasherRequired = errors.New("paradex account requests need PARADEX_HASH_CMD to sign")
+
+// ParadexTypedData is a SNIP-12 (Starknet typed data) message, as Paradex signs auth requests
+// and orders.
+type ParadexTypedData struct {
+	Types       map[string][]ParadexTypedField `json:"types"`
+	PrimaryType string                         `json:"primaryType"`
+	Domain      map[string]string              `json:"domain"`
+	Message     map[string]string              `json:"message"`
+}
+
+// ParadexTypedField is one field of a typed data type.
+type ParadexTypedField struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// ParadexHasher computes the message hash of typed data signed by account, as a hex felt.
+type ParadexHasher interface {
+	Hash(ctx context.Context, account string, typedData ParadexTypedData) (string, error)
+}
+
+// CommandHasher delegates typed data hashing to an external program, e.g. a wrapper around
+// starknet.py's TypedData.message_hash. The Extended SDK signs Stark hashes but only hashes its
+// own order format, so the Pedersen hashing of Paradex's messages is left to the program; the
+// private key never leaves the bot. The program receives {"account", "typed_data"} as JSON on
+// stdin and must print the hash as hex on stdout.
+type CommandHasher struct {
+	command []string
+}
+
+// NewCommandHasher creates a hasher running command, split on whitespace.
+func NewCommandHasher(command string) (*CommandHasher, error) {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return nil, errors.New("empty Paradex hash command")
+	}
+	return &CommandHasher{command: args}, nil
+}
+
+// Hash runs the hash program for one message.
+func (c *CommandHasher) Hash(ctx context.Context, account string, typedData ParadexTypedData) (string, error) {
+	input, err := json.Marshal(struct {
+		Account   string           `json:"account"`
+		TypedData ParadexTypedData `json:"typed_data"`
+	}{account, typedData})
+	if err != nil {
+		return "", err
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, c.command[0], c.command[1:]...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("paradex hash command failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	hash := strings.TrimSpace(stdout.String())
+	if _, ok := new(big.Int).SetString(strings.TrimPrefix(hash, "0x"), 16); !ok {
+		return "", fmt.Errorf("paradex hash command returned an invalid hash: %q", hash)
+	}
+	return hash, nil
+}
+
+// Paradex is the implementation for Paradex perpetuals on Starknet. Markets such as "BTC-USD"
+// trade as the "BTC-USD-PERP" contract, margined in USDC. Market data is public; account requests
+// use a JWT obtained by signing an auth request with the account's Stark key.
+type Paradex struct {
+	client     *http.Client
+	account    string
+	privateKey string
+	baseURL    string
+	testnet    bool
+	hasher     ParadexHasher
+	// sign signs a hash with a Stark private key, returning r, s and v as 64 hex digits each.
+	sign func(hash, privateKey string) (string, error)
+
+	mu        sync.Mutex
+	chainID   string
+	jwt       string
+	jwtIssued time.Time
+	markets   map[string]paradexMarket
+}
+
+// NewParadex creates a new Paradex client for the Starknet account address, signing with its
+// Stark private key.
+func NewParadex(account, privateKey string, testnet bool) *Paradex {
+	baseURL := ParadexMainnetBaseURL
+	if testnet {
+		baseURL = ParadexTestnetBaseURL
+	}
+	return &Paradex{
+		client:     &http.Client{Timeout: 10 * time.Second},
+		account:    account,
+		privateKey: privateKey,
+		baseURL:    baseURL,
+		testnet:    testnet,
+		sign:       sdk.SignMessage,
+	}
+}
+
+// SetHasher sets the typed data hasher that account and order requests are signed with.
+func (p *Paradex) SetHasher(hasher ParadexHasher) {
+	p.hasher = hasher
+}
+
+// SetRateLimiter makes every Paradex API call wait for limiter.
+func (p *Paradex) SetRateLimiter(limiter *httpclient.Limiter) {
+	p.client = limiter.Client(p.client)
+}
+
+func (p *Paradex) Name() string {
+	return "Paradex"
+}
+
+// FundingInterval returns the period Paradex funding rates are quoted over.
+func (p *Paradex) FundingInterval() time.Duration {
+	return paradexFundingInterval
+}
+
+func (p *Paradex) CollateralAsset() string {
+	return "USDC"
+}
+
+func (p *Paradex) SetTestnet(testnet bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.testnet = testnet
+	if testnet {
+		p.baseURL = ParadexTestnetBaseURL
+	} else {
+		p.baseURL = ParadexMainnetBaseURL
+	}
+	p.chainID, p.jwt, p.markets = "", "", nil
+}
+
+// SetTransport replaces the HTTP transport used for REST requests, e.g. with a recorder in tests.
+func (p *Paradex) SetTransport(rt http.RoundTripper) {
+	p.client.Transport = rt
+}
+
+// Symbol converts "BTC-USD" into "BTC-USD-PERP".
+func (p *Paradex) Symbol(market string) string {
+	return market + paradexMarketSuffix
+}
+
+// market converts a perpetual symbol back into a market name. It reports false for options.
+func (p *Paradex) market(symbol string) (string, bool) {
+	market, ok := strings.CutSuffix(symbol, paradexMarketSuffix)
+	return market, ok && market != ""
+}
+
+// ParadexMarketSummary is the mark price and funding of a market.
+type ParadexMarketSummary struct {
+	Symbol      string `json:"symbol"`
+	MarkPrice   string `json:"mark_price"`
+	FundingRate string `json:"funding_rate"`
+}
+
+// GetFundingRates fetches the funding rate of every perpetual, quoted per 8 hours.
+func (p *Paradex) GetFundingRates(ctx context.Context) ([]*FundingRate, error) {
+	var response struct {
+		Results []ParadexMarketSummary `json:"results"`
+	}
+	if err := p.sendRequest(ctx, "GET", "/v1/markets/summary", url.Values{"market": {"ALL"}}, nil, false, &response); err != nil {
+		return nil, fmt.Errorf("failed to get funding rates from Paradex: %w", err)
+	}
+	var fundingRates []*FundingRate
+	for _, summary := range response.Results {
+		market, ok := p.market(summary.Symbol)
+		if !ok {
+			continue
+		}
+		rate, err := decimal.NewFromString(summary.FundingRate)
+		if err != nil {
+			continue
+		}
+		fundingRates = append(fundingRates, &FundingRate{Market: market, Rate: rate})
+	}
+	return fundingRates, nil
+}
+
+// GetMarkPrice returns the mark price of market.
+func (p *Paradex) GetMarkPrice(ctx context.Context, market string) (float64, error) {
+	var response struct {
+		Results []ParadexMarketSummary `json:"results"`
+	}
+	if err := p.sendRequest(ctx, "GET", "/v1/markets/summary", url.Values{"market": {p.Symbol(market)}}, nil, false, &response); err != nil {
+		return 0, fmt.Errorf("failed to get mark price from Paradex: %w", err)
+	}
+	if len(response.Results) == 0 {
+		return 0, fmt.Errorf("market %s not found on Paradex", market)
+	}
+	price, err := strconv.ParseFloat(response.Results[0].MarkPrice, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse mark price for %s from Paradex: %w", market, err)
+	}
+	return price, nil
+}
+
+// GetFundingPayments returns the funding paid and received on the account's market position
+// since the given time.
+func (p *Paradex) GetFundingPayments(ctx context.Context, market string, since time.Time) ([]FundingPayment, error) {
+	var payments []FundingPayment
+	cursor := ""
+	for {
+		params := url.Values{"market": {p.Symbol(market)}, "start_at": {strconv.FormatInt(since.UnixMilli(), 10)}}
+		if cursor != "" {
+			params.Set("cursor", cursor)
+		}
+		var response struct {
+			Next    string `json:"next"`
+			Results []struct {
+				Payment     string `json:"payment"`
+				FundingRate string `json:"funding_rate"`
+				CreatedAt   int64  `json:"created_at"`
+			} `json:"results"`
+		}
+		if err := p.sendRequest(ctx, "GET", "/v1/funding/payments", params, nil, true, &response); err != nil {
+			return nil, fmt.Errorf("failed to get funding payments from Paradex: %w", err)
+		}
+		for _, payment := range response.Results {
+			amount, err := strconv.ParseFloat(payment.Payment, 64)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse funding payment for %s from Paradex: %w", market, err)
+			}
+			rate, _ := strconv.ParseFloat(payment.FundingRate, 64)
+			payments = append(payments, FundingPayment{Market: market, Time: time.UnixMilli(payment.CreatedAt), Rate: rate, Amount: amount})
+		}
+		if response.Next == "" || len(response.Results) == 0 {
+			break
+		}
+		cursor = response.Next
+	}
+	sort.Slice(payments, func(i, j int) bool { return payments[i].Time.Before(payments[j].Time) })
+	return payments, nil
+}
+
+// GetOrderbook returns the order book of market.
+func (p *Paradex) GetOrderbook(ctx context.Context, market string) (*Orderbook, error) {
+	var response struct {
+		Bids [][2]string `json:"bids"`
+		Asks [][2]string `json:"asks"`
+	}
+	if err := p.sendRequest(ctx, "GET", "/v1/orderbook/"+p.Symbol(market), url.Values{"depth": {"100"}}, nil, false, &response); err != nil {
+		return nil, fmt.Errorf("failed to get orderbook from Paradex: %w", err)
+	}
+	bids, err := parseLevels(response.Bids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s orderbook from Paradex: %w", market, err)
+	}
+	asks, err := parseLevels(response.Asks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s orderbook from Paradex: %w", market, err)
+	}
+	book := &Orderbook{Market: market, Bids: bids, Asks: asks}
+	book.sortLevels()
+	return book, nil
+}
+
+// ParadexOrder is an order as reported by the order endpoints.
+type ParadexOrder struct {
+	ID            string `json:"id"`
+	Side          string `json:"side"`
+	Type          string `json:"type"`
+	Size          string `json:"size"`
+	RemainingSize string `json:"remaining_size"`
+	Price         string `json:"price"`
+	AvgFillPrice  string `json:"avg_fill_price"`
+	Status        string `json:"status"`
+	CancelReason  string `json:"cancel_reason"`
+	CreatedAt     int64  `json:"created_at"`
+}
+
+// order converts the response into an Order on market. Paradex reports an order as NEW, OPEN or
+// CLOSED; a closed order with nothing left unfilled is reported as FILLED.
+func (o ParadexOrder) order(market string) *Order {
+	price := parseDecimal(o.AvgFillPrice)
+	if price.IsZero() {
+		price = parseDecimal(o.Price)
+	}
+	amount := parseDecimal(o.Size)
+	remaining := parseDecimal(o.RemainingSize)
+	status := o.Status
+	if status == "CLOSED" && remaining.IsZero() && o.CancelReason == "" {
+		status = "FILLED"
+	}
+	return &Order{
+		ID:        o.ID,
+		Market:    market,
+		Side:      OrderSide(o.Side),
+		Type:      OrderType(o.Type),
+		Price:     price,
+		Amount:    amount,
+		Filled:    amount.Sub(remaining),
+		Status:    status,
+		Timestamp: o.CreatedAt / 1000,
+	}
+}
+
+// PlaceOrder signs and sends an order, rounding amount down to the market's size increment and a
+// limit price to its tick size. Limit orders rest until cancelled; market orders fill
+// immediately or are cancelled.
+func (p *Paradex) PlaceOrder(ctx context.Context, market string, side OrderSide, orderType OrderType, amount, price decimal.Decimal) (*Order, error) {
+	return p.placeOrder(ctx, market, side, orderType, amount, price, false)
+}
+
+func (p *Paradex) placeOrder(ctx context.Context, market string, side OrderSide, orderType OrderType, amount, price decimal.Decimal, reduceOnly bool) (*Order, error) {
+	rules, err := p.marketRules(ctx, market)
+	if err != nil {
+		return nil, err
+	}
+	size := rules.sizeIncrement.floor(amount)
+	if !size.IsPositive() {
+		return nil, fmt.Errorf("order amount %s is below the Paradex size increment %s for %s", amount, rules.sizeIncrement, market)
+	}
+	if orderType == Limit {
+		price = rules.tickSize.round(price)
+	} else {
+		price = decimal.Zero
+	}
+
+	timestamp := time.Now().UnixMilli()
+	chainSide := "1"
+	if side == Sell {
+		chainSide = "2"
+	}
+	signature, err := p.signTypedData(ctx, "Order", []ParadexTypedField{
+		{Name: "timestamp", Type: "felt"},
+		{Name: "market", Type: "felt"},
+		{Name: "side", Type: "felt"},
+		{Name: "orderType", Type: "felt"},
+		{Name: "size", Type: "felt"},
+		{Name: "price", Type: "felt"},
+	}, map[string]string{
+		"timestamp": strconv.FormatInt(timestamp, 10),
+		"market":    p.Symbol(market),
+		"side":      chainSide,
+		"orderType": string(orderType),
+		"size":      paradexChainAmount(size),
+		"price":     paradexChainAmount(price),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign order for Paradex: %w", err)
+	}
+
+	request := map[string]interface{}{
+		"market":              p.Symbol(market),
+		"side":                string(side),
+		"type":                string(orderType),
+		"size":                rules.sizeIncrement.format(size),
+		"instruction":         "GTC",
+		"signature":           signature,
+		"signature_timestamp": timestamp,
+	}
+	if orderType == Limit {
+		request["price"] = rules.tickSize.format(price)
+	} else {
+		request["instruction"] = "IOC"
+	}
+	if reduceOnly {
+		request["flags"] = []string{"REDUCE_ONLY"}
+	}
+	var response ParadexOrder
+	if err := p.sendRequest(ctx, "POST", "/v1/orders", nil, request, true, &response); err != nil {
+		return nil, fmt.Errorf("failed to place order on Paradex: %w", err)
+	}
+	return response.order(market), nil
+}
+
+// paradexChainAmount scales a size or price to the fixed-point integer signed in orders.
+func paradexChainAmount(v decimal.Decimal) string {
+	return v.Shift(paradexChainDecimals).Round(0).String()
+}
+
+func (p *Paradex) GetOrderStatus(ctx context.Context, orderID string, market string) (*Order, error) {
+	var response ParadexOrder
+	if err := p.sendRequest(ctx, "GET", "/v1/orders/"+url.PathEscape(orderID), nil, nil, true, &response); err != nil {
+		return nil, fmt.Errorf("failed to get order status from Paradex: %w", err)
+	}
+	return response.order(market), nil
+}
+
+func (p *Paradex) CancelOrder(ctx context.Context, orderID string, market string) error {
+	if err := p.sendRequest(ctx, "DELETE", "/v1/orders/"+url.PathEscape(orderID), nil, nil, true, nil); err != nil {
+		return fmt.Errorf("failed to cancel order on Paradex: %w", err)
+	}
+	return nil
+}
+
+// ClosePosition reduces the position in market with a reduce-only market order on the opposite
+// side, so it can never flip the position.
+func (p *Paradex) ClosePosition(ctx context.Context, market string, side OrderSide, amount decimal.Decimal) (*Order, error) {
+	closeSide := Sell
+	if side == Sell {
+		closeSide = Buy
+	}
+	return p.placeOrder(ctx, market, closeSide, Market, amount, decimal.Zero, true)
+}
+
+// GetBalance returns the account value, the USDC collateral plus the unrealized PnL of the open
+// positions. Paradex margins every market in USDC, so asset is only checked against it.
+func (p *Paradex) GetBalance(ctx context.Context, asset string) (float64, error) {
+	if asset != "" && !strings.EqualFold(asset, p.CollateralAsset()) {
+		return 0, nil
+	}
+	var response struct {
+		AccountValue string `json:"account_value"`
+	}
+	if err := p.sendRequest(ctx, "GET", "/v1/account", nil, nil, true, &response); err != nil {
+		return 0, fmt.Errorf("failed to get balance from Paradex: %w", err)
+	}
+	value, err := strconv.ParseFloat(response.AccountValue, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse balance float from Paradex: %w", err)
+	}
+	return value, nil
+}
+
+// ParadexPosition is a position as listed by the positions endpoint. Size is negative for shorts.
+type ParadexPosition struct {
+	Market            string `json:"market"`
+	Side              string `json:"side"`
+	Size              string `json:"size"`
+	AverageEntryPrice string `json:"average_entry_price"`
+	LiquidationPrice  string `json:"liquidation_price"`
+	Status            string `json:"status"`
+}
+
+// positions fetches the open perpetual positions of the account, keyed by market.
+func (p *Paradex) positions(ctx context.Context) (map[string]ParadexPosition, error) {
+	var response struct {
+		Results []ParadexPosition `json:"results"`
+	}
+	if err := p.sendRequest(ctx, "GET", "/v1/positions", nil, nil, true, &response); err != nil {
+		return nil, err
+	}
+	open := make(map[string]ParadexPosition)
+	for _, position := range response.Results {
+		market, ok := p.market(position.Market)
+		if !ok || position.Status != "OPEN" {
+			continue
+		}
+		if size, _ := strconv.ParseFloat(position.Size, 64); size != 0 {
+			open[market] = position
+		}
+	}
+	return open, nil
+}
+
+// side returns Buy for longs and Sell for shorts.
+func (pp ParadexPosition) side() OrderSide {
+	if pp.Side == "SHORT" {
+		return Sell
+	}
+	return Buy
+}
+
+// GetPositions fetches the open positions of the account.
+func (p *Paradex) GetPositions(ctx context.Context) ([]Position, error) {
+	open, err := p.positions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get positions from Paradex: %w", err)
+	}
+	positions := make([]Position, 0, len(open))
+	for market, position := range open {
+		size, err := strconv.ParseFloat(position.Size, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse position size for %s from Paradex: %w", market, err)
+		}
+		entry, _ := strconv.ParseFloat(position.AverageEntryPrice, 64)
+		positions = append(positions, Position{Market: market, Side: position.side(), Size: math.Abs(size), EntryPrice: entry})
+	}
+	sort.Slice(positions, func(i, j int) bool { return positions[i].Market < positions[j].Market })
+	return positions, nil
+}
+
+// GetPositionRisk fetches the liquidation price of the open position in market. Paradex accounts
+// are cross-margined, so the position is backed by the account value.
+func (p *Paradex) GetPositionRisk(ctx context.Context, market string) (*PositionRisk, error) {
+	open, err := p.positions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the %s position from Paradex: %w", market, err)
+	}
+	position, ok := open[market]
+	if !ok {
+		return nil, fmt.Errorf("no open %s position on Paradex", market)
+	}
+	size, err := strconv.ParseFloat(position.Size, 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse position size for %s from Paradex: %w", market, err)
+	}
+	mark, err := p.GetMarkPrice(ctx, market)
+	if err != nil {
+		return nil, err
+	}
+	margin, err := p.GetBalance(ctx, p.CollateralAsset())
+	if err != nil {
+		return nil, err
+	}
+	liquidation, _ := strconv.ParseFloat(position.LiquidationPrice, 64)
+	return &PositionRisk{Market: market, Side: position.side(), Size: math.Abs(size), MarkPrice: mark, LiquidationPrice: liquidation, Margin: margin}, nil
+}
+
+// GetMarketInfo returns the trading rules of market.
+func (p *Paradex) GetMarketInfo(ctx context.Context, market string) (*MarketInfo, error) {
+	rules, err := p.marketRules(ctx, market)
+	if err != nil {
+		return nil, err
+	}
+	return &MarketInfo{
+		Market:             market,
+		TickSize:           rules.tickSize.step,
+		LotSize:            rules.sizeIncrement.step,
+		MinNotional:        rules.minNotional,
+		ContractMultiplier: 1,
+	}, nil
+}
+
+// paradexMarket holds the trading rules of a perpetual.
+type paradexMarket struct {
+	sizeIncrement decimalStep
+	tickSize      decimalStep
+	minNotional   float64
+}
+
+// marketRules returns the trading rules of market. The rules of every market are fetched on
+// first use and cached.
+func (p *Paradex) marketRules(ctx context.Context, market string) (paradexMarket, error) {
+	p.mu.Lock()
+	markets := p.markets
+	p.mu.Unlock()
+	if markets == nil {
+		var response struct {
+			Results []struct {
+				Symbol             string `json:"symbol"`
+				OrderSizeIncrement string `json:"order_size_increment"`
+				PriceTickSize      string `json:"price_tick_size"`
+				MinNotional        string `json:"min_notional"`
+			} `json:"results"`
+		}
+		if err := p.sendRequest(ctx, "GET", "/v1/markets", nil, nil, false, &response); err != nil {
+			return paradexMarket{}, fmt.Errorf("failed to get markets from Paradex: %w", err)
+		}
+		markets = make(map[string]paradexMarket, len(response.Results))
+		for _, m := range response.Results {
+			markets[m.Symbol] = paradexMarket{
+				sizeIncrement: parseDecimalStep(m.OrderSizeIncrement),
+				tickSize:      parseDecimalStep(m.PriceTickSize),
+				minNotional:   parseRule(m.MinNotional),
+			}
+		}
+		p.mu.Lock()
+		p.markets = markets
+		p.mu.Unlock()
+	}
+	rules, ok := markets[p.Symbol(market)]
+	if !ok {
+		return paradexMarket{}, fmt.Errorf("market %s not found on Paradex", market)
+	}
+	return rules, nil
+}
+
+// signTypedData hashes a message of the given type in the Paradex domain and signs the hash with
+// the account's Stark key. The signature is returned as Paradex expects it: a JSON array of r and
+// s in decimal.
+func (p *Paradex) signTypedData(ctx context.Context, primaryType string, fields []ParadexTypedField, message map[string]string) (string, error) {
+	if p.hasher == nil {
+		return "", ErrParadexHasherRequired
+	}
+	chainID, err := p.starknetChainID(ctx)
+	if err != nil {
+		return "", err
+	}
+	typedData := ParadexTypedData{
+		Types: map[string][]ParadexTypedField{
+			"StarkNetDomain": {{Name: "name", Type: "felt"}, {Name: "chainId", Type: "felt"}, {Name: "version", Type: "felt"}},
+			primaryType:      fields,
+		},
+		PrimaryType: primaryType,
+		Domain:      map[string]string{"name": "Paradex", "chainId": chainID, "version": "1"},
+		Message:     message,
+	}
+	hash, err := p.hasher.Hash(ctx, p.account, typedData)
+	if err != nil {
+		return "", err
+	}
+	signature, err := p.sign(hash, p.privateKey)
+	if err != nil {
+		return "", err
+	}
+	if len(signature) < 128 {
+		return "", fmt.Errorf("unexpected Stark signature %q", signature)
+	}
+	r, okR := new(big.Int).SetString(signature[:64], 16)
+	s, okS := new(big.Int).SetString(signature[64:128], 16)
+	if !okR || !okS {
+		return "", fmt.Errorf("unexpected Stark signature %q", signature)
+	}
+	encoded, err := json.Marshal([]string{r.String(), s.String()})
+	return string(encoded), err
+}
+
+// starknetChainID returns the chain ID of the Paradex network, as the hex felt of its short
+// string name. It is fetched from the system config on first use.
+func (p *Paradex) starknetChainID(ctx context.Context) (string, error) {
+	p.mu.Lock()
+	chainID := p.chainID
+	p.mu.Unlock()
+	if chainID != "" {
+		return chainID, nil
+	}
+	var response struct {
+		StarknetChainID string `json:"starknet_chain_id"`
+	}
+	if err := p.sendRequest(ctx, "GET", "/v1/system/config", nil, nil, false, &response); err != nil {
+		return "", fmt.Errorf("failed to get the chain ID from Paradex: %w", err)
+	}
+	chainID = "0x" + hex.EncodeToString([]byte(response.StarknetChainID))
+	p.mu.Lock()
+	p.chainID = chainID
+	p.mu.Unlock()
+	return chainID, nil
+}
+
+// token returns a JWT for account requests, signing a new auth request when the last one is
+// about to expire.
+func (p *Paradex) token(ctx context.Context) (string, error) {
+	p.mu.Lock()
+	jwt, issued := p.jwt, p.jwtIssued
+	p.mu.Unlock()
+	if jwt != "" && time.Since(issued) < paradexJWTLifetime {
+		return jwt, nil
+	}
+
+	now := time.Now()
+	expiration := now.Add(paradexSignatureLifetime)
+	signature, err := p.signTypedData(ctx, "Request", []ParadexTypedField{
+		{Name: "method", Type: "felt"},
+		{Name: "path", Type: "felt"},
+		{Name: "body", Type: "felt"},
+		{Name: "timestamp", Type: "felt"},
+		{Name: "expiration", Type: "felt"},
+	}, map[string]string{
+		"method":     "POST",
+		"path":       "/v1/auth",
+		"body":       "",
+		"timestamp":  strconv.FormatInt(now.Unix(), 10),
+		"expiration": strconv.FormatInt(expiration.Unix(), 10),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to sign Paradex auth request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/v1/auth", nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("PARADEX-STARKNET-ACCOUNT", p.account)
+	req.Header.Set("PARADEX-STARKNET-SIGNATURE", signature)
+	req.Header.Set("PARADEX-TIMESTAMP", strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set("PARADEX-SIGNATURE-EXPIRATION", strconv.FormatInt(expiration.Unix(), 10))
+	var response struct {
+		JWTToken string `json:"jwt_token"`
+	}
+	if err := doJSON(p.client, req, &response); err != nil {
+		return "", fmt.Errorf("failed to authenticate with Paradex: %w", err)
+	}
+
+	p.mu.Lock()
+	p.jwt, p.jwtIssued = response.JWTToken, now
+	p.mu.Unlock()
+	return response.JWTToken, nil
+}
+
+// sendRequest sends a request to the Paradex API, with params in the query string and body as
+// JSON. Private requests carry the account's JWT.
+func (p *Paradex) sendRequest(ctx context.Context, method, endpoint string, params url.Values, body interface{}, private bool, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		buf, err := encodeJSON(body)
+		if err != nil {
+			return err
+		}
+		defer putBuffer(buf)
+		reader = buf
+	}
+	target := p.baseURL + endpoint
+	if len(params) > 0 {
+		target += "?" + params.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if private {
+		jwt, err := p.token(ctx)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+jwt)
+	}
+	return doJSON(p.client, req, out)
+}
@@ -0,0 +1,119 @@
+package exchange
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+// recordingHasher returns a fixed hash and keeps the typed data it was asked to hash.
+type recordingHasher struct {
+	hashed []ParadexTypedData
+}
+
+func (h *recordingHasher) Hash(ctx context.Context, account string, typedData ParadexTypedData) (string, error) {
+	h.hashed = append(h.hashed, typedData)
+	return "0x1234", nil
+}
+
+func TestParadexFundingRates(t *testing.T) {
+	api := newFakeAPI(t)
+	api.respond("GET", "/v1/markets/summary", http.StatusOK, `{"results":[
+		{"symbol":"BTC-USD-PERP","mark_price":"65000.5","funding_rate":"0.0004"},
+		{"symbol":"BTC-USD-65000-C","mark_price":"120","funding_rate":""}]}`)
+	ex := newTestParadex(api, nil)
+
+	rates, err := ex.GetFundingRates(context.Background())
+	if err != nil {
+		t.Fatalf("GetFundingRates: %v", err)
+	}
+	if len(rates) != 1 || rates[0].Market != "BTC-USD" || !rates[0].Rate.Equal(decimal.NewFromFloat(0.0004)) {
+		t.Errorf("expected only the BTC perpetual, got %+v", rates)
+	}
+	if got := FundingIntervalOf(ex); got.Hours() != 8 {
+		t.Errorf("expected rates quoted per 8 hours, got %s", got)
+	}
+}
+
+func TestParadexAccountRequestsNeedAHasher(t *testing.T) {
+	api := newFakeAPI(t)
+	ex := newTestParadex(api, nil)
+
+	if _, err := ex.GetPositions(context.Background()); !errors.Is(err, ErrParadexHasherRequired) {
+		t.Errorf("expected ErrParadexHasherRequired, got %v", err)
+	}
+}
+
+func TestParadexSignedOrder(t *testing.T) {
+	api := newFakeAPI(t)
+	api.respond("GET", "/v1/system/config", http.StatusOK, `{"starknet_chain_id":"PRIVATE_SN_POTC_SEPOLIA"}`)
+	api.respond("POST", "/v1/auth", http.StatusOK, `{"jwt_token":"test-jwt"}`)
+	api.respond("GET", "/v1/markets", http.StatusOK, `{"results":[{"symbol":"BTC-USD-PERP","order_size_increment":"0.001","price_tick_size":"0.1"}]}`)
+	var order map[string]interface{}
+	var auth string
+	api.handle("POST", "/v1/orders", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &order)
+		auth = r.Header.Get("Authorization")
+		_, _ = w.Write([]byte(`{"id":"o-1","side":"SELL","type":"LIMIT","size":"0.012","remaining_size":"0.012","price":"64999.9","avg_fill_price":"","status":"NEW","created_at":1700000000000}`))
+	})
+	hasher := &recordingHasher{}
+	ex := newTestParadex(api, hasher)
+
+	placed, err := ex.PlaceOrder(context.Background(), "BTC-USD", Sell, Limit, decimal.NewFromFloat(0.01234), decimal.NewFromFloat(64999.87))
+	if err != nil {
+		t.Fatalf("PlaceOrder: %v", err)
+	}
+	if placed.ID != "o-1" || placed.Status != "NEW" || !placed.Filled.Equal(decimal.Zero) {
+		t.Errorf("unexpected order %+v", placed)
+	}
+	if auth != "Bearer test-jwt" {
+		t.Errorf("expected the order to carry the JWT, got %q", auth)
+	}
+	if order["market"] != "BTC-USD-PERP" || order["size"] != "0.012" || order["price"] != "64999.9" || order["signature"] != `["1","2"]` {
+		t.Errorf("unexpected order request %v", order)
+	}
+
+	if len(hasher.hashed) != 2 || hasher.hashed[0].PrimaryType != "Order" || hasher.hashed[1].PrimaryType != "Request" {
+		t.Fatalf("expected the order and the auth request to be hashed, got %+v", hasher.hashed)
+	}
+	signed := hasher.hashed[0]
+	if signed.Domain["chainId"] != "0x505249564154455f534e5f504f54435f5345504f4c4941" {
+		t.Errorf("chain ID = %s, want the hex short string of the network name", signed.Domain["chainId"])
+	}
+	if m := signed.Message; m["side"] != "2" || m["size"] != "1200000" || m["price"] != "6499990000000" || m["orderType"] != "LIMIT" {
+		t.Errorf("unexpected signed order %v", m)
+	}
+}
+
+func TestParadexPositions(t *testing.T) {
+	api := newFakeAPI(t)
+	api.respond("GET", "/v1/system/config", http.StatusOK, `{"starknet_chain_id":"PRIVATE_SN_POTC_SEPOLIA"}`)
+	api.respond("POST", "/v1/auth", http.StatusOK, `{"jwt_token":"test-jwt"}`)
+	api.respond("GET", "/v1/positions", http.StatusOK, `{"results":[
+		{"market":"ETH-USD-PERP","side":"SHORT","size":"-1.5","average_entry_price":"3200","liquidation_price":"4000","status":"OPEN"},
+		{"market":"BTC-USD-PERP","side":"LONG","size":"0","average_entry_price":"0","liquidation_price":"","status":"CLOSED"}]}`)
+	api.respond("GET", "/v1/markets/summary", http.StatusOK, `{"results":[{"symbol":"ETH-USD-PERP","mark_price":"3200","funding_rate":"0.0001"}]}`)
+	api.respond("GET", "/v1/account", http.StatusOK, `{"account_value":"2500.5"}`)
+	ex := newTestParadex(api, &recordingHasher{})
+
+	positions, err := ex.GetPositions(context.Background())
+	if err != nil {
+		t.Fatalf("GetPositions: %v", err)
+	}
+	if len(positions) != 1 || positions[0] != (Position{Market: "ETH-USD", Side: Sell, Size: 1.5, EntryPrice: 3200}) {
+		t.Errorf("expected only the open ETH short, got %+v", positions)
+	}
+	risk, err := ex.GetPositionRisk(context.Background(), "ETH-USD")
+	if err != nil {
+		t.Fatalf("GetPositionRisk: %v", err)
+	}
+	if distance, ok := risk.LiquidationDistance(); risk.Margin != 2500.5 || !ok || distance != 0.25 {
+		t.Errorf("unexpected risk %+v (distance %f)", risk, distance)
+	}
+}
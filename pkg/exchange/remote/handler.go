@@ -0,0 +1,143 @@
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/exchange"
+)
+
+// Handler serves ex over the sidecar protocol, for adapters written in Go outside this
+// repository:
+//
+//	http.ListenAndServe("localhost:9000", remote.Handler(myExchange))
+//
+// The exchange is switched to testnet or mainnet as the X-Testnet header of each call asks.
+func Handler(ex exchange.Exchange) http.Handler {
+	h := &handler{ex: ex}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/{method}", h.serve)
+	return mux
+}
+
+type handler struct {
+	ex exchange.Exchange
+
+	mu      sync.Mutex
+	testnet *bool
+}
+
+func (h *handler) serve(w http.ResponseWriter, r *http.Request) {
+	var request Request
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid request: " + err.Error()})
+		return
+	}
+	if value := r.Header.Get(TestnetHeader); value != "" {
+		h.setTestnet(strings.EqualFold(value, "true"))
+	}
+	result, err := h.dispatch(r.Context(), r.PathValue("method"), request)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, errUnknownMethod) {
+			status = http.StatusNotFound
+		}
+		writeJSON(w, status, ErrorResponse{Error: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// setTestnet switches the exchange when a call asks for the other network.
+func (h *handler) setTestnet(testnet bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.testnet == nil || *h.testnet != testnet {
+		h.ex.SetTestnet(testnet)
+		h.testnet = &testnet
+	}
+}
+
+var errUnknownMethod = errors.New("unknown method")
+
+func (h *handler) dispatch(ctx context.Context, method string, r Request) (interface{}, error) {
+	switch method {
+	case MethodInfo:
+		info := Info{FundingIntervalSeconds: int64(exchange.FundingIntervalOf(h.ex).Seconds())}
+		if a, ok := h.ex.(interface{ CollateralAsset() string }); ok {
+			info.Collateral = a.CollateralAsset()
+		}
+		return info, nil
+	case MethodFundingRates:
+		rates, err := h.ex.GetFundingRates(ctx)
+		if err != nil {
+			return nil, err
+		}
+		result := make([]FundingRate, 0, len(rates))
+		for _, rate := range rates {
+			entry := FundingRate{Market: rate.Market, Rate: number(rate.Rate), NextTime: rate.NextTime}
+			if rate.HasPredicted {
+				predicted := number(rate.PredictedRate)
+				entry.PredictedRate = &predicted
+			}
+			result = append(result, entry)
+		}
+		return result, nil
+	case MethodOrderbook:
+		orderbook, err := h.ex.GetOrderbook(ctx, r.Market)
+		if err != nil {
+			return nil, err
+		}
+		result := Orderbook{Market: orderbook.Market, Bids: []Level{}, Asks: []Level{}}
+		for _, level := range orderbook.Bids {
+			result.Bids = append(result.Bids, Level{Price: level.Price, Size: level.Size})
+		}
+		for _, level := range orderbook.Asks {
+			result.Asks = append(result.Asks, Level{Price: level.Price, Size: level.Size})
+		}
+		return result, nil
+	case MethodMarkPrice:
+		price, err := h.ex.GetMarkPrice(ctx, r.Market)
+		return Price{Price: price}, err
+	case MethodPlaceOrder:
+		return orderResult(h.ex.PlaceOrder(ctx, r.Market, exchange.OrderSide(r.Side), exchange.OrderType(r.Type), parseNumber(r.Amount), parseNumber(r.Price)))
+	case MethodOrderStatus:
+		return orderResult(h.ex.GetOrderStatus(ctx, r.OrderID, r.Market))
+	case MethodCancelOrder:
+		return struct{}{}, h.ex.CancelOrder(ctx, r.OrderID, r.Market)
+	case MethodBalance:
+		balance, err := h.ex.GetBalance(ctx, r.Asset)
+		return Balance{Balance: balance}, err
+	case MethodPositions:
+		positions, err := h.ex.GetPositions(ctx)
+		if err != nil {
+			return nil, err
+		}
+		result := make([]Position, 0, len(positions))
+		for _, p := range positions {
+			result = append(result, Position{Market: p.Market, Side: string(p.Side), Size: p.Size, EntryPrice: p.EntryPrice})
+		}
+		return result, nil
+	case MethodClosePosition:
+		return orderResult(h.ex.ClosePosition(ctx, r.Market, exchange.OrderSide(r.Side), parseNumber(r.Amount)))
+	default:
+		return nil, errUnknownMethod
+	}
+}
+
+func orderResult(order *exchange.Order, err error) (interface{}, error) {
+	if err != nil {
+		return nil, err
+	}
+	return toOrder(order), nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
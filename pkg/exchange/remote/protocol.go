@@ -0,0 +1,141 @@
+// Package remote connects to exchange adapters running as sidecar processes, so venues can be
+// added without forking the bot: an adapter written in any language serves the protocol below,
+// and one written in Go can wrap its exchange.Exchange with Handler.
+//
+// The protocol is JSON over HTTP. Each Exchange method is a POST to /v1/<method> on the sidecar,
+// e.g. /v1/funding_rates, with its arguments as a JSON object in the body. A sidecar replies with
+// status 200 and the JSON result, or with another status and an ErrorResponse. Every request
+// carries the X-Testnet header, "true" or "false", so the sidecar can pick the matching venue.
+// Rates, order amounts and order prices are read as decimals, so they are passed on without
+// binary rounding; they may be sent as JSON numbers or as strings.
+package remote
+
+import (
+	"encoding/json"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/exchange"
+)
+
+// Methods of the protocol, each served at /v1/<method>.
+const (
+	MethodInfo          = "info"
+	MethodFundingRates  = "funding_rates"
+	MethodOrderbook     = "orderbook"
+	MethodMarkPrice     = "mark_price"
+	MethodPlaceOrder    = "place_order"
+	MethodOrderStatus   = "order_status"
+	MethodCancelOrder   = "cancel_order"
+	MethodBalance       = "balance"
+	MethodPositions     = "positions"
+	MethodClosePosition = "close_position"
+)
+
+// TestnetHeader tells the sidecar whether the bot runs on testnet.
+const TestnetHeader = "X-Testnet"
+
+// ErrorResponse is the body of a failed call.
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// Info is the result of the info method. Both fields are optional: the funding interval defaults
+// to an hour and the collateral to USDC.
+type Info struct {
+	FundingIntervalSeconds int64  `json:"funding_interval_seconds,omitempty"`
+	Collateral             string `json:"collateral,omitempty"`
+}
+
+// Request holds the arguments of a call. Each method reads the ones it takes: orderbook and
+// mark_price read market; place_order reads market, side, type, amount and price; order_status
+// and cancel_order read order_id and market; balance reads asset; close_position reads market,
+// side and amount.
+type Request struct {
+	Market  string      `json:"market,omitempty"`
+	OrderID string      `json:"order_id,omitempty"`
+	Side    string      `json:"side,omitempty"`
+	Type    string      `json:"type,omitempty"`
+	Amount  json.Number `json:"amount,omitempty"`
+	Price   json.Number `json:"price,omitempty"`
+	Asset   string      `json:"asset,omitempty"`
+}
+
+// FundingRate is an entry of the funding_rates result. NextTime is a Unix time in seconds.
+// PredictedRate is left out by venues that don't forecast the next rate.
+type FundingRate struct {
+	Market        string       `json:"market"`
+	Rate          json.Number  `json:"rate"`
+	NextTime      int64        `json:"next_time,omitempty"`
+	PredictedRate *json.Number `json:"predicted_rate,omitempty"`
+}
+
+// Level is a price level of the orderbook result, with its size in the base asset.
+type Level struct {
+	Price float64 `json:"price"`
+	Size  float64 `json:"size"`
+}
+
+// Orderbook is the result of orderbook, with bids from the highest price and asks from the
+// lowest.
+type Orderbook struct {
+	Market string  `json:"market"`
+	Bids   []Level `json:"bids"`
+	Asks   []Level `json:"asks"`
+}
+
+// Price is the result of mark_price.
+type Price struct {
+	Price float64 `json:"price"`
+}
+
+// Balance is the result of balance.
+type Balance struct {
+	Balance float64 `json:"balance"`
+}
+
+// Order is the result of place_order, order_status and close_position. Side is BUY or SELL,
+// Type is LIMIT or MARKET and Timestamp is a Unix time in seconds.
+type Order struct {
+	ID        string      `json:"id"`
+	Market    string      `json:"market"`
+	Side      string      `json:"side"`
+	Type      string      `json:"type"`
+	Price     json.Number `json:"price"`
+	Amount    json.Number `json:"amount"`
+	Filled    json.Number `json:"filled"`
+	Status    string      `json:"status"`
+	Timestamp int64       `json:"timestamp,omitempty"`
+}
+
+// Position is an entry of the positions result. Side is BUY for longs and SELL for shorts.
+type Position struct {
+	Market     string  `json:"market"`
+	Side       string  `json:"side"`
+	Size       float64 `json:"size"`
+	EntryPrice float64 `json:"entry_price"`
+}
+
+func toOrder(o *exchange.Order) *Order {
+	return &Order{ID: o.ID, Market: o.Market, Side: string(o.Side), Type: string(o.Type), Price: number(o.Price),
+		Amount: number(o.Amount), Filled: number(o.Filled), Status: o.Status, Timestamp: o.Timestamp}
+}
+
+func fromOrder(o *Order) *exchange.Order {
+	return &exchange.Order{ID: o.ID, Market: o.Market, Side: exchange.OrderSide(o.Side), Type: exchange.OrderType(o.Type),
+		Price: parseNumber(o.Price), Amount: parseNumber(o.Amount), Filled: parseNumber(o.Filled), Status: o.Status, Timestamp: o.Timestamp}
+}
+
+// number writes d as a JSON number.
+func number(d decimal.Decimal) json.Number {
+	return json.Number(d.String())
+}
+
+// parseNumber reads a number of the protocol, zero if it is missing or malformed.
+func parseNumber(n json.Number) decimal.Decimal {
+	d, err := decimal.NewFromString(n.String())
+	if err != nil {
+		return decimal.Zero
+	}
+	return d
+}
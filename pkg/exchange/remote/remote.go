@@ -0,0 +1,215 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/exchange"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/httpclient"
+)
+
+// maxErrorBodySize caps how much of an error response body is kept for error messages.
+const maxErrorBodySize = 4 << 10
+
+// Exchange is an exchange served by a sidecar.
+type Exchange struct {
+	client  *http.Client
+	name    string
+	baseURL string
+	testnet bool
+
+	// info is fetched from the sidecar the first time it is needed.
+	infoOnce sync.Once
+	info     Info
+}
+
+// New creates a client for the sidecar serving the exchange name at baseURL, e.g.
+// "http://localhost:9000".
+func New(name, baseURL string, testnet bool) *Exchange {
+	return &Exchange{
+		client:  &http.Client{Timeout: 30 * time.Second},
+		name:    name,
+		baseURL: strings.TrimRight(baseURL, "/"),
+		testnet: testnet,
+	}
+}
+
+// SetRateLimiter makes every call to the sidecar wait for limiter.
+func (e *Exchange) SetRateLimiter(limiter *httpclient.Limiter) {
+	e.client = limiter.Client(e.client)
+}
+
+func (e *Exchange) Name() string {
+	return e.name
+}
+
+// SetTestnet sets whether calls ask the sidecar for its testnet venue.
+func (e *Exchange) SetTestnet(testnet bool) {
+	e.testnet = testnet
+}
+
+// SetTransport replaces the HTTP transport used for REST requests, e.g. with a recorder in tests.
+func (e *Exchange) SetTransport(rt http.RoundTripper) {
+	e.client.Transport = rt
+}
+
+// FundingInterval returns the funding interval the sidecar reports, or the default if it reports
+// none or can't be reached.
+func (e *Exchange) FundingInterval() time.Duration {
+	if seconds := e.getInfo().FundingIntervalSeconds; seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return exchange.DefaultFundingInterval
+}
+
+// CollateralAsset returns the collateral the sidecar reports, USDC by default.
+func (e *Exchange) CollateralAsset() string {
+	if collateral := e.getInfo().Collateral; collateral != "" {
+		return collateral
+	}
+	return "USDC"
+}
+
+// getInfo fetches the sidecar's info once. A failure leaves the defaults in place, since the
+// methods that need the sidecar report it anyway.
+func (e *Exchange) getInfo() Info {
+	e.infoOnce.Do(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_ = e.call(ctx, MethodInfo, Request{}, &e.info)
+	})
+	return e.info
+}
+
+func (e *Exchange) GetFundingRates(ctx context.Context) ([]*exchange.FundingRate, error) {
+	var result []FundingRate
+	if err := e.call(ctx, MethodFundingRates, Request{}, &result); err != nil {
+		return nil, err
+	}
+	fundingRates := make([]*exchange.FundingRate, len(result))
+	for i, rate := range result {
+		fundingRates[i] = &exchange.FundingRate{Market: rate.Market, Rate: parseNumber(rate.Rate), NextTime: rate.NextTime}
+		if rate.PredictedRate != nil {
+			fundingRates[i].PredictedRate, fundingRates[i].HasPredicted = parseNumber(*rate.PredictedRate), true
+		}
+	}
+	return fundingRates, nil
+}
+
+func (e *Exchange) GetOrderbook(ctx context.Context, market string) (*exchange.Orderbook, error) {
+	var result Orderbook
+	if err := e.call(ctx, MethodOrderbook, Request{Market: market}, &result); err != nil {
+		return nil, err
+	}
+	orderbook := &exchange.Orderbook{Market: market}
+	for _, level := range result.Bids {
+		orderbook.Bids = append(orderbook.Bids, exchange.PriceLevel{Price: level.Price, Size: level.Size})
+	}
+	for _, level := range result.Asks {
+		orderbook.Asks = append(orderbook.Asks, exchange.PriceLevel{Price: level.Price, Size: level.Size})
+	}
+	return orderbook, nil
+}
+
+func (e *Exchange) GetMarkPrice(ctx context.Context, market string) (float64, error) {
+	var result Price
+	if err := e.call(ctx, MethodMarkPrice, Request{Market: market}, &result); err != nil {
+		return 0, err
+	}
+	return result.Price, nil
+}
+
+func (e *Exchange) PlaceOrder(ctx context.Context, market string, side exchange.OrderSide, orderType exchange.OrderType, amount, price decimal.Decimal) (*exchange.Order, error) {
+	var result Order
+	request := Request{Market: market, Side: string(side), Type: string(orderType), Amount: number(amount), Price: number(price)}
+	if err := e.call(ctx, MethodPlaceOrder, request, &result); err != nil {
+		return nil, err
+	}
+	return fromOrder(&result), nil
+}
+
+func (e *Exchange) GetOrderStatus(ctx context.Context, orderID string, market string) (*exchange.Order, error) {
+	var result Order
+	if err := e.call(ctx, MethodOrderStatus, Request{OrderID: orderID, Market: market}, &result); err != nil {
+		return nil, err
+	}
+	return fromOrder(&result), nil
+}
+
+func (e *Exchange) CancelOrder(ctx context.Context, orderID string, market string) error {
+	return e.call(ctx, MethodCancelOrder, Request{OrderID: orderID, Market: market}, nil)
+}
+
+func (e *Exchange) GetBalance(ctx context.Context, asset string) (float64, error) {
+	var result Balance
+	if err := e.call(ctx, MethodBalance, Request{Asset: asset}, &result); err != nil {
+		return 0, err
+	}
+	return result.Balance, nil
+}
+
+func (e *Exchange) GetPositions(ctx context.Context) ([]exchange.Position, error) {
+	var result []Position
+	if err := e.call(ctx, MethodPositions, Request{}, &result); err != nil {
+		return nil, err
+	}
+	var positions []exchange.Position
+	for _, p := range result {
+		positions = append(positions, exchange.Position{Market: p.Market, Side: exchange.OrderSide(p.Side), Size: p.Size, EntryPrice: p.EntryPrice})
+	}
+	return positions, nil
+}
+
+func (e *Exchange) ClosePosition(ctx context.Context, market string, side exchange.OrderSide, amount decimal.Decimal) (*exchange.Order, error) {
+	var result Order
+	if err := e.call(ctx, MethodClosePosition, Request{Market: market, Side: string(side), Amount: number(amount)}, &result); err != nil {
+		return nil, err
+	}
+	return fromOrder(&result), nil
+}
+
+// call sends a method call to the sidecar and decodes its result into out, unless out is nil.
+func (e *Exchange) call(ctx context.Context, method string, request Request, out interface{}) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.baseURL+"/v1/"+method, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(TestnetHeader, strconv.FormatBool(e.testnet))
+
+	resp, err := httpclient.Do(e.client, req)
+	if err != nil {
+		return fmt.Errorf("%s sidecar: %w", e.name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+		var errorResponse ErrorResponse
+		if json.Unmarshal(message, &errorResponse) == nil && errorResponse.Error != "" {
+			return fmt.Errorf("%s sidecar: %s failed: %s", e.name, method, errorResponse.Error)
+		}
+		return fmt.Errorf("%s sidecar: %s failed: %s - %s", e.name, method, resp.Status, message)
+	}
+	if out == nil {
+		_, err = io.Copy(io.Discard, resp.Body)
+		return err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("%s sidecar: invalid %s result: %w", e.name, method, err)
+	}
+	return nil
+}
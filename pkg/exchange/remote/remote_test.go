@@ -0,0 +1,142 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/exchange"
+)
+
+// stubExchange is the adapter a sidecar would serve. It records the calls it receives.
+type stubExchange struct {
+	testnet  bool
+	placed   exchange.Order
+	canceled string
+}
+
+func (s *stubExchange) Name() string                   { return "stub" }
+func (s *stubExchange) SetTestnet(testnet bool)        { s.testnet = testnet }
+func (s *stubExchange) FundingInterval() time.Duration { return 8 * time.Hour }
+func (s *stubExchange) CollateralAsset() string        { return "USDT" }
+func (s *stubExchange) GetMarkPrice(ctx context.Context, market string) (float64, error) {
+	return 65000.5, nil
+}
+
+func (s *stubExchange) GetFundingRates(ctx context.Context) ([]*exchange.FundingRate, error) {
+	return []*exchange.FundingRate{{Market: "BTC-USD", Rate: decimal.NewFromFloat(0.0001), NextTime: 1700003600}, {Market: "ETH-USD", Rate: decimal.NewFromFloat(-0.00002)}}, nil
+}
+
+func (s *stubExchange) GetOrderbook(ctx context.Context, market string) (*exchange.Orderbook, error) {
+	return &exchange.Orderbook{Market: market, Bids: []exchange.PriceLevel{{Price: 64999, Size: 1.5}}, Asks: []exchange.PriceLevel{{Price: 65001, Size: 2}}}, nil
+}
+
+func (s *stubExchange) PlaceOrder(ctx context.Context, market string, side exchange.OrderSide, orderType exchange.OrderType, amount, price decimal.Decimal) (*exchange.Order, error) {
+	s.placed = exchange.Order{ID: "42", Market: market, Side: side, Type: orderType, Amount: amount, Price: price, Status: "NEW", Timestamp: 1700000000}
+	order := s.placed
+	return &order, nil
+}
+
+func (s *stubExchange) GetOrderStatus(ctx context.Context, orderID string, market string) (*exchange.Order, error) {
+	order := s.placed
+	order.Filled, order.Status = order.Amount, "FILLED"
+	return &order, nil
+}
+
+func (s *stubExchange) CancelOrder(ctx context.Context, orderID string, market string) error {
+	s.canceled = orderID
+	return nil
+}
+
+func (s *stubExchange) GetBalance(ctx context.Context, asset string) (float64, error) {
+	if asset != "USDT" {
+		return 0, errors.New("no " + asset + " balance")
+	}
+	return 1234.5, nil
+}
+
+func (s *stubExchange) GetPositions(ctx context.Context) ([]exchange.Position, error) {
+	return []exchange.Position{{Market: "BTC-USD", Side: exchange.Sell, Size: 0.1, EntryPrice: 65010}}, nil
+}
+
+func (s *stubExchange) ClosePosition(ctx context.Context, market string, side exchange.OrderSide, amount decimal.Decimal) (*exchange.Order, error) {
+	closeSide := exchange.Buy
+	if side == exchange.Buy {
+		closeSide = exchange.Sell
+	}
+	return s.PlaceOrder(ctx, market, closeSide, exchange.Market, amount, decimal.Zero)
+}
+
+func TestRoundTrip(t *testing.T) {
+	stub := &stubExchange{}
+	server := httptest.NewServer(Handler(stub))
+	defer server.Close()
+	ex := New("myvenue", server.URL+"/", true)
+	ctx := context.Background()
+
+	if ex.Name() != "myvenue" || ex.FundingInterval() != 8*time.Hour || ex.CollateralAsset() != "USDT" {
+		t.Errorf("unexpected info %s %s %s", ex.Name(), ex.FundingInterval(), ex.CollateralAsset())
+	}
+	if !stub.testnet {
+		t.Error("expected the sidecar to be switched to testnet")
+	}
+
+	rates, err := ex.GetFundingRates(ctx)
+	if err != nil || len(rates) != 2 || rates[0].Market != "BTC-USD" || !rates[0].Rate.Equal(decimal.NewFromFloat(0.0001)) ||
+		rates[0].NextTime != 1700003600 || rates[0].HasPredicted || !rates[1].Rate.Equal(decimal.NewFromFloat(-0.00002)) {
+		t.Errorf("GetFundingRates = %v, %v", rates, err)
+	}
+	orderbook, err := ex.GetOrderbook(ctx, "BTC-USD")
+	if err != nil || orderbook.Bids[0] != (exchange.PriceLevel{Price: 64999, Size: 1.5}) || orderbook.Asks[0].Size != 2 {
+		t.Errorf("GetOrderbook = %+v, %v", orderbook, err)
+	}
+	if price, err := ex.GetMarkPrice(ctx, "BTC-USD"); err != nil || price != 65000.5 {
+		t.Errorf("GetMarkPrice = %f, %v", price, err)
+	}
+
+	order, err := ex.PlaceOrder(ctx, "BTC-USD", exchange.Buy, exchange.Limit, decimal.NewFromFloat(0.1), decimal.NewFromInt(64000))
+	if err != nil || order.ID != stub.placed.ID || !order.Amount.Equal(stub.placed.Amount) || !order.Price.Equal(stub.placed.Price) ||
+		order.Timestamp != stub.placed.Timestamp || stub.placed.Side != exchange.Buy || !stub.placed.Amount.Equal(decimal.NewFromFloat(0.1)) ||
+		!stub.placed.Price.Equal(decimal.NewFromInt(64000)) {
+		t.Errorf("PlaceOrder = %+v, %v; the sidecar placed %+v", order, err, stub.placed)
+	}
+	if status, err := ex.GetOrderStatus(ctx, "42", "BTC-USD"); err != nil || status.Status != "FILLED" || !status.Filled.Equal(decimal.NewFromFloat(0.1)) {
+		t.Errorf("GetOrderStatus = %+v, %v", status, err)
+	}
+	if err := ex.CancelOrder(ctx, "42", "BTC-USD"); err != nil || stub.canceled != "42" {
+		t.Errorf("CancelOrder: %v, canceled %q", err, stub.canceled)
+	}
+	positions, err := ex.GetPositions(ctx)
+	if err != nil || len(positions) != 1 || positions[0] != (exchange.Position{Market: "BTC-USD", Side: exchange.Sell, Size: 0.1, EntryPrice: 65010}) {
+		t.Errorf("GetPositions = %+v, %v", positions, err)
+	}
+	if closed, err := ex.ClosePosition(ctx, "BTC-USD", exchange.Sell, decimal.NewFromFloat(0.1)); err != nil || closed.Side != exchange.Buy || closed.Type != exchange.Market {
+		t.Errorf("ClosePosition = %+v, %v", closed, err)
+	}
+
+	if balance, err := ex.GetBalance(ctx, "USDT"); err != nil || balance != 1234.5 {
+		t.Errorf("GetBalance = %f, %v", balance, err)
+	}
+	if _, err := ex.GetBalance(ctx, "USDC"); err == nil || !strings.Contains(err.Error(), "no USDC balance") {
+		t.Errorf("expected the sidecar's error, got %v", err)
+	}
+}
+
+func TestUnreachableSidecar(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	ex := New("myvenue", server.URL, false)
+	server.Close()
+
+	if _, err := ex.GetFundingRates(context.Background()); err == nil || !strings.Contains(err.Error(), "myvenue sidecar") {
+		t.Errorf("expected an error naming the venue, got %v", err)
+	}
+	if ex.FundingInterval() != exchange.DefaultFundingInterval || ex.CollateralAsset() != "USDC" {
+		t.Errorf("expected the defaults without info, got %s %s", ex.FundingInterval(), ex.CollateralAsset())
+	}
+}
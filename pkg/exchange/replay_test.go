@@ -0,0 +1,51 @@
+package exchange
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	sdk "github.com/extended-protocol/extended-sdk-golang/src"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/vcr"
+)
+
+// TestExtendedReplay replays recorded Extended testnet responses. Re-record the cassette with
+// VCR_MODE=record go test ./pkg/exchange -run TestExtendedReplay
+func TestExtendedReplay(t *testing.T) {
+	recorder, err := vcr.New("testdata/extended_market_data.json", vcr.ModeFromEnv(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := recorder.Save(); err != nil {
+			t.Errorf("failed to save cassette: %v", err)
+		}
+	}()
+
+	ex := &Extended{
+		httpClient: recorder.Client(),
+		baseURL:    ExtendedTestnetBaseURL,
+		testnet:    true,
+		templates:  make(map[string]sdk.CreateOrderObjectParams),
+	}
+
+	price, err := ex.GetMarkPrice(context.Background(), "BTC-USD")
+	if err != nil || price <= 0 {
+		t.Fatalf("GetMarkPrice = %f, %v", price, err)
+	}
+
+	prices, err := ex.GetMarkPrices(context.Background(), []string{"BTC-USD", "ETH-USD"})
+	if err != nil || len(prices) != 2 {
+		t.Fatalf("GetMarkPrices = %v, %v", prices, err)
+	}
+
+	stats, err := ex.GetMarketStats(context.Background(), []string{"BTC-USD", "ETH-USD"})
+	if err != nil || stats["BTC-USD"].Volume24hUSD <= 0 || stats["ETH-USD"].OpenInterestUSD <= 0 {
+		t.Fatalf("GetMarketStats = %+v, %v", stats, err)
+	}
+
+	if _, err := ex.GetBalance(context.Background(), "USDC"); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("expected an unauthorized error without an API key, got %v", err)
+	}
+}
@@ -0,0 +1,44 @@
+package exchange
+
+import (
+	"context"
+	"errors"
+	"math"
+)
+
+// ErrPositionRiskUnsupported is returned by GetPositionRisk on wrappers whose exchange doesn't
+// report liquidation prices, and on venues whose positions can't be liquidated.
+var ErrPositionRiskUnsupported = errors.New("position risk is not reported")
+
+// PositionRisk is the margin and liquidation price of an open position. Side is Buy for longs
+// and Sell for shorts; Size is always positive, in the base asset.
+type PositionRisk struct {
+	Market           string
+	Side             OrderSide
+	Size             float64
+	MarkPrice        float64
+	LiquidationPrice float64
+	// Margin is the collateral backing the position: its own margin when isolated, or the
+	// account's when cross-margined.
+	Margin float64
+}
+
+// LiquidationDistance returns how far the mark price can move against the position before it is
+// liquidated, as a fraction of the mark price. It reports false when the venue gave no
+// liquidation price, e.g. because the position is fully collateralized.
+func (r PositionRisk) LiquidationDistance() (float64, bool) {
+	if r.MarkPrice <= 0 || r.LiquidationPrice <= 0 {
+		return 0, false
+	}
+	if r.Side == Sell {
+		return math.Max(r.LiquidationPrice-r.MarkPrice, 0) / r.MarkPrice, true
+	}
+	return math.Max(r.MarkPrice-r.LiquidationPrice, 0) / r.MarkPrice, true
+}
+
+// PositionRiskReporter is implemented by exchanges that report how close the account's positions
+// are to liquidation.
+type PositionRiskReporter interface {
+	// GetPositionRisk returns the risk of the open position in market, or an error if there is none.
+	GetPositionRisk(ctx context.Context, market string) (*PositionRisk, error)
+}
@@ -0,0 +1,78 @@
+package exchange
+
+import (
+	"math/big"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// decimalStep is a lot or tick size as venues list it. Quantities are rounded to it in decimal
+// arithmetic and sent with the number of decimals it is written with, so an amount such as 0.3
+// with a 0.1 step is neither floored to 0.2 nor sent as 0.30000000000000004.
+type decimalStep struct {
+	step     decimal.Decimal
+	decimals int
+}
+
+func parseDecimalStep(s string) decimalStep {
+	step, err := decimal.NewFromString(strings.TrimSpace(s))
+	if err != nil || !step.IsPositive() {
+		return decimalStep{}
+	}
+	// String drops trailing zeros, so "0.0100" is written with two decimals.
+	decimals := 0
+	if _, fraction, ok := strings.Cut(step.String(), "."); ok {
+		decimals = len(fraction)
+	}
+	return decimalStep{step: step, decimals: decimals}
+}
+
+// String returns the step as the venue lists it, or "0" when it is unknown.
+func (s decimalStep) String() string {
+	return s.step.String()
+}
+
+// floor rounds v down to a multiple of the step.
+func (s decimalStep) floor(v decimal.Decimal) decimal.Decimal {
+	if !s.step.IsPositive() {
+		return v
+	}
+	return v.Div(s.step).Floor().Mul(s.step)
+}
+
+// round rounds v to the nearest multiple of the step.
+func (s decimalStep) round(v decimal.Decimal) decimal.Decimal {
+	if !s.step.IsPositive() {
+		return v
+	}
+	return v.Div(s.step).Round(0).Mul(s.step)
+}
+
+// format writes v with the decimals of the step, or as is when the step is unknown.
+func (s decimalStep) format(v decimal.Decimal) string {
+	if !s.step.IsPositive() {
+		return v.String()
+	}
+	return v.StringFixed(int32(s.decimals))
+}
+
+// CommonStep returns the smallest step that is a multiple of both a and b, so an amount rounded to
+// it is accepted by both venues. A zero step accepts any amount.
+func CommonStep(a, b decimal.Decimal) decimal.Decimal {
+	if !a.IsPositive() {
+		return b
+	}
+	if !b.IsPositive() {
+		return a
+	}
+	// Scale both to integers and take their least common multiple.
+	exp := a.Exponent()
+	if b.Exponent() < exp {
+		exp = b.Exponent()
+	}
+	x, y := a.Shift(-exp).BigInt(), b.Shift(-exp).BigInt()
+	gcd := new(big.Int).GCD(nil, nil, x, y)
+	lcm := new(big.Int).Mul(new(big.Int).Quo(x, gcd), y)
+	return decimal.NewFromBigInt(lcm, exp)
+}
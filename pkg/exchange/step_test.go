@@ -0,0 +1,39 @@
+package exchange
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestDecimalStepRoundsExactly(t *testing.T) {
+	lot := parseDecimalStep("0.100")
+	// 0.3/0.1 is 2.9999999999999996 in float64, which floors to 0.2.
+	if got := lot.floor(decimal.RequireFromString("0.3")); got.String() != "0.3" {
+		t.Errorf("floor(0.3) = %s, want 0.3", got)
+	}
+	if got := lot.format(decimal.NewFromFloat(0.1).Add(decimal.NewFromFloat(0.2))); got != "0.3" {
+		t.Errorf("format(0.1+0.2) = %q, want 0.3", got)
+	}
+	tick := parseDecimalStep("1e-05")
+	if got := tick.format(tick.round(decimal.RequireFromString("65000.123456"))); got != "65000.12346" {
+		t.Errorf("round = %q, want 65000.12346", got)
+	}
+	if got := parseDecimalStep("0").floor(decimal.RequireFromString("1.23456")); got.String() != "1.23456" {
+		t.Errorf("a zero step must not round, got %s", got)
+	}
+}
+
+func TestCommonStep(t *testing.T) {
+	for _, c := range []struct{ a, b, want string }{
+		{"0.001", "0.01", "0.01"},
+		{"0.002", "0.003", "0.006"},
+		{"0.5", "0.2", "1"},
+		{"0", "0.01", "0.01"},
+	} {
+		got := CommonStep(decimal.RequireFromString(c.a), decimal.RequireFromString(c.b))
+		if !got.Equal(decimal.RequireFromString(c.want)) {
+			t.Errorf("CommonStep(%s, %s) = %s, want %s", c.a, c.b, got, c.want)
+		}
+	}
+}
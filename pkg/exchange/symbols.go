@@ -0,0 +1,317 @@
+package exchange
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// SymbolMap holds the market name a venue lists an asset under, where it differs from the name
+// the bot uses for it, keyed by "EXCHANGE:MARKET". Every client already converts the bot's
+// "BTC-USD" into its own symbol, such as BTCUSDT, BTC-USDT-SWAP or BTC-PERP, so entries are only
+// needed for assets a venue lists under another ticker, e.g. after a rebrand.
+type SymbolMap map[string]string
+
+// ParseSymbolMap parses entries of the form "EXCHANGE:MARKET=VENUE_MARKET", e.g.
+// "Binance:MATIC-USD=POL-USD". Both markets are written the bot's way, BASE-USD.
+func ParseSymbolMap(entries []string) (SymbolMap, error) {
+	symbols := make(SymbolMap)
+	venueMarkets := make(map[string]string)
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		target, value, ok := strings.Cut(entry, "=")
+		exchangeName, market, hasMarket := strings.Cut(strings.TrimSpace(target), ":")
+		venueMarket := strings.ToUpper(strings.TrimSpace(value))
+		market = strings.ToUpper(strings.TrimSpace(market))
+		if !ok || !hasMarket || exchangeName == "" || market == "" || venueMarket == "" {
+			return nil, fmt.Errorf("invalid symbol mapping %q, expected EXCHANGE:MARKET=VENUE_MARKET", entry)
+		}
+		key := strings.ToLower(strings.TrimSpace(exchangeName)) + ":" + venueMarket
+		if other, ok := venueMarkets[key]; ok && other != market {
+			return nil, fmt.Errorf("invalid symbol mapping %q: %s is already mapped to %s", entry, venueMarket, other)
+		}
+		venueMarkets[key] = market
+		symbols[strings.TrimSpace(exchangeName)+":"+market] = venueMarket
+	}
+	return symbols, nil
+}
+
+// For returns the markets mapped on exchangeName, matched case-insensitively, keyed by the bot's
+// name, or nil if none is.
+func (m SymbolMap) For(exchangeName string) map[string]string {
+	var markets map[string]string
+	for key, venueMarket := range m {
+		name, market, _ := strings.Cut(key, ":")
+		if !strings.EqualFold(name, exchangeName) {
+			continue
+		}
+		if markets == nil {
+			markets = make(map[string]string)
+		}
+		markets[market] = venueMarket
+	}
+	return markets
+}
+
+// Renamed wraps an Exchange that lists some markets under other names, so rates, prices, orders
+// and positions are all reported under the bot's names and compared with the other venues'.
+// Optional capabilities other than those forwarded here are hidden, as with Paper.
+type Renamed struct {
+	Exchange
+	toVenue   map[string]string
+	fromVenue map[string]string
+}
+
+// NewRenamed wraps ex so that each market in markets, keyed by the bot's name, is traded under
+// the venue's name it maps to.
+func NewRenamed(ex Exchange, markets map[string]string) *Renamed {
+	r := &Renamed{Exchange: ex, toVenue: make(map[string]string), fromVenue: make(map[string]string)}
+	for market, venueMarket := range markets {
+		r.toVenue[market] = venueMarket
+		r.fromVenue[venueMarket] = market
+	}
+	return r
+}
+
+// venue returns the venue's name for market.
+func (r *Renamed) venue(market string) string {
+	if venueMarket, ok := r.toVenue[market]; ok {
+		return venueMarket
+	}
+	return market
+}
+
+// bot returns the bot's name for a market named by the venue.
+func (r *Renamed) bot(venueMarket string) string {
+	if market, ok := r.fromVenue[venueMarket]; ok {
+		return market
+	}
+	return venueMarket
+}
+
+func (r *Renamed) Name() string {
+	return r.Exchange.Name()
+}
+
+// FundingInterval forwards to the wrapped exchange.
+func (r *Renamed) FundingInterval() time.Duration {
+	return FundingIntervalOf(r.Exchange)
+}
+
+// CollateralAsset forwards to the wrapped exchange.
+func (r *Renamed) CollateralAsset() string {
+	if a, ok := r.Exchange.(interface{ CollateralAsset() string }); ok {
+		return a.CollateralAsset()
+	}
+	return ""
+}
+
+func (r *Renamed) GetFundingRates(ctx context.Context) ([]*FundingRate, error) {
+	rates, err := r.Exchange.GetFundingRates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	renamed := make([]*FundingRate, 0, len(rates))
+	for _, rate := range rates {
+		// A market listed under the bot's name for another asset would shadow the mapped one.
+		if _, shadowed := r.toVenue[rate.Market]; shadowed && r.fromVenue[rate.Market] == "" {
+			continue
+		}
+		copied := *rate
+		copied.Market = r.bot(rate.Market)
+		renamed = append(renamed, &copied)
+	}
+	return renamed, nil
+}
+
+func (r *Renamed) GetOrderbook(ctx context.Context, market string) (*Orderbook, error) {
+	book, err := r.Exchange.GetOrderbook(ctx, r.venue(market))
+	if err != nil {
+		return nil, err
+	}
+	copied := *book
+	copied.Market = market
+	return &copied, nil
+}
+
+func (r *Renamed) GetMarkPrice(ctx context.Context, market string) (float64, error) {
+	return r.Exchange.GetMarkPrice(ctx, r.venue(market))
+}
+
+func (r *Renamed) PlaceOrder(ctx context.Context, market string, side OrderSide, orderType OrderType, amount, price decimal.Decimal) (*Order, error) {
+	return r.order(r.Exchange.PlaceOrder(ctx, r.venue(market), side, orderType, amount, price))
+}
+
+func (r *Renamed) GetOrderStatus(ctx context.Context, orderID string, market string) (*Order, error) {
+	return r.order(r.Exchange.GetOrderStatus(ctx, orderID, r.venue(market)))
+}
+
+func (r *Renamed) CancelOrder(ctx context.Context, orderID string, market string) error {
+	return r.Exchange.CancelOrder(ctx, orderID, r.venue(market))
+}
+
+func (r *Renamed) GetPositions(ctx context.Context) ([]Position, error) {
+	positions, err := r.Exchange.GetPositions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	renamed := make([]Position, len(positions))
+	for i, p := range positions {
+		p.Market = r.bot(p.Market)
+		renamed[i] = p
+	}
+	return renamed, nil
+}
+
+func (r *Renamed) ClosePosition(ctx context.Context, market string, side OrderSide, amount decimal.Decimal) (*Order, error) {
+	return r.order(r.Exchange.ClosePosition(ctx, r.venue(market), side, amount))
+}
+
+// order renames the market of an order returned by the wrapped exchange.
+func (r *Renamed) order(order *Order, err error) (*Order, error) {
+	if order == nil {
+		return nil, err
+	}
+	copied := *order
+	copied.Market = r.bot(order.Market)
+	return &copied, err
+}
+
+// SetMarginMode forwards to the wrapped exchange.
+func (r *Renamed) SetMarginMode(ctx context.Context, market string, mode MarginMode) error {
+	setter, ok := r.Exchange.(MarginModeSetter)
+	if !ok {
+		return fmt.Errorf("%s does not support selecting the margin mode", r.Exchange.Name())
+	}
+	return setter.SetMarginMode(ctx, r.venue(market), mode)
+}
+
+// SetLeverage forwards to the wrapped exchange.
+func (r *Renamed) SetLeverage(ctx context.Context, market string, leverage float64) error {
+	setter, ok := r.Exchange.(LeverageSetter)
+	if !ok {
+		return fmt.Errorf("%s does not support setting the leverage", r.Exchange.Name())
+	}
+	return setter.SetLeverage(ctx, r.venue(market), leverage)
+}
+
+// GetMarketInfo forwards to the wrapped exchange.
+func (r *Renamed) GetMarketInfo(ctx context.Context, market string) (*MarketInfo, error) {
+	provider, ok := r.Exchange.(MarketInfoProvider)
+	if !ok {
+		return nil, ErrMarketInfoUnsupported
+	}
+	info, err := provider.GetMarketInfo(ctx, r.venue(market))
+	if err != nil {
+		return nil, err
+	}
+	copied := *info
+	copied.Market = market
+	return &copied, nil
+}
+
+// GetMarketStats forwards to the wrapped exchange.
+func (r *Renamed) GetMarketStats(ctx context.Context, markets []string) (map[string]MarketStats, error) {
+	statser, ok := r.Exchange.(MarketStatser)
+	if !ok {
+		return nil, fmt.Errorf("%s does not report market statistics", r.Exchange.Name())
+	}
+	venueMarkets := make([]string, len(markets))
+	for i, market := range markets {
+		venueMarkets[i] = r.venue(market)
+	}
+	stats, err := statser.GetMarketStats(ctx, venueMarkets)
+	if err != nil {
+		return nil, err
+	}
+	renamed := make(map[string]MarketStats, len(stats))
+	for venueMarket, st := range stats {
+		st.Market = r.bot(venueMarket)
+		renamed[st.Market] = st
+	}
+	return renamed, nil
+}
+
+// Stream forwards to the wrapped exchange, renaming the markets of the pushed events.
+func (r *Renamed) Stream(markets []string, handler StreamHandler, stop <-chan struct{}) error {
+	streamer, ok := r.Exchange.(StreamingExchange)
+	if !ok {
+		return ErrStreamingUnsupported
+	}
+	venueMarkets := make([]string, len(markets))
+	for i, market := range markets {
+		venueMarkets[i] = r.venue(market)
+	}
+	renamed := handler
+	if handler.OnFundingRates != nil {
+		renamed.OnFundingRates = func(rates []*FundingRate) {
+			copied := make([]*FundingRate, len(rates))
+			for i, rate := range rates {
+				c := *rate
+				c.Market = r.bot(rate.Market)
+				copied[i] = &c
+			}
+			handler.OnFundingRates(copied)
+		}
+	}
+	if handler.OnMarkPrice != nil {
+		renamed.OnMarkPrice = func(market string, price float64) {
+			handler.OnMarkPrice(r.bot(market), price)
+		}
+	}
+	if handler.OnOrder != nil {
+		renamed.OnOrder = func(order *Order) {
+			copied := *order
+			copied.Market = r.bot(order.Market)
+			handler.OnOrder(&copied)
+		}
+	}
+	return streamer.Stream(venueMarkets, renamed, stop)
+}
+
+// GetFundingPayments forwards to the wrapped exchange.
+func (r *Renamed) GetFundingPayments(ctx context.Context, market string, since time.Time) ([]FundingPayment, error) {
+	reporter, ok := r.Exchange.(FundingPaymentReporter)
+	if !ok {
+		return nil, ErrFundingPaymentsUnsupported
+	}
+	payments, err := reporter.GetFundingPayments(ctx, r.venue(market), since)
+	for i := range payments {
+		payments[i].Market = market
+	}
+	return payments, err
+}
+
+// GetForcedCloses forwards to the wrapped exchange.
+func (r *Renamed) GetForcedCloses(ctx context.Context, market string, since time.Time) ([]ForcedClose, error) {
+	reporter, ok := r.Exchange.(LiquidationReporter)
+	if !ok {
+		return nil, ErrForcedClosesUnsupported
+	}
+	closes, err := reporter.GetForcedCloses(ctx, r.venue(market), since)
+	for i := range closes {
+		closes[i].Market = market
+	}
+	return closes, err
+}
+
+// GetPositionRisk forwards to the wrapped exchange.
+func (r *Renamed) GetPositionRisk(ctx context.Context, market string) (*PositionRisk, error) {
+	reporter, ok := r.Exchange.(PositionRiskReporter)
+	if !ok {
+		return nil, ErrPositionRiskUnsupported
+	}
+	risk, err := reporter.GetPositionRisk(ctx, r.venue(market))
+	if err != nil {
+		return nil, err
+	}
+	copied := *risk
+	copied.Market = market
+	return &copied, nil
+}
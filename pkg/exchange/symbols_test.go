@@ -0,0 +1,67 @@
+package exchange
+
+import (
+	"context"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestParseSymbolMap(t *testing.T) {
+	symbols, err := ParseSymbolMap([]string{"Binance:MATIC-USD=POL-USD", " binance:rndr-usd = render-usd ", "OKX:FTM-USD=S-USD"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	binance := symbols.For("Binance")
+	if len(binance) != 2 || binance["MATIC-USD"] != "POL-USD" || binance["RNDR-USD"] != "RENDER-USD" {
+		t.Errorf("unexpected Binance markets %v", binance)
+	}
+	if symbols.For("Bybit") != nil {
+		t.Error("expected no markets for an unmapped exchange")
+	}
+
+	for _, entry := range []string{"Binance=POL-USD", "Binance:MATIC-USD", ":MATIC-USD=POL-USD"} {
+		if _, err := ParseSymbolMap([]string{entry}); err == nil {
+			t.Errorf("expected %q to be rejected", entry)
+		}
+	}
+	if _, err := ParseSymbolMap([]string{"Binance:MATIC-USD=POL-USD", "Binance:POL-USD=POL-USD"}); err == nil {
+		t.Error("expected two markets mapped to one venue market to be rejected")
+	}
+}
+
+func TestRenamedTranslatesMarkets(t *testing.T) {
+	fake := &fakeExchange{rates: []*FundingRate{
+		{Market: "POL-USD", Rate: decimal.NewFromFloat(0.0001)},
+		{Market: "MATIC-USD", Rate: decimal.NewFromFloat(0.05)},
+		{Market: "BTC-USD", Rate: decimal.NewFromFloat(0.0002)},
+	}}
+	ex := NewRenamed(fake, map[string]string{"MATIC-USD": "POL-USD"})
+
+	rates, err := ex.GetFundingRates(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]float64)
+	for _, rate := range rates {
+		got[rate.Market] = rate.Rate.InexactFloat64()
+	}
+	// The venue's own MATIC-USD is a different asset and must not shadow the mapped one.
+	if len(got) != 2 || got["MATIC-USD"] != 0.0001 || got["BTC-USD"] != 0.0002 {
+		t.Errorf("unexpected rates %v", got)
+	}
+	if fake.rates[0].Market != "POL-USD" {
+		t.Error("the wrapped exchange's rates must not be modified")
+	}
+
+	order, err := ex.PlaceOrder(context.Background(), "MATIC-USD", Buy, Market, decimal.NewFromInt(10), decimal.NewFromFloat(0.5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.Market != "MATIC-USD" {
+		t.Errorf("expected the order to be reported as MATIC-USD, got %s", order.Market)
+	}
+	if _, err := ex.GetMarketInfo(context.Background(), "MATIC-USD"); err != ErrMarketInfoUnsupported {
+		t.Errorf("expected ErrMarketInfoUnsupported from an exchange without market rules, got %v", err)
+	}
+}
@@ -0,0 +1,165 @@
+package exchange
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// wsServer accepts websocket handshakes and hands each connection to serve.
+func wsServer(t *testing.T, serve func(r *http.Request, conn net.Conn, rw *bufio.ReadWriter)) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sum := sha1.Sum([]byte(r.Header.Get("Sec-WebSocket-Key") + wsAcceptGUID))
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Errorf("hijack: %v", err)
+			return
+		}
+		defer conn.Close()
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+			"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(sum[:]) + "\r\n\r\n")
+		rw.Flush()
+		serve(r, conn, rw)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// wsServerFrame encodes an unmasked server frame.
+func wsServerFrame(fin bool, opcode byte, payload string) []byte {
+	head := opcode
+	if fin {
+		head |= 0x80
+	}
+	frame := []byte{head}
+	if len(payload) < 126 {
+		frame = append(frame, byte(len(payload)))
+	} else {
+		frame = append(frame, 126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(len(payload)))
+	}
+	return append(frame, payload...)
+}
+
+func wsURL(server *httptest.Server) string {
+	return "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+func TestWebsocketReadsFragmentsAndAnswersPings(t *testing.T) {
+	pong := make(chan []byte, 1)
+	server := wsServer(t, func(r *http.Request, conn net.Conn, rw *bufio.ReadWriter) {
+		rw.Write(wsServerFrame(true, wsPing, "hi"))
+		rw.Write(wsServerFrame(false, wsText, `{"a":`))
+		rw.Write(wsServerFrame(true, wsContinuation, strings.Repeat(" ", 200)+`1}`))
+		rw.Flush()
+
+		client := &wsConn{conn: conn, br: rw.Reader}
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		if _, opcode, payload, err := client.readFrame(); err == nil && opcode == wsPong {
+			pong <- payload
+		}
+		rw.Write(wsServerFrame(true, wsClose, ""))
+		rw.Flush()
+	})
+
+	conn, err := dialWebsocket(wsURL(server), nil, time.Second)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	message, err := conn.ReadMessage()
+	if err != nil || string(message) != `{"a":`+strings.Repeat(" ", 200)+`1}` {
+		t.Fatalf("expected the reassembled message, got %q, %v", message, err)
+	}
+	select {
+	case payload := <-pong:
+		if string(payload) != "hi" {
+			t.Errorf("expected the pong to echo the ping, got %q", payload)
+		}
+	case <-time.After(time.Second):
+		t.Error("expected a pong")
+	}
+	if _, err := conn.ReadMessage(); err == nil {
+		t.Error("expected the close frame to end the stream")
+	}
+}
+
+func TestExtendedStream(t *testing.T) {
+	accountKey := make(chan string, 1)
+	server := wsServer(t, func(r *http.Request, conn net.Conn, rw *bufio.ReadWriter) {
+		switch r.URL.Path {
+		case extendedStreamPath + "/funding":
+			rw.Write(wsServerFrame(true, wsText, `{"ts":1,"data":{"m":"ETH-USD","T":1,"f":"0.5"},"seq":1}`))
+			rw.Write(wsServerFrame(true, wsText, `{"ts":1,"data":{"m":"BTC-USD","T":1,"f":"0.0001"},"seq":2}`))
+		case extendedStreamPath + "/prices/mark":
+			rw.Write(wsServerFrame(true, wsText, `{"type":"MP","data":{"m":"BTC-USD","p":"65000.5","ts":1},"ts":1,"seq":1}`))
+		case extendedStreamPath + "/account":
+			accountKey <- r.Header.Get("X-Api-Key")
+			rw.Write(wsServerFrame(true, wsText, `{"type":"ORDER","data":{"orders":[{"id":42,"market":"BTC-USD","type":"MARKET","side":"BUY","status":"FILLED","price":"66000","averagePrice":"65010","qty":"0.01","filledQty":"0.01"}]},"ts":1,"seq":1}`))
+		}
+		rw.Flush()
+		// Hold the connection open until the client disconnects.
+		rw.ReadByte()
+	})
+
+	api := newFakeAPI(t)
+	ex := newTestExtended(api)
+	ex.baseURL = server.URL
+
+	rates := make(chan *FundingRate, 4)
+	prices := make(chan float64, 4)
+	orders := make(chan *Order, 4)
+	stop := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- ex.Stream([]string{"BTC-USD"}, StreamHandler{
+			OnFundingRates: func(r []*FundingRate) { rates <- r[0] },
+			OnMarkPrice:    func(market string, price float64) { prices <- price },
+			OnOrder:        func(o *Order) { orders <- o },
+		}, stop)
+	}()
+
+	select {
+	case rate := <-rates:
+		if rate.Market != "BTC-USD" || !rate.Rate.Equal(decimal.NewFromFloat(0.0001)) {
+			t.Errorf("expected only the subscribed market's rate, got %+v", rate)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected a funding rate")
+	}
+	select {
+	case price := <-prices:
+		if price != 65000.5 {
+			t.Errorf("unexpected mark price %f", price)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected a mark price")
+	}
+	select {
+	case order := <-orders:
+		if order.ID != "42" || order.Side != Buy || !order.Price.Equal(decimal.NewFromInt(65010)) || !order.Filled.Equal(decimal.NewFromFloat(0.01)) {
+			t.Errorf("unexpected order %+v", order)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected an order update")
+	}
+	if key := <-accountKey; key != "test-key" {
+		t.Errorf("expected the account stream to authenticate with the API key, got %q", key)
+	}
+
+	close(stop)
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected Stream to return after stop")
+	}
+}
@@ -0,0 +1,205 @@
+// Package execution enters positions as a maker: limit orders rest at the top of the book and are
+// re-priced ("chased") as the market moves, falling back to a market order only when they haven't
+// filled in time. This saves the spread and taker fees that market orders give away.
+package execution
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/exchange"
+)
+
+// Config controls how an order is chased on one exchange.
+type Config struct {
+	// Chases is how many times an unfilled order is cancelled and re-posted at the new top of book.
+	Chases int
+	// RepriceAfter is how long an order rests before it is re-priced.
+	RepriceAfter time.Duration
+	// Timeout bounds the whole chase; whatever is unfilled then is sent as a market order.
+	Timeout time.Duration
+	// PollInterval is how often the order status is checked while an order rests.
+	PollInterval time.Duration
+}
+
+// DefaultConfig is used for exchanges listed without explicit settings.
+var DefaultConfig = Config{Chases: 3, RepriceAfter: 5 * time.Second, Timeout: 30 * time.Second, PollInterval: time.Second}
+
+// ParseConfigs parses entries of the form "EXCHANGE" or "EXCHANGE=CHASES:REPRICE_SECONDS:TIMEOUT_SECONDS",
+// e.g. "Extended" or "Lighter=5:3:20", into the chase settings of each exchange.
+func ParseConfigs(entries []string) (map[string]Config, error) {
+	configs := make(map[string]Config)
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, spec, hasSpec := strings.Cut(entry, "=")
+		cfg := DefaultConfig
+		if hasSpec {
+			parts := strings.Split(spec, ":")
+			if len(parts) != 3 {
+				return nil, fmt.Errorf("invalid maker entry %q, expected EXCHANGE[=CHASES:REPRICE_SECONDS:TIMEOUT_SECONDS]", entry)
+			}
+			chases, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+			if err != nil || chases < 0 {
+				return nil, fmt.Errorf("invalid chase count in maker entry %q", entry)
+			}
+			reprice, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+			if err != nil || reprice <= 0 {
+				return nil, fmt.Errorf("invalid reprice interval in maker entry %q", entry)
+			}
+			timeout, err := strconv.ParseFloat(strings.TrimSpace(parts[2]), 64)
+			if err != nil || timeout <= 0 {
+				return nil, fmt.Errorf("invalid timeout in maker entry %q", entry)
+			}
+			cfg.Chases = chases
+			cfg.RepriceAfter = time.Duration(reprice * float64(time.Second))
+			cfg.Timeout = time.Duration(timeout * float64(time.Second))
+		}
+		configs[strings.TrimSpace(name)] = cfg
+	}
+	return configs, nil
+}
+
+// dust is the fraction of the order size below which the remainder is considered filled.
+var dust = decimal.New(1, -9)
+
+// Chase fills amount of market on ex with limit orders at the top of the book, re-pricing them
+// up to cfg.Chases times, and sends whatever is left after cfg.Timeout as a market order. Venues
+// without an order book go straight to the market order.
+//
+// The returned order sums up every fill, with Price the average fill price. On error it reports
+// what filled before the failure, so a partial position is never lost track of.
+func Chase(ctx context.Context, ex exchange.Exchange, market string, side exchange.OrderSide, amount decimal.Decimal, cfg Config) (*exchange.Order, error) {
+	result := &exchange.Order{Market: market, Side: side, Type: exchange.Limit, Amount: amount, Timestamp: time.Now().Unix()}
+	notional := decimal.Zero
+	fill := func(order *exchange.Order, amount decimal.Decimal) {
+		if !amount.IsPositive() {
+			return
+		}
+		result.ID = order.ID
+		result.Filled = result.Filled.Add(amount)
+		if order.Price.IsPositive() {
+			notional = notional.Add(amount.Mul(order.Price))
+			result.Price = notional.Div(result.Filled)
+		}
+	}
+
+	tolerance := amount.Mul(dust)
+	deadline := time.Now().Add(cfg.Timeout)
+	for attempt := 0; attempt <= cfg.Chases && amount.Sub(result.Filled).GreaterThan(tolerance) && time.Now().Before(deadline); attempt++ {
+		price, err := topOfBook(ctx, ex, market, side)
+		if errors.Is(err, exchange.ErrOrderbookUnsupported) {
+			break
+		}
+		if err != nil {
+			return result, err
+		}
+
+		order, err := ex.PlaceOrder(ctx, market, side, exchange.Limit, amount.Sub(result.Filled), decimal.NewFromFloat(price))
+		if err != nil {
+			return result, fmt.Errorf("failed to post %s limit order on %s: %w", market, ex.Name(), err)
+		}
+		wait := time.Until(deadline)
+		if attempt < cfg.Chases && cfg.RepriceAfter < wait {
+			wait = cfg.RepriceAfter
+		}
+		final, err := rest(ctx, ex, order, wait, cfg.PollInterval)
+		fill(final, filledAmount(final))
+		if err != nil {
+			return result, err
+		}
+	}
+
+	remaining := amount.Sub(result.Filled)
+	if remaining.LessThanOrEqual(tolerance) {
+		result.Status = "FILLED"
+		return result, nil
+	}
+	order, err := ex.PlaceOrder(ctx, market, side, exchange.Market, remaining, result.Price)
+	if err != nil {
+		return result, fmt.Errorf("failed to fill the remaining %s of %s on %s at market: %w", remaining, market, ex.Name(), err)
+	}
+	result.Type = exchange.Market
+	fill(order, remaining)
+	result.Status = "FILLED"
+	return result, nil
+}
+
+// topOfBook returns the price a maker order on side joins: the best bid for a buy and the best
+// ask for a sell.
+func topOfBook(ctx context.Context, ex exchange.Exchange, market string, side exchange.OrderSide) (float64, error) {
+	book, err := ex.GetOrderbook(ctx, market)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get the %s order book on %s: %w", market, ex.Name(), err)
+	}
+	levels := book.Bids
+	if side == exchange.Sell {
+		levels = book.Asks
+	}
+	if len(levels) == 0 || levels[0].Price <= 0 {
+		return 0, fmt.Errorf("the %s order book on %s has no %s side to join", market, ex.Name(), side)
+	}
+	return levels[0].Price, nil
+}
+
+// rest polls order until it fills or wait elapses, then cancels what is left and returns the last
+// known state of the order. The cancel and the final status check ignore cancellation of ctx, so
+// an order is never left resting on shutdown.
+func rest(ctx context.Context, ex exchange.Exchange, order *exchange.Order, wait, poll time.Duration) (*exchange.Order, error) {
+	if filledAmount(order).GreaterThanOrEqual(order.Amount) {
+		return order, nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	ticker := time.NewTicker(poll)
+	defer ticker.Stop()
+
+polling:
+	for {
+		select {
+		case <-ctx.Done():
+			break polling
+		case <-timer.C:
+			break polling
+		case <-ticker.C:
+			status, err := ex.GetOrderStatus(ctx, order.ID, order.Market)
+			if err != nil {
+				break polling
+			}
+			if filledAmount(status).GreaterThanOrEqual(status.Amount) {
+				return status, nil
+			}
+		}
+	}
+
+	unwind := context.WithoutCancel(ctx)
+	cancelErr := ex.CancelOrder(unwind, order.ID, order.Market)
+	status, err := ex.GetOrderStatus(unwind, order.ID, order.Market)
+	if err != nil {
+		return order, fmt.Errorf("could not confirm the state of %s order %s on %s after cancelling it: %w", order.Market, order.ID, ex.Name(), errors.Join(cancelErr, err))
+	}
+	if filledAmount(status).GreaterThanOrEqual(status.Amount) {
+		return status, nil
+	}
+	if cancelErr != nil {
+		return status, fmt.Errorf("failed to cancel %s order %s on %s: %w", order.Market, order.ID, ex.Name(), cancelErr)
+	}
+	return status, ctx.Err()
+}
+
+// filledAmount is how much of order has filled. An order reported FILLED counts as filled in
+// full, and fills within dust of the order size are rounded up to it.
+func filledAmount(order *exchange.Order) decimal.Decimal {
+	if order.Status == "FILLED" || (order.Amount.IsPositive() && order.Filled.GreaterThanOrEqual(order.Amount.Sub(order.Amount.Mul(dust)))) {
+		return order.Amount
+	}
+	return order.Filled
+}
@@ -0,0 +1,123 @@
+package execution
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/exchange"
+)
+
+// fakeVenue rests limit orders until the fillOn-th one (counting from 1) is polled. Its best bid
+// moves up by one with every order posted, so each chase re-prices.
+type fakeVenue struct {
+	exchange.Exchange
+	fillOn int
+
+	mu      sync.Mutex
+	orders  []*exchange.Order
+	cancels int
+}
+
+func (f *fakeVenue) Name() string { return "fake" }
+
+func (f *fakeVenue) GetOrderbook(context.Context, string) (*exchange.Orderbook, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	bid := 100 + float64(len(f.orders))
+	return &exchange.Orderbook{Bids: []exchange.PriceLevel{{Price: bid, Size: 1}}, Asks: []exchange.PriceLevel{{Price: bid + 1, Size: 1}}}, nil
+}
+
+func (f *fakeVenue) PlaceOrder(ctx context.Context, market string, side exchange.OrderSide, orderType exchange.OrderType, amount, price decimal.Decimal) (*exchange.Order, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	order := &exchange.Order{ID: fmt.Sprint(len(f.orders) + 1), Market: market, Side: side, Type: orderType, Price: price, Amount: amount, Status: "OPEN"}
+	if orderType == exchange.Market {
+		order.Price, order.Filled, order.Status = decimal.NewFromInt(110), amount, "FILLED"
+	}
+	f.orders = append(f.orders, order)
+	copied := *order
+	return &copied, nil
+}
+
+func (f *fakeVenue) GetOrderStatus(ctx context.Context, orderID, market string) (*exchange.Order, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, order := range f.orders {
+		if order.ID == orderID {
+			if i+1 == f.fillOn && order.Status == "OPEN" {
+				order.Filled, order.Status = order.Amount, "FILLED"
+			}
+			copied := *order
+			return &copied, nil
+		}
+	}
+	return nil, fmt.Errorf("order %s not found", orderID)
+}
+
+func (f *fakeVenue) CancelOrder(ctx context.Context, orderID, market string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.cancels++
+	for _, order := range f.orders {
+		if order.ID == orderID && order.Status == "OPEN" {
+			order.Status = "CANCELLED"
+		}
+	}
+	return nil
+}
+
+var fastConfig = Config{Chases: 2, RepriceAfter: 10 * time.Millisecond, Timeout: 100 * time.Millisecond, PollInterval: time.Millisecond}
+
+func TestChaseRepricesUntilFilled(t *testing.T) {
+	venue := &fakeVenue{fillOn: 2}
+
+	order, err := Chase(context.Background(), venue, "BTC-USD", exchange.Buy, decimal.NewFromInt(2), fastConfig)
+	if err != nil {
+		t.Fatalf("Chase: %v", err)
+	}
+	if !order.Filled.Equal(decimal.NewFromInt(2)) || order.Type != exchange.Limit || !order.Price.Equal(decimal.NewFromInt(101)) {
+		t.Errorf("expected the re-priced limit order to fill at 101, got %+v", order)
+	}
+	if len(venue.orders) != 2 || !venue.orders[0].Price.Equal(decimal.NewFromInt(100)) || venue.cancels != 1 {
+		t.Errorf("expected the first order at 100 to be cancelled and re-posted, got %d orders and %d cancels", len(venue.orders), venue.cancels)
+	}
+}
+
+func TestChaseFallsBackToMarket(t *testing.T) {
+	venue := &fakeVenue{}
+
+	order, err := Chase(context.Background(), venue, "BTC-USD", exchange.Sell, decimal.NewFromInt(1), fastConfig)
+	if err != nil {
+		t.Fatalf("Chase: %v", err)
+	}
+	if len(venue.orders) != fastConfig.Chases+2 || venue.orders[len(venue.orders)-1].Type != exchange.Market {
+		t.Fatalf("expected %d limit orders and a market order, got %d orders", fastConfig.Chases+1, len(venue.orders))
+	}
+	if !venue.orders[0].Price.Equal(decimal.NewFromInt(101)) {
+		t.Errorf("expected a sell to join the best ask, got %s", venue.orders[0].Price)
+	}
+	if !order.Filled.Equal(decimal.NewFromInt(1)) || order.Type != exchange.Market || order.Status != "FILLED" {
+		t.Errorf("expected the remainder to be filled at market, got %+v", order)
+	}
+}
+
+func TestParseConfigs(t *testing.T) {
+	configs, err := ParseConfigs([]string{"Extended", " Lighter=5:2.5:20"})
+	if err != nil {
+		t.Fatalf("ParseConfigs: %v", err)
+	}
+	if configs["Extended"] != DefaultConfig {
+		t.Errorf("expected the defaults for a bare exchange, got %+v", configs["Extended"])
+	}
+	if got := configs["Lighter"]; got.Chases != 5 || got.RepriceAfter != 2500*time.Millisecond || got.Timeout != 20*time.Second {
+		t.Errorf("unexpected Lighter settings %+v", got)
+	}
+	if _, err := ParseConfigs([]string{"Lighter=5:3"}); err == nil {
+		t.Error("expected an entry without a timeout to be rejected")
+	}
+}
@@ -0,0 +1,270 @@
+package export
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	sheetsScope   = "https://www.googleapis.com/auth/spreadsheets"
+	sheetsBaseURL = "https://sheets.googleapis.com/v4/spreadsheets"
+
+	// Tab names the exporter appends to. They must exist in the spreadsheet.
+	PositionsSheet = "Positions"
+	FundingSheet   = "Funding"
+	DailySheet     = "Daily"
+)
+
+// serviceAccount is the subset of a Google service account key file used for authentication.
+type serviceAccount struct {
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// ClosedPosition is a row describing a completed arbitrage.
+type ClosedPosition struct {
+	Market        string
+	LongExchange  string
+	ShortExchange string
+	SizeUSD       float64
+	EntryRateDiff float64
+	OpenedAt      time.Time
+	ClosedAt      time.Time
+	RealizedPnL   float64
+}
+
+// FundingPayment is a row describing a single funding payment on one leg.
+type FundingPayment struct {
+	Exchange string
+	Market   string
+	Amount   float64
+	Time     time.Time
+}
+
+// DailySummary is a row summarising one day of strategy activity.
+type DailySummary struct {
+	Date            time.Time
+	FundingUSD      float64
+	FeesUSD         float64
+	PositionsOpened int
+	PositionsClosed int
+	ExposureUSD     float64
+}
+
+// SheetsExporter appends strategy records to a Google Sheet using a service account.
+type SheetsExporter struct {
+	spreadsheetID string
+	account       serviceAccount
+	key           *rsa.PrivateKey
+	baseURL       string
+	client        *http.Client
+	logger        *log.Logger
+
+	// pending tracks the appends still in flight, which Close waits for.
+	pending sync.WaitGroup
+
+	mu          sync.Mutex
+	token       string
+	tokenExpiry time.Time
+}
+
+// NewSheetsExporter creates an exporter from a service account key file.
+// It returns nil if the credentials file or spreadsheet ID is not provided.
+func NewSheetsExporter(credentialsFile, spreadsheetID string, logger *log.Logger) (*SheetsExporter, error) {
+	if credentialsFile == "" || spreadsheetID == "" {
+		logger.Println("Google Sheets credentials or spreadsheet ID not provided, Sheets export disabled.")
+		return nil, nil
+	}
+
+	raw, err := os.ReadFile(credentialsFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service account file: %w", err)
+	}
+	var account serviceAccount
+	if err := json.Unmarshal(raw, &account); err != nil {
+		return nil, fmt.Errorf("failed to parse service account file: %w", err)
+	}
+	if account.TokenURI == "" {
+		account.TokenURI = "https://oauth2.googleapis.com/token"
+	}
+
+	block, _ := pem.Decode([]byte(account.PrivateKey))
+	if block == nil {
+		return nil, errors.New("service account private key is not PEM encoded")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse service account private key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("service account private key is not an RSA key")
+	}
+
+	logger.Println("Google Sheets exporter initialized successfully.")
+	return &SheetsExporter{
+		spreadsheetID: spreadsheetID,
+		account:       account,
+		key:           key,
+		baseURL:       sheetsBaseURL,
+		client:        &http.Client{Timeout: 15 * time.Second},
+		logger:        logger,
+	}, nil
+}
+
+// ExportClosedPosition appends a closed position row in the background.
+func (se *SheetsExporter) ExportClosedPosition(p ClosedPosition) {
+	if se == nil {
+		return
+	}
+	se.appendAsync(PositionsSheet, []interface{}{
+		p.ClosedAt.UTC().Format(time.RFC3339), p.Market, p.LongExchange, p.ShortExchange,
+		p.SizeUSD, p.EntryRateDiff, p.OpenedAt.UTC().Format(time.RFC3339), p.RealizedPnL,
+	})
+}
+
+// ExportFundingPayment appends a funding payment row in the background.
+func (se *SheetsExporter) ExportFundingPayment(p FundingPayment) {
+	if se == nil {
+		return
+	}
+	se.appendAsync(FundingSheet, []interface{}{
+		p.Time.UTC().Format(time.RFC3339), p.Exchange, p.Market, p.Amount,
+	})
+}
+
+// ExportDailySummary appends a daily summary row in the background.
+func (se *SheetsExporter) ExportDailySummary(d DailySummary) {
+	if se == nil {
+		return
+	}
+	se.appendAsync(DailySheet, []interface{}{
+		d.Date.UTC().Format("2006-01-02"), d.FundingUSD, d.FeesUSD,
+		d.PositionsOpened, d.PositionsClosed, d.ExposureUSD,
+	})
+}
+
+// Close waits for the rows being appended in the background. It is safe to call on a nil exporter.
+func (se *SheetsExporter) Close() {
+	if se == nil {
+		return
+	}
+	se.pending.Wait()
+}
+
+// appendAsync appends a row without blocking the caller; failures are logged.
+func (se *SheetsExporter) appendAsync(sheet string, row []interface{}) {
+	se.pending.Add(1)
+	go func() {
+		defer se.pending.Done()
+		if err := se.Append(sheet, row); err != nil {
+			se.logger.Printf("Failed to export row to Google Sheet %q: %v", sheet, err)
+		}
+	}()
+}
+
+// Append adds a single row to the end of the given sheet tab.
+func (se *SheetsExporter) Append(sheet string, row []interface{}) error {
+	token, err := se.accessToken()
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{"values": [][]interface{}{row}})
+	if err != nil {
+		return err
+	}
+	endpoint := fmt.Sprintf("%s/%s/values/%s:append?valueInputOption=USER_ENTERED&insertDataOption=INSERT_ROWS",
+		se.baseURL, se.spreadsheetID, url.PathEscape(sheet+"!A1"))
+	req, err := http.NewRequest("POST", endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := se.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("sheets API error: %s", resp.Status)
+	}
+	return nil
+}
+
+// accessToken returns a cached OAuth token, exchanging a signed JWT for a new one when it expires.
+func (se *SheetsExporter) accessToken() (string, error) {
+	se.mu.Lock()
+	defer se.mu.Unlock()
+	if se.token != "" && time.Now().Before(se.tokenExpiry) {
+		return se.token, nil
+	}
+
+	assertion, err := se.signedJWT(time.Now())
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+	resp, err := se.client.PostForm(se.account.TokenURI, form)
+	if err != nil {
+		return "", fmt.Errorf("failed to request Google access token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("failed to decode Google token response: %w", err)
+	}
+	if resp.StatusCode >= 400 || token.AccessToken == "" {
+		return "", fmt.Errorf("google token request failed: %s", resp.Status)
+	}
+
+	se.token = token.AccessToken
+	// Refresh a minute early to avoid using a token that expires mid-request.
+	se.tokenExpiry = time.Now().Add(time.Duration(token.ExpiresIn)*time.Second - time.Minute)
+	return se.token, nil
+}
+
+// signedJWT builds the RS256-signed assertion used in the service account token exchange.
+func (se *SheetsExporter) signedJWT(now time.Time) (string, error) {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	claims, _ := json.Marshal(map[string]interface{}{
+		"iss":   se.account.ClientEmail,
+		"scope": sheetsScope,
+		"aud":   se.account.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, se.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign JWT: %w", err)
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
@@ -0,0 +1,207 @@
+package export
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeGoogle stands in for the OAuth token endpoint and the Sheets API, recording what it receives.
+type fakeGoogle struct {
+	*httptest.Server
+	key *rsa.PrivateKey
+
+	mu          sync.Mutex
+	tokenStatus int
+	appendCode  int
+	assertions  []string
+	appends     map[string][][]interface{}
+	auth        []string
+}
+
+func newFakeGoogle(t *testing.T) *fakeGoogle {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := &fakeGoogle{key: key, tokenStatus: http.StatusOK, appendCode: http.StatusOK, appends: make(map[string][][]interface{})}
+	g.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		if r.URL.Path == "/token" {
+			_ = r.ParseForm()
+			if r.Form.Get("grant_type") != "urn:ietf:params:oauth:grant-type:jwt-bearer" {
+				http.Error(w, "bad grant type", http.StatusBadRequest)
+				return
+			}
+			g.assertions = append(g.assertions, r.Form.Get("assertion"))
+			w.WriteHeader(g.tokenStatus)
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "token-1", "expires_in": 3600})
+			return
+		}
+		// /sheets/{spreadsheet}/values/{range}:append
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/sheets/"), "/")
+		if len(parts) != 3 || parts[0] != "sheet-id" || parts[1] != "values" || !strings.HasSuffix(parts[2], ":append") {
+			http.NotFound(w, r)
+			return
+		}
+		var body struct {
+			Values [][]interface{} `json:"values"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		sheet := strings.TrimSuffix(strings.TrimSuffix(parts[2], ":append"), "!A1")
+		g.appends[sheet] = append(g.appends[sheet], body.Values...)
+		g.auth = append(g.auth, r.Header.Get("Authorization"))
+		w.WriteHeader(g.appendCode)
+	}))
+	t.Cleanup(g.Close)
+	return g
+}
+
+// newTestExporter returns an exporter authenticating against g with a key file written to a
+// temporary directory.
+func newTestExporter(t *testing.T, g *fakeGoogle) *SheetsExporter {
+	der, err := x509.MarshalPKCS8PrivateKey(g.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := json.Marshal(serviceAccount{
+		ClientEmail: "bot@project.iam.gserviceaccount.com",
+		PrivateKey:  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		TokenURI:    g.URL + "/token",
+	})
+	path := filepath.Join(t.TempDir(), "service-account.json")
+	if err := os.WriteFile(path, raw, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	se, err := NewSheetsExporter(path, "sheet-id", log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	se.baseURL = g.URL + "/sheets"
+	se.client = g.Client()
+	return se
+}
+
+func TestSheetsExporterAppendsRowsToEachTab(t *testing.T) {
+	g := newFakeGoogle(t)
+	se := newTestExporter(t, g)
+	opened := time.Date(2024, 3, 15, 8, 0, 0, 0, time.UTC)
+	closed := opened.Add(26 * time.Hour)
+
+	se.ExportClosedPosition(ClosedPosition{Market: "BTC-USD", LongExchange: "extended", ShortExchange: "hyperliquid",
+		SizeUSD: 1000, EntryRateDiff: 0.0004, OpenedAt: opened, ClosedAt: closed, RealizedPnL: 12.5})
+	se.ExportFundingPayment(FundingPayment{Exchange: "extended", Market: "BTC-USD", Amount: -0.25, Time: opened})
+	se.ExportDailySummary(DailySummary{Date: closed, FundingUSD: 3.5, FeesUSD: 1.25, PositionsOpened: 2, PositionsClosed: 1, ExposureUSD: 2000})
+	// Close waits for the rows appended in the background.
+	se.Close()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	want := map[string][]interface{}{
+		PositionsSheet: {"2024-03-16T10:00:00Z", "BTC-USD", "extended", "hyperliquid", 1000.0, 0.0004, "2024-03-15T08:00:00Z", 12.5},
+		FundingSheet:   {"2024-03-15T08:00:00Z", "extended", "BTC-USD", -0.25},
+		DailySheet:     {"2024-03-16", 3.5, 1.25, 2.0, 1.0, 2000.0},
+	}
+	for sheet, row := range want {
+		rows := g.appends[sheet]
+		if len(rows) != 1 {
+			t.Fatalf("%s sheet got %d rows, want 1", sheet, len(rows))
+		}
+		if len(rows[0]) != len(row) {
+			t.Fatalf("%s row = %v, want %v", sheet, rows[0], row)
+		}
+		for i := range row {
+			if rows[0][i] != row[i] {
+				t.Errorf("%s row column %d = %v, want %v", sheet, i, rows[0][i], row[i])
+			}
+		}
+	}
+	for _, auth := range g.auth {
+		if auth != "Bearer token-1" {
+			t.Errorf("append authorized with %q, want the exchanged token", auth)
+		}
+	}
+	// The token is cached between appends.
+	if len(g.assertions) != 1 {
+		t.Errorf("token exchanged %d times, want 1", len(g.assertions))
+	}
+}
+
+func TestSheetsExporterSignsTheTokenAssertion(t *testing.T) {
+	g := newFakeGoogle(t)
+	se := newTestExporter(t, g)
+	if err := se.Append(FundingSheet, []interface{}{"row"}); err != nil {
+		t.Fatal(err)
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	parts := strings.Split(g.assertions[0], ".")
+	if len(parts) != 3 {
+		t.Fatalf("assertion %q is not a JWT", g.assertions[0])
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(&g.key.PublicKey, crypto.SHA256, digest[:], signature); err != nil {
+		t.Fatalf("assertion signature does not verify: %v", err)
+	}
+	raw, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	var claims map[string]interface{}
+	if err := json.Unmarshal(raw, &claims); err != nil {
+		t.Fatal(err)
+	}
+	if claims["iss"] != "bot@project.iam.gserviceaccount.com" || claims["scope"] != sheetsScope || claims["aud"] != g.URL+"/token" {
+		t.Errorf("claims = %v", claims)
+	}
+}
+
+func TestSheetsExporterReportsFailures(t *testing.T) {
+	g := newFakeGoogle(t)
+	g.tokenStatus = http.StatusUnauthorized
+	se := newTestExporter(t, g)
+	if err := se.Append(FundingSheet, []interface{}{"row"}); err == nil || !strings.Contains(err.Error(), "token request failed") {
+		t.Errorf("Append with a rejected token exchange = %v, want a token error", err)
+	}
+
+	g.mu.Lock()
+	g.tokenStatus, g.appendCode = http.StatusOK, http.StatusForbidden
+	g.mu.Unlock()
+	if err := se.Append(FundingSheet, []interface{}{"row"}); err == nil || !strings.Contains(err.Error(), "sheets API error") {
+		t.Errorf("Append rejected by the Sheets API = %v, want an API error", err)
+	}
+}
+
+func TestNilSheetsExporterIsANoOp(t *testing.T) {
+	var se *SheetsExporter
+	se.ExportClosedPosition(ClosedPosition{})
+	se.ExportFundingPayment(FundingPayment{})
+	se.ExportDailySummary(DailySummary{})
+	se.Close()
+
+	se, err := NewSheetsExporter("", "", log.New(io.Discard, "", 0))
+	if se != nil || err != nil {
+		t.Errorf("NewSheetsExporter without credentials = %v, %v, want nil, nil", se, err)
+	}
+}
@@ -0,0 +1,181 @@
+// Package httpclient retries exchange HTTP calls that failed transiently, with exponential backoff
+// and jitter, so a venue hiccup costs a short delay instead of a failed strategy tick.
+//
+// A failure is retryable when the venue rate limited the call (429), failed on its side (5xx),
+// timed out, or dropped the connection. Anything else, such as a rejected order or a bad
+// signature, is returned at once. Requests that aren't idempotent, such as placing an order, are
+// only retried after a 429, which guarantees the venue didn't act on them: retrying one that timed
+// out or failed with a 5xx could execute it twice.
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// Policy is how often and how patiently calls are retried.
+type Policy struct {
+	// Attempts is the number of tries, including the first. Values below 1 mean 1.
+	Attempts int
+	// BaseDelay is the delay before the first retry, doubled for every further retry.
+	BaseDelay time.Duration
+	// MaxDelay caps the delay between tries, including one asked for by a Retry-After header.
+	MaxDelay time.Duration
+}
+
+// DefaultPolicy is the policy used by Do and Retry. Its delays stay well inside the receive
+// windows of signed requests, which are timestamped once and resent as they are.
+var DefaultPolicy = Policy{Attempts: 3, BaseDelay: 250 * time.Millisecond, MaxDelay: 2 * time.Second}
+
+// StatusError is an HTTP response with a 4xx or 5xx status.
+type StatusError struct {
+	StatusCode int
+	Status     string
+	// Body is the start of the response body, which usually explains the error.
+	Body string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("API error: %s - %s", e.Status, e.Body)
+}
+
+// Retryable reports whether err is a transient failure worth retrying: a 429 or 5xx StatusError,
+// a timeout, or a refused, reset or dropped connection.
+func Retryable(err error) bool {
+	var status *StatusError
+	if errors.As(err, &status) {
+		return retryableStatus(status.StatusCode)
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+func retryableStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code >= 500
+}
+
+// idempotent reports whether a request with method can be sent twice without a second effect.
+func idempotent(method string) bool {
+	switch method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// Do sends req with client under DefaultPolicy.
+func Do(client *http.Client, req *http.Request) (*http.Response, error) {
+	return DefaultPolicy.Do(client, req)
+}
+
+// Retry calls fn under DefaultPolicy.
+func Retry(ctx context.Context, fn func() error) error {
+	return DefaultPolicy.Retry(ctx, fn)
+}
+
+// Do sends req with client, resending it while it fails transiently. A response with a retryable
+// status is returned as is once the attempts run out, for the caller to report. Requests whose
+// body can't be replayed are sent once.
+func (p Policy) Do(client *http.Client, req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	for attempt := 1; ; attempt++ {
+		if attempt > 1 && req.Body != nil && req.Body != http.NoBody {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+		resp, err := client.Do(req)
+		last := attempt >= p.Attempts || ctx.Err() != nil || (req.Body != nil && req.Body != http.NoBody && req.GetBody == nil)
+		var delay time.Duration
+		switch {
+		case err != nil:
+			if last || !idempotent(req.Method) || !Retryable(err) {
+				return nil, err
+			}
+			delay = p.backoff(attempt)
+			slog.WarnContext(ctx, "Retrying HTTP request", "method", req.Method, "host", req.URL.Host, "path", req.URL.Path, "attempt", attempt, "error", err)
+		case retryableStatus(resp.StatusCode) && !last && (idempotent(req.Method) || resp.StatusCode == http.StatusTooManyRequests):
+			delay = p.retryAfter(resp, attempt)
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10))
+			resp.Body.Close()
+			slog.WarnContext(ctx, "Retrying HTTP request", "method", req.Method, "host", req.URL.Host, "path", req.URL.Path, "attempt", attempt, "status", resp.Status)
+		default:
+			return resp, err
+		}
+		if err := sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Retry calls fn until it succeeds, fails with an error that isn't Retryable, the attempts run
+// out or ctx is done, and returns its last error. It is for calls made through clients this
+// package can't see into, such as SDKs; fn must be safe to repeat.
+func (p Policy) Retry(ctx context.Context, fn func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= p.Attempts || ctx.Err() != nil || !Retryable(err) {
+			return err
+		}
+		slog.WarnContext(ctx, "Retrying call", "attempt", attempt, "error", err)
+		if err := sleep(ctx, p.backoff(attempt)); err != nil {
+			return err
+		}
+	}
+}
+
+// backoff is the delay after the given failed attempt: a random duration between half and all of
+// BaseDelay doubled per earlier retry, capped at MaxDelay. The jitter keeps concurrent callers
+// from retrying in lockstep.
+func (p Policy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempt && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if delay <= 1 {
+		return delay
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// retryAfter honours a Retry-After header given in seconds, up to MaxDelay, and backs off
+// otherwise.
+func (p Policy) retryAfter(resp *http.Response, attempt int) time.Duration {
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+		delay := time.Duration(seconds) * time.Second
+		if p.MaxDelay > 0 && delay > p.MaxDelay {
+			delay = p.MaxDelay
+		}
+		return delay
+	}
+	return p.backoff(attempt)
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
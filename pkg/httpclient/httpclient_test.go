@@ -0,0 +1,122 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var fast = Policy{Attempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+
+// flaky serves the statuses in order, then 200s, and counts the requests.
+func flaky(statuses ...int) (*httptest.Server, *int32) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		body, _ := io.ReadAll(r.Body)
+		if int(n) <= len(statuses) {
+			w.WriteHeader(statuses[n-1])
+			return
+		}
+		w.Write(body)
+	}))
+	return server, &calls
+}
+
+func TestDoRetriesTransientFailures(t *testing.T) {
+	server, calls := flaky(http.StatusServiceUnavailable, http.StatusTooManyRequests)
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	resp, err := fast.Do(server.Client(), req)
+	if err != nil || resp.StatusCode != http.StatusOK || *calls != 3 {
+		t.Fatalf("expected success on the third try, got %v, %v after %d calls", resp, err, *calls)
+	}
+	resp.Body.Close()
+
+	// Once the attempts run out, the last response is returned for the caller to report.
+	server2, calls2 := flaky(500, 502, 504)
+	defer server2.Close()
+	req, _ = http.NewRequest(http.MethodGet, server2.URL, nil)
+	resp, err = fast.Do(server2.Client(), req)
+	if err != nil || resp.StatusCode != http.StatusGatewayTimeout || *calls2 != 3 {
+		t.Fatalf("expected the third failure back, got %v, %v after %d calls", resp, err, *calls2)
+	}
+	resp.Body.Close()
+}
+
+func TestDoOnlyRetriesRateLimitedWrites(t *testing.T) {
+	server, calls := flaky(http.StatusTooManyRequests, http.StatusInternalServerError)
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, server.URL, strings.NewReader(`{"side":"buy"}`))
+	resp, err := fast.Do(server.Client(), req)
+	if err != nil || resp.StatusCode != http.StatusInternalServerError || *calls != 2 {
+		t.Fatalf("expected the 429 to be retried and the 500 returned, got %v, %v after %d calls", resp, err, *calls)
+	}
+	resp.Body.Close()
+
+	server2, calls2 := flaky(http.StatusTooManyRequests)
+	defer server2.Close()
+	req, _ = http.NewRequest(http.MethodPost, server2.URL, strings.NewReader(`{"side":"buy"}`))
+	resp, err = fast.Do(server2.Client(), req)
+	if err != nil || *calls2 != 2 {
+		t.Fatalf("expected a retry, got %v after %d calls", err, *calls2)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != `{"side":"buy"}` {
+		t.Errorf("retried request body = %q, want it resent in full", body)
+	}
+}
+
+func TestRetryStopsOnFatalErrors(t *testing.T) {
+	calls := 0
+	err := fast.Retry(context.Background(), func() error {
+		calls++
+		return &StatusError{StatusCode: http.StatusBadRequest, Status: "400 Bad Request"}
+	})
+	if err == nil || calls != 1 {
+		t.Errorf("expected a 400 to fail at once, got %v after %d calls", err, calls)
+	}
+
+	calls = 0
+	err = fast.Retry(context.Background(), func() error {
+		calls++
+		if calls < 3 {
+			return io.ErrUnexpectedEOF
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Errorf("expected a dropped connection to be retried, got %v after %d calls", err, calls)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	calls = 0
+	err = fast.Retry(ctx, func() error {
+		calls++
+		return &StatusError{StatusCode: http.StatusServiceUnavailable}
+	})
+	if calls != 1 || !errors.As(err, new(*StatusError)) {
+		t.Errorf("expected no retry once the context is done, got %v after %d calls", err, calls)
+	}
+}
+
+func TestBackoffGrowsWithJitterUpToTheCap(t *testing.T) {
+	p := Policy{Attempts: 10, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	for attempt, max := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 3: 400 * time.Millisecond, 8: time.Second} {
+		for i := 0; i < 20; i++ {
+			if d := p.backoff(attempt); d < max/2 || d > max {
+				t.Fatalf("backoff(%d) = %s, want between %s and %s", attempt, d, max/2, max)
+			}
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package httpclient
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Limiter is a token bucket shared by every call to one API: it holds up to burst tokens, refills
+// at rate tokens per second, and each call takes one, waiting for it when the bucket is empty.
+// A nil *Limiter doesn't limit.
+type Limiter struct {
+	rate  float64
+	burst float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// NewLimiter returns a limiter allowing rate calls per second on average and bursts of up to burst
+// calls. A burst below 1 means 1.
+func NewLimiter(rate float64, burst int) *Limiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &Limiter{rate: rate, burst: float64(burst), tokens: float64(burst)}
+}
+
+// Wait takes a token, blocking until one is available or ctx is done.
+func (l *Limiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	delay := l.reserve(time.Now())
+	if delay <= 0 {
+		return nil
+	}
+	if err := sleep(ctx, delay); err != nil {
+		// Give the token back, so an abandoned call doesn't slow down the next ones.
+		l.mu.Lock()
+		l.tokens = math.Min(l.tokens+1, l.burst)
+		l.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// reserve takes a token at now and returns how long to wait until it is due. Tokens go negative
+// while callers queue, so each waits for its own refill rather than racing for the next one.
+func (l *Limiter) reserve(now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.last.IsZero() {
+		l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	}
+	l.last = now
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+// Client returns a copy of client whose requests wait for l first, retries included. A nil l
+// returns client itself.
+func (l *Limiter) Client(client *http.Client) *http.Client {
+	if l == nil {
+		return client
+	}
+	limited := *client
+	base := client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	limited.Transport = &limitedTransport{limiter: l, base: base}
+	return &limited
+}
+
+type limitedTransport struct {
+	limiter *Limiter
+	base    http.RoundTripper
+}
+
+func (t *limitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.limiter.Wait(req.Context()); err != nil {
+		return nil, err
+	}
+	return t.base.RoundTrip(req)
+}
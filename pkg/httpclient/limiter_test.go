@@ -0,0 +1,62 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLimiterAllowsABurstThenTheRate(t *testing.T) {
+	l := NewLimiter(10, 3)
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		if d := l.reserve(now); d != 0 {
+			t.Fatalf("call %d of the burst waited %s", i+1, d)
+		}
+	}
+	// Queued callers are spaced by the refill interval.
+	if d := l.reserve(now); d != 100*time.Millisecond {
+		t.Errorf("4th call waits %s, want 100ms", d)
+	}
+	if d := l.reserve(now); d != 200*time.Millisecond {
+		t.Errorf("5th call waits %s, want 200ms", d)
+	}
+	// A quiet second refills the bucket, but never beyond the burst.
+	later := now.Add(time.Second)
+	for i := 0; i < 3; i++ {
+		if d := l.reserve(later); d != 0 {
+			t.Fatalf("call %d after refilling waited %s", i+1, d)
+		}
+	}
+	if d := l.reserve(later); d <= 0 {
+		t.Error("expected the bucket to hold at most the burst")
+	}
+}
+
+func TestLimitedClientWaitsAndGivesUpWithTheContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	client := NewLimiter(1, 1).Client(server.Client())
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	req, _ = http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	start := time.Now()
+	if _, err := client.Do(req); err == nil || time.Since(start) > 500*time.Millisecond {
+		t.Errorf("expected the second call to give up with its context, got %v after %s", err, time.Since(start))
+	}
+
+	var unlimited *Limiter
+	if unlimited.Client(server.Client()) != server.Client() || unlimited.Wait(context.Background()) != nil {
+		t.Error("a nil limiter must not limit")
+	}
+}
@@ -0,0 +1,102 @@
+package instance
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+// ErrAlreadyRunning is returned when another bot instance holds the lock.
+var ErrAlreadyRunning = errors.New("another instance of the bot is already running against these accounts")
+
+// Lock is an exclusive, process-wide lock on a set of exchange accounts, one lock file each.
+type Lock struct {
+	paths []string
+	files []*os.File
+}
+
+// LockPath returns the lock file path for an account identifier. Instances trading the account
+// resolve to the same file, whatever other accounts they trade.
+func LockPath(dir, accountID string) string {
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	sum := sha256.Sum256([]byte(accountID))
+	return filepath.Join(dir, "funding-rate-arb-bot-"+hex.EncodeToString(sum[:8])+".lock")
+}
+
+// LockPaths returns the lock file path of every account identifier, see LockPath.
+func LockPaths(dir string, accountIDs ...string) []string {
+	paths := make([]string, 0, len(accountIDs))
+	for _, id := range accountIDs {
+		paths = append(paths, LockPath(dir, id))
+	}
+	return paths
+}
+
+// Acquire takes the lock at every path, in sorted order so two instances can't each hold a part.
+// It returns ErrAlreadyRunning if another live process holds any of them, and then holds none.
+func Acquire(paths ...string) (*Lock, error) {
+	paths = append([]string(nil), paths...)
+	sort.Strings(paths)
+	lock := &Lock{}
+	for i, path := range paths {
+		if i > 0 && path == paths[i-1] {
+			continue
+		}
+		file, err := acquire(path)
+		if err != nil {
+			lock.Release()
+			return nil, err
+		}
+		lock.paths = append(lock.paths, path)
+		lock.files = append(lock.files, file)
+	}
+	return lock, nil
+}
+
+// acquire takes the lock file at path.
+func acquire(path string) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file %s: %w", path, err)
+	}
+
+	if err := lockFile(file); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("%w (lock file %s)", ErrAlreadyRunning, path)
+	}
+
+	// Record the owner to make stale locks easy to diagnose.
+	if err := file.Truncate(0); err == nil {
+		_, _ = file.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	}
+	return file, nil
+}
+
+// Paths returns the locations of the lock files.
+func (l *Lock) Paths() []string {
+	return l.paths
+}
+
+// Release frees the lock. The files are left in place, emptied of the owner's PID: removing them
+// would let a process that opened one just before, and locks it just after, hold a lock on a file
+// nobody else can find.
+func (l *Lock) Release() error {
+	if l == nil {
+		return nil
+	}
+	var errs []error
+	for _, file := range l.files {
+		file.Truncate(0)
+		errs = append(errs, unlockFile(file))
+		file.Close()
+	}
+	l.files = nil
+	return errors.Join(errs...)
+}
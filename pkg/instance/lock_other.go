@@ -0,0 +1,37 @@
+//go:build !unix
+
+package instance
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// lockFile falls back to a marker check on platforms without flock: a lock file holding the PID
+// of a running process is treated as held. Release empties the file, and the PID of a process
+// that is gone, e.g. after a crash, is ignored.
+func lockFile(file *os.File) error {
+	content, err := os.ReadFile(file.Name())
+	if err != nil {
+		return err
+	}
+	owner := strings.TrimSpace(string(content))
+	if owner == "" {
+		return nil
+	}
+	pid, err := strconv.Atoi(owner)
+	if err != nil {
+		return errors.New("lock file is not empty")
+	}
+	if process, err := os.FindProcess(pid); err == nil {
+		process.Release()
+		return errors.New("lock file is held by process " + owner)
+	}
+	return nil
+}
+
+func unlockFile(file *os.File) error {
+	return nil
+}
@@ -0,0 +1,60 @@
+package instance
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestAcquireRefusesSecondInstance(t *testing.T) {
+	paths := LockPaths(t.TempDir(), "lighter-key", "extended-key:1")
+
+	first, err := Acquire(paths...)
+	if err != nil {
+		t.Fatalf("first acquire failed: %v", err)
+	}
+
+	if _, err := Acquire(paths...); !errors.Is(err, ErrAlreadyRunning) {
+		t.Fatalf("expected ErrAlreadyRunning, got %v", err)
+	}
+
+	if err := first.Release(); err != nil {
+		t.Fatalf("release failed: %v", err)
+	}
+	for _, path := range paths {
+		if info, err := os.Stat(path); err != nil || info.Size() != 0 {
+			t.Errorf("expected the lock file to stay, emptied, after release: %v", err)
+		}
+	}
+
+	second, err := Acquire(paths...)
+	if err != nil {
+		t.Fatalf("acquire after release failed: %v", err)
+	}
+	second.Release()
+}
+
+func TestAcquireRefusesASharedAccount(t *testing.T) {
+	dir := t.TempDir()
+	first, err := Acquire(LockPaths(dir, "binance:key", "extended:key:1")...)
+	if err != nil {
+		t.Fatalf("first acquire failed: %v", err)
+	}
+	defer first.Release()
+
+	if _, err := Acquire(LockPaths(dir, "binance:key", "extended:key:2")...); !errors.Is(err, ErrAlreadyRunning) {
+		t.Fatalf("expected ErrAlreadyRunning for a configuration sharing the Binance account, got %v", err)
+	}
+	// The failed attempt must not keep the Extended vault 2 lock it took.
+	other, err := Acquire(LockPaths(dir, "extended:key:2")...)
+	if err != nil {
+		t.Fatalf("expected the other vault to be free: %v", err)
+	}
+	other.Release()
+}
+
+func TestLockPathDependsOnAccounts(t *testing.T) {
+	if LockPath("/tmp", "a") == LockPath("/tmp", "b") {
+		t.Fatal("expected different lock paths for different accounts")
+	}
+}
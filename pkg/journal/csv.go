@@ -0,0 +1,44 @@
+package journal
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+)
+
+// csvHeader names the columns written by WriteCSV.
+var csvHeader = []string{"time", "type", "exchange", "market", "order_id", "side", "amount", "price", "fee", "decision_price", "latency_ms", "funding", "message"}
+
+// WriteCSV writes entries as CSV with a header row, one row per entry.
+func WriteCSV(w io.Writer, entries []Entry) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if err := cw.Write([]string{
+			e.Time.UTC().Format(time.RFC3339Nano),
+			string(e.Type),
+			e.Exchange,
+			e.Market,
+			e.OrderID,
+			e.Side,
+			formatFloat(e.Amount),
+			formatFloat(e.Price),
+			formatFloat(e.Fee),
+			formatFloat(e.DecisionPrice),
+			strconv.FormatInt(e.LatencyMs, 10),
+			formatFloat(e.Funding),
+			e.Message,
+		}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
@@ -0,0 +1,8 @@
+//go:build sqlite
+
+package journal
+
+// The pure-Go SQLite driver is only linked in when building with -tags sqlite, so the default
+// build doesn't pull in a database engine that most deployments never use. Add it to the module
+// first with `go get modernc.org/sqlite`.
+import _ "modernc.org/sqlite"
@@ -0,0 +1,142 @@
+// Package journal records every order, fill, close, funding payment and error the bot sees, so
+// exchange statements can be reconciled and PnL computed from what actually happened.
+package journal
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// EntryType classifies a journal entry.
+type EntryType string
+
+const (
+	// EntryOrder is an order submitted to an exchange, whether or not it was accepted.
+	EntryOrder EntryType = "ORDER"
+	// EntryFill is an executed order.
+	EntryFill EntryType = "FILL"
+	// EntryClose is an arbitrage position being closed.
+	EntryClose EntryType = "CLOSE"
+	// EntryError is a failed order or another error that left the account in an unexpected state.
+	EntryError EntryType = "ERROR"
+	// EntryFunding is a funding payment received (positive) or paid (negative).
+	EntryFunding EntryType = "FUNDING"
+	// EntryForcedClose is an arbitrage position closed because an exchange liquidated or
+	// auto-deleveraged one of its legs.
+	EntryForcedClose EntryType = "FORCED_CLOSE"
+)
+
+// Entry is a single record in the trade journal.
+type Entry struct {
+	Time     time.Time `json:"time"`
+	Type     EntryType `json:"type"`
+	Exchange string    `json:"exchange"`
+	Market   string    `json:"market"`
+	OrderID  string    `json:"orderId,omitempty"`
+	Side     string    `json:"side,omitempty"`
+	Amount   float64   `json:"amount,omitempty"`
+	Price    float64   `json:"price,omitempty"`
+	Fee      float64   `json:"fee,omitempty"`
+	// DecisionPrice is the mark price when the order was decided on; LatencyMs is the time from
+	// submitting the order to the exchange acknowledging it.
+	DecisionPrice float64 `json:"decisionPrice,omitempty"`
+	LatencyMs     int64   `json:"latencyMs,omitempty"`
+	// Funding is the funding payment in USD for EntryFunding entries.
+	Funding float64 `json:"funding,omitempty"`
+	Message string  `json:"message,omitempty"`
+}
+
+// Journal is an append-only trade journal, stored as JSON lines or, for paths ending in .db,
+// .sqlite or .sqlite3, in a SQLite database.
+type Journal struct {
+	mu   sync.Mutex
+	file *os.File
+	db   *sql.DB
+}
+
+// Open opens (or creates) the journal at path. It returns nil if path is empty.
+func Open(path string) (*Journal, error) {
+	if path == "" {
+		return nil, nil
+	}
+	if isSQLite(path) {
+		db, err := openSQLite(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open journal %s: %w", path, err)
+		}
+		return &Journal{db: db}, nil
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open journal %s: %w", path, err)
+	}
+	return &Journal{file: file}, nil
+}
+
+// Record appends an entry to the journal. Entries without a time are stamped with the current time.
+func (j *Journal) Record(e Entry) error {
+	if j == nil {
+		return nil
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	if j.db != nil {
+		return insertSQLite(j.db, e)
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	_, err = j.file.Write(append(line, '\n'))
+	return err
+}
+
+// Close closes the underlying file.
+func (j *Journal) Close() error {
+	if j == nil {
+		return nil
+	}
+	if j.db != nil {
+		return j.db.Close()
+	}
+	return j.file.Close()
+}
+
+// ReadAll loads every entry of the journal at path in the order they were written.
+func ReadAll(path string) ([]Entry, error) {
+	if isSQLite(path) {
+		return readSQLite(path)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("journal %s line %d: %w", path, line, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
@@ -0,0 +1,72 @@
+package journal
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJournalRoundTripAndCSVExport(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+	j, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t0 := time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC)
+	written := []Entry{
+		{Time: t0, Type: EntryOrder, Exchange: "Lighter", Market: "BTC-USD", Side: "BUY", Amount: 0.01, Price: 60000, Message: "MARKET"},
+		{Time: t0, Type: EntryFill, Exchange: "Lighter", Market: "BTC-USD", OrderID: "42", Side: "BUY", Amount: 0.01, Price: 60010, LatencyMs: 120},
+		{Time: t0, Type: EntryError, Exchange: "Extended", Market: "BTC-USD", Message: "MARKET SELL order failed: insufficient margin, retrying"},
+		{Time: t0.Add(8 * time.Hour), Type: EntryFunding, Exchange: "Lighter", Market: "BTC-USD", Funding: -0.3},
+		{Time: t0.Add(9 * time.Hour), Type: EntryClose, Market: "BTC-USD", Amount: 0.01},
+	}
+	for _, e := range written {
+		if err := j.Record(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := j.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := ReadAll(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != len(written) {
+		t.Fatalf("read %d entries, want %d", len(entries), len(written))
+	}
+	for i := range written {
+		if entries[i] != written[i] {
+			t.Errorf("entry %d = %+v, want %+v", i, entries[i], written[i])
+		}
+	}
+
+	var out strings.Builder
+	if err := WriteCSV(&out, entries); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != len(written)+1 || lines[0] != strings.Join(csvHeader, ",") {
+		t.Fatalf("unexpected CSV:\n%s", out.String())
+	}
+	if want := "2024-03-01T08:00:00Z,FILL,Lighter,BTC-USD,42,BUY,0.01,60010,0,0,120,0,"; lines[2] != want {
+		t.Errorf("fill row = %q, want %q", lines[2], want)
+	}
+	if want := `"MARKET SELL order failed: insufficient margin, retrying"`; !strings.HasSuffix(lines[3], want) {
+		t.Errorf("error row %q should quote the message", lines[3])
+	}
+}
+
+func TestSQLiteJournalNeedsDriver(t *testing.T) {
+	if _, err := sqliteDriver(); err == nil {
+		t.Skip("a SQLite driver is linked in")
+	}
+	if _, err := Open(filepath.Join(t.TempDir(), "journal.db")); err == nil || !strings.Contains(err.Error(), "-tags sqlite") {
+		t.Errorf("expected opening a SQLite journal without a driver to explain how to enable it, got %v", err)
+	}
+	if entries, err := ReadAll(filepath.Join(t.TempDir(), "missing.db")); err != nil || entries != nil {
+		t.Errorf("ReadAll of a missing SQLite journal = %v, %v; want nothing", entries, err)
+	}
+}
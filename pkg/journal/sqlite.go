@@ -0,0 +1,123 @@
+package journal
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// sqliteDrivers are the database/sql driver names registered by the common SQLite drivers
+// (modernc.org/sqlite and github.com/mattn/go-sqlite3).
+var sqliteDrivers = []string{"sqlite", "sqlite3"}
+
+// sqliteSchema creates the journal table. Times are stored as RFC 3339 strings in UTC, so they
+// sort and compare correctly in SQL.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS entries (
+	id             INTEGER PRIMARY KEY AUTOINCREMENT,
+	time           TEXT    NOT NULL,
+	type           TEXT    NOT NULL,
+	exchange       TEXT    NOT NULL DEFAULT '',
+	market         TEXT    NOT NULL DEFAULT '',
+	order_id       TEXT    NOT NULL DEFAULT '',
+	side           TEXT    NOT NULL DEFAULT '',
+	amount         REAL    NOT NULL DEFAULT 0,
+	price          REAL    NOT NULL DEFAULT 0,
+	fee            REAL    NOT NULL DEFAULT 0,
+	decision_price REAL    NOT NULL DEFAULT 0,
+	latency_ms     INTEGER NOT NULL DEFAULT 0,
+	funding        REAL    NOT NULL DEFAULT 0,
+	message        TEXT    NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS entries_time ON entries (time);
+CREATE INDEX IF NOT EXISTS entries_market ON entries (market, type);`
+
+const sqliteColumns = "time, type, exchange, market, order_id, side, amount, price, fee, decision_price, latency_ms, funding, message"
+
+// isSQLite reports whether path names a SQLite journal rather than a JSON lines file.
+func isSQLite(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".db", ".sqlite", ".sqlite3":
+		return true
+	}
+	return false
+}
+
+// sqliteDriver returns the name of a registered SQLite driver. None is linked into the default
+// build; see the sqlite build tag in driver_sqlite.go.
+func sqliteDriver() (string, error) {
+	registered := make(map[string]bool)
+	for _, name := range sql.Drivers() {
+		registered[name] = true
+	}
+	for _, name := range sqliteDrivers {
+		if registered[name] {
+			return name, nil
+		}
+	}
+	return "", errors.New("no SQLite driver is linked into this binary, build it with -tags sqlite or use a .jsonl journal")
+}
+
+// openSQLite opens the SQLite journal at path, creating the database and table if needed.
+func openSQLite(path string) (*sql.DB, error) {
+	driver, err := sqliteDriver()
+	if err != nil {
+		return nil, err
+	}
+	db, err := sql.Open(driver, path)
+	if err != nil {
+		return nil, err
+	}
+	// SQLite allows a single writer; one connection avoids "database is locked" errors.
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create journal table: %w", err)
+	}
+	return db, nil
+}
+
+func insertSQLite(db *sql.DB, e Entry) error {
+	_, err := db.Exec("INSERT INTO entries ("+sqliteColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		e.Time.UTC().Format(time.RFC3339Nano), string(e.Type), e.Exchange, e.Market, e.OrderID, e.Side,
+		e.Amount, e.Price, e.Fee, e.DecisionPrice, e.LatencyMs, e.Funding, e.Message)
+	return err
+}
+
+// readSQLite loads every entry of the SQLite journal at path in the order they were written.
+func readSQLite(path string) ([]Entry, error) {
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	db, err := openSQLite(path)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	rows, err := db.Query("SELECT " + sqliteColumns + " FROM entries ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []Entry
+	for rows.Next() {
+		var e Entry
+		var at, typ string
+		if err := rows.Scan(&at, &typ, &e.Exchange, &e.Market, &e.OrderID, &e.Side,
+			&e.Amount, &e.Price, &e.Fee, &e.DecisionPrice, &e.LatencyMs, &e.Funding, &e.Message); err != nil {
+			return nil, err
+		}
+		if e.Time, err = time.Parse(time.RFC3339Nano, at); err != nil {
+			return nil, fmt.Errorf("journal %s: invalid time %q: %w", path, at, err)
+		}
+		e.Type = EntryType(typ)
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,148 @@
+// Package logging builds the leveled, structured logger of the bot from its configuration: text or
+// JSON lines at a minimum level, with the configured secrets redacted from every record.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"strings"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/config"
+)
+
+// Options selects how records are written.
+type Options struct {
+	// Level is debug, info, warn or error; empty means info.
+	Level string
+	// Format is text or json; empty means text.
+	Format string
+	// Secrets are replaced by config.Redacted wherever they appear in a record.
+	Secrets []string
+}
+
+// FromConfig returns the options of LOG_LEVEL and LOG_FORMAT, redacting every credential set in cfg.
+func FromConfig(cfg config.Config) Options {
+	return Options{Level: cfg.LogLevel, Format: cfg.LogFormat, Secrets: cfg.Secrets()}
+}
+
+// New creates a logger writing to w.
+func New(w io.Writer, opts Options) (*slog.Logger, error) {
+	var level slog.Level
+	if opts.Level != "" {
+		if err := level.UnmarshalText([]byte(opts.Level)); err != nil {
+			return nil, fmt.Errorf("invalid LOG_LEVEL %q, expected debug, info, warn or error", opts.Level)
+		}
+	}
+	handlerOpts := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	switch strings.ToLower(opts.Format) {
+	case "", "text":
+		handler = slog.NewTextHandler(w, handlerOpts)
+	case "json":
+		handler = slog.NewJSONHandler(w, handlerOpts)
+	default:
+		return nil, fmt.Errorf("invalid LOG_FORMAT %q, expected text or json", opts.Format)
+	}
+
+	// Short values would be masked inside unrelated words, and real credentials are never that short.
+	var secrets []string
+	for _, secret := range opts.Secrets {
+		if len(secret) >= 6 {
+			secrets = append(secrets, secret)
+		}
+	}
+	if len(secrets) > 0 {
+		handler = &redactingHandler{Handler: handler, secrets: secrets}
+	}
+	return slog.New(handler), nil
+}
+
+// Std returns a *log.Logger for the code that logs with Printf, writing each message to logger as
+// a record of the given component. The level is read from the message: CRITICAL and ERROR
+// messages are errors; WARNING messages and failures are warnings; the rest is info.
+func Std(logger *slog.Logger, component string) *log.Logger {
+	return log.New(lineWriter{logger.With("component", component)}, "", 0)
+}
+
+type lineWriter struct {
+	logger *slog.Logger
+}
+
+func (w lineWriter) Write(p []byte) (int, error) {
+	message := strings.TrimSuffix(string(p), "\n")
+	w.logger.Log(context.Background(), LevelOf(message), message)
+	return len(p), nil
+}
+
+// warningPrefixes start the messages logged at warning level.
+var warningPrefixes = []string{"WARNING", "Failed", "Could not", "Cannot", "Error"}
+
+// LevelOf returns the level of a Printf message.
+func LevelOf(message string) slog.Level {
+	switch {
+	case strings.HasPrefix(message, "CRITICAL"), strings.HasPrefix(message, "ERROR"):
+		return slog.LevelError
+	}
+	for _, prefix := range warningPrefixes {
+		if strings.HasPrefix(message, prefix) {
+			return slog.LevelWarn
+		}
+	}
+	return slog.LevelInfo
+}
+
+// redactingHandler masks secrets in the message and string attributes of every record.
+type redactingHandler struct {
+	slog.Handler
+	secrets []string
+}
+
+func (h *redactingHandler) Handle(ctx context.Context, record slog.Record) error {
+	redacted := slog.NewRecord(record.Time, record.Level, h.redact(record.Message), record.PC)
+	record.Attrs(func(attr slog.Attr) bool {
+		redacted.AddAttrs(h.redactAttr(attr))
+		return true
+	})
+	return h.Handler.Handle(ctx, redacted)
+}
+
+func (h *redactingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	for i, attr := range attrs {
+		attrs[i] = h.redactAttr(attr)
+	}
+	return &redactingHandler{Handler: h.Handler.WithAttrs(attrs), secrets: h.secrets}
+}
+
+func (h *redactingHandler) WithGroup(name string) slog.Handler {
+	return &redactingHandler{Handler: h.Handler.WithGroup(name), secrets: h.secrets}
+}
+
+func (h *redactingHandler) redactAttr(attr slog.Attr) slog.Attr {
+	attr.Value = attr.Value.Resolve()
+	switch attr.Value.Kind() {
+	case slog.KindGroup:
+		group := attr.Value.Group()
+		attrs := make([]slog.Attr, len(group))
+		for i, a := range group {
+			attrs[i] = h.redactAttr(a)
+		}
+		return slog.Attr{Key: attr.Key, Value: slog.GroupValue(attrs...)}
+	case slog.KindString:
+		return slog.String(attr.Key, h.redact(attr.Value.String()))
+	case slog.KindAny:
+		if err, ok := attr.Value.Any().(error); ok {
+			return slog.String(attr.Key, h.redact(err.Error()))
+		}
+	}
+	return attr
+}
+
+func (h *redactingHandler) redact(s string) string {
+	for _, secret := range h.secrets {
+		s = strings.ReplaceAll(s, secret, config.Redacted)
+	}
+	return s
+}
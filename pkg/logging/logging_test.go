@@ -0,0 +1,61 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestJSONRecordsAreLeveledAndRedacted(t *testing.T) {
+	var out bytes.Buffer
+	logger, err := New(&out, Options{Level: "warn", Format: "json", Secrets: []string{"s3cr3t-key", "abc"}})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	std := Std(logger, "bot")
+	std.Printf("Opened BTC-USD position")
+	std.Printf("Failed to sign with s3cr3t-key")
+	logger.With("token", "s3cr3t-key").Error("CRITICAL: rollback failed", "err", errors.New("bad key s3cr3t-key"), "note", "abc")
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected the info record to be dropped, got:\n%s", out.String())
+	}
+	var warning map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &warning); err != nil {
+		t.Fatalf("not JSON: %v", err)
+	}
+	if warning["level"] != "WARN" || warning["msg"] != "Failed to sign with ***" || warning["component"] != "bot" {
+		t.Errorf("unexpected warning %v", warning)
+	}
+	if strings.Contains(lines[1], "s3cr3t-key") || !strings.Contains(lines[1], `"token":"***"`) || !strings.Contains(lines[1], `"note":"abc"`) {
+		t.Errorf("expected the secret redacted and short values kept, got %s", lines[1])
+	}
+}
+
+func TestLevelOf(t *testing.T) {
+	cases := map[string]slog.Level{
+		"CRITICAL: unhedged leg":       slog.LevelError,
+		"WARNING: saved position":      slog.LevelWarn,
+		"Could not fetch funding":      slog.LevelWarn,
+		"Successfully opened position": slog.LevelInfo,
+	}
+	for message, want := range cases {
+		if got := LevelOf(message); got != want {
+			t.Errorf("LevelOf(%q) = %v, want %v", message, got, want)
+		}
+	}
+}
+
+func TestNewRejectsUnknownSettings(t *testing.T) {
+	if _, err := New(&bytes.Buffer{}, Options{Level: "verbose"}); err == nil {
+		t.Error("expected an unknown level to be rejected")
+	}
+	if _, err := New(&bytes.Buffer{}, Options{Format: "xml"}); err == nil {
+		t.Error("expected an unknown format to be rejected")
+	}
+}
@@ -0,0 +1,366 @@
+package marketdata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/exchange"
+)
+
+// DefaultTTL is used when a cache is created without an explicit time-to-live.
+const DefaultTTL = 30 * time.Second
+
+// DefaultMarketInfoTTL is how long the trading rules of a market are reused unless set otherwise.
+// Venues change them rarely, so they outlive prices by far.
+const DefaultMarketInfoTTL = time.Hour
+
+// bulkMarkPricer is implemented by exchanges that can price many markets in one request.
+type bulkMarkPricer interface {
+	GetMarkPrices(ctx context.Context, markets []string) (map[string]float64, error)
+}
+
+// Snapshot is the latest known market data for a single market on a single exchange.
+type Snapshot struct {
+	Exchange    string
+	Market      string
+	FundingRate decimal.Decimal
+	NextFunding int64
+	// PredictedRate is the venue's forecast of the next rate, known when HasPredicted is set.
+	PredictedRate decimal.Decimal
+	HasPredicted  bool
+	MarkPrice     float64
+	BestBid       float64
+	BestAsk       float64
+	RateUpdated   time.Time
+	PriceUpdated  time.Time
+	BookUpdated   time.Time
+}
+
+// IsStale reports whether the funding rate in the snapshot is older than maxAge.
+func (s Snapshot) IsStale(maxAge time.Duration) bool {
+	return s.RateUpdated.IsZero() || time.Since(s.RateUpdated) > maxAge
+}
+
+type key struct {
+	exchange string
+	market   string
+}
+
+// Cache is a shared store of market data keyed by exchange and market.
+// Consumers read through it so that a value fetched once is reused until it expires.
+type Cache struct {
+	ttl          time.Duration
+	mu           sync.RWMutex
+	entries      map[key]*Snapshot
+	ratesFetched map[string]time.Time
+	fetchMu      map[string]*sync.Mutex
+
+	infoTTL time.Duration
+	infos   map[key]cachedInfo
+}
+
+type cachedInfo struct {
+	info    *exchange.MarketInfo
+	fetched time.Time
+}
+
+// NewCache creates a cache whose entries are considered fresh for ttl.
+func NewCache(ttl time.Duration) *Cache {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	return &Cache{
+		ttl:          ttl,
+		entries:      make(map[key]*Snapshot),
+		ratesFetched: make(map[string]time.Time),
+		fetchMu:      make(map[string]*sync.Mutex),
+		infoTTL:      DefaultMarketInfoTTL,
+		infos:        make(map[key]cachedInfo),
+	}
+}
+
+// SetMarketInfoTTL sets how long the trading rules of a market are reused. A ttl of zero or less
+// restores DefaultMarketInfoTTL.
+func (c *Cache) SetMarketInfoTTL(ttl time.Duration) {
+	if ttl <= 0 {
+		ttl = DefaultMarketInfoTTL
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.infoTTL = ttl
+}
+
+// TTL returns the freshness window of the cache.
+func (c *Cache) TTL() time.Duration {
+	return c.ttl
+}
+
+// FundingRates returns the funding rates of ex, fetching them only if the cached copy has expired.
+// Concurrent callers for the same exchange share a single request.
+func (c *Cache) FundingRates(ctx context.Context, ex exchange.Exchange) ([]*exchange.FundingRate, error) {
+	lock := c.lockFor("rates:" + ex.Name())
+	lock.Lock()
+	defer lock.Unlock()
+
+	if rates, ok := c.cachedRates(ex.Name()); ok {
+		return rates, nil
+	}
+
+	rates, err := ex.GetFundingRates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.replaceFundingRates(ex.Name(), rates)
+	return rates, nil
+}
+
+// StoreFundingRates records freshly fetched funding rates, e.g. from a stream. Markets missing
+// from rates keep their last rate.
+func (c *Cache) StoreFundingRates(exchangeName string, rates []*exchange.FundingRate) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.storeRates(exchangeName, rates)
+}
+
+// replaceFundingRates records the full list of funding rates of an exchange, forgetting the rates
+// of the markets it no longer lists.
+func (c *Cache) replaceFundingRates(exchangeName string, rates []*exchange.FundingRate) {
+	listed := make(map[string]bool, len(rates))
+	for _, r := range rates {
+		listed[r.Market] = true
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k, entry := range c.entries {
+		if k.exchange != exchangeName || listed[k.market] {
+			continue
+		}
+		entry.FundingRate, entry.NextFunding = decimal.Zero, 0
+		entry.PredictedRate, entry.HasPredicted = decimal.Zero, false
+		entry.RateUpdated = time.Time{}
+	}
+	c.storeRates(exchangeName, rates)
+}
+
+// storeRates records rates as of now. The caller must hold c.mu.
+func (c *Cache) storeRates(exchangeName string, rates []*exchange.FundingRate) {
+	now := time.Now()
+	for _, r := range rates {
+		entry := c.entry(exchangeName, r.Market)
+		entry.FundingRate = r.Rate
+		entry.NextFunding = r.NextTime
+		entry.PredictedRate, entry.HasPredicted = r.PredictedRate, r.HasPredicted
+		entry.RateUpdated = now
+	}
+	c.ratesFetched[exchangeName] = now
+}
+
+// RatesUpdated returns when the funding rates of an exchange were last fetched or pushed.
+func (c *Cache) RatesUpdated(exchangeName string) (time.Time, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	fetched, ok := c.ratesFetched[exchangeName]
+	return fetched, ok
+}
+
+// MarkPrice returns the mark price of market on ex, fetching it only if the cached copy has expired.
+func (c *Cache) MarkPrice(ctx context.Context, ex exchange.Exchange, market string) (float64, error) {
+	lock := c.lockFor("price:" + ex.Name() + ":" + market)
+	lock.Lock()
+	defer lock.Unlock()
+
+	c.mu.RLock()
+	entry, ok := c.entries[key{ex.Name(), market}]
+	if ok && !entry.PriceUpdated.IsZero() && time.Since(entry.PriceUpdated) <= c.ttl {
+		price := entry.MarkPrice
+		c.mu.RUnlock()
+		return price, nil
+	}
+	c.mu.RUnlock()
+
+	price, err := ex.GetMarkPrice(ctx, market)
+	if err != nil {
+		return 0, err
+	}
+	c.StoreMarkPrice(ex.Name(), market, price)
+	return price, nil
+}
+
+// MarkPrices returns mark prices for several markets on ex. Fresh cached values are reused and
+// the remaining markets are fetched in a single request when the exchange supports it.
+func (c *Cache) MarkPrices(ctx context.Context, ex exchange.Exchange, markets []string) (map[string]float64, error) {
+	prices := make(map[string]float64, len(markets))
+	var missing []string
+	c.mu.RLock()
+	for _, market := range markets {
+		entry, ok := c.entries[key{ex.Name(), market}]
+		if ok && !entry.PriceUpdated.IsZero() && time.Since(entry.PriceUpdated) <= c.ttl {
+			prices[market] = entry.MarkPrice
+		} else {
+			missing = append(missing, market)
+		}
+	}
+	c.mu.RUnlock()
+	if len(missing) == 0 {
+		return prices, nil
+	}
+
+	bulk, ok := ex.(bulkMarkPricer)
+	if !ok {
+		for _, market := range missing {
+			price, err := c.MarkPrice(ctx, ex, market)
+			if err != nil {
+				return nil, err
+			}
+			prices[market] = price
+		}
+		return prices, nil
+	}
+
+	fetched, err := bulk.GetMarkPrices(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+	for market, price := range fetched {
+		c.StoreMarkPrice(ex.Name(), market, price)
+	}
+	for _, market := range missing {
+		price, ok := fetched[market]
+		if !ok {
+			return nil, fmt.Errorf("%s returned no mark price for %s", ex.Name(), market)
+		}
+		prices[market] = price
+	}
+	return prices, nil
+}
+
+// MarketInfo returns the trading rules of market on ex, fetching them only if the cached copy is
+// older than the market info TTL. ok is false if ex doesn't list them, including wrappers whose
+// exchange doesn't.
+func (c *Cache) MarketInfo(ctx context.Context, ex exchange.Exchange, market string) (info *exchange.MarketInfo, ok bool, err error) {
+	provider, ok := ex.(exchange.MarketInfoProvider)
+	if !ok {
+		return nil, false, nil
+	}
+	lock := c.lockFor("info:" + ex.Name() + ":" + market)
+	lock.Lock()
+	defer lock.Unlock()
+
+	k := key{ex.Name(), market}
+	c.mu.RLock()
+	cached, found := c.infos[k]
+	fresh := found && time.Since(cached.fetched) <= c.infoTTL
+	c.mu.RUnlock()
+	if fresh {
+		return cached.info, true, nil
+	}
+
+	info, err = provider.GetMarketInfo(ctx, market)
+	if errors.Is(err, exchange.ErrMarketInfoUnsupported) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, true, err
+	}
+	c.mu.Lock()
+	c.infos[k] = cachedInfo{info: info, fetched: time.Now()}
+	c.mu.Unlock()
+	return info, true, nil
+}
+
+// StoreMarkPrice records a freshly observed mark price.
+func (c *Cache) StoreMarkPrice(exchangeName, market string, price float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry := c.entry(exchangeName, market)
+	entry.MarkPrice = price
+	entry.PriceUpdated = time.Now()
+}
+
+// StoreOrderbookTop records the best bid and ask of a market.
+func (c *Cache) StoreOrderbookTop(exchangeName, market string, bid, ask float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry := c.entry(exchangeName, market)
+	entry.BestBid = bid
+	entry.BestAsk = ask
+	entry.BookUpdated = time.Now()
+}
+
+// Get returns a copy of the snapshot for a market on an exchange.
+func (c *Cache) Get(exchangeName, market string) (Snapshot, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	entry, ok := c.entries[key{exchangeName, market}]
+	if !ok {
+		return Snapshot{}, false
+	}
+	return *entry, true
+}
+
+// Snapshots returns copies of every snapshot currently held by the cache.
+func (c *Cache) Snapshots() []Snapshot {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	snapshots := make([]Snapshot, 0, len(c.entries))
+	for _, entry := range c.entries {
+		snapshots = append(snapshots, *entry)
+	}
+	return snapshots
+}
+
+// cachedRates rebuilds the funding rate list of an exchange if it is still fresh. A rate pushed
+// for one market doesn't refresh the others, so the list is only fresh while every rate in it is.
+func (c *Cache) cachedRates(exchangeName string) ([]*exchange.FundingRate, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	fetched, ok := c.ratesFetched[exchangeName]
+	if !ok || time.Since(fetched) > c.ttl {
+		return nil, false
+	}
+	var rates []*exchange.FundingRate
+	for k, entry := range c.entries {
+		if k.exchange != exchangeName || entry.RateUpdated.IsZero() {
+			continue
+		}
+		if time.Since(entry.RateUpdated) > c.ttl {
+			return nil, false
+		}
+		rates = append(rates, &exchange.FundingRate{
+			Market:        entry.Market,
+			Rate:          entry.FundingRate,
+			NextTime:      entry.NextFunding,
+			PredictedRate: entry.PredictedRate,
+			HasPredicted:  entry.HasPredicted,
+		})
+	}
+	return rates, true
+}
+
+// entry returns the snapshot for a key, creating it if needed. The caller must hold c.mu.
+func (c *Cache) entry(exchangeName, market string) *Snapshot {
+	k := key{exchangeName, market}
+	entry, ok := c.entries[k]
+	if !ok {
+		entry = &Snapshot{Exchange: exchangeName, Market: market}
+		c.entries[k] = entry
+	}
+	return entry
+}
+
+// lockFor returns the mutex that serialises fetches for name.
+func (c *Cache) lockFor(name string) *sync.Mutex {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	lock, ok := c.fetchMu[name]
+	if !ok {
+		lock = &sync.Mutex{}
+		c.fetchMu[name] = lock
+	}
+	return lock
+}
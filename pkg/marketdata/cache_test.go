@@ -0,0 +1,172 @@
+package marketdata
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/exchange"
+)
+
+// countingExchange is a minimal exchange that counts funding rate requests.
+type countingExchange struct {
+	exchange.Exchange
+	calls int
+}
+
+func (c *countingExchange) Name() string { return "Counting" }
+
+func (c *countingExchange) GetFundingRates(ctx context.Context) ([]*exchange.FundingRate, error) {
+	c.calls++
+	return []*exchange.FundingRate{{Market: "BTC-USD", Rate: decimal.NewFromFloat(0.0001)}}, nil
+}
+
+func TestFundingRatesAreReusedWithinTTL(t *testing.T) {
+	ex := &countingExchange{}
+	cache := NewCache(time.Minute)
+
+	for i := 0; i < 3; i++ {
+		rates, err := cache.FundingRates(context.Background(), ex)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(rates) != 1 || !rates[0].Rate.Equal(decimal.NewFromFloat(0.0001)) {
+			t.Fatalf("unexpected rates: %+v", rates)
+		}
+	}
+	if ex.calls != 1 {
+		t.Fatalf("expected 1 fetch, got %d", ex.calls)
+	}
+
+	snapshot, ok := cache.Get("Counting", "BTC-USD")
+	if !ok || snapshot.IsStale(time.Minute) {
+		t.Fatalf("expected a fresh snapshot, got %+v", snapshot)
+	}
+}
+
+func TestFundingRatesRefetchAfterTTL(t *testing.T) {
+	ex := &countingExchange{}
+	cache := NewCache(time.Millisecond)
+
+	if _, err := cache.FundingRates(context.Background(), ex); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if _, err := cache.FundingRates(context.Background(), ex); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ex.calls != 2 {
+		t.Fatalf("expected 2 fetches, got %d", ex.calls)
+	}
+}
+
+// listingExchange returns the funding rates it is given and counts the requests.
+type listingExchange struct {
+	exchange.Exchange
+	rates []*exchange.FundingRate
+	calls int
+}
+
+func (e *listingExchange) Name() string { return "Listing" }
+
+func (e *listingExchange) GetFundingRates(ctx context.Context) ([]*exchange.FundingRate, error) {
+	e.calls++
+	return e.rates, nil
+}
+
+func TestFundingRatesForgetMarketsMissingFromTheLatestFetch(t *testing.T) {
+	ex := &listingExchange{rates: []*exchange.FundingRate{
+		{Market: "BTC-USD", Rate: decimal.NewFromFloat(0.0001)},
+		{Market: "ETH-USD", Rate: decimal.NewFromFloat(0.0002)},
+	}}
+	cache := NewCache(20 * time.Millisecond)
+	if _, err := cache.FundingRates(context.Background(), ex); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	ex.rates = ex.rates[:1]
+	if _, err := cache.FundingRates(context.Background(), ex); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rates, err := cache.FundingRates(context.Background(), ex)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ex.calls != 2 {
+		t.Fatalf("expected 2 fetches, got %d", ex.calls)
+	}
+	if len(rates) != 1 || rates[0].Market != "BTC-USD" {
+		t.Fatalf("expected only BTC-USD from the cache, got %+v", rates)
+	}
+	if snapshot, ok := cache.Get("Listing", "ETH-USD"); !ok || !snapshot.IsStale(time.Minute) {
+		t.Fatalf("expected ETH-USD to have no fresh rate, got %+v", snapshot)
+	}
+}
+
+func TestPushedRateDoesNotRefreshTheOtherMarkets(t *testing.T) {
+	ex := &listingExchange{rates: []*exchange.FundingRate{
+		{Market: "BTC-USD", Rate: decimal.NewFromFloat(0.0001)},
+		{Market: "ETH-USD", Rate: decimal.NewFromFloat(0.0002)},
+	}}
+	cache := NewCache(20 * time.Millisecond)
+	if _, err := cache.FundingRates(context.Background(), ex); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	cache.StoreFundingRates("Listing", []*exchange.FundingRate{{Market: "BTC-USD", Rate: decimal.NewFromFloat(0.0003)}})
+	if _, err := cache.FundingRates(context.Background(), ex); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ex.calls != 2 {
+		t.Fatalf("expected the stale ETH-USD rate to be fetched again, got %d fetches", ex.calls)
+	}
+}
+
+// infoExchange lists the trading rules of its markets and counts the requests.
+type infoExchange struct {
+	countingExchange
+}
+
+func (e *infoExchange) GetMarketInfo(ctx context.Context, market string) (*exchange.MarketInfo, error) {
+	e.calls++
+	return &exchange.MarketInfo{Market: market, MaxLeverage: 20}, nil
+}
+
+func TestMarketInfoIsReusedWithinItsTTL(t *testing.T) {
+	ex := &infoExchange{}
+	cache := NewCache(time.Millisecond)
+	cache.SetMarketInfoTTL(time.Minute)
+
+	for i := 0; i < 2; i++ {
+		info, ok, err := cache.MarketInfo(context.Background(), ex, "BTC-USD")
+		if err != nil || !ok || info.MaxLeverage != 20 {
+			t.Fatalf("MarketInfo = %+v, %v, %v", info, ok, err)
+		}
+		// Market data expiring doesn't expire the rules.
+		time.Sleep(5 * time.Millisecond)
+	}
+	if ex.calls != 1 {
+		t.Fatalf("expected 1 fetch, got %d", ex.calls)
+	}
+
+	cache.SetMarketInfoTTL(time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	if _, _, err := cache.MarketInfo(context.Background(), ex, "BTC-USD"); err != nil {
+		t.Fatal(err)
+	}
+	if ex.calls != 2 {
+		t.Fatalf("expected the rules to be fetched again after their TTL, got %d fetches", ex.calls)
+	}
+
+	if _, ok, _ := cache.MarketInfo(context.Background(), &countingExchange{}, "ETH-USD"); ok {
+		t.Error("an exchange without market rules must report ok=false")
+	}
+	paper := exchange.NewPaper(&countingExchange{}, exchange.PaperConfig{})
+	if _, ok, err := cache.MarketInfo(context.Background(), paper, "ETH-USD"); ok || err != nil {
+		t.Errorf("a wrapper around an exchange without market rules must report ok=false, got %v, %v", ok, err)
+	}
+}
@@ -0,0 +1,139 @@
+// Package metrics exposes the bot's health and trading activity for Prometheus.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// namespace prefixes every metric name.
+const namespace = "arb_bot_"
+
+// latencyBuckets are the upper bounds, in seconds, of the exchange request latency histogram.
+var latencyBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Metrics records the bot's metrics. A nil *Metrics discards everything, so callers don't need
+// to check whether metrics are enabled.
+type Metrics struct {
+	registry *Registry
+
+	rateDiff        *Gauge
+	openPositions   *Gauge
+	exposureUSD     *Gauge
+	ordersPlaced    *Counter
+	ordersFailed    *Counter
+	fundingPnL      *Gauge
+	netDeltaUSD     *Gauge
+	basisPnL        *Gauge
+	requestDuration *Histogram
+	requestErrors   *Counter
+}
+
+// New creates the bot's metrics in a fresh registry.
+func New() *Metrics {
+	r := NewRegistry()
+	return &Metrics{
+		registry:        r,
+		rateDiff:        r.NewGauge(namespace+"funding_rate_diff", "Latest funding rate difference per market between the short and long venue of its open position, or of the widest pair when none is open.", "market"),
+		openPositions:   r.NewGauge(namespace+"open_positions", "Number of open arbitrage positions."),
+		exposureUSD:     r.NewGauge(namespace+"position_exposure_usd", "Total notional of open arbitrage positions, in USD."),
+		ordersPlaced:    r.NewCounter(namespace+"orders_placed_total", "Orders accepted by an exchange.", "exchange"),
+		ordersFailed:    r.NewCounter(namespace+"orders_failed_total", "Orders rejected by an exchange or that failed to submit.", "exchange"),
+		fundingPnL:      r.NewGauge(namespace+"funding_pnl_usd", "Funding earned by open and closed positions, in USD.", "market"),
+		netDeltaUSD:     r.NewGauge(namespace+"net_delta_usd", "Net position per market summed over the exchanges and valued at the mark price, in USD; positive is net long.", "market"),
+		basisPnL:        r.NewGauge(namespace+"basis_pnl_usd", "Mark-to-market PnL of both legs of the open position per market at each venue's mark price, excluding funding, in USD.", "market"),
+		requestDuration: r.NewHistogram(namespace+"exchange_request_duration_seconds", "Latency of exchange API requests.", latencyBuckets, "exchange"),
+		requestErrors:   r.NewCounter(namespace+"exchange_request_errors_total", "Exchange API requests that failed or returned an error status.", "exchange", "code"),
+	}
+}
+
+// Handler serves the metrics for Prometheus to scrape.
+func (m *Metrics) Handler() http.Handler {
+	if m == nil {
+		return http.NotFoundHandler()
+	}
+	return m.registry.Handler()
+}
+
+// SetRateDiff records the latest funding rate difference on market.
+func (m *Metrics) SetRateDiff(market string, diff float64) {
+	if m == nil {
+		return
+	}
+	m.rateDiff.Set(diff, market)
+}
+
+// SetPositions records the number and total notional of open positions.
+func (m *Metrics) SetPositions(count int, exposureUSD float64) {
+	if m == nil {
+		return
+	}
+	m.openPositions.Set(float64(count))
+	m.exposureUSD.Set(exposureUSD)
+}
+
+// OrderResult counts an order submitted to exchange, as failed if err is not nil.
+func (m *Metrics) OrderResult(exchange string, err error) {
+	if m == nil {
+		return
+	}
+	if err != nil {
+		m.ordersFailed.Inc(exchange)
+		return
+	}
+	m.ordersPlaced.Inc(exchange)
+}
+
+// AddFundingPnL adds funding earned (or paid, if negative) on market.
+func (m *Metrics) AddFundingPnL(market string, usd float64) {
+	if m == nil {
+		return
+	}
+	m.fundingPnL.Add(usd, market)
+}
+
+// SetNetDelta records the net position on market across the exchanges, in USD.
+func (m *Metrics) SetNetDelta(market string, usd float64) {
+	if m == nil {
+		return
+	}
+	m.netDeltaUSD.Set(usd, market)
+}
+
+// SetBasisPnL records the basis PnL of the open position on market, in USD.
+func (m *Metrics) SetBasisPnL(market string, usd float64) {
+	if m == nil {
+		return
+	}
+	m.basisPnL.Set(usd, market)
+}
+
+// Transport wraps base so that every request records its latency and errors under exchange.
+// Install it with the exchange client's SetTransport.
+func (m *Metrics) Transport(exchange string, base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if m == nil {
+		return base
+	}
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		start := time.Now()
+		resp, err := base.RoundTrip(req)
+		m.requestDuration.Observe(time.Since(start).Seconds(), exchange)
+		switch {
+		case err != nil:
+			m.requestErrors.Inc(exchange, "transport")
+		case resp.StatusCode >= 400:
+			m.requestErrors.Inc(exchange, strconv.Itoa(resp.StatusCode))
+		}
+		return resp, err
+	})
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -0,0 +1,98 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func scrape(t *testing.T, m *Metrics) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	return rec.Body.String()
+}
+
+func TestMetricsExposition(t *testing.T) {
+	m := New()
+	m.SetRateDiff("BTC-USD", 0.0005)
+	m.SetRateDiff("ETH-USD", -0.0002)
+	m.SetPositions(2, 1500)
+	m.OrderResult("Extended", nil)
+	m.OrderResult("Extended", nil)
+	m.OrderResult("Lighter", errors.New("rejected"))
+	m.AddFundingPnL("BTC-USD", 1.5)
+	m.AddFundingPnL("BTC-USD", -0.25)
+	m.SetNetDelta("ETH-USD", -42.5)
+	m.SetBasisPnL("BTC-USD", -3.75)
+
+	body := scrape(t, m)
+	for _, want := range []string{
+		"# TYPE arb_bot_funding_rate_diff gauge\n",
+		`arb_bot_funding_rate_diff{market="BTC-USD"} 0.0005` + "\n",
+		`arb_bot_funding_rate_diff{market="ETH-USD"} -0.0002` + "\n",
+		"arb_bot_open_positions 2\n",
+		"arb_bot_position_exposure_usd 1500\n",
+		"# TYPE arb_bot_orders_placed_total counter\n",
+		`arb_bot_orders_placed_total{exchange="Extended"} 2` + "\n",
+		`arb_bot_orders_failed_total{exchange="Lighter"} 1` + "\n",
+		`arb_bot_funding_pnl_usd{market="BTC-USD"} 1.25` + "\n",
+		`arb_bot_net_delta_usd{market="ETH-USD"} -42.5` + "\n",
+		`arb_bot_basis_pnl_usd{market="BTC-USD"} -3.75` + "\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("exposition is missing %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestTransportRecordsLatencyAndErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer server.Close()
+
+	m := New()
+	client := &http.Client{Transport: m.Transport("Dydx", nil)}
+	for _, path := range []string{"/ok", "/ok", "/fail"} {
+		resp, err := client.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		resp.Body.Close()
+	}
+	if _, err := client.Get("http://127.0.0.1:0/"); err == nil {
+		t.Fatal("expected the request to an invalid port to fail")
+	}
+
+	body := scrape(t, m)
+	for _, want := range []string{
+		"# TYPE arb_bot_exchange_request_duration_seconds histogram\n",
+		`arb_bot_exchange_request_duration_seconds_bucket{exchange="Dydx",le="+Inf"} 4` + "\n",
+		`arb_bot_exchange_request_duration_seconds_count{exchange="Dydx"} 4` + "\n",
+		`arb_bot_exchange_request_errors_total{exchange="Dydx",code="429"} 1` + "\n",
+		`arb_bot_exchange_request_errors_total{exchange="Dydx",code="transport"} 1` + "\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("exposition is missing %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestNilMetricsIsDisabled(t *testing.T) {
+	var m *Metrics
+	m.SetRateDiff("BTC-USD", 1)
+	m.SetPositions(1, 1)
+	m.OrderResult("Extended", nil)
+	m.AddFundingPnL("BTC-USD", 1)
+	if rt := m.Transport("Extended", nil); rt != http.DefaultTransport {
+		t.Errorf("Transport on nil metrics = %v, want the base transport", rt)
+	}
+}
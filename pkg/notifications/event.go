@@ -0,0 +1,67 @@
+package notifications
+
+import "time"
+
+// EventType names a kind of structured event.
+type EventType string
+
+const (
+	// EventOpportunity is a funding rate difference the strategy decided to trade, or that the
+	// watch command alerts on.
+	EventOpportunity EventType = "opportunity_found"
+	// EventOrderPlaced and EventOrderFailed report an order on one leg of a position.
+	EventOrderPlaced EventType = "order_placed"
+	EventOrderFailed EventType = "order_failed"
+	// EventPositionOpened and EventPositionClosed report a hedged position with both legs.
+	EventPositionOpened EventType = "position_opened"
+	EventPositionClosed EventType = "position_closed"
+	// EventRiskAlert needs the operator's attention, such as a leg close to liquidation or a
+	// failed rollback.
+	EventRiskAlert EventType = "risk_alert"
+	// EventMessage is any other notification, as sent to the chat notifiers.
+	EventMessage EventType = "message"
+)
+
+// EventTypes lists every event type.
+var EventTypes = []EventType{EventOpportunity, EventOrderPlaced, EventOrderFailed, EventPositionOpened, EventPositionClosed, EventRiskAlert, EventMessage}
+
+// Event is a structured notification. Only the fields that apply to its type are set.
+type Event struct {
+	Type EventType `json:"type"`
+	Time time.Time `json:"time"`
+	// Exchange and Action are the venue and kind of an order, e.g. OPEN LONG.
+	Exchange      string  `json:"exchange,omitempty"`
+	Action        string  `json:"action,omitempty"`
+	Market        string  `json:"market,omitempty"`
+	LongExchange  string  `json:"longExchange,omitempty"`
+	ShortExchange string  `json:"shortExchange,omitempty"`
+	SizeUSD       float64 `json:"sizeUsd,omitempty"`
+	RateDiff      float64 `json:"rateDiff,omitempty"`
+	Message       string  `json:"message,omitempty"`
+	Error         string  `json:"error,omitempty"`
+}
+
+// EventSender is implemented by notifiers that deliver structured events as well as messages.
+type EventSender interface {
+	SendEvent(event Event)
+}
+
+// Publish sends event to n if it delivers structured events, stamping it with the current time
+// unless it has one. Chat notifiers are told about the same activity with messages instead.
+func Publish(n Notifier, event Event) {
+	sender, ok := n.(EventSender)
+	if !ok {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	sender.SendEvent(event)
+}
+
+// SendEvent implements EventSender.
+func (m Multi) SendEvent(event Event) {
+	for _, n := range m {
+		Publish(n, event)
+	}
+}
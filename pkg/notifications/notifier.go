@@ -0,0 +1,31 @@
+package notifications
+
+// Notifier delivers alerts about the bot's trading to the operator. Implementations are safe to
+// use when they are not configured, in which case they drop every notification.
+type Notifier interface {
+	// SendMessage sends a message to the operator.
+	SendMessage(message string)
+	// SendPositionNotification reports an order placed to open, close or adjust a position on
+	// market, failed if err is not nil.
+	SendPositionNotification(action, exchangeName, market string, positionSizeUSD float64, err error)
+}
+
+// Multi sends every notification to each of its notifiers, in order.
+type Multi []Notifier
+
+// Discard drops every notification.
+var Discard Notifier = Multi(nil)
+
+// SendMessage implements Notifier.
+func (m Multi) SendMessage(message string) {
+	for _, n := range m {
+		n.SendMessage(message)
+	}
+}
+
+// SendPositionNotification implements Notifier.
+func (m Multi) SendPositionNotification(action, exchangeName, market string, positionSizeUSD float64, err error) {
+	for _, n := range m {
+		n.SendPositionNotification(action, exchangeName, market, positionSizeUSD, err)
+	}
+}
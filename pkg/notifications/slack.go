@@ -0,0 +1,137 @@
+package notifications
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const slackPostMessageURL = "https://slack.com/api/chat.postMessage"
+
+// SlackNotifier posts notifications to a Slack channel, either as a bot, with a bot token and a
+// channel, or through an incoming webhook, which is bound to its channel. As a bot, the position
+// events of each market are posted in one thread, started by the market's first event; incoming
+// webhooks can't thread, so they post every event to the channel.
+type SlackNotifier struct {
+	token      string
+	channel    string
+	webhookURL string
+	apiURL     string
+	client     *http.Client
+	logger     *log.Logger
+
+	mu      sync.Mutex
+	threads map[string]string // market -> timestamp of the thread's first message
+}
+
+// NewSlackNotifier creates a Slack notifier posting as a bot when token and channel are set, and
+// through webhookURL otherwise. It returns nil if neither is configured.
+func NewSlackNotifier(token, channel, webhookURL string, logger *log.Logger) *SlackNotifier {
+	if (token == "" || channel == "") && webhookURL == "" {
+		logger.Println("Slack bot token and channel or webhook URL not provided, Slack notifier disabled.")
+		return nil
+	}
+	if token == "" || channel == "" {
+		token, channel = "", ""
+	}
+	logger.Println("Slack notifier initialized successfully.")
+	return &SlackNotifier{
+		token:      token,
+		channel:    channel,
+		webhookURL: webhookURL,
+		apiURL:     slackPostMessageURL,
+		client:     &http.Client{Timeout: 10 * time.Second},
+		logger:     logger,
+		threads:    make(map[string]string),
+	}
+}
+
+// SendMessage posts a message to the channel.
+func (sn *SlackNotifier) SendMessage(message string) {
+	if sn == nil {
+		return
+	}
+	if _, err := sn.post(message, ""); err != nil {
+		sn.logger.Printf("Failed to send Slack message: %v", err)
+	}
+}
+
+// SendPositionNotification posts a trading event in the thread of its market.
+func (sn *SlackNotifier) SendPositionNotification(action, exchangeName, market string, positionSizeUSD float64, err error) {
+	if sn == nil {
+		return
+	}
+
+	status := "✅ SUCCESS"
+	if err != nil {
+		status = "❌ FAILED"
+	}
+	message := fmt.Sprintf("*%s* %s\n*Exchange:* `%s`  *Market:* `%s`  *Size:* `%.2f USD`",
+		action, status, exchangeName, market, positionSizeUSD)
+	if err != nil {
+		message += fmt.Sprintf("\n*Error:* `%v`", err)
+	}
+
+	sn.mu.Lock()
+	defer sn.mu.Unlock()
+	ts, postErr := sn.post(message, sn.threads[market])
+	if postErr != nil {
+		sn.logger.Printf("Failed to send Slack message: %v", postErr)
+		return
+	}
+	if _, ok := sn.threads[market]; !ok && ts != "" {
+		sn.threads[market] = ts
+	}
+}
+
+// post sends text, in the thread started by threadTS if it is set, and returns the timestamp
+// identifying the message, which is empty for webhooks.
+func (sn *SlackNotifier) post(text, threadTS string) (string, error) {
+	if sn.token == "" {
+		body, _ := json.Marshal(map[string]string{"text": text})
+		resp, err := sn.client.Post(sn.webhookURL, "application/json", bytes.NewReader(body))
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			reply, _ := io.ReadAll(resp.Body)
+			return "", fmt.Errorf("webhook returned %s: %s", resp.Status, reply)
+		}
+		return "", nil
+	}
+
+	body, _ := json.Marshal(struct {
+		Channel  string `json:"channel"`
+		Text     string `json:"text"`
+		ThreadTS string `json:"thread_ts,omitempty"`
+	}{sn.channel, text, threadTS})
+	req, err := http.NewRequest("POST", sn.apiURL, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Authorization", "Bearer "+sn.token)
+	resp, err := sn.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	var reply struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+		TS    string `json:"ts"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&reply); err != nil {
+		return "", fmt.Errorf("unexpected response %s: %w", resp.Status, err)
+	}
+	if !reply.OK {
+		return "", fmt.Errorf("chat.postMessage failed: %s", reply.Error)
+	}
+	return reply.TS, nil
+}
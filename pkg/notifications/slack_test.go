@@ -0,0 +1,81 @@
+package notifications
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// slackAPI records the messages posted to chat.postMessage.
+type slackAPI struct {
+	mu       sync.Mutex
+	messages []map[string]string
+}
+
+func (a *slackAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if r.Header.Get("Authorization") != "Bearer xoxb-test" {
+		fmt.Fprint(w, `{"ok":false,"error":"invalid_auth"}`)
+		return
+	}
+	var message map[string]string
+	json.NewDecoder(r.Body).Decode(&message)
+	a.messages = append(a.messages, message)
+	fmt.Fprintf(w, `{"ok":true,"ts":"1700000000.%06d"}`, len(a.messages))
+}
+
+func TestSlackThreadsPositionEventsPerMarket(t *testing.T) {
+	api := &slackAPI{}
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	sn := NewSlackNotifier("xoxb-test", "C123", "", log.New(io.Discard, "", 0))
+	sn.apiURL = server.URL
+
+	sn.SendPositionNotification("OPEN LONG", "Binance", "BTC-USD", 100, nil)
+	sn.SendPositionNotification("OPEN SHORT", "OKX", "BTC-USD", 100, errors.New("rejected"))
+	sn.SendPositionNotification("OPEN LONG", "Binance", "ETH-USD", 50, nil)
+	sn.SendMessage("PnL summary")
+
+	if len(api.messages) != 4 {
+		t.Fatalf("expected 4 messages, got %+v", api.messages)
+	}
+	if api.messages[0]["channel"] != "C123" || api.messages[0]["thread_ts"] != "" {
+		t.Errorf("expected the first BTC-USD event to start a thread, got %+v", api.messages[0])
+	}
+	if api.messages[1]["thread_ts"] != "1700000000.000001" || !strings.Contains(api.messages[1]["text"], "rejected") {
+		t.Errorf("expected the second BTC-USD event in its thread, got %+v", api.messages[1])
+	}
+	if api.messages[2]["thread_ts"] != "" || api.messages[3]["thread_ts"] != "" {
+		t.Errorf("expected ETH-USD and plain messages outside the BTC-USD thread, got %+v", api.messages[2:])
+	}
+}
+
+func TestSlackWebhook(t *testing.T) {
+	var texts []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var message map[string]string
+		json.NewDecoder(r.Body).Decode(&message)
+		texts = append(texts, message["text"])
+		fmt.Fprint(w, "ok")
+	}))
+	defer server.Close()
+
+	sn := NewSlackNotifier("", "", server.URL, log.New(io.Discard, "", 0))
+	sn.SendPositionNotification("CLOSE LONG", "Binance", "BTC-USD", 100, nil)
+	if len(texts) != 1 || !strings.Contains(texts[0], "CLOSE LONG") {
+		t.Errorf("expected the event posted to the webhook, got %q", texts)
+	}
+
+	if NewSlackNotifier("", "C123", "", log.New(io.Discard, "", 0)) != nil {
+		t.Error("expected a channel without a token or webhook to disable the notifier")
+	}
+}
@@ -3,6 +3,7 @@ package notifications
 import (
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"gopkg.in/telebot.v3"
@@ -42,7 +43,31 @@ func NewTelegramNotifier(token string, chatID int64, logger *log.Logger) *Telegr
 	}
 }
 
-// Start begins polling for updates. This is required by the telebot library to send messages.
+// CommandHandler answers a chat command. args is the text after the command, trimmed.
+type CommandHandler func(args string) string
+
+// HandleCommand answers /name with handler. Only the configured chat is authorized: commands
+// from any other chat are logged and ignored, since they could control the bot's positions.
+func (tn *TelegramNotifier) HandleCommand(name string, handler CommandHandler) {
+	if tn == nil {
+		return
+	}
+	tn.bot.Handle("/"+name, func(c telebot.Context) error {
+		if c.Chat() == nil || c.Chat().ID != tn.chatID {
+			var chatID int64
+			if c.Chat() != nil {
+				chatID = c.Chat().ID
+			}
+			tn.logger.Printf("Ignoring Telegram command /%s from unauthorized chat %d", name, chatID)
+			return nil
+		}
+		tn.logger.Printf("Telegram command received: /%s %s", name, c.Message().Payload)
+		// Replies are sent as plain text, since market names and errors aren't escaped for Markdown.
+		return c.Send(handler(strings.TrimSpace(c.Message().Payload)))
+	})
+}
+
+// Start begins polling for updates, which delivers the commands registered with HandleCommand.
 func (tn *TelegramNotifier) Start() {
 	if tn == nil {
 		return
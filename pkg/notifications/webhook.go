@@ -0,0 +1,118 @@
+package notifications
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WebhookNotifier POSTs every event as a JSON object to a URL, for custom automation. With a
+// secret, each request is signed: the X-Webhook-Timestamp header carries the Unix time of the
+// request and X-Webhook-Signature is "sha256=" followed by the hex HMAC-SHA256 of the timestamp,
+// a dot and the body, keyed with the secret; see SignWebhook.
+type WebhookNotifier struct {
+	url    string
+	secret string
+	events map[EventType]bool
+	client *http.Client
+	logger *log.Logger
+}
+
+// NewWebhookNotifier creates a webhook notifier sending the listed event types, or every type
+// when events is empty. It returns nil if url is empty, and an error for unknown event types.
+func NewWebhookNotifier(url, secret string, events []string, logger *log.Logger) (*WebhookNotifier, error) {
+	if url == "" {
+		return nil, nil
+	}
+	wanted := make(map[EventType]bool)
+	for _, name := range events {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		known := false
+		for _, t := range EventTypes {
+			if string(t) == name {
+				known = true
+			}
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown webhook event %q, expected one of %v", name, EventTypes)
+		}
+		wanted[EventType(name)] = true
+	}
+	if secret == "" {
+		logger.Println("WEBHOOK_SECRET not set, webhook requests are not signed.")
+	}
+	logger.Println("Webhook notifier initialized successfully.")
+	return &WebhookNotifier{url: url, secret: secret, events: wanted, client: &http.Client{Timeout: 10 * time.Second}, logger: logger}, nil
+}
+
+// SignWebhook returns the X-Webhook-Signature of a request with the given timestamp and body.
+func SignWebhook(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// SendMessage sends message as an event of type message.
+func (wn *WebhookNotifier) SendMessage(message string) {
+	Publish(wn, Event{Type: EventMessage, Message: message})
+}
+
+// SendPositionNotification sends an order_placed or order_failed event.
+func (wn *WebhookNotifier) SendPositionNotification(action, exchangeName, market string, positionSizeUSD float64, err error) {
+	event := Event{Type: EventOrderPlaced, Action: action, Exchange: exchangeName, Market: market, SizeUSD: positionSizeUSD}
+	if err != nil {
+		event.Type, event.Error = EventOrderFailed, err.Error()
+	}
+	Publish(wn, event)
+}
+
+// SendEvent POSTs event, unless its type is filtered out.
+func (wn *WebhookNotifier) SendEvent(event Event) {
+	if wn == nil || (len(wn.events) > 0 && !wn.events[event.Type]) {
+		return
+	}
+	if err := wn.post(event); err != nil {
+		wn.logger.Printf("Failed to send %s webhook: %v", event.Type, err)
+	}
+}
+
+func (wn *WebhookNotifier) post(event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", wn.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-Event", string(event.Type))
+	if wn.secret != "" {
+		timestamp := time.Now().Unix()
+		req.Header.Set("X-Webhook-Timestamp", strconv.FormatInt(timestamp, 10))
+		req.Header.Set("X-Webhook-Signature", SignWebhook(wn.secret, timestamp, body))
+	}
+	resp, err := wn.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		reply, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s returned %s: %s", wn.url, resp.Status, reply)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package notifications
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestWebhookSignsAndFiltersEvents(t *testing.T) {
+	var received []Event
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		timestamp, _ := strconv.ParseInt(r.Header.Get("X-Webhook-Timestamp"), 10, 64)
+		if r.Header.Get("X-Webhook-Signature") != SignWebhook("hush", timestamp, body) {
+			http.Error(w, "bad signature", http.StatusUnauthorized)
+			return
+		}
+		var event Event
+		json.Unmarshal(body, &event)
+		if r.Header.Get("X-Webhook-Event") != string(event.Type) {
+			t.Errorf("X-Webhook-Event = %q for a %s event", r.Header.Get("X-Webhook-Event"), event.Type)
+		}
+		received = append(received, event)
+	}))
+	defer server.Close()
+
+	wn, err := NewWebhookNotifier(server.URL, "hush", []string{"order_failed", "position_closed"}, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatalf("NewWebhookNotifier: %v", err)
+	}
+	notifier := Multi{(*TelegramNotifier)(nil), wn}
+	notifier.SendMessage("hello")
+	notifier.SendPositionNotification("OPEN LONG", "Binance", "BTC-USD", 100, nil)
+	notifier.SendPositionNotification("OPEN SHORT", "OKX", "BTC-USD", 100, errors.New("rejected"))
+	Publish(notifier, Event{Type: EventPositionClosed, Market: "BTC-USD", LongExchange: "Binance", ShortExchange: "OKX"})
+
+	if len(received) != 2 {
+		t.Fatalf("expected only the filtered events, got %+v", received)
+	}
+	if e := received[0]; e.Type != EventOrderFailed || e.Exchange != "OKX" || e.Error != "rejected" || e.Time.IsZero() {
+		t.Errorf("unexpected order event %+v", e)
+	}
+	if e := received[1]; e.Type != EventPositionClosed || e.ShortExchange != "OKX" {
+		t.Errorf("unexpected position event %+v", e)
+	}
+
+	if _, err := NewWebhookNotifier(server.URL, "", []string{"position_open"}, log.New(io.Discard, "", 0)); err == nil {
+		t.Error("expected an unknown event type to be rejected")
+	}
+}
@@ -0,0 +1,78 @@
+// Package oracle cross-checks venue mark prices against a neutral reference price.
+package oracle
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Source returns a reference USD price for a market such as "BTC-USD".
+type Source interface {
+	Name() string
+	Price(market string) (float64, error)
+}
+
+// Deviation is a venue mark price that differs from the reference by more than allowed.
+type Deviation struct {
+	Exchange  string
+	MarkPrice float64
+	Reference float64
+	Relative  float64
+}
+
+// DeviationError reports every venue whose mark price deviates from the reference.
+type DeviationError struct {
+	Market     string
+	Source     string
+	Deviations []Deviation
+}
+
+func (e *DeviationError) Error() string {
+	parts := make([]string, len(e.Deviations))
+	for i, d := range e.Deviations {
+		parts[i] = fmt.Sprintf("%s mark %.4f vs %.4f (%.2f%%)", d.Exchange, d.MarkPrice, d.Reference, d.Relative*100)
+	}
+	return fmt.Sprintf("%s price deviates from %s: %s", e.Market, e.Source, strings.Join(parts, ", "))
+}
+
+// Checker compares venue mark prices with a reference source.
+type Checker struct {
+	source       Source
+	maxDeviation float64
+}
+
+// NewChecker creates a checker that rejects mark prices deviating from source by more than
+// maxDeviation (0.005 = 0.5%).
+func NewChecker(source Source, maxDeviation float64) *Checker {
+	return &Checker{source: source, maxDeviation: maxDeviation}
+}
+
+// Check compares the mark prices of market, keyed by exchange name, against the reference price.
+// It returns a *DeviationError if any venue is out of bounds. A nil Checker accepts every price.
+func (c *Checker) Check(market string, markPrices map[string]float64) error {
+	if c == nil || len(markPrices) == 0 {
+		return nil
+	}
+	reference, err := c.source.Price(market)
+	if err != nil {
+		return fmt.Errorf("failed to get %s reference price for %s: %w", c.source.Name(), market, err)
+	}
+	if reference <= 0 {
+		return fmt.Errorf("%s returned an invalid reference price %f for %s", c.source.Name(), reference, market)
+	}
+
+	var deviations []Deviation
+	for exchange, mark := range markPrices {
+		relative := math.Abs(mark-reference) / reference
+		if relative > c.maxDeviation {
+			deviations = append(deviations, Deviation{Exchange: exchange, MarkPrice: mark, Reference: reference, Relative: relative})
+		}
+	}
+	if len(deviations) == 0 {
+		return nil
+	}
+	sort.Slice(deviations, func(i, j int) bool { return deviations[i].Exchange < deviations[j].Exchange })
+	return &DeviationError{Market: market, Source: c.source.Name(), Deviations: deviations}
+}
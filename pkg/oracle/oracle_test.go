@@ -0,0 +1,40 @@
+package oracle
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPythPriceAndDeviationCheck(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("ids[]") != DefaultPythFeeds["BTC-USD"] {
+			http.Error(w, "unknown feed", http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"parsed":[{"id":"e62d","price":{"price":"6500000000000","conf":"1000","expo":-8,"publish_time":1718812800}}]}`))
+	}))
+	defer server.Close()
+
+	pyth := NewPyth(nil)
+	pyth.baseURL = server.URL
+	price, err := pyth.Price("BTC-USD")
+	if err != nil || math.Abs(price-65000) > 1e-6 {
+		t.Fatalf("Price = %f, %v; want 65000", price, err)
+	}
+
+	checker := NewChecker(pyth, 0.005)
+	if err := checker.Check("BTC-USD", map[string]float64{"A": 65100, "B": 64900}); err != nil {
+		t.Errorf("prices within 0.5%% should pass: %v", err)
+	}
+	err = checker.Check("BTC-USD", map[string]float64{"A": 65100, "B": 66000})
+	var deviation *DeviationError
+	if !errors.As(err, &deviation) || len(deviation.Deviations) != 1 || deviation.Deviations[0].Exchange != "B" {
+		t.Errorf("expected venue B to be flagged, got %v", err)
+	}
+	if err := checker.Check("SOL-USD", map[string]float64{"A": 150}); err == nil {
+		t.Error("expected an error for a market without a feed")
+	}
+}
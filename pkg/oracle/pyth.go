@@ -0,0 +1,110 @@
+package oracle
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PythHermesURL is the public Pyth Hermes price service.
+const PythHermesURL = "https://hermes.pyth.network"
+
+// DefaultPythFeeds maps markets to Pyth price feed IDs for the most common markets.
+var DefaultPythFeeds = map[string]string{
+	"BTC-USD": "e62df6c8b4a85fe1a67db44dc12de5db330f7ac66b72dc658afedf0f4a415b43",
+	"ETH-USD": "ff61491a931112ddf1bd8147cd1b641375f79f5825126d665480874634fd0ace",
+}
+
+// Pyth reads the latest aggregate prices from Pyth's Hermes API.
+type Pyth struct {
+	baseURL string
+	feeds   map[string]string
+	client  *http.Client
+}
+
+// NewPyth creates a Pyth source. feeds maps markets to feed IDs and is merged over DefaultPythFeeds.
+func NewPyth(feeds map[string]string) *Pyth {
+	merged := make(map[string]string, len(DefaultPythFeeds)+len(feeds))
+	for market, id := range DefaultPythFeeds {
+		merged[market] = id
+	}
+	for market, id := range feeds {
+		merged[market] = strings.TrimPrefix(id, "0x")
+	}
+	return &Pyth{baseURL: PythHermesURL, feeds: merged, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// ParseFeeds parses entries of the form "MARKET=FEED_ID".
+func ParseFeeds(entries []string) (map[string]string, error) {
+	feeds := make(map[string]string)
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		market, id, ok := strings.Cut(entry, "=")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("invalid oracle feed %q, expected MARKET=FEED_ID", entry)
+		}
+		feeds[strings.TrimSpace(market)] = strings.TrimSpace(id)
+	}
+	return feeds, nil
+}
+
+// Name implements Source.
+func (p *Pyth) Name() string {
+	return "Pyth"
+}
+
+type pythPrice struct {
+	Price string `json:"price"`
+	Expo  int    `json:"expo"`
+}
+
+type pythResponse struct {
+	Parsed []struct {
+		ID    string    `json:"id"`
+		Price pythPrice `json:"price"`
+	} `json:"parsed"`
+}
+
+// Price implements Source.
+func (p *Pyth) Price(market string) (float64, error) {
+	id, ok := p.feeds[market]
+	if !ok {
+		return 0, fmt.Errorf("no Pyth feed configured for %s", market)
+	}
+
+	req, err := http.NewRequest("GET", p.baseURL+"/v2/updates/price/latest?ids[]="+url.QueryEscape(id)+"&parsed=true", nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+		return 0, fmt.Errorf("API error: %s - %s", resp.Status, body)
+	}
+
+	var response pythResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return 0, fmt.Errorf("failed to decode Pyth response: %w", err)
+	}
+	if len(response.Parsed) == 0 {
+		return 0, fmt.Errorf("Pyth returned no price for %s", market)
+	}
+	price, err := strconv.ParseFloat(response.Parsed[0].Price.Price, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse Pyth price for %s: %w", market, err)
+	}
+	return price * math.Pow10(response.Parsed[0].Price.Expo), nil
+}
@@ -0,0 +1,135 @@
+// Package pnl accounts for the profit and loss of hedged positions: the funding received and paid
+// on both legs, and the price PnL of the legs, which a perfect hedge keeps close to zero.
+package pnl
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Leg is one side of a hedged position. Price is the current mark price of an open position and
+// the exit price of a closed one.
+type Leg struct {
+	Exchange   string
+	EntryPrice float64
+	Price      float64
+}
+
+// Position is the PnL of a hedged position, in USD. Amount is the size of each leg in the base
+// asset and Funding the net funding received on both legs.
+type Position struct {
+	Market   string
+	Long     Leg
+	Short    Leg
+	Amount   float64
+	SizeUSD  float64
+	Funding  float64
+	OpenedAt time.Time
+	ClosedAt time.Time
+}
+
+// PricePnL is the gain of the long leg plus the gain of the short leg. Legs without an entry or
+// current price contribute nothing.
+func (p Position) PricePnL() float64 {
+	pnl := 0.0
+	if p.Long.EntryPrice > 0 && p.Long.Price > 0 {
+		pnl += p.Amount * (p.Long.Price - p.Long.EntryPrice)
+	}
+	if p.Short.EntryPrice > 0 && p.Short.Price > 0 {
+		pnl += p.Amount * (p.Short.EntryPrice - p.Short.Price)
+	}
+	return pnl
+}
+
+// NetPnL is the price PnL plus funding.
+func (p Position) NetPnL() float64 {
+	return p.PricePnL() + p.Funding
+}
+
+// Summary aggregates the PnL of the open positions and of the positions closed since the ledger
+// was created. Unrealized figures cover the open positions, realized ones the closed positions.
+type Summary struct {
+	Open               []Position
+	Closed             int
+	RealizedFunding    float64
+	RealizedPricePnL   float64
+	UnrealizedFunding  float64
+	UnrealizedPricePnL float64
+}
+
+// Realized is the net PnL of the closed positions.
+func (s Summary) Realized() float64 {
+	return s.RealizedFunding + s.RealizedPricePnL
+}
+
+// Unrealized is the net PnL of the open positions if they were closed at the mark price.
+func (s Summary) Unrealized() float64 {
+	return s.UnrealizedFunding + s.UnrealizedPricePnL
+}
+
+// Total is the realized and unrealized PnL.
+func (s Summary) Total() float64 {
+	return s.Realized() + s.Unrealized()
+}
+
+// Ledger accumulates the PnL of closed positions. It is safe for concurrent use, and a nil
+// *Ledger records nothing.
+type Ledger struct {
+	mu     sync.Mutex
+	closed int
+	// funding and price are the realized funding and price PnL.
+	funding float64
+	price   float64
+}
+
+// NewLedger creates an empty ledger.
+func NewLedger() *Ledger {
+	return &Ledger{}
+}
+
+// Close realizes the PnL of a closed position.
+func (l *Ledger) Close(p Position) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.closed++
+	l.funding += p.Funding
+	l.price += p.PricePnL()
+}
+
+// Summary combines the realized PnL with the unrealized PnL of open, sorted by market.
+func (l *Ledger) Summary(open []Position) Summary {
+	s := Summary{Open: append([]Position(nil), open...)}
+	sort.Slice(s.Open, func(i, j int) bool { return s.Open[i].Market < s.Open[j].Market })
+	for _, p := range s.Open {
+		s.UnrealizedFunding += p.Funding
+		s.UnrealizedPricePnL += p.PricePnL()
+	}
+	if l != nil {
+		l.mu.Lock()
+		s.Closed, s.RealizedFunding, s.RealizedPricePnL = l.closed, l.funding, l.price
+		l.mu.Unlock()
+	}
+	return s
+}
+
+// Format renders s as plain text for logs and chat messages.
+func Format(s Summary) string {
+	var b strings.Builder
+	for _, p := range s.Open {
+		fmt.Fprintf(&b, "%s long %s / short %s, %.2f USD: funding %+.2f, price %+.2f, net %+.2f\n",
+			p.Market, p.Long.Exchange, p.Short.Exchange, p.SizeUSD, p.Funding, p.PricePnL(), p.NetPnL())
+	}
+	if len(s.Open) == 0 {
+		b.WriteString("No open positions\n")
+	}
+	fmt.Fprintf(&b, "Unrealized: %+.2f USD (funding %+.2f, price %+.2f)\n", s.Unrealized(), s.UnrealizedFunding, s.UnrealizedPricePnL)
+	fmt.Fprintf(&b, "Realized: %+.2f USD over %d closed positions (funding %+.2f, price %+.2f)\n", s.Realized(), s.Closed, s.RealizedFunding, s.RealizedPricePnL)
+	fmt.Fprintf(&b, "Total: %+.2f USD", s.Total())
+	return b.String()
+}
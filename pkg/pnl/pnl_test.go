@@ -0,0 +1,49 @@
+package pnl
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestLedgerSummary(t *testing.T) {
+	ledger := NewLedger()
+	ledger.Close(Position{
+		Market:  "ETH-USD",
+		Long:    Leg{Exchange: "Extended", EntryPrice: 3000, Price: 3100},
+		Short:   Leg{Exchange: "Lighter", EntryPrice: 3002, Price: 3101},
+		Amount:  1,
+		Funding: 4,
+	})
+	open := Position{
+		Market:  "BTC-USD",
+		Long:    Leg{Exchange: "Lighter", EntryPrice: 60000, Price: 59000},
+		Short:   Leg{Exchange: "Extended", EntryPrice: 60010, Price: 59005},
+		Amount:  0.01,
+		SizeUSD: 600,
+		Funding: -0.5,
+	}
+
+	s := ledger.Summary([]Position{open})
+	// Closed: long +100, short -99, funding 4.
+	if s.Closed != 1 || math.Abs(s.RealizedPricePnL-1) > 1e-9 || s.RealizedFunding != 4 {
+		t.Errorf("unexpected realized PnL %+v", s)
+	}
+	// Open: long -10, short +10.05, funding -0.5.
+	if math.Abs(s.UnrealizedPricePnL-0.05) > 1e-9 || s.UnrealizedFunding != -0.5 {
+		t.Errorf("unexpected unrealized PnL %+v", s)
+	}
+	if math.Abs(s.Total()-4.55) > 1e-9 {
+		t.Errorf("Total = %f, want 4.55", s.Total())
+	}
+	if text := Format(s); !strings.Contains(text, "BTC-USD long Lighter / short Extended") || !strings.Contains(text, "Total: +4.55 USD") {
+		t.Errorf("unexpected summary:\n%s", text)
+	}
+}
+
+func TestPricePnLSkipsUnpricedLegs(t *testing.T) {
+	p := Position{Long: Leg{EntryPrice: 100, Price: 110}, Short: Leg{EntryPrice: 100}, Amount: 2}
+	if got := p.PricePnL(); got != 20 {
+		t.Errorf("PricePnL = %f, want 20 from the priced long leg only", got)
+	}
+}
@@ -0,0 +1,146 @@
+package rates
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/exchange"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/marketdata"
+)
+
+// VenueRate is a funding rate on one exchange normalized to an annual basis.
+type VenueRate struct {
+	Exchange       string    `json:"exchange"`
+	Market         string    `json:"market"`
+	Rate           float64   `json:"rate"`
+	IntervalHours  float64   `json:"intervalHours"`
+	AnnualizedRate float64   `json:"annualizedRate"`
+	NextTime       int64     `json:"nextTime,omitempty"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
+
+// Spread is the annualized funding difference between two exchanges on one market,
+// oriented so that shorting ShortExchange and longing LongExchange collects it.
+type Spread struct {
+	Market         string  `json:"market"`
+	LongExchange   string  `json:"longExchange"`
+	ShortExchange  string  `json:"shortExchange"`
+	AnnualizedDiff float64 `json:"annualizedDiff"`
+}
+
+// Snapshot is a point-in-time view of rates across all venues.
+type Snapshot struct {
+	Rates     []VenueRate       `json:"rates"`
+	Spreads   []Spread          `json:"spreads"`
+	Errors    map[string]string `json:"errors,omitempty"`
+	CreatedAt time.Time         `json:"createdAt"`
+}
+
+// Aggregator collects funding rates from several exchanges through a shared cache.
+type Aggregator struct {
+	exchanges []exchange.Exchange
+	markets   []string
+	cache     *marketdata.Cache
+}
+
+// NewAggregator creates an aggregator. If markets is empty, every market
+// listed on at least two exchanges is included.
+func NewAggregator(exchanges []exchange.Exchange, markets []string, cache *marketdata.Cache) *Aggregator {
+	return &Aggregator{exchanges: exchanges, markets: markets, cache: cache}
+}
+
+// Collect fetches rates from every exchange concurrently and computes pairwise spreads.
+// Requests are abandoned when ctx is done.
+// Exchanges that fail are reported in Snapshot.Errors and left out of the result.
+func (a *Aggregator) Collect(ctx context.Context) Snapshot {
+	type result struct {
+		ex    exchange.Exchange
+		rates []*exchange.FundingRate
+		err   error
+	}
+	results := make([]result, len(a.exchanges))
+	var wg sync.WaitGroup
+	for i, ex := range a.exchanges {
+		wg.Add(1)
+		go func(i int, ex exchange.Exchange) {
+			defer wg.Done()
+			rates, err := a.cache.FundingRates(ctx, ex)
+			results[i] = result{ex: ex, rates: rates, err: err}
+		}(i, ex)
+	}
+	wg.Wait()
+
+	wanted := make(map[string]bool, len(a.markets))
+	for _, m := range a.markets {
+		wanted[m] = true
+	}
+
+	snapshot := Snapshot{CreatedAt: time.Now()}
+	byMarket := make(map[string][]VenueRate)
+	for _, res := range results {
+		if res.err != nil {
+			if snapshot.Errors == nil {
+				snapshot.Errors = make(map[string]string)
+			}
+			snapshot.Errors[res.ex.Name()] = res.err.Error()
+			continue
+		}
+		interval := exchange.FundingIntervalOf(res.ex)
+		for _, r := range res.rates {
+			if len(wanted) > 0 && !wanted[r.Market] {
+				continue
+			}
+			vr := VenueRate{
+				Exchange:       res.ex.Name(),
+				Market:         r.Market,
+				Rate:           r.Rate.InexactFloat64(),
+				IntervalHours:  interval.Hours(),
+				AnnualizedRate: exchange.Annualize(r.Rate.InexactFloat64(), interval),
+				NextTime:       r.NextTime,
+			}
+			if cached, ok := a.cache.Get(vr.Exchange, vr.Market); ok {
+				vr.UpdatedAt = cached.RateUpdated
+			}
+			snapshot.Rates = append(snapshot.Rates, vr)
+			byMarket[r.Market] = append(byMarket[r.Market], vr)
+		}
+	}
+
+	for market, venues := range byMarket {
+		snapshot.Spreads = append(snapshot.Spreads, PairwiseSpreads(market, venues)...)
+	}
+
+	sort.Slice(snapshot.Rates, func(i, j int) bool {
+		if snapshot.Rates[i].Market != snapshot.Rates[j].Market {
+			return snapshot.Rates[i].Market < snapshot.Rates[j].Market
+		}
+		return snapshot.Rates[i].Exchange < snapshot.Rates[j].Exchange
+	})
+	sort.Slice(snapshot.Spreads, func(i, j int) bool {
+		return snapshot.Spreads[i].AnnualizedDiff > snapshot.Spreads[j].AnnualizedDiff
+	})
+	return snapshot
+}
+
+// PairwiseSpreads returns one spread per pair of venues for a market, oriented so the
+// venue with the higher annualized rate is the short leg.
+func PairwiseSpreads(market string, venues []VenueRate) []Spread {
+	var spreads []Spread
+	for i := 0; i < len(venues); i++ {
+		for j := i + 1; j < len(venues); j++ {
+			hi, lo := venues[i], venues[j]
+			if lo.AnnualizedRate > hi.AnnualizedRate {
+				hi, lo = lo, hi
+			}
+			spreads = append(spreads, Spread{
+				Market:         market,
+				LongExchange:   lo.Exchange,
+				ShortExchange:  hi.Exchange,
+				AnnualizedDiff: hi.AnnualizedRate - lo.AnnualizedRate,
+			})
+		}
+	}
+	return spreads
+}
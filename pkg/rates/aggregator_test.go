@@ -0,0 +1,122 @@
+package rates
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPairwiseSpreadsOrientsShortOnHigherRate(t *testing.T) {
+	venues := []VenueRate{
+		{Exchange: "A", AnnualizedRate: 0.10},
+		{Exchange: "B", AnnualizedRate: 0.30},
+		{Exchange: "C", AnnualizedRate: 0.05},
+	}
+
+	spreads := PairwiseSpreads("BTC-USD", venues)
+	if len(spreads) != 3 {
+		t.Fatalf("expected 3 pairs, got %d", len(spreads))
+	}
+	for _, s := range spreads {
+		if s.AnnualizedDiff < 0 {
+			t.Errorf("spread %+v has negative diff", s)
+		}
+		if s.LongExchange == "B" {
+			t.Errorf("venue with the highest rate should never be the long leg: %+v", s)
+		}
+	}
+}
+
+func TestBuildMatricesFindsBestPair(t *testing.T) {
+	snapshot := Snapshot{Rates: []VenueRate{
+		{Exchange: "A", Market: "BTC-USD", AnnualizedRate: 0.10},
+		{Exchange: "B", Market: "BTC-USD", AnnualizedRate: 0.30},
+		{Exchange: "C", Market: "BTC-USD", AnnualizedRate: 0.05},
+		{Exchange: "A", Market: "ETH-USD", AnnualizedRate: 0.10},
+	}}
+
+	matrices := BuildMatrices(snapshot)
+	if len(matrices) != 1 {
+		t.Fatalf("expected only BTC-USD to have a matrix, got %d", len(matrices))
+	}
+	m := matrices[0]
+	if m.Best == nil || m.Best.ShortExchange != "B" || m.Best.LongExchange != "C" {
+		t.Fatalf("expected best pair short B / long C, got %+v", m.Best)
+	}
+	if diff := m.Diffs[1][2]; diff < 0.249 || diff > 0.251 {
+		t.Errorf("expected B-C diff of 0.25, got %f", diff)
+	}
+	if m.Diffs[0][1] != -m.Diffs[1][0] {
+		t.Errorf("matrix should be antisymmetric: %v", m.Diffs)
+	}
+}
+
+func TestOpportunitiesKeepBestPerMarket(t *testing.T) {
+	snapshot := Snapshot{
+		Rates: []VenueRate{
+			{Exchange: "A", Market: "BTC-USD", AnnualizedRate: 0.10, NextTime: 1700003600},
+			{Exchange: "B", Market: "BTC-USD", AnnualizedRate: 0.30, NextTime: 1700028800},
+			{Exchange: "C", Market: "BTC-USD", AnnualizedRate: 0.05},
+			{Exchange: "A", Market: "ETH-USD", AnnualizedRate: 0.20},
+			{Exchange: "B", Market: "ETH-USD", AnnualizedRate: 0.08},
+		},
+		Spreads: []Spread{
+			{Market: "BTC-USD", ShortExchange: "B", LongExchange: "C", AnnualizedDiff: 0.25},
+			{Market: "BTC-USD", ShortExchange: "B", LongExchange: "A", AnnualizedDiff: 0.20},
+			{Market: "ETH-USD", ShortExchange: "A", LongExchange: "B", AnnualizedDiff: 0.12},
+			{Market: "BTC-USD", ShortExchange: "A", LongExchange: "C", AnnualizedDiff: 0.05},
+		},
+	}
+
+	if all := Opportunities(snapshot, false); len(all) != 4 || all[1].NextTime != 1700003600 {
+		t.Errorf("expected every spread, with the earlier next funding of both legs, got %+v", all)
+	}
+	best := Opportunities(snapshot, true)
+	if len(best) != 2 {
+		t.Fatalf("expected one opportunity per market, got %+v", best)
+	}
+	want := Opportunity{Market: "BTC-USD", ShortExchange: "B", ShortAnnualized: 0.30, LongExchange: "C", LongAnnualized: 0.05, AnnualizedDiff: 0.25, NextTime: 1700028800}
+	if best[0] != want || best[1].Market != "ETH-USD" || best[1].LongAnnualized != 0.08 {
+		t.Errorf("unexpected opportunities %+v", best)
+	}
+}
+
+func TestWatcherAlertsOnCrossingPairChangeAndRepeat(t *testing.T) {
+	w := &Watcher{MinAnnualDiff: 0.2, Repeat: time.Hour}
+	wide := Snapshot{
+		Rates: []VenueRate{{Exchange: "A", Market: "BTC-USD", AnnualizedRate: 0.30}, {Exchange: "B", Market: "BTC-USD", AnnualizedRate: 0.05}},
+		Spreads: []Spread{
+			{Market: "BTC-USD", ShortExchange: "A", LongExchange: "B", AnnualizedDiff: 0.25},
+			{Market: "ETH-USD", ShortExchange: "A", LongExchange: "B", AnnualizedDiff: 0.10},
+		},
+	}
+	now := time.Unix(1700000000, 0)
+	if due := w.Due(wide, now); len(due) != 1 || due[0].Market != "BTC-USD" {
+		t.Fatalf("expected only BTC-USD above the threshold, got %+v", due)
+	}
+	if due := w.Due(wide, now.Add(30*time.Minute)); len(due) != 0 {
+		t.Errorf("expected no alert before the repeat interval, got %+v", due)
+	}
+	if due := w.Due(wide, now.Add(time.Hour)); len(due) != 1 {
+		t.Errorf("expected a repeat alert after an hour, got %+v", due)
+	}
+
+	flipped := Snapshot{Spreads: []Spread{{Market: "BTC-USD", ShortExchange: "B", LongExchange: "A", AnnualizedDiff: 0.22}}}
+	if due := w.Due(flipped, now.Add(61*time.Minute)); len(due) != 1 || due[0].ShortExchange != "B" {
+		t.Errorf("expected an alert when the best pair changes, got %+v", due)
+	}
+
+	narrow := Snapshot{Spreads: []Spread{{Market: "BTC-USD", ShortExchange: "B", LongExchange: "A", AnnualizedDiff: 0.1}}}
+	if due := w.Due(narrow, now.Add(62*time.Minute)); len(due) != 0 {
+		t.Errorf("expected no alert below the threshold, got %+v", due)
+	}
+	if due := w.Due(flipped, now.Add(63*time.Minute)); len(due) != 1 {
+		t.Errorf("expected an alert when the spread crosses the threshold again, got %+v", due)
+	}
+
+	message := FormatAlert(Opportunity{Market: "BTC-USD", ShortExchange: "A", ShortAnnualized: 0.30, LongExchange: "B", LongAnnualized: 0.05,
+		AnnualizedDiff: 0.25, NextTime: now.Add(90 * time.Minute).Unix()}, 10000, now)
+	want := "BTC-USD funding spread of 25.00% APR: short on A (30.00% APR) / long on B (5.00% APR). About 6.85 USD a day on 10000.00 USD, before fees. Next funding in 1h30m0s."
+	if message != want {
+		t.Errorf("unexpected alert %q", message)
+	}
+}
@@ -0,0 +1,81 @@
+package rates
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// Matrix holds the annualized funding spread for every ordered venue pair on one market.
+// Diffs[i][j] is what shorting Exchanges[i] and longing Exchanges[j] collects per year.
+type Matrix struct {
+	Market    string      `json:"market"`
+	Exchanges []string    `json:"exchanges"`
+	Diffs     [][]float64 `json:"diffs"`
+	Best      *Spread     `json:"best,omitempty"`
+}
+
+// BuildMatrices groups the snapshot's rates by market and builds one matrix per market
+// quoted on at least two venues. Venues missing a market are left out of that market's matrix.
+func BuildMatrices(snapshot Snapshot) []Matrix {
+	byMarket := make(map[string][]VenueRate)
+	for _, r := range snapshot.Rates {
+		byMarket[r.Market] = append(byMarket[r.Market], r)
+	}
+
+	matrices := make([]Matrix, 0, len(byMarket))
+	for market, venues := range byMarket {
+		if len(venues) < 2 {
+			continue
+		}
+		sort.Slice(venues, func(i, j int) bool { return venues[i].Exchange < venues[j].Exchange })
+		m := Matrix{Market: market, Exchanges: make([]string, len(venues)), Diffs: make([][]float64, len(venues))}
+		for i, short := range venues {
+			m.Exchanges[i] = short.Exchange
+			m.Diffs[i] = make([]float64, len(venues))
+			for j, long := range venues {
+				if i == j {
+					continue
+				}
+				diff := short.AnnualizedRate - long.AnnualizedRate
+				m.Diffs[i][j] = diff
+				if m.Best == nil || diff > m.Best.AnnualizedDiff {
+					m.Best = &Spread{Market: market, LongExchange: long.Exchange, ShortExchange: short.Exchange, AnnualizedDiff: diff}
+				}
+			}
+		}
+		matrices = append(matrices, m)
+	}
+	sort.Slice(matrices, func(i, j int) bool { return matrices[i].Market < matrices[j].Market })
+	return matrices
+}
+
+// WriteMatrices renders each matrix as a text table with short venues as rows and long venues
+// as columns. Values are annualized percentages and the best pair is marked with an asterisk.
+func WriteMatrices(w io.Writer, matrices []Matrix) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	for _, m := range matrices {
+		fmt.Fprintf(tw, "%s (short \\ long)\t%s\t\n", m.Market, strings.Join(m.Exchanges, "\t"))
+		for i, short := range m.Exchanges {
+			cells := make([]string, len(m.Exchanges))
+			for j, long := range m.Exchanges {
+				if i == j {
+					cells[j] = "-"
+					continue
+				}
+				cells[j] = fmt.Sprintf("%.2f%%", m.Diffs[i][j]*100)
+				if m.Best != nil && m.Best.ShortExchange == short && m.Best.LongExchange == long {
+					cells[j] = "*" + cells[j]
+				}
+			}
+			fmt.Fprintf(tw, "%s\t%s\t\n", short, strings.Join(cells, "\t"))
+		}
+		if m.Best != nil {
+			fmt.Fprintf(tw, "best: short %s / long %s at %.2f%% APR\t\n", m.Best.ShortExchange, m.Best.LongExchange, m.Best.AnnualizedDiff*100)
+		}
+		fmt.Fprintln(tw)
+	}
+	return tw.Flush()
+}
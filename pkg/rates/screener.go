@@ -0,0 +1,70 @@
+package rates
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+)
+
+// Opportunity is a spread together with the annualized rates of its two legs.
+type Opportunity struct {
+	Market          string  `json:"market"`
+	ShortExchange   string  `json:"shortExchange"`
+	ShortAnnualized float64 `json:"shortAnnualizedRate"`
+	LongExchange    string  `json:"longExchange"`
+	LongAnnualized  float64 `json:"longAnnualizedRate"`
+	AnnualizedDiff  float64 `json:"annualizedDiff"`
+	// NextTime is the earlier of the two legs' next funding times, 0 if neither venue reports it.
+	NextTime int64 `json:"nextTime,omitempty"`
+}
+
+// Opportunities returns the snapshot's spreads, widest first, with the rates of both legs. With
+// bestOnly set, only the widest spread of each market is kept.
+func Opportunities(snapshot Snapshot, bestOnly bool) []Opportunity {
+	type key struct{ exchange, market string }
+	byVenue := make(map[key]VenueRate, len(snapshot.Rates))
+	for _, r := range snapshot.Rates {
+		byVenue[key{r.Exchange, r.Market}] = r
+	}
+
+	seen := make(map[string]bool)
+	var opportunities []Opportunity
+	for _, s := range snapshot.Spreads {
+		if bestOnly && seen[s.Market] {
+			continue
+		}
+		seen[s.Market] = true
+		short, long := byVenue[key{s.ShortExchange, s.Market}], byVenue[key{s.LongExchange, s.Market}]
+		next := short.NextTime
+		if next == 0 || (long.NextTime != 0 && long.NextTime < next) {
+			next = long.NextTime
+		}
+		opportunities = append(opportunities, Opportunity{
+			Market:          s.Market,
+			ShortExchange:   s.ShortExchange,
+			ShortAnnualized: short.AnnualizedRate,
+			LongExchange:    s.LongExchange,
+			LongAnnualized:  long.AnnualizedRate,
+			AnnualizedDiff:  s.AnnualizedDiff,
+			NextTime:        next,
+		})
+	}
+	return opportunities
+}
+
+// WriteOpportunities renders opportunities as a text table. Rates are annualized percentages and
+// the next funding is shown as the time left until it, relative to now.
+func WriteOpportunities(w io.Writer, opportunities []Opportunity, now time.Time) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "MARKET\tSHORT\tSHORT APR\tLONG\tLONG APR\tDIFF APR\tNEXT FUNDING")
+	for _, o := range opportunities {
+		next := "-"
+		if o.NextTime > 0 {
+			next = time.Unix(o.NextTime, 0).Sub(now).Truncate(time.Minute).String()
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%.2f%%\t%s\t%.2f%%\t%.2f%%\t%s\n",
+			o.Market, o.ShortExchange, o.ShortAnnualized*100, o.LongExchange, o.LongAnnualized*100, o.AnnualizedDiff*100, next)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,112 @@
+package rates
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/notifications"
+)
+
+// Watcher notifies the operator of the markets whose widest funding spread reaches MinAnnualDiff,
+// for traders who execute by hand. Nothing is traded. A market is alerted when its spread crosses
+// the threshold, and again while it stays above when the best venue pair changes or Repeat has
+// passed since the last alert.
+type Watcher struct {
+	Aggregator    *Aggregator
+	Notifier      notifications.Notifier
+	MinAnnualDiff float64
+	// Repeat is how often a market still above the threshold is alerted again; 0 alerts it once
+	// per crossing.
+	Repeat time.Duration
+	// SizeUSD, if set, is the position size the alerts estimate the daily funding of.
+	SizeUSD float64
+	Logger  *log.Logger
+
+	alerted map[string]watchAlert
+}
+
+// watchAlert is the last alert sent for a market.
+type watchAlert struct {
+	short, long string
+	at          time.Time
+}
+
+// Due returns the widest spread of each market in snapshot that is to be alerted at now, widest
+// first, and records them as alerted. Markets below the threshold are forgotten, so they are
+// alerted as soon as they cross it again.
+func (w *Watcher) Due(snapshot Snapshot, now time.Time) []Opportunity {
+	if w.alerted == nil {
+		w.alerted = make(map[string]watchAlert)
+	}
+	above := make(map[string]bool)
+	var due []Opportunity
+	for _, o := range Opportunities(snapshot, true) {
+		if o.AnnualizedDiff < w.MinAnnualDiff {
+			continue
+		}
+		above[o.Market] = true
+		last, ok := w.alerted[o.Market]
+		samePair := ok && last.short == o.ShortExchange && last.long == o.LongExchange
+		if samePair && (w.Repeat <= 0 || now.Sub(last.at) < w.Repeat) {
+			continue
+		}
+		w.alerted[o.Market] = watchAlert{short: o.ShortExchange, long: o.LongExchange, at: now}
+		due = append(due, o)
+	}
+	for market := range w.alerted {
+		if !above[market] {
+			delete(w.alerted, market)
+		}
+	}
+	return due
+}
+
+// Check collects the funding rates once and alerts the spreads that are due. It returns how many
+// were alerted.
+func (w *Watcher) Check(ctx context.Context, now time.Time) int {
+	snapshot := w.Aggregator.Collect(ctx)
+	for name, msg := range snapshot.Errors {
+		w.Logger.Printf("%s: %s", name, msg)
+	}
+	due := w.Due(snapshot, now)
+	for _, o := range due {
+		message := FormatAlert(o, w.SizeUSD, now)
+		w.Logger.Println(message)
+		w.Notifier.SendMessage("📡 " + message)
+		notifications.Publish(w.Notifier, notifications.Event{Type: notifications.EventOpportunity, Time: now, Market: o.Market,
+			LongExchange: o.LongExchange, ShortExchange: o.ShortExchange, SizeUSD: w.SizeUSD, RateDiff: o.AnnualizedDiff, Message: message})
+	}
+	return len(due)
+}
+
+// Run checks the spreads every interval until ctx is done.
+func (w *Watcher) Run(ctx context.Context, every time.Duration) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		if alerted := w.Check(ctx, time.Now()); alerted == 0 {
+			w.Logger.Printf("No new spread of %.2f%% APR or more.", w.MinAnnualDiff*100)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// FormatAlert describes o as a trade to place by hand: the legs, the annualized spread and, with a
+// size, the funding it would earn a day before fees.
+func FormatAlert(o Opportunity, sizeUSD float64, now time.Time) string {
+	message := fmt.Sprintf("%s funding spread of %.2f%% APR: short on %s (%.2f%% APR) / long on %s (%.2f%% APR).",
+		o.Market, o.AnnualizedDiff*100, o.ShortExchange, o.ShortAnnualized*100, o.LongExchange, o.LongAnnualized*100)
+	if sizeUSD > 0 {
+		message += fmt.Sprintf(" About %.2f USD a day on %.2f USD, before fees.", sizeUSD*o.AnnualizedDiff/365, sizeUSD)
+	}
+	if o.NextTime > 0 {
+		message += fmt.Sprintf(" Next funding in %s.", time.Unix(o.NextTime, 0).Sub(now).Truncate(time.Minute))
+	}
+	return message
+}
@@ -0,0 +1,116 @@
+package report
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/journal"
+)
+
+// VenueExecution summarizes execution quality on one exchange. Slippage is signed so that
+// positive values are adverse (paid more on buys, received less on sells), in basis points
+// of the price at decision time.
+type VenueExecution struct {
+	Exchange       string
+	Fills          int
+	AvgSlippageBps float64
+	P95SlippageBps float64
+	MaxSlippageBps float64
+	AvgLatencyMs   float64
+	P95LatencyMs   float64
+}
+
+// BuildExecutionReport computes per-venue slippage and latency statistics from the fills in
+// entries between from and to. Fills without a decision price are counted for latency only.
+func BuildExecutionReport(entries []journal.Entry, from, to time.Time) []VenueExecution {
+	type samples struct {
+		slippage []float64
+		latency  []float64
+		fills    int
+	}
+	byVenue := make(map[string]*samples)
+	for _, e := range entries {
+		if e.Type != journal.EntryFill {
+			continue
+		}
+		if (!from.IsZero() && e.Time.Before(from)) || (!to.IsZero() && !e.Time.Before(to)) {
+			continue
+		}
+		s, ok := byVenue[e.Exchange]
+		if !ok {
+			s = &samples{}
+			byVenue[e.Exchange] = s
+		}
+		s.fills++
+		if e.DecisionPrice > 0 && e.Price > 0 {
+			bps := (e.Price - e.DecisionPrice) / e.DecisionPrice * 1e4
+			if strings.EqualFold(e.Side, "SELL") {
+				bps = -bps
+			}
+			s.slippage = append(s.slippage, bps)
+		}
+		if e.LatencyMs > 0 {
+			s.latency = append(s.latency, float64(e.LatencyMs))
+		}
+	}
+
+	report := make([]VenueExecution, 0, len(byVenue))
+	for venue, s := range byVenue {
+		v := VenueExecution{Exchange: venue, Fills: s.fills}
+		v.AvgSlippageBps, v.P95SlippageBps, v.MaxSlippageBps = summarize(s.slippage)
+		v.AvgLatencyMs, v.P95LatencyMs, _ = summarize(s.latency)
+		report = append(report, v)
+	}
+	sort.Slice(report, func(i, j int) bool { return report[i].Exchange < report[j].Exchange })
+	return report
+}
+
+// summarize returns the mean, 95th percentile and maximum of values.
+func summarize(values []float64) (mean, p95, max float64) {
+	if len(values) == 0 {
+		return 0, 0, 0
+	}
+	sorted := make([]float64, len(values))
+	copy(sorted, values)
+	sort.Float64s(sorted)
+	sum := 0.0
+	for _, v := range sorted {
+		sum += v
+	}
+	idx := int(math.Ceil(0.95*float64(len(sorted)))) - 1
+	return sum / float64(len(sorted)), sorted[idx], sorted[len(sorted)-1]
+}
+
+// WriteExecutionCSV writes per-venue execution statistics.
+func WriteExecutionCSV(w io.Writer, report []VenueExecution) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"Exchange", "Fills", "Avg Slippage (bps)", "P95 Slippage (bps)", "Max Slippage (bps)", "Avg Latency (ms)", "P95 Latency (ms)"})
+	for _, v := range report {
+		cw.Write([]string{v.Exchange, fmt.Sprint(v.Fills), formatFloat(round(v.AvgSlippageBps)), formatFloat(round(v.P95SlippageBps)),
+			formatFloat(round(v.MaxSlippageBps)), formatFloat(round(v.AvgLatencyMs)), formatFloat(round(v.P95LatencyMs))})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// FormatExecution renders the report as a short human-readable summary, one line per venue.
+func FormatExecution(report []VenueExecution) string {
+	if len(report) == 0 {
+		return "No fills recorded."
+	}
+	var b strings.Builder
+	for _, v := range report {
+		fmt.Fprintf(&b, "%s: %d fills, slippage avg %.1f / p95 %.1f bps, latency avg %.0f / p95 %.0f ms\n",
+			v.Exchange, v.Fills, v.AvgSlippageBps, v.P95SlippageBps, v.AvgLatencyMs, v.P95LatencyMs)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,32 @@
+package report
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/journal"
+)
+
+func TestBuildExecutionReport(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	entries := []journal.Entry{
+		{Time: t0, Type: journal.EntryFill, Exchange: "A", Side: "BUY", Price: 100.1, DecisionPrice: 100, LatencyMs: 100},
+		{Time: t0, Type: journal.EntryFill, Exchange: "A", Side: "SELL", Price: 99.8, DecisionPrice: 100, LatencyMs: 300},
+		{Time: t0, Type: journal.EntryFill, Exchange: "B", Side: "SELL", Price: 100.05, DecisionPrice: 100},
+		{Time: t0, Type: journal.EntryFunding, Exchange: "B", Funding: 1},
+	}
+
+	report := BuildExecutionReport(entries, time.Time{}, time.Time{})
+	if len(report) != 2 {
+		t.Fatalf("expected two venues, got %+v", report)
+	}
+	a, b := report[0], report[1]
+	if a.Fills != 2 || math.Abs(a.AvgSlippageBps-15) > 1e-6 || a.MaxSlippageBps < 19.99 || a.AvgLatencyMs != 200 {
+		t.Errorf("unexpected stats for A: %+v", a)
+	}
+	// Selling above the decision price is favorable, so slippage is negative.
+	if b.Fills != 1 || b.AvgSlippageBps >= 0 {
+		t.Errorf("unexpected stats for B: %+v", b)
+	}
+}
@@ -0,0 +1,214 @@
+package report
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/journal"
+)
+
+// RealizedGain is the result of closing (part of) a lot.
+type RealizedGain struct {
+	Exchange  string
+	Market    string
+	Side      string // side of the lot being closed: LONG or SHORT
+	Amount    float64
+	OpenedAt  time.Time
+	ClosedAt  time.Time
+	CostBasis float64 // entry notional plus the pro-rata share of fees
+	Proceeds  float64 // exit notional
+	Gain      float64
+}
+
+// DailyFunding is the net funding received on one market and day.
+type DailyFunding struct {
+	Date     string
+	Exchange string
+	Market   string
+	Amount   float64
+}
+
+// TaxReport is the accounting view of a journal.
+type TaxReport struct {
+	Gains   []RealizedGain
+	Funding []DailyFunding
+}
+
+// lot is an open quantity with its per-unit cost including fees.
+type lot struct {
+	amount   float64
+	price    float64
+	feeUnit  float64
+	openedAt time.Time
+}
+
+// BuildTaxReport matches fills first-in-first-out per exchange and market to compute realized gains,
+// and aggregates funding payments by UTC day. Entries outside [from, to) are ignored for the output
+// but still used to build lots. A zero from or to leaves that side unbounded.
+func BuildTaxReport(entries []journal.Entry, from, to time.Time) TaxReport {
+	sorted := make([]journal.Entry, len(entries))
+	copy(sorted, entries)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Time.Before(sorted[j].Time) })
+
+	inRange := func(t time.Time) bool {
+		return (from.IsZero() || !t.Before(from)) && (to.IsZero() || t.Before(to))
+	}
+
+	var report TaxReport
+	longs := make(map[string][]lot)
+	shorts := make(map[string][]lot)
+	funding := make(map[[3]string]float64)
+
+	for _, e := range sorted {
+		switch e.Type {
+		case journal.EntryFunding:
+			if inRange(e.Time) {
+				funding[[3]string{e.Time.UTC().Format("2006-01-02"), e.Exchange, e.Market}] += e.Funding
+			}
+		case journal.EntryFill:
+			if e.Amount <= 0 {
+				continue
+			}
+			key := e.Exchange + "|" + e.Market
+			feeUnit := e.Fee / e.Amount
+			buy := strings.EqualFold(e.Side, "BUY")
+
+			// A buy first closes shorts, a sell first closes longs; any remainder opens a new lot.
+			opposite, same := shorts, longs
+			closedSide := "SHORT"
+			if !buy {
+				opposite, same = longs, shorts
+				closedSide = "LONG"
+			}
+			remaining := e.Amount
+			lots := opposite[key]
+			for remaining > 0 && len(lots) > 0 {
+				l := &lots[0]
+				qty := remaining
+				if l.amount < qty {
+					qty = l.amount
+				}
+				gain := RealizedGain{
+					Exchange: e.Exchange,
+					Market:   e.Market,
+					Side:     closedSide,
+					Amount:   qty,
+					OpenedAt: l.openedAt,
+					ClosedAt: e.Time,
+				}
+				fees := (l.feeUnit + feeUnit) * qty
+				if closedSide == "LONG" {
+					gain.CostBasis = l.price*qty + fees
+					gain.Proceeds = e.Price * qty
+				} else {
+					// For a short, the entry sale is the proceeds and the buy-back is the cost.
+					gain.CostBasis = e.Price*qty + fees
+					gain.Proceeds = l.price * qty
+				}
+				gain.Gain = gain.Proceeds - gain.CostBasis
+				if inRange(e.Time) {
+					report.Gains = append(report.Gains, gain)
+				}
+				l.amount -= qty
+				remaining -= qty
+				if l.amount <= 1e-12 {
+					lots = lots[1:]
+				}
+			}
+			opposite[key] = lots
+			if remaining > 1e-12 {
+				same[key] = append(same[key], lot{amount: remaining, price: e.Price, feeUnit: feeUnit, openedAt: e.Time})
+			}
+		}
+	}
+
+	for k, amount := range funding {
+		report.Funding = append(report.Funding, DailyFunding{Date: k[0], Exchange: k[1], Market: k[2], Amount: amount})
+	}
+	sort.Slice(report.Funding, func(i, j int) bool {
+		a, b := report.Funding[i], report.Funding[j]
+		if a.Date != b.Date {
+			return a.Date < b.Date
+		}
+		return a.Exchange+a.Market < b.Exchange+b.Market
+	})
+	return report
+}
+
+// WriteGainsCSV writes realized gains in a generic cost-basis format.
+func WriteGainsCSV(w io.Writer, gains []RealizedGain) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"Date Acquired", "Date Sold", "Exchange", "Market", "Position", "Amount", "Cost Basis (USD)", "Proceeds (USD)", "Gain (USD)"})
+	for _, g := range gains {
+		cw.Write([]string{
+			g.OpenedAt.UTC().Format(time.RFC3339), g.ClosedAt.UTC().Format(time.RFC3339),
+			g.Exchange, g.Market, g.Side, formatFloat(g.Amount),
+			formatFloat(g.CostBasis), formatFloat(g.Proceeds), formatFloat(g.Gain),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteFundingCSV writes funding income aggregated by day.
+func WriteFundingCSV(w io.Writer, funding []DailyFunding) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"Date", "Exchange", "Market", "Funding (USD)"})
+	for _, f := range funding {
+		cw.Write([]string{f.Date, f.Exchange, f.Market, formatFloat(f.Amount)})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteKoinlyCSV writes realized gains and funding in Koinly's universal import format, where
+// derivatives PnL is recorded as "realized gain" and funding as income or a margin fee.
+func WriteKoinlyCSV(w io.Writer, report TaxReport) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"Date", "Sent Amount", "Sent Currency", "Received Amount", "Received Currency",
+		"Fee Amount", "Fee Currency", "Net Worth Amount", "Net Worth Currency", "Label", "Description", "TxHash"})
+
+	row := func(date time.Time, amount float64, label, description string) {
+		sent, received := "", ""
+		if amount < 0 {
+			sent = formatFloat(-amount)
+		} else {
+			received = formatFloat(amount)
+		}
+		sentCurrency, receivedCurrency := "", ""
+		if sent != "" {
+			sentCurrency = "USD"
+		}
+		if received != "" {
+			receivedCurrency = "USD"
+		}
+		cw.Write([]string{date.UTC().Format("2006-01-02 15:04:05 UTC"), sent, sentCurrency, received, receivedCurrency,
+			"", "", "", "", label, description, ""})
+	}
+
+	for _, g := range report.Gains {
+		row(g.ClosedAt, g.Gain, "realized gain", fmt.Sprintf("%s %s %s close on %s", g.Market, g.Side, formatFloat(g.Amount), g.Exchange))
+	}
+	for _, f := range report.Funding {
+		date, err := time.Parse("2006-01-02", f.Date)
+		if err != nil {
+			return err
+		}
+		label := "income"
+		if f.Amount < 0 {
+			label = "margin fee"
+		}
+		row(date, f.Amount, label, fmt.Sprintf("%s funding on %s", f.Market, f.Exchange))
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
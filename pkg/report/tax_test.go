@@ -0,0 +1,44 @@
+package report
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/journal"
+)
+
+func TestBuildTaxReportMatchesFillsFIFO(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	entries := []journal.Entry{
+		{Time: t0, Type: journal.EntryFill, Exchange: "A", Market: "BTC-USD", Side: "BUY", Amount: 1, Price: 100},
+		{Time: t0.Add(time.Hour), Type: journal.EntryFill, Exchange: "A", Market: "BTC-USD", Side: "BUY", Amount: 1, Price: 110},
+		{Time: t0.Add(2 * time.Hour), Type: journal.EntryFill, Exchange: "A", Market: "BTC-USD", Side: "SELL", Amount: 1.5, Price: 120, Fee: 1.5},
+		{Time: t0.Add(3 * time.Hour), Type: journal.EntryFill, Exchange: "B", Market: "BTC-USD", Side: "SELL", Amount: 1, Price: 120},
+		{Time: t0.Add(4 * time.Hour), Type: journal.EntryFill, Exchange: "B", Market: "BTC-USD", Side: "BUY", Amount: 1, Price: 100},
+		{Time: t0.Add(5 * time.Hour), Type: journal.EntryFunding, Exchange: "B", Market: "BTC-USD", Funding: 0.5},
+		{Time: t0.Add(6 * time.Hour), Type: journal.EntryFunding, Exchange: "B", Market: "BTC-USD", Funding: 0.25},
+	}
+
+	report := BuildTaxReport(entries, time.Time{}, time.Time{})
+
+	if len(report.Gains) != 3 {
+		t.Fatalf("expected 3 realized gains, got %d: %+v", len(report.Gains), report.Gains)
+	}
+	// First lot: 1 @ 100 sold @ 120 with 1.0 of the 1.5 fee.
+	if math.Abs(report.Gains[0].Gain-19) > 1e-9 {
+		t.Errorf("expected first gain 19, got %f", report.Gains[0].Gain)
+	}
+	// Second lot: 0.5 @ 110 sold @ 120 with 0.5 of the fee.
+	if math.Abs(report.Gains[1].Gain-4.5) > 1e-9 {
+		t.Errorf("expected second gain 4.5, got %f", report.Gains[1].Gain)
+	}
+	// Short: sold @ 120, bought back @ 100.
+	if report.Gains[2].Side != "SHORT" || math.Abs(report.Gains[2].Gain-20) > 1e-9 {
+		t.Errorf("expected short gain 20, got %+v", report.Gains[2])
+	}
+
+	if len(report.Funding) != 1 || report.Funding[0].Amount != 0.75 {
+		t.Errorf("expected 0.75 funding aggregated into one day, got %+v", report.Funding)
+	}
+}
@@ -0,0 +1,124 @@
+// Package state persists open positions so that a restarted bot resumes managing them.
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Position is the persisted form of an open hedged position. Exchanges are stored by name.
+type Position struct {
+	Market        string  `json:"market"`
+	LongExchange  string  `json:"longExchange"`
+	ShortExchange string  `json:"shortExchange"`
+	SizeUSD       float64 `json:"sizeUsd"`
+	// Amount is the base amount of each leg; 0 in files written before it was recorded.
+	Amount        decimal.Decimal `json:"amount,omitzero"`
+	EntryRateDiff float64         `json:"entryRateDiff"`
+	EntrySlippage float64         `json:"entrySlippage,omitempty"`
+	OpenedAt      time.Time       `json:"openedAt"`
+	// LongEntryPrice and ShortEntryPrice are the fill prices of the legs, and Funding the net
+	// funding received up to FundingSyncedAt.
+	LongEntryPrice  float64   `json:"longEntryPrice,omitempty"`
+	ShortEntryPrice float64   `json:"shortEntryPrice,omitempty"`
+	Funding         float64   `json:"funding,omitempty"`
+	FundingSyncedAt time.Time `json:"fundingSyncedAt,omitempty"`
+	// CollectAfter is when the position is closed with EXIT_AFTER_FUNDING.
+	CollectAfter time.Time `json:"collectAfter,omitempty"`
+}
+
+// Store is a JSON state file holding the open positions of each strategy. A nil *Store is valid
+// and persists nothing.
+type Store struct {
+	mu   sync.Mutex
+	path string
+}
+
+// Open returns the store at path. It returns nil if path is empty.
+func Open(path string) *Store {
+	if path == "" {
+		return nil
+	}
+	return &Store{path: path}
+}
+
+// Load returns the positions saved for strategy. A missing state file holds no positions.
+func (s *Store) Load(strategy string) ([]Position, error) {
+	if s == nil {
+		return nil, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	all, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	return all[strategy], nil
+}
+
+// Save replaces the positions saved for strategy. The file is written to a temporary file and
+// renamed over the old one, so a crash never leaves a truncated state behind.
+func (s *Store) Save(strategy string, positions []Position) error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	all, err := s.read()
+	if err != nil {
+		return err
+	}
+	if len(positions) == 0 {
+		delete(all, strategy)
+	} else {
+		all[strategy] = positions
+	}
+
+	data, err := json.MarshalIndent(all, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write state %s: %w", s.path, err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write state %s: %w", s.path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write state %s: %w", s.path, err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write state %s: %w", s.path, err)
+	}
+	return nil
+}
+
+// read loads every strategy's positions. The caller must hold s.mu.
+func (s *Store) read() (map[string][]Position, error) {
+	all := make(map[string][]Position)
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return all, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state %s: %w", s.path, err)
+	}
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, fmt.Errorf("failed to parse state %s: %w", s.path, err)
+	}
+	return all, nil
+}
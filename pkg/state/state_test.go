@@ -0,0 +1,52 @@
+package state
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStoreRoundTrip(t *testing.T) {
+	store := Open(filepath.Join(t.TempDir(), "state.json"))
+	opened := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	if positions, err := store.Load("funding-rate-arb"); err != nil || len(positions) != 0 {
+		t.Fatalf("expected no positions before anything is saved, got %v, %v", positions, err)
+	}
+
+	saved := []Position{{Market: "BTC-USD", LongExchange: "Extended", ShortExchange: "Lighter", SizeUSD: 600, EntryRateDiff: 0.0004, OpenedAt: opened}}
+	if err := store.Save("funding-rate-arb", saved); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save("spot-perp-hedge", []Position{{Market: "ETH-USD"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := store.Load("funding-rate-arb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded) != 1 || loaded[0] != saved[0] {
+		t.Errorf("expected %+v, got %+v", saved, loaded)
+	}
+
+	if err := store.Save("funding-rate-arb", nil); err != nil {
+		t.Fatal(err)
+	}
+	if loaded, _ := store.Load("funding-rate-arb"); len(loaded) != 0 {
+		t.Errorf("expected the positions to be cleared, got %+v", loaded)
+	}
+	if other, _ := store.Load("spot-perp-hedge"); len(other) != 1 {
+		t.Errorf("expected other strategies to be kept, got %+v", other)
+	}
+}
+
+func TestNilStore(t *testing.T) {
+	var store *Store
+	if err := store.Save("x", []Position{{Market: "BTC-USD"}}); err != nil {
+		t.Error(err)
+	}
+	if positions, err := store.Load("x"); positions != nil || err != nil {
+		t.Errorf("expected a nil store to hold nothing, got %v, %v", positions, err)
+	}
+}
@@ -0,0 +1,82 @@
+package strategy
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/exchange"
+)
+
+// insufficientFundsError reports a venue without the free collateral to margin a new position.
+type insufficientFundsError struct {
+	exchange string
+	freeUSD  float64
+	needUSD  float64
+}
+
+func (e *insufficientFundsError) Error() string {
+	return fmt.Sprintf("insufficient collateral on %s: %.2f USD free, %.2f USD needed", e.exchange, e.freeUSD, e.needUSD)
+}
+
+// leverage is the configured LEVERAGE, with values below 1 treated as 1.
+func (s *Strategy) leverage() float64 {
+	if s.config.Leverage < 1 {
+		return 1
+	}
+	return s.config.Leverage
+}
+
+// venueLeverage is the leverage of market on ex: its LEVERAGES entry, or LEVERAGE.
+func (s *Strategy) venueLeverage(ex exchange.Exchange, market string) float64 {
+	return s.margin.leverageFor(ex.Name(), market, s.leverage())
+}
+
+// checkBalances verifies that both venues have the free collateral to margin a leg of sizeUSD in
+// market at the leverage configured for the venue. Free collateral is the venue's balance less
+// the margin of the positions already open on it. The caller must not hold s.mu, since the
+// balances are read from the venues.
+func (s *Strategy) checkBalances(market string, longEx, shortEx exchange.Exchange, sizeUSD float64) error {
+	for _, ex := range []exchange.Exchange{longEx, shortEx} {
+		need := sizeUSD / s.venueLeverage(ex, market)
+		balance, err := s.collateral.BalanceUSD(s.ctx, ex)
+		if err != nil {
+			return fmt.Errorf("could not get the balance on %s: %w", ex.Name(), err)
+		}
+		s.mu.Lock()
+		free := balance - s.committedMargin(ex)
+		s.mu.Unlock()
+		if free < need {
+			return &insufficientFundsError{exchange: ex.Name(), freeUSD: free, needUSD: need}
+		}
+	}
+	return nil
+}
+
+// committedMargin is the margin held by the open positions with a leg on ex. The caller must
+// hold s.mu.
+func (s *Strategy) committedMargin(ex exchange.Exchange) float64 {
+	total := 0.0
+	for _, position := range s.positions {
+		if position.LongExchange.Name() == ex.Name() || position.ShortExchange.Name() == ex.Name() {
+			total += position.SizeUSD / s.venueLeverage(ex, position.Market)
+		}
+	}
+	return total
+}
+
+// notifyUnderfunded sends a notification the first time market is skipped for insufficient
+// collateral. Failures to read a balance are only logged.
+func (s *Strategy) notifyUnderfunded(market string, err error) {
+	var insufficient *insufficientFundsError
+	if !errors.As(err, &insufficient) {
+		return
+	}
+	s.mu.Lock()
+	notified := s.underfunded[market]
+	s.underfunded[market] = true
+	s.mu.Unlock()
+	if notified {
+		return
+	}
+	s.notifier.SendMessage(fmt.Sprintf("⚠️ Skipping %s: %v", market, err))
+}
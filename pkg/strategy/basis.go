@@ -0,0 +1,77 @@
+package strategy
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/notifications"
+)
+
+// basisCheckInterval is how often the basis PnL of open positions is measured against
+// BASIS_STOP_USD.
+const basisCheckInterval = time.Minute
+
+// basisPnL returns the mark-to-market PnL of position's legs at the mark prices of their own
+// venues, excluding funding: what the basis between the venues has moved since entry. It reports
+// false when either leg can't be priced or has no entry price.
+func (s *Strategy) basisPnL(position PositionInfo) (basis, longMark, shortMark float64, ok bool) {
+	if position.LongEntryPrice <= 0 || position.ShortEntryPrice <= 0 {
+		return 0, 0, 0, false
+	}
+	var err error
+	if longMark, err = s.marketData.MarkPrice(s.ctx, position.LongExchange, position.Market); err != nil || longMark <= 0 {
+		return 0, 0, 0, false
+	}
+	if shortMark, err = s.marketData.MarkPrice(s.ctx, position.ShortExchange, position.Market); err != nil || shortMark <= 0 {
+		return 0, 0, 0, false
+	}
+	return pnlOf(position, longMark, shortMark).PricePnL(), longMark, shortMark, true
+}
+
+// checkBasisStop measures the basis PnL of every open position and closes both legs of those that
+// have lost more than BASIS_STOP_USD to it. Funding is hedged, but the prices of the two venues
+// can drift apart, and the losing leg's margin pays for the divergence until the basis converges.
+// The stop fires regardless of MIN_HOLD_MINUTES.
+func (s *Strategy) checkBasisStop() {
+	s.mu.Lock()
+	positions := make([]PositionInfo, 0, len(s.positions))
+	for _, position := range s.positions {
+		positions = append(positions, *position)
+	}
+	previous := s.basis
+	s.mu.Unlock()
+
+	current := make(map[string]float64, len(positions))
+	for _, position := range positions {
+		basis, longMark, shortMark, ok := s.basisPnL(position)
+		if !ok {
+			continue
+		}
+		current[position.Market] = basis
+		s.metrics.SetBasisPnL(position.Market, basis)
+		if basis >= -s.config.BasisStopUSD {
+			continue
+		}
+		message := fmt.Sprintf("Basis stop on %s: the legs have lost %.2f USD to the price difference between the venues, beyond BASIS_STOP_USD %.2f. Long %s at %.2f (entry %.2f), short %s at %.2f (entry %.2f). Closing both legs.",
+			position.Market, -basis, s.config.BasisStopUSD, position.LongExchange.Name(), longMark, position.LongEntryPrice,
+			position.ShortExchange.Name(), shortMark, position.ShortEntryPrice)
+		s.logger.Printf("WARNING: %s", message)
+		s.notifier.SendMessage("🛑 " + message)
+		notifications.Publish(s.notifier, notifications.Event{Type: notifications.EventRiskAlert, Market: position.Market,
+			Action: "BASIS STOP", Message: message})
+		s.mu.Lock()
+		open := s.positions[position.Market]
+		s.mu.Unlock()
+		if open != nil {
+			s.closeArbitrage(open)
+		}
+	}
+	for market := range previous {
+		if _, held := current[market]; !held {
+			s.metrics.SetBasisPnL(market, 0)
+		}
+	}
+	s.mu.Lock()
+	s.basis = current
+	s.mu.Unlock()
+}
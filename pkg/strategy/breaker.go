@@ -0,0 +1,193 @@
+package strategy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/config"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/exchange"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/notifications"
+)
+
+const (
+	// defaultBreakerFailures is used when CIRCUIT_BREAKER_FAILURES is not set.
+	defaultBreakerFailures = 5
+	// defaultBreakerProbeInterval is used when CIRCUIT_BREAKER_PROBE_SECONDS is not set.
+	defaultBreakerProbeInterval = time.Minute
+)
+
+// circuitBreaker tracks consecutive API failures per exchange. Once an exchange reaches the
+// threshold its breaker opens: no new positions use it, so the strategy doesn't keep opening one
+// leg of a hedge against a venue that can't be relied on for the other. Open breakers are closed
+// again by a successful probe, not by the calls that keep running meanwhile, which may be served
+// from cache.
+type circuitBreaker struct {
+	threshold  int
+	probeEvery time.Duration
+
+	mu     sync.Mutex
+	venues map[string]*venueHealth
+}
+
+type venueHealth struct {
+	failures  int
+	lastErr   error
+	open      bool
+	nextProbe time.Time
+}
+
+func newCircuitBreaker(cfg config.Config) *circuitBreaker {
+	b := &circuitBreaker{
+		threshold:  cfg.CircuitBreakerFailures,
+		probeEvery: time.Duration(cfg.CircuitBreakerProbeSeconds) * time.Second,
+		venues:     make(map[string]*venueHealth),
+	}
+	if b.threshold <= 0 {
+		b.threshold = defaultBreakerFailures
+	}
+	if b.probeEvery <= 0 {
+		b.probeEvery = defaultBreakerProbeInterval
+	}
+	return b
+}
+
+func (b *circuitBreaker) health(name string) *venueHealth {
+	h, ok := b.venues[name]
+	if !ok {
+		h = &venueHealth{}
+		b.venues[name] = h
+	}
+	return h
+}
+
+// record counts the outcome of a call to an exchange and reports whether it opened the breaker.
+// Calls cancelled by the bot itself, e.g. on shutdown, don't count.
+func (b *circuitBreaker) record(name string, err error, now time.Time) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	h := b.health(name)
+	if h.open {
+		return false
+	}
+	if err == nil {
+		h.failures = 0
+		return false
+	}
+	h.failures++
+	h.lastErr = err
+	if h.failures < b.threshold {
+		return false
+	}
+	h.open = true
+	h.nextProbe = now.Add(b.probeEvery)
+	return true
+}
+
+// isOpen reports whether new positions on the exchange are blocked.
+func (b *circuitBreaker) isOpen(name string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	h, ok := b.venues[name]
+	return ok && h.open
+}
+
+// due returns the exchanges with an open breaker whose next probe is due, and schedules the
+// probe after that.
+func (b *circuitBreaker) due(now time.Time) []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var names []string
+	for name, h := range b.venues {
+		if h.open && !now.Before(h.nextProbe) {
+			h.nextProbe = now.Add(b.probeEvery)
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// reset closes the breaker of an exchange.
+func (b *circuitBreaker) reset(name string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	*b.health(name) = venueHealth{}
+}
+
+// openNames returns the exchanges with an open breaker, sorted.
+func (b *circuitBreaker) openNames() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var names []string
+	for name, h := range b.venues {
+		if h.open {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// recordVenueResult feeds the outcome of a call to ex into its circuit breaker, and alerts the
+// operator when it opens.
+func (s *Strategy) recordVenueResult(ex exchange.Exchange, err error) {
+	if !s.breaker.record(ex.Name(), err, time.Now()) {
+		return
+	}
+	message := fmt.Sprintf("Circuit breaker opened for %s after %d consecutive failures, the last: %v. No new positions are opened on %s until it answers again; probing every %s.",
+		ex.Name(), s.breaker.threshold, err, ex.Name(), s.breaker.probeEvery)
+	s.logger.Printf("CRITICAL: %s", message)
+	s.notifier.SendMessage("🔌 " + message)
+	notifications.Publish(s.notifier, notifications.Event{Type: notifications.EventRiskAlert, Exchange: ex.Name(), Message: message, Error: err.Error()})
+}
+
+// probeBreakers checks every exchange whose breaker is open and due for a probe with read-only
+// calls, and closes the breaker of those that answer.
+func (s *Strategy) probeBreakers() {
+	for _, name := range s.breaker.due(time.Now()) {
+		ex := s.exchangeByName(name)
+		if ex == nil {
+			continue
+		}
+		_, err := ex.GetFundingRates(s.ctx)
+		if err == nil {
+			_, err = ex.GetPositions(s.ctx)
+		}
+		if err != nil {
+			s.logger.Printf("%s is still failing, keeping its circuit breaker open: %v", name, err)
+			continue
+		}
+		s.breaker.reset(name)
+		message := fmt.Sprintf("%s is answering again, circuit breaker closed. New positions may use it.", name)
+		s.logger.Println(message)
+		s.notifier.SendMessage("✅ " + message)
+	}
+}
+
+// healthyVenues returns the venues whose circuit breaker is closed, in order.
+func (s *Strategy) healthyVenues(venues []exchange.Exchange) []exchange.Exchange {
+	healthy := make([]exchange.Exchange, 0, len(venues))
+	for _, ex := range venues {
+		if !s.breaker.isOpen(ex.Name()) {
+			healthy = append(healthy, ex)
+		}
+	}
+	return healthy
+}
+
+// describeBreakers lists the exchanges with an open circuit breaker, or returns "" if none.
+func (s *Strategy) describeBreakers() string {
+	names := s.breaker.openNames()
+	if len(names) == 0 {
+		return ""
+	}
+	return "Circuit breaker open, no new positions on: " + strings.Join(names, ", ")
+}
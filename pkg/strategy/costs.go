@@ -0,0 +1,95 @@
+package strategy
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/config"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/exchange"
+)
+
+// defaultTakerFeeBps is the taker fee assumed per fill when TAKER_FEE_BPS is not set.
+const defaultTakerFeeBps = 5
+
+// newExecutionCosts parses EXECUTION_COSTS. Invalid entries are logged and every venue falls back
+// to TAKER_FEE_BPS.
+func newExecutionCosts(cfg config.Config, logger *log.Logger) map[string]exchange.Costs {
+	costs, err := exchange.ParseCosts(cfg.ExecutionCosts)
+	if err != nil {
+		logger.Printf("Ignoring EXECUTION_COSTS: %v", err)
+		return nil
+	}
+	return costs
+}
+
+// venueCosts returns the costs of trading on ex: its EXECUTION_COSTS entry, or else TAKER_FEE_BPS
+// and no gas.
+func (s *Strategy) venueCosts(ex exchange.Exchange) exchange.Costs {
+	if c, ok := s.costs[ex.Name()]; ok {
+		return c
+	}
+	fee := s.config.TakerFeeBps
+	if fee <= 0 {
+		fee = defaultTakerFeeBps
+	}
+	return exchange.Costs{TakerFeeBps: fee}
+}
+
+// fillCosts returns the fees and gas, in USD, of opening and closing sizeUSD on each of the two
+// venues: four fills in all.
+func (s *Strategy) fillCosts(longEx, shortEx exchange.Exchange, sizeUSD float64) float64 {
+	var total float64
+	for _, ex := range []exchange.Exchange{longEx, shortEx} {
+		c := s.venueCosts(ex)
+		total += 2 * (sizeUSD*c.TakerFeeBps/10000 + c.GasUSD)
+	}
+	return total
+}
+
+// estimateRoundTripCost estimates, in USD, the cost of opening and closing sizeUSD on market
+// across the two venues: the fees and gas of the four fills plus the slippage of walking each
+// order book for sizeUSD, taken at the worst level reached and assumed again on exit. Venues that
+// don't serve an order book add no slippage; a book that can't be fetched or is too thin for the
+// size is an error.
+func (s *Strategy) estimateRoundTripCost(market string, longEx, shortEx exchange.Exchange, sizeUSD float64) (float64, error) {
+	legs := []struct {
+		ex   exchange.Exchange
+		side exchange.OrderSide
+	}{{longEx, exchange.Buy}, {shortEx, exchange.Sell}}
+	var impactBps float64
+	for _, leg := range legs {
+		book, err := s.orderbook(leg.ex, market)
+		if errors.Is(err, exchange.ErrOrderbookUnsupported) {
+			continue
+		}
+		if err != nil {
+			return 0, fmt.Errorf("could not estimate the %s slippage on %s: %w", market, leg.ex.Name(), err)
+		}
+		impact, ok := book.ImpactBps(leg.side, sizeUSD)
+		if !ok {
+			return 0, fmt.Errorf("the %s order book on %s is too thin to %s %.2f USD", market, leg.ex.Name(), leg.side, sizeUSD)
+		}
+		impactBps += impact
+	}
+	return s.fillCosts(longEx, shortEx, sizeUSD) + 2*sizeUSD*impactBps/10000, nil
+}
+
+// checkEntryCost makes sure the funding a position is expected to capture over
+// ENTRY_HORIZON_HOURS at rateDiff, the hourly funding rate difference, exceeds its round-trip cost,
+// see estimateRoundTripCost, by ENTRY_COST_MARGIN. A cost that can't be estimated blocks the entry.
+func (s *Strategy) checkEntryCost(market string, longEx, shortEx exchange.Exchange, rateDiff, sizeUSD float64) error {
+	if s.config.EntryHorizonHours <= 0 {
+		return nil
+	}
+	cost, err := s.estimateRoundTripCost(market, longEx, shortEx, sizeUSD)
+	if err != nil {
+		return err
+	}
+	capture := rateDiff * s.config.EntryHorizonHours * sizeUSD
+	if required := cost * (1 + s.config.EntryCostMargin); capture < required {
+		return fmt.Errorf("expected funding of %.2f USD over %g hours doesn't cover the round-trip cost of %.2f USD with a %g%% margin (%.2f USD)",
+			capture, s.config.EntryHorizonHours, cost, s.config.EntryCostMargin*100, required)
+	}
+	return nil
+}
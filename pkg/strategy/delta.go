@@ -0,0 +1,229 @@
+package strategy
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/exchange"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/notifications"
+)
+
+// netDeltaCheckInterval is how often the net delta of every market is measured against
+// NET_DELTA_ALERT_USD.
+const netDeltaCheckInterval = time.Minute
+
+// marketDelta is the net position on a market summed over the positions the exchanges report.
+type marketDelta struct {
+	Market string
+	// Net is the base amount held long less the amount held short, and USD its value at the mark
+	// price.
+	Net float64
+	USD float64
+	// legs holds the position on the market of each exchange holding one, by exchange name.
+	legs map[string]exchange.Position
+}
+
+// netDeltas measures the net position of every market held on any exchange, ordered by market.
+// Exchanges that can't report their positions and markets without a mark price are skipped.
+func (s *Strategy) netDeltas() []marketDelta {
+	deltas := make(map[string]*marketDelta)
+	priced := make(map[string]exchange.Exchange)
+	for _, ex := range s.exchanges {
+		positions, err := ex.GetPositions(s.ctx)
+		if err != nil {
+			s.logger.Printf("Could not check the net delta on %s: %v", ex.Name(), err)
+			continue
+		}
+		for _, p := range positions {
+			d, ok := deltas[p.Market]
+			if !ok {
+				d = &marketDelta{Market: p.Market, legs: make(map[string]exchange.Position)}
+				deltas[p.Market] = d
+				priced[p.Market] = ex
+			}
+			size := p.Size
+			if p.Side == exchange.Sell {
+				size = -size
+			}
+			d.Net += size
+			d.legs[ex.Name()] = p
+		}
+	}
+
+	markets := make([]string, 0, len(deltas))
+	for market := range deltas {
+		markets = append(markets, market)
+	}
+	sort.Strings(markets)
+	result := make([]marketDelta, 0, len(markets))
+	for _, market := range markets {
+		price, err := s.marketData.MarkPrice(s.ctx, priced[market], market)
+		if err != nil {
+			s.logger.Printf("Could not value the net delta of %s: %v", market, err)
+			continue
+		}
+		d := deltas[market]
+		d.USD = d.Net * price
+		result = append(result, *d)
+	}
+	return result
+}
+
+// checkNetDelta records the net position of every market and alerts the operator when one
+// exceeds NET_DELTA_ALERT_USD, which happens when a leg was liquidated, partially closed or
+// auto-deleveraged by its venue. With NET_DELTA_REBALANCE the larger leg is trimmed to match the
+// other, once the next check confirms the breach. Each breach is alerted and rebalanced once,
+// until the market is back within the limit.
+func (s *Strategy) checkNetDelta() {
+	deltas := s.netDeltas()
+	s.mu.Lock()
+	previous, alerted := s.netDelta, s.deltaAlerts
+	s.mu.Unlock()
+
+	current := make(map[string]float64, len(deltas))
+	breached := make(map[string]bool)
+	for _, d := range deltas {
+		current[d.Market] = d.USD
+		s.metrics.SetNetDelta(d.Market, d.USD)
+		if math.Abs(d.USD) <= s.config.NetDeltaAlertUSD {
+			continue
+		}
+		if alerted[d.Market] {
+			breached[d.Market] = true
+			continue
+		}
+		last, seen := previous[d.Market]
+		if s.config.NetDeltaRebalance && (!seen || math.Abs(last) <= s.config.NetDeltaAlertUSD || (last > 0) != (d.USD > 0)) {
+			// A venue may briefly list no positions, or stale ones, so a breach is only
+			// rebalanced once it is confirmed by the next check.
+			s.logger.Printf("Net delta on %s is %+.2f USD, checking again before rebalancing.", d.Market, d.USD)
+			continue
+		}
+		breached[d.Market] = true
+		s.alertNetDelta(d)
+	}
+	for market := range previous {
+		if _, held := current[market]; !held {
+			s.metrics.SetNetDelta(market, 0)
+		}
+	}
+	s.mu.Lock()
+	s.netDelta, s.deltaAlerts = current, breached
+	s.mu.Unlock()
+}
+
+// describeNetDelta lists the markets left with a net position by the last net delta check, or
+// returns "" when every market is hedged. The caller must hold s.mu.
+func (s *Strategy) describeNetDelta() string {
+	markets := make([]string, 0, len(s.netDelta))
+	for market, usd := range s.netDelta {
+		if math.Abs(usd) >= 0.01 {
+			markets = append(markets, market)
+		}
+	}
+	if len(markets) == 0 {
+		return ""
+	}
+	sort.Strings(markets)
+	for i, market := range markets {
+		markets[i] = fmt.Sprintf("%s %+.2f USD", market, s.netDelta[market])
+	}
+	return "Net delta: " + strings.Join(markets, ", ")
+}
+
+// alertNetDelta reports a market whose net delta exceeds NET_DELTA_ALERT_USD and, with
+// NET_DELTA_REBALANCE, rebalances it.
+func (s *Strategy) alertNetDelta(d marketDelta) {
+	names := make([]string, 0, len(d.legs))
+	for name := range d.legs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	legs := make([]string, 0, len(names))
+	for _, name := range names {
+		leg := d.legs[name]
+		legs = append(legs, fmt.Sprintf("%s %f on %s", leg.Side, leg.Size, name))
+	}
+	message := fmt.Sprintf("Net delta on %s is %+.6f (%+.2f USD), beyond NET_DELTA_ALERT_USD %.2f: %s.", d.Market, d.Net, d.USD, s.config.NetDeltaAlertUSD, strings.Join(legs, ", "))
+	if s.config.NetDeltaRebalance {
+		action, err := s.rebalanceNetDelta(d)
+		if err != nil {
+			message += fmt.Sprintf(" Could not rebalance: %v.", err)
+		} else {
+			message += " " + action
+		}
+	} else {
+		message += " Set NET_DELTA_REBALANCE=true to rebalance automatically, or check the positions on the venues."
+	}
+	s.logger.Printf("WARNING: %s", message)
+	s.notifier.SendMessage("⚖️ " + message)
+	notifications.Publish(s.notifier, notifications.Event{Type: notifications.EventRiskAlert, Market: d.Market, Message: message})
+}
+
+// rebalanceNetDelta trims the larger leg of the position on d.Market to the size of the other, so
+// the legs hedge each other again, and shrinks the recorded position to match. A leg whose hedge
+// is gone is closed, and the position forgotten. It returns what was done. Deltas that aren't
+// between the legs of a recorded position are left to the operator, since they may have been
+// opened by hand.
+func (s *Strategy) rebalanceNetDelta(d marketDelta) (string, error) {
+	s.mu.Lock()
+	open, ok := s.positions[d.Market]
+	var position PositionInfo
+	if ok {
+		position = *open
+	}
+	s.mu.Unlock()
+	if !ok {
+		return "", errors.New("no position on it was opened by the bot")
+	}
+	var longSize, shortSize float64
+	if leg, ok := d.legs[position.LongExchange.Name()]; ok && leg.Side == exchange.Buy {
+		longSize = leg.Size
+	}
+	if leg, ok := d.legs[position.ShortExchange.Name()]; ok && leg.Side == exchange.Sell {
+		shortSize = leg.Size
+	}
+	excess := longSize - shortSize
+	if excess == 0 || (excess > 0) != (d.Net > 0) {
+		return "", fmt.Errorf("the delta is not between the legs on %s and %s", position.LongExchange.Name(), position.ShortExchange.Name())
+	}
+	ex, side := position.LongExchange, exchange.Buy
+	if excess < 0 {
+		ex, side, excess = position.ShortExchange, exchange.Sell, -excess
+	}
+	order, err := ex.ClosePosition(s.unwindContext(), d.Market, side, decimal.NewFromFloat(excess))
+	s.recordOrder(ex, d.Market, oppositeSide(side), exchange.Market, decimal.NewFromFloat(excess), 0, order, err)
+	if err != nil {
+		return "", fmt.Errorf("trimming the %s leg on %s: %w", side, ex.Name(), err)
+	}
+
+	// The position may have been closed or resized while the order was out.
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.positions[d.Market] != open {
+		return fmt.Sprintf("Trimmed the %s leg on %s by %f; the position changed meanwhile and was left as it is.", side, ex.Name(), excess), nil
+	}
+	remaining := math.Min(longSize, shortSize)
+	if remaining <= 0 {
+		s.forgetPosition(open)
+		s.persistPositions()
+		return fmt.Sprintf("Closed the %s leg of %f on %s, whose hedge is gone.", side, excess, ex.Name()), nil
+	}
+	recorded := open.Amount.InexactFloat64()
+	if recorded <= 0 {
+		recorded = math.Max(longSize, shortSize)
+	}
+	if remaining < recorded {
+		sizeUSD := open.SizeUSD * remaining / recorded
+		s.capital.Release(DefaultName, open.SizeUSD-sizeUSD)
+		open.SizeUSD, open.Amount = sizeUSD, decimal.NewFromFloat(remaining)
+	}
+	s.persistPositions()
+	return fmt.Sprintf("Trimmed the %s leg on %s by %f, the position now holds %f on each leg (%.2f USD).", side, ex.Name(), excess, remaining, open.SizeUSD), nil
+}
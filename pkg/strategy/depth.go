@@ -0,0 +1,52 @@
+package strategy
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/exchange"
+)
+
+// checkDepth walks the order book of each leg to make sure sizeUSD can be filled within
+// MAX_ENTRY_IMPACT_BPS of the top of the book: the long leg buys through the asks and the short
+// leg sells through the bids. A book that can't be fetched blocks the entry, since the slippage
+// can't be bounded; venues that don't serve one aren't checked.
+func (s *Strategy) checkDepth(market string, longEx, shortEx exchange.Exchange, sizeUSD float64) error {
+	if s.config.MaxEntryImpactBps <= 0 {
+		return nil
+	}
+	legs := []struct {
+		ex   exchange.Exchange
+		side exchange.OrderSide
+	}{{longEx, exchange.Buy}, {shortEx, exchange.Sell}}
+	for _, leg := range legs {
+		book, err := s.orderbook(leg.ex, market)
+		if errors.Is(err, exchange.ErrOrderbookUnsupported) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("could not check the %s order book depth on %s: %w", market, leg.ex.Name(), err)
+		}
+		impact, ok := book.ImpactBps(leg.side, sizeUSD)
+		if !ok {
+			return fmt.Errorf("the %s order book on %s is too thin to %s %.2f USD", market, leg.ex.Name(), leg.side, sizeUSD)
+		}
+		if impact > s.config.MaxEntryImpactBps {
+			return fmt.Errorf("filling %.2f USD of %s on %s would move the price %.1f bps, above %.1f bps", sizeUSD, market, leg.ex.Name(), impact, s.config.MaxEntryImpactBps)
+		}
+	}
+	return nil
+}
+
+// orderbook fetches the order book of market on ex and records its top in the market data cache,
+// where decisionPrice takes the mid from.
+func (s *Strategy) orderbook(ex exchange.Exchange, market string) (*exchange.Orderbook, error) {
+	book, err := ex.GetOrderbook(s.ctx, market)
+	if err != nil {
+		return nil, err
+	}
+	if len(book.Bids) > 0 && len(book.Asks) > 0 {
+		s.marketData.StoreOrderbookTop(ex.Name(), market, book.Bids[0].Price, book.Asks[0].Price)
+	}
+	return book, nil
+}
@@ -0,0 +1,227 @@
+package strategy
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/config"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/exchange"
+)
+
+const (
+	// defaultCheckInterval is how often rates are polled when nothing else triggers a check, unless
+	// CHECK_INTERVAL_SECONDS is set.
+	defaultCheckInterval = 1 * time.Minute
+	// minPolledCheckInterval is the shortest CHECK_INTERVAL_SECONDS without STREAMING: every check
+	// polls every exchange, so checking faster would run into their rate limits.
+	minPolledCheckInterval = 10 * time.Second
+	// fastCheckInterval is used when a funding payment is imminent.
+	fastCheckInterval = 10 * time.Second
+	// fundingApproachWindow is how close to a funding timestamp the loop switches to fastCheckInterval.
+	fundingApproachWindow = 5 * time.Minute
+	// eventBufferSize is the capacity of each event channel.
+	eventBufferSize = 64
+	// rateCheckInterval is the shortest time between checks triggered by pushed rates. Rates pushed
+	// in between are stored and evaluated together by the next check.
+	rateCheckInterval = 2 * time.Second
+)
+
+// CommandName identifies an operator command.
+type CommandName string
+
+const (
+	CommandCheck  CommandName = "check"
+	CommandPause  CommandName = "pause"
+	CommandResume CommandName = "resume"
+	CommandClose  CommandName = "close"
+	// CommandFlatten closes every position. While shutting down with SHUTDOWN_POLICY ask-telegram,
+	// it and CommandPreserve answer whether to close the positions before exiting.
+	CommandFlatten  CommandName = "flatten"
+	CommandPreserve CommandName = "preserve"
+)
+
+// Command is an operator instruction delivered to the strategy loop.
+// If Reply is set, the result of the command is sent on it.
+type Command struct {
+	Name   CommandName
+	Market string
+	Reply  chan error
+}
+
+// RateUpdate carries funding rates pushed by an exchange (e.g. from a stream).
+type RateUpdate struct {
+	Exchange string
+	Rates    []*exchange.FundingRate
+}
+
+// events groups the channels that feed the strategy loop.
+type events struct {
+	commands chan Command
+	fills    chan *exchange.Order
+	rates    chan RateUpdate
+}
+
+func newEvents() events {
+	return events{
+		commands: make(chan Command, eventBufferSize),
+		fills:    make(chan *exchange.Order, eventBufferSize),
+		rates:    make(chan RateUpdate, eventBufferSize),
+	}
+}
+
+// SubmitCommand queues an operator command for the strategy loop. It never blocks: when the queue
+// is full the command is dropped and ErrCommandQueueFull is sent on its Reply.
+func (s *Strategy) SubmitCommand(cmd Command) {
+	select {
+	case s.events.commands <- cmd:
+	default:
+		s.logger.Printf("Dropping command %s: %v", cmd.Name, ErrCommandQueueFull)
+		if cmd.Reply != nil {
+			select {
+			case cmd.Reply <- ErrCommandQueueFull:
+			default:
+			}
+		}
+	}
+}
+
+// PublishFill notifies the strategy loop of an order fill. It never blocks: fills are only logged,
+// so those arriving while the queue is full are dropped.
+func (s *Strategy) PublishFill(order *exchange.Order) {
+	select {
+	case s.events.fills <- order:
+	default:
+		s.logger.Printf("Dropping fill of order %s on %s: the event queue is full.", order.ID, order.Market)
+	}
+}
+
+// PublishRates notifies the strategy loop of new funding rates for an exchange. It never blocks:
+// while the queue is full the rates go straight to the market data cache, where the check of the
+// updates already queued reads them.
+func (s *Strategy) PublishRates(exchangeName string, rates []*exchange.FundingRate) {
+	select {
+	case s.events.rates <- RateUpdate{Exchange: exchangeName, Rates: rates}:
+	default:
+		s.marketData.StoreFundingRates(exchangeName, rates)
+	}
+}
+
+// handleCommand executes an operator command.
+func (s *Strategy) handleCommand(cmd Command) {
+	var err error
+	switch cmd.Name {
+	case CommandCheck:
+		s.checkFundingRates()
+	case CommandPause:
+		s.setPaused(true)
+		s.logger.Println("Strategy paused: no new positions will be opened.")
+	case CommandResume:
+		s.setPaused(false)
+		s.logger.Println("Strategy resumed.")
+	case CommandClose:
+		s.mu.Lock()
+		position, exists := s.positions[cmd.Market]
+		s.mu.Unlock()
+		if !exists {
+			err = fmt.Errorf("no open position for market %s", cmd.Market)
+			break
+		}
+		s.logger.Printf("Closing position for %s on operator request.", cmd.Market)
+		s.closeArbitrage(position)
+	case CommandFlatten:
+		err = s.flattenAll()
+	case CommandPreserve:
+		err = fmt.Errorf("the bot isn't shutting down, positions are kept open while it runs")
+	default:
+		err = fmt.Errorf("unknown command %q", cmd.Name)
+	}
+
+	if err != nil {
+		s.logger.Printf("Command %s failed: %v", cmd.Name, err)
+	}
+	if cmd.Reply != nil {
+		cmd.Reply <- err
+	}
+}
+
+// handleFill records an order fill reported by an exchange.
+func (s *Strategy) handleFill(order *exchange.Order) {
+	s.logger.Printf("Fill received: %s %s %s of %s (order %s, status %s)",
+		order.Side, order.Type, order.Filled, order.Market, order.ID, order.Status)
+}
+
+// handleRateUpdate stores pushed rates. The loop re-evaluates opportunities with them after
+// rateCheckDelay.
+func (s *Strategy) handleRateUpdate(update RateUpdate) {
+	s.marketData.StoreFundingRates(update.Exchange, update.Rates)
+}
+
+// rateCheckDelay returns how long to wait before checking pushed rates, given the last check: none
+// if it ran rateCheckInterval ago, so a burst of updates costs one check rather than one each.
+func rateCheckDelay(lastCheck, now time.Time) time.Duration {
+	if delay := lastCheck.Add(rateCheckInterval).Sub(now); delay > 0 {
+		return delay
+	}
+	return 0
+}
+
+// checkInterval returns how often rates are checked when nothing else triggers a check:
+// CHECK_INTERVAL_SECONDS, or defaultCheckInterval. Without STREAMING it is at least
+// minPolledCheckInterval.
+func checkInterval(cfg config.Config) time.Duration {
+	if cfg.CheckIntervalSeconds <= 0 {
+		return defaultCheckInterval
+	}
+	interval := time.Duration(cfg.CheckIntervalSeconds * float64(time.Second))
+	if !cfg.Streaming && interval < minPolledCheckInterval {
+		return minPolledCheckInterval
+	}
+	return interval
+}
+
+// checkJitter returns a random delay of up to CHECK_JITTER_SECONDS, added to each check so
+// instances started together don't poll the venues in lockstep.
+func checkJitter(cfg config.Config) time.Duration {
+	jitter := time.Duration(cfg.CheckJitterSeconds * float64(time.Second))
+	if jitter <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(jitter) + 1))
+}
+
+// firstCheckDelay returns how long to wait before the first timer-driven check: none with
+// CHECK_ON_START, otherwise a full nextCheckDelay.
+func (s *Strategy) firstCheckDelay() time.Duration {
+	if s.config.CheckOnStart {
+		return 0
+	}
+	return s.nextCheckDelay()
+}
+
+// nextCheckDelay returns how long to wait before the next timer-driven check, plus the jitter.
+// The loop polls faster when a funding payment on any tracked market is close,
+// and wakes up early enough to enter the approach window on time.
+func (s *Strategy) nextCheckDelay() time.Duration {
+	return s.scheduledCheckDelay(time.Now()) + checkJitter(s.config)
+}
+
+func (s *Strategy) scheduledCheckDelay(now time.Time) time.Duration {
+	interval := checkInterval(s.config)
+	fast := fastCheckInterval
+	if interval < fast {
+		fast = interval
+	}
+	next := s.calendar.Soonest(s.exchangeNames(), s.config.Markets, now)
+	if next.IsZero() {
+		return interval
+	}
+	until := next.Sub(now)
+	if until <= fundingApproachWindow {
+		return fast
+	}
+	if wake := until - fundingApproachWindow; wake < interval {
+		return wake
+	}
+	return interval
+}
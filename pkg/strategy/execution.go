@@ -0,0 +1,141 @@
+package strategy
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/exchange"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/journal"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/report"
+)
+
+// executionLog keeps the fills made since the last execution report.
+type executionLog struct {
+	mu    sync.Mutex
+	fills []journal.Entry
+}
+
+// add appends a fill to the log.
+func (l *executionLog) add(e journal.Entry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.fills = append(l.fills, e)
+}
+
+// drain returns the logged fills and empties the log.
+func (l *executionLog) drain() []journal.Entry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	fills := l.fills
+	l.fills = nil
+	return fills
+}
+
+// decisionPrice returns the price the strategy expects to trade market at on ex: the mid of the
+// cached order book when one is known, otherwise the venue's mark price, batched per venue by
+// priceMarkets during a check, otherwise fallback.
+func (s *Strategy) decisionPrice(ex exchange.Exchange, market string, fallback float64) float64 {
+	if snapshot, ok := s.marketData.Get(ex.Name(), market); ok && snapshot.BestBid > 0 && snapshot.BestAsk > 0 &&
+		time.Since(snapshot.BookUpdated) <= s.marketData.TTL() {
+		return (snapshot.BestBid + snapshot.BestAsk) / 2
+	}
+	if price, err := s.marketData.MarkPrice(s.ctx, ex, market); err == nil && price > 0 {
+		return price
+	}
+	return fallback
+}
+
+// recordFill appends an executed order to the trade journal and the execution log. decisionPrice
+// is the price expected when the order was submitted and latency the time the venue took to
+// acknowledge it.
+func (s *Strategy) recordFill(ex exchange.Exchange, market string, side exchange.OrderSide, amount decimal.Decimal, decisionPrice float64, latency time.Duration, order *exchange.Order) {
+	entry := journal.Entry{
+		Time:          time.Now().UTC(),
+		Type:          journal.EntryFill,
+		Exchange:      ex.Name(),
+		Market:        market,
+		Side:          string(side),
+		Amount:        amount.InexactFloat64(),
+		Price:         decisionPrice,
+		DecisionPrice: decisionPrice,
+		LatencyMs:     latency.Milliseconds(),
+	}
+	if order != nil {
+		entry.OrderID = order.ID
+		entry.Fee = order.Fee
+		if order.Price.IsPositive() {
+			entry.Price = order.Price.InexactFloat64()
+		}
+	}
+	s.executions.add(entry)
+	s.activity.addFee(entry.Fee)
+	if err := s.journal.Record(entry); err != nil {
+		s.logger.Printf("Failed to record %s fill on %s to the journal: %v", market, ex.Name(), err)
+	}
+}
+
+// recordOrder journals an order submitted to ex and counts it in the metrics, whether or not the
+// venue accepted it. Rejected orders are journaled as errors too.
+func (s *Strategy) recordOrder(ex exchange.Exchange, market string, side exchange.OrderSide, orderType exchange.OrderType, amount decimal.Decimal, price float64, order *exchange.Order, err error) {
+	s.metrics.OrderResult(ex.Name(), err)
+	s.recordVenueResult(ex, err)
+	entry := journal.Entry{
+		Time:     time.Now().UTC(),
+		Type:     journal.EntryOrder,
+		Exchange: ex.Name(),
+		Market:   market,
+		Side:     string(side),
+		Amount:   amount.InexactFloat64(),
+		Price:    price,
+		Message:  string(orderType),
+	}
+	if order != nil {
+		entry.OrderID = order.ID
+	}
+	if jerr := s.journal.Record(entry); jerr != nil {
+		s.logger.Printf("Failed to record %s order on %s to the journal: %v", market, ex.Name(), jerr)
+	}
+	if err != nil {
+		s.recordError(ex.Name(), market, fmt.Sprintf("%s %s order for %s failed: %v", orderType, side, amount, err))
+	}
+}
+
+// recordError journals an error that affects trading on market.
+func (s *Strategy) recordError(exchangeName, market, message string) {
+	s.activity.addError(exchangeName, market, message)
+	if err := s.journal.Record(journal.Entry{Type: journal.EntryError, Exchange: exchangeName, Market: market, Message: message}); err != nil {
+		s.logger.Printf("Failed to record %s error to the journal: %v", market, err)
+	}
+}
+
+// recordClose journals that position was closed. Each leg's closing order is journaled separately.
+func (s *Strategy) recordClose(position *PositionInfo, amount decimal.Decimal, longErr, shortErr error) {
+	message := fmt.Sprintf("long %s / short %s, %.2f USD held %s", position.LongExchange.Name(), position.ShortExchange.Name(),
+		position.SizeUSD, time.Since(position.OpenedAt).Round(time.Second))
+	if longErr != nil || shortErr != nil {
+		message += ", closing a leg failed"
+	}
+	if err := s.journal.Record(journal.Entry{Type: journal.EntryClose, Market: position.Market, Amount: amount.InexactFloat64(), Message: message}); err != nil {
+		s.logger.Printf("Failed to record the %s close to the journal: %v", position.Market, err)
+	}
+}
+
+// reportExecution logs and sends the slippage and latency of the fills made since the last
+// report.
+func (s *Strategy) reportExecution() {
+	fills := s.executions.drain()
+	if len(fills) == 0 {
+		return
+	}
+	summary := report.FormatExecution(report.BuildExecutionReport(fills, time.Time{}, time.Time{}))
+	s.logger.Printf("Execution quality:\n%s", summary)
+	s.notifier.SendMessage(fmt.Sprintf("📊 Execution quality (%d fills)\n%s", len(fills), summary))
+}
+
+// executionReportInterval is how often reportExecution runs, or zero when disabled.
+func (s *Strategy) executionReportInterval() time.Duration {
+	return time.Duration(s.config.ExecutionReportHours * float64(time.Hour))
+}
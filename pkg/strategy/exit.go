@@ -0,0 +1,67 @@
+package strategy
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/exchange"
+)
+
+// exitReason returns why position should be closed, given the hourly funding rate difference
+// between its venues, or "" to keep it. A position is closed when the difference falls EXIT_HYSTERESIS
+// past the exit level: EXIT_MIN_ANNUAL_DIFF annualized, zero by default, i.e. once the spread
+// flattens or inverts. Within MIN_HOLD_MINUTES of opening, the spread alone never closes it, so a
+// noisy rate print doesn't cost a round trip. It is also closed when its funding has been
+// collected with EXIT_AFTER_FUNDING, or when the funding received covers TAKE_PROFIT_MULTIPLE
+// times its estimated round-trip cost.
+func (s *Strategy) exitReason(position *PositionInfo, diff float64, now time.Time) string {
+	held := now.Sub(position.OpenedAt)
+	if annual := exchange.Annualize(diff+s.config.ExitHysteresis, time.Hour); annual <= s.config.ExitMinAnnualDiff {
+		switch {
+		case held < s.minHold():
+			s.logger.Printf("Funding rate difference for %s reached the exit level, but the position is held for at least %s (open %s).", position.Market, s.minHold(), held.Round(time.Second))
+		case s.config.ExitMinAnnualDiff == 0 && s.config.ExitHysteresis == 0:
+			return fmt.Sprintf("Funding rate difference for %s is no longer favorable.", position.Market)
+		default:
+			return fmt.Sprintf("Funding rate difference for %s is %.2f%% a year, past the exit level of %.2f%% (EXIT_MIN_ANNUAL_DIFF) less %.6f an hour (EXIT_HYSTERESIS).",
+				position.Market, exchange.Annualize(diff, time.Hour)*100, s.config.ExitMinAnnualDiff*100, s.config.ExitHysteresis)
+		}
+	}
+	if s.fundingCollected(position, now) {
+		return fmt.Sprintf("Funding on %s has been collected.", position.Market)
+	}
+	if s.config.TakeProfitMultiple > 0 {
+		s.mu.Lock()
+		funding := position.Funding
+		s.mu.Unlock()
+		if cost := s.roundTripCost(position); funding >= s.config.TakeProfitMultiple*cost {
+			return fmt.Sprintf("Funding received on %s, %.2f USD, covers %gx the estimated round-trip cost of %.2f USD.", position.Market, funding, s.config.TakeProfitMultiple, cost)
+		}
+	}
+	return ""
+}
+
+// minHold is how long a position is kept regardless of its spread.
+func (s *Strategy) minHold() time.Duration {
+	return time.Duration(s.config.MinHoldMinutes * float64(time.Minute))
+}
+
+// cooldownRemaining returns how much longer a new position on market must wait after the last one
+// was closed, so a spread hovering around the entry threshold doesn't close and reopen it on
+// consecutive checks, paying fees each time.
+func (s *Strategy) cooldownRemaining(market string, now time.Time) time.Duration {
+	cooldown := time.Duration(s.config.ReentryCooldownMinutes * float64(time.Minute))
+	s.mu.Lock()
+	closed, ok := s.closedAt[market]
+	s.mu.Unlock()
+	if !ok || cooldown <= 0 {
+		return 0
+	}
+	return closed.Add(cooldown).Sub(now)
+}
+
+// roundTripCost estimates what opening and closing position costs in USD: the fees and gas of the
+// four fills, plus the slippage measured on entry, assumed again on exit.
+func (s *Strategy) roundTripCost(position *PositionInfo) float64 {
+	return s.fillCosts(position.LongExchange, position.ShortExchange, position.SizeUSD) + position.SizeUSD*2*position.EntrySlippage
+}
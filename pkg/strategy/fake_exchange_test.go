@@ -0,0 +1,191 @@
+package strategy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/exchange"
+)
+
+// fakeExchange is an in-memory exchange that records orders and can be told to fail.
+type fakeExchange struct {
+	name  string
+	rates []*exchange.FundingRate
+	// ratesErr fails GetFundingRates.
+	ratesErr error
+	// ratesDelay holds GetFundingRates back, or until the request is cancelled.
+	ratesDelay time.Duration
+	stats      map[string]exchange.MarketStats
+	// book is what GetOrderbook reports, whatever the market; nil means no order book.
+	book    *exchange.Orderbook
+	balance float64
+	price   float64
+	// held is what GetPositions reports.
+	held []exchange.Position
+	// payments is what GetFundingPayments reports, whatever the market.
+	payments []exchange.FundingPayment
+	// forced is what GetForcedCloses reports, whatever the market.
+	forced []exchange.ForcedClose
+	// risk is what GetPositionRisk reports, whatever the market; nil means it isn't reported.
+	risk *exchange.PositionRisk
+	// interval is the funding interval; zero means the default.
+	interval time.Duration
+
+	mu       sync.Mutex
+	orders   []exchange.Order
+	closes   []exchange.OrderSide
+	placeErr error
+	closeErr error
+	// closeFailures fails that many ClosePosition calls before they succeed.
+	closeFailures int
+	// partialFills fills that many orders only halfway.
+	partialFills int
+}
+
+func newFakeExchange(name string) *fakeExchange {
+	return &fakeExchange{name: name, balance: 1e6, price: 60000}
+}
+
+func (f *fakeExchange) Name() string    { return f.name }
+func (f *fakeExchange) SetTestnet(bool) {}
+
+func (f *fakeExchange) FundingInterval() time.Duration { return f.interval }
+
+func (f *fakeExchange) GetFundingRates(ctx context.Context) ([]*exchange.FundingRate, error) {
+	if f.ratesErr != nil {
+		return nil, f.ratesErr
+	}
+	if f.ratesDelay > 0 {
+		select {
+		case <-time.After(f.ratesDelay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return f.rates, nil
+}
+
+func (f *fakeExchange) GetMarketStats(context.Context, []string) (map[string]exchange.MarketStats, error) {
+	return f.stats, nil
+}
+
+func (f *fakeExchange) GetOrderbook(context.Context, string) (*exchange.Orderbook, error) {
+	if f.book == nil {
+		return nil, exchange.ErrOrderbookUnsupported
+	}
+	return f.book, nil
+}
+
+func (f *fakeExchange) GetMarkPrice(context.Context, string) (float64, error) {
+	if f.price <= 0 {
+		return 0, errors.New("no price")
+	}
+	return f.price, nil
+}
+
+func (f *fakeExchange) PlaceOrder(ctx context.Context, market string, side exchange.OrderSide, orderType exchange.OrderType, amount, price decimal.Decimal) (*exchange.Order, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.placeErr != nil {
+		return nil, f.placeErr
+	}
+	order := exchange.Order{
+		ID:     fmt.Sprintf("%s-%d", f.name, len(f.orders)+1),
+		Market: market,
+		Side:   side,
+		Type:   orderType,
+		Price:  price,
+		Amount: amount,
+		Filled: amount,
+		Status: "FILLED",
+	}
+	if f.partialFills > 0 {
+		f.partialFills--
+		order.Filled, order.Status = amount.Div(decimal.NewFromInt(2)), "PARTIALLY_FILLED"
+	}
+	f.orders = append(f.orders, order)
+	return &order, nil
+}
+
+func (f *fakeExchange) GetOrderStatus(ctx context.Context, orderID string, market string) (*exchange.Order, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i := range f.orders {
+		if f.orders[i].ID == orderID {
+			order := f.orders[i]
+			return &order, nil
+		}
+	}
+	return nil, fmt.Errorf("order %s not found", orderID)
+}
+
+func (f *fakeExchange) CancelOrder(context.Context, string, string) error { return nil }
+
+func (f *fakeExchange) GetBalance(context.Context, string) (float64, error) { return f.balance, nil }
+
+func (f *fakeExchange) GetPositions(ctx context.Context) ([]exchange.Position, error) {
+	return f.held, nil
+}
+
+func (f *fakeExchange) GetFundingPayments(ctx context.Context, market string, since time.Time) ([]exchange.FundingPayment, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var payments []exchange.FundingPayment
+	for _, p := range f.payments {
+		if !p.Time.Before(since) {
+			payments = append(payments, p)
+		}
+	}
+	return payments, nil
+}
+
+func (f *fakeExchange) GetForcedCloses(ctx context.Context, market string, since time.Time) ([]exchange.ForcedClose, error) {
+	var closes []exchange.ForcedClose
+	for _, c := range f.forced {
+		if !c.Time.Before(since) {
+			closes = append(closes, c)
+		}
+	}
+	return closes, nil
+}
+
+func (f *fakeExchange) GetPositionRisk(ctx context.Context, market string) (*exchange.PositionRisk, error) {
+	if f.risk == nil {
+		return nil, exchange.ErrPositionRiskUnsupported
+	}
+	return f.risk, nil
+}
+
+func (f *fakeExchange) ClosePosition(ctx context.Context, market string, side exchange.OrderSide, amount decimal.Decimal) (*exchange.Order, error) {
+	f.mu.Lock()
+	if f.closeErr != nil {
+		f.mu.Unlock()
+		return nil, f.closeErr
+	}
+	if f.closeFailures > 0 {
+		f.closeFailures--
+		f.mu.Unlock()
+		return nil, errors.New("API error: 502 Bad Gateway")
+	}
+	f.closes = append(f.closes, side)
+	f.mu.Unlock()
+	closeSide := exchange.Sell
+	if side == exchange.Sell {
+		closeSide = exchange.Buy
+	}
+	return f.PlaceOrder(ctx, market, closeSide, exchange.Market, amount, decimal.Zero)
+}
+
+func (f *fakeExchange) orderCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.orders)
+}
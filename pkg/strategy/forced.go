@@ -0,0 +1,262 @@
+package strategy
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/exchange"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/journal"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/notifications"
+)
+
+const (
+	// forcedCloseCheckInterval is how often the legs of open positions are checked for
+	// liquidations and auto-deleveraging.
+	forcedCloseCheckInterval = time.Minute
+	// forcedCloseLookback is how far before the previous check the trade history is searched
+	// again, for forced fills the venue reports late.
+	forcedCloseLookback = 5 * time.Minute
+)
+
+// forcedLeg is a leg of an open position that its exchange liquidated or auto-deleveraged.
+type forcedLeg struct {
+	exchange exchange.Exchange
+	// side is the side of the leg, Buy for the long one.
+	side exchange.OrderSide
+	// reason says how the forced close was detected, for the journal and the alert.
+	reason string
+	// fills are the exchange's forced fills, when its trade history reports them.
+	fills []exchange.ForcedClose
+}
+
+// heldPositions fetches the positions of each exchange once per check, by market. It reports
+// false for exchanges whose positions can't be fetched.
+type heldPositions struct {
+	s      *Strategy
+	byName map[string]map[string]exchange.Position
+}
+
+func (h *heldPositions) on(ex exchange.Exchange) (map[string]exchange.Position, bool) {
+	if held, ok := h.byName[ex.Name()]; ok {
+		return held, held != nil
+	}
+	positions, err := ex.GetPositions(h.s.ctx)
+	if err != nil {
+		h.s.logger.Printf("Could not check %s for forced closes: %v", ex.Name(), err)
+		h.byName[ex.Name()] = nil
+		return nil, false
+	}
+	held := make(map[string]exchange.Position, len(positions))
+	for _, p := range positions {
+		held[p.Market] = p
+	}
+	h.byName[ex.Name()] = held
+	return held, true
+}
+
+// size returns the size of the side position on market held on ex, and false when ex can't
+// report its positions.
+func (h *heldPositions) size(ex exchange.Exchange, market string, side exchange.OrderSide) (float64, bool) {
+	held, ok := h.on(ex)
+	if !ok {
+		return 0, false
+	}
+	if p, open := held[market]; open && p.Side == side {
+		return p.Size, true
+	}
+	return 0, true
+}
+
+// checkForcedCloses looks for legs of the open positions that their exchange liquidated or
+// auto-deleveraged: in the trade history of the venues that mark forced fills, and in the
+// positions every venue reports, where a leg gone on two checks in a row while its hedge is still
+// open was closed by the venue. The position is then force-closed.
+func (s *Strategy) checkForcedCloses() {
+	s.mu.Lock()
+	positions := make([]*PositionInfo, 0, len(s.positions))
+	for _, position := range s.positions {
+		positions = append(positions, position)
+	}
+	checkedAt, missing := s.forcedCheckedAt, s.legsMissing
+	s.mu.Unlock()
+
+	now := time.Now()
+	held := &heldPositions{s: s, byName: make(map[string]map[string]exchange.Position)}
+	nextCheckedAt := make(map[string]time.Time, len(positions))
+	nextMissing := make(map[string]bool)
+	for _, position := range positions {
+		since := position.OpenedAt
+		if last, ok := checkedAt[position.Market]; ok && last.Add(-forcedCloseLookback).After(since) {
+			since = last.Add(-forcedCloseLookback)
+		}
+		leg, ok := s.forcedFills(position, since)
+		if !ok {
+			leg, ok = s.missingLeg(position, held)
+			if ok && !missing[position.Market] {
+				// A venue may briefly list no positions, so a missing leg is only acted on once
+				// it is confirmed by the next check.
+				s.logger.Printf("The %s leg of %s on %s is gone, checking again before closing the hedge.", leg.side, position.Market, leg.exchange.Name())
+				nextMissing[position.Market] = true
+				ok = false
+			}
+		}
+		if ok {
+			s.forceClose(position, leg, held)
+			continue
+		}
+		nextCheckedAt[position.Market] = now
+	}
+
+	s.mu.Lock()
+	s.forcedCheckedAt, s.legsMissing = nextCheckedAt, nextMissing
+	s.mu.Unlock()
+}
+
+// forcedFills returns the leg of position that its exchange's trade history reports forced fills
+// on since the given time.
+func (s *Strategy) forcedFills(position *PositionInfo, since time.Time) (forcedLeg, bool) {
+	for _, leg := range []forcedLeg{{exchange: position.LongExchange, side: exchange.Buy}, {exchange: position.ShortExchange, side: exchange.Sell}} {
+		reporter, ok := leg.exchange.(exchange.LiquidationReporter)
+		if !ok {
+			continue
+		}
+		closes, err := reporter.GetForcedCloses(s.ctx, position.Market, since)
+		if err != nil {
+			if !errors.Is(err, exchange.ErrForcedClosesUnsupported) {
+				s.logger.Printf("Could not check %s on %s for forced closes: %v", position.Market, leg.exchange.Name(), err)
+			}
+			continue
+		}
+		var fills []string
+		for _, c := range closes {
+			if c.Side != leg.side || c.Time.Before(position.OpenedAt) {
+				continue
+			}
+			leg.fills = append(leg.fills, c)
+			fills = append(fills, fmt.Sprintf("%s of %f at %.2f", c.Kind, c.Amount, c.Price))
+		}
+		if len(leg.fills) > 0 {
+			leg.reason = fmt.Sprintf("%s reported %s on the %s leg", leg.exchange.Name(), strings.Join(fills, ", "), leg.side)
+			return leg, true
+		}
+	}
+	return forcedLeg{}, false
+}
+
+// missingLeg returns the leg of position that is no longer open on its exchange while the other
+// leg still is.
+func (s *Strategy) missingLeg(position *PositionInfo, held *heldPositions) (forcedLeg, bool) {
+	long, okLong := held.size(position.LongExchange, position.Market, exchange.Buy)
+	short, okShort := held.size(position.ShortExchange, position.Market, exchange.Sell)
+	if !okLong || !okShort {
+		return forcedLeg{}, false
+	}
+	switch {
+	case long > 0 && short == 0:
+		return forcedLeg{exchange: position.ShortExchange, side: exchange.Sell,
+			reason: fmt.Sprintf("the %s leg on %s is gone while the %s leg on %s is open", exchange.Sell, position.ShortExchange.Name(), exchange.Buy, position.LongExchange.Name())}, true
+	case short > 0 && long == 0:
+		return forcedLeg{exchange: position.LongExchange, side: exchange.Buy,
+			reason: fmt.Sprintf("the %s leg on %s is gone while the %s leg on %s is open", exchange.Buy, position.LongExchange.Name(), exchange.Sell, position.ShortExchange.Name())}, true
+	}
+	return forcedLeg{}, false
+}
+
+// forceClose closes what is left of position after its exchange liquidated or auto-deleveraged
+// leg: the surviving leg and any remainder of the forced one. The position is journaled as
+// force-closed and the operator alerted.
+func (s *Strategy) forceClose(position *PositionInfo, leg forcedLeg, held *heldPositions) {
+	price, err := s.markPrice(position.Market, position.LongExchange, position.ShortExchange)
+	if err != nil {
+		price = position.LongEntryPrice
+	}
+
+	s.mu.Lock()
+	if _, exists := s.positions[position.Market]; !exists {
+		s.mu.Unlock()
+		return
+	}
+	delete(s.positions, position.Market)
+	s.closedAt[position.Market] = time.Now()
+	s.persistPositions()
+	s.mu.Unlock()
+
+	survivor := forcedLeg{exchange: position.ShortExchange, side: exchange.Sell}
+	if leg.side == exchange.Sell {
+		survivor = forcedLeg{exchange: position.LongExchange, side: exchange.Buy}
+	}
+	exits := map[exchange.OrderSide]float64{exchange.Buy: price, exchange.Sell: price}
+	if forced := averagePrice(leg.fills); forced > 0 {
+		exits[leg.side] = forced
+	}
+	var failed []string
+	for i, l := range []forcedLeg{survivor, leg} {
+		size, ok := held.size(l.exchange, position.Market, l.side)
+		amount := decimal.NewFromFloat(size)
+		if !ok && i == 0 {
+			amount = position.amountAt(price)
+		}
+		if !amount.IsPositive() {
+			continue
+		}
+		order, latency, err := s.rollbackLeg(l.exchange, position.Market, l.side, amount)
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("closing the %s leg on %s failed: %v", l.side, l.exchange.Name(), err))
+			continue
+		}
+		s.recordFill(l.exchange, position.Market, oppositeSide(l.side), amount, price, latency, order)
+		exits[l.side] = fillPrice(order, exits[l.side])
+	}
+
+	s.capital.Release(DefaultName, position.SizeUSD)
+	s.activity.addClosed()
+	s.recordForcedClose(position, leg)
+	s.exportClosed(position, s.realizePnL(position, exits[exchange.Buy], exits[exchange.Sell]))
+
+	message := fmt.Sprintf("🚨 FORCED CLOSE on %s: %s. The position (long %s / short %s, %.2f USD) is closed.",
+		position.Market, leg.reason, position.LongExchange.Name(), position.ShortExchange.Name(), position.SizeUSD)
+	if len(failed) > 0 {
+		message += " " + strings.Join(failed, "; ") + "."
+	}
+	s.logger.Printf("CRITICAL: %s", message)
+	s.notifier.SendMessage(message)
+	notifications.Publish(s.notifier, notifications.Event{Type: notifications.EventRiskAlert, Market: position.Market,
+		Exchange: leg.exchange.Name(), Action: "FORCED CLOSE", Message: message})
+	notifications.Publish(s.notifier, notifications.Event{Type: notifications.EventPositionClosed, Market: position.Market,
+		LongExchange: position.LongExchange.Name(), ShortExchange: position.ShortExchange.Name(), SizeUSD: position.SizeUSD,
+		RateDiff: position.EntryRateDiff, Error: leg.reason})
+}
+
+// averagePrice is the amount-weighted price of fills, or 0 without any.
+func averagePrice(fills []exchange.ForcedClose) float64 {
+	var amount, notional float64
+	for _, f := range fills {
+		amount += f.Amount
+		notional += f.Amount * f.Price
+	}
+	if amount == 0 {
+		return 0
+	}
+	return notional / amount
+}
+
+// recordForcedClose journals the exchange's forced fills on leg and that position was
+// force-closed.
+func (s *Strategy) recordForcedClose(position *PositionInfo, leg forcedLeg) {
+	for _, fill := range leg.fills {
+		entry := journal.Entry{Time: fill.Time.UTC(), Type: journal.EntryFill, Exchange: leg.exchange.Name(), Market: position.Market,
+			Side: string(oppositeSide(fill.Side)), Amount: fill.Amount, Price: fill.Price, Message: string(fill.Kind)}
+		if err := s.journal.Record(entry); err != nil {
+			s.logger.Printf("Failed to record the %s forced fill to the journal: %v", position.Market, err)
+		}
+	}
+	message := fmt.Sprintf("long %s / short %s, %.2f USD held %s: %s", position.LongExchange.Name(), position.ShortExchange.Name(),
+		position.SizeUSD, time.Since(position.OpenedAt).Round(time.Second), leg.reason)
+	if err := s.journal.Record(journal.Entry{Type: journal.EntryForcedClose, Exchange: leg.exchange.Name(), Market: position.Market, Message: message}); err != nil {
+		s.logger.Printf("Failed to record the %s forced close to the journal: %v", position.Market, err)
+	}
+}
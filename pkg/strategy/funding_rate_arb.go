@@ -1,14 +1,32 @@
 package strategy
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
-	"math"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/shopspring/decimal"
+
 	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/config"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/blackout"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/calendar"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/capital"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/collateral"
 	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/exchange"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/execution"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/export"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/journal"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/marketdata"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/metrics"
 	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/notifications"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/oracle"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/pnl"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/state"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/tuning"
 )
 
 // PositionInfo tracks an open arbitrage position.
@@ -17,133 +35,508 @@ type PositionInfo struct {
 	LongExchange  exchange.Exchange
 	ShortExchange exchange.Exchange
 	SizeUSD       float64
+	// Amount is the base amount of each leg, rounded to the lot sizes of both venues. It is 0
+	// for positions saved before it was recorded, which are closed at SizeUSD over the mark price.
+	Amount        decimal.Decimal
+	EntryRateDiff float64
+	EntrySlippage float64
+	OpenedAt      time.Time
+	// LongEntryPrice and ShortEntryPrice are the fill prices of the legs.
+	LongEntryPrice  float64
+	ShortEntryPrice float64
+	// Funding is the net funding received on both legs, as reported by the exchanges up to
+	// FundingSyncedAt.
+	Funding         float64
+	FundingSyncedAt time.Time
+	// CollectAfter is when every leg has been paid the funding the position was opened for. It
+	// is only set with EXIT_AFTER_FUNDING, which closes the position then.
+	CollectAfter time.Time
 }
 
 // Strategy holds the core logic for the funding rate arbitrage bot.
 type Strategy struct {
-	config    config.Config
-	exchange1 exchange.Exchange
-	exchange2 exchange.Exchange
-	logger    *log.Logger
-	notifier  *notifications.TelegramNotifier
-	positions map[string]*PositionInfo
-	mu        sync.Mutex
+	config     config.Config
+	exchanges  []exchange.Exchange
+	logger     *log.Logger
+	notifier   notifications.Notifier
+	sheets     *export.SheetsExporter
+	journal    *journal.Journal
+	state      *state.Store
+	capital    *capital.Manager
+	marketData *marketdata.Cache
+	calendar   *calendar.Calendar
+	thresholds *tuning.Threshold
+	oracle     *oracle.Checker
+	margin     *marginSelector
+	maker      map[string]execution.Config
+	rollback   rollbackPolicy
+	breaker    *circuitBreaker
+	costs      map[string]exchange.Costs
+	prices     priceHistory
+	blackout   *blackout.Rules
+	collateral *collateral.Converter
+	executions executionLog
+	activity   activityLog
+	events     events
+	metrics    *metrics.Metrics
+	pnl        *pnl.Ledger
+	paused     bool
+	positions  map[string]*PositionInfo
+	// opening holds the size of the entries placing orders on each market, which executeArbitrage
+	// does without s.mu.
+	opening map[string]float64
+	mu      sync.Mutex
+	// entering is held while an entry places its orders, so shutdown waits for it rather than
+	// aborting it halfway.
+	entering sync.Mutex
+
+	// ctx is passed to every exchange request and cancelled when Run is stopped, so shutdown
+	// doesn't wait on hanging requests.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// fundingAccruedAt is when funding was last estimated for each open position.
+	fundingAccruedAt map[string]time.Time
+	// liquidationAlerts holds the markets whose liquidation alert has been sent, so it is sent
+	// once until the position recovers.
+	liquidationAlerts map[string]bool
+	// underfunded holds the markets skipped for insufficient collateral until the balances suffice,
+	// so the operator is notified once rather than on every check.
+	underfunded map[string]bool
+	// closedAt holds when the position on each market was last closed, for REENTRY_COOLDOWN_MINUTES.
+	closedAt map[string]time.Time
+	// netDelta is the net position on each market held on the exchanges, in USD, as of the last
+	// net delta check; deltaAlerts holds the markets beyond NET_DELTA_ALERT_USD then, so each
+	// breach is alerted once.
+	netDelta    map[string]float64
+	deltaAlerts map[string]bool
+	// forcedCheckedAt is when each open position was last checked for forced closes, and
+	// legsMissing holds the markets whose position had a leg gone at that check.
+	forcedCheckedAt map[string]time.Time
+	legsMissing     map[string]bool
+	// basis is the basis PnL of each open position, in USD, as of the last basis check.
+	basis map[string]float64
+	// killSwitch is why the kill switch halted new entries, or "" while it is armed.
+	killSwitch string
+	// staleRates describes the exchanges whose funding rates the last check went without.
+	staleRates []string
+	// pnlPeak is the highest total PnL since the kill switch was armed, which drawdowns are
+	// measured from; pnlPeakSet is false until it is first measured.
+	pnlPeak    float64
+	pnlPeakSet bool
 }
 
-// NewFundingRateArb creates a new arbitrage strategy instance.
-func NewFundingRateArb(cfg config.Config, ex1, ex2 exchange.Exchange, logger *log.Logger, notifier *notifications.TelegramNotifier) *Strategy {
+// NewFundingRateArb creates a new arbitrage strategy instance trading between every pair of
+// exchanges.
+func NewFundingRateArb(cfg config.Config, exchanges []exchange.Exchange, logger *log.Logger, notifier notifications.Notifier) *Strategy {
+	if notifier == nil {
+		notifier = notifications.Discard
+	}
+	ctx, cancel := context.WithCancel(context.Background())
 	return &Strategy{
-		config:    cfg,
-		exchange1: ex1,
-		exchange2: ex2,
-		logger:    logger,
-		notifier:  notifier,
-		positions: make(map[string]*PositionInfo),
+		config:      cfg,
+		exchanges:   exchanges,
+		logger:      logger,
+		notifier:    notifier,
+		marketData:  NewMarketData(cfg),
+		calendar:    newFundingCalendar(cfg, logger, exchanges...),
+		thresholds:  newThresholdTuner(cfg),
+		oracle:      newOracleChecker(cfg, logger),
+		margin:      newMarginSelector(cfg, logger),
+		maker:       newMakerEntry(cfg, logger),
+		rollback:    newRollbackPolicy(cfg),
+		breaker:     newCircuitBreaker(cfg),
+		costs:       newExecutionCosts(cfg, logger),
+		blackout:    newBlackoutRules(cfg, logger),
+		collateral:  newCollateralConverter(cfg, logger, exchanges...),
+		events:      newEvents(),
+		pnl:         pnl.NewLedger(),
+		positions:   make(map[string]*PositionInfo),
+		opening:     make(map[string]float64),
+		underfunded: make(map[string]bool),
+		closedAt:    make(map[string]time.Time),
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+}
+
+// newCollateralConverter builds the converter used to value venue balances in USD. Configured
+// prices take precedence; other assets are priced from the mark prices of the first exchange.
+func newCollateralConverter(cfg config.Config, logger *log.Logger, exchanges ...exchange.Exchange) *collateral.Converter {
+	var sources []collateral.PriceSource
+	prices, err := collateral.ParseStaticPrices(cfg.CollateralPrices)
+	if err != nil {
+		logger.Printf("Ignoring COLLATERAL_PRICES: %v", err)
+	} else {
+		sources = append(sources, prices)
+	}
+	if len(exchanges) > 0 {
+		sources = append(sources, collateral.MarkPriceSource{Exchange: exchanges[0]})
+	}
+	return collateral.NewConverter(sources...)
+}
+
+// newFundingCalendar seeds the funding calendar with each exchange's funding interval,
+// overridden by any FUNDING_SCHEDULES entries.
+func newFundingCalendar(cfg config.Config, logger *log.Logger, exchanges ...exchange.Exchange) *calendar.Calendar {
+	cal := calendar.New()
+	for _, ex := range exchanges {
+		cal.SetSchedule(ex.Name(), calendar.Schedule{Interval: exchange.FundingIntervalOf(ex)})
+	}
+	schedules, err := calendar.ParseSchedules(cfg.FundingSchedules)
+	if err != nil {
+		logger.Printf("Ignoring FUNDING_SCHEDULES: %v", err)
+		return cal
+	}
+	for name, schedule := range schedules {
+		cal.SetSchedule(name, schedule)
+	}
+	return cal
+}
+
+// SetSheetsExporter enables exporting closed positions to a Google Sheet.
+func (s *Strategy) SetSheetsExporter(sheets *export.SheetsExporter) {
+	s.sheets = sheets
+}
+
+// SetJournal enables recording fills to the trade journal.
+func (s *Strategy) SetJournal(j *journal.Journal) {
+	s.journal = j
+}
+
+// SetCapitalManager shares capital with other strategies running in the same process.
+func (s *Strategy) SetCapitalManager(manager *capital.Manager) {
+	s.capital = manager
+}
+
+// Calendar returns the funding calendar used to time checks and entries.
+func (s *Strategy) Calendar() *calendar.Calendar {
+	return s.calendar
+}
+
+// SetMarketData makes the strategy read rates and prices through cache, shared with the other
+// strategies and the status API. A nil cache keeps the strategy's own.
+func (s *Strategy) SetMarketData(cache *marketdata.Cache) {
+	if cache != nil {
+		s.marketData = cache
 	}
 }
 
+// MarketData returns the shared market data cache so that other consumers can read
+// the rates and prices the strategy has already fetched.
+func (s *Strategy) MarketData() *marketdata.Cache {
+	return s.marketData
+}
+
 // Run starts the arbitrage strategy loop.
 func (s *Strategy) Run(stop chan struct{}) {
 	s.logger.Println("Starting funding rate arbitrage strategy...")
-	s.logger.Printf("Exchanges: %s, %s", s.exchange1.Name(), s.exchange2.Name())
+	s.logger.Printf("Exchanges: %s", strings.Join(s.exchangeNames(), ", "))
 	s.logger.Printf("Markets: %v", s.config.Markets)
 	s.logger.Printf("Minimum Rate Difference: %.4f%%", s.config.MinFundingRateDiff*100)
 	s.logger.Printf("Position Size (USD): %.2f", s.config.PositionSizeUSD)
-
-	// Run checks on a ticker
-	ticker := time.NewTicker(1 * time.Minute) // Check every minute
-	defer ticker.Stop()
+	if s.config.CheckIntervalSeconds > 0 && s.config.CheckIntervalSeconds < minPolledCheckInterval.Seconds() && !s.config.Streaming {
+		s.logger.Printf("Ignoring CHECK_INTERVAL_SECONDS: below %s requires STREAMING, checking every %s.", minPolledCheckInterval, checkInterval(s.config))
+	}
+	policy := s.shutdownPolicy()
+	if policy == ShutdownPreserve {
+		cancelOnStop(stop, &s.entering, s.cancel)
+	}
+	defer s.cancel()
+	s.restorePositions()
+	s.reconcile()
+	s.startStreams(stop)
+
+	// Timer events poll the exchanges; pushed rates, fills and operator commands are handled as they arrive.
+	timer := time.NewTimer(s.firstCheckDelay())
+	defer timer.Stop()
+
+	// A nil channel never fires, which leaves the execution report disabled.
+	var executionReport <-chan time.Time
+	if interval := s.executionReportInterval(); interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		executionReport = ticker.C
+	}
+	var pnlReport <-chan time.Time
+	if interval := s.pnlReportInterval(); interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		pnlReport = ticker.C
+	}
+	var summary <-chan time.Time
+	var summaryTimer *time.Timer
+	schedule, summaryEnabled := s.summarySchedule()
+	if summaryEnabled {
+		summaryTimer = time.NewTimer(time.Until(schedule.Next(time.Now())))
+		defer summaryTimer.Stop()
+		summary = summaryTimer.C
+	}
+	started := time.Now()
+	fundingSync := time.NewTicker(fundingSyncInterval)
+	defer fundingSync.Stop()
+	var liquidationCheck <-chan time.Time
+	if s.liquidationCheckEnabled() {
+		ticker := time.NewTicker(liquidationCheckInterval)
+		defer ticker.Stop()
+		liquidationCheck = ticker.C
+	}
+	forcedCloseCheck := time.NewTicker(forcedCloseCheckInterval)
+	defer forcedCloseCheck.Stop()
+	var killSwitchCheck <-chan time.Time
+	if s.killSwitchEnabled() {
+		ticker := time.NewTicker(killSwitchCheckInterval)
+		defer ticker.Stop()
+		killSwitchCheck = ticker.C
+	}
+	var netDeltaCheck <-chan time.Time
+	if s.config.NetDeltaAlertUSD > 0 {
+		ticker := time.NewTicker(netDeltaCheckInterval)
+		defer ticker.Stop()
+		netDeltaCheck = ticker.C
+	}
+	var basisCheck <-chan time.Time
+	if s.config.BasisStopUSD > 0 {
+		ticker := time.NewTicker(basisCheckInterval)
+		defer ticker.Stop()
+		basisCheck = ticker.C
+	}
+	var rebalanceCheck <-chan time.Time
+	if interval := s.rebalanceInterval(); interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		rebalanceCheck = ticker.C
+	}
+	// Pushed rates arm rateCheck; the updates arriving before it fires share its check.
+	var rateCheck <-chan time.Time
+	var lastCheck time.Time
 
 	for {
+		// Operator commands take priority over everything else.
+		select {
+		case cmd := <-s.events.commands:
+			s.handleCommand(cmd)
+			continue
+		case <-stop:
+			s.logger.Println("Stopping strategy...")
+			s.shutdown(policy)
+			return
+		default:
+		}
+
+		// Fills come next so position state is current before rates are evaluated.
+		select {
+		case order := <-s.events.fills:
+			s.handleFill(order)
+			continue
+		default:
+		}
+
 		select {
-		case <-ticker.C:
+		case cmd := <-s.events.commands:
+			s.handleCommand(cmd)
+		case order := <-s.events.fills:
+			s.handleFill(order)
+		case update := <-s.events.rates:
+			s.handleRateUpdate(update)
+			if rateCheck == nil {
+				rateCheck = time.After(rateCheckDelay(lastCheck, time.Now()))
+			}
+		case <-rateCheck:
+			rateCheck = nil
+			s.checkFundingRates()
+			lastCheck = time.Now()
+		case <-timer.C:
 			s.checkFundingRates()
+			lastCheck = time.Now()
+			timer.Reset(s.nextCheckDelay())
+		case <-executionReport:
+			s.reportExecution()
+		case <-fundingSync.C:
+			s.syncFunding()
+		case <-liquidationCheck:
+			s.checkLiquidationRisk()
+		case <-forcedCloseCheck.C:
+			s.checkForcedCloses()
+		case <-killSwitchCheck:
+			s.checkKillSwitch()
+		case <-netDeltaCheck:
+			s.checkNetDelta()
+		case <-basisCheck:
+			s.checkBasisStop()
+		case <-rebalanceCheck:
+			s.checkRebalance()
+		case <-pnlReport:
+			s.reportPnL()
+		case <-summary:
+			s.reportSummary(started)
+			summaryTimer.Reset(time.Until(schedule.Next(time.Now())))
 		case <-stop:
 			s.logger.Println("Stopping strategy...")
+			s.shutdown(policy)
 			return
 		}
 	}
 }
 
-// checkFundingRates fetches and compares funding rates to find opportunities.
-func (s *Strategy) checkFundingRates() {
-	s.logger.Println("Checking for funding rate arbitrage opportunities...")
+// cancelOnStop calls cancel once stop is closed and the entry placing orders, if any, has
+// completed, aborting the other exchange requests in flight. entering may be nil. Policies that
+// close positions on shutdown need the requests, so Run cancels after applying them instead.
+func cancelOnStop(stop <-chan struct{}, entering sync.Locker, cancel context.CancelFunc) {
+	go func() {
+		<-stop
+		if entering != nil {
+			entering.Lock()
+			defer entering.Unlock()
+		}
+		cancel()
+	}()
+}
 
-	rates1, err := s.exchange1.GetFundingRates()
-	if err != nil {
-		s.logger.Printf("Error getting funding rates from %s: %v", s.exchange1.Name(), err)
-		return
+// unwindContext is used for orders that reduce exposure, such as closes and rollbacks. It
+// ignores shutdown, since abandoning them halfway would leave a leg unhedged.
+func (s *Strategy) unwindContext() context.Context {
+	return context.WithoutCancel(s.ctx)
+}
+
+// setPaused toggles whether new positions may be opened.
+func (s *Strategy) setPaused(paused bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.paused = paused
+	if !paused {
+		// Resuming accepts the losses so far: the kill switch is re-armed from the current PnL.
+		s.killSwitch, s.pnlPeakSet = "", false
 	}
+}
 
-	rates2, err := s.exchange2.GetFundingRates()
-	if err != nil {
-		s.logger.Printf("Error getting funding rates from %s: %v", s.exchange2.Name(), err)
+// Check evaluates the funding rates once, opening and closing positions as the polling loop does.
+// It lets backtests drive the strategy from recorded data.
+func (s *Strategy) Check() {
+	s.checkFundingRates()
+}
+
+// checkFundingRates fetches and compares funding rates to find opportunities. Every pair of
+// exchanges is scanned for each market and the widest spread is traded, the best opportunities
+// first, see rankOpportunities; open positions are closed once the spread between their own two
+// venues is no longer worth holding, see exitReason, or rotated out for a better opportunity.
+func (s *Strategy) checkFundingRates() {
+	s.logger.Println("Checking for funding rate arbitrage opportunities...")
+
+	s.probeBreakers()
+	rates, venues := s.fundingRates()
+	if len(venues) < 2 {
+		s.logger.Println("Funding rates are available from fewer than two exchanges, skipping this check.")
 		return
 	}
-
-	rates1Map := make(map[string]float64)
-	for _, r := range rates1 {
-		rates1Map[r.Market] = r.Rate
+	s.accrueFunding(rates, time.Now())
+	s.priceMarkets(rates, venues)
+	if s.config.DynamicSizing && s.config.SizingTargetVolatility > 0 {
+		s.samplePrices(rates, venues, time.Now())
 	}
+	// Open positions are still managed on every venue; only new ones avoid a tripped venue.
+	openable := s.healthyVenues(venues)
 
-	rates2Map := make(map[string]float64)
-	for _, r := range rates2 {
-		rates2Map[r.Market] = r.Rate
-	}
+	liquid := s.liquidityFilter(s.config.Markets)
 
+	var opportunities []opportunity
+	// held is the current annualized spread of each position kept open, for rotating out of it.
+	held := make(map[string]float64)
 	for _, market := range s.config.Markets {
-		rate1, ok1 := rates1Map[market]
-		rate2, ok2 := rates2Map[market]
-
-		if !ok1 || !ok2 {
-			s.logger.Printf("Market %s not available on both exchanges, skipping.", market)
-			continue
-		}
-
-		diff := rate1 - rate2
-		s.logger.Printf("Market: %s | %s Rate: %.6f | %s Rate: %.6f | Diff: %.6f",
-			market, s.exchange1.Name(), rate1, s.exchange2.Name(), rate2, diff)
+		s.logger.Printf("Market: %s | %s", market, describeRates(market, venues, rates))
 
 		s.mu.Lock()
 		position, exists := s.positions[market]
 		s.mu.Unlock()
 
-		// Condition to OPEN a position
-		if !exists && math.Abs(diff) > s.config.MinFundingRateDiff {
-			if diff > 0 {
-				// rate1 is higher, short on exchange1, long on exchange2
-				s.executeArbitrage(market, s.exchange2, s.exchange1, diff)
+		// Condition to CLOSE a position: the spread between its venues has inverted or flattened.
+		if exists {
+			shortRate, okShort := rates[position.ShortExchange.Name()][market]
+			longRate, okLong := rates[position.LongExchange.Name()][market]
+			if !okShort || !okLong {
+				s.logger.Printf("No funding rate for %s on %s and %s, keeping the position.", market, position.LongExchange.Name(), position.ShortExchange.Name())
+				continue
+			}
+			diff := hourlyRate(position.ShortExchange, shortRate) - hourlyRate(position.LongExchange, longRate)
+			s.metrics.SetRateDiff(market, diff)
+			s.logger.Printf("Open position on %s: long %s / short %s | Diff: %.6f", market, position.LongExchange.Name(), position.ShortExchange.Name(), diff)
+			if reason := s.exitReason(position, diff, time.Now()); reason != "" {
+				s.logger.Printf("%s Closing position.", reason)
+				s.closeArbitrage(position)
 			} else {
-				// rate2 is higher, short on exchange2, long on exchange1
-				s.executeArbitrage(market, s.exchange1, s.exchange2, -diff)
+				held[market] = exchange.Annualize(diff, time.Hour)
 			}
-		} else if exists { // Condition to CLOSE a position
-			// Close if the rate difference has inverted or flattened.
-			shouldClose := false
-			// Case 1: We are short exchange1 because its rate was higher.
-			if position.ShortExchange.Name() == s.exchange1.Name() && diff <= 0 {
-				shouldClose = true
+			continue
+		}
+
+		best, ok := bestPair(market, openable, rates)
+		if !ok {
+			s.logger.Printf("Market %s not available on two healthy exchanges, skipping.", market)
+			continue
+		}
+		s.metrics.SetRateDiff(market, best.diff())
+		s.logger.Printf("Best pair for %s: long %s / short %s | Diff: %.6f", market, best.longEx.Name(), best.shortEx.Name(), best.diff())
+
+		// Condition to OPEN a position
+		if liquid != nil && !liquid[market] {
+			continue
+		}
+		if best.diff() > s.entryThreshold(market) {
+			if reason := s.blackout.Blocked(market, time.Now()); reason != "" {
+				s.logger.Printf("Not opening a position: %s.", reason)
+				continue
 			}
-			// Case 2: We are short exchange2 because its rate was higher.
-			if position.ShortExchange.Name() == s.exchange2.Name() && diff >= 0 {
-				shouldClose = true
+			if remaining := s.cooldownRemaining(market, time.Now()); remaining > 0 {
+				s.logger.Printf("Position on %s was closed recently, waiting %s before reopening it.", market, remaining.Round(time.Second))
+				continue
 			}
-
-			if shouldClose {
-				s.logger.Printf("Funding rate difference for %s is no longer favorable. Closing position.", market)
-				s.closeArbitrage(position)
+			if !s.inEntryWindow(market, best.longEx, best.shortEx, time.Now()) {
+				s.logger.Printf("Next funding on %s is more than %s away, waiting to enter.", market, s.entryWindow())
+				continue
 			}
+			opportunities = append(opportunities, opportunity{market: market, longEx: best.longEx, shortEx: best.shortEx, rateDiff: best.diff()})
 		}
 	}
+
+	opportunities = s.rankOpportunities(opportunities)
+	s.rotatePositions(opportunities, held)
+	for _, opp := range s.sizeOpportunities(opportunities) {
+		s.executeArbitrage(opp.market, opp.longEx, opp.shortEx, opp.rateDiff, opp.sizeUSD)
+	}
+	s.recordPositionMetrics()
+}
+
+// observeFunding records a next funding time reported by an exchange in the calendar.
+func (s *Strategy) observeFunding(ex exchange.Exchange, rate *exchange.FundingRate) {
+	if rate.NextTime > 0 {
+		s.calendar.Observe(ex.Name(), rate.Market, time.Unix(rate.NextTime, 0))
+	}
 }
 
 // executeArbitrage places the long and short orders to capitalize on a funding rate difference.
-func (s *Strategy) executeArbitrage(market string, longEx, shortEx exchange.Exchange, rateDiff float64) {
+// s.mu is only held to read and record positions, not while the venues are called, so status
+// requests aren't held up by an entry or the retries of its rollback.
+func (s *Strategy) executeArbitrage(market string, longEx, shortEx exchange.Exchange, rateDiff, sizeUSD float64) {
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	paused := s.paused
+	_, exists := s.positions[market]
+	exists = exists || s.opening[market] > 0
+	s.mu.Unlock()
+
+	if paused {
+		s.logger.Printf("Strategy is paused, not opening a position for %s.", market)
+		return
+	}
+
+	for _, ex := range []exchange.Exchange{longEx, shortEx} {
+		if s.breaker.isOpen(ex.Name()) {
+			s.logger.Printf("Circuit breaker for %s is open, not opening a position for %s.", ex.Name(), market)
+			return
+		}
+	}
 
 	// Check if a position is already open for this market
-	if _, exists := s.positions[market]; exists {
+	if exists {
 		s.logger.Printf("Position already open for market %s, skipping.", market)
 		return
 	}
@@ -152,59 +545,234 @@ func (s *Strategy) executeArbitrage(market string, longEx, shortEx exchange.Exch
 	s.logger.Printf("  - Long on: %s", longEx.Name())
 	s.logger.Printf("  - Short on: %s", shortEx.Name())
 	s.logger.Printf("  - Rate Difference: %.6f", rateDiff)
+	s.logger.Printf("  - Size (USD): %.2f", sizeUSD)
+	if until := s.calendar.TimeUntil(shortEx.Name(), market, time.Now()); until >= 0 {
+		s.logger.Printf("  - Next funding on %s in: %s", shortEx.Name(), until.Round(time.Second))
+	}
+	notifications.Publish(s.notifier, notifications.Event{Type: notifications.EventOpportunity, Market: market,
+		LongExchange: longEx.Name(), ShortExchange: shortEx.Name(), SizeUSD: sizeUSD, RateDiff: rateDiff})
 
-	// Check if opening a new position exceeds the max total position size
-	if s.getTotalPositionValue()+s.config.PositionSizeUSD > s.config.MaxPositionUSD {
+	// Check if opening a new position exceeds the max total position size, counting the entries
+	// still placing orders, and claim the market until this one is done.
+	s.mu.Lock()
+	if s.opening[market] > 0 {
+		s.mu.Unlock()
+		s.logger.Printf("Position already being opened for market %s, skipping.", market)
+		return
+	}
+	if s.getTotalPositionValue()+s.openingValue()+sizeUSD > s.config.MaxPositionUSD {
+		s.mu.Unlock()
 		s.logger.Printf("Cannot open new position, max total position size of %.2f USD would be exceeded.", s.config.MaxPositionUSD)
 		return
 	}
+	s.opening[market] = sizeUSD
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.opening, market)
+		s.mu.Unlock()
+	}()
 
-	// TODO: Fetch the current price to calculate the amount in the base currency.
-	// This is a placeholder as the exchange interface does not yet support fetching price tickers.
-	// Using a hardcoded price for BTC-USD for demonstration.
-	var currentPrice float64
-	if market == "BTC-USD" {
-		currentPrice = 60000.0
-	} else if market == "ETH-USD" {
-		currentPrice = 3000.0
-	} else {
-		s.logger.Printf("No placeholder price for market %s, cannot calculate order amount.", market)
+	currentPrice, err := s.markPrice(market, longEx, shortEx)
+	if err != nil {
+		s.logger.Printf("Cannot calculate order amount for %s: %v", market, err)
 		return
 	}
 
-	amount := s.config.PositionSizeUSD / currentPrice
-
-	// Place orders
-	s.logger.Printf("Placing LONG order on %s for %f of %s at price %.2f", longEx.Name(), amount, market, currentPrice)
-	longOrder, err := longEx.PlaceOrder(market, exchange.Buy, exchange.Market, amount, currentPrice)
-	s.notifier.SendPositionNotification("OPEN LONG", longEx.Name(), market, s.config.PositionSizeUSD, err)
+	amount, err := s.orderAmount(market, longEx, shortEx, sizeUSD, currentPrice)
 	if err != nil {
-		s.logger.Printf("Failed to place LONG order on %s: %v", longEx.Name(), err)
-		return // Don't proceed to short if long fails
+		s.logger.Printf("Not opening a position for %s: %v", market, err)
+		return
 	}
-	s.logger.Printf("Successfully placed LONG order: ID %s", longOrder.ID)
 
-	s.logger.Printf("Placing SHORT order on %s for %f of %s at price %.2f", shortEx.Name(), amount, market, currentPrice)
-	shortOrder, err := shortEx.PlaceOrder(market, exchange.Sell, exchange.Market, amount, currentPrice)
-	s.notifier.SendPositionNotification("OPEN SHORT", shortEx.Name(), market, s.config.PositionSizeUSD, err)
-	if err != nil {
-		s.logger.Printf("Failed to place SHORT order on %s: %v", shortEx.Name(), err)
-		// TODO: Need to handle the case where the long order was placed but the short failed.
-		// This would involve cancelling the long order immediately.
-		s.logger.Println("CRITICAL: Long order was placed but short order failed. Manual intervention may be required.")
+	if err := s.checkOracle(market, longEx, shortEx); err != nil {
+		s.logger.Printf("Not opening a position for %s: %v", market, err)
+		return
+	}
+
+	if err := s.checkDepth(market, longEx, shortEx, sizeUSD); err != nil {
+		s.logger.Printf("Not opening a position for %s: %v", market, err)
+		return
+	}
+
+	if err := s.checkEntryCost(market, longEx, shortEx, rateDiff, sizeUSD); err != nil {
+		s.logger.Printf("Not opening a position for %s: %v", market, err)
+		return
+	}
+
+	if err := s.checkBalances(market, longEx, shortEx, sizeUSD); err != nil {
+		s.logger.Printf("Not opening a position for %s: %v", market, err)
+		s.notifyUnderfunded(market, err)
+		return
+	}
+	s.mu.Lock()
+	delete(s.underfunded, market)
+	s.mu.Unlock()
+
+	if err := s.margin.apply(s.ctx, market, longEx, shortEx); err != nil {
+		s.logger.Printf("Not opening a position for %s: %v", market, err)
+		return
+	}
+
+	// Once orders are placed the entry runs to the end, hedged or rolled back, even on shutdown.
+	s.entering.Lock()
+	defer s.entering.Unlock()
+	if s.ctx.Err() != nil {
+		return
+	}
+
+	if err := s.capital.Reserve(DefaultName, sizeUSD); err != nil {
+		s.logger.Printf("Cannot open new position for %s: %v", market, err)
+		return
+	}
+
+	// Place both legs concurrently so the unhedged window is a single round-trip.
+	s.logger.Printf("Placing LONG order on %s and SHORT order on %s for %s of %s at price %.2f", longEx.Name(), shortEx.Name(), amount, market, currentPrice)
+	longRef, shortRef := s.decisionPrice(longEx, market, currentPrice), s.decisionPrice(shortEx, market, currentPrice)
+	longLeg, shortLeg := s.placeLegs(market, longEx, shortEx, amount, currentPrice)
+	longLeg.decisionPrice, shortLeg.decisionPrice = longRef, shortRef
+	s.notifier.SendPositionNotification("OPEN LONG", longEx.Name(), market, sizeUSD, longLeg.err)
+	s.notifier.SendPositionNotification("OPEN SHORT", shortEx.Name(), market, sizeUSD, shortLeg.err)
+
+	if longLeg.err != nil || shortLeg.err != nil {
+		if longLeg.err != nil {
+			s.logger.Printf("Failed to place LONG order on %s: %v", longEx.Name(), longLeg.err)
+		}
+		if shortLeg.err != nil {
+			s.logger.Printf("Failed to place SHORT order on %s: %v", shortEx.Name(), shortLeg.err)
+		}
+		s.compensateLegs(market, longEx, shortEx, longLeg, shortLeg, amount, sizeUSD)
+		s.capital.Release(DefaultName, sizeUSD)
 		return
 	}
-	s.logger.Printf("Successfully placed SHORT order: ID %s", shortOrder.ID)
+	s.logger.Printf("Successfully placed LONG order: ID %s", longLeg.order.ID)
+	s.logger.Printf("Successfully placed SHORT order: ID %s", shortLeg.order.ID)
+	s.recordFill(longEx, market, exchange.Buy, amount, longLeg.decisionPrice, longLeg.latency, longLeg.order)
+	s.recordFill(shortEx, market, exchange.Sell, amount, shortLeg.decisionPrice, shortLeg.latency, shortLeg.order)
+
+	// A partially filled leg leaves the position smaller than planned; give back the capital.
+	if hedged := s.repairHedge(market, longEx, shortEx, longLeg.order, shortLeg.order, amount, currentPrice); hedged.LessThan(amount) {
+		hedgedUSD := hedged.InexactFloat64() * currentPrice
+		s.capital.Release(DefaultName, sizeUSD-hedgedUSD)
+		sizeUSD = hedgedUSD
+		amount = hedged
+	}
 
 	// Record the new position
-	s.positions[market] = &PositionInfo{
-		Market:        market,
-		LongExchange:  longEx,
-		ShortExchange: shortEx,
-		SizeUSD:       s.config.PositionSizeUSD,
+	now := time.Now()
+	position := &PositionInfo{
+		Market:          market,
+		LongExchange:    longEx,
+		ShortExchange:   shortEx,
+		SizeUSD:         sizeUSD,
+		Amount:          amount,
+		EntryRateDiff:   rateDiff,
+		EntrySlippage:   slippage(currentPrice, longLeg.order, shortLeg.order),
+		OpenedAt:        now,
+		LongEntryPrice:  fillPrice(longLeg.order, currentPrice),
+		ShortEntryPrice: fillPrice(shortLeg.order, currentPrice),
+	}
+	if s.config.ExitAfterFunding {
+		position.CollectAfter = s.collectAfter(market, longEx, shortEx, now)
+	}
+	s.mu.Lock()
+	s.positions[market] = position
+	s.persistPositions()
+	total := s.getTotalPositionValue()
+	s.mu.Unlock()
+
+	s.activity.addOpened()
+	s.logger.Printf("Successfully opened arbitrage position for %s. Total position value: %.2f USD", market, total)
+	notifications.Publish(s.notifier, notifications.Event{Type: notifications.EventPositionOpened, Market: market,
+		LongExchange: longEx.Name(), ShortExchange: shortEx.Name(), SizeUSD: sizeUSD, RateDiff: rateDiff})
+}
+
+// markPrice returns the mean mark price of market across venues, ignoring venues that can't be
+// priced. Both legs trade the same notional, so the mean splits any basis between them. During a
+// check the prices come from the batch priceMarkets fetched.
+func (s *Strategy) markPrice(market string, venues ...exchange.Exchange) (float64, error) {
+	total, priced := 0.0, 0
+	var lastErr error
+	for _, ex := range venues {
+		price, err := s.marketData.MarkPrice(s.ctx, ex, market)
+		if err != nil || price <= 0 {
+			lastErr = err
+			continue
+		}
+		total += price
+		priced++
+	}
+	if priced == 0 {
+		if lastErr == nil {
+			lastErr = fmt.Errorf("no mark price for %s", market)
+		}
+		return 0, lastErr
+	}
+	return total / float64(priced), nil
+}
+
+// legResult holds the outcome of placing a single leg of an arbitrage.
+type legResult struct {
+	order   *exchange.Order
+	err     error
+	latency time.Duration
+	// decisionPrice is the mark price of the leg's venue when the orders were submitted.
+	decisionPrice float64
+}
+
+// placeLegs submits the long and short orders concurrently and waits for both to fill.
+func (s *Strategy) placeLegs(market string, longEx, shortEx exchange.Exchange, amount decimal.Decimal, price float64) (legResult, legResult) {
+	var longLeg, shortLeg legResult
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		start := time.Now()
+		longLeg.order, longLeg.err = s.placeEntry(longEx, market, exchange.Buy, amount, price)
+		longLeg.latency = time.Since(start)
+	}()
+	go func() {
+		defer wg.Done()
+		start := time.Now()
+		shortLeg.order, shortLeg.err = s.placeEntry(shortEx, market, exchange.Sell, amount, price)
+		shortLeg.latency = time.Since(start)
+	}()
+	wg.Wait()
+	return longLeg, shortLeg
+}
+
+// compensateLegs unwinds the leg that succeeded when the other one failed, so no naked exposure is left behind.
+func (s *Strategy) compensateLegs(market string, longEx, shortEx exchange.Exchange, longLeg, shortLeg legResult, amount decimal.Decimal, sizeUSD float64) {
+	var filledEx exchange.Exchange
+	var filledSide exchange.OrderSide
+	var filled legResult
+	switch {
+	case longLeg.err == nil && shortLeg.err != nil:
+		filledEx, filledSide, filled = longEx, exchange.Buy, longLeg
+	case shortLeg.err == nil && longLeg.err != nil:
+		filledEx, filledSide, filled = shortEx, exchange.Sell, shortLeg
+	default:
+		s.logger.Printf("Both legs failed for %s, no compensation required.", market)
+		return
 	}
 
-	s.logger.Printf("Successfully opened arbitrage position for %s. Total position value: %.2f USD", market, s.getTotalPositionValue())
+	s.logger.Printf("Only the %s leg on %s succeeded for %s, rolling it back...", filledSide, filledEx.Name(), market)
+	s.recordFill(filledEx, market, filledSide, amount, filled.decisionPrice, filled.latency, filled.order)
+	closeOrder, latency, err := s.rollbackLeg(filledEx, market, filledSide, amount)
+	s.notifier.SendPositionNotification("COMPENSATE "+string(filledSide), filledEx.Name(), market, sizeUSD, err)
+	if err != nil {
+		return
+	}
+	s.recordFill(filledEx, market, oppositeSide(filledSide), amount, filled.decisionPrice, latency, closeOrder)
+	s.logger.Printf("Successfully unwound %s leg on %s.", filledSide, filledEx.Name())
+}
+
+// oppositeSide returns the side that reduces a position opened with side.
+func oppositeSide(side exchange.OrderSide) exchange.OrderSide {
+	if side == exchange.Buy {
+		return exchange.Sell
+	}
+	return exchange.Buy
 }
 
 // getTotalPositionValue calculates the total value of all open positions.
@@ -216,8 +784,25 @@ func (s *Strategy) getTotalPositionValue() float64 {
 	return totalValue
 }
 
+// openingValue is the size of the entries still placing orders. The caller must hold s.mu.
+func (s *Strategy) openingValue() float64 {
+	total := 0.0
+	for _, sizeUSD := range s.opening {
+		total += sizeUSD
+	}
+	return total
+}
+
 // closeArbitrage closes an open arbitrage position and sends notifications.
 func (s *Strategy) closeArbitrage(position *PositionInfo) {
+	// Price the close before giving up the position, so it is retried on the next check if the
+	// venues can't be priced.
+	currentPrice, err := s.markPrice(position.Market, position.LongExchange, position.ShortExchange)
+	if err != nil {
+		s.logger.Printf("Cannot calculate close order amount for %s, keeping the position open: %v", position.Market, err)
+		return
+	}
+
 	s.mu.Lock()
 	// Check if it's still there, might have been closed by another thread.
 	if _, exists := s.positions[position.Market]; !exists {
@@ -226,36 +811,70 @@ func (s *Strategy) closeArbitrage(position *PositionInfo) {
 	}
 	// remove from map immediately to prevent re-entry
 	delete(s.positions, position.Market)
+	s.closedAt[position.Market] = time.Now()
+	s.persistPositions()
 	s.mu.Unlock()
 
 	s.logger.Printf("Closing arbitrage position for %s...", position.Market)
 
-	// Amount needs to be calculated based on SizeUSD and current price
-	var currentPrice float64
-	if position.Market == "BTC-USD" {
-		currentPrice = 60000.0
-	} else if position.Market == "ETH-USD" {
-		currentPrice = 3000.0
-	} else {
-		s.logger.Printf("No placeholder price for market %s, cannot calculate close order amount.", position.Market)
-		return
-	}
-	amount := position.SizeUSD / currentPrice
+	// Close the amount opened, so a price move since entry doesn't leave dust on either leg.
+	amount := position.amountAt(currentPrice)
 
-	// Close positions
-	_, longCloseErr := position.LongExchange.ClosePosition(position.Market, exchange.Buy, amount)
+	// Close positions. The closes are not cancelled on shutdown, which could leave one leg open.
+	unwind := s.unwindContext()
+	longRef, shortRef := s.decisionPrice(position.LongExchange, position.Market, currentPrice), s.decisionPrice(position.ShortExchange, position.Market, currentPrice)
+	start := time.Now()
+	longClose, longCloseErr := position.LongExchange.ClosePosition(unwind, position.Market, exchange.Buy, amount)
+	longLatency := time.Since(start)
+	s.recordOrder(position.LongExchange, position.Market, exchange.Sell, exchange.Market, amount, currentPrice, longClose, longCloseErr)
 	s.notifier.SendPositionNotification("CLOSE LONG", position.LongExchange.Name(), position.Market, position.SizeUSD, longCloseErr)
 	if longCloseErr != nil {
 		s.logger.Printf("Failed to close LONG position on %s: %v", position.LongExchange.Name(), longCloseErr)
 	} else {
 		s.logger.Printf("Successfully closed LONG position on %s.", position.LongExchange.Name())
+		s.recordFill(position.LongExchange, position.Market, exchange.Sell, amount, longRef, longLatency, longClose)
 	}
 
-	_, shortCloseErr := position.ShortExchange.ClosePosition(position.Market, exchange.Sell, amount)
+	start = time.Now()
+	shortClose, shortCloseErr := position.ShortExchange.ClosePosition(unwind, position.Market, exchange.Sell, amount)
+	shortLatency := time.Since(start)
+	s.recordOrder(position.ShortExchange, position.Market, exchange.Buy, exchange.Market, amount, currentPrice, shortClose, shortCloseErr)
 	s.notifier.SendPositionNotification("CLOSE SHORT", position.ShortExchange.Name(), position.Market, position.SizeUSD, shortCloseErr)
 	if shortCloseErr != nil {
 		s.logger.Printf("Failed to close SHORT position on %s: %v", position.ShortExchange.Name(), shortCloseErr)
 	} else {
 		s.logger.Printf("Successfully closed SHORT position on %s.", position.ShortExchange.Name())
+		s.recordFill(position.ShortExchange, position.Market, exchange.Buy, amount, shortRef, shortLatency, shortClose)
 	}
+
+	s.capital.Release(DefaultName, position.SizeUSD)
+	s.activity.addClosed()
+	s.recordClose(position, amount, longCloseErr, shortCloseErr)
+	realized := s.realizePnL(position, fillPrice(longClose, currentPrice), fillPrice(shortClose, currentPrice))
+
+	if longCloseErr == nil && shortCloseErr == nil {
+		s.recordOutcome(position, slippage(currentPrice, longClose, shortClose))
+	}
+	closed := notifications.Event{Type: notifications.EventPositionClosed, Market: position.Market,
+		LongExchange: position.LongExchange.Name(), ShortExchange: position.ShortExchange.Name(), SizeUSD: position.SizeUSD, RateDiff: position.EntryRateDiff}
+	if err := errors.Join(longCloseErr, shortCloseErr); err != nil {
+		closed.Error = err.Error()
+	}
+	notifications.Publish(s.notifier, closed)
+
+	s.exportClosed(position, realized)
+}
+
+// exportClosed appends a closed position and its realized PnL to the Positions sheet.
+func (s *Strategy) exportClosed(position *PositionInfo, realized pnl.Position) {
+	s.sheets.ExportClosedPosition(export.ClosedPosition{
+		Market:        position.Market,
+		LongExchange:  position.LongExchange.Name(),
+		ShortExchange: position.ShortExchange.Name(),
+		SizeUSD:       position.SizeUSD,
+		EntryRateDiff: position.EntryRateDiff,
+		OpenedAt:      position.OpenedAt,
+		ClosedAt:      realized.ClosedAt,
+		RealizedPnL:   realized.NetPnL(),
+	})
 }
@@ -0,0 +1,164 @@
+//go:build integration
+
+package strategy
+
+import (
+	"context"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/config"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/exchange"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/notifications"
+)
+
+// TestArbitrageExecution is an integration test that opens and closes positions.
+// It requires a valid `.env` file with testnet API keys and only runs with `-tags integration`.
+func TestArbitrageExecution(t *testing.T) {
+	// Load config from the project root, two directories up from the current package.
+	cfg, err := config.LoadConfig("../..")
+	if err != nil {
+		t.Fatalf("cannot load config for test. Make sure .env is present in project root: %v", err)
+	}
+
+	// This is a safety check to ensure this test only runs in testnet mode.
+	if !cfg.Testnet {
+		t.Skip("Skipping execution test: TESTNET is not set to true in config")
+	}
+
+	logger := log.New(os.Stdout, "[ARB-TEST] ", log.LstdFlags)
+
+	logger.Println("Initializing exchanges for integration test...")
+	lighterEx := exchange.NewLighter(cfg.LighterAPIKey, cfg.LighterPrivateKey, true)
+	extendedEx, err := exchange.NewExtended(cfg.ExtendedAPIKey, cfg.ExtendedPrivateKey, cfg.ExtendedPublicKey, cfg.ExtendedVaultID, true)
+	if err != nil {
+		t.Fatalf("Failed to create the Extended client: %v", err)
+	}
+
+	notifier := notifications.NewTelegramNotifier(cfg.TelegramBotToken, cfg.TelegramChatID, logger)
+	notifier.Start()
+	defer notifier.Stop()
+
+	// --- Test Parameters ---
+	market := "BTC-USD" // Using a common market for the test
+	if len(cfg.Markets) > 0 && cfg.Markets[0] != "" {
+		market = cfg.Markets[0] // Use the first market from config if available
+	}
+	positionSizeUSD := cfg.PositionSizeUSD
+	if positionSizeUSD == 0 {
+		t.Skip("Skipping execution test: POSITION_SIZE_USD is not set in config")
+	}
+	markPrice, err := extendedEx.GetMarkPrice(context.Background(), market)
+	if err != nil {
+		t.Fatalf("Failed to get %s mark price from Extended: %v", market, err)
+	}
+	amount := positionSizeUSD / markPrice
+
+	// --- Scenario 1: Short Lighter, Long Extended ---
+	logger.Printf("\n--- Starting Scenario 1: Short on Lighter, Long on Extended for %s ---\n", market)
+
+	// Open positions
+	logger.Println("Placing orders to open positions...")
+	shortOrder, err := lighterEx.PlaceOrder(context.Background(), market, exchange.Sell, exchange.Market, amount, 0) // price 0 for market order
+	notifier.SendPositionNotification("TEST OPEN SHORT", lighterEx.Name(), market, positionSizeUSD, err)
+	if err != nil {
+		t.Fatalf("Scenario 1: Failed to place SHORT order on Lighter: %v", err)
+	}
+	logger.Printf("Scenario 1: Placed SHORT order on Lighter: ID %s", shortOrder.ID)
+	logger.Printf("Scenario 1: Lighter Response: %+v", shortOrder)
+
+	longOrder, err := extendedEx.PlaceOrder(context.Background(), market, exchange.Buy, exchange.Market, amount, 0)
+	notifier.SendPositionNotification("TEST OPEN LONG", extendedEx.Name(), market, positionSizeUSD, err)
+	if err != nil {
+		// If the second leg fails, we should try to close the first one to avoid an open position.
+		logger.Printf("Scenario 1: Failed to place LONG order on Extended, attempting to reverse position on Lighter...")
+		_, reverseErr := lighterEx.PlaceOrder(context.Background(), market, exchange.Buy, exchange.Market, amount, 0)
+		if reverseErr != nil {
+			logger.Printf("CRITICAL: Failed to reverse Lighter position: %v", reverseErr)
+		}
+		t.Fatalf("Scenario 1: Failed to place LONG order on Extended: %v", err)
+	}
+	logger.Printf("Scenario 1: Placed LONG order on Extended: ID %s", longOrder.ID)
+	logger.Printf("Scenario 1: Extended Response: %+v", longOrder)
+
+	logger.Println("Scenario 1: Positions opened successfully. Waiting 30 seconds...")
+	time.Sleep(30 * time.Second)
+
+	// Close positions
+	logger.Println("Scenario 1: Closing positions...")
+	closeShort, err := lighterEx.ClosePosition(context.Background(), market, exchange.Sell, amount)
+	notifier.SendPositionNotification("TEST CLOSE SHORT", lighterEx.Name(), market, positionSizeUSD, err)
+	if err != nil {
+		t.Errorf("Scenario 1: Failed to close position on Lighter: %v", err)
+	} else {
+		logger.Printf("Scenario 1: Position closure order placed on Lighter: ID %s", closeShort.ID)
+		logger.Printf("Scenario 1: Lighter Close Response: %+v", closeShort)
+	}
+
+	closeLong, err := extendedEx.ClosePosition(context.Background(), market, exchange.Buy, amount)
+	notifier.SendPositionNotification("TEST CLOSE LONG", extendedEx.Name(), market, positionSizeUSD, err)
+	if err != nil {
+		t.Errorf("Scenario 1: Failed to close position on Extended: %v", err)
+	} else {
+		logger.Printf("Scenario 1: Position closure order placed on Extended: ID %s", closeLong.ID)
+		logger.Printf("Scenario 1: Extended Close Response: %+v", closeLong)
+	}
+	logger.Println("--- Finished Scenario 1 ---")
+
+	// Pause between scenarios to let exchanges process
+	logger.Println("Waiting 15 seconds before next scenario...")
+	time.Sleep(15 * time.Second)
+
+	// --- Scenario 2: Long Lighter, Short Extended ---
+	logger.Printf("\n--- Starting Scenario 2: Long on Lighter, Short on Extended for %s ---\n", market)
+
+	// Open positions
+	logger.Println("Placing orders to open positions...")
+	longOrder2, err := lighterEx.PlaceOrder(context.Background(), market, exchange.Buy, exchange.Market, amount, 0)
+	notifier.SendPositionNotification("TEST OPEN LONG", lighterEx.Name(), market, positionSizeUSD, err)
+	if err != nil {
+		t.Fatalf("Scenario 2: Failed to place LONG order on Lighter: %v", err)
+	}
+	logger.Printf("Scenario 2: Placed LONG order on Lighter: ID %s", longOrder2.ID)
+	logger.Printf("Scenario 2: Lighter Response: %+v", longOrder2)
+
+	shortOrder2, err := extendedEx.PlaceOrder(context.Background(), market, exchange.Sell, exchange.Market, amount, 0)
+	notifier.SendPositionNotification("TEST OPEN SHORT", extendedEx.Name(), market, positionSizeUSD, err)
+	if err != nil {
+		// Attempt to close the first leg if the second fails
+		logger.Printf("Scenario 2: Failed to place SHORT order on Extended, attempting to reverse position on Lighter...")
+		_, reverseErr := lighterEx.PlaceOrder(context.Background(), market, exchange.Sell, exchange.Market, amount, 0)
+		if reverseErr != nil {
+			logger.Printf("CRITICAL: Failed to reverse Lighter position: %v", reverseErr)
+		}
+		t.Fatalf("Scenario 2: Failed to place SHORT order on Extended: %v", err)
+	}
+	logger.Printf("Scenario 2: Placed SHORT order on Extended: ID %s", shortOrder2.ID)
+	logger.Printf("Scenario 2: Extended Response: %+v", shortOrder2)
+
+	logger.Println("Scenario 2: Positions opened successfully. Waiting 30 seconds...")
+	time.Sleep(30 * time.Second)
+
+	// Close positions
+	logger.Println("Scenario 2: Closing positions...")
+	closeLong2, err := lighterEx.ClosePosition(context.Background(), market, exchange.Buy, amount)
+	notifier.SendPositionNotification("TEST CLOSE LONG", lighterEx.Name(), market, positionSizeUSD, err)
+	if err != nil {
+		t.Errorf("Scenario 2: Failed to close position on Lighter: %v", err)
+	} else {
+		logger.Printf("Scenario 2: Position closure order placed on Lighter: ID %s", closeLong2.ID)
+		logger.Printf("Scenario 2: Lighter Close Response: %+v", closeLong2)
+	}
+
+	closeShort2, err := extendedEx.ClosePosition(context.Background(), market, exchange.Sell, amount)
+	notifier.SendPositionNotification("TEST CLOSE SHORT", extendedEx.Name(), market, positionSizeUSD, err)
+	if err != nil {
+		t.Errorf("Scenario 2: Failed to close position on Extended: %v", err)
+	} else {
+		logger.Printf("Scenario 2: Position closure order placed on Extended: ID %s", closeShort2.ID)
+		logger.Printf("Scenario 2: Extended Close Response: %+v", closeShort2)
+	}
+	logger.Println("--- Finished Scenario 2 ---")
+}
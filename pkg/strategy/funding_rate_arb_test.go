@@ -1,155 +1,1755 @@
 package strategy
 
 import (
+	"context"
+	"errors"
+	"io"
 	"log"
-	"os"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/shopspring/decimal"
+
 	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/config"
 	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/exchange"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/execution"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/export"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/journal"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/metrics"
 	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/notifications"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/state"
 )
 
-// TestArbitrageExecution is an integration test that opens and closes positions.
-// It requires a valid `example.env` file with testnet API keys.
-func TestArbitrageExecution(t *testing.T) {
-	// Load config from the project root, two directories up from the current package.
-	cfg, err := config.LoadConfig("../..")
-	if err != nil {
-		t.Fatalf("cannot load config for test. Make sure .env is present in project root: %v", err)
+func newTestStrategy(ex1, ex2 exchange.Exchange) *Strategy {
+	cfg := config.Config{
+		Markets:            []string{"BTC-USD"},
+		MinFundingRateDiff: 0.0001,
+		PositionSizeUSD:    600,
+		MaxPositionUSD:     10000,
 	}
+	return NewFundingRateArb(cfg, []exchange.Exchange{ex1, ex2}, log.New(io.Discard, "", 0), nil)
+}
+
+func TestFundingRatesOpenAndClosePosition(t *testing.T) {
+	lighter, extended := newFakeExchange("Lighter"), newFakeExchange("Extended")
+	lighter.rates = []*exchange.FundingRate{{Market: "BTC-USD", Rate: decimal.NewFromFloat(0.0005)}}
+	extended.rates = []*exchange.FundingRate{{Market: "BTC-USD", Rate: decimal.NewFromFloat(0.0001)}}
+	s := newTestStrategy(lighter, extended)
 
-	// This is a safety check to ensure this test only runs in testnet mode.
-	if !cfg.Testnet {
-		t.Skip("Skipping execution test: TESTNET is not set to true in config")
+	s.checkFundingRates()
+
+	position, ok := s.positions["BTC-USD"]
+	if !ok {
+		t.Fatal("expected a position to be opened")
+	}
+	if position.ShortExchange != lighter || position.LongExchange != extended {
+		t.Errorf("expected short on the higher rate venue, got long %s / short %s", position.LongExchange.Name(), position.ShortExchange.Name())
+	}
+	if lighter.orders[0].Side != exchange.Sell || extended.orders[0].Side != exchange.Buy {
+		t.Errorf("unexpected order sides: lighter %s, extended %s", lighter.orders[0].Side, extended.orders[0].Side)
+	}
+	if amount := extended.orders[0].Amount; !amount.Equal(decimal.NewFromFloat(0.01)) {
+		t.Errorf("expected 600 USD at a 60000 mark price to be 0.01, got %s", amount)
 	}
 
-	logger := log.New(os.Stdout, "[ARB-TEST] ", log.LstdFlags)
+	s.closeArbitrage(position)
+	if _, ok := s.positions["BTC-USD"]; ok {
+		t.Error("expected the position to be removed after closing")
+	}
+	if len(lighter.closes) != 1 || len(extended.closes) != 1 {
+		t.Errorf("expected both legs to be closed, got %d and %d", len(lighter.closes), len(extended.closes))
+	}
+}
 
-	logger.Println("Initializing exchanges for integration test...")
-	lighterEx := exchange.NewLighter(cfg.LighterAPIKey, cfg.LighterPrivateKey, true)
-	extendedEx := exchange.NewExtended(cfg.ExtendedAPIKey, cfg.ExtendedPrivateKey, cfg.ExtendedPublicKey, cfg.ExtendedVaultID, true)
+func TestStopCancelsEntriesButNotCloses(t *testing.T) {
+	lighter, extended := newFakeExchange("Lighter"), newFakeExchange("Extended")
+	lighter.rates = []*exchange.FundingRate{{Market: "BTC-USD", Rate: decimal.NewFromFloat(0.0005)}}
+	extended.rates = []*exchange.FundingRate{{Market: "BTC-USD", Rate: decimal.NewFromFloat(0.0001)}}
+	s := newTestStrategy(lighter, extended)
+	s.checkFundingRates()
+	position, ok := s.positions["BTC-USD"]
+	if !ok {
+		t.Fatal("expected a position to be opened")
+	}
 
-	notifier := notifications.NewTelegramNotifier(cfg.TelegramBotToken, cfg.TelegramChatID, logger)
-	notifier.Start()
-	defer notifier.Stop()
+	stop := make(chan struct{})
+	cancelOnStop(stop, &s.entering, s.cancel)
+	close(stop)
+	<-s.ctx.Done()
 
-	// --- Test Parameters ---
-	market := "BTC-USD" // Using a common market for the test
-	if len(cfg.Markets) > 0 && cfg.Markets[0] != "" {
-		market = cfg.Markets[0] // Use the first market from config if available
+	if longLeg, _ := s.placeLegs("ETH-USD", extended, lighter, decimal.NewFromFloat(0.1), 3000); !errors.Is(longLeg.err, context.Canceled) {
+		t.Errorf("expected new orders to be cancelled after stop, got %v", longLeg.err)
 	}
-	positionSizeUSD := cfg.PositionSizeUSD
-	if positionSizeUSD == 0 {
-		t.Skip("Skipping execution test: POSITION_SIZE_USD is not set in config")
+	s.closeArbitrage(position)
+	if len(lighter.closes) != 1 || len(extended.closes) != 1 {
+		t.Errorf("expected both legs to be closed despite the stop, got %d and %d", len(lighter.closes), len(extended.closes))
 	}
-	placeholderPrice := 60000.0 // A recent approximate price to calculate order amount
-	amount := positionSizeUSD / placeholderPrice
+}
 
-	// --- Scenario 1: Short Lighter, Long Extended ---
-	logger.Printf("\n--- Starting Scenario 1: Short on Lighter, Long on Extended for %s ---\n", market)
+func TestBestPairAcrossThreeExchanges(t *testing.T) {
+	lighter, extended, dydx := newFakeExchange("Lighter"), newFakeExchange("Extended"), newFakeExchange("Dydx")
+	lighter.rates = []*exchange.FundingRate{{Market: "BTC-USD", Rate: decimal.NewFromFloat(0.0002)}}
+	extended.rates = []*exchange.FundingRate{{Market: "BTC-USD", Rate: decimal.NewFromFloat(0.0006)}}
+	dydx.rates = []*exchange.FundingRate{{Market: "BTC-USD", Rate: decimal.NewFromFloat(-0.0001)}}
+	s := newTestStrategy(lighter, extended)
+	s.exchanges = append(s.exchanges, dydx)
 
-	// Open positions
-	logger.Println("Placing orders to open positions...")
-	shortOrder, err := lighterEx.PlaceOrder(market, exchange.Sell, exchange.Market, amount, 0) // price 0 for market order
-	notifier.SendPositionNotification("TEST OPEN SHORT", lighterEx.Name(), market, positionSizeUSD, err)
-	if err != nil {
-		t.Fatalf("Scenario 1: Failed to place SHORT order on Lighter: %v", err)
+	s.checkFundingRates()
+
+	position, ok := s.positions["BTC-USD"]
+	if !ok {
+		t.Fatal("expected a position to be opened")
+	}
+	if position.LongExchange != dydx || position.ShortExchange != extended {
+		t.Fatalf("expected long Dydx / short Extended, got long %s / short %s", position.LongExchange.Name(), position.ShortExchange.Name())
+	}
+	if math.Abs(position.EntryRateDiff-0.0007) > 1e-12 {
+		t.Errorf("entry rate diff = %f, want 0.0007", position.EntryRateDiff)
+	}
+	if len(lighter.orders) != 0 {
+		t.Errorf("expected no orders on Lighter, got %d", len(lighter.orders))
 	}
-	logger.Printf("Scenario 1: Placed SHORT order on Lighter: ID %s", shortOrder.ID)
-	logger.Printf("Scenario 1: Lighter Response: %+v", shortOrder)
 
-	longOrder, err := extendedEx.PlaceOrder(market, exchange.Buy, exchange.Market, amount, 0)
-	notifier.SendPositionNotification("TEST OPEN LONG", extendedEx.Name(), market, positionSizeUSD, err)
-	if err != nil {
-		// If the second leg fails, we should try to close the first one to avoid an open position.
-		logger.Printf("Scenario 1: Failed to place LONG order on Extended, attempting to reverse position on Lighter...")
-		_, reverseErr := lighterEx.PlaceOrder(market, exchange.Buy, exchange.Market, amount, 0)
-		if reverseErr != nil {
-			logger.Printf("CRITICAL: Failed to reverse Lighter position: %v", reverseErr)
+	// Lighter now pays the most, but the position stays open while its own pair is favorable.
+	lighter.rates = []*exchange.FundingRate{{Market: "BTC-USD", Rate: decimal.NewFromFloat(0.002)}}
+	s.marketData.StoreFundingRates("Lighter", lighter.rates)
+	s.checkFundingRates()
+	if _, ok := s.positions["BTC-USD"]; !ok {
+		t.Fatal("expected the position to stay open while Extended still pays more than Dydx")
+	}
+
+	extended.rates = []*exchange.FundingRate{{Market: "BTC-USD", Rate: decimal.NewFromFloat(-0.0002)}}
+	s.marketData.StoreFundingRates("Extended", extended.rates)
+	s.checkFundingRates()
+	if _, ok := s.positions["BTC-USD"]; ok {
+		t.Error("expected the position to close once its own pair inverted")
+	}
+	if len(extended.closes) != 1 || len(dydx.closes) != 1 || len(lighter.closes) != 0 {
+		t.Errorf("expected only the Extended and Dydx legs to close, got %d, %d and %d", len(extended.closes), len(dydx.closes), len(lighter.closes))
+	}
+}
+
+func TestFailedLegIsCompensated(t *testing.T) {
+	lighter, extended := newFakeExchange("Lighter"), newFakeExchange("Extended")
+	extended.placeErr = errors.New("API error: 503 Service Unavailable")
+	s := newTestStrategy(lighter, extended)
+
+	s.executeArbitrage("BTC-USD", lighter, extended, 0.0004, 600)
+
+	if _, ok := s.positions["BTC-USD"]; ok {
+		t.Fatal("no position should be recorded when a leg fails")
+	}
+	if len(lighter.closes) != 1 || lighter.closes[0] != exchange.Buy {
+		t.Fatalf("expected the filled long leg to be unwound, got closes %v", lighter.closes)
+	}
+	if lighter.orderCount() != 2 {
+		t.Errorf("expected an entry and an unwind order, got %d orders", lighter.orderCount())
+	}
+}
+
+func TestMaxPositionIsRespected(t *testing.T) {
+	lighter, extended := newFakeExchange("Lighter"), newFakeExchange("Extended")
+	s := newTestStrategy(lighter, extended)
+	s.config.MaxPositionUSD = 500
+
+	s.executeArbitrage("BTC-USD", lighter, extended, 0.0004, 600)
+
+	if lighter.orderCount() != 0 || extended.orderCount() != 0 {
+		t.Error("no orders should be placed when the position cap would be exceeded")
+	}
+}
+
+func TestIlliquidMarketsAreNotEntered(t *testing.T) {
+	lighter, extended := newFakeExchange("Lighter"), newFakeExchange("Extended")
+	lighter.rates = []*exchange.FundingRate{{Market: "BTC-USD", Rate: decimal.NewFromFloat(0.0005)}}
+	extended.rates = []*exchange.FundingRate{{Market: "BTC-USD", Rate: decimal.NewFromFloat(0.0001)}}
+	extended.stats = map[string]exchange.MarketStats{
+		"BTC-USD": {Market: "BTC-USD", Volume24hUSD: 50000, OpenInterestUSD: 1e6},
+	}
+	s := newTestStrategy(lighter, extended)
+	s.config.MinVolume24hUSD = 1e6
+
+	s.checkFundingRates()
+	if len(s.positions) != 0 {
+		t.Fatal("expected no position on a market below the volume floor")
+	}
+
+	s.config.MinVolume24hUSD = 10000
+	s.checkFundingRates()
+	if len(s.positions) != 1 {
+		t.Fatal("expected a position once the market meets the floor")
+	}
+}
+
+func TestThinOrderbooksBlockEntry(t *testing.T) {
+	lighter, extended := newFakeExchange("Lighter"), newFakeExchange("Extended")
+	// 600 USD of BTC buys through the 60000 ask into the 60060 one, a 10 bps move.
+	lighter.book = &exchange.Orderbook{
+		Bids: []exchange.PriceLevel{{Price: 59990, Size: 1}},
+		Asks: []exchange.PriceLevel{{Price: 60000, Size: 0.005}, {Price: 60060, Size: 1}},
+	}
+	s := newTestStrategy(lighter, extended)
+	s.config.MaxEntryImpactBps = 5
+
+	s.executeArbitrage("BTC-USD", lighter, extended, 0.0004, 600)
+	if lighter.orderCount() != 0 || extended.orderCount() != 0 {
+		t.Fatal("no orders should be placed when the long leg would move the book too far")
+	}
+	if snapshot, _ := s.marketData.Get("Lighter", "BTC-USD"); snapshot.BestBid != 59990 || snapshot.BestAsk != 60000 {
+		t.Errorf("expected the fetched book top to be cached, got bid %f ask %f", snapshot.BestBid, snapshot.BestAsk)
+	}
+
+	s.config.MaxEntryImpactBps = 15
+	s.executeArbitrage("BTC-USD", lighter, extended, 0.0004, 600)
+	if _, ok := s.positions["BTC-USD"]; !ok {
+		t.Fatal("expected a position once the impact is within the limit; venues without a book are not checked")
+	}
+}
+
+func TestMakerEntryPostsAtTopOfBook(t *testing.T) {
+	lighter, extended := newFakeExchange("Lighter"), newFakeExchange("Extended")
+	lighter.book = &exchange.Orderbook{
+		Bids: []exchange.PriceLevel{{Price: 59990, Size: 1}},
+		Asks: []exchange.PriceLevel{{Price: 60010, Size: 1}},
+	}
+	s := newTestStrategy(lighter, extended)
+	s.maker = map[string]execution.Config{"Lighter": execution.DefaultConfig}
+
+	s.executeArbitrage("BTC-USD", lighter, extended, 0.0004, 600)
+
+	if _, ok := s.positions["BTC-USD"]; !ok {
+		t.Fatal("expected a position to be opened")
+	}
+	if order := lighter.orders[0]; order.Type != exchange.Limit || !order.Price.Equal(decimal.NewFromInt(59990)) {
+		t.Errorf("expected the long leg to join the best bid with a limit order, got %+v", order)
+	}
+	if order := extended.orders[0]; order.Type != exchange.Market {
+		t.Errorf("expected the short leg to enter at market, got %+v", order)
+	}
+}
+
+func TestEntryWindowAndExitAfterFunding(t *testing.T) {
+	lighter, extended := newFakeExchange("Lighter"), newFakeExchange("Extended")
+	far := time.Now().Add(30 * time.Minute).Unix()
+	lighter.rates = []*exchange.FundingRate{{Market: "BTC-USD", Rate: decimal.NewFromFloat(0.0005), NextTime: far}}
+	extended.rates = []*exchange.FundingRate{{Market: "BTC-USD", Rate: decimal.NewFromFloat(0.0001), NextTime: far}}
+	s := newTestStrategy(lighter, extended)
+	s.config.EntryWindowMinutes = 10
+	s.config.ExitAfterFunding = true
+
+	s.checkFundingRates()
+	if _, ok := s.positions["BTC-USD"]; ok {
+		t.Fatal("no position should be opened 30 minutes before funding with a 10 minute window")
+	}
+
+	near := time.Now().Add(5 * time.Minute)
+	lighter.rates[0].NextTime = near.Unix()
+	extended.rates[0].NextTime = near.Add(time.Minute).Unix()
+	s.marketData.StoreFundingRates("Lighter", lighter.rates)
+	s.marketData.StoreFundingRates("Extended", extended.rates)
+	s.checkFundingRates()
+	position, ok := s.positions["BTC-USD"]
+	if !ok {
+		t.Fatal("expected a position to be opened within the entry window")
+	}
+	if want := time.Unix(near.Add(time.Minute).Unix(), 0).Add(fundingSettleDelay); !position.CollectAfter.Equal(want) {
+		t.Errorf("collect after = %s, want the later funding time plus the settle delay (%s)", position.CollectAfter, want)
+	}
+
+	s.checkFundingRates()
+	if _, ok := s.positions["BTC-USD"]; !ok {
+		t.Fatal("the position should be kept until funding has been collected")
+	}
+	position.CollectAfter = time.Now().Add(-time.Second)
+	s.checkFundingRates()
+	if _, ok := s.positions["BTC-USD"]; ok {
+		t.Error("expected the position to be closed once funding was collected")
+	}
+}
+
+func TestExitOnAnnualFloorAndTakeProfit(t *testing.T) {
+	lighter, extended := newFakeExchange("Lighter"), newFakeExchange("Extended")
+	lighter.rates = []*exchange.FundingRate{{Market: "BTC-USD", Rate: decimal.NewFromFloat(0.0005)}}
+	extended.rates = []*exchange.FundingRate{{Market: "BTC-USD", Rate: decimal.NewFromFloat(0.0001)}}
+	s := newTestStrategy(lighter, extended)
+	s.config.ExitMinAnnualDiff = 1 // 100% a year, about 0.000114 an hour
+
+	s.checkFundingRates()
+	if _, ok := s.positions["BTC-USD"]; !ok {
+		t.Fatal("expected a position to be opened")
+	}
+	lighter.rates = []*exchange.FundingRate{{Market: "BTC-USD", Rate: decimal.NewFromFloat(0.0002)}}
+	s.marketData.StoreFundingRates("Lighter", lighter.rates)
+	s.checkFundingRates()
+	if _, ok := s.positions["BTC-USD"]; ok {
+		t.Fatal("expected the position to be closed below the annual floor, though the spread is still positive")
+	}
+
+	// Four fills at the default 5 bps on 600 USD cost 1.20 USD; a 2x take profit needs 2.40 USD.
+	s.config.ExitMinAnnualDiff, s.config.TakeProfitMultiple = 0, 2
+	lighter.rates = []*exchange.FundingRate{{Market: "BTC-USD", Rate: decimal.NewFromFloat(0.0005)}}
+	s.marketData.StoreFundingRates("Lighter", lighter.rates)
+	s.checkFundingRates()
+	position := s.positions["BTC-USD"]
+	if position == nil {
+		t.Fatal("expected a position to be reopened")
+	}
+	if cost := s.roundTripCost(position); math.Abs(cost-1.2) > 1e-9 {
+		t.Errorf("round-trip cost = %f, want 1.20", cost)
+	}
+	paid := position.OpenedAt.Add(time.Minute)
+	lighter.payments = []exchange.FundingPayment{{Market: "BTC-USD", Time: paid, Amount: 2}}
+	s.syncFunding()
+	s.checkFundingRates()
+	if _, ok := s.positions["BTC-USD"]; !ok {
+		t.Fatal("the position was closed before the funding covered the take profit")
+	}
+	lighter.payments = append(lighter.payments, exchange.FundingPayment{Market: "BTC-USD", Time: paid.Add(time.Hour), Amount: 0.5})
+	s.syncFunding()
+	s.checkFundingRates()
+	if _, ok := s.positions["BTC-USD"]; ok {
+		t.Error("expected the position to be closed once the funding covered 2x the round-trip cost")
+	}
+}
+
+func TestLiquidationRiskDeleveragesBothLegs(t *testing.T) {
+	lighter, extended := newFakeExchange("Lighter"), newFakeExchange("Extended")
+	s := newTestStrategy(lighter, extended)
+	s.config.LiquidationAlertDistance = 0.1
+	s.executeArbitrage("BTC-USD", lighter, extended, 0.0004, 600)
+	position := s.positions["BTC-USD"]
+
+	// 5% from liquidation on the long leg: alert, but without a target don't trade.
+	lighter.risk = &exchange.PositionRisk{Market: "BTC-USD", Side: exchange.Buy, Size: 0.01, MarkPrice: 60000, LiquidationPrice: 57000}
+	s.checkLiquidationRisk()
+	if !s.liquidationAlerts["BTC-USD"] {
+		t.Error("expected an alert for a leg within the alert distance")
+	}
+	if len(lighter.closes) != 0 || len(extended.closes) != 0 {
+		t.Fatal("no legs should be reduced without DELEVERAGE_TARGET_DISTANCE")
+	}
+
+	// Restoring a 20% buffer from 5% keeps a quarter of the position.
+	s.config.DeleverageTargetDistance = 0.2
+	s.checkLiquidationRisk()
+	if len(lighter.closes) != 1 || len(extended.closes) != 1 {
+		t.Fatalf("expected both legs to be reduced, got %d and %d", len(lighter.closes), len(extended.closes))
+	}
+	if got := lighter.orders[len(lighter.orders)-1].Amount; math.Abs(got.InexactFloat64()-450.0/60000) > 1e-12 {
+		t.Errorf("reduced by %s BTC, want %f", got, 450.0/60000)
+	}
+	if math.Abs(position.SizeUSD-150) > 1e-9 {
+		t.Errorf("position size = %f, want 150", position.SizeUSD)
+	}
+
+	lighter.risk.LiquidationPrice = 40000
+	s.checkLiquidationRisk()
+	if s.liquidationAlerts["BTC-USD"] {
+		t.Error("expected the alert to clear once the leg is away from liquidation")
+	}
+}
+
+func TestInsufficientCollateralBlocksEntry(t *testing.T) {
+	lighter, extended := newFakeExchange("Lighter"), newFakeExchange("Extended")
+	extended.balance = 250
+	s := newTestStrategy(lighter, extended)
+	s.config.Leverage = 2
+
+	s.executeArbitrage("BTC-USD", lighter, extended, 0.0004, 600)
+	if lighter.orderCount() != 0 || extended.orderCount() != 0 {
+		t.Fatal("no orders should be placed when a venue can't margin its leg")
+	}
+	if !s.underfunded["BTC-USD"] {
+		t.Error("expected the shortfall to be remembered so it is notified once")
+	}
+
+	extended.balance = 300
+	s.executeArbitrage("BTC-USD", lighter, extended, 0.0004, 600)
+	if _, ok := s.positions["BTC-USD"]; !ok {
+		t.Fatal("expected a position once 300 USD covers 600 USD at 2x leverage")
+	}
+	if s.underfunded["BTC-USD"] {
+		t.Error("expected the shortfall to clear once the balances suffice")
+	}
+
+	// The open position holds Extended's 300 USD, leaving nothing for another one.
+	if err := s.checkBalances("BTC-USD", lighter, extended, 600); err == nil {
+		t.Error("expected the margin of open positions to count against the balance")
+	}
+}
+
+// noopLighterSigner lets a Lighter client read its account without signing anything.
+type noopLighterSigner struct{}
+
+func (noopLighterSigner) Sign(ctx context.Context, txType int, tx interface{}) (string, error) {
+	return "", errors.New("not signing in tests")
+}
+
+func (noopLighterSigner) AuthToken(ctx context.Context, accountIndex int64, apiKeyIndex uint8, deadline time.Time) (string, error) {
+	return "", errors.New("not signing in tests")
+}
+
+func TestBalanceCheckReadsTheLighterCollateral(t *testing.T) {
+	var collateral atomic.Value
+	collateral.Store("250")
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/account" {
+			http.NotFound(w, r)
+			return
 		}
-		t.Fatalf("Scenario 1: Failed to place LONG order on Extended: %v", err)
+		_, _ = w.Write([]byte(`{"code":200,"accounts":[{"collateral":"` + collateral.Load().(string) + `","positions":[]}]}`))
+	}))
+	defer api.Close()
+	lighter := exchange.NewLighter("", "", true)
+	lighter.SetSigner(noopLighterSigner{}, 7, 0)
+	lighter.SetTransport(rewriteHost{target: api.URL})
+	extended := newFakeExchange("Extended")
+	s := newTestStrategy(lighter, extended)
+	s.config.Leverage = 2
+
+	var insufficient *insufficientFundsError
+	if err := s.checkBalances("BTC-USD", lighter, extended, 600); !errors.As(err, &insufficient) || insufficient.exchange != "Lighter" {
+		t.Fatalf("expected 250 USD of Lighter collateral to be short of 300 USD, got %v", err)
 	}
-	logger.Printf("Scenario 1: Placed LONG order on Extended: ID %s", longOrder.ID)
-	logger.Printf("Scenario 1: Extended Response: %+v", longOrder)
+	collateral.Store("1000")
+	if err := s.checkBalances("BTC-USD", lighter, extended, 600); err != nil {
+		t.Errorf("expected 1000 USD of Lighter collateral to margin the leg, got %v", err)
+	}
+}
 
-	logger.Println("Scenario 1: Positions opened successfully. Waiting 30 seconds...")
-	time.Sleep(30 * time.Second)
+// rewriteHost sends every request to target, e.g. an httptest server standing in for a venue.
+type rewriteHost struct {
+	target string
+}
 
-	// Close positions
-	logger.Println("Scenario 1: Closing positions...")
-	closeShort, err := lighterEx.ClosePosition(market, exchange.Sell, amount)
-	notifier.SendPositionNotification("TEST CLOSE SHORT", lighterEx.Name(), market, positionSizeUSD, err)
+func (rt rewriteHost) RoundTrip(req *http.Request) (*http.Response, error) {
+	target, err := url.Parse(rt.target)
 	if err != nil {
-		t.Errorf("Scenario 1: Failed to close position on Lighter: %v", err)
+		return nil, err
+	}
+	req = req.Clone(req.Context())
+	req.URL.Scheme, req.URL.Host = target.Scheme, target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestRatesAreComparedPerHour(t *testing.T) {
+	lighter, binance := newFakeExchange("Lighter"), newFakeExchange("Binance")
+	binance.interval = 8 * time.Hour
+	// 0.0008 per 8 hours is 0.0001 per hour, below Lighter's hourly 0.00015.
+	lighter.rates = []*exchange.FundingRate{{Market: "BTC-USD", Rate: decimal.NewFromFloat(0.00015)}}
+	binance.rates = []*exchange.FundingRate{{Market: "BTC-USD", Rate: decimal.NewFromFloat(0.0008)}}
+	s := newTestStrategy(lighter, binance)
+	s.config.MinFundingRateDiff = 0.00001
+
+	s.checkFundingRates()
+	position, ok := s.positions["BTC-USD"]
+	if !ok {
+		t.Fatal("expected a position to be opened")
+	}
+	if position.ShortExchange != lighter || position.LongExchange != binance {
+		t.Errorf("expected to short the higher hourly rate on Lighter, got long %s / short %s", position.LongExchange.Name(), position.ShortExchange.Name())
+	}
+	if math.Abs(position.EntryRateDiff-0.00005) > 1e-12 {
+		t.Errorf("entry rate diff = %f, want the hourly 0.00005", position.EntryRateDiff)
+	}
+}
+
+func TestUnsupportedMarginModeBlocksEntry(t *testing.T) {
+	lighter, extended := newFakeExchange("Lighter"), newFakeExchange("Extended")
+	s := newTestStrategy(lighter, extended)
+	s.margin = newMarginSelector(config.Config{MarginModes: []string{"Lighter=isolated"}}, s.logger)
+
+	s.executeArbitrage("BTC-USD", lighter, extended, 0.0004, 600)
+
+	if lighter.orderCount() != 0 || extended.orderCount() != 0 {
+		t.Error("no orders should be placed when a venue can't select the configured margin mode")
+	}
+}
+
+// leveragedExchange is a fakeExchange that records the leverage set per market.
+type leveragedExchange struct {
+	*fakeExchange
+	leverage map[string]float64
+}
+
+func (l *leveragedExchange) SetLeverage(ctx context.Context, market string, leverage float64) error {
+	l.leverage[market] = leverage
+	return nil
+}
+
+func TestLeverageIsSetBeforeTrading(t *testing.T) {
+	lighter := &leveragedExchange{fakeExchange: newFakeExchange("Lighter"), leverage: make(map[string]float64)}
+	extended := newFakeExchange("Extended")
+	// 150 USD margins 600 USD at the 5x configured for Lighter, not at the 2x default.
+	lighter.balance = 150
+	s := newTestStrategy(lighter, extended)
+	s.config.Leverage = 2
+	s.margin = newMarginSelector(config.Config{SetLeverage: true, Leverage: 2, Leverages: []string{"Lighter:BTC-USD=5"}}, s.logger)
+
+	s.executeArbitrage("BTC-USD", lighter, extended, 0.0004, 600)
+	if _, ok := s.positions["BTC-USD"]; !ok {
+		t.Fatal("expected a position, Extended keeping its default leverage")
+	}
+	if lighter.leverage["BTC-USD"] != 5 {
+		t.Errorf("Lighter leverage = %g, want the 5x override", lighter.leverage["BTC-USD"])
+	}
+
+	s.executeArbitrage("ETH-USD", lighter, extended, 0.0004, 50)
+	if lighter.leverage["ETH-USD"] != 2 {
+		t.Errorf("Lighter ETH-USD leverage = %g, want LEVERAGE 2", lighter.leverage["ETH-USD"])
+	}
+
+	// A leverage listed for a venue that can't set it blocks the entry.
+	lighter.balance = 1e6
+	s.margin = newMarginSelector(config.Config{Leverages: []string{"Extended=3"}}, s.logger)
+	orders := extended.orderCount()
+	s.executeArbitrage("SOL-USD", lighter, extended, 0.0004, 100)
+	if extended.orderCount() != orders {
+		t.Error("no orders should be placed when a venue can't set the configured leverage")
+	}
+}
+
+// infoExchange is a fakeExchange that lists the trading rules of its markets.
+type infoExchange struct {
+	*fakeExchange
+	info  exchange.MarketInfo
+	calls int
+}
+
+func (e *infoExchange) GetMarketInfo(ctx context.Context, market string) (*exchange.MarketInfo, error) {
+	e.calls++
+	info := e.info
+	info.Market = market
+	return &info, nil
+}
+
+func TestLegsAreRoundedToBothLotSizes(t *testing.T) {
+	lighter := &infoExchange{fakeExchange: newFakeExchange("Lighter"), info: exchange.MarketInfo{LotSize: decimal.RequireFromString("0.003")}}
+	extended := &infoExchange{fakeExchange: newFakeExchange("Extended"), info: exchange.MarketInfo{LotSize: decimal.RequireFromString("0.002")}}
+	s := newTestStrategy(lighter, extended)
+
+	s.executeArbitrage("ETH-USD", lighter, extended, 0.0004, 300)
+	if lighter.orderCount() != 0 || extended.orderCount() != 0 {
+		t.Fatal("no orders should be placed below one lot both venues accept")
+	}
+
+	// 1000 USD at 60000 is 0.01666…, rounded down to a multiple of 0.006.
+	s.executeArbitrage("BTC-USD", lighter, extended, 0.0004, 1000)
+	if !lighter.orders[0].Amount.Equal(decimal.NewFromFloat(0.012)) || !extended.orders[0].Amount.Equal(decimal.NewFromFloat(0.012)) {
+		t.Fatalf("expected both legs to trade 0.012, got %s and %s", lighter.orders[0].Amount, extended.orders[0].Amount)
+	}
+
+	// The close trades the amount opened, whatever the price has done since.
+	lighter.price, extended.price = 50000, 50000
+	s.closeArbitrage(s.positions["BTC-USD"])
+	if !lighter.orders[1].Amount.Equal(decimal.NewFromFloat(0.012)) || !extended.orders[1].Amount.Equal(decimal.NewFromFloat(0.012)) {
+		t.Errorf("expected both closes to trade 0.012, got %s and %s", lighter.orders[1].Amount, extended.orders[1].Amount)
+	}
+}
+
+func TestOrdersAreValidatedAgainstMarketInfo(t *testing.T) {
+	lighter := &infoExchange{fakeExchange: newFakeExchange("Lighter"), info: exchange.MarketInfo{
+		TickSize: decimal.RequireFromString("0.5"), LotSize: decimal.RequireFromString("0.001"), MinNotional: 500,
+	}}
+	extended := &infoExchange{fakeExchange: newFakeExchange("Extended"), info: exchange.MarketInfo{
+		LotSize: decimal.RequireFromString("0.001"), MinSize: decimal.RequireFromString("0.01"), MaxLeverage: 20,
+	}}
+	lighter.price, extended.price = 60000.3, 60000.3
+	s := newTestStrategy(lighter, extended)
+
+	// 420 USD at 60000.3 rounds down to 0.006: below Lighter's minimum value and Extended's minimum size.
+	s.executeArbitrage("BTC-USD", lighter, extended, 0.0004, 420)
+	if lighter.orderCount() != 0 || extended.orderCount() != 0 {
+		t.Fatal("no orders should be placed below either venue's minimum")
+	}
+
+	s.config.Leverage = 25
+	s.executeArbitrage("BTC-USD", lighter, extended, 0.0004, 1200)
+	if lighter.orderCount() != 0 || extended.orderCount() != 0 {
+		t.Fatal("no orders should be placed above a venue's maximum leverage")
+	}
+
+	s.config.Leverage = 10
+	s.executeArbitrage("BTC-USD", lighter, extended, 0.0004, 1200)
+	if lighter.orderCount() != 1 || extended.orderCount() != 1 {
+		t.Fatalf("expected both legs to be placed, got %d and %d orders", lighter.orderCount(), extended.orderCount())
+	}
+	if price := lighter.orders[0].Price; !price.Equal(decimal.NewFromFloat(60000.5)) {
+		t.Errorf("expected the Lighter order to be priced on its 0.5 tick, got %v", price)
+	}
+	// The rules are fetched once and reused within MARKET_INFO_TTL_MINUTES.
+	if lighter.calls != 1 || extended.calls != 1 {
+		t.Errorf("expected the rules to be fetched once per venue, got %d and %d", lighter.calls, extended.calls)
+	}
+}
+
+func TestOrdersAreSizedFromMarkPrices(t *testing.T) {
+	lighter, extended := newFakeExchange("Lighter"), newFakeExchange("Extended")
+	lighter.price, extended.price = 2990, 3010
+	s := newTestStrategy(lighter, extended)
+
+	s.executeArbitrage("SOL-USD", lighter, extended, 0.0004, 600)
+
+	if amount := lighter.orders[0].Amount; !amount.Equal(decimal.NewFromFloat(0.2)) {
+		t.Errorf("expected 600 USD at the mean mark price of 3000 to be 0.2, got %s", amount)
+	}
+
+	lighter.price, extended.price = 0, 0
+	unpriced := &PositionInfo{Market: "AVAX-USD", LongExchange: lighter, ShortExchange: extended, SizeUSD: 600}
+	s.positions["AVAX-USD"] = unpriced
+	s.closeArbitrage(unpriced)
+	if _, ok := s.positions["AVAX-USD"]; !ok {
+		t.Error("expected the position to stay open when it can't be priced")
+	}
+}
+
+func TestSlowVenueIsLeftOutOfTheCheck(t *testing.T) {
+	lighter, extended, binance := newFakeExchange("Lighter"), newFakeExchange("Extended"), newFakeExchange("Binance")
+	lighter.rates = []*exchange.FundingRate{{Market: "BTC-USD", Rate: decimal.NewFromFloat(0.0005)}}
+	extended.rates = []*exchange.FundingRate{{Market: "BTC-USD", Rate: decimal.NewFromFloat(0.0001)}}
+	binance.rates = []*exchange.FundingRate{{Market: "BTC-USD", Rate: decimal.NewFromFloat(0.0009)}}
+	binance.ratesDelay = 5 * time.Second
+	cfg := config.Config{Markets: []string{"BTC-USD"}, MinFundingRateDiff: 0.0001, PositionSizeUSD: 600, MaxPositionUSD: 10000, FundingFetchTimeoutSeconds: 0.05}
+	s := NewFundingRateArb(cfg, []exchange.Exchange{binance, lighter, extended}, log.New(io.Discard, "", 0), nil)
+
+	start := time.Now()
+	s.checkFundingRates()
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("expected the check not to wait for the slow venue, took %s", elapsed)
+	}
+	position, ok := s.positions["BTC-USD"]
+	if !ok || position.LongExchange != extended || position.ShortExchange != lighter {
+		t.Fatalf("expected a position between the venues that answered, got %+v", position)
+	}
+	if status := s.Status(); !strings.Contains(status, "Stale funding rates") || !strings.Contains(status, "Binance (never fetched: no answer within 50ms)") {
+		t.Errorf("expected Binance to be flagged stale, got:\n%s", status)
+	}
+}
+
+func TestRollbackRetriesAndEscalates(t *testing.T) {
+	lighter, extended := newFakeExchange("Lighter"), newFakeExchange("Extended")
+	extended.placeErr = errors.New("API error: 503 Service Unavailable")
+	lighter.closeFailures = 2
+	s := newTestStrategy(lighter, extended)
+	s.rollback = rollbackPolicy{attempts: 3, delay: time.Millisecond}
+
+	s.executeArbitrage("BTC-USD", lighter, extended, 0.0004, 600)
+	if len(lighter.closes) != 1 || s.paused {
+		t.Fatalf("expected the leg to be rolled back on the third attempt, got %d closes (paused %v)", len(lighter.closes), s.paused)
+	}
+
+	lighter.closeErr = errors.New("API error: 502 Bad Gateway")
+	s.executeArbitrage("BTC-USD", lighter, extended, 0.0004, 600)
+	if !s.paused {
+		t.Error("expected new entries to be paused when the rollback fails")
+	}
+}
+
+func TestPlaceLegsReportsEachLeg(t *testing.T) {
+	lighter, extended := newFakeExchange("Lighter"), newFakeExchange("Extended")
+	extended.placeErr = errors.New("API error: 503 Service Unavailable")
+	s := newTestStrategy(lighter, extended)
+
+	amount := decimal.NewFromFloat(0.01)
+	longLeg, shortLeg := s.placeLegs("BTC-USD", lighter, extended, amount, 60000)
+	if longLeg.err != nil || longLeg.order == nil {
+		t.Fatalf("expected the long leg to be placed, got %v", longLeg.err)
+	}
+	if longLeg.order.Side != exchange.Buy || !longLeg.order.Amount.Equal(amount) {
+		t.Errorf("expected a buy of %s on the long venue, got %s %s", amount, longLeg.order.Side, longLeg.order.Amount)
+	}
+	if shortLeg.err == nil || shortLeg.order != nil {
+		t.Errorf("expected the short leg to fail, got %+v", shortLeg)
+	}
+	if lighter.orderCount() != 1 || extended.orderCount() != 0 {
+		t.Errorf("expected one order on the long venue only, got %d and %d", lighter.orderCount(), extended.orderCount())
+	}
+}
+
+func TestCompensateLegsUnwindsOnlyTheFilledLeg(t *testing.T) {
+	lighter, extended := newFakeExchange("Lighter"), newFakeExchange("Extended")
+	s := newTestStrategy(lighter, extended)
+	amount := decimal.NewFromFloat(0.01)
+	failed := legResult{err: errors.New("API error: 503 Service Unavailable")}
+
+	short := legResult{order: &exchange.Order{ID: "short-1", Side: exchange.Sell, Amount: amount, Filled: amount}}
+	s.compensateLegs("BTC-USD", lighter, extended, failed, short, amount, 600)
+	if len(extended.closes) != 1 || extended.closes[0] != exchange.Sell || len(lighter.closes) != 0 {
+		t.Fatalf("expected the filled short leg to be unwound, got closes %v and %v", lighter.closes, extended.closes)
+	}
+
+	s.compensateLegs("BTC-USD", lighter, extended, failed, failed, amount, 600)
+	if len(extended.closes) != 1 || len(lighter.closes) != 0 {
+		t.Errorf("expected nothing to unwind when both legs failed, got closes %v and %v", lighter.closes, extended.closes)
+	}
+}
+
+func TestRollbackDoesNotHoldTheLock(t *testing.T) {
+	lighter, extended := newFakeExchange("Lighter"), newFakeExchange("Extended")
+	extended.placeErr = errors.New("API error: 503 Service Unavailable")
+	lighter.closeFailures = 2
+	s := newTestStrategy(lighter, extended)
+	s.rollback = rollbackPolicy{attempts: 3, delay: 100 * time.Millisecond}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.executeArbitrage("BTC-USD", lighter, extended, 0.0004, 600)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	locked := make(chan struct{})
+	go func() {
+		s.mu.Lock()
+		s.mu.Unlock()
+		close(locked)
+	}()
+	select {
+	case <-locked:
+	case <-done:
+		t.Fatal("expected the rollback to still be retrying")
+	case <-time.After(50 * time.Millisecond):
+		t.Error("expected s.mu to be free while the rollback waits to retry")
+	}
+	<-done
+	if len(lighter.closes) != 1 {
+		t.Errorf("expected the leg to be rolled back on the third attempt, got %d closes", len(lighter.closes))
+	}
+}
+
+func TestPositionsSurviveRestart(t *testing.T) {
+	lighter, extended := newFakeExchange("Lighter"), newFakeExchange("Extended")
+	store := state.Open(filepath.Join(t.TempDir(), "state.json"))
+	s := newTestStrategy(lighter, extended)
+	s.SetStateStore(store)
+	s.executeArbitrage("BTC-USD", extended, lighter, 0.0004, 600)
+
+	restarted := newTestStrategy(lighter, extended)
+	restarted.SetStateStore(store)
+	restarted.restorePositions()
+	position, ok := restarted.positions["BTC-USD"]
+	if !ok {
+		t.Fatal("expected the open position to be restored")
+	}
+	if position.LongExchange != extended || position.ShortExchange != lighter || position.EntryRateDiff != 0.0004 {
+		t.Errorf("unexpected restored position %+v", position)
+	}
+
+	restarted.closeArbitrage(position)
+	if saved, _ := store.Load(DefaultName); len(saved) != 0 {
+		t.Errorf("expected closed positions to be removed from the state, got %+v", saved)
+	}
+}
+
+func TestReconcileFlagsWithoutRepair(t *testing.T) {
+	lighter, extended := newFakeExchange("Lighter"), newFakeExchange("Extended")
+	s := newTestStrategy(lighter, extended)
+	s.positions["BTC-USD"] = &PositionInfo{Market: "BTC-USD", LongExchange: extended, ShortExchange: lighter, SizeUSD: 600}
+	extended.held = []exchange.Position{{Market: "BTC-USD", Side: exchange.Buy, Size: 0.01}}
+
+	s.reconcile()
+	if _, ok := s.positions["BTC-USD"]; !ok {
+		t.Error("expected the mismatched position to be kept when repair is disabled")
+	}
+	if len(extended.closes) != 0 {
+		t.Errorf("expected no orders when repair is disabled, got closes %v", extended.closes)
+	}
+}
+
+func TestReconcileRepairsOrphansAndAdoptsUnknownPairs(t *testing.T) {
+	lighter, extended := newFakeExchange("Lighter"), newFakeExchange("Extended")
+	s := newTestStrategy(lighter, extended)
+	s.config.ReconcileRepair = true
+	s.positions["BTC-USD"] = &PositionInfo{Market: "BTC-USD", LongExchange: extended, ShortExchange: lighter, SizeUSD: 600}
+	s.positions["ETH-USD"] = &PositionInfo{Market: "ETH-USD", LongExchange: extended, ShortExchange: lighter, SizeUSD: 600}
+	s.positions["SOL-USD"] = &PositionInfo{Market: "SOL-USD", LongExchange: lighter, ShortExchange: extended, SizeUSD: 600}
+	extended.held = []exchange.Position{
+		{Market: "BTC-USD", Side: exchange.Buy, Size: 0.01},
+		{Market: "SOL-USD", Side: exchange.Sell, Size: 4},
+		{Market: "AVAX-USD", Side: exchange.Sell, Size: 20},
+	}
+	lighter.held = []exchange.Position{
+		{Market: "SOL-USD", Side: exchange.Buy, Size: 4},
+		{Market: "AVAX-USD", Side: exchange.Buy, Size: 20},
+	}
+
+	s.reconcile()
+	if _, ok := s.positions["BTC-USD"]; ok || len(extended.closes) != 1 || extended.closes[0] != exchange.Buy {
+		t.Errorf("expected the orphaned BTC-USD long to be closed and forgotten, closes %v", extended.closes)
+	}
+	if _, ok := s.positions["ETH-USD"]; ok {
+		t.Error("expected the ETH-USD position that is open nowhere to be forgotten")
+	}
+	if _, ok := s.positions["SOL-USD"]; !ok {
+		t.Error("expected the matching SOL-USD position to be kept")
+	}
+	adopted, ok := s.positions["AVAX-USD"]
+	if !ok || adopted.LongExchange != lighter || adopted.ShortExchange != extended || adopted.SizeUSD <= 0 {
+		t.Errorf("expected the unknown AVAX-USD pair to be adopted long Lighter, short Extended, got %+v", adopted)
+	}
+}
+
+// lockProbe reports whether s.mu was held while its mark price was read.
+type lockProbe struct {
+	*fakeExchange
+	s      *Strategy
+	locked atomic.Bool
+}
+
+func (p *lockProbe) GetMarkPrice(ctx context.Context, market string) (float64, error) {
+	if !p.s.mu.TryLock() {
+		p.locked.Store(true)
 	} else {
-		logger.Printf("Scenario 1: Position closure order placed on Lighter: ID %s", closeShort.ID)
-		logger.Printf("Scenario 1: Lighter Close Response: %+v", closeShort)
+		p.s.mu.Unlock()
 	}
+	return p.fakeExchange.GetMarkPrice(ctx, market)
+}
 
-	closeLong, err := extendedEx.ClosePosition(market, exchange.Buy, amount)
-	notifier.SendPositionNotification("TEST CLOSE LONG", extendedEx.Name(), market, positionSizeUSD, err)
+func TestReconcilePricesAdoptedPairsWithoutTheLock(t *testing.T) {
+	lighter := &lockProbe{fakeExchange: newFakeExchange("Lighter")}
+	extended := newFakeExchange("Extended")
+	s := newTestStrategy(lighter, extended)
+	lighter.s = s
+	s.config.ReconcileRepair = true
+	lighter.held = []exchange.Position{{Market: "AVAX-USD", Side: exchange.Buy, Size: 20}}
+	extended.held = []exchange.Position{{Market: "AVAX-USD", Side: exchange.Sell, Size: 20}}
+
+	s.reconcile()
+	if adopted, ok := s.positions["AVAX-USD"]; !ok || adopted.SizeUSD != 20*60000 {
+		t.Fatalf("expected the pair to be adopted at the mark price, got %+v", adopted)
+	}
+	if lighter.locked.Load() {
+		t.Error("expected s.mu to be free while the adopted pair is priced")
+	}
+}
+
+func TestFundingAccrualIsEstimatedForOpenPositions(t *testing.T) {
+	lighter, extended := newFakeExchange("Lighter"), newFakeExchange("Extended")
+	s := newTestStrategy(lighter, extended)
+	m := metrics.New()
+	s.SetMetrics(m)
+	now := time.Now()
+	s.positions["BTC-USD"] = &PositionInfo{Market: "BTC-USD", LongExchange: extended, ShortExchange: lighter, SizeUSD: 600,
+		OpenedAt: now.Add(-2 * exchange.DefaultFundingInterval)}
+	rates := map[string]map[string]float64{"Lighter": {"BTC-USD": 0.0005}, "Extended": {"BTC-USD": 0.0001}}
+
+	s.accrueFunding(rates, now)
+	// The same check must not be counted twice.
+	s.accrueFunding(rates, now)
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	var earned float64
+	for _, line := range strings.Split(rec.Body.String(), "\n") {
+		if value, ok := strings.CutPrefix(line, `arb_bot_funding_pnl_usd{market="BTC-USD"} `); ok {
+			earned, _ = strconv.ParseFloat(value, 64)
+		}
+	}
+	// Two intervals of a 0.0004 spread on 600 USD.
+	if math.Abs(earned-0.48) > 1e-9 {
+		t.Errorf("estimated funding = %f, want 0.48", earned)
+	}
+}
+
+func TestOrdersFillsClosesAndErrorsAreJournaled(t *testing.T) {
+	lighter, extended := newFakeExchange("Lighter"), newFakeExchange("Extended")
+	lighter.rates = []*exchange.FundingRate{{Market: "BTC-USD", Rate: decimal.NewFromFloat(0.0005)}}
+	extended.rates = []*exchange.FundingRate{{Market: "BTC-USD", Rate: decimal.NewFromFloat(0.0001)}}
+	s := newTestStrategy(lighter, extended)
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+	j, err := journal.Open(path)
 	if err != nil {
-		t.Errorf("Scenario 1: Failed to close position on Extended: %v", err)
-	} else {
-		logger.Printf("Scenario 1: Position closure order placed on Extended: ID %s", closeLong.ID)
-		logger.Printf("Scenario 1: Extended Close Response: %+v", closeLong)
+		t.Fatal(err)
 	}
-	logger.Println("--- Finished Scenario 1 ---")
+	s.SetJournal(j)
 
-	// Pause between scenarios to let exchanges process
-	logger.Println("Waiting 15 seconds before next scenario...")
-	time.Sleep(15 * time.Second)
+	s.checkFundingRates()
+	s.closeArbitrage(s.positions["BTC-USD"])
+	extended.placeErr = errors.New("insufficient margin")
+	s.checkFundingRates()
+	j.Close()
 
-	// --- Scenario 2: Long Lighter, Short Extended ---
-	logger.Printf("\n--- Starting Scenario 2: Long on Lighter, Short on Extended for %s ---\n", market)
+	entries, err := journal.ReadAll(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := make(map[journal.EntryType]int)
+	for _, e := range entries {
+		counts[e.Type]++
+	}
+	// Two opening and two closing orders, then two more opening orders and the unwind of the
+	// Lighter leg after the Extended leg is rejected.
+	want := map[journal.EntryType]int{journal.EntryOrder: 7, journal.EntryFill: 6, journal.EntryClose: 1, journal.EntryError: 1}
+	for typ, n := range want {
+		if counts[typ] != n {
+			t.Errorf("journaled %d %s entries, want %d (all: %v)", counts[typ], typ, n, counts)
+		}
+	}
+}
 
-	// Open positions
-	logger.Println("Placing orders to open positions...")
-	longOrder2, err := lighterEx.PlaceOrder(market, exchange.Buy, exchange.Market, amount, 0)
-	notifier.SendPositionNotification("TEST OPEN LONG", lighterEx.Name(), market, positionSizeUSD, err)
+func TestForcedClosesCloseTheSurvivingLeg(t *testing.T) {
+	lighter, extended := newFakeExchange("Lighter"), newFakeExchange("Extended")
+	lighter.rates = []*exchange.FundingRate{{Market: "BTC-USD", Rate: decimal.NewFromFloat(0.0005)}}
+	extended.rates = []*exchange.FundingRate{{Market: "BTC-USD", Rate: decimal.NewFromFloat(0.0001)}}
+	s := newTestStrategy(lighter, extended)
+	notifier := messageNotifier{Notifier: notifications.Discard, messages: make(chan string, 10)}
+	s.notifier = notifier
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+	j, err := journal.Open(path)
 	if err != nil {
-		t.Fatalf("Scenario 2: Failed to place LONG order on Lighter: %v", err)
+		t.Fatal(err)
 	}
-	logger.Printf("Scenario 2: Placed LONG order on Lighter: ID %s", longOrder2.ID)
-	logger.Printf("Scenario 2: Lighter Response: %+v", longOrder2)
+	s.SetJournal(j)
 
-	shortOrder2, err := extendedEx.PlaceOrder(market, exchange.Sell, exchange.Market, amount, 0)
-	notifier.SendPositionNotification("TEST OPEN SHORT", extendedEx.Name(), market, positionSizeUSD, err)
+	// The venue liquidates the long leg on Extended, which its trade history reports.
+	s.checkFundingRates()
+	lighter.held = []exchange.Position{{Market: "BTC-USD", Side: exchange.Sell, Size: 0.01}}
+	extended.held = []exchange.Position{{Market: "BTC-USD", Side: exchange.Buy, Size: 0.01}}
+	s.checkForcedCloses()
+	if len(s.positions) != 1 {
+		t.Fatal("a hedged position was force-closed")
+	}
+	extended.forced = []exchange.ForcedClose{{Market: "BTC-USD", Kind: exchange.Liquidation, Side: exchange.Buy, Amount: 0.01, Price: 57000, Time: time.Now()}}
+	extended.held = nil
+	s.checkForcedCloses()
+	if len(s.positions) != 0 || len(lighter.closes) != 1 || lighter.closes[0] != exchange.Sell || len(extended.closes) != 0 {
+		t.Fatalf("expected only the surviving short leg closed, positions %d, closes %v and %v", len(s.positions), lighter.closes, extended.closes)
+	}
+	if message := <-notifier.messages; !strings.Contains(message, "FORCED CLOSE") || !strings.Contains(message, "LIQUIDATION") {
+		t.Errorf("unexpected alert %q", message)
+	}
+
+	// A venue without trade history loses the short leg: it is closed once the next check
+	// confirms the leg is gone.
+	extended.forced = nil
+	s.closedAt = make(map[string]time.Time)
+	lighter.closes = nil
+	s.checkFundingRates()
+	lighter.held = nil
+	extended.held = []exchange.Position{{Market: "BTC-USD", Side: exchange.Buy, Size: 0.01}}
+	s.checkForcedCloses()
+	if len(s.positions) != 1 {
+		t.Fatal("a missing leg was acted on before it was confirmed")
+	}
+	s.checkForcedCloses()
+	if len(s.positions) != 0 || len(extended.closes) != 1 || extended.closes[0] != exchange.Buy || len(lighter.closes) != 0 {
+		t.Fatalf("expected only the surviving long leg closed, positions %d, closes %v and %v", len(s.positions), lighter.closes, extended.closes)
+	}
+	j.Close()
+
+	entries, err := journal.ReadAll(path)
 	if err != nil {
-		// Attempt to close the first leg if the second fails
-		logger.Printf("Scenario 2: Failed to place SHORT order on Extended, attempting to reverse position on Lighter...")
-		_, reverseErr := lighterEx.PlaceOrder(market, exchange.Sell, exchange.Market, amount, 0)
-		if reverseErr != nil {
-			logger.Printf("CRITICAL: Failed to reverse Lighter position: %v", reverseErr)
+		t.Fatal(err)
+	}
+	forced, liquidations := 0, 0
+	for _, e := range entries {
+		if e.Type == journal.EntryForcedClose {
+			forced++
+		}
+		if e.Type == journal.EntryFill && e.Message == string(exchange.Liquidation) {
+			liquidations++
 		}
-		t.Fatalf("Scenario 2: Failed to place SHORT order on Extended: %v", err)
 	}
-	logger.Printf("Scenario 2: Placed SHORT order on Extended: ID %s", shortOrder2.ID)
-	logger.Printf("Scenario 2: Extended Response: %+v", shortOrder2)
+	if forced != 2 || liquidations != 1 {
+		t.Errorf("journaled %d forced closes and %d liquidation fills, want 2 and 1", forced, liquidations)
+	}
+}
+
+func TestFundingPaymentsAreSyncedJournaledAndRealized(t *testing.T) {
+	lighter, extended := newFakeExchange("Lighter"), newFakeExchange("Extended")
+	lighter.rates = []*exchange.FundingRate{{Market: "BTC-USD", Rate: decimal.NewFromFloat(0.0005)}}
+	extended.rates = []*exchange.FundingRate{{Market: "BTC-USD", Rate: decimal.NewFromFloat(0.0001)}}
+	s := newTestStrategy(lighter, extended)
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+	j, err := journal.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetJournal(j)
 
-	logger.Println("Scenario 2: Positions opened successfully. Waiting 30 seconds...")
-	time.Sleep(30 * time.Second)
+	s.checkFundingRates()
+	position := s.positions["BTC-USD"]
+	paid := position.OpenedAt.Add(time.Minute)
+	lighter.payments = []exchange.FundingPayment{{Market: "BTC-USD", Time: paid, Amount: 0.3}}
+	extended.payments = []exchange.FundingPayment{{Market: "BTC-USD", Time: paid, Amount: -0.06}, {Market: "BTC-USD", Time: position.OpenedAt.Add(-time.Hour), Amount: -5}}
 
-	// Close positions
-	logger.Println("Scenario 2: Closing positions...")
-	closeLong2, err := lighterEx.ClosePosition(market, exchange.Buy, amount)
-	notifier.SendPositionNotification("TEST CLOSE LONG", lighterEx.Name(), market, positionSizeUSD, err)
+	s.syncFunding()
+	s.syncFunding()
+	if math.Abs(position.Funding-0.24) > 1e-9 {
+		t.Errorf("Funding = %f, want 0.24 from the payments since the position opened", position.Funding)
+	}
+	if summary := s.PnL(); len(summary.Open) != 1 || math.Abs(summary.Unrealized()-0.24) > 1e-9 {
+		t.Errorf("unexpected PnL summary %+v", summary)
+	}
+
+	s.closeArbitrage(position)
+	if summary := s.PnL(); summary.Closed != 1 || math.Abs(summary.Realized()-0.24) > 1e-9 || len(summary.Open) != 0 {
+		t.Errorf("expected the funding to be realized on close, got %+v", summary)
+	}
+	j.Close()
+
+	entries, err := journal.ReadAll(path)
 	if err != nil {
-		t.Errorf("Scenario 2: Failed to close position on Lighter: %v", err)
-	} else {
-		logger.Printf("Scenario 2: Position closure order placed on Lighter: ID %s", closeLong2.ID)
-		logger.Printf("Scenario 2: Lighter Close Response: %+v", closeLong2)
+		t.Fatal(err)
+	}
+	funding := 0
+	for _, e := range entries {
+		if e.Type == journal.EntryFunding {
+			funding++
+		}
 	}
+	if funding != 2 {
+		t.Errorf("journaled %d funding payments, want each of the 2 payments once", funding)
+	}
+}
+
+func TestSummaryCoversActivitySinceTheLastOne(t *testing.T) {
+	lighter, extended := newFakeExchange("Lighter"), newFakeExchange("Extended")
+	lighter.rates = []*exchange.FundingRate{{Market: "BTC-USD", Rate: decimal.NewFromFloat(0.0005)}}
+	extended.rates = []*exchange.FundingRate{{Market: "BTC-USD", Rate: decimal.NewFromFloat(0.0001)}}
+	s := newTestStrategy(lighter, extended)
+	start := time.Now()
 
-	closeShort2, err := extendedEx.ClosePosition(market, exchange.Sell, amount)
-	notifier.SendPositionNotification("TEST CLOSE SHORT", extendedEx.Name(), market, positionSizeUSD, err)
+	s.checkFundingRates()
+	position := s.positions["BTC-USD"]
+	lighter.payments = []exchange.FundingPayment{{Market: "BTC-USD", Time: position.OpenedAt.Add(time.Minute), Amount: 0.3}}
+	s.syncFunding()
+	s.closeArbitrage(position)
+	extended.placeErr = errors.New("insufficient margin")
+	s.checkFundingRates()
+
+	a := s.activity.drain(start, time.Now())
+	if a.Opened != 1 || a.Closed != 1 || math.Abs(a.Funding-0.3) > 1e-9 || len(a.Errors) != 1 || !a.Since.Equal(start) {
+		t.Errorf("unexpected activity %+v", a)
+	}
+	text := formatSummary(a, 0, 0)
+	for _, want := range []string{"Funding collected: +0.30 USD", "Positions opened: 1, closed: 1", "Errors: 1", "insufficient margin"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("summary is missing %q:\n%s", want, text)
+		}
+	}
+	if a := s.activity.drain(start, time.Now()); a.Opened != 0 || len(a.Errors) != 0 || a.Since.Equal(start) {
+		t.Errorf("expected the next summary to start empty, got %+v", a)
+	}
+}
+
+func TestDailySheetIsWrittenWithoutASummaryCron(t *testing.T) {
+	s := newTestStrategy(newFakeExchange("Lighter"), newFakeExchange("Extended"))
+	if _, ok := s.summarySchedule(); ok {
+		t.Error("expected no summary without DAILY_SUMMARY_CRON or a Sheets exporter")
+	}
+	s.SetSheetsExporter(&export.SheetsExporter{})
+	schedule, ok := s.summarySchedule()
+	if !ok {
+		t.Fatal("expected a daily summary for the Daily sheet")
+	}
+	from := time.Date(2024, 3, 15, 8, 30, 0, 0, time.UTC)
+	if next := schedule.Next(from); !next.Equal(time.Date(2024, 3, 16, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("summary exported at %s, want midnight UTC", next)
+	}
+}
+
+func TestCircuitBreakerBlocksAFailingVenueUntilAProbeSucceeds(t *testing.T) {
+	lighter, extended, dydx := newFakeExchange("Lighter"), newFakeExchange("Extended"), newFakeExchange("Dydx")
+	lighter.rates = []*exchange.FundingRate{{Market: "BTC-USD", Rate: decimal.NewFromFloat(0.0005)}}
+	extended.rates = []*exchange.FundingRate{{Market: "BTC-USD", Rate: decimal.NewFromFloat(0.0001)}}
+	dydx.rates = []*exchange.FundingRate{{Market: "BTC-USD", Rate: decimal.NewFromFloat(0.001)}}
+	dydx.ratesErr = errors.New("502 Bad Gateway")
+	cfg := config.Config{Markets: []string{"BTC-USD"}, MinFundingRateDiff: 0.0001, PositionSizeUSD: 600, MaxPositionUSD: 10000, CircuitBreakerFailures: 2}
+	s := NewFundingRateArb(cfg, []exchange.Exchange{lighter, extended, dydx}, log.New(io.Discard, "", 0), nil)
+
+	s.checkFundingRates()
+	if s.breaker.isOpen("Dydx") {
+		t.Fatal("the breaker opened after a single failure")
+	}
+	s.closeArbitrage(s.positions["BTC-USD"])
+	s.checkFundingRates()
+	if !s.breaker.isOpen("Dydx") || !strings.Contains(s.Status(), "no new positions on: Dydx") {
+		t.Fatalf("expected the breaker to open after 2 failures, status:\n%s", s.Status())
+	}
+	s.closeArbitrage(s.positions["BTC-USD"])
+
+	// Dydx answers again, but new positions avoid it until a probe succeeds.
+	dydx.ratesErr = nil
+	s.checkFundingRates()
+	if p := s.positions["BTC-USD"]; p == nil || p.ShortExchange != lighter {
+		t.Fatalf("expected a position avoiding Dydx, got %+v", p)
+	}
+	s.executeArbitrage("BTC-USD", extended, dydx, 0.0009, 600)
+	if s.positions["BTC-USD"].ShortExchange != lighter {
+		t.Error("a position was opened on a venue with an open breaker")
+	}
+	s.closeArbitrage(s.positions["BTC-USD"])
+
+	s.breaker.venues["Dydx"].nextProbe = time.Time{}
+	s.checkFundingRates()
+	if s.breaker.isOpen("Dydx") {
+		t.Fatal("expected a successful probe to close the breaker")
+	}
+	if p := s.positions["BTC-USD"]; p == nil || p.ShortExchange != dydx {
+		t.Errorf("expected Dydx to be traded again, got %+v", p)
+	}
+}
+
+func TestKillSwitchHaltsAndUnwindsOnDrawdownOrNetDelta(t *testing.T) {
+	lighter, extended := newFakeExchange("Lighter"), newFakeExchange("Extended")
+	lighter.rates = []*exchange.FundingRate{{Market: "BTC-USD", Rate: decimal.NewFromFloat(0.0005)}}
+	extended.rates = []*exchange.FundingRate{{Market: "BTC-USD", Rate: decimal.NewFromFloat(0.0001)}}
+	cfg := config.Config{Markets: []string{"BTC-USD"}, MinFundingRateDiff: 0.0001, PositionSizeUSD: 600, MaxPositionUSD: 10000, MaxDrawdownUSD: 20, KillSwitchUnwind: true}
+	s := NewFundingRateArb(cfg, []exchange.Exchange{lighter, extended}, log.New(io.Discard, "", 0), nil)
+
+	s.checkFundingRates()
+	s.checkKillSwitch()
+	if s.paused {
+		t.Fatal("the kill switch fired without a drawdown")
+	}
+	// The long leg on Extended loses 5% of 600 USD while the short leg's price holds.
+	extended.price = 57000
+	s.marketData.StoreMarkPrice("Extended", "BTC-USD", 57000)
+	s.checkKillSwitch()
+	if !s.paused || len(s.positions) != 0 || !strings.Contains(s.Status(), "MAX_DRAWDOWN_USD") {
+		t.Fatalf("expected a halt and an unwind, paused=%v positions=%d status:\n%s", s.paused, len(s.positions), s.Status())
+	}
+	s.checkFundingRates()
+	if len(s.positions) != 0 {
+		t.Error("a position was opened after the kill switch fired")
+	}
+
+	// Resuming re-arms it from the current PnL; an unhedged leg then trips the delta limit,
+	// which halts entries without unwinding.
+	s.setPaused(false)
+	s.config.MaxDrawdownUSD, s.config.MaxNetDeltaUSD, s.config.KillSwitchUnwind = 0, 100, false
+	s.checkFundingRates()
+	s.checkKillSwitch()
+	if s.paused {
+		t.Fatal("the kill switch fired with both legs hedged")
+	}
+	lighter.held = []exchange.Position{{Market: "BTC-USD", Side: exchange.Sell, Size: 0.01}}
+	extended.held = []exchange.Position{{Market: "BTC-USD", Side: exchange.Buy, Size: 0.01}}
+	s.checkKillSwitch()
+	if s.paused {
+		t.Fatal("the kill switch fired on offsetting legs")
+	}
+	extended.held = nil
+	s.checkKillSwitch()
+	if !s.paused || len(s.positions) != 1 || !strings.Contains(s.Status(), "net delta on BTC-USD") {
+		t.Errorf("expected a halt without an unwind, paused=%v positions=%d status:\n%s", s.paused, len(s.positions), s.Status())
+	}
+}
+
+func TestOperatorCommandsAndStatus(t *testing.T) {
+	lighter, extended := newFakeExchange("Lighter"), newFakeExchange("Extended")
+	lighter.rates = []*exchange.FundingRate{{Market: "BTC-USD", Rate: decimal.NewFromFloat(0.0005)}}
+	extended.rates = []*exchange.FundingRate{{Market: "BTC-USD", Rate: decimal.NewFromFloat(0.0001)}}
+	s := newTestStrategy(lighter, extended)
+	s.checkFundingRates()
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		s.Run(stop)
+		close(done)
+	}()
+	defer func() {
+		close(stop)
+		<-done
+	}()
+
+	if err := s.Do(CommandPause, "", time.Second); err != nil {
+		t.Fatalf("pause: %v", err)
+	}
+	status := s.Status()
+	for _, want := range []string{"paused", "Open positions: 1, 600.00 USD", "BTC-USD long Extended / short Lighter", "BTC-USD 0.0400%"} {
+		if !strings.Contains(status, want) {
+			t.Errorf("status is missing %q:\n%s", want, status)
+		}
+	}
+	if err := s.Do(CommandClose, "ETH-USD", time.Second); err == nil {
+		t.Error("expected closing a market without a position to fail")
+	}
+	if err := s.Do(CommandClose, "BTC-USD", time.Second); err != nil {
+		t.Errorf("close: %v", err)
+	}
+	if balances := s.Balances(); !strings.Contains(balances, "Lighter: 1000000.00 USD") {
+		t.Errorf("unexpected balances:\n%s", balances)
+	}
+}
+
+func TestShutdownPolicies(t *testing.T) {
+	run := func(policy string, answer CommandName) (*fakeExchange, *Strategy) {
+		lighter, extended := newFakeExchange("Lighter"), newFakeExchange("Extended")
+		lighter.rates = []*exchange.FundingRate{{Market: "BTC-USD", Rate: decimal.NewFromFloat(0.0005)}}
+		extended.rates = []*exchange.FundingRate{{Market: "BTC-USD", Rate: decimal.NewFromFloat(0.0001)}}
+		s := newTestStrategy(lighter, extended)
+		s.config.ShutdownPolicy = policy
+		s.config.ShutdownAskTimeoutSeconds = 5
+		s.checkFundingRates()
+		asked := make(chan string, 8)
+		s.notifier = messageNotifier{Notifier: notifications.Discard, messages: asked}
+
+		stop := make(chan struct{})
+		done := make(chan struct{})
+		go func() {
+			s.Run(stop)
+			close(done)
+		}()
+		close(stop)
+		if answer != "" {
+			// Answer once asked, so the answer isn't taken by the loop before it stops.
+			for message := range asked {
+				if strings.Contains(message, "Reply /flatten") {
+					break
+				}
+			}
+			if err := s.Do(answer, "", 5*time.Second); err != nil {
+				t.Errorf("%s: %v", answer, err)
+			}
+		}
+		<-done
+		return lighter, s
+	}
+
+	if lighter, s := run("preserve", ""); len(lighter.closes) != 0 || len(s.positions) != 1 {
+		t.Errorf("expected preserve to leave the position open, got %d closes", len(lighter.closes))
+	}
+	if lighter, s := run("flatten", ""); len(lighter.closes) != 1 || len(s.positions) != 0 {
+		t.Errorf("expected flatten to close the position, got %d closes", len(lighter.closes))
+	}
+	if lighter, s := run("ask-telegram", CommandFlatten); len(lighter.closes) != 1 || len(s.positions) != 0 {
+		t.Errorf("expected /flatten to close the position, got %d closes", len(lighter.closes))
+	}
+	if lighter, s := run("ask-telegram", CommandPreserve); len(lighter.closes) != 0 || len(s.positions) != 1 {
+		t.Errorf("expected /preserve to leave the position open, got %d closes", len(lighter.closes))
+	}
+}
+
+// messageNotifier passes the messages sent to the operator on messages.
+func TestNetDeltaAlertsAndRebalances(t *testing.T) {
+	lighter, extended := newFakeExchange("Lighter"), newFakeExchange("Extended")
+	lighter.rates = []*exchange.FundingRate{{Market: "BTC-USD", Rate: decimal.NewFromFloat(0.0005)}}
+	extended.rates = []*exchange.FundingRate{{Market: "BTC-USD", Rate: decimal.NewFromFloat(0.0001)}}
+	cfg := config.Config{Markets: []string{"BTC-USD"}, MinFundingRateDiff: 0.0001, PositionSizeUSD: 600, MaxPositionUSD: 10000, NetDeltaAlertUSD: 100}
+	notifier := messageNotifier{Notifier: notifications.Discard, messages: make(chan string, 10)}
+	s := NewFundingRateArb(cfg, []exchange.Exchange{lighter, extended}, log.New(io.Discard, "", 0), notifier)
+	s.checkFundingRates()
+	if len(s.positions) != 1 {
+		t.Fatal("expected a position to open")
+	}
+
+	// The venue auto-deleverages part of the long leg.
+	lighter.held = []exchange.Position{{Market: "BTC-USD", Side: exchange.Sell, Size: 0.01}}
+	extended.held = []exchange.Position{{Market: "BTC-USD", Side: exchange.Buy, Size: 0.004}}
+	s.checkNetDelta()
+	s.checkNetDelta()
+	if len(notifier.messages) != 1 || len(lighter.closes) != 0 || !strings.Contains(s.Status(), "Net delta: BTC-USD -360.00 USD") {
+		t.Fatalf("expected one alert without a rebalance, got %d alerts, closes %v, status:\n%s", len(notifier.messages), lighter.closes, s.Status())
+	}
+	if message := <-notifier.messages; !strings.Contains(message, "NET_DELTA_ALERT_USD") {
+		t.Errorf("unexpected alert %q", message)
+	}
+
+	// Once rebalancing is enabled the next breach trims the short leg to the long one, once the
+	// following check confirms it.
+	s.config.NetDeltaRebalance = true
+	lighter.held = []exchange.Position{{Market: "BTC-USD", Side: exchange.Sell, Size: 0.004}}
+	s.checkNetDelta()
+	lighter.held = []exchange.Position{{Market: "BTC-USD", Side: exchange.Sell, Size: 0.01}}
+	s.checkNetDelta()
+	if len(lighter.closes) != 0 || len(notifier.messages) != 0 {
+		t.Fatalf("expected an unconfirmed breach to be left alone, closes %v", lighter.closes)
+	}
+	s.checkNetDelta()
+	position := s.positions["BTC-USD"]
+	if len(lighter.closes) != 1 || lighter.closes[0] != exchange.Sell || position == nil || position.SizeUSD != 240 || !position.Amount.Equal(decimal.NewFromFloat(0.004)) {
+		t.Fatalf("expected the short leg trimmed and the position shrunk, closes %v, position %+v", lighter.closes, position)
+	}
+
+	// A liquidated leg leaves nothing to hedge: the other is closed and the position forgotten,
+	// unless the leg is listed again by the next check.
+	lighter.held = []exchange.Position{{Market: "BTC-USD", Side: exchange.Sell, Size: 0.004}}
+	s.checkNetDelta()
+	extended.held = nil
+	s.checkNetDelta()
+	extended.held = []exchange.Position{{Market: "BTC-USD", Side: exchange.Buy, Size: 0.004}}
+	s.checkNetDelta()
+	if len(lighter.closes) != 1 || len(s.positions) != 1 {
+		t.Fatalf("expected a leg missing from one check to be left alone, closes %v", lighter.closes)
+	}
+	extended.held = nil
+	s.checkNetDelta()
+	s.checkNetDelta()
+	if len(lighter.closes) != 2 || len(s.positions) != 0 {
+		t.Errorf("expected the remaining leg closed, closes %v, positions %d", lighter.closes, len(s.positions))
+	}
+}
+
+type messageNotifier struct {
+	notifications.Notifier
+	messages chan string
+}
+
+func (n messageNotifier) SendMessage(message string) {
+	n.messages <- message
+}
+
+func TestUnwindClosesSavedPositions(t *testing.T) {
+	store := state.Open(filepath.Join(t.TempDir(), "state.json"))
+	saved := []state.Position{
+		{Market: "BTC-USD", LongExchange: "A", ShortExchange: "B", SizeUSD: 6000},
+		{Market: "ETH-USD", LongExchange: "A", ShortExchange: "B", SizeUSD: 3000},
+	}
+	if err := store.Save(DefaultName, saved); err != nil {
+		t.Fatal(err)
+	}
+	exA, exB := newFakeExchange("A"), newFakeExchange("B")
+	exA.held = []exchange.Position{{Market: "BTC-USD", Side: exchange.Buy, Size: 0.1}, {Market: "ETH-USD", Side: exchange.Buy, Size: 1}}
+	exB.held = []exchange.Position{{Market: "BTC-USD", Side: exchange.Sell, Size: 0.1}, {Market: "ETH-USD", Side: exchange.Sell, Size: 1}}
+	exB.closeErr = errors.New("API error: 502 Bad Gateway")
+
+	results, err := Unwind(context.Background(), store, []exchange.Exchange{exA, exB}, "BTC-USD")
 	if err != nil {
-		t.Errorf("Scenario 2: Failed to close position on Extended: %v", err)
-	} else {
-		logger.Printf("Scenario 2: Position closure order placed on Extended: ID %s", closeShort2.ID)
-		logger.Printf("Scenario 2: Extended Close Response: %+v", closeShort2)
+		t.Fatalf("Unwind: %v", err)
+	}
+	if len(results) != 1 || results[0].LongErr != nil || results[0].ShortErr == nil || !results[0].Amount.Equal(decimal.NewFromFloat(0.1)) {
+		t.Fatalf("expected the BTC long to close and the short to fail, got %+v", results)
+	}
+	if remaining, _ := SavedPositions(store, ""); len(remaining) != 2 {
+		t.Errorf("a position with a failed leg must stay saved, got %+v", remaining)
+	}
+
+	// The retry only closes the leg still listed on the exchange.
+	exA.held = exA.held[1:]
+	exB.closeErr = nil
+	exA.closes = nil
+	if results, err = Unwind(context.Background(), store, []exchange.Exchange{exA, exB}, ""); err != nil || len(results) != 2 {
+		t.Fatalf("Unwind = %+v, %v", results, err)
+	}
+	for _, r := range results {
+		if !r.Closed() {
+			t.Errorf("expected %s to close, got %+v", r.Position.Market, r)
+		}
+	}
+	if len(exA.closes) != 1 {
+		t.Errorf("expected only the ETH long to be closed again on A, got %d closes", len(exA.closes))
+	}
+	if remaining, _ := SavedPositions(store, ""); len(remaining) != 0 {
+		t.Errorf("expected no saved positions left, got %+v", remaining)
+	}
+}
+
+func TestExitHysteresisAndMinimumHold(t *testing.T) {
+	lighter, extended := newFakeExchange("Lighter"), newFakeExchange("Extended")
+	s := newTestStrategy(lighter, extended)
+	s.config.ExitHysteresis, s.config.MinHoldMinutes = 0.0001, 30
+	s.executeArbitrage("BTC-USD", lighter, extended, 0.0004, 600)
+	position := s.positions["BTC-USD"]
+	if position == nil {
+		t.Fatal("expected a position to be opened")
+	}
+
+	settled := position.OpenedAt.Add(time.Hour)
+	if reason := s.exitReason(position, -0.00005, settled); reason != "" {
+		t.Errorf("a spread inside the hysteresis band closed the position: %s", reason)
+	}
+	if reason := s.exitReason(position, -0.0002, position.OpenedAt.Add(10*time.Minute)); reason != "" {
+		t.Errorf("the spread closed the position within the minimum hold: %s", reason)
+	}
+	if reason := s.exitReason(position, -0.0002, settled); reason == "" {
+		t.Error("expected the position to be closed once the spread moved past the hysteresis band")
+	}
+}
+
+func TestReentryCooldownAfterClosing(t *testing.T) {
+	lighter, extended := newFakeExchange("Lighter"), newFakeExchange("Extended")
+	lighter.rates = []*exchange.FundingRate{{Market: "BTC-USD", Rate: decimal.NewFromFloat(0.0005)}}
+	extended.rates = []*exchange.FundingRate{{Market: "BTC-USD", Rate: decimal.NewFromFloat(0.0001)}}
+	s := newTestStrategy(lighter, extended)
+	s.config.ReentryCooldownMinutes = 10
+
+	s.checkFundingRates()
+	position := s.positions["BTC-USD"]
+	if position == nil {
+		t.Fatal("expected a position to be opened")
+	}
+	s.closeArbitrage(position)
+	s.checkFundingRates()
+	if _, ok := s.positions["BTC-USD"]; ok {
+		t.Fatal("the position was reopened during the cooldown")
+	}
+
+	s.closedAt["BTC-USD"] = time.Now().Add(-11 * time.Minute)
+	s.checkFundingRates()
+	if _, ok := s.positions["BTC-USD"]; !ok {
+		t.Error("expected the position to be reopened after the cooldown")
+	}
+}
+
+func TestEntryCostGate(t *testing.T) {
+	lighter, extended := newFakeExchange("Lighter"), newFakeExchange("Extended")
+	// 600 USD of BTC buys through the 60000 ask into the 60060 one, a 10 bps move.
+	lighter.book = &exchange.Orderbook{
+		Bids: []exchange.PriceLevel{{Price: 59990, Size: 1}},
+		Asks: []exchange.PriceLevel{{Price: 60000, Size: 0.005}, {Price: 60060, Size: 1}},
+	}
+	s := newTestStrategy(lighter, extended)
+	s.costs = map[string]exchange.Costs{"Lighter": {}}
+	s.config.EntryHorizonHours, s.config.EntryCostMargin = 8, 0.5
+
+	// Two fills on Extended at the default 5 bps cost 0.60 USD, and 10 bps of slippage in and out
+	// 1.20 USD: 2.70 USD with the margin, more than the 1.92 USD captured in 8 hours.
+	if cost := s.fillCosts(lighter, extended, 600); math.Abs(cost-0.6) > 1e-9 {
+		t.Errorf("fill costs = %f, want 0.60", cost)
+	}
+	s.executeArbitrage("BTC-USD", lighter, extended, 0.0004, 600)
+	if lighter.orderCount() != 0 || extended.orderCount() != 0 {
+		t.Fatal("no orders should be placed when the expected funding doesn't cover the costs")
+	}
+
+	s.config.EntryHorizonHours = 12
+	s.executeArbitrage("BTC-USD", lighter, extended, 0.0004, 600)
+	if _, ok := s.positions["BTC-USD"]; !ok {
+		t.Fatal("expected a position once 12 hours of funding, 2.88 USD, cover the costs with the margin")
+	}
+}
+
+func TestTradeOnPredictedRates(t *testing.T) {
+	lighter, extended := newFakeExchange("Lighter"), newFakeExchange("Extended")
+	lighter.rates = []*exchange.FundingRate{{Market: "BTC-USD", Rate: decimal.NewFromFloat(0.0001), PredictedRate: decimal.NewFromFloat(0.0005), HasPredicted: true}}
+	extended.rates = []*exchange.FundingRate{{Market: "BTC-USD", Rate: decimal.NewFromFloat(0.0001)}}
+	s := newTestStrategy(lighter, extended)
+
+	s.checkFundingRates()
+	if _, ok := s.positions["BTC-USD"]; ok {
+		t.Fatal("the current rates are flat, no position should be opened")
+	}
+
+	// The cached rates keep the prediction.
+	s.config.UsePredictedRates = true
+	s.checkFundingRates()
+	position := s.positions["BTC-USD"]
+	if position == nil {
+		t.Fatal("expected a position on the predicted spread")
+	}
+	if position.ShortExchange != lighter || position.LongExchange != extended {
+		t.Errorf("expected to short Lighter, whose predicted rate is higher, got long %s / short %s", position.LongExchange.Name(), position.ShortExchange.Name())
+	}
+}
+
+func TestBlackoutsBlockEntries(t *testing.T) {
+	lighter, extended := newFakeExchange("Lighter"), newFakeExchange("Extended")
+	lighter.rates = []*exchange.FundingRate{{Market: "BTC-USD", Rate: decimal.NewFromFloat(0.0005)}}
+	extended.rates = []*exchange.FundingRate{{Market: "BTC-USD", Rate: decimal.NewFromFloat(0.0001)}}
+	s := newTestStrategy(lighter, extended)
+	now := time.Now().UTC()
+	window := now.Add(-time.Hour).Format(time.RFC3339) + "/" + now.Add(time.Hour).Format(time.RFC3339)
+	s.blackout = newBlackoutRules(config.Config{MarketBlackouts: []string{"BTC-USD=" + window}}, s.logger)
+
+	s.checkFundingRates()
+	if _, ok := s.positions["BTC-USD"]; ok {
+		t.Fatal("a position was opened during the market's blackout window")
+	}
+
+	s.blackout = newBlackoutRules(config.Config{MarketBlacklist: []string{"ETH-USD"}}, s.logger)
+	s.checkFundingRates()
+	if _, ok := s.positions["BTC-USD"]; !ok {
+		t.Error("expected a position once BTC-USD is neither blacked out nor blacklisted")
+	}
+}
+
+func TestDynamicSizingScalesWithSpreadAndVolatility(t *testing.T) {
+	s := newTestStrategy(newFakeExchange("Lighter"), newFakeExchange("Extended"))
+	s.config.DynamicSizing = true
+	s.config.MinFundingRateDiff = 0.0001 // 87.6% a year
+	s.config.PositionSizeUSD, s.config.MinPositionSizeUSD, s.config.MaxPositionUSD = 1000, 200, 1500
+	opp := func(market string, diff float64) opportunity {
+		return opportunity{market: market, longEx: s.exchanges[0], shortEx: s.exchanges[1], rateDiff: diff}
+	}
+
+	// Halfway from the threshold to twice the threshold is halfway from the minimum to the maximum.
+	if size := s.dynamicSize(opp("BTC-USD", 0.00015)); math.Abs(size-600) > 1e-6 {
+		t.Errorf("size at 1.5x the threshold = %.2f, want 600", size)
+	}
+	sized := s.sizeOpportunities([]opportunity{opp("ETH-USD", 0.00015), opp("BTC-USD", 0.0003)})
+	if len(sized) != 2 || sized[0].market != "BTC-USD" || sized[0].sizeUSD != 1000 || math.Abs(sized[1].sizeUSD-500) > 1e-6 {
+		t.Errorf("expected BTC-USD at the full 1000 USD first and ETH-USD capped to the 500 USD left, got %+v", sized)
+	}
+
+	// A market moving about 6% a day is sized at half, against a 3% target.
+	s.config.SizingTargetVolatility = 0.03
+	start := time.Now()
+	for i := 0; i <= 24; i++ {
+		price := 60000.0
+		if i%2 == 1 {
+			price *= math.Exp(0.06 / math.Sqrt(24))
+		}
+		s.prices.add("BTC-USD", price, start.Add(time.Duration(i)*time.Hour))
+	}
+	if size := s.dynamicSize(opp("BTC-USD", 0.0003)); math.Abs(size-500) > 1 {
+		t.Errorf("size of a market twice as volatile as the target = %.2f, want about 500", size)
+	}
+}
+
+func TestCapitalGoesToTheBestOpportunitiesAndRotates(t *testing.T) {
+	lighter, extended := newFakeExchange("Lighter"), newFakeExchange("Extended")
+	setRates := func(btc, eth, sol float64) {
+		lighter.rates = []*exchange.FundingRate{{Market: "BTC-USD", Rate: decimal.NewFromFloat(btc)}, {Market: "ETH-USD", Rate: decimal.NewFromFloat(eth)}, {Market: "SOL-USD", Rate: decimal.NewFromFloat(sol)}}
+		extended.rates = []*exchange.FundingRate{{Market: "BTC-USD"}, {Market: "ETH-USD"}, {Market: "SOL-USD"}}
+	}
+	setRates(0.0002, 0.0005, 0.0003)
+	s := newTestStrategy(lighter, extended)
+	s.config.Markets = []string{"BTC-USD", "ETH-USD", "SOL-USD"}
+	s.config.MaxPositionUSD = 1200
+
+	// Only two positions fit: the widest spreads get them, not the first markets listed.
+	s.checkFundingRates()
+	if _, ok := s.positions["BTC-USD"]; ok || len(s.positions) != 2 {
+		t.Fatalf("expected ETH-USD and SOL-USD to be opened, got %v", s.positions)
+	}
+	opp := opportunity{market: "ETH-USD", longEx: extended, shortEx: lighter, rateDiff: 0.0005}
+	// 4.38 a year less 1.20 USD of fees on 600 USD amortized over a week.
+	if score := s.scoreOpportunity(opp); math.Abs(score-(4.38-0.002*8760/168)) > 1e-9 {
+		t.Errorf("score = %f", score)
+	}
+
+	// Without rotation, a better opportunity waits for capital.
+	setRates(0.001, 0.0005, 0.00015)
+	s.marketData.StoreFundingRates("Lighter", lighter.rates)
+	s.marketData.StoreFundingRates("Extended", extended.rates)
+	s.checkFundingRates()
+	if _, ok := s.positions["BTC-USD"]; ok {
+		t.Fatal("no position should be closed to make room without ROTATION_MIN_ANNUAL_GAIN")
+	}
+
+	s.config.RotationMinAnnualGain = 1
+	s.checkFundingRates()
+	if _, ok := s.positions["SOL-USD"]; ok {
+		t.Error("expected the narrowest spread to be rotated out")
+	}
+	if _, ok := s.positions["BTC-USD"]; !ok || len(s.positions) != 2 {
+		t.Errorf("expected the capital to move to BTC-USD, got %v", s.positions)
+	}
+}
+
+func TestRebalanceSwapsIntoAWiderPair(t *testing.T) {
+	lighter, extended, dydx := newFakeExchange("Lighter"), newFakeExchange("Extended"), newFakeExchange("Dydx")
+	lighter.rates = []*exchange.FundingRate{{Market: "BTC-USD", Rate: decimal.NewFromFloat(0.0003)}}
+	extended.rates = []*exchange.FundingRate{{Market: "BTC-USD", Rate: decimal.NewFromFloat(0.0001)}}
+	s := newTestStrategy(lighter, extended)
+	s.exchanges = append(s.exchanges, dydx)
+	notifier := messageNotifier{Notifier: notifications.Discard, messages: make(chan string, 10)}
+	s.notifier = notifier
+	s.checkFundingRates()
+	if position := s.positions["BTC-USD"]; position == nil || position.LongExchange != extended {
+		t.Fatal("expected a position long Extended / short Lighter")
+	}
+
+	// Dydx now pays 0.0006 an hour more to the long leg: 60.48 USD on 600 USD over a week, against
+	// 1.80 USD of fees to close the position and round-trip the new one.
+	dydx.rates = []*exchange.FundingRate{{Market: "BTC-USD", Rate: decimal.NewFromFloat(-0.0005)}}
+	s.marketData.StoreFundingRates("Dydx", dydx.rates)
+	s.config.RebalanceEdge = 40
+	s.checkRebalance()
+	if position := s.positions["BTC-USD"]; position == nil || position.LongExchange != extended {
+		t.Fatal("the swap doesn't beat its cost by a 4000% edge and should be skipped")
+	}
+
+	s.config.RebalanceEdge = 0.5
+	s.checkRebalance()
+	position := s.positions["BTC-USD"]
+	if position == nil || position.LongExchange != dydx || position.ShortExchange != lighter || position.SizeUSD != 600 {
+		t.Fatalf("expected the position swapped to long Dydx / short Lighter, got %+v", position)
+	}
+	if len(extended.closes) != 1 || extended.closes[0] != exchange.Buy {
+		t.Errorf("expected the long Extended leg to be closed, got %v", extended.closes)
+	}
+	var rebalanced bool
+	for len(notifier.messages) > 0 {
+		rebalanced = rebalanced || strings.Contains(<-notifier.messages, "Rebalanced 600.00 USD")
+	}
+	if !rebalanced {
+		t.Error("expected the swap to be reported")
+	}
+}
+
+func TestBasisStopClosesBothLegs(t *testing.T) {
+	lighter, extended := newFakeExchange("Lighter"), newFakeExchange("Extended")
+	lighter.rates = []*exchange.FundingRate{{Market: "BTC-USD", Rate: decimal.NewFromFloat(0.0005)}}
+	extended.rates = []*exchange.FundingRate{{Market: "BTC-USD", Rate: decimal.NewFromFloat(0.0001)}}
+	s := newTestStrategy(lighter, extended)
+	notifier := messageNotifier{Notifier: notifications.Discard, messages: make(chan string, 10)}
+	s.notifier = notifier
+	s.config.BasisStopUSD = 15
+	s.checkFundingRates()
+	if _, ok := s.positions["BTC-USD"]; !ok {
+		t.Fatal("expected a position long Extended / short Lighter")
+	}
+
+	// The short leg on Lighter loses 10 USD on 0.01 BTC as its price rises 1000 above Extended's.
+	s.marketData.StoreMarkPrice("Lighter", "BTC-USD", 61000)
+	s.checkBasisStop()
+	if basis := s.basis["BTC-USD"]; math.Abs(basis+10) > 1e-6 {
+		t.Errorf("basis PnL = %f, want -10", basis)
+	}
+	if _, ok := s.positions["BTC-USD"]; !ok {
+		t.Fatal("a 10 USD basis loss is within BASIS_STOP_USD and shouldn't close the position")
+	}
+
+	s.marketData.StoreMarkPrice("Lighter", "BTC-USD", 62000)
+	s.checkBasisStop()
+	if _, ok := s.positions["BTC-USD"]; ok {
+		t.Fatal("expected the position to be closed on a 20 USD basis loss")
+	}
+	if len(lighter.closes) != 1 || len(extended.closes) != 1 {
+		t.Errorf("expected both legs to be closed, got %v and %v", lighter.closes, extended.closes)
+	}
+	var stopped bool
+	for len(notifier.messages) > 0 {
+		stopped = stopped || strings.Contains(<-notifier.messages, "Basis stop on BTC-USD")
+	}
+	if !stopped {
+		t.Error("expected the basis stop to be reported")
+	}
+}
+
+func TestPartialFillIsToppedUp(t *testing.T) {
+	lighter, extended := newFakeExchange("Lighter"), newFakeExchange("Extended")
+	s := newTestStrategy(lighter, extended)
+	s.config.HedgeToleranceUSD = 1
+	// The short leg fills 0.005 of 0.01, then its top-up fills halfway too.
+	extended.partialFills = 2
+
+	s.executeArbitrage("BTC-USD", lighter, extended, 0.0004, 600)
+	position := s.positions["BTC-USD"]
+	if position == nil {
+		t.Fatal("expected a position to be opened")
+	}
+	if n := extended.orderCount(); n != 3 {
+		t.Fatalf("expected two top-ups of the short leg, got %d orders", n-1)
+	}
+	if !extended.orders[1].Amount.Equal(decimal.NewFromFloat(0.005)) || !extended.orders[2].Amount.Equal(decimal.NewFromFloat(0.0025)) {
+		t.Errorf("expected top-ups for the missing 0.005 and then 0.0025, got %+v", extended.orders[1:])
+	}
+	if position.SizeUSD != 600 {
+		t.Errorf("position size = %.2f, want the full 600 once the hedge is balanced", position.SizeUSD)
+	}
+
+	// Without a tolerance fills aren't checked.
+	s = newTestStrategy(newFakeExchange("Lighter"), newFakeExchange("Extended"))
+	short := s.exchanges[1].(*fakeExchange)
+	short.partialFills = 1
+	s.executeArbitrage("BTC-USD", s.exchanges[0], short, 0.0004, 600)
+	if short.orderCount() != 1 {
+		t.Error("no top-up should be placed with HEDGE_TOLERANCE_USD unset")
+	}
+}
+
+func TestCheckIntervalAndJitter(t *testing.T) {
+	s := newTestStrategy(newFakeExchange("Lighter"), newFakeExchange("Extended"))
+	quiet := time.Date(2024, 1, 1, 2, 30, 0, 0, time.UTC)
+	nearFunding := time.Date(2024, 1, 1, 7, 58, 0, 0, time.UTC)
+
+	if delay := s.scheduledCheckDelay(quiet); delay != defaultCheckInterval {
+		t.Errorf("expected the default interval away from funding, got %s", delay)
+	}
+	s.config.CheckIntervalSeconds = 2
+	if delay := s.scheduledCheckDelay(quiet); delay != minPolledCheckInterval {
+		t.Errorf("expected a 2s interval without streaming to be raised to %s, got %s", minPolledCheckInterval, delay)
+	}
+	s.config.Streaming = true
+	if delay := s.scheduledCheckDelay(quiet); delay != 2*time.Second {
+		t.Errorf("expected a 2s interval with streaming, got %s", delay)
+	}
+	if delay := s.scheduledCheckDelay(nearFunding); delay != 2*time.Second {
+		t.Errorf("expected the fast interval to be capped at the configured one, got %s", delay)
+	}
+
+	s.config.CheckJitterSeconds = 1
+	for i := 0; i < 20; i++ {
+		if jitter := checkJitter(s.config); jitter < 0 || jitter > time.Second {
+			t.Fatalf("expected a jitter of up to 1s, got %s", jitter)
+		}
+	}
+
+	if s.firstCheckDelay() == 0 {
+		t.Error("expected the first check to wait without CHECK_ON_START")
+	}
+	s.config.CheckOnStart = true
+	if delay := s.firstCheckDelay(); delay != 0 {
+		t.Errorf("expected an immediate first check with CHECK_ON_START, got %s", delay)
+	}
+}
+
+func TestRateCheckDelayCoalescesBursts(t *testing.T) {
+	now := time.Now()
+	if delay := rateCheckDelay(time.Time{}, now); delay != 0 {
+		t.Errorf("expected the first pushed rates to be checked at once, got %s", delay)
+	}
+	if delay := rateCheckDelay(now.Add(-time.Second), now); delay != rateCheckInterval-time.Second {
+		t.Errorf("expected rates pushed a second after a check to wait %s, got %s", rateCheckInterval-time.Second, delay)
+	}
+	if delay := rateCheckDelay(now.Add(-time.Minute), now); delay != 0 {
+		t.Errorf("expected no wait long after the last check, got %s", delay)
+	}
+}
+
+func TestEventsDontBlockWhenTheQueueIsFull(t *testing.T) {
+	s := newTestStrategy(newFakeExchange("Lighter"), newFakeExchange("Extended"))
+	rates := []*exchange.FundingRate{{Market: "BTC-USD", Rate: decimal.NewFromFloat(0.0001)}}
+	for i := 0; i < eventBufferSize+1; i++ {
+		s.SubmitCommand(Command{Name: CommandCheck})
+		s.PublishFill(&exchange.Order{ID: "1", Market: "BTC-USD"})
+		s.PublishRates("Lighter", rates)
+	}
+	if err := s.Do(CommandPause, "", time.Second); !errors.Is(err, ErrCommandQueueFull) {
+		t.Errorf("expected ErrCommandQueueFull with a full queue, got %v", err)
+	}
+	if snapshot, ok := s.marketData.Get("Lighter", "BTC-USD"); !ok || snapshot.IsStale(time.Minute) {
+		t.Errorf("expected rates pushed past a full queue to reach the cache, got %+v", snapshot)
+	}
+}
+
+// bulkPricedExchange is a fakeExchange that prices many markets in one request and counts the
+// requests of each kind.
+type bulkPricedExchange struct {
+	*fakeExchange
+	bulk, single int
+}
+
+func (b *bulkPricedExchange) GetMarkPrices(ctx context.Context, markets []string) (map[string]float64, error) {
+	b.bulk++
+	prices := make(map[string]float64, len(markets))
+	for _, market := range markets {
+		prices[market] = b.price
+	}
+	return prices, nil
+}
+
+func (b *bulkPricedExchange) GetMarkPrice(ctx context.Context, market string) (float64, error) {
+	b.single++
+	return b.fakeExchange.GetMarkPrice(ctx, market)
+}
+
+func TestCheckPricesEveryMarketInOneRequest(t *testing.T) {
+	lighter := &bulkPricedExchange{fakeExchange: newFakeExchange("Lighter")}
+	extended := newFakeExchange("Extended")
+	lighter.rates = []*exchange.FundingRate{{Market: "BTC-USD", Rate: decimal.NewFromFloat(0.0005)}, {Market: "ETH-USD", Rate: decimal.NewFromFloat(0.0005)}, {Market: "SOL-USD", Rate: decimal.NewFromFloat(0.0001)}}
+	extended.rates = []*exchange.FundingRate{{Market: "BTC-USD", Rate: decimal.NewFromFloat(0.0001)}, {Market: "ETH-USD", Rate: decimal.NewFromFloat(0.0001)}, {Market: "SOL-USD", Rate: decimal.NewFromFloat(0.0001)}}
+	s := newTestStrategy(lighter, extended)
+	s.config.Markets = []string{"BTC-USD", "ETH-USD", "SOL-USD"}
+
+	s.checkFundingRates()
+
+	if len(s.positions) != 2 {
+		t.Fatalf("expected BTC-USD and ETH-USD to be opened, got %d positions", len(s.positions))
+	}
+	if lighter.bulk != 1 || lighter.single != 0 {
+		t.Errorf("expected the markets to be priced in 1 request and reused, got %d bulk and %d single requests", lighter.bulk, lighter.single)
 	}
-	logger.Println("--- Finished Scenario 2 ---")
 }
@@ -0,0 +1,99 @@
+package strategy
+
+import (
+	"fmt"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/exchange"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/notifications"
+)
+
+// defaultHedgeRepairAttempts is used when HEDGE_REPAIR_ATTEMPTS is not set.
+const defaultHedgeRepairAttempts = 3
+
+// legFill returns how much of order has filled, as ex reports it. It reports false when the fill
+// isn't known: the status can't be fetched, or reports nothing filled, as venues that don't track
+// fills do.
+func (s *Strategy) legFill(ex exchange.Exchange, market string, order *exchange.Order) (decimal.Decimal, bool) {
+	if order == nil || order.ID == "" {
+		return decimal.Zero, false
+	}
+	status, err := ex.GetOrderStatus(s.ctx, order.ID, market)
+	if err != nil {
+		s.logger.Printf("Could not check the fill of order %s on %s: %v", order.ID, ex.Name(), err)
+		return decimal.Zero, false
+	}
+	if !status.Filled.IsPositive() {
+		return decimal.Zero, false
+	}
+	return status.Filled, true
+}
+
+// repairHedge checks how much of each entry leg filled and, while the legs differ by more than
+// HEDGE_TOLERANCE_USD, tops up the lagging leg with a market order for the difference, or trims
+// the leading one when the top-up fails, up to HEDGE_REPAIR_ATTEMPTS times. It returns the amount
+// both legs hold afterwards, or amount when the fills can't be verified. A hedge that is still
+// off balance is escalated to the operator.
+func (s *Strategy) repairHedge(market string, longEx, shortEx exchange.Exchange, longOrder, shortOrder *exchange.Order, amount decimal.Decimal, price float64) decimal.Decimal {
+	if s.config.HedgeToleranceUSD <= 0 {
+		return amount
+	}
+	longFilled, okLong := s.legFill(longEx, market, longOrder)
+	shortFilled, okShort := s.legFill(shortEx, market, shortOrder)
+	if !okLong || !okShort {
+		s.logger.Printf("Could not verify the %s fills, assuming both legs filled %s.", market, amount)
+		return amount
+	}
+	attempts := s.config.HedgeRepairAttempts
+	if attempts <= 0 {
+		attempts = defaultHedgeRepairAttempts
+	}
+
+	for attempt := 1; ; attempt++ {
+		residual := longFilled.Sub(shortFilled)
+		if residual.Abs().InexactFloat64()*price <= s.config.HedgeToleranceUSD {
+			return decimal.Min(longFilled, shortFilled)
+		}
+		if attempt > attempts {
+			break
+		}
+		// The lagging leg is topped up; the leading one trimmed if that fails.
+		lagEx, lagSide, leadEx, leadSide := shortEx, exchange.Sell, longEx, exchange.Buy
+		lagFilled, leadFilled := &shortFilled, &longFilled
+		if residual.IsNegative() {
+			lagEx, lagSide, leadEx, leadSide = longEx, exchange.Buy, shortEx, exchange.Sell
+			lagFilled, leadFilled = &longFilled, &shortFilled
+		}
+		gap := residual.Abs()
+		s.logger.Printf("The %s legs are off by %s (%.2f USD), topping up the %s leg on %s (attempt %d/%d).", market, gap, gap.InexactFloat64()*price, lagSide, lagEx.Name(), attempt, attempts)
+		order, err := lagEx.PlaceOrder(s.ctx, market, lagSide, exchange.Market, gap, decimal.NewFromFloat(price))
+		s.recordOrder(lagEx, market, lagSide, exchange.Market, gap, price, order, err)
+		if err == nil {
+			*lagFilled = lagFilled.Add(s.filledOr(lagEx, market, order, gap))
+			continue
+		}
+		s.logger.Printf("Topping up the %s leg on %s failed, trimming the %s leg on %s instead: %v", lagSide, lagEx.Name(), leadSide, leadEx.Name(), err)
+		order, err = leadEx.ClosePosition(s.unwindContext(), market, leadSide, gap)
+		s.recordOrder(leadEx, market, oppositeSide(leadSide), exchange.Market, gap, 0, order, err)
+		if err == nil {
+			*leadFilled = leadFilled.Sub(s.filledOr(leadEx, market, order, gap))
+		}
+	}
+
+	message := fmt.Sprintf("The %s hedge is off balance after %d repair attempts: long %s on %s, short %s on %s. Check the positions on both venues.",
+		market, attempts, longFilled, longEx.Name(), shortFilled, shortEx.Name())
+	s.logger.Printf("CRITICAL: %s", message)
+	s.recordError("", market, message)
+	s.notifier.SendMessage("⚖️ " + message)
+	notifications.Publish(s.notifier, notifications.Event{Type: notifications.EventRiskAlert, Market: market, Message: message})
+	return decimal.Min(longFilled, shortFilled)
+}
+
+// filledOr returns how much of order has filled, or fallback when ex doesn't say.
+func (s *Strategy) filledOr(ex exchange.Exchange, market string, order *exchange.Order, fallback decimal.Decimal) decimal.Decimal {
+	if filled, ok := s.legFill(ex, market, order); ok {
+		return filled
+	}
+	return fallback
+}
@@ -0,0 +1,98 @@
+package strategy
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/notifications"
+)
+
+// killSwitchCheckInterval is how often the drawdown and the net delta are checked against their
+// limits.
+const killSwitchCheckInterval = time.Minute
+
+// killSwitchEnabled reports whether MAX_DRAWDOWN_USD or MAX_NET_DELTA_USD is set.
+func (s *Strategy) killSwitchEnabled() bool {
+	return s.config.MaxDrawdownUSD > 0 || s.config.MaxNetDeltaUSD > 0
+}
+
+// checkKillSwitch halts new entries when the total PnL has fallen MAX_DRAWDOWN_USD below its peak
+// since the strategy started or was last resumed, or when the net position of any market across
+// the exchanges exceeds MAX_NET_DELTA_USD. With KILL_SWITCH_UNWIND every position is closed too.
+// It fires once; resuming the strategy re-arms it.
+func (s *Strategy) checkKillSwitch() {
+	s.mu.Lock()
+	fired := s.killSwitch != ""
+	s.mu.Unlock()
+	if fired {
+		return
+	}
+	var breaches []string
+	if s.config.MaxDrawdownUSD > 0 {
+		if breach := s.drawdownBreach(); breach != "" {
+			breaches = append(breaches, breach)
+		}
+	}
+	if s.config.MaxNetDeltaUSD > 0 {
+		breaches = append(breaches, s.netDeltaBreaches()...)
+	}
+	if len(breaches) > 0 {
+		s.tripKillSwitch(strings.Join(breaches, "; "))
+	}
+}
+
+// drawdownBreach returns why the drawdown exceeds MAX_DRAWDOWN_USD, or "".
+func (s *Strategy) drawdownBreach() string {
+	s.syncFunding()
+	total := s.PnL().Total()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.pnlPeakSet || total > s.pnlPeak {
+		s.pnlPeak, s.pnlPeakSet = total, true
+	}
+	if drawdown := s.pnlPeak - total; drawdown >= s.config.MaxDrawdownUSD {
+		return fmt.Sprintf("drawdown of %.2f USD (PnL %+.2f USD, peak %+.2f USD) reached MAX_DRAWDOWN_USD %.2f", drawdown, total, s.pnlPeak, s.config.MaxDrawdownUSD)
+	}
+	return ""
+}
+
+// netDeltaBreaches returns why the net position of each market, summed over the positions the
+// exchanges report and valued at the mark price, exceeds MAX_NET_DELTA_USD.
+func (s *Strategy) netDeltaBreaches() []string {
+	var breaches []string
+	for _, d := range s.netDeltas() {
+		if math.Abs(d.USD) > s.config.MaxNetDeltaUSD {
+			breaches = append(breaches, fmt.Sprintf("net delta on %s is %+.6f (%+.2f USD), beyond MAX_NET_DELTA_USD %.2f", d.Market, d.Net, d.USD, s.config.MaxNetDeltaUSD))
+		}
+	}
+	return breaches
+}
+
+// tripKillSwitch halts new entries, alerts the operator and, with KILL_SWITCH_UNWIND, closes every
+// position.
+func (s *Strategy) tripKillSwitch(reason string) {
+	s.mu.Lock()
+	s.paused = true
+	s.killSwitch = reason
+	positions := make([]*PositionInfo, 0, len(s.positions))
+	for _, position := range s.positions {
+		positions = append(positions, position)
+	}
+	s.mu.Unlock()
+
+	message := fmt.Sprintf("🛑 KILL SWITCH: %s. New entries are halted until /resume.", reason)
+	if s.config.KillSwitchUnwind {
+		message += fmt.Sprintf(" Unwinding %d position(s).", len(positions))
+	}
+	s.logger.Printf("CRITICAL: %s", message)
+	s.notifier.SendMessage(message)
+	notifications.Publish(s.notifier, notifications.Event{Type: notifications.EventRiskAlert, Message: message})
+	if !s.config.KillSwitchUnwind {
+		return
+	}
+	for _, position := range positions {
+		s.closeArbitrage(position)
+	}
+}
@@ -0,0 +1,44 @@
+package strategy
+
+import (
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/exchange"
+)
+
+// liquidityFilter returns the markets that meet the configured volume and open interest floors on
+// every exchange that reports them. It returns nil when no floors are configured. Exchanges that
+// don't report market statistics, or whose statistics can't be fetched, don't exclude any market.
+func (s *Strategy) liquidityFilter(markets []string) map[string]bool {
+	if s.config.MinVolume24hUSD <= 0 && s.config.MinOpenInterestUSD <= 0 {
+		return nil
+	}
+
+	liquid := make(map[string]bool, len(markets))
+	for _, market := range markets {
+		liquid[market] = true
+	}
+	for _, ex := range s.exchanges {
+		statser, ok := ex.(exchange.MarketStatser)
+		if !ok {
+			continue
+		}
+		stats, err := statser.GetMarketStats(s.ctx, markets)
+		if err != nil {
+			s.logger.Printf("Could not get market statistics from %s, not filtering on it: %v", ex.Name(), err)
+			continue
+		}
+		for _, market := range markets {
+			st, ok := stats[market]
+			if !ok {
+				continue
+			}
+			if st.Volume24hUSD < s.config.MinVolume24hUSD {
+				s.logger.Printf("Excluding %s: 24h volume on %s is %.0f USD, below %.0f USD.", market, ex.Name(), st.Volume24hUSD, s.config.MinVolume24hUSD)
+				liquid[market] = false
+			} else if st.OpenInterestUSD < s.config.MinOpenInterestUSD {
+				s.logger.Printf("Excluding %s: open interest on %s is %.0f USD, below %.0f USD.", market, ex.Name(), st.OpenInterestUSD, s.config.MinOpenInterestUSD)
+				liquid[market] = false
+			}
+		}
+	}
+	return liquid
+}
@@ -0,0 +1,85 @@
+package strategy
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/config"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/exchange"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/marketdata"
+)
+
+// NewMarketData returns a market data cache for the strategies, keeping the trading rules of each
+// market for MARKET_INFO_TTL_MINUTES.
+func NewMarketData(cfg config.Config) *marketdata.Cache {
+	cache := marketdata.NewCache(time.Duration(cfg.MarketDataTTLSeconds) * time.Second)
+	cache.SetMarketInfoTTL(time.Duration(cfg.MarketInfoTTLMinutes * float64(time.Minute)))
+	return cache
+}
+
+// orderAmount converts sizeUSD at price into the base amount each leg trades, rounded as in
+// lotAmount, and checks it against the minimum order size and value of both venues, and the
+// leverage configured for each against its maximum, so neither leg is rejected after the other
+// filled.
+func (s *Strategy) orderAmount(market string, longEx, shortEx exchange.Exchange, sizeUSD, price float64) (decimal.Decimal, error) {
+	amount, err := s.lotAmount(market, longEx, shortEx, decimal.NewFromFloat(sizeUSD).Div(decimal.NewFromFloat(price)))
+	if err != nil {
+		return decimal.Zero, err
+	}
+	for _, ex := range []exchange.Exchange{longEx, shortEx} {
+		info, ok, err := s.marketData.MarketInfo(s.ctx, ex, market)
+		if err != nil {
+			return decimal.Zero, fmt.Errorf("could not get the trading rules of %s on %s: %w", market, ex.Name(), err)
+		}
+		if !ok {
+			continue
+		}
+		if err := info.Validate(amount, price, s.venueLeverage(ex, market)); err != nil {
+			return decimal.Zero, fmt.Errorf("%s rejects the order: %w", ex.Name(), err)
+		}
+	}
+	return amount, nil
+}
+
+// lotAmount rounds amount down to a multiple of the lot size of both venues, in decimal
+// arithmetic, so neither venue rounds its leg differently from the other and the hedge is opened
+// and closed without dust. Venues that don't list their lot size don't constrain it.
+func (s *Strategy) lotAmount(market string, longEx, shortEx exchange.Exchange, amount decimal.Decimal) (decimal.Decimal, error) {
+	step := decimal.Zero
+	for _, ex := range []exchange.Exchange{longEx, shortEx} {
+		info, ok, err := s.marketData.MarketInfo(s.ctx, ex, market)
+		if err != nil {
+			return decimal.Zero, fmt.Errorf("could not get the lot size of %s on %s: %w", market, ex.Name(), err)
+		}
+		if ok {
+			step = exchange.CommonStep(step, info.LotSize)
+		}
+	}
+	if step.IsPositive() {
+		amount = amount.Div(step).Floor().Mul(step)
+	}
+	if !amount.IsPositive() {
+		return decimal.Zero, fmt.Errorf("the amount is below one lot of %s %s, the smallest size both %s and %s accept", step, market, longEx.Name(), shortEx.Name())
+	}
+	return amount, nil
+}
+
+// tickPrice rounds price to the tick size of market on ex, when the venue lists it.
+func (s *Strategy) tickPrice(ex exchange.Exchange, market string, price float64) float64 {
+	info, ok, err := s.marketData.MarketInfo(s.ctx, ex, market)
+	if err != nil || !ok {
+		return price
+	}
+	return info.RoundPrice(price)
+}
+
+// amountAt returns the base amount of each leg of position. Positions saved before it was
+// recorded are valued at price.
+func (p *PositionInfo) amountAt(price float64) decimal.Decimal {
+	if p.Amount.IsPositive() {
+		return p.Amount
+	}
+	return decimal.NewFromFloat(p.SizeUSD).Div(decimal.NewFromFloat(price))
+}
@@ -0,0 +1,41 @@
+package strategy
+
+import (
+	"log"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/config"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/exchange"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/execution"
+)
+
+// newMakerEntry parses MAKER_ENTRY into the chase settings of each exchange that enters with
+// limit orders. Exchanges that aren't listed enter with market orders.
+func newMakerEntry(cfg config.Config, logger *log.Logger) map[string]execution.Config {
+	maker, err := execution.ParseConfigs(cfg.MakerEntry)
+	if err != nil {
+		logger.Printf("Ignoring MAKER_ENTRY, entering with market orders: %v", err)
+		return nil
+	}
+	return maker
+}
+
+// placeEntry opens one leg of a position: chased limit orders on exchanges configured for maker
+// entry, a market order elsewhere, priced on the venue's tick. The order is journaled either way.
+func (s *Strategy) placeEntry(ex exchange.Exchange, market string, side exchange.OrderSide, amount decimal.Decimal, price float64) (*exchange.Order, error) {
+	price = s.tickPrice(ex, market, price)
+	cfg, ok := s.maker[ex.Name()]
+	if !ok {
+		order, err := ex.PlaceOrder(s.ctx, market, side, exchange.Market, amount, decimal.NewFromFloat(price))
+		s.recordOrder(ex, market, side, exchange.Market, amount, price, order, err)
+		return order, err
+	}
+	order, err := execution.Chase(s.ctx, ex, market, side, amount, cfg)
+	orderType := exchange.Limit
+	if order != nil {
+		orderType = order.Type
+	}
+	s.recordOrder(ex, market, side, orderType, amount, price, order, err)
+	return order, err
+}
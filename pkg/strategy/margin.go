@@ -0,0 +1,108 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sync"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/config"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/exchange"
+)
+
+// marginSelector applies the configured margin mode and leverage to a market on each venue before
+// the first position in it is opened. A nil selector leaves every venue at its account defaults.
+type marginSelector struct {
+	modes     exchange.MarginModes
+	leverages exchange.Leverages
+	// leverage is set on venues without a LEVERAGES entry when SET_LEVERAGE is on, or 0.
+	leverage float64
+	logger   *log.Logger
+	applied  map[string]bool
+	mu       sync.Mutex
+}
+
+// newMarginSelector parses MARGIN_MODES and LEVERAGES, returning nil when neither is configured
+// and SET_LEVERAGE is off.
+func newMarginSelector(cfg config.Config, logger *log.Logger) *marginSelector {
+	modes, err := exchange.ParseMarginModes(cfg.MarginModes)
+	if err != nil {
+		logger.Printf("Ignoring MARGIN_MODES: %v", err)
+		modes = nil
+	}
+	leverages, err := exchange.ParseLeverages(cfg.Leverages)
+	if err != nil {
+		logger.Printf("Ignoring LEVERAGES: %v", err)
+		leverages = nil
+	}
+	m := &marginSelector{modes: modes, leverages: leverages, logger: logger, applied: make(map[string]bool)}
+	if cfg.SetLeverage {
+		m.leverage = math.Max(cfg.Leverage, 1)
+	}
+	if len(m.modes) == 0 && len(m.leverages) == 0 && m.leverage == 0 {
+		return nil
+	}
+	return m
+}
+
+// leverageFor returns the leverage configured for market on exchangeName in LEVERAGES, or
+// fallback.
+func (m *marginSelector) leverageFor(exchangeName, market string, fallback float64) float64 {
+	if m == nil {
+		return fallback
+	}
+	if leverage, ok := m.leverages.For(exchangeName, market); ok {
+		return leverage
+	}
+	return fallback
+}
+
+// apply sets the configured leverage and then the margin mode of market on each venue that has
+// them and hasn't been set yet. The leverage goes first because Lighter sends the margin mode
+// together with it. A venue that can't select the margin mode or a leverage listed in
+// LEVERAGES is an error, because its liquidation behavior would not be the one the operator asked
+// for. A venue that can't set the leverage SET_LEVERAGE applies everywhere keeps its default, and
+// is only logged.
+func (m *marginSelector) apply(ctx context.Context, market string, venues ...exchange.Exchange) error {
+	if m == nil {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, ex := range venues {
+		key := ex.Name() + ":" + market
+		if m.applied[key] {
+			continue
+		}
+		leverage, listed := m.leverages.For(ex.Name(), market)
+		if !listed {
+			leverage = m.leverage
+		}
+		if leverage > 0 {
+			setter, ok := ex.(exchange.LeverageSetter)
+			switch {
+			case !ok && listed:
+				return fmt.Errorf("%s does not support setting the leverage", ex.Name())
+			case !ok:
+				m.logger.Printf("%s does not support setting the leverage, keeping its default for %s.", ex.Name(), market)
+			default:
+				if err := setter.SetLeverage(ctx, market, leverage); err != nil {
+					return fmt.Errorf("failed to set %gx leverage on %s for %s: %w", leverage, ex.Name(), market, err)
+				}
+			}
+		}
+		if mode, ok := m.modes.For(ex.Name(), market); ok {
+			setter, ok := ex.(exchange.MarginModeSetter)
+			if !ok {
+				return fmt.Errorf("%s does not support selecting the margin mode", ex.Name())
+			}
+			if err := setter.SetMarginMode(ctx, market, mode); err != nil {
+				return fmt.Errorf("failed to set %s margin on %s for %s: %w", mode, ex.Name(), market, err)
+			}
+		}
+		m.applied[key] = true
+	}
+	return nil
+}
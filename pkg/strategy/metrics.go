@@ -0,0 +1,67 @@
+package strategy
+
+import (
+	"time"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/exchange"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/metrics"
+)
+
+// SetMetrics enables recording the strategy's activity for Prometheus.
+func (s *Strategy) SetMetrics(m *metrics.Metrics) {
+	s.metrics = m
+}
+
+// recordPositionMetrics publishes the open position count and exposure.
+func (s *Strategy) recordPositionMetrics() {
+	if s.metrics == nil {
+		return
+	}
+	s.mu.Lock()
+	count, exposure := len(s.positions), s.getTotalPositionValue()
+	s.mu.Unlock()
+	s.metrics.SetPositions(count, exposure)
+}
+
+// accrueFunding estimates the funding earned by open positions since the previous check from the
+// current rates, which are quoted per funding interval of their exchange. It is an estimate: the
+// venues settle at their own times and may revise a rate before it is paid.
+func (s *Strategy) accrueFunding(rates map[string]map[string]float64, now time.Time) {
+	if s.metrics == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fundingAccruedAt == nil {
+		s.fundingAccruedAt = make(map[string]time.Time)
+	}
+	for market, position := range s.positions {
+		since, ok := s.fundingAccruedAt[market]
+		if !ok || since.Before(position.OpenedAt) {
+			since = position.OpenedAt
+		}
+		s.fundingAccruedAt[market] = now
+		elapsed := now.Sub(since)
+		if elapsed <= 0 {
+			continue
+		}
+		longRate, okLong := rates[position.LongExchange.Name()][market]
+		shortRate, okShort := rates[position.ShortExchange.Name()][market]
+		if !okLong || !okShort {
+			continue
+		}
+		// Shorts receive the rate and longs pay it.
+		earned := position.SizeUSD * (shortRate*intervals(position.ShortExchange, elapsed) - longRate*intervals(position.LongExchange, elapsed))
+		s.metrics.AddFundingPnL(market, earned)
+	}
+	for market := range s.fundingAccruedAt {
+		if _, open := s.positions[market]; !open {
+			delete(s.fundingAccruedAt, market)
+		}
+	}
+}
+
+// intervals returns how many funding intervals of ex elapsed covers.
+func intervals(ex exchange.Exchange, elapsed time.Duration) float64 {
+	return float64(elapsed) / float64(exchange.FundingIntervalOf(ex))
+}
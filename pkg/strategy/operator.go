@@ -0,0 +1,144 @@
+package strategy
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/collateral"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/exchange"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/state"
+)
+
+// ErrCommandPending is returned by Do when the strategy loop hasn't executed a command in time.
+// The command stays queued and runs once the loop is free.
+var ErrCommandPending = errors.New("command queued, the strategy is busy")
+
+// ErrCommandQueueFull is returned by Do when too many commands are waiting for the strategy loop.
+// The command is dropped.
+var ErrCommandQueueFull = errors.New("command dropped, too many commands are queued")
+
+// Do submits an operator command and waits up to timeout for the strategy loop to execute it.
+func (s *Strategy) Do(name CommandName, market string, timeout time.Duration) error {
+	reply := make(chan error, 1)
+	s.SubmitCommand(Command{Name: name, Market: market, Reply: reply})
+	select {
+	case err := <-reply:
+		return err
+	case <-time.After(timeout):
+		return ErrCommandPending
+	}
+}
+
+// Status describes whether entries are paused, the open positions and the current funding rate
+// difference of every configured market: between the venues of its open position, or of the best
+// pair when none is open.
+func (s *Strategy) Status() string {
+	var b strings.Builder
+	s.mu.Lock()
+	state := "running"
+	if s.paused {
+		state = "paused, no new positions are opened"
+	}
+	fmt.Fprintf(&b, "Strategy %s\n", state)
+	if s.killSwitch != "" {
+		fmt.Fprintf(&b, "Kill switch fired: %s\n", s.killSwitch)
+	}
+	if breakers := s.describeBreakers(); breakers != "" {
+		fmt.Fprintf(&b, "%s\n", breakers)
+	}
+	if stale := s.describeStaleRates(); stale != "" {
+		fmt.Fprintf(&b, "%s\n", stale)
+	}
+	if delta := s.describeNetDelta(); delta != "" {
+		fmt.Fprintf(&b, "%s\n", delta)
+	}
+	markets := make([]string, 0, len(s.positions))
+	for market := range s.positions {
+		markets = append(markets, market)
+	}
+	sort.Strings(markets)
+	fmt.Fprintf(&b, "Open positions: %d, %.2f USD\n", len(markets), s.getTotalPositionValue())
+	for _, market := range markets {
+		p := s.positions[market]
+		fmt.Fprintf(&b, "%s long %s / short %s, %.2f USD, entry diff %.4f%%, open %s\n",
+			market, p.LongExchange.Name(), p.ShortExchange.Name(), p.SizeUSD, p.EntryRateDiff*100, time.Since(p.OpenedAt).Round(time.Minute))
+	}
+	s.mu.Unlock()
+
+	rates, venues := s.fundingRates()
+	if len(venues) < 2 {
+		b.WriteString("Funding rates unavailable from at least two exchanges")
+		return b.String()
+	}
+	b.WriteString("Rate differences (short - long):")
+	for _, market := range s.config.Markets {
+		s.mu.Lock()
+		position, exists := s.positions[market]
+		s.mu.Unlock()
+		if exists {
+			shortRate, okShort := rates[position.ShortExchange.Name()][market]
+			longRate, okLong := rates[position.LongExchange.Name()][market]
+			if okShort && okLong {
+				fmt.Fprintf(&b, "\n%s %.4f%% long %s / short %s", market, (shortRate-longRate)*100, position.LongExchange.Name(), position.ShortExchange.Name())
+				continue
+			}
+		}
+		best, ok := bestPair(market, venues, rates)
+		if !ok {
+			fmt.Fprintf(&b, "\n%s not listed on two exchanges", market)
+			continue
+		}
+		fmt.Fprintf(&b, "\n%s %.4f%% best long %s / short %s", market, best.diff()*100, best.longEx.Name(), best.shortEx.Name())
+	}
+	return b.String()
+}
+
+// Positions returns the open positions, ordered by market.
+func (s *Strategy) Positions() []state.Position {
+	s.mu.Lock()
+	positions := s.savedPositions()
+	s.mu.Unlock()
+	sort.Slice(positions, func(i, j int) bool { return positions[i].Market < positions[j].Market })
+	return positions
+}
+
+// Balances reports the USD value of the collateral on each exchange.
+func (s *Strategy) Balances() string {
+	var lines []string
+	for _, ex := range s.exchanges {
+		balance, err := s.collateral.BalanceUSD(s.ctx, ex)
+		if err != nil {
+			lines = append(lines, fmt.Sprintf("%s: unavailable (%v)", ex.Name(), err))
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%s: %.2f USD", ex.Name(), balance))
+		lines = append(lines, s.accountBalances(ex)...)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// accountBalances breaks down the balance of an exchange traded through several accounts.
+func (s *Strategy) accountBalances(ex exchange.Exchange) []string {
+	balancer, ok := ex.(exchange.AccountBalancer)
+	if !ok {
+		return nil
+	}
+	asset := collateral.AssetOf(ex)
+	balances, err := balancer.GetAccountBalances(s.ctx, asset)
+	if err != nil {
+		return []string{fmt.Sprintf("  accounts: unavailable (%v)", err)}
+	}
+	names := make([]string, 0, len(balances))
+	for name := range balances {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var lines []string
+	for _, name := range names {
+		lines = append(lines, fmt.Sprintf("  %s: %.2f %s", name, balances[name], asset))
+	}
+	return lines
+}
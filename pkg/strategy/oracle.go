@@ -0,0 +1,58 @@
+package strategy
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/config"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/exchange"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/oracle"
+)
+
+// newOracleChecker returns the configured price oracle cross-check, or nil when ORACLE is unset.
+func newOracleChecker(cfg config.Config, logger *log.Logger) *oracle.Checker {
+	switch cfg.Oracle {
+	case "":
+		return nil
+	case "pyth":
+		feeds, err := oracle.ParseFeeds(cfg.OracleFeeds)
+		if err != nil {
+			logger.Printf("Ignoring ORACLE_FEEDS: %v", err)
+		}
+		return oracle.NewChecker(oracle.NewPyth(feeds), cfg.OracleMaxDeviation)
+	default:
+		logger.Printf("Unknown ORACLE %q, price cross-check disabled.", cfg.Oracle)
+		return nil
+	}
+}
+
+// checkOracle compares the mark price of market on each venue with the reference oracle. A venue
+// trading away from the reference means the spread is a venue-specific dislocation, so entering
+// would take directional risk; that is reported as an error. Venues or markets that can't be
+// priced are logged and don't block the entry.
+func (s *Strategy) checkOracle(market string, venues ...exchange.Exchange) error {
+	if s.oracle == nil {
+		return nil
+	}
+	marks := make(map[string]float64, len(venues))
+	for _, ex := range venues {
+		price, err := s.marketData.MarkPrice(s.ctx, ex, market)
+		if err != nil {
+			s.logger.Printf("Could not get %s mark price on %s for the oracle check: %v", market, ex.Name(), err)
+			continue
+		}
+		marks[ex.Name()] = price
+	}
+
+	err := s.oracle.Check(market, marks)
+	var deviation *oracle.DeviationError
+	if errors.As(err, &deviation) {
+		s.notifier.SendMessage(fmt.Sprintf("⚠️ Entry blocked: %v", deviation))
+		return deviation
+	}
+	if err != nil {
+		s.logger.Printf("Oracle cross-check skipped for %s: %v", market, err)
+	}
+	return nil
+}
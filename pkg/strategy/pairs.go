@@ -0,0 +1,198 @@
+package strategy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/exchange"
+)
+
+// pair is a long/short venue combination on one market, oriented to short the higher rate. Its
+// rates are hourly, so venues that pay funding at different intervals compare fairly.
+type pair struct {
+	longEx    exchange.Exchange
+	shortEx   exchange.Exchange
+	longRate  float64
+	shortRate float64
+}
+
+// diff is the funding rate the pair collects per hour.
+func (p pair) diff() float64 {
+	return p.shortRate - p.longRate
+}
+
+// bestPair scans every pair of venues quoting market and returns the one with the widest funding
+// rate difference. On a tie the pair listed first in the configuration wins. It reports false
+// when fewer than two venues quote the market.
+func bestPair(market string, venues []exchange.Exchange, rates map[string]map[string]float64) (pair, bool) {
+	var best pair
+	found := false
+	for i, a := range venues {
+		rateA, ok := rates[a.Name()][market]
+		if !ok {
+			continue
+		}
+		rateA = hourlyRate(a, rateA)
+		for _, b := range venues[i+1:] {
+			rateB, ok := rates[b.Name()][market]
+			if !ok {
+				continue
+			}
+			rateB = hourlyRate(b, rateB)
+			p := pair{longEx: b, shortEx: a, longRate: rateB, shortRate: rateA}
+			if rateB > rateA {
+				p = pair{longEx: a, shortEx: b, longRate: rateA, shortRate: rateB}
+			}
+			if !found || p.diff() > best.diff() {
+				best, found = p, true
+			}
+		}
+	}
+	return best, found
+}
+
+// hourlyRate converts a rate quoted per funding interval of ex into a rate per hour.
+func hourlyRate(ex exchange.Exchange, rate float64) float64 {
+	return rate * float64(time.Hour) / float64(exchange.FundingIntervalOf(ex))
+}
+
+// defaultFundingFetchTimeout is used when FUNDING_FETCH_TIMEOUT_SECONDS is not set.
+const defaultFundingFetchTimeout = 10 * time.Second
+
+// fundingFetchTimeout is how long each exchange has to answer a funding rate request.
+func (s *Strategy) fundingFetchTimeout() time.Duration {
+	if s.config.FundingFetchTimeoutSeconds > 0 {
+		return time.Duration(s.config.FundingFetchTimeoutSeconds * float64(time.Second))
+	}
+	return defaultFundingFetchTimeout
+}
+
+// fundingRates fetches the funding rates of every exchange, keyed by exchange name and market,
+// and returns the exchanges that answered. Rates are per funding interval of their exchange, as
+// reported. With USE_PREDICTED_RATES the predicted rate of the next interval is used wherever the
+// venue publishes one, so positions are opened and closed ahead of the settled rates. Exchanges
+// are fetched concurrently, each within FUNDING_FETCH_TIMEOUT_SECONDS, so a slow venue doesn't
+// hold up the others; those that fail or time out are flagged stale and left out.
+func (s *Strategy) fundingRates() (map[string]map[string]float64, []exchange.Exchange) {
+	type result struct {
+		rates []*exchange.FundingRate
+		err   error
+	}
+	results := make([]result, len(s.exchanges))
+	var wg sync.WaitGroup
+	for i, ex := range s.exchanges {
+		wg.Add(1)
+		go func(i int, ex exchange.Exchange) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(s.ctx, s.fundingFetchTimeout())
+			defer cancel()
+			results[i].rates, results[i].err = s.marketData.FundingRates(ctx, ex)
+		}(i, ex)
+	}
+	wg.Wait()
+
+	rates := make(map[string]map[string]float64, len(s.exchanges))
+	var venues []exchange.Exchange
+	var stale []string
+	for i, ex := range s.exchanges {
+		fetched, err := results[i].rates, results[i].err
+		s.recordVenueResult(ex, err)
+		if err != nil {
+			if errors.Is(err, context.DeadlineExceeded) && s.ctx.Err() == nil {
+				err = fmt.Errorf("no answer within %s", s.fundingFetchTimeout())
+			}
+			s.logger.Printf("Error getting funding rates from %s, leaving it out of this check: %v", ex.Name(), err)
+			stale = append(stale, s.describeStale(ex, err))
+			continue
+		}
+		byMarket := make(map[string]float64, len(fetched))
+		for _, r := range fetched {
+			byMarket[r.Market] = r.Rate.InexactFloat64()
+			if s.config.UsePredictedRates && r.HasPredicted {
+				byMarket[r.Market] = r.PredictedRate.InexactFloat64()
+			}
+			s.observeFunding(ex, r)
+		}
+		rates[ex.Name()] = byMarket
+		venues = append(venues, ex)
+	}
+	s.mu.Lock()
+	s.staleRates = stale
+	s.mu.Unlock()
+	return rates, venues
+}
+
+// priceMarkets prices every market of MARKETS each venue has a funding rate for through the
+// market data cache, with one request per venue where it can price many markets at once. The
+// prices are then reused from the cache, within its TTL, by markPrice and decisionPrice for the
+// rest of the check instead of being fetched one market at a time. Venues are priced
+// concurrently; one that fails is only logged, its markets are priced on demand.
+func (s *Strategy) priceMarkets(rates map[string]map[string]float64, venues []exchange.Exchange) {
+	var wg sync.WaitGroup
+	for _, ex := range venues {
+		var markets []string
+		for _, market := range s.config.Markets {
+			if _, ok := rates[ex.Name()][market]; ok {
+				markets = append(markets, market)
+			}
+		}
+		if len(markets) == 0 {
+			continue
+		}
+		wg.Add(1)
+		go func(ex exchange.Exchange, markets []string) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(s.ctx, s.fundingFetchTimeout())
+			defer cancel()
+			if _, err := s.marketData.MarkPrices(ctx, ex, markets); err != nil {
+				s.logger.Printf("Could not price the markets on %s in one request, pricing them on demand: %v", ex.Name(), err)
+			}
+		}(ex, markets)
+	}
+	wg.Wait()
+}
+
+// describeStale describes the funding rates of ex as stale after a failed fetch, with the age of
+// the last ones it did return.
+func (s *Strategy) describeStale(ex exchange.Exchange, err error) string {
+	updated, ok := s.marketData.RatesUpdated(ex.Name())
+	if !ok {
+		return fmt.Sprintf("%s (never fetched: %v)", ex.Name(), err)
+	}
+	return fmt.Sprintf("%s (last fetched %s ago: %v)", ex.Name(), time.Since(updated).Round(time.Second), err)
+}
+
+// describeStaleRates lists the exchanges left out of the last funding rate check, or returns ""
+// if none was. The caller must hold s.mu.
+func (s *Strategy) describeStaleRates() string {
+	if len(s.staleRates) == 0 {
+		return ""
+	}
+	return "Stale funding rates, left out of the last check: " + strings.Join(s.staleRates, "; ")
+}
+
+// describeRates formats the rate of market on each venue for the log.
+func describeRates(market string, venues []exchange.Exchange, rates map[string]map[string]float64) string {
+	parts := make([]string, 0, len(venues))
+	for _, ex := range venues {
+		if rate, ok := rates[ex.Name()][market]; ok {
+			parts = append(parts, fmt.Sprintf("%s Rate: %.6f", ex.Name(), rate))
+		} else {
+			parts = append(parts, fmt.Sprintf("%s Rate: n/a", ex.Name()))
+		}
+	}
+	return strings.Join(parts, " | ")
+}
+
+// exchangeNames returns the names of the configured exchanges in order.
+func (s *Strategy) exchangeNames() []string {
+	names := make([]string, len(s.exchanges))
+	for i, ex := range s.exchanges {
+		names[i] = ex.Name()
+	}
+	return names
+}
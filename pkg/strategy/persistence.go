@@ -0,0 +1,96 @@
+package strategy
+
+import (
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/exchange"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/state"
+)
+
+// SetStateStore enables persisting open positions so they survive a restart.
+func (s *Strategy) SetStateStore(store *state.Store) {
+	s.state = store
+}
+
+// restorePositions reloads the positions saved by a previous run. Positions on exchanges that
+// are no longer configured can't be managed and are reported instead.
+func (s *Strategy) restorePositions() {
+	saved, err := s.state.Load(DefaultName)
+	if err != nil {
+		s.logger.Printf("Could not load saved positions, starting without them: %v", err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, p := range saved {
+		longEx, shortEx := s.exchangeByName(p.LongExchange), s.exchangeByName(p.ShortExchange)
+		if longEx == nil || shortEx == nil {
+			s.logger.Printf("WARNING: Saved %s position (long %s, short %s) uses an exchange that is not configured, it must be managed manually.",
+				p.Market, p.LongExchange, p.ShortExchange)
+			continue
+		}
+		if err := s.capital.Reserve(DefaultName, p.SizeUSD); err != nil {
+			s.logger.Printf("Restored %s position exceeds the capital budget: %v", p.Market, err)
+		}
+		s.positions[p.Market] = &PositionInfo{
+			Market:          p.Market,
+			LongExchange:    longEx,
+			ShortExchange:   shortEx,
+			SizeUSD:         p.SizeUSD,
+			Amount:          p.Amount,
+			EntryRateDiff:   p.EntryRateDiff,
+			EntrySlippage:   p.EntrySlippage,
+			OpenedAt:        p.OpenedAt,
+			LongEntryPrice:  p.LongEntryPrice,
+			ShortEntryPrice: p.ShortEntryPrice,
+			Funding:         p.Funding,
+			FundingSyncedAt: p.FundingSyncedAt,
+			CollectAfter:    p.CollectAfter,
+		}
+		s.logger.Printf("Restored %s position: long %s, short %s, %.2f USD, opened %s.",
+			p.Market, p.LongExchange, p.ShortExchange, p.SizeUSD, p.OpenedAt.Format("2006-01-02 15:04:05"))
+	}
+}
+
+// persistPositions saves the open positions. The caller must hold s.mu.
+func (s *Strategy) persistPositions() {
+	if s.state == nil {
+		return
+	}
+	if err := s.state.Save(DefaultName, s.savedPositions()); err != nil {
+		s.logger.Printf("Failed to save positions: %v", err)
+	}
+}
+
+// savedPositions returns the open positions in the form they are saved in. The caller must hold
+// s.mu.
+func (s *Strategy) savedPositions() []state.Position {
+	positions := make([]state.Position, 0, len(s.positions))
+	for _, p := range s.positions {
+		positions = append(positions, state.Position{
+			Market:          p.Market,
+			LongExchange:    p.LongExchange.Name(),
+			ShortExchange:   p.ShortExchange.Name(),
+			SizeUSD:         p.SizeUSD,
+			Amount:          p.Amount,
+			EntryRateDiff:   p.EntryRateDiff,
+			EntrySlippage:   p.EntrySlippage,
+			OpenedAt:        p.OpenedAt,
+			LongEntryPrice:  p.LongEntryPrice,
+			ShortEntryPrice: p.ShortEntryPrice,
+			Funding:         p.Funding,
+			FundingSyncedAt: p.FundingSyncedAt,
+			CollectAfter:    p.CollectAfter,
+		})
+	}
+	return positions
+}
+
+// exchangeByName returns the configured exchange called name, or nil.
+func (s *Strategy) exchangeByName(name string) exchange.Exchange {
+	for _, ex := range s.exchanges {
+		if ex.Name() == name {
+			return ex
+		}
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package strategy
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/exchange"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/export"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/journal"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/pnl"
+)
+
+// fundingSyncInterval is how often the funding payments of open positions are fetched.
+const fundingSyncInterval = 15 * time.Minute
+
+// fillPrice returns the price order filled at, or fallback when the venue didn't report one.
+func fillPrice(order *exchange.Order, fallback float64) float64 {
+	if order != nil && order.Price.IsPositive() {
+		return order.Price.InexactFloat64()
+	}
+	return fallback
+}
+
+// syncFunding fetches the funding paid and received on every open position from the exchanges
+// that report it, and journals and exports the payments not seen before.
+func (s *Strategy) syncFunding() {
+	s.mu.Lock()
+	positions := make([]*PositionInfo, 0, len(s.positions))
+	for _, position := range s.positions {
+		positions = append(positions, position)
+	}
+	s.mu.Unlock()
+
+	for _, position := range positions {
+		s.syncPositionFunding(position)
+	}
+
+	s.mu.Lock()
+	s.persistPositions()
+	s.mu.Unlock()
+}
+
+// syncPositionFunding refreshes the funding of one position. The total is recomputed from every
+// payment since the position was opened, so a failed or repeated sync never double counts.
+func (s *Strategy) syncPositionFunding(position *PositionInfo) {
+	s.mu.Lock()
+	openedAt, lastSynced := position.OpenedAt, position.FundingSyncedAt
+	s.mu.Unlock()
+
+	total := 0.0
+	var fresh []journal.Entry
+	synced := lastSynced
+	for _, ex := range []exchange.Exchange{position.LongExchange, position.ShortExchange} {
+		reporter, ok := ex.(exchange.FundingPaymentReporter)
+		if !ok {
+			continue
+		}
+		payments, err := reporter.GetFundingPayments(s.ctx, position.Market, openedAt)
+		if errors.Is(err, exchange.ErrFundingPaymentsUnsupported) {
+			continue
+		}
+		if err != nil {
+			s.logger.Printf("Could not fetch %s funding payments from %s, keeping the last known total: %v", position.Market, ex.Name(), err)
+			return
+		}
+		for _, payment := range payments {
+			total += payment.Amount
+			if payment.Time.After(lastSynced) {
+				fresh = append(fresh, journal.Entry{Time: payment.Time.UTC(), Type: journal.EntryFunding, Exchange: ex.Name(), Market: position.Market, Funding: payment.Amount})
+				if payment.Time.After(synced) {
+					synced = payment.Time
+				}
+			}
+		}
+	}
+
+	for _, entry := range fresh {
+		s.activity.addFunding(entry.Funding)
+		if err := s.journal.Record(entry); err != nil {
+			s.logger.Printf("Failed to record %s funding on %s to the journal: %v", entry.Market, entry.Exchange, err)
+		}
+		s.sheets.ExportFundingPayment(export.FundingPayment{Exchange: entry.Exchange, Market: entry.Market, Amount: entry.Funding, Time: entry.Time})
+	}
+	s.mu.Lock()
+	position.Funding = total
+	position.FundingSyncedAt = synced
+	s.mu.Unlock()
+}
+
+// pnlPosition values position at the current mark prices of its venues. The caller must not hold
+// s.mu, since pricing may call the exchanges.
+func (s *Strategy) pnlPosition(position PositionInfo) pnl.Position {
+	longMark, _ := s.marketData.MarkPrice(s.ctx, position.LongExchange, position.Market)
+	shortMark, _ := s.marketData.MarkPrice(s.ctx, position.ShortExchange, position.Market)
+	return pnlOf(position, longMark, shortMark)
+}
+
+// pnlOf is the PnL of position with its legs at the given prices.
+func pnlOf(position PositionInfo, longPrice, shortPrice float64) pnl.Position {
+	amount := position.Amount.InexactFloat64()
+	if entry := (position.LongEntryPrice + position.ShortEntryPrice) / 2; amount == 0 && entry > 0 {
+		amount = position.SizeUSD / entry
+	}
+	return pnl.Position{
+		Market:   position.Market,
+		Long:     pnl.Leg{Exchange: position.LongExchange.Name(), EntryPrice: position.LongEntryPrice, Price: longPrice},
+		Short:    pnl.Leg{Exchange: position.ShortExchange.Name(), EntryPrice: position.ShortEntryPrice, Price: shortPrice},
+		Amount:   amount,
+		SizeUSD:  position.SizeUSD,
+		Funding:  position.Funding,
+		OpenedAt: position.OpenedAt,
+	}
+}
+
+// realizePnL books the PnL of a position closed at the given exit prices, after collecting the
+// funding paid up to the close, and returns it.
+func (s *Strategy) realizePnL(position *PositionInfo, longExit, shortExit float64) pnl.Position {
+	s.syncPositionFunding(position)
+	s.mu.Lock()
+	closed := pnlOf(*position, longExit, shortExit)
+	s.mu.Unlock()
+	closed.ClosedAt = time.Now()
+	s.pnl.Close(closed)
+	s.logger.Printf("Realized PnL on %s: funding %+.2f USD, price %+.2f USD, net %+.2f USD.",
+		position.Market, closed.Funding, closed.PricePnL(), closed.NetPnL())
+	return closed
+}
+
+// PnL returns the realized PnL since the strategy started and the unrealized PnL of the open
+// positions at the current mark prices.
+func (s *Strategy) PnL() pnl.Summary {
+	s.mu.Lock()
+	positions := make([]PositionInfo, 0, len(s.positions))
+	for _, position := range s.positions {
+		positions = append(positions, *position)
+	}
+	s.mu.Unlock()
+
+	open := make([]pnl.Position, 0, len(positions))
+	for _, position := range positions {
+		open = append(open, s.pnlPosition(position))
+	}
+	return s.pnl.Summary(open)
+}
+
+// reportPnL refreshes funding, then logs and sends the PnL summary.
+func (s *Strategy) reportPnL() {
+	s.syncFunding()
+	summary := pnl.Format(s.PnL())
+	s.logger.Printf("PnL:\n%s", summary)
+	s.notifier.SendMessage(fmt.Sprintf("💰 PnL summary\n%s", summary))
+}
+
+// pnlReportInterval is how often reportPnL runs, or zero when disabled.
+func (s *Strategy) pnlReportInterval() time.Duration {
+	return time.Duration(s.config.PnlReportHours * float64(time.Hour))
+}
@@ -0,0 +1,170 @@
+package strategy
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/exchange"
+)
+
+// rebalanceInterval is how often open positions are compared against the best opportunities,
+// REBALANCE_INTERVAL_MINUTES.
+func (s *Strategy) rebalanceInterval() time.Duration {
+	return time.Duration(s.config.RebalanceIntervalMinutes * float64(time.Minute))
+}
+
+// swapCandidate is an opportunity an open position could be swapped into.
+type swapCandidate struct {
+	opportunity
+	// annual is the opportunity's annualized rate difference.
+	annual float64
+}
+
+// heldSpread is an open position and the annualized rate difference between its venues now.
+type heldSpread struct {
+	position *PositionInfo
+	annual   float64
+}
+
+// checkRebalance swaps open positions into better opportunities when the extra funding expected
+// over ENTRY_HORIZON_HOURS, a week by default, beats the cost of the swap by REBALANCE_EDGE. The
+// cost is closing the position plus the estimated round trip of the new one. A position can move
+// to a wider venue pair on its own market, or, when MAX_POSITION_USD leaves no room for a market
+// above the entry threshold, give up its capital to that market. The narrowest positions are
+// swapped first, into the widest opportunities.
+func (s *Strategy) checkRebalance() {
+	s.mu.Lock()
+	paused := s.paused
+	held := make(map[string]heldSpread, len(s.positions))
+	for market, position := range s.positions {
+		held[market] = heldSpread{position: position}
+	}
+	free := s.config.MaxPositionUSD - s.getTotalPositionValue()
+	s.mu.Unlock()
+	if paused || len(held) == 0 {
+		return
+	}
+
+	rates, venues := s.fundingRates()
+	now := time.Now()
+	for market, h := range held {
+		// Positions within MIN_HOLD_MINUTES, or whose spread can't be measured, are kept.
+		position := h.position
+		shortRate, okShort := rates[position.ShortExchange.Name()][market]
+		longRate, okLong := rates[position.LongExchange.Name()][market]
+		if !okShort || !okLong || now.Sub(position.OpenedAt) < s.minHold() {
+			delete(held, market)
+			continue
+		}
+		h.annual = exchange.Annualize(hourlyRate(position.ShortExchange, shortRate)-hourlyRate(position.LongExchange, longRate), time.Hour)
+		held[market] = h
+	}
+
+	openable := s.healthyVenues(venues)
+	var candidates []swapCandidate
+	for _, market := range s.config.Markets {
+		best, ok := bestPair(market, openable, rates)
+		if !ok || best.diff() <= 0 {
+			continue
+		}
+		candidate := swapCandidate{opportunity: opportunity{market: market, longEx: best.longEx, shortEx: best.shortEx, rateDiff: best.diff()},
+			annual: exchange.Annualize(best.diff(), time.Hour)}
+		s.mu.Lock()
+		_, open := s.positions[market]
+		s.mu.Unlock()
+		if h, ok := held[market]; ok {
+			if best.longEx == h.position.LongExchange && best.shortEx == h.position.ShortExchange {
+				continue
+			}
+		} else if open || free >= s.config.PositionSizeUSD || best.diff() <= s.entryThreshold(market) ||
+			s.blackout.Blocked(market, now) != "" || s.cooldownRemaining(market, now) > 0 {
+			// Markets the regular check would open given the room, and only those, are worth
+			// making room for.
+			continue
+		}
+		if !s.inEntryWindow(market, best.longEx, best.shortEx, now) {
+			continue
+		}
+		candidates = append(candidates, candidate)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].annual > candidates[j].annual })
+
+	for _, candidate := range candidates {
+		h, ok := held[candidate.market]
+		if !ok {
+			h, ok = narrowestHeld(held)
+		}
+		if !ok {
+			return
+		}
+		if reason, ok := s.swapWorthwhile(h, candidate); !ok {
+			s.logger.Printf("Not swapping %s into %s: %s.", h.position.Market, candidate.market, reason)
+			continue
+		}
+		delete(held, h.position.Market)
+		s.swap(h, candidate)
+	}
+}
+
+// narrowestHeld returns the open position with the narrowest spread.
+func narrowestHeld(held map[string]heldSpread) (heldSpread, bool) {
+	var narrowest heldSpread
+	found := false
+	for _, h := range held {
+		if !found || h.annual < narrowest.annual || h.annual == narrowest.annual && h.position.Market < narrowest.position.Market {
+			narrowest, found = h, true
+		}
+	}
+	return narrowest, found
+}
+
+// swapWorthwhile reports whether swapping h into candidate is expected to pay for itself by
+// REBALANCE_EDGE over the horizon, or else why not.
+func (s *Strategy) swapWorthwhile(h heldSpread, candidate swapCandidate) (string, bool) {
+	size := h.position.SizeUSD
+	horizon := s.config.EntryHorizonHours
+	if horizon <= 0 {
+		horizon = defaultScoreHorizonHours
+	}
+	gain := (candidate.annual - h.annual) * size * horizon / (365 * 24)
+	if gain <= 0 {
+		return "the spread is no wider", false
+	}
+	cost, err := s.estimateRoundTripCost(candidate.market, candidate.longEx, candidate.shortEx, size)
+	if err != nil {
+		return err.Error(), false
+	}
+	cost += s.fillCosts(h.position.LongExchange, h.position.ShortExchange, size) / 2
+	if required := cost * (1 + s.config.RebalanceEdge); gain <= required {
+		return fmt.Sprintf("%.2f USD more funding over %g hours doesn't beat the %.2f USD cost of the swap with a %g%% edge", gain, horizon, cost, s.config.RebalanceEdge*100), false
+	}
+	return "", true
+}
+
+// swap closes h and opens candidate with the capital it held.
+func (s *Strategy) swap(h heldSpread, candidate swapCandidate) {
+	position := h.position
+	s.logger.Printf("Swapping %s (long %s / short %s, %.2f%% a year) into %s (long %s / short %s, %.2f%% a year).",
+		position.Market, position.LongExchange.Name(), position.ShortExchange.Name(), h.annual*100,
+		candidate.market, candidate.longEx.Name(), candidate.shortEx.Name(), candidate.annual*100)
+	s.closeArbitrage(position)
+	s.mu.Lock()
+	_, stillOpen := s.positions[position.Market]
+	s.mu.Unlock()
+	if stillOpen {
+		return
+	}
+	s.executeArbitrage(candidate.market, candidate.longEx, candidate.shortEx, candidate.rateDiff, position.SizeUSD)
+
+	s.mu.Lock()
+	_, opened := s.positions[candidate.market]
+	s.mu.Unlock()
+	message := fmt.Sprintf("🔁 Rebalanced %.2f USD from %s (long %s / short %s, %.2f%% a year) to %s (long %s / short %s, %.2f%% a year).",
+		position.SizeUSD, position.Market, position.LongExchange.Name(), position.ShortExchange.Name(), h.annual*100,
+		candidate.market, candidate.longEx.Name(), candidate.shortEx.Name(), candidate.annual*100)
+	if !opened {
+		message += " The new position could not be opened, its capital is free for the next check."
+	}
+	s.notifier.SendMessage(message)
+}
@@ -0,0 +1,180 @@
+package strategy
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/exchange"
+)
+
+// adoptSizeTolerance is how far apart, relatively, two unknown legs may be in size and still be
+// adopted as one hedged position.
+const adoptSizeTolerance = 0.01
+
+// reconcile compares the recorded positions with the positions the exchanges report and flags
+// any drift. With RECONCILE_REPAIR, legs whose hedge is gone are closed, recorded positions that
+// are no longer open are forgotten, and unknown hedged pairs are adopted. Unknown single legs are
+// only reported, since they may have been opened by hand.
+func (s *Strategy) reconcile() {
+	held := make(map[string]map[string]exchange.Position)
+	for _, ex := range s.exchanges {
+		positions, err := ex.GetPositions(s.ctx)
+		if err != nil {
+			s.logger.Printf("Skipping reconciliation, could not get positions from %s: %v", ex.Name(), err)
+			return
+		}
+		byMarket := make(map[string]exchange.Position, len(positions))
+		for _, p := range positions {
+			byMarket[p.Market] = p
+		}
+		held[ex.Name()] = byMarket
+	}
+
+	repair := s.config.ReconcileRepair
+	var issues []string
+	// Legs whose hedge is gone are closed after s.mu is released, as rollbackLeg requires.
+	type unhedgedLeg struct {
+		position *PositionInfo
+		exchange exchange.Exchange
+		leg      exchange.Position
+	}
+	var unhedged []unhedgedLeg
+	// Unknown hedged pairs are adopted once priced, after s.mu is released too.
+	type unknownPair struct {
+		market          string
+		longEx, shortEx exchange.Exchange
+		leg             exchange.Position
+	}
+	var unknown []unknownPair
+	s.mu.Lock()
+
+	for market, position := range s.positions {
+		long, hasLong := held[position.LongExchange.Name()][market]
+		hasLong = hasLong && long.Side == exchange.Buy
+		short, hasShort := held[position.ShortExchange.Name()][market]
+		hasShort = hasShort && short.Side == exchange.Sell
+		if hasLong {
+			delete(held[position.LongExchange.Name()], market)
+		}
+		if hasShort {
+			delete(held[position.ShortExchange.Name()], market)
+		}
+
+		switch {
+		case hasLong && hasShort:
+			continue
+		case !hasLong && !hasShort:
+			issues = append(issues, fmt.Sprintf("%s: recorded position is not open on %s or %s", market, position.LongExchange.Name(), position.ShortExchange.Name()))
+			if repair {
+				s.forgetPosition(position)
+			}
+		default:
+			ex, leg := position.LongExchange, long
+			if hasShort {
+				ex, leg = position.ShortExchange, short
+			}
+			issues = append(issues, fmt.Sprintf("%s: only the %s leg on %s is open, the hedge is missing", market, leg.Side, ex.Name()))
+			if repair {
+				unhedged = append(unhedged, unhedgedLeg{position: position, exchange: ex, leg: leg})
+			}
+		}
+	}
+
+	// Unknown legs are paired across any two venues holding opposite sides of matching size.
+	for i, ex := range s.exchanges {
+		for market, leg := range held[ex.Name()] {
+			for _, other := range s.exchanges[i+1:] {
+				hedge, ok := held[other.Name()][market]
+				if !ok || hedge.Side == leg.Side || math.Abs(hedge.Size-leg.Size) > adoptSizeTolerance*leg.Size {
+					continue
+				}
+				delete(held[ex.Name()], market)
+				delete(held[other.Name()], market)
+				longEx, shortEx := ex, other
+				if leg.Side == exchange.Sell {
+					longEx, shortEx = other, ex
+				}
+				issues = append(issues, fmt.Sprintf("%s: unknown hedged position, long %s / short %s of %f", market, longEx.Name(), shortEx.Name(), leg.Size))
+				if repair {
+					unknown = append(unknown, unknownPair{market: market, longEx: longEx, shortEx: shortEx, leg: leg})
+				}
+				break
+			}
+		}
+	}
+	for _, ex := range s.exchanges {
+		for market, leg := range held[ex.Name()] {
+			issues = append(issues, fmt.Sprintf("%s: unknown %s leg of %f on %s", market, leg.Side, leg.Size, ex.Name()))
+		}
+	}
+	s.mu.Unlock()
+
+	for _, u := range unhedged {
+		if _, _, err := s.rollbackLeg(u.exchange, u.position.Market, u.leg.Side, decimal.NewFromFloat(u.leg.Size)); err == nil {
+			s.mu.Lock()
+			s.forgetPosition(u.position)
+			s.mu.Unlock()
+		}
+	}
+	for _, u := range unknown {
+		price := u.leg.EntryPrice
+		if mark, err := s.markPrice(u.market, u.longEx, u.shortEx); err == nil {
+			price = mark
+		}
+		s.mu.Lock()
+		s.adoptPosition(u.market, u.longEx, u.shortEx, u.leg, price)
+		s.mu.Unlock()
+	}
+
+	if len(issues) == 0 {
+		s.logger.Println("Reconciliation: recorded positions match the exchanges.")
+		return
+	}
+	if repair {
+		s.mu.Lock()
+		s.persistPositions()
+		s.mu.Unlock()
+	}
+	for _, issue := range issues {
+		s.logger.Printf("Reconciliation mismatch: %s", issue)
+	}
+	action := "Set RECONCILE_REPAIR=true to repair automatically, or resolve manually."
+	if repair {
+		action = "Repairs were attempted, check the log for failures."
+	}
+	s.notifier.SendMessage(fmt.Sprintf("⚠️ Position reconciliation found %d mismatch(es):\n%s\n%s", len(issues), strings.Join(issues, "\n"), action))
+}
+
+// forgetPosition drops a recorded position and releases its capital. The caller must hold s.mu.
+func (s *Strategy) forgetPosition(position *PositionInfo) {
+	delete(s.positions, position.Market)
+	s.capital.Release(DefaultName, position.SizeUSD)
+}
+
+// adoptPosition starts managing a hedged pair the bot has no record of, valued at price. The entry
+// rate difference is unknown, so the position is closed on the first check where the spread no
+// longer favors it. A position recorded on the market in the meantime is kept. The caller must
+// hold s.mu.
+func (s *Strategy) adoptPosition(market string, longEx, shortEx exchange.Exchange, leg exchange.Position, price float64) {
+	if _, exists := s.positions[market]; exists {
+		return
+	}
+	sizeUSD := leg.Size * price
+	if err := s.capital.Reserve(DefaultName, sizeUSD); err != nil {
+		s.logger.Printf("Adopted %s position exceeds the capital budget: %v", market, err)
+	}
+	s.positions[market] = &PositionInfo{
+		Market:        market,
+		LongExchange:  longEx,
+		ShortExchange: shortEx,
+		SizeUSD:       sizeUSD,
+		OpenedAt:      time.Now(),
+		// The fill prices are unknown, so price PnL is measured from adoption.
+		LongEntryPrice:  price,
+		ShortEntryPrice: price,
+	}
+}
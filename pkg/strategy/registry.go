@@ -0,0 +1,116 @@
+package strategy
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/config"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/capital"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/exchange"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/export"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/journal"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/marketdata"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/metrics"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/notifications"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/state"
+)
+
+// DefaultName is the strategy used when none is configured.
+const DefaultName = "funding-rate-arb"
+
+// Runner is a strategy that can be started by the trade command.
+// Run must block until stop is closed.
+type Runner interface {
+	Run(stop chan struct{})
+}
+
+// Dependencies are the shared services handed to every strategy factory.
+type Dependencies struct {
+	Config    config.Config
+	Exchanges []exchange.Exchange
+	Spot      exchange.SpotExchange
+	Logger    *log.Logger
+	Notifier  notifications.Notifier
+	Sheets    *export.SheetsExporter
+	Journal   *journal.Journal
+	Capital   *capital.Manager
+	State     *state.Store
+	Metrics   *metrics.Metrics
+	// MarketData is the rate and price cache shared by the strategies and the status API. Each
+	// strategy keeps its own when it is nil.
+	MarketData *marketdata.Cache
+}
+
+// Factory builds a strategy from its dependencies.
+type Factory func(deps Dependencies) (Runner, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Factory)
+)
+
+// Register makes a strategy available under name. It is intended to be called from init
+// functions, so that importing a package is enough to make its strategy selectable:
+//
+//	func init() {
+//		strategy.Register("my-strategy", func(deps strategy.Dependencies) (strategy.Runner, error) {
+//			return newMyStrategy(deps), nil
+//		})
+//	}
+//
+// Register panics if name is empty, the factory is nil, or name is already registered.
+func Register(name string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if name == "" || factory == nil {
+		panic("strategy: Register called with empty name or nil factory")
+	}
+	if _, dup := registry[name]; dup {
+		panic("strategy: Register called twice for " + name)
+	}
+	registry[name] = factory
+}
+
+// New builds the strategy registered under name.
+func New(name string, deps Dependencies) (Runner, error) {
+	if name == "" {
+		name = DefaultName
+	}
+	registryMu.RLock()
+	factory, ok := registry[name]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown strategy %q (available: %v)", name, Names())
+	}
+	return factory(deps)
+}
+
+// Names returns the registered strategy names in sorted order.
+func Names() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	Register(DefaultName, func(deps Dependencies) (Runner, error) {
+		if len(deps.Exchanges) < 2 {
+			return nil, fmt.Errorf("%s requires at least two exchanges, got %d", DefaultName, len(deps.Exchanges))
+		}
+		s := NewFundingRateArb(deps.Config, deps.Exchanges, deps.Logger, deps.Notifier)
+		s.SetSheetsExporter(deps.Sheets)
+		s.SetJournal(deps.Journal)
+		s.SetCapitalManager(deps.Capital)
+		s.SetStateStore(deps.State)
+		s.SetMetrics(deps.Metrics)
+		s.SetMarketData(deps.MarketData)
+		return s, nil
+	})
+}
@@ -0,0 +1,155 @@
+package strategy
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/exchange"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/notifications"
+)
+
+// liquidationCheckInterval is how often the legs of open positions are checked for liquidation
+// risk.
+const liquidationCheckInterval = time.Minute
+
+// liquidationRisk is the leg of a position closest to liquidation.
+type liquidationRisk struct {
+	exchange exchange.Exchange
+	distance float64
+}
+
+// checkLiquidationRisk fetches how close each leg of every open position is to liquidation. A leg
+// within LIQUIDATION_ALERT_DISTANCE of its liquidation price raises an alert, once until it
+// recovers, and with DELEVERAGE_TARGET_DISTANCE set both legs are reduced to restore that buffer.
+// Venues that don't report position risk are skipped.
+func (s *Strategy) checkLiquidationRisk() {
+	s.mu.Lock()
+	positions := make([]*PositionInfo, 0, len(s.positions))
+	for _, position := range s.positions {
+		positions = append(positions, position)
+	}
+	s.mu.Unlock()
+
+	// Alerts are kept for the positions still at risk, so closed positions are forgotten.
+	alerted := make(map[string]bool)
+	defer func() { s.liquidationAlerts = alerted }()
+	for _, position := range positions {
+		closest, ok := s.closestToLiquidation(position)
+		if !ok {
+			alerted[position.Market] = s.liquidationAlerts[position.Market]
+			continue
+		}
+		if closest.distance >= s.config.LiquidationAlertDistance {
+			continue
+		}
+		alerted[position.Market] = true
+		if !s.liquidationAlerts[position.Market] {
+			message := fmt.Sprintf("⚠️ The %s leg on %s is %.2f%% from liquidation.", position.Market, closest.exchange.Name(), closest.distance*100)
+			s.logger.Print(message)
+			s.notifier.SendMessage(message)
+			notifications.Publish(s.notifier, notifications.Event{Type: notifications.EventRiskAlert, Market: position.Market,
+				Exchange: closest.exchange.Name(), Message: message})
+		}
+		if s.config.DeleverageTargetDistance > 0 {
+			s.deleverage(position, closest.distance)
+		}
+	}
+}
+
+// closestToLiquidation returns the leg of position nearest its liquidation price. It reports
+// false when neither venue reports a liquidation price for the position.
+func (s *Strategy) closestToLiquidation(position *PositionInfo) (liquidationRisk, bool) {
+	var closest liquidationRisk
+	found := false
+	for _, ex := range []exchange.Exchange{position.LongExchange, position.ShortExchange} {
+		reporter, ok := ex.(exchange.PositionRiskReporter)
+		if !ok {
+			continue
+		}
+		risk, err := reporter.GetPositionRisk(s.ctx, position.Market)
+		if errors.Is(err, exchange.ErrPositionRiskUnsupported) {
+			continue
+		}
+		if err != nil {
+			s.logger.Printf("Could not check the liquidation risk of %s on %s: %v", position.Market, ex.Name(), err)
+			continue
+		}
+		distance, ok := risk.LiquidationDistance()
+		if !ok {
+			continue
+		}
+		if !found || distance < closest.distance {
+			closest, found = liquidationRisk{exchange: ex, distance: distance}, true
+		}
+	}
+	return closest, found
+}
+
+// deleverage reduces both legs of position by the same fraction so that the leg at distance from
+// liquidation moves back to DELEVERAGE_TARGET_DISTANCE. With its margin unchanged, a leg's
+// distance to liquidation grows roughly in inverse proportion to its size, so keeping
+// distance/target of the position restores the buffer.
+func (s *Strategy) deleverage(position *PositionInfo, distance float64) {
+	keep := distance / s.config.DeleverageTargetDistance
+	if keep >= 1 {
+		return
+	}
+	currentPrice, err := s.markPrice(position.Market, position.LongExchange, position.ShortExchange)
+	if err != nil {
+		s.logger.Printf("Cannot deleverage %s: %v", position.Market, err)
+		return
+	}
+
+	s.mu.Lock()
+	if _, exists := s.positions[position.Market]; !exists {
+		s.mu.Unlock()
+		return
+	}
+	full := position.amountAt(currentPrice)
+	sizeUSD := position.SizeUSD
+	s.mu.Unlock()
+	amount, err := s.lotAmount(position.Market, position.LongExchange, position.ShortExchange, full.Mul(decimal.NewFromFloat(1-keep)))
+	if err != nil {
+		s.logger.Printf("Cannot deleverage %s: %v", position.Market, err)
+		return
+	}
+	reduceUSD := sizeUSD * amount.Div(full).InexactFloat64()
+	s.logger.Printf("Deleveraging %s: reducing both legs by %.2f USD to restore a %.2f%% liquidation buffer.",
+		position.Market, reduceUSD, s.config.DeleverageTargetDistance*100)
+
+	// Like closes, the reductions are not cancelled on shutdown, which could leave the legs uneven.
+	unwind := s.unwindContext()
+	longOrder, longErr := position.LongExchange.ClosePosition(unwind, position.Market, exchange.Buy, amount)
+	s.recordOrder(position.LongExchange, position.Market, exchange.Sell, exchange.Market, amount, currentPrice, longOrder, longErr)
+	shortOrder, shortErr := position.ShortExchange.ClosePosition(unwind, position.Market, exchange.Sell, amount)
+	s.recordOrder(position.ShortExchange, position.Market, exchange.Buy, exchange.Market, amount, currentPrice, shortOrder, shortErr)
+	s.notifier.SendPositionNotification("DELEVERAGE LONG", position.LongExchange.Name(), position.Market, reduceUSD, longErr)
+	s.notifier.SendPositionNotification("DELEVERAGE SHORT", position.ShortExchange.Name(), position.Market, reduceUSD, shortErr)
+
+	if longErr != nil || shortErr != nil {
+		// Reducing only one leg leaves the position unhedged by the difference, which the
+		// operator has to resolve; the tracked size is left alone until then.
+		s.logger.Printf("Failed to deleverage %s evenly (long: %v, short: %v); the legs may no longer match.", position.Market, longErr, shortErr)
+		s.recordError("", position.Market, fmt.Sprintf("deleveraging failed, long: %v, short: %v", longErr, shortErr))
+		return
+	}
+
+	s.mu.Lock()
+	position.SizeUSD -= reduceUSD
+	if position.Amount.IsPositive() {
+		position.Amount = position.Amount.Sub(amount)
+	}
+	remaining := position.SizeUSD
+	s.persistPositions()
+	s.mu.Unlock()
+	s.capital.Release(DefaultName, reduceUSD)
+	s.logger.Printf("Deleveraged %s to %.2f USD.", position.Market, remaining)
+}
+
+// liquidationCheckEnabled reports whether open positions are monitored for liquidation risk.
+func (s *Strategy) liquidationCheckEnabled() bool {
+	return s.config.LiquidationAlertDistance > 0
+}
@@ -0,0 +1,72 @@
+package strategy
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/config"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/exchange"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/notifications"
+)
+
+const (
+	// defaultRollbackAttempts is used when ROLLBACK_ATTEMPTS is not set.
+	defaultRollbackAttempts = 3
+	// defaultRollbackDelay is the wait before the first retry when ROLLBACK_RETRY_DELAY_MS is not set.
+	defaultRollbackDelay = 500 * time.Millisecond
+)
+
+// rollbackPolicy bounds how hard the strategy tries to close a leg whose hedge failed. The delay
+// doubles after every failed attempt.
+type rollbackPolicy struct {
+	attempts int
+	delay    time.Duration
+}
+
+func newRollbackPolicy(cfg config.Config) rollbackPolicy {
+	policy := rollbackPolicy{attempts: cfg.RollbackAttempts, delay: time.Duration(cfg.RollbackRetryDelayMs) * time.Millisecond}
+	if policy.attempts <= 0 {
+		policy.attempts = defaultRollbackAttempts
+	}
+	if policy.delay <= 0 {
+		policy.delay = defaultRollbackDelay
+	}
+	return policy
+}
+
+// rollbackLeg market-closes a leg opened with side, retrying with backoff. It returns the closing
+// order and the latency of the successful attempt. If every attempt fails the exposure is
+// escalated to the operator and new entries are paused, since the account is no longer hedged.
+// The caller must not hold s.mu, which would block status requests through the retries.
+func (s *Strategy) rollbackLeg(ex exchange.Exchange, market string, side exchange.OrderSide, amount decimal.Decimal) (*exchange.Order, time.Duration, error) {
+	ctx := s.unwindContext()
+	delay := s.rollback.delay
+	var err error
+	for attempt := 1; attempt <= s.rollback.attempts; attempt++ {
+		start := time.Now()
+		var order *exchange.Order
+		order, err = ex.ClosePosition(ctx, market, side, amount)
+		s.recordOrder(ex, market, oppositeSide(side), exchange.Market, amount, 0, order, err)
+		if err == nil {
+			return order, time.Since(start), nil
+		}
+		s.logger.Printf("Rollback attempt %d/%d of the %s leg on %s for %s failed: %v", attempt, s.rollback.attempts, side, ex.Name(), market, err)
+		if attempt < s.rollback.attempts {
+			time.Sleep(delay)
+			delay *= 2
+		}
+	}
+
+	s.mu.Lock()
+	s.paused = true
+	s.mu.Unlock()
+	s.recordError(ex.Name(), market, fmt.Sprintf("rollback of the %s leg failed after %d attempts, strategy paused: %v", side, s.rollback.attempts, err))
+	s.logger.Printf("CRITICAL: Could not roll back the %s leg on %s for %s, strategy paused. Manual intervention is required.", side, ex.Name(), market)
+	s.notifier.SendMessage(fmt.Sprintf("🚨 ROLLBACK FAILED\nUnhedged %s %s %s on %s after %d attempts: %v\nNew entries are paused until the position is closed manually and the strategy is resumed.",
+		side, amount, market, ex.Name(), s.rollback.attempts, err))
+	notifications.Publish(s.notifier, notifications.Event{Type: notifications.EventRiskAlert, Market: market, Exchange: ex.Name(),
+		Action: "ROLLBACK " + string(side), Message: "rollback failed, the leg is unhedged and the strategy is paused", Error: err.Error()})
+	return nil, 0, err
+}
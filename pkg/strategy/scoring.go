@@ -0,0 +1,113 @@
+package strategy
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/exchange"
+)
+
+// defaultScoreHorizonHours is how long an opportunity is assumed to be held when its round-trip
+// cost is amortized, unless ENTRY_HORIZON_HOURS is set.
+const defaultScoreHorizonHours = 7 * 24
+
+// rankOpportunities scores each opportunity with scoreOpportunity and orders them best first, so
+// the capital under MAX_POSITION_USD goes to the best ones rather than to the first markets in
+// MARKETS.
+func (s *Strategy) rankOpportunities(opportunities []opportunity) []opportunity {
+	for i := range opportunities {
+		opportunities[i].score = s.scoreOpportunity(opportunities[i])
+	}
+	sort.SliceStable(opportunities, func(i, j int) bool { return opportunities[i].better(opportunities[j]) })
+	if len(opportunities) > 1 {
+		for i, opp := range opportunities {
+			s.logger.Printf("Opportunity #%d: %s long %s / short %s, %.2f%% annualized after costs.", i+1, opp.market, opp.longEx.Name(), opp.shortEx.Name(), opp.score*100)
+		}
+	}
+	return opportunities
+}
+
+// better reports whether o ranks before other: a higher score, or the wider rate difference for
+// equal scores.
+func (o opportunity) better(other opportunity) bool {
+	if o.score != other.score {
+		return o.score > other.score
+	}
+	return o.rateDiff > other.rateDiff
+}
+
+// scoreOpportunity returns the annualized rate difference of opp less its round-trip cost for
+// POSITION_SIZE_USD as a fraction of the size, amortized over ENTRY_HORIZON_HOURS, a week by
+// default. The cost includes the slippage of walking both order books, so markets too thin for the
+// size rank lower; an opportunity whose cost can't be estimated ranks last.
+func (s *Strategy) scoreOpportunity(opp opportunity) float64 {
+	annual := exchange.Annualize(opp.rateDiff, time.Hour)
+	size := s.config.PositionSizeUSD
+	if size <= 0 {
+		return annual
+	}
+	cost, err := s.estimateRoundTripCost(opp.market, opp.longEx, opp.shortEx, size)
+	if err != nil {
+		s.logger.Printf("Ranking %s last: %v", opp.market, err)
+		return math.Inf(-1)
+	}
+	horizon := s.config.EntryHorizonHours
+	if horizon <= 0 {
+		horizon = defaultScoreHorizonHours
+	}
+	return annual - exchange.Annualize(cost/size, time.Duration(horizon*float64(time.Hour)))
+}
+
+// rotatePositions makes room under MAX_POSITION_USD for the ranked opportunities that don't fit,
+// best first, by closing the open position with the narrowest current annualized spread, as given
+// by held, when the opportunity's score beats it by more than ROTATION_MIN_ANNUAL_GAIN. Closed
+// positions are removed from held.
+func (s *Strategy) rotatePositions(ranked []opportunity, held map[string]float64) {
+	if s.config.RotationMinAnnualGain <= 0 || len(held) == 0 {
+		return
+	}
+	s.mu.Lock()
+	free := s.config.MaxPositionUSD - s.getTotalPositionValue()
+	s.mu.Unlock()
+	for _, opp := range ranked {
+		if free >= s.config.PositionSizeUSD {
+			free -= s.config.PositionSizeUSD
+			continue
+		}
+		weakest, ok := weakestPosition(held)
+		if !ok || opp.score-held[weakest] <= s.config.RotationMinAnnualGain {
+			return
+		}
+		s.mu.Lock()
+		position, exists := s.positions[weakest]
+		s.mu.Unlock()
+		annual := held[weakest]
+		delete(held, weakest)
+		if !exists {
+			continue
+		}
+		s.logger.Printf("Rotating out of %s (%.2f%% annualized) for %s (%.2f%% annualized after costs).", weakest, annual*100, opp.market, opp.score*100)
+		s.closeArbitrage(position)
+		s.mu.Lock()
+		_, stillOpen := s.positions[weakest]
+		s.mu.Unlock()
+		if stillOpen {
+			return
+		}
+		if free += position.SizeUSD; free >= s.config.PositionSizeUSD {
+			free -= s.config.PositionSizeUSD
+		}
+	}
+}
+
+// weakestPosition returns the market in held with the narrowest spread.
+func weakestPosition(held map[string]float64) (string, bool) {
+	weakest, found := "", false
+	for market, annual := range held {
+		if !found || annual < held[weakest] || annual == held[weakest] && market < weakest {
+			weakest, found = market, true
+		}
+	}
+	return weakest, found
+}
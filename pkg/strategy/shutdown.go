@@ -0,0 +1,161 @@
+package strategy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ShutdownPolicy decides what happens to the open positions when the bot is stopped.
+type ShutdownPolicy string
+
+const (
+	// ShutdownPreserve leaves the positions open, to be restored on the next start.
+	ShutdownPreserve ShutdownPolicy = "preserve"
+	// ShutdownFlatten closes every position before exiting.
+	ShutdownFlatten ShutdownPolicy = "flatten"
+	// ShutdownAskTelegram asks the operator on Telegram whether to flatten, and preserves the
+	// positions if there is no answer within SHUTDOWN_ASK_TIMEOUT_SECONDS.
+	ShutdownAskTelegram ShutdownPolicy = "ask-telegram"
+)
+
+// defaultShutdownAskTimeout is used when SHUTDOWN_ASK_TIMEOUT_SECONDS is not set.
+const defaultShutdownAskTimeout = 2 * time.Minute
+
+// ParseShutdownPolicy parses SHUTDOWN_POLICY, case-insensitively. An empty value is ShutdownPreserve.
+func ParseShutdownPolicy(s string) (ShutdownPolicy, error) {
+	switch policy := ShutdownPolicy(strings.ToLower(strings.TrimSpace(s))); policy {
+	case "":
+		return ShutdownPreserve, nil
+	case ShutdownPreserve, ShutdownFlatten, ShutdownAskTelegram:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown shutdown policy %q, expected preserve, flatten or ask-telegram", s)
+	}
+}
+
+// ShutdownAskTimeout returns how long ShutdownAskTelegram waits for an answer.
+func ShutdownAskTimeout(seconds float64) time.Duration {
+	if seconds <= 0 {
+		return defaultShutdownAskTimeout
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// shutdownPolicy returns the configured SHUTDOWN_POLICY, or ShutdownPreserve if it is invalid.
+func (s *Strategy) shutdownPolicy() ShutdownPolicy {
+	policy, err := ParseShutdownPolicy(s.config.ShutdownPolicy)
+	if err != nil {
+		s.logger.Printf("Ignoring SHUTDOWN_POLICY: %v", err)
+		return ShutdownPreserve
+	}
+	return policy
+}
+
+// shutdown applies SHUTDOWN_POLICY to the positions still open once Run is stopped.
+func (s *Strategy) shutdown(policy ShutdownPolicy) {
+	positions := s.openPositions()
+	if len(positions) == 0 {
+		return
+	}
+	markets := make([]string, len(positions))
+	for i, position := range positions {
+		markets[i] = position.Market
+	}
+
+	var answer *Command
+	if policy == ShutdownAskTelegram {
+		policy, answer = s.askShutdownPolicy(markets)
+	}
+
+	var err error
+	if policy == ShutdownFlatten {
+		s.logger.Printf("Shutting down: closing %d position(s): %s", len(positions), strings.Join(markets, ", "))
+		for _, position := range positions {
+			s.closeArbitrage(position)
+		}
+		err = s.openPositionsError()
+	} else {
+		message := fmt.Sprintf("Shutting down: leaving %d position(s) open: %s. They are restored on the next start.", len(positions), strings.Join(markets, ", "))
+		s.logger.Println(message)
+		s.notifier.SendMessage("⏹ " + message)
+	}
+	if err != nil {
+		s.logger.Printf("Shutting down: %v", err)
+		s.notifier.SendMessage(fmt.Sprintf("⚠️ Shutting down: %v", err))
+	}
+	if answer != nil && answer.Reply != nil {
+		answer.Reply <- err
+	}
+}
+
+// askShutdownPolicy asks the operator whether to flatten the positions on markets, and waits for
+// /flatten or /preserve. It returns the policy chosen and the command that answered, nil on timeout.
+func (s *Strategy) askShutdownPolicy(markets []string) (ShutdownPolicy, *Command) {
+	timeout := ShutdownAskTimeout(s.config.ShutdownAskTimeoutSeconds)
+	s.logger.Printf("Shutting down: asking whether to close %d position(s), waiting up to %s.", len(markets), timeout)
+	s.notifier.SendMessage(fmt.Sprintf("⏹ Shutting down with %d open position(s): %s. Reply /flatten to close them or /preserve to leave them open. They are left open if there is no answer within %s.",
+		len(markets), strings.Join(markets, ", "), timeout))
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	for {
+		select {
+		case cmd := <-s.events.commands:
+			switch cmd.Name {
+			case CommandFlatten:
+				return ShutdownFlatten, &cmd
+			case CommandPreserve:
+				return ShutdownPreserve, &cmd
+			}
+			if cmd.Reply != nil {
+				cmd.Reply <- fmt.Errorf("shutting down, answer /flatten or /preserve")
+			}
+		case <-deadline.C:
+			s.logger.Printf("Shutting down: no answer within %s.", timeout)
+			return ShutdownPreserve, nil
+		}
+	}
+}
+
+// flattenAll closes every open position on operator request.
+func (s *Strategy) flattenAll() error {
+	positions := s.openPositions()
+	if len(positions) == 0 {
+		return fmt.Errorf("no open positions")
+	}
+	s.logger.Printf("Closing %d position(s) on operator request.", len(positions))
+	for _, position := range positions {
+		s.closeArbitrage(position)
+	}
+	return s.openPositionsError()
+}
+
+// openPositions returns the open positions, ordered by market.
+func (s *Strategy) openPositions() []*PositionInfo {
+	s.mu.Lock()
+	positions := make([]*PositionInfo, 0, len(s.positions))
+	for _, position := range s.positions {
+		positions = append(positions, position)
+	}
+	s.mu.Unlock()
+	sort.Slice(positions, func(i, j int) bool { return positions[i].Market < positions[j].Market })
+	return positions
+}
+
+// openPositionsError reports the positions left open after closing all of them, which happens
+// when their venues can't be priced.
+func (s *Strategy) openPositionsError() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.positions) == 0 {
+		return nil
+	}
+	markets := make([]string, 0, len(s.positions))
+	for market := range s.positions {
+		markets = append(markets, market)
+	}
+	sort.Strings(markets)
+	return fmt.Errorf("%d position(s) could not be closed: %s", len(markets), strings.Join(markets, ", "))
+}
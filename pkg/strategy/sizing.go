@@ -0,0 +1,171 @@
+package strategy
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/allocator"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/exchange"
+)
+
+// opportunity is a market whose funding rate difference exceeds the entry threshold.
+type opportunity struct {
+	market   string
+	longEx   exchange.Exchange
+	shortEx  exchange.Exchange
+	rateDiff float64
+	// score ranks the opportunity, see scoreOpportunity.
+	score   float64
+	sizeUSD float64
+}
+
+// sizeOpportunities assigns a position size to each opportunity. With the portfolio allocator
+// disabled every opportunity gets the flat PositionSizeUSD, or a size scaled by its rate
+// difference and volatility with DYNAMIC_SIZING; otherwise the remaining capital is split across
+// opportunities by score, subject to per-market caps, venue margin and
+// correlation between markets.
+func (s *Strategy) sizeOpportunities(opportunities []opportunity) []opportunity {
+	if !s.config.AllocatorEnabled && s.config.DynamicSizing {
+		return s.scaleOpportunities(opportunities)
+	}
+	if !s.config.AllocatorEnabled {
+		for i := range opportunities {
+			opportunities[i].sizeUSD = s.config.PositionSizeUSD
+		}
+		return opportunities
+	}
+	if len(opportunities) == 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	remaining := s.config.MaxPositionUSD - s.getTotalPositionValue()
+	s.mu.Unlock()
+
+	byKey := make(map[string]opportunity, len(opportunities))
+	candidates := make([]allocator.Opportunity, 0, len(opportunities))
+	for _, opp := range opportunities {
+		byKey[opp.market] = opp
+		candidates = append(candidates, allocator.Opportunity{
+			Market:        opp.market,
+			LongExchange:  opp.longEx.Name(),
+			ShortExchange: opp.shortEx.Name(),
+			Score:         opp.score,
+		})
+	}
+
+	allocations := allocator.Allocate(candidates, allocator.Constraints{
+		TotalCapitalUSD:    remaining,
+		PerMarketCapUSD:    s.config.PerMarketCapUSD,
+		MinSizeUSD:         s.config.MinPositionSizeUSD,
+		Leverage:           s.config.Leverage,
+		VenueMarginUSD:     s.venueMargins(),
+		Correlations:       parseCorrelations(s.config.MarketCorrelations),
+		CorrelationPenalty: s.config.CorrelationPenalty,
+	})
+
+	sized := make([]opportunity, 0, len(allocations))
+	for _, alloc := range allocations {
+		opp := byKey[alloc.Market]
+		opp.sizeUSD = alloc.SizeUSD
+		s.logger.Printf("Allocator assigned %.2f USD to %s (score %.4f)", alloc.SizeUSD, alloc.Market, alloc.Score)
+		sized = append(sized, opp)
+	}
+	return sized
+}
+
+// scaleOpportunities sizes each opportunity with dynamicSize, best score first, capped
+// by PER_MARKET_CAP_USD and by the capital left under MAX_POSITION_USD. Opportunities that can't
+// get MIN_POSITION_SIZE_USD are skipped.
+func (s *Strategy) scaleOpportunities(opportunities []opportunity) []opportunity {
+	s.mu.Lock()
+	remaining := s.config.MaxPositionUSD - s.getTotalPositionValue()
+	s.mu.Unlock()
+
+	sort.SliceStable(opportunities, func(i, j int) bool { return opportunities[i].better(opportunities[j]) })
+	sized := make([]opportunity, 0, len(opportunities))
+	for _, opp := range opportunities {
+		size := s.dynamicSize(opp)
+		if s.config.PerMarketCapUSD > 0 {
+			size = math.Min(size, s.config.PerMarketCapUSD)
+		}
+		size = math.Min(size, remaining)
+		if size <= 0 || size < s.config.MinPositionSizeUSD {
+			s.logger.Printf("Not enough capital left under MAX_POSITION_USD to size %s, skipping it.", opp.market)
+			continue
+		}
+		remaining -= size
+		opp.sizeUSD = size
+		sized = append(sized, opp)
+	}
+	return sized
+}
+
+// dynamicSize scales the size of opp from MIN_POSITION_SIZE_USD at the entry threshold up to
+// POSITION_SIZE_USD at SIZING_FULL_ANNUAL_DIFF, twice the threshold by default, both annualized.
+// When the market's daily volatility exceeds SIZING_TARGET_VOLATILITY the size shrinks in
+// proportion, down to MIN_POSITION_SIZE_USD.
+func (s *Strategy) dynamicSize(opp opportunity) float64 {
+	hi := s.config.PositionSizeUSD
+	lo := math.Min(s.config.MinPositionSizeUSD, hi)
+
+	annual := exchange.Annualize(opp.rateDiff, time.Hour)
+	entry := exchange.Annualize(s.entryThreshold(opp.market), time.Hour)
+	full := s.config.SizingFullAnnualDiff
+	if full <= 0 {
+		full = 2 * entry
+	}
+	conviction := 1.0
+	if full > entry {
+		conviction = math.Max(0, math.Min(1, (annual-entry)/(full-entry)))
+	}
+	size := lo + (hi-lo)*conviction
+
+	if target := s.config.SizingTargetVolatility; target > 0 {
+		if vol, ok := s.prices.dailyVolatility(opp.market); ok && vol > target {
+			size = math.Max(lo, size*target/vol)
+		}
+	}
+	s.logger.Printf("Sized %s at %.2f USD for a %.2f%% annualized rate difference.", opp.market, size, annual*100)
+	return size
+}
+
+// venueMargins returns the free collateral reported by each exchange, converted to USD.
+// Exchanges whose balance cannot be fetched or priced are left out, which the allocator
+// treats as unconstrained.
+func (s *Strategy) venueMargins() map[string]float64 {
+	margins := make(map[string]float64)
+	for _, ex := range s.exchanges {
+		balance, err := s.collateral.BalanceUSD(s.ctx, ex)
+		if err != nil {
+			s.logger.Printf("Could not get balance from %s, not constraining its margin: %v", ex.Name(), err)
+			continue
+		}
+		margins[ex.Name()] = balance
+	}
+	return margins
+}
+
+// parseCorrelations parses entries of the form "BTC-USD:ETH-USD=0.8".
+func parseCorrelations(entries []string) map[[2]string]float64 {
+	table := make(map[[2]string]float64)
+	for _, entry := range entries {
+		pair, value, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok {
+			continue
+		}
+		a, b, ok := strings.Cut(pair, ":")
+		if !ok {
+			continue
+		}
+		corr, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			continue
+		}
+		table[[2]string{a, b}] = corr
+	}
+	return table
+}
@@ -0,0 +1,291 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/config"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/capital"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/collateral"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/exchange"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/marketdata"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/notifications"
+)
+
+// SpotHedgeName is the registry name of the spot-perp hedge strategy.
+const SpotHedgeName = "spot-perp-hedge"
+
+// spotFeeBuffer is the extra quote balance required on top of the spot notional to cover fees and price moves.
+const spotFeeBuffer = 0.005
+
+// spotHedgePosition tracks a perp short hedged by a spot holding.
+type spotHedgePosition struct {
+	Market     string
+	SpotAmount decimal.Decimal
+	SizeUSD    float64
+	EntryRate  float64
+	OpenedAt   time.Time
+}
+
+// SpotHedge collects positive funding by shorting a perpetual and buying the same amount of the
+// underlying on a spot exchange, for markets where no second perp venue is available.
+type SpotHedge struct {
+	config     config.Config
+	perp       exchange.Exchange
+	spot       exchange.SpotExchange
+	logger     *log.Logger
+	notifier   notifications.Notifier
+	marketData *marketdata.Cache
+	collateral *collateral.Converter
+	capital    *capital.Manager
+	margin     *marginSelector
+	positions  map[string]*spotHedgePosition
+	mu         sync.Mutex
+}
+
+// NewSpotHedge creates a spot-perp hedge strategy instance.
+func NewSpotHedge(cfg config.Config, perp exchange.Exchange, spot exchange.SpotExchange, logger *log.Logger, notifier notifications.Notifier) *SpotHedge {
+	if notifier == nil {
+		notifier = notifications.Discard
+	}
+	return &SpotHedge{
+		config:     cfg,
+		perp:       perp,
+		spot:       spot,
+		logger:     logger,
+		notifier:   notifier,
+		marketData: NewMarketData(cfg),
+		collateral: newCollateralConverter(cfg, logger, perp),
+		margin:     newMarginSelector(cfg, logger),
+		positions:  make(map[string]*spotHedgePosition),
+	}
+}
+
+// SetMarketData makes the strategy read prices through cache, shared with the other strategies
+// and the status API. A nil cache keeps the strategy's own.
+func (h *SpotHedge) SetMarketData(cache *marketdata.Cache) {
+	if cache != nil {
+		h.marketData = cache
+	}
+}
+
+// SetCapitalManager shares capital with other strategies running in the same process.
+func (h *SpotHedge) SetCapitalManager(manager *capital.Manager) {
+	h.capital = manager
+}
+
+// Run starts the spot-perp hedge loop.
+func (h *SpotHedge) Run(stop chan struct{}) {
+	h.logger.Println("Starting spot-perp hedge strategy...")
+	h.logger.Printf("Perp: %s, Spot: %s (quote %s)", h.perp.Name(), h.spot.Name(), h.spot.QuoteAsset())
+	h.logger.Printf("Markets: %v", h.config.Markets)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cancelOnStop(stop, nil, cancel)
+
+	delay := checkInterval(h.config) + checkJitter(h.config)
+	if h.config.CheckOnStart {
+		delay = 0
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			h.check(ctx)
+			timer.Reset(checkInterval(h.config) + checkJitter(h.config))
+		case <-stop:
+			h.logger.Println("Stopping strategy...")
+			return
+		}
+	}
+}
+
+// check opens hedges where shorts are paid enough funding and closes them when funding turns negative.
+func (h *SpotHedge) check(ctx context.Context) {
+	rates, err := h.marketData.FundingRates(ctx, h.perp)
+	if err != nil {
+		h.logger.Printf("Error getting funding rates from %s: %v", h.perp.Name(), err)
+		return
+	}
+	ratesMap := make(map[string]float64, len(rates))
+	for _, r := range rates {
+		ratesMap[r.Market] = r.Rate.InexactFloat64()
+	}
+
+	for _, market := range h.config.Markets {
+		rate, ok := ratesMap[market]
+		if !ok {
+			h.logger.Printf("Market %s not available on %s, skipping.", market, h.perp.Name())
+			continue
+		}
+
+		h.mu.Lock()
+		position, exists := h.positions[market]
+		h.mu.Unlock()
+
+		switch {
+		case !exists && rate > h.config.MinFundingRateDiff:
+			h.open(ctx, market, rate)
+		case exists && rate <= 0:
+			h.logger.Printf("Funding on %s for %s is no longer positive (%.6f). Closing hedge.", h.perp.Name(), market, rate)
+			h.close(ctx, position)
+		}
+	}
+}
+
+// checkBalances verifies the spot quote balance covers the purchase and the perp venue has margin for the short.
+func (h *SpotHedge) checkBalances(ctx context.Context, sizeUSD float64) error {
+	quote, err := h.spot.GetBalance(ctx, h.spot.QuoteAsset())
+	if err != nil {
+		return fmt.Errorf("could not get %s balance on %s: %w", h.spot.QuoteAsset(), h.spot.Name(), err)
+	}
+	quoteUSD, err := h.collateral.ToUSD(ctx, h.spot.QuoteAsset(), quote)
+	if err != nil {
+		return err
+	}
+	if quoteUSD < sizeUSD*(1+spotFeeBuffer) {
+		return fmt.Errorf("insufficient %s on %s: have %.2f USD, need %.2f USD", h.spot.QuoteAsset(), h.spot.Name(), quoteUSD, sizeUSD*(1+spotFeeBuffer))
+	}
+
+	marginUSD, err := h.collateral.BalanceUSD(ctx, h.perp)
+	if err != nil {
+		return fmt.Errorf("could not get collateral on %s: %w", h.perp.Name(), err)
+	}
+	leverage := h.config.Leverage
+	if leverage < 1 {
+		leverage = 1
+	}
+	if marginUSD < sizeUSD/leverage {
+		return fmt.Errorf("insufficient margin on %s: have %.2f USD, need %.2f USD", h.perp.Name(), marginUSD, sizeUSD/leverage)
+	}
+	return nil
+}
+
+// open buys spot first and then shorts the perp for the quantity actually bought, so lot-size
+// rounding on the spot venue doesn't leave the legs mismatched.
+func (h *SpotHedge) open(ctx context.Context, market string, rate float64) {
+	sizeUSD := h.config.PositionSizeUSD
+
+	h.mu.Lock()
+	total := 0.0
+	for _, p := range h.positions {
+		total += p.SizeUSD
+	}
+	h.mu.Unlock()
+	if total+sizeUSD > h.config.MaxPositionUSD {
+		h.logger.Printf("Cannot open new hedge, max total position size of %.2f USD would be exceeded.", h.config.MaxPositionUSD)
+		return
+	}
+
+	if err := h.checkBalances(ctx, sizeUSD); err != nil {
+		h.logger.Printf("Skipping spot hedge for %s: %v", market, err)
+		return
+	}
+
+	price, err := h.spot.GetPrice(ctx, market)
+	if err != nil {
+		h.logger.Printf("Could not get spot price for %s: %v", market, err)
+		return
+	}
+
+	if err := h.margin.apply(ctx, market, h.perp); err != nil {
+		h.logger.Printf("Skipping spot hedge for %s: %v", market, err)
+		return
+	}
+
+	if err := h.capital.Reserve(SpotHedgeName, sizeUSD); err != nil {
+		h.logger.Printf("Skipping spot hedge for %s: %v", market, err)
+		return
+	}
+
+	amount := decimal.NewFromFloat(sizeUSD).Div(decimal.NewFromFloat(price))
+	h.logger.Printf("Opening spot hedge for %s: funding %.6f, buying %s on %s", market, rate, amount, h.spot.Name())
+	spotOrder, err := h.spot.PlaceSpotOrder(ctx, market, exchange.Buy, amount)
+	h.notifier.SendPositionNotification("OPEN SPOT LONG", h.spot.Name(), market, sizeUSD, err)
+	if err != nil {
+		h.logger.Printf("Failed to buy spot on %s: %v", h.spot.Name(), err)
+		h.capital.Release(SpotHedgeName, sizeUSD)
+		return
+	}
+	amount = spotOrder.Filled
+	if amount.IsZero() {
+		amount = spotOrder.Amount
+	}
+
+	_, err = h.perp.PlaceOrder(ctx, market, exchange.Sell, exchange.Market, amount, decimal.NewFromFloat(price))
+	h.notifier.SendPositionNotification("OPEN SHORT", h.perp.Name(), market, sizeUSD, err)
+	if err != nil {
+		h.logger.Printf("Failed to short %s on %s: %v. Selling spot back...", market, h.perp.Name(), err)
+		// The unwind is not cancelled on shutdown, which would leave the spot leg unhedged.
+		_, unwindErr := h.spot.PlaceSpotOrder(context.WithoutCancel(ctx), market, exchange.Sell, amount)
+		h.notifier.SendPositionNotification("COMPENSATE SPOT", h.spot.Name(), market, sizeUSD, unwindErr)
+		if unwindErr != nil {
+			h.logger.Printf("CRITICAL: Failed to sell spot on %s: %v. Manual intervention may be required.", h.spot.Name(), unwindErr)
+		}
+		h.capital.Release(SpotHedgeName, sizeUSD)
+		return
+	}
+
+	h.mu.Lock()
+	h.positions[market] = &spotHedgePosition{
+		Market:     market,
+		SpotAmount: amount,
+		SizeUSD:    sizeUSD,
+		EntryRate:  rate,
+		OpenedAt:   time.Now(),
+	}
+	h.mu.Unlock()
+	h.logger.Printf("Successfully opened spot hedge for %s (%s units).", market, amount)
+}
+
+// close buys back the perp short and sells the spot holding. Once started, the close runs to
+// completion even if the strategy is stopped.
+func (h *SpotHedge) close(ctx context.Context, position *spotHedgePosition) {
+	ctx = context.WithoutCancel(ctx)
+	h.mu.Lock()
+	if _, exists := h.positions[position.Market]; !exists {
+		h.mu.Unlock()
+		return
+	}
+	delete(h.positions, position.Market)
+	h.mu.Unlock()
+
+	_, perpErr := h.perp.ClosePosition(ctx, position.Market, exchange.Sell, position.SpotAmount)
+	h.notifier.SendPositionNotification("CLOSE SHORT", h.perp.Name(), position.Market, position.SizeUSD, perpErr)
+	if perpErr != nil {
+		h.logger.Printf("Failed to close SHORT position on %s: %v", h.perp.Name(), perpErr)
+	}
+
+	_, spotErr := h.spot.PlaceSpotOrder(ctx, position.Market, exchange.Sell, position.SpotAmount)
+	h.notifier.SendPositionNotification("CLOSE SPOT LONG", h.spot.Name(), position.Market, position.SizeUSD, spotErr)
+	if spotErr != nil {
+		h.logger.Printf("Failed to sell spot on %s: %v", h.spot.Name(), spotErr)
+	}
+	h.capital.Release(SpotHedgeName, position.SizeUSD)
+}
+
+func init() {
+	Register(SpotHedgeName, func(deps Dependencies) (Runner, error) {
+		if deps.Spot == nil {
+			return nil, fmt.Errorf("%s requires a spot exchange (set SPOT_EXCHANGE)", SpotHedgeName)
+		}
+		name := deps.Config.SpotHedgePerpExchange
+		for _, ex := range deps.Exchanges {
+			if name == "" || ex.Name() == name {
+				h := NewSpotHedge(deps.Config, ex, deps.Spot, deps.Logger, deps.Notifier)
+				h.SetCapitalManager(deps.Capital)
+				h.SetMarketData(deps.MarketData)
+				return h, nil
+			}
+		}
+		return nil, fmt.Errorf("%s: perp exchange %q is not configured", SpotHedgeName, name)
+	})
+}
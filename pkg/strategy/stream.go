@@ -0,0 +1,43 @@
+package strategy
+
+import (
+	"errors"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/exchange"
+)
+
+// startStreams subscribes to the exchanges that push market data when STREAMING is enabled.
+// Pushed funding rates trigger a check, at most one per rateCheckInterval, pushed mark prices
+// refresh the market data cache and order updates are handled as fills. Exchanges that don't stream keep being polled.
+func (s *Strategy) startStreams(stop chan struct{}) {
+	if !s.config.Streaming {
+		return
+	}
+	s.logger.Println("Streaming enabled, subscribing to exchanges that push market data.")
+	for _, ex := range s.exchanges {
+		streamer, ok := ex.(exchange.StreamingExchange)
+		if !ok {
+			continue
+		}
+		name := ex.Name()
+		handler := exchange.StreamHandler{
+			OnFundingRates: func(rates []*exchange.FundingRate) {
+				s.PublishRates(name, rates)
+			},
+			OnMarkPrice: func(market string, price float64) {
+				s.marketData.StoreMarkPrice(name, market, price)
+			},
+			OnOrder: func(order *exchange.Order) {
+				s.PublishFill(order)
+			},
+			OnError: func(err error) {
+				s.logger.Printf("%s stream disconnected, reconnecting: %v", name, err)
+			},
+		}
+		go func() {
+			if err := streamer.Stream(s.config.Markets, handler, stop); err != nil && !errors.Is(err, exchange.ErrStreamingUnsupported) {
+				s.logger.Printf("Streaming from %s stopped: %v", name, err)
+			}
+		}()
+	}
+}
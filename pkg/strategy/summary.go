@@ -0,0 +1,144 @@
+package strategy
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/cron"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/export"
+)
+
+// summaryErrorLines is how many of the errors since the last summary are listed in it.
+const summaryErrorLines = 10
+
+// activityLog accumulates the trading activity reported by the periodic summary since the last
+// one. The zero value is ready to use.
+type activityLog struct {
+	mu      sync.Mutex
+	since   time.Time
+	funding float64
+	fees    float64
+	opened  int
+	closed  int
+	errors  []string
+}
+
+// activity is a snapshot of an activityLog.
+type activity struct {
+	Since   time.Time
+	Funding float64
+	Fees    float64
+	Opened  int
+	Closed  int
+	Errors  []string
+}
+
+func (l *activityLog) addFunding(amount float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.funding += amount
+}
+
+func (l *activityLog) addFee(fee float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.fees += fee
+}
+
+func (l *activityLog) addOpened() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.opened++
+}
+
+func (l *activityLog) addClosed() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.closed++
+}
+
+func (l *activityLog) addError(exchangeName, market, message string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	where := market
+	if exchangeName != "" {
+		where = exchangeName + " " + market
+	}
+	l.errors = append(l.errors, fmt.Sprintf("%s %s: %s", time.Now().UTC().Format("15:04"), where, message))
+}
+
+// drain returns the activity since the last drain, which started at start if the log has never
+// been drained, and empties the log.
+func (l *activityLog) drain(start, now time.Time) activity {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	a := activity{Since: l.since, Funding: l.funding, Fees: l.fees, Opened: l.opened, Closed: l.closed, Errors: l.errors}
+	if a.Since.IsZero() {
+		a.Since = start
+	}
+	l.since, l.funding, l.fees, l.opened, l.closed, l.errors = now, 0, 0, 0, 0, nil
+	return a
+}
+
+// sheetsSummaryCron is when the summary is exported to the Daily sheet if DAILY_SUMMARY_CRON is
+// empty.
+const sheetsSummaryCron = "@daily"
+
+// summarySchedule returns when the periodic summary is sent. Without DAILY_SUMMARY_CRON it is
+// only exported to the Daily sheet, at midnight UTC; it reports false when there is nowhere to
+// send it or the expression is invalid.
+func (s *Strategy) summarySchedule() (cron.Schedule, bool) {
+	expr := s.config.DailySummaryCron
+	if expr == "" {
+		if s.sheets == nil {
+			return cron.Schedule{}, false
+		}
+		expr = sheetsSummaryCron
+	}
+	schedule, err := cron.Parse(expr)
+	if err != nil {
+		s.logger.Printf("Invalid DAILY_SUMMARY_CRON, the summary is disabled: %v", err)
+		return cron.Schedule{}, false
+	}
+	return schedule, true
+}
+
+// reportSummary logs and sends the activity since the last summary, with the current exposure,
+// to every notification channel if DAILY_SUMMARY_CRON is set, and exports it to the Daily sheet.
+func (s *Strategy) reportSummary(start time.Time) {
+	s.syncFunding()
+	s.mu.Lock()
+	open, exposure := len(s.positions), s.getTotalPositionValue()
+	s.mu.Unlock()
+	now := time.Now()
+	a := s.activity.drain(start, now)
+	summary := formatSummary(a, open, exposure)
+	s.logger.Printf("Summary:\n%s", summary)
+	if s.config.DailySummaryCron != "" {
+		s.notifier.SendMessage(fmt.Sprintf("📅 Summary\n%s", summary))
+	}
+	s.sheets.ExportDailySummary(export.DailySummary{Date: now, FundingUSD: a.Funding, FeesUSD: a.Fees,
+		PositionsOpened: a.Opened, PositionsClosed: a.Closed, ExposureUSD: exposure})
+}
+
+// formatSummary renders the activity and the open positions as text.
+func formatSummary(a activity, open int, exposure float64) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Since %s UTC\n", a.Since.UTC().Format("2006-01-02 15:04"))
+	fmt.Fprintf(&b, "Funding collected: %+.2f USD\n", a.Funding)
+	fmt.Fprintf(&b, "Fees paid: %.2f USD\n", a.Fees)
+	fmt.Fprintf(&b, "Positions opened: %d, closed: %d\n", a.Opened, a.Closed)
+	fmt.Fprintf(&b, "Exposure: %d open position(s), %.2f USD\n", open, exposure)
+	fmt.Fprintf(&b, "Errors: %d", len(a.Errors))
+	errors := a.Errors
+	if len(errors) > summaryErrorLines {
+		errors = errors[len(errors)-summaryErrorLines:]
+		fmt.Fprintf(&b, ", the last %d:", summaryErrorLines)
+	}
+	for _, e := range errors {
+		fmt.Fprintf(&b, "\n- %s", e)
+	}
+	return b.String()
+}
@@ -0,0 +1,57 @@
+package strategy
+
+import (
+	"math"
+	"time"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/config"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/exchange"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/tuning"
+)
+
+// newThresholdTuner returns the adaptive threshold tuner, or nil when ADAPTIVE_THRESHOLD is off.
+func newThresholdTuner(cfg config.Config) *tuning.Threshold {
+	if !cfg.AdaptiveThreshold {
+		return nil
+	}
+	return tuning.NewThreshold(cfg.MinFundingRateDiff, cfg.AdaptiveThresholdFloor, cfg.AdaptiveThresholdCeiling, cfg.AdaptiveThresholdWindow)
+}
+
+// entryThreshold returns the minimum rate difference required to open a position on market.
+func (s *Strategy) entryThreshold(market string) float64 {
+	if s.thresholds == nil {
+		return s.config.MinFundingRateDiff
+	}
+	return s.thresholds.For(market)
+}
+
+// recordOutcome feeds a closed position back into the adaptive threshold.
+func (s *Strategy) recordOutcome(position *PositionInfo, exitSlippage float64) {
+	if s.thresholds == nil {
+		return
+	}
+	held := time.Since(position.OpenedAt)
+	outcome := tuning.Outcome{
+		Slippage:  position.EntrySlippage + exitSlippage,
+		Intervals: float64(held) / float64(exchange.FundingIntervalOf(position.ShortExchange)),
+	}
+	s.thresholds.Record(position.Market, outcome)
+	s.logger.Printf("Adaptive threshold for %s is now %.6f (slippage %.6f, spread lasted %.2f intervals)",
+		position.Market, s.thresholds.For(position.Market), outcome.Slippage, outcome.Intervals)
+}
+
+// slippage returns how far the prices reported on orders deviated from the reference price,
+// relative to it and summed over the orders. Orders without a price are ignored.
+func slippage(reference float64, orders ...*exchange.Order) float64 {
+	if reference <= 0 {
+		return 0
+	}
+	total := 0.0
+	for _, order := range orders {
+		if order == nil || !order.Price.IsPositive() {
+			continue
+		}
+		total += math.Abs(order.Price.InexactFloat64()-reference) / reference
+	}
+	return total
+}
@@ -0,0 +1,63 @@
+package strategy
+
+import (
+	"log"
+	"time"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/config"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/blackout"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/exchange"
+)
+
+// fundingSettleDelay is how long after a funding timestamp a position is kept with
+// EXIT_AFTER_FUNDING, so that the venues have booked the payment before it is closed.
+const fundingSettleDelay = time.Minute
+
+// entryWindow is how long before a funding timestamp positions may be opened, or zero when
+// entries are not timed.
+func (s *Strategy) entryWindow() time.Duration {
+	return time.Duration(s.config.EntryWindowMinutes * float64(time.Minute))
+}
+
+// inEntryWindow reports whether the next funding payment on either leg of a position on market
+// is close enough to open it. Entries are allowed at any time when the window is disabled or
+// neither venue's funding schedule is known.
+func (s *Strategy) inEntryWindow(market string, longEx, shortEx exchange.Exchange, now time.Time) bool {
+	window := s.entryWindow()
+	if window <= 0 {
+		return true
+	}
+	next := s.calendar.Soonest([]string{longEx.Name(), shortEx.Name()}, []string{market}, now)
+	return next.IsZero() || next.Sub(now) <= window
+}
+
+// collectAfter returns when both legs of a position on market opened at now have been paid
+// funding once, or the zero time when that is unknown.
+func (s *Strategy) collectAfter(market string, longEx, shortEx exchange.Exchange, now time.Time) time.Time {
+	longNext := s.calendar.NextFunding(longEx.Name(), market, now)
+	shortNext := s.calendar.NextFunding(shortEx.Name(), market, now)
+	if longNext.IsZero() || shortNext.IsZero() {
+		return time.Time{}
+	}
+	last := longNext
+	if shortNext.After(last) {
+		last = shortNext
+	}
+	return last.Add(fundingSettleDelay)
+}
+
+// fundingCollected reports whether position is due to be closed under EXIT_AFTER_FUNDING.
+func (s *Strategy) fundingCollected(position *PositionInfo, now time.Time) bool {
+	return s.config.ExitAfterFunding && !position.CollectAfter.IsZero() && !now.Before(position.CollectAfter)
+}
+
+// newBlackoutRules builds the rules of MARKET_BLACKLIST and MARKET_BLACKOUTS, returning nil when
+// neither is set. Invalid windows are logged and left out; the blacklist still applies.
+func newBlackoutRules(cfg config.Config, logger *log.Logger) *blackout.Rules {
+	windows, err := blackout.ParseWindows(cfg.MarketBlackouts)
+	if err != nil {
+		logger.Printf("Ignoring MARKET_BLACKOUTS: %v", err)
+		windows = nil
+	}
+	return blackout.New(cfg.MarketBlacklist, windows)
+}
@@ -0,0 +1,136 @@
+package strategy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/exchange"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/state"
+)
+
+// UnwindResult is the outcome of closing both legs of a saved position. A nil error means the
+// leg was closed.
+type UnwindResult struct {
+	Position state.Position
+	Amount   decimal.Decimal
+	LongErr  error
+	ShortErr error
+}
+
+// Closed reports whether both legs were closed.
+func (r UnwindResult) Closed() bool {
+	return r.LongErr == nil && r.ShortErr == nil
+}
+
+// SavedPositions returns the positions saved in store by the funding-rate-arb strategy, only
+// those on market unless it is empty.
+func SavedPositions(store *state.Store, market string) ([]state.Position, error) {
+	saved, err := store.Load(DefaultName)
+	if err != nil {
+		return nil, err
+	}
+	if market == "" {
+		return saved, nil
+	}
+	var positions []state.Position
+	for _, p := range saved {
+		if p.Market == market {
+			positions = append(positions, p)
+		}
+	}
+	return positions, nil
+}
+
+// Unwind market-closes both legs of the positions saved in store, only those on market unless it
+// is empty, for an operator unwinding without the bot running. Each leg is sized like the
+// strategy's own closes, at the position's USD size over the mean mark price of its venues.
+// Legs the exchange no longer lists are skipped. Positions whose legs both close are removed from
+// store; the others stay, so the unwind can be retried. It returns an error only if the store
+// can't be read or written.
+func Unwind(ctx context.Context, store *state.Store, exchanges []exchange.Exchange, market string) ([]UnwindResult, error) {
+	saved, err := store.Load(DefaultName)
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]exchange.Exchange, len(exchanges))
+	for _, ex := range exchanges {
+		byName[ex.Name()] = ex
+	}
+
+	var results []UnwindResult
+	var remaining []state.Position
+	for _, p := range saved {
+		if market != "" && p.Market != market {
+			remaining = append(remaining, p)
+			continue
+		}
+		result := unwindPosition(ctx, p, byName[p.LongExchange], byName[p.ShortExchange])
+		if !result.Closed() {
+			remaining = append(remaining, p)
+		}
+		results = append(results, result)
+	}
+	if err := store.Save(DefaultName, remaining); err != nil {
+		return results, err
+	}
+	return results, nil
+}
+
+// unwindPosition closes the legs of p on longEx and shortEx, which are nil when the exchange is
+// not configured.
+func unwindPosition(ctx context.Context, p state.Position, longEx, shortEx exchange.Exchange) UnwindResult {
+	result := UnwindResult{Position: p}
+	if longEx == nil || shortEx == nil {
+		err := fmt.Errorf("long %s or short %s is not configured in EXCHANGES", p.LongExchange, p.ShortExchange)
+		result.LongErr, result.ShortErr = err, err
+		return result
+	}
+
+	total, priced := 0.0, 0
+	var priceErrs []error
+	for _, ex := range []exchange.Exchange{longEx, shortEx} {
+		price, err := ex.GetMarkPrice(ctx, p.Market)
+		if err != nil || price <= 0 {
+			priceErrs = append(priceErrs, fmt.Errorf("no mark price for %s on %s: %v", p.Market, ex.Name(), err))
+			continue
+		}
+		total += price
+		priced++
+	}
+	if priced == 0 {
+		err := errors.Join(priceErrs...)
+		result.LongErr, result.ShortErr = err, err
+		return result
+	}
+	result.Amount = decimal.NewFromFloat(p.SizeUSD).Div(decimal.NewFromFloat(total / float64(priced)))
+	if p.Amount.IsPositive() {
+		result.Amount = p.Amount
+	}
+
+	if legOpen(ctx, longEx, p.Market, exchange.Buy) {
+		_, result.LongErr = longEx.ClosePosition(ctx, p.Market, exchange.Buy, result.Amount)
+	}
+	if legOpen(ctx, shortEx, p.Market, exchange.Sell) {
+		_, result.ShortErr = shortEx.ClosePosition(ctx, p.Market, exchange.Sell, result.Amount)
+	}
+	return result
+}
+
+// legOpen reports whether ex may still hold the leg on side of market. A leg is only known to be
+// closed when ex lists its positions without it, so that retrying an unwind whose other leg
+// failed doesn't trade the closed leg again.
+func legOpen(ctx context.Context, ex exchange.Exchange, market string, side exchange.OrderSide) bool {
+	positions, err := ex.GetPositions(ctx)
+	if err != nil {
+		return true
+	}
+	for _, position := range positions {
+		if position.Market == market && position.Side == side && position.Size > 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+package strategy
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/exchange"
+)
+
+const (
+	// volatilityWindow is how far back mark prices are kept to measure a market's volatility.
+	volatilityWindow = 24 * time.Hour
+	// minVolatilitySamples is how many prices a market needs before its volatility is trusted.
+	minVolatilitySamples = 10
+)
+
+// priceHistory keeps the mark prices of each market seen over the last volatilityWindow. The zero
+// value is ready to use.
+type priceHistory struct {
+	mu     sync.Mutex
+	points map[string][]pricePoint
+}
+
+type pricePoint struct {
+	at    time.Time
+	price float64
+}
+
+// add records the price of market at a time, and forgets the prices that have left the window.
+func (h *priceHistory) add(market string, price float64, at time.Time) {
+	if price <= 0 {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.points == nil {
+		h.points = make(map[string][]pricePoint)
+	}
+	points := append(h.points[market], pricePoint{at: at, price: price})
+	cutoff := at.Add(-volatilityWindow)
+	for len(points) > 0 && points[0].at.Before(cutoff) {
+		points = points[1:]
+	}
+	h.points[market] = points
+}
+
+// dailyVolatility returns the realized volatility of market over the window as the standard
+// deviation of its daily log returns, e.g. 0.03 for 3% a day. The prices may be unevenly spaced,
+// so the squared returns are summed over the time they span. It reports false until enough prices
+// have been seen.
+func (h *priceHistory) dailyVolatility(market string) (float64, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	points := h.points[market]
+	if len(points) < minVolatilitySamples {
+		return 0, false
+	}
+	var variance float64
+	for i := 1; i < len(points); i++ {
+		r := math.Log(points[i].price / points[i-1].price)
+		variance += r * r
+	}
+	span := points[len(points)-1].at.Sub(points[0].at)
+	if span <= 0 {
+		return 0, false
+	}
+	return math.Sqrt(variance * float64(24*time.Hour) / float64(span)), true
+}
+
+// samplePrices records the mark price of every market on the first venue quoting it, for sizing
+// by volatility.
+func (s *Strategy) samplePrices(rates map[string]map[string]float64, venues []exchange.Exchange, now time.Time) {
+	for _, market := range s.config.Markets {
+		for _, ex := range venues {
+			if _, ok := rates[ex.Name()][market]; !ok {
+				continue
+			}
+			price, err := s.marketData.MarkPrice(s.ctx, ex, market)
+			if err != nil {
+				s.logger.Printf("Could not sample the %s price on %s for its volatility: %v", market, ex.Name(), err)
+			} else {
+				s.prices.add(market, price, now)
+			}
+			break
+		}
+	}
+}
@@ -0,0 +1,90 @@
+// Package tuning adapts strategy parameters to realized trading outcomes.
+package tuning
+
+import "sync"
+
+// DefaultWindow is the number of outcomes per market kept when no window is configured.
+const DefaultWindow = 20
+
+// Outcome is the realized result of one closed position.
+type Outcome struct {
+	// Slippage is the entry and exit cost relative to notional (0.0005 = 5 bps), summed over both legs.
+	Slippage float64
+	// Intervals is how many funding intervals the spread stayed favorable while the position was open.
+	Intervals float64
+}
+
+// Threshold adjusts the minimum funding rate difference per market from a rolling window of outcomes.
+// A market's threshold rises when spreads fail to persist for a full funding interval and when
+// slippage, amortized over how long spreads typically last, exceeds the base threshold.
+type Threshold struct {
+	base    float64
+	floor   float64
+	ceiling float64
+	window  int
+
+	mu       sync.Mutex
+	outcomes map[string][]Outcome
+}
+
+// NewThreshold creates a tuner around base, bounded by floor and ceiling. A zero ceiling
+// means unbounded and a non-positive window uses DefaultWindow.
+func NewThreshold(base, floor, ceiling float64, window int) *Threshold {
+	if window <= 0 {
+		window = DefaultWindow
+	}
+	return &Threshold{base: base, floor: floor, ceiling: ceiling, window: window, outcomes: make(map[string][]Outcome)}
+}
+
+// Record adds an outcome for market, dropping the oldest one once the window is full.
+func (t *Threshold) Record(market string, outcome Outcome) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	outcomes := append(t.outcomes[market], outcome)
+	if len(outcomes) > t.window {
+		outcomes = outcomes[len(outcomes)-t.window:]
+	}
+	t.outcomes[market] = outcomes
+}
+
+// For returns the current threshold for market. Markets without outcomes use the base threshold.
+func (t *Threshold) For(market string) float64 {
+	t.mu.Lock()
+	outcomes := t.outcomes[market]
+	t.mu.Unlock()
+	if len(outcomes) == 0 {
+		return t.clamp(t.base)
+	}
+
+	var slippage, intervals float64
+	failed := 0
+	for _, o := range outcomes {
+		slippage += o.Slippage
+		intervals += o.Intervals
+		if o.Intervals < 1 {
+			failed++
+		}
+	}
+	n := float64(len(outcomes))
+	avgSlippage := slippage / n
+	avgIntervals := intervals / n
+	if avgIntervals < 1 {
+		avgIntervals = 1
+	}
+
+	threshold := t.base * (1 + float64(failed)/n)
+	if amortized := avgSlippage / avgIntervals; amortized > threshold {
+		threshold = amortized
+	}
+	return t.clamp(threshold)
+}
+
+func (t *Threshold) clamp(v float64) float64 {
+	if v < t.floor {
+		return t.floor
+	}
+	if t.ceiling > 0 && v > t.ceiling {
+		return t.ceiling
+	}
+	return v
+}
@@ -0,0 +1,37 @@
+package tuning
+
+import (
+	"math"
+	"testing"
+)
+
+func TestThresholdAdapts(t *testing.T) {
+	th := NewThreshold(0.0001, 0.00005, 0.0005, 4)
+
+	if got := th.For("BTC-USD"); got != 0.0001 {
+		t.Fatalf("expected the base threshold without outcomes, got %f", got)
+	}
+
+	// Half of the spreads collapsed before the first funding payment.
+	th.Record("BTC-USD", Outcome{Intervals: 0.5})
+	th.Record("BTC-USD", Outcome{Intervals: 3})
+	if got := th.For("BTC-USD"); math.Abs(got-0.00015) > 1e-12 {
+		t.Errorf("expected the threshold to rise to 0.00015, got %f", got)
+	}
+
+	// Heavy slippage amortized over short-lived spreads hits the ceiling.
+	for i := 0; i < 4; i++ {
+		th.Record("ETH-USD", Outcome{Slippage: 0.002, Intervals: 1})
+	}
+	if got := th.For("ETH-USD"); got != 0.0005 {
+		t.Errorf("expected the ceiling, got %f", got)
+	}
+
+	// Older outcomes fall out of the window.
+	for i := 0; i < 4; i++ {
+		th.Record("ETH-USD", Outcome{Intervals: 10})
+	}
+	if got := th.For("ETH-USD"); got != 0.0001 {
+		t.Errorf("expected the base threshold after good outcomes, got %f", got)
+	}
+}
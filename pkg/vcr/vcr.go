@@ -0,0 +1,273 @@
+// Package vcr records HTTP interactions with exchanges into fixture files and replays them,
+// so adapters can be regression-tested against realistic responses without network access.
+package vcr
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Mode selects whether a Recorder talks to the network.
+type Mode int
+
+const (
+	// ModeReplay serves responses from the cassette and fails on unknown requests.
+	ModeReplay Mode = iota
+	// ModeRecord forwards requests to the real transport and appends them to the cassette.
+	ModeRecord
+)
+
+// ModeFromEnv returns ModeRecord when VCR_MODE=record, ModeReplay otherwise.
+func ModeFromEnv() Mode {
+	if strings.EqualFold(os.Getenv("VCR_MODE"), "record") {
+		return ModeRecord
+	}
+	return ModeReplay
+}
+
+// ErrNoCassette is returned by New when replaying a cassette that has not been recorded.
+var ErrNoCassette = errors.New("vcr: cassette not found, record it with VCR_MODE=record")
+
+// Redacted replaces secret header and query values in recorded interactions.
+const Redacted = "REDACTED"
+
+// secretHeaders are never written to cassettes.
+var secretHeaders = []string{"Authorization", "X-Api-Key", "X-Mbx-Apikey", "Cookie", "Set-Cookie"}
+
+// volatileParams change on every request and are ignored when matching and redacted when recording.
+var volatileParams = []string{"signature", "timestamp", "nonce", "recvWindow"}
+
+// Interaction is a single recorded request/response pair.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request is the recorded part of an HTTP request.
+type Request struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Body   string `json:"body,omitempty"`
+}
+
+// Response is the recorded part of an HTTP response.
+type Response struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body"`
+}
+
+// Cassette is the on-disk fixture format.
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Recorder is an http.RoundTripper that records to or replays from a cassette file.
+type Recorder struct {
+	path      string
+	mode      Mode
+	transport http.RoundTripper
+
+	mu       sync.Mutex
+	cassette Cassette
+	used     map[int]bool
+}
+
+// New opens the cassette at path. In replay mode the cassette must exist.
+// transport is used in record mode and defaults to http.DefaultTransport.
+func New(path string, mode Mode, transport http.RoundTripper) (*Recorder, error) {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	r := &Recorder{path: path, mode: mode, transport: transport, used: make(map[int]bool)}
+	if mode == ModeRecord {
+		return r, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNoCassette, path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cassette: %w", err)
+	}
+	if err := json.Unmarshal(data, &r.cassette); err != nil {
+		return nil, fmt.Errorf("failed to parse cassette %s: %w", path, err)
+	}
+	return r, nil
+}
+
+// Client returns an HTTP client using the recorder as its transport.
+func (r *Recorder) Client() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+	recorded := Request{Method: req.Method, URL: redactURL(req.URL), Body: body}
+
+	if r.mode == ModeRecord {
+		return r.record(req, recorded)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// Identical requests are replayed in the order they were recorded; the last one repeats.
+	match := -1
+	for i, interaction := range r.cassette.Interactions {
+		if !matches(interaction.Request, recorded) {
+			continue
+		}
+		match = i
+		if !r.used[i] {
+			break
+		}
+	}
+	if match < 0 {
+		return nil, fmt.Errorf("vcr: no recorded interaction for %s %s in %s", recorded.Method, recorded.URL, r.path)
+	}
+	r.used[match] = true
+	return toHTTPResponse(req, r.cassette.Interactions[match].Response), nil
+}
+
+func (r *Recorder) record(req *http.Request, recorded Request) (*http.Response, error) {
+	resp, err := r.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	response := Response{Status: resp.StatusCode, Body: string(data), Headers: make(map[string]string)}
+	for name := range resp.Header {
+		if !isSecretHeader(name) {
+			response.Headers[name] = resp.Header.Get(name)
+		}
+	}
+
+	r.mu.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, Interaction{Request: recorded, Response: response})
+	r.mu.Unlock()
+	return toHTTPResponse(req, response), nil
+}
+
+// Save writes the recorded interactions to the cassette file. It is a no-op in replay mode.
+func (r *Recorder) Save() error {
+	if r.mode != ModeRecord {
+		return nil
+	}
+	r.mu.Lock()
+	data, err := json.MarshalIndent(r.cassette, "", "  ")
+	r.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(r.path, append(data, '\n'), 0o644)
+}
+
+func readBody(req *http.Request) (string, error) {
+	if req.Body == nil {
+		return "", nil
+	}
+	data, err := io.ReadAll(req.Body)
+	if err != nil {
+		return "", err
+	}
+	req.Body.Close()
+	req.Body = io.NopCloser(bytes.NewReader(data))
+	return string(data), nil
+}
+
+// redactURL returns the path and sorted query of u with volatile values redacted,
+// so the recorded URL is stable across runs and safe to commit.
+func redactURL(u *url.URL) string {
+	query := u.Query()
+	for _, param := range volatileParams {
+		if query.Has(param) {
+			query.Set(param, Redacted)
+		}
+	}
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		for _, v := range query[k] {
+			if b.Len() > 0 {
+				b.WriteByte('&')
+			}
+			b.WriteString(url.QueryEscape(k) + "=" + url.QueryEscape(v))
+		}
+	}
+	out := u.Path
+	if b.Len() > 0 {
+		out += "?" + b.String()
+	}
+	return out
+}
+
+func matches(recorded, actual Request) bool {
+	return recorded.Method == actual.Method && recorded.URL == actual.URL && jsonEqual(recorded.Body, actual.Body)
+}
+
+// jsonEqual compares bodies semantically when both are JSON, so key order does not matter.
+func jsonEqual(a, b string) bool {
+	if a == b {
+		return true
+	}
+	var va, vb interface{}
+	if json.Unmarshal([]byte(a), &va) != nil || json.Unmarshal([]byte(b), &vb) != nil {
+		return false
+	}
+	ja, _ := json.Marshal(va)
+	jb, _ := json.Marshal(vb)
+	return bytes.Equal(ja, jb)
+}
+
+func isSecretHeader(name string) bool {
+	for _, secret := range secretHeaders {
+		if strings.EqualFold(name, secret) {
+			return true
+		}
+	}
+	return false
+}
+
+func toHTTPResponse(req *http.Request, r Response) *http.Response {
+	header := make(http.Header)
+	for k, v := range r.Headers {
+		header.Set(k, v)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.Status, http.StatusText(r.Status)),
+		StatusCode:    r.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(r.Body)),
+		ContentLength: int64(len(r.Body)),
+		Request:       req,
+	}
+}
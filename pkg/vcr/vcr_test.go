@@ -0,0 +1,57 @@
+package vcr
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecordThenReplay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Set-Cookie", "session=secret-cookie")
+		w.Write([]byte(`{"price":"` + r.URL.Query().Get("symbol") + `"}`))
+	}))
+	path := filepath.Join(t.TempDir(), "cassette.json")
+
+	recorder, err := New(path, ModeRecord, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest("GET", server.URL+"/price?symbol=BTC&timestamp=123&signature=abc", nil)
+	req.Header.Set("X-Api-Key", "secret-key")
+	if _, err := recorder.Client().Do(req); err != nil {
+		t.Fatal(err)
+	}
+	if err := recorder.Save(); err != nil {
+		t.Fatal(err)
+	}
+	server.Close()
+
+	data, _ := os.ReadFile(path)
+	for _, secret := range []string{"secret-key", "secret-cookie", "abc", "123"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("cassette leaks %q: %s", secret, data)
+		}
+	}
+
+	replayer, err := New(path, ModeReplay, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := replayer.Client().Get("https://elsewhere.example/price?signature=new&symbol=BTC&timestamp=456")
+	if err != nil {
+		t.Fatalf("replay failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != `{"price":"BTC"}` {
+		t.Errorf("unexpected replayed body %s", body)
+	}
+
+	if _, err := replayer.Client().Get("https://elsewhere.example/price?symbol=ETH"); err == nil {
+		t.Error("expected unrecorded requests to fail in replay mode")
+	}
+}
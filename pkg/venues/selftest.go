@@ -0,0 +1,66 @@
+package venues
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/collateral"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/exchange"
+)
+
+// DefaultMaxClockSkew is used when MAX_CLOCK_SKEW_MS is not set.
+const DefaultMaxClockSkew = 2 * time.Second
+
+// SelfTest checks that each exchange is reachable, accepts its credentials and, where it reports
+// its server time, agrees with the local clock within maxSkew. It returns every failure, so a
+// misconfigured venue is found at startup rather than on the first order.
+func SelfTest(ctx context.Context, exchanges []exchange.Exchange, maxSkew time.Duration) error {
+	if maxSkew <= 0 {
+		maxSkew = DefaultMaxClockSkew
+	}
+	var errs []error
+	for _, ex := range exchanges {
+		if err := selfTest(ctx, ex, maxSkew); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", ex.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func selfTest(ctx context.Context, ex exchange.Exchange, maxSkew time.Duration) error {
+	if _, err := ex.GetFundingRates(ctx); err != nil {
+		return fmt.Errorf("cannot reach the API: %w", err)
+	}
+
+	var err error
+	if pinger, ok := ex.(exchange.Pinger); ok {
+		err = pinger.Ping(ctx)
+	} else {
+		_, err = ex.GetBalance(ctx, collateral.AssetOf(ex))
+	}
+	if err != nil {
+		return fmt.Errorf("authenticated call failed, check the API credentials: %w", err)
+	}
+
+	clock, ok := ex.(exchange.ServerClock)
+	if accounts, isAccounts := ex.(*exchange.Accounts); isAccounts {
+		// The accounts share the first one's server.
+		clock, ok = accounts.Primary().(exchange.ServerClock)
+	}
+	if !ok {
+		return nil
+	}
+	sent := time.Now()
+	server, err := clock.ServerTime(ctx)
+	if err != nil {
+		return fmt.Errorf("cannot read the server time: %w", err)
+	}
+	// The server read its clock about halfway through the round trip.
+	local := sent.Add(time.Since(sent) / 2)
+	if skew := local.Sub(server); skew > maxSkew || skew < -maxSkew {
+		return fmt.Errorf("local clock is %s off the server's, beyond %s, so signed requests may be rejected; sync it with NTP", skew.Round(time.Millisecond), maxSkew)
+	}
+	return nil
+}
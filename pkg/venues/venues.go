@@ -0,0 +1,479 @@
+// Package venues builds the perpetual exchange clients selected in the configuration.
+package venues
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/config"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/exchange"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/exchange/generic"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/exchange/remote"
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/pkg/httpclient"
+)
+
+// Default is used when EXCHANGES is not set.
+var Default = []string{"lighter", "extended"}
+
+// Available lists the exchanges New can create.
+var Available = []string{"lighter", "extended", "dydx", "binance", "bybit", "aster", "paradex", "drift", "apex", "aevo", "orderly", "okx", "gmx"}
+
+// errAsterTestnet refuses Aster on testnet: Aster has none, so its orders would be real.
+var errAsterTestnet = errors.New("aster has no testnet; set TESTNET=false to use it, with --paper to trade it without real orders")
+
+// Names returns the configured exchange names, lower-cased, or Default.
+func Names(cfg config.Config) []string {
+	var names []string
+	for _, name := range cfg.Exchanges {
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return Default
+	}
+	return names
+}
+
+// TransportWrapper wraps the HTTP transport of the named exchange's client, e.g. to time its
+// requests.
+type TransportWrapper func(exchange string, base http.RoundTripper) http.RoundTripper
+
+// FromConfig creates a client for each exchange named in EXCHANGES, in order. Exchanges with
+// accounts in ACCOUNTS are traded through all of them.
+func FromConfig(cfg config.Config) ([]exchange.Exchange, error) {
+	return FromConfigWrapped(cfg, nil)
+}
+
+// FromConfigWrapped is FromConfig with the transport of every client, those of ACCOUNTS included,
+// wrapped by wrap. The wrapper sits below the RATE_LIMITS limiter, so time spent waiting for the
+// limiter is not part of the wrapped requests.
+func FromConfigWrapped(cfg config.Config, wrap TransportWrapper) ([]exchange.Exchange, error) {
+	accounts, err := config.ParseAccounts(cfg.Accounts)
+	if err != nil {
+		return nil, err
+	}
+	var exchanges []exchange.Exchange
+	for _, name := range Names(cfg) {
+		ex, err := newWrapped(name, cfg, wrap)
+		if err != nil {
+			return nil, err
+		}
+		if ex, err = withAccounts(name, ex, cfg, accounts, wrap); err != nil {
+			return nil, err
+		}
+		exchanges = append(exchanges, ex)
+	}
+	return exchanges, nil
+}
+
+// AccountIDs identifies every account the configuration trades: one entry per exchange in
+// EXCHANGES and per account of it in ACCOUNTS, plus the SPOT_EXCHANGE account, sorted. Two
+// configurations trading the same accounts get the same IDs, which key the single-instance lock.
+func AccountIDs(cfg config.Config) ([]string, error) {
+	accounts, err := config.ParseAccounts(cfg.Accounts)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, name := range Names(cfg) {
+		ids = append(ids, accountID(name, cfg))
+		for _, account := range accounts {
+			if account.Exchange != name || account.IsDefault() {
+				continue
+			}
+			accountCfg, err := cfg.ForAccount(account)
+			if err != nil {
+				return nil, fmt.Errorf("ACCOUNTS: %w", err)
+			}
+			ids = append(ids, accountID(name, accountCfg))
+		}
+	}
+	if cfg.SpotExchange != "" {
+		ids = append(ids, "spot:"+accountID(cfg.SpotExchange, cfg))
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// accountID identifies the account of exchange name in cfg by the settings that select it: its
+// API key, address or subaccount. Sidecars and descriptor venues are identified by name.
+func accountID(name string, cfg config.Config) string {
+	var parts []string
+	switch name {
+	case "lighter":
+		parts = []string{cfg.LighterAPIKey, strconv.FormatInt(cfg.LighterAccountIndex, 10)}
+	case "extended":
+		parts = []string{cfg.ExtendedAPIKey, strconv.Itoa(cfg.ExtendedVaultID)}
+	case "dydx":
+		parts = []string{cfg.DydxAddress, strconv.Itoa(cfg.DydxSubaccount)}
+	case "binance":
+		parts = []string{cfg.BinanceAPIKey}
+	case "bybit":
+		parts = []string{cfg.BybitAPIKey}
+	case "aster":
+		parts = []string{cfg.AsterAPIKey}
+	case "paradex":
+		parts = []string{cfg.ParadexAccount}
+	case "drift":
+		parts = []string{cfg.DriftGatewayURL, strconv.Itoa(cfg.DriftSubaccount)}
+	case "apex":
+		parts = []string{cfg.ApexAPIKey}
+	case "aevo":
+		parts = []string{cfg.AevoAccount}
+	case "orderly":
+		parts = []string{cfg.OrderlyAccountID}
+	case "okx":
+		parts = []string{cfg.OKXAPIKey}
+	case "gmx":
+		parts = []string{cfg.GmxAccount}
+	}
+	return name + ":" + strings.Join(parts, ":")
+}
+
+// withAccounts returns ex, the client of the exchange's default account, together with a client
+// for each other account of the exchange in accounts, or ex alone if it has none.
+func withAccounts(name string, ex exchange.Exchange, cfg config.Config, accounts []config.Account, wrap TransportWrapper) (exchange.Exchange, error) {
+	all := []exchange.Account{{Name: config.DefaultAccount, Exchange: ex}}
+	for _, account := range accounts {
+		if account.Exchange != strings.ToLower(name) {
+			continue
+		}
+		if account.IsDefault() {
+			all[0].Markets = account.Markets
+			continue
+		}
+		accountCfg, err := cfg.ForAccount(account)
+		if err != nil {
+			return nil, fmt.Errorf("ACCOUNTS: %w", err)
+		}
+		client, err := newWrapped(name, accountCfg, wrap)
+		if err != nil {
+			return nil, fmt.Errorf("%s account %s: %w", name, account.Name, err)
+		}
+		all = append(all, exchange.Account{Name: account.Name, Exchange: client, Markets: account.Markets})
+	}
+	if len(all) == 1 {
+		return ex, nil
+	}
+	accountsEx, err := exchange.NewAccounts(all)
+	if err != nil {
+		return nil, fmt.Errorf("ACCOUNTS: %s: %w", name, err)
+	}
+	return accountsEx, nil
+}
+
+// New creates the client for a single exchange, rate limited if RATE_LIMITS lists it.
+func New(name string, cfg config.Config) (exchange.Exchange, error) {
+	return newWrapped(name, cfg, nil)
+}
+
+// newWrapped is New with the client's transport wrapped by wrap, if set, before it is rate
+// limited.
+func newWrapped(name string, cfg config.Config, wrap TransportWrapper) (exchange.Exchange, error) {
+	limits, err := ParseRateLimits(cfg.RateLimits)
+	if err != nil {
+		return nil, err
+	}
+	ex, err := create(name, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if wrap != nil {
+		if t, ok := ex.(interface{ SetTransport(http.RoundTripper) }); ok {
+			t.SetTransport(wrap(ex.Name(), http.DefaultTransport))
+		}
+	}
+	if limit, ok := limits[strings.ToLower(name)]; ok {
+		limited, ok := ex.(exchange.RateLimited)
+		if !ok {
+			return nil, fmt.Errorf("RATE_LIMITS: %s can't be rate limited", name)
+		}
+		limited.SetRateLimiter(httpclient.NewLimiter(limit.Rate, limit.Burst))
+	}
+	return ex, nil
+}
+
+// Tradable splits exchanges into those that can submit orders and the read-only ones, each in
+// order.
+func Tradable(exchanges []exchange.Exchange) (tradable, readOnly []exchange.Exchange) {
+	for _, ex := range exchanges {
+		if exchange.IsReadOnly(ex) {
+			readOnly = append(readOnly, ex)
+		} else {
+			tradable = append(tradable, ex)
+		}
+	}
+	return tradable, readOnly
+}
+
+// Rename wraps each exchange that SYMBOL_MAP lists markets for, so they are reported and traded
+// under the bot's market names.
+func Rename(cfg config.Config, exchanges []exchange.Exchange) ([]exchange.Exchange, error) {
+	symbols, err := exchange.ParseSymbolMap(cfg.SymbolMap)
+	if err != nil {
+		return nil, fmt.Errorf("SYMBOL_MAP: %w", err)
+	}
+	renamed := make([]exchange.Exchange, len(exchanges))
+	for i, ex := range exchanges {
+		renamed[i] = ex
+		if markets := symbols.For(ex.Name()); markets != nil {
+			renamed[i] = exchange.NewRenamed(ex, markets)
+		}
+	}
+	return renamed, nil
+}
+
+// RateLimit is the request budget of one exchange.
+type RateLimit struct {
+	// Rate is the sustained number of requests per second.
+	Rate float64
+	// Burst is how many requests may be sent at once after a quiet period.
+	Burst int
+}
+
+// ParseRateLimits parses RATE_LIMITS entries of the form "EXCHANGE=RATE[:BURST]", e.g.
+// "binance=20:40", by lower-cased exchange name. The burst defaults to the rate, rounded up.
+func ParseRateLimits(entries []string) (map[string]RateLimit, error) {
+	limits := make(map[string]RateLimit)
+	for _, entry := range entries {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(entry, "=")
+		name = strings.ToLower(strings.TrimSpace(name))
+		rateSpec, burstSpec, hasBurst := strings.Cut(strings.TrimSpace(value), ":")
+		rate, err := strconv.ParseFloat(rateSpec, 64)
+		if !ok || name == "" || err != nil || rate <= 0 {
+			return nil, fmt.Errorf("invalid RATE_LIMITS entry %q, expected exchange=requests_per_second[:burst] with a positive rate", entry)
+		}
+		limit := RateLimit{Rate: rate, Burst: int(math.Ceil(rate))}
+		if hasBurst {
+			if limit.Burst, err = strconv.Atoi(burstSpec); err != nil || limit.Burst < 1 {
+				return nil, fmt.Errorf("invalid burst in RATE_LIMITS entry %q, expected a positive whole number", entry)
+			}
+		}
+		if _, ok := limits[name]; ok {
+			return nil, fmt.Errorf("RATE_LIMITS: %s is listed twice", name)
+		}
+		limits[name] = limit
+	}
+	return limits, nil
+}
+
+// create builds the client for a single exchange.
+func create(name string, cfg config.Config) (exchange.Exchange, error) {
+	switch strings.ToLower(name) {
+	case "lighter":
+		lighter := exchange.NewLighter(cfg.LighterAPIKey, cfg.LighterPrivateKey, cfg.Testnet)
+		if cfg.LighterSignerCmd != "" {
+			signer, err := exchange.NewCommandSigner(cfg.LighterSignerCmd, cfg.LighterPrivateKey, cfg.Testnet)
+			if err != nil {
+				return nil, err
+			}
+			lighter.SetSigner(signer, cfg.LighterAccountIndex, cfg.LighterAPIKeyIndex)
+		}
+		return lighter, nil
+	case "extended":
+		return exchange.NewExtended(cfg.ExtendedAPIKey, cfg.ExtendedPrivateKey, cfg.ExtendedPublicKey, cfg.ExtendedVaultID, cfg.Testnet)
+	case "dydx":
+		if cfg.DydxAddress == "" {
+			return nil, fmt.Errorf("dydx requires DYDX_ADDRESS")
+		}
+		dydx := exchange.NewDydx(cfg.DydxAddress, cfg.DydxMnemonic, cfg.DydxSubaccount, cfg.Testnet)
+		if cfg.DydxValidatorURL != "" {
+			dydx.SetValidatorURL(cfg.DydxValidatorURL)
+		}
+		if cfg.DydxSignerCmd != "" {
+			signer, err := exchange.NewDydxCommandSigner(cfg.DydxSignerCmd, cfg.DydxMnemonic, cfg.Testnet)
+			if err != nil {
+				return nil, err
+			}
+			dydx.SetSigner(signer)
+		}
+		return dydx, nil
+	case "binance":
+		return exchange.NewBinance(cfg.BinanceAPIKey, cfg.BinanceSecretKey, cfg.Testnet), nil
+	case "bybit":
+		return exchange.NewBybit(cfg.BybitAPIKey, cfg.BybitSecretKey, cfg.Testnet), nil
+	case "aster":
+		if cfg.Testnet {
+			return nil, errAsterTestnet
+		}
+		return exchange.NewAster(cfg.AsterAPIKey, cfg.AsterSecretKey), nil
+	case "paradex":
+		paradex := exchange.NewParadex(cfg.ParadexAccount, cfg.ParadexPrivateKey, cfg.Testnet)
+		if cfg.ParadexHashCmd != "" {
+			hasher, err := exchange.NewCommandHasher(cfg.ParadexHashCmd)
+			if err != nil {
+				return nil, err
+			}
+			paradex.SetHasher(hasher)
+		}
+		return paradex, nil
+	case "drift":
+		return exchange.NewDrift(cfg.DriftGatewayURL, cfg.DriftSubaccount, cfg.Testnet), nil
+	case "apex":
+		apex := exchange.NewApex(cfg.ApexAPIKey, cfg.ApexSecretKey, cfg.ApexPassphrase, cfg.Testnet)
+		if cfg.ApexSignerCmd != "" {
+			signer, err := exchange.NewApexCommandSigner(cfg.ApexSignerCmd, cfg.ApexZKSeeds)
+			if err != nil {
+				return nil, err
+			}
+			apex.SetSigner(signer)
+		}
+		return apex, nil
+	case "aevo":
+		aevo := exchange.NewAevo(cfg.AevoAccount, cfg.AevoAPIKey, cfg.AevoSecretKey, cfg.Testnet)
+		if cfg.AevoSignerCmd != "" {
+			signer, err := exchange.NewAevoCommandSigner(cfg.AevoSignerCmd, cfg.AevoSigningKey, cfg.Testnet)
+			if err != nil {
+				return nil, err
+			}
+			aevo.SetSigner(signer)
+		}
+		return aevo, nil
+	case "orderly":
+		return exchange.NewOrderly(cfg.OrderlyAccountID, cfg.OrderlySecretKey, cfg.Testnet), nil
+	case "okx":
+		return exchange.NewOKX(cfg.OKXAPIKey, cfg.OKXSecretKey, cfg.OKXPassphrase, cfg.Testnet), nil
+	case "gmx":
+		gmx := exchange.NewGmx(cfg.GmxAccount)
+		if cfg.GmxRPCURL != "" {
+			gmx.SetRPCURL(cfg.GmxRPCURL)
+		}
+		if cfg.GmxSignerCmd != "" {
+			wallet, err := exchange.NewGmxCommandWallet(cfg.GmxSignerCmd, cfg.GmxPrivateKey)
+			if err != nil {
+				return nil, err
+			}
+			gmx.SetWallet(wallet)
+		}
+		return gmx, nil
+	default:
+		remotes, err := remotes(cfg)
+		if err != nil {
+			return nil, err
+		}
+		if url, ok := remotes[strings.ToLower(name)]; ok {
+			return remote.New(strings.ToLower(name), url, cfg.Testnet), nil
+		}
+		descriptors, err := descriptors(cfg)
+		if err != nil {
+			return nil, err
+		}
+		if d, ok := descriptors[strings.ToLower(name)]; ok {
+			return generic.New(*d, cfg.Testnet), nil
+		}
+		return nil, fmt.Errorf("unknown exchange %q (available: %s)", name, strings.Join(Available, ", "))
+	}
+}
+
+// descriptors loads the venue descriptors listed in VENUE_DESCRIPTORS, by lower-cased name.
+func descriptors(cfg config.Config) (map[string]*generic.Descriptor, error) {
+	descriptors := make(map[string]*generic.Descriptor)
+	for _, path := range cfg.VenueDescriptors {
+		if path = strings.TrimSpace(path); path == "" {
+			continue
+		}
+		d, err := generic.LoadDescriptor(path)
+		if err != nil {
+			return nil, err
+		}
+		name := strings.ToLower(d.Name)
+		if _, ok := descriptors[name]; ok || contains(Available, name) {
+			return nil, fmt.Errorf("venue descriptor %s: the name %q is already taken", path, d.Name)
+		}
+		descriptors[name] = d
+	}
+	return descriptors, nil
+}
+
+// remotes parses REMOTE_EXCHANGES into the sidecar URL of each venue, by lower-cased name.
+func remotes(cfg config.Config) (map[string]string, error) {
+	remotes := make(map[string]string)
+	for _, entry := range cfg.RemoteExchanges {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		name, url, ok := strings.Cut(entry, "=")
+		name, url = strings.ToLower(strings.TrimSpace(name)), strings.TrimSpace(url)
+		if !ok || name == "" || url == "" {
+			return nil, fmt.Errorf("invalid REMOTE_EXCHANGES entry %q, expected name=url", entry)
+		}
+		if _, ok := remotes[name]; ok || contains(Available, name) {
+			return nil, fmt.Errorf("REMOTE_EXCHANGES: the name %q is already taken", name)
+		}
+		remotes[name] = url
+	}
+	return remotes, nil
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks that every exchange named in EXCHANGES is available and has the settings it
+// can't be created without, without creating any client.
+func Validate(cfg config.Config) error {
+	var errs []error
+	if _, err := ParseRateLimits(cfg.RateLimits); err != nil {
+		errs = append(errs, err)
+	}
+	if accounts, err := config.ParseAccounts(cfg.Accounts); err != nil {
+		errs = append(errs, err)
+	} else {
+		for _, account := range accounts {
+			if !contains(Names(cfg), account.Exchange) {
+				errs = append(errs, fmt.Errorf("ACCOUNTS: %s is not in EXCHANGES", account.Exchange))
+			}
+		}
+	}
+	if _, err := exchange.ParseSymbolMap(cfg.SymbolMap); err != nil {
+		errs = append(errs, fmt.Errorf("SYMBOL_MAP: %w", err))
+	}
+	remotes, remotesErr := remotes(cfg)
+	if remotesErr != nil {
+		errs = append(errs, remotesErr)
+	}
+	descriptors, err := descriptors(cfg)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	for _, name := range Names(cfg) {
+		switch name {
+		case "lighter", "extended", "binance", "bybit", "paradex", "drift", "apex", "aevo", "orderly", "okx":
+		case "gmx":
+			if cfg.GmxSignerCmd != "" && cfg.GmxAccount == "" {
+				errs = append(errs, fmt.Errorf("gmx requires GMX_ACCOUNT with GMX_SIGNER_CMD"))
+			}
+		case "dydx":
+			if cfg.DydxAddress == "" {
+				errs = append(errs, fmt.Errorf("dydx requires DYDX_ADDRESS"))
+			}
+		case "aster":
+			if cfg.Testnet {
+				errs = append(errs, errAsterTestnet)
+			}
+		default:
+			_, isRemote := remotes[name]
+			_, isDescribed := descriptors[name]
+			if isRemote || isDescribed || remotesErr != nil || err != nil {
+				// Venues of entries that failed to load are already reported.
+				continue
+			}
+			errs = append(errs, fmt.Errorf("unknown exchange %q in EXCHANGES (available: %s)", name, strings.Join(Available, ", ")))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,89 @@
+package venues
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/basel-ax/perp-dex-funding-rate-arb-bot/config"
+)
+
+// countingTransport counts the requests it forwards to base.
+type countingTransport struct {
+	base  http.RoundTripper
+	count *int32
+}
+
+func (t countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	atomic.AddInt32(t.count, 1)
+	return t.base.RoundTrip(req)
+}
+
+func TestWrappedTransportKeepsTheRateLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("[]"))
+	}))
+	defer server.Close()
+
+	var wrapped int32
+	cfg := config.Config{
+		Exchanges:       []string{"sidecar"},
+		RemoteExchanges: []string{"sidecar=" + server.URL},
+		RateLimits:      []string{"sidecar=10:1"},
+	}
+	exchanges, err := FromConfigWrapped(cfg, func(name string, base http.RoundTripper) http.RoundTripper {
+		if name != "sidecar" {
+			t.Errorf("wrapped the transport of %q, want sidecar", name)
+		}
+		return countingTransport{base: base, count: &wrapped}
+	})
+	if err != nil {
+		t.Fatalf("FromConfigWrapped: %v", err)
+	}
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if _, err := exchanges[0].GetFundingRates(context.Background()); err != nil {
+			t.Fatalf("GetFundingRates: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("3 requests at 10 a second with a burst of 1 took %s, want the rate limit to hold", elapsed)
+	}
+	if got := atomic.LoadInt32(&wrapped); got != 3 {
+		t.Errorf("wrapped transport saw %d requests, want 3", got)
+	}
+}
+
+func TestAccountIDsCoverEveryTradedAccount(t *testing.T) {
+	cfg := config.Config{
+		Exchanges:       []string{"binance", "extended"},
+		BinanceAPIKey:   "binance-key",
+		ExtendedAPIKey:  "extended-key",
+		ExtendedVaultID: 1,
+		Accounts:        []string{"extended:vault2"},
+		AccountSettings: map[string]string{"EXTENDED_VAULT2_API_KEY": "vault2-key", "EXTENDED_VAULT2_VAULT_ID": "2"},
+	}
+	ids, err := AccountIDs(cfg)
+	if err != nil {
+		t.Fatalf("AccountIDs: %v", err)
+	}
+	want := []string{"binance:binance-key", "extended:extended-key:1", "extended:vault2-key:2"}
+	if strings.Join(ids, ",") != strings.Join(want, ",") {
+		t.Errorf("AccountIDs = %v, want %v", ids, want)
+	}
+
+	// The order of EXCHANGES doesn't matter, another Binance key does.
+	cfg.Exchanges = []string{"extended", "binance"}
+	if reordered, _ := AccountIDs(cfg); strings.Join(reordered, ",") != strings.Join(ids, ",") {
+		t.Errorf("expected the same IDs for reordered EXCHANGES, got %v", reordered)
+	}
+	cfg.BinanceAPIKey = "other-key"
+	if other, _ := AccountIDs(cfg); strings.Join(other, ",") == strings.Join(ids, ",") {
+		t.Error("expected another Binance account to change the IDs")
+	}
+}